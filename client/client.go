@@ -3,20 +3,34 @@ package client
 import (
 	"context"
 	"net/http"
+	"time"
 
 	clusterRequest "github.com/canonical/lxd/lxd/cluster/request"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
 	"github.com/gorilla/websocket"
 
 	"github.com/canonical/microcluster/v3/internal/rest/client"
 	"github.com/canonical/microcluster/v3/rest/types"
 )
 
+// eventStreamReconnectDelay is how long to wait before resuming an interrupted event stream.
+const eventStreamReconnectDelay = time.Second
+
 // Client is a rest client for the microcluster daemon.
 type Client struct {
 	client.Client
 }
 
+// Interceptor wraps the requests sent by a client, for instance to add headers or log requests.
+type Interceptor = client.Interceptor
+
+// RetryPolicy configures how a client retries requests that failed with a transient error.
+type RetryPolicy = client.RetryPolicy
+
+// DefaultRetryPolicy returns a RetryPolicy suitable for riding out a daemon restart or a leader election.
+var DefaultRetryPolicy = client.DefaultRetryPolicy
+
 // IsNotification determines if this request is to be considered a cluster-wide notification.
 func IsNotification(r *http.Request) bool {
 	return r.Header.Get("User-Agent") == clusterRequest.UserAgentNotifier
@@ -40,6 +54,46 @@ func (c *Client) Websocket(ctx context.Context, prefix types.EndpointPrefix, pat
 	return c.RawWebsocket(websocketCtx, prefix, path)
 }
 
+// EventStream is a helper for consuming a server-sent event stream on any endpoints defined external to microcluster,
+// such as those returning rest.EventStreamResponse. handler is called with each event until the context is cancelled
+// or handler returns an error, or the server ends the stream. If the connection is lost, the stream is resumed after
+// the last received event.
+func (c *Client) EventStream(ctx context.Context, prefix types.EndpointPrefix, path *api.URL, handler func(event types.ServerSentEvent) error) error {
+	var handlerErr error
+	handle := func(event types.ServerSentEvent) error {
+		handlerErr = handler(event)
+
+		return handlerErr
+	}
+
+	lastEventID := ""
+	for {
+		var err error
+		lastEventID, err = c.RawEventStream(ctx, prefix, path, lastEventID, handle)
+		if handlerErr != nil {
+			return handlerErr
+		}
+
+		// Errors returned by the endpoint itself are not resolved by reconnecting.
+		if api.StatusErrorCheck(err) {
+			return err
+		}
+
+		// The server ended the stream.
+		if err == nil {
+			return nil
+		}
+
+		logger.Warn("Event stream interrupted, reconnecting", logger.Ctx{"path": path.String(), "error": err})
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(eventStreamReconnectDelay):
+		}
+	}
+}
+
 // UseTarget returns a new client with the query "?target=name" set.
 func (c *Client) UseTarget(name string) *Client {
 	newClient := c.Client.UseTarget(name)
@@ -2,8 +2,14 @@ package client
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
+	"net/http"
 	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
 )
 
 // Cluster is a list of clients belonging to a cluster.
@@ -54,3 +60,87 @@ func (c Cluster) Query(ctx context.Context, concurrent bool, query func(context.
 
 	return nil
 }
+
+// MemberResult is the outcome of a query to a single cluster member.
+type MemberResult struct {
+	// Address is the address of the cluster member.
+	Address string
+
+	// Error is the error returned by the query to the cluster member, or nil if it succeeded.
+	Error error
+
+	// StatusCode is the HTTP status code the cluster member answered the failed query with, or 0 if the query
+	// succeeded or the member did not answer.
+	StatusCode int
+}
+
+// FanOutResult holds the outcome of a query to each cluster member, in the order of the clients of the cluster.
+type FanOutResult []MemberResult
+
+// Failed returns the results of the cluster members the query failed on.
+func (r FanOutResult) Failed() []MemberResult {
+	failed := []MemberResult{}
+	for _, result := range r {
+		if result.Error != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+// Err returns the errors of every cluster member the query failed on, or nil if it succeeded on all of them.
+// The error has the status code the cluster members answered with if they all answered with the same one, and 500
+// otherwise, rather than the status code of whichever member is listed first.
+func (r FanOutResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+
+	status := failed[0].StatusCode
+	errs := make([]error, 0, len(failed))
+	for _, result := range failed {
+		if result.StatusCode != status || result.StatusCode == 0 {
+			status = http.StatusInternalServerError
+		}
+
+		errs = append(errs, fmt.Errorf("Cluster member %q: %w", result.Address, result.Error))
+	}
+
+	return api.StatusErrorf(status, "Query failed on %d of %d cluster members: %w", len(failed), len(r), errors.Join(errs...))
+}
+
+// FanOut executes the given hook on all members of the cluster in parallel, and reports the outcome for each of them.
+// Unlike Query, a failure on one member does not prevent reporting the others. If timeout is set, the hook is
+// cancelled on members that have not responded within it.
+func (c Cluster) FanOut(ctx context.Context, timeout time.Duration, query func(context.Context, *Client) error) FanOutResult {
+	results := make(FanOutResult, len(c))
+	wg := sync.WaitGroup{}
+	for i, client := range c {
+		results[i].Address = client.URL().URL.Host
+
+		wg.Add(1)
+		go func(i int, client Client) {
+			defer wg.Done()
+
+			queryCtx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				queryCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			results[i].Error = query(queryCtx, &client)
+
+			status, ok := api.StatusErrorMatch(results[i].Error)
+			if ok {
+				results[i].StatusCode = status
+			}
+		}(i, client)
+	}
+
+	wg.Wait()
+
+	return results
+}
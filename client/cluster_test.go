@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures a fan-out query reports the outcome and status code of each cluster member, and that its error keeps the
+// status code only if every member failed with it.
+func TestFanOut(t *testing.T) {
+	addresses := []string{"10.0.0.1:9000", "10.0.0.2:9000", "10.0.0.3:9000"}
+
+	// slow answers after the fan-out timeout.
+	slow := func(w http.ResponseWriter) {
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	unavailable := failure(http.StatusServiceUnavailable, fmt.Errorf("Daemon is shutting down"))
+	notFound := failure(http.StatusNotFound, types.ErrMemberNotFound)
+
+	cases := []struct {
+		name           string
+		respond        []func(w http.ResponseWriter)
+		stopped        []bool
+		expectStatuses []int
+		expectStatus   int
+	}{
+		{name: "All members succeed", respond: []func(w http.ResponseWriter){nil, nil, nil}, expectStatuses: []int{0, 0, 0}},
+		{name: "All members fail alike", respond: []func(w http.ResponseWriter){unavailable, unavailable, unavailable}, expectStatuses: []int{503, 503, 503}, expectStatus: http.StatusServiceUnavailable},
+		{name: "Members fail differently", respond: []func(w http.ResponseWriter){nil, notFound, unavailable}, expectStatuses: []int{0, 404, 503}, expectStatus: http.StatusInternalServerError},
+		{name: "Member unreachable", respond: []func(w http.ResponseWriter){unavailable, unavailable, nil}, stopped: []bool{false, false, true}, expectStatuses: []int{503, 503, 0}, expectStatus: http.StatusInternalServerError},
+		{name: "Member times out", respond: []func(w http.ResponseWriter){nil, nil, slow}, expectStatuses: []int{0, 0, 0}, expectStatus: http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			testCluster := newTestCluster(t, addresses...)
+			cluster := make(Cluster, 0, len(addresses))
+			for i, address := range addresses {
+				member := testCluster.members[address]
+				member.set(types.MemberOnline, false, c.respond[i])
+				if c.stopped != nil && c.stopped[i] {
+					member.stop(t)
+				}
+
+				client, err := testCluster.connect(address)
+				require.NoError(t, err)
+
+				cluster = append(cluster, *client)
+			}
+
+			result := cluster.FanOut(context.Background(), 100*time.Millisecond, func(ctx context.Context, c *Client) error {
+				return c.Query(ctx, "POST", types.EndpointPrefix("1.0"), api.NewURL().Path("query"), nil, nil)
+			})
+
+			require.Len(t, result, len(addresses))
+			failed := 0
+			for i, member := range result {
+				require.Equal(t, cluster[i].URL().URL.Host, member.Address)
+				require.Equal(t, c.expectStatuses[i], member.StatusCode)
+				if c.respond[i] != nil || (c.stopped != nil && c.stopped[i]) {
+					require.Error(t, member.Error)
+					failed++
+				} else {
+					require.NoError(t, member.Error)
+				}
+			}
+
+			require.Len(t, result.Failed(), failed)
+
+			err := result.Err()
+			if c.expectStatus == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			status, ok := api.StatusErrorMatch(err)
+			require.True(t, ok)
+			require.Equal(t, c.expectStatus, status)
+
+			// The errors of each member remain available to the caller.
+			for _, member := range result.Failed() {
+				require.ErrorIs(t, err, member.Error)
+			}
+
+			if slices.Contains(c.expectStatuses, http.StatusNotFound) {
+				require.ErrorIs(t, err, types.ErrMemberNotFound)
+			}
+		})
+	}
+}
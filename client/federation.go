@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/internal/rest/client"
+)
+
+// FederationConfig holds the settings shared by the clients of a Federation.
+type FederationConfig struct {
+	// ClientCert is the certificate presented to the deployments added with AddRemote.
+	ClientCert *shared.CertInfo
+
+	// Proxy is used by the clients to reach deployments over HTTPS. Clients to a control socket connect directly.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// Interceptors wrap the requests sent by every client of the federation.
+	Interceptors []Interceptor
+}
+
+// Federation holds clients to several independent microcluster deployments, such as different products embedding
+// microcluster side by side on the same hosts, so that they can be managed with the same settings and queried together.
+// Each deployment is identified by a name, such as the name of the product.
+type Federation struct {
+	config FederationConfig
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+	order   []string
+}
+
+// NewFederation returns an empty federation whose clients use the given settings.
+func NewFederation(config FederationConfig) *Federation {
+	return &Federation{
+		config:  config,
+		clients: map[string]*Client{},
+		order:   []string{},
+	}
+}
+
+// Add adds a client to the deployment with the given name. The federation keeps its own copy of the client, with the
+// shared proxy and interceptors applied, so the given client is left unchanged.
+func (f *Federation) Add(name string, c *Client) error {
+	if name == "" {
+		return fmt.Errorf("Deployment name cannot be empty")
+	}
+
+	if c == nil {
+		return fmt.Errorf("Client for deployment %q cannot be nil", name)
+	}
+
+	federated, err := f.apply(*c)
+	if err != nil {
+		return fmt.Errorf("Failed to configure client for deployment %q: %w", name, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.clients[name]
+	if ok {
+		return fmt.Errorf("Deployment %q is already part of the federation", name)
+	}
+
+	f.clients[name] = federated
+	f.order = append(f.order, name)
+
+	return nil
+}
+
+// AddLocal adds a client to the control socket at the given path, for a deployment running on the local host.
+func (f *Federation) AddLocal(name string, socketPath string) error {
+	c, err := client.New(*api.NewURL().Scheme("http").Host(socketPath), nil, nil, false)
+	if err != nil {
+		return fmt.Errorf("Failed to create client for deployment %q: %w", name, err)
+	}
+
+	return f.Add(name, &Client{Client: *c})
+}
+
+// AddRemote adds a client to the cluster member at the given address, for a deployment whose cluster certificate is
+// remoteCert. The shared client certificate is presented to the deployment, which must trust it.
+func (f *Federation) AddRemote(name string, address string, remoteCert *x509.Certificate) error {
+	if f.config.ClientCert == nil {
+		return fmt.Errorf("A client certificate is required to add remote deployment %q", name)
+	}
+
+	c, err := client.New(*api.NewURL().Scheme("https").Host(address), f.config.ClientCert, remoteCert, false)
+	if err != nil {
+		return fmt.Errorf("Failed to create client for deployment %q: %w", name, err)
+	}
+
+	return f.Add(name, &Client{Client: *c})
+}
+
+// apply returns a copy of the client with the shared settings of the federation.
+func (f *Federation) apply(c Client) (*Client, error) {
+	c.AddInterceptors(f.config.Interceptors...)
+
+	if f.config.Proxy == nil || c.URL().URL.Scheme != "https" {
+		return &c, nil
+	}
+
+	tx, ok := c.Client.Client.Transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("Invalid underlying client transport, expected %T, got %T", &http.Transport{}, c.Client.Client.Transport)
+	}
+
+	// The transport and HTTP client may be shared with other clients, so the proxy is set on copies of them.
+	tx = tx.Clone()
+	tx.Proxy = f.config.Proxy
+
+	httpClient := *c.Client.Client
+	httpClient.Transport = tx
+	c.Client.Client = &httpClient
+
+	return &c, nil
+}
+
+// Remove removes the deployment with the given name from the federation.
+func (f *Federation) Remove(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.clients[name]
+	if !ok {
+		return
+	}
+
+	delete(f.clients, name)
+	for i, deployment := range f.order {
+		if deployment == name {
+			f.order = append(f.order[:i:i], f.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Names returns the names of the deployments of the federation, in the order they were added.
+func (f *Federation) Names() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return append([]string{}, f.order...)
+}
+
+// Client returns the client to the deployment with the given name.
+func (f *Federation) Client(name string) (*Client, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	c, ok := f.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("Deployment %q is not part of the federation", name)
+	}
+
+	return c, nil
+}
+
+// DeploymentResult is the outcome of a query to a single deployment of a federation.
+type DeploymentResult struct {
+	// Name is the name of the deployment.
+	Name string
+
+	// Error is the error returned by the query to the deployment, or nil if it succeeded.
+	Error error
+}
+
+// FederationResult holds the outcome of a query to each deployment, in the order the deployments were added.
+type FederationResult []DeploymentResult
+
+// Failed returns the results of the deployments the query failed on.
+func (r FederationResult) Failed() []DeploymentResult {
+	failed := []DeploymentResult{}
+	for _, result := range r {
+		if result.Error != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+// Err returns the errors of every deployment the query failed on, or nil if it succeeded on all of them.
+func (r FederationResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, len(failed))
+	for _, result := range failed {
+		errs = append(errs, fmt.Errorf("Deployment %q: %w", result.Name, result.Error))
+	}
+
+	return fmt.Errorf("Query failed on %d of %d deployments: %w", len(failed), len(r), errors.Join(errs...))
+}
+
+// FanOut executes the given hook on all deployments of the federation in parallel, and reports the outcome for each of
+// them. A failure on one deployment does not prevent reporting the others. If timeout is set, the hook is cancelled on
+// deployments that have not responded within it.
+func (f *Federation) FanOut(ctx context.Context, timeout time.Duration, query func(ctx context.Context, name string, c *Client) error) FederationResult {
+	f.mu.RLock()
+	names := append([]string{}, f.order...)
+	clients := make([]*Client, 0, len(names))
+	for _, name := range names {
+		clients = append(clients, f.clients[name])
+	}
+
+	f.mu.RUnlock()
+
+	results := make(FederationResult, len(names))
+	wg := sync.WaitGroup{}
+	for i, name := range names {
+		results[i].Name = name
+
+		wg.Add(1)
+		go func(i int, name string, c Client) {
+			defer wg.Done()
+
+			queryCtx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				queryCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			results[i].Error = query(queryCtx, name, &c)
+		}(i, name, *clients[i])
+	}
+
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures deployments are added and removed by name, and that their clients use the shared settings of the federation
+// without changing the clients they were added with.
+func TestFederation(t *testing.T) {
+	var mu sync.Mutex
+	intercepted := 0
+	proxied := 0
+
+	federation := NewFederation(FederationConfig{
+		Proxy: func(r *http.Request) (*url.URL, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			proxied++
+
+			return nil, nil
+		},
+		Interceptors: []Interceptor{func(r *http.Request, next func(r *http.Request) (*http.Response, error)) (*http.Response, error) {
+			mu.Lock()
+			intercepted++
+			mu.Unlock()
+
+			return next(r)
+		}},
+	})
+
+	cluster := newTestCluster(t, "10.0.0.1:9000", "10.0.0.2:9000")
+	first, err := cluster.connect("10.0.0.1:9000")
+	require.NoError(t, err)
+
+	require.Error(t, federation.Add("", first))
+	require.Error(t, federation.Add("microceph", nil))
+	require.NoError(t, federation.Add("microceph", first))
+	require.Error(t, federation.Add("microceph", first))
+	require.NoError(t, federation.AddLocal("microovn", cluster.members["10.0.0.2:9000"].socket))
+
+	// Remote deployments require a client certificate to be set.
+	require.Error(t, federation.AddRemote("lxd", "10.0.0.3:8443", nil))
+	require.Equal(t, []string{"microceph", "microovn"}, federation.Names())
+
+	query := func(c *Client) error {
+		return c.Query(context.Background(), "GET", types.EndpointPrefix("1.0"), api.NewURL().Path("query"), nil, nil)
+	}
+
+	// The client the deployment was added with is left without the interceptors of the federation.
+	require.NoError(t, query(first))
+	require.Zero(t, intercepted)
+
+	for _, name := range federation.Names() {
+		c, err := federation.Client(name)
+		require.NoError(t, err)
+		require.NoError(t, query(c))
+	}
+
+	require.Equal(t, 2, intercepted)
+
+	// Control socket clients connect directly, without the proxy.
+	require.Zero(t, proxied)
+
+	federation.Remove("microceph")
+	federation.Remove("microceph")
+	require.Equal(t, []string{"microovn"}, federation.Names())
+	_, err = federation.Client("microceph")
+	require.Error(t, err)
+}
+
+// Ensures clients to remote deployments present the shared client certificate, and reach them through the proxy.
+func TestFederationRemote(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || shared.CertFingerprint(r.TLS.PeerCertificates[0]) != shared.TestingAltKeyPair().Fingerprint() {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"type": "error", "error_code": 403, "error": "not authorized"}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200, "metadata": {}}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	proxied := false
+	federation := NewFederation(FederationConfig{
+		ClientCert: shared.TestingAltKeyPair(),
+		Proxy: func(r *http.Request) (*url.URL, error) {
+			proxied = true
+
+			return nil, nil
+		},
+	})
+
+	require.NoError(t, federation.AddRemote("microcloud", server.Listener.Addr().String(), server.Certificate()))
+
+	c, err := federation.Client("microcloud")
+	require.NoError(t, err)
+	require.NoError(t, c.Query(context.Background(), "GET", types.EndpointPrefix("1.0"), nil, nil, nil))
+	require.True(t, proxied)
+}
+
+// Ensures a query fanned out to the deployments of a federation reports the outcome of each of them in the order they
+// were added, including those that failed or did not respond in time.
+func TestFederationFanOut(t *testing.T) {
+	cluster := newTestCluster(t, "10.0.0.1:9000", "10.0.0.2:9000", "10.0.0.3:9000")
+	cluster.members["10.0.0.2:9000"].set(types.MemberOnline, false, failure(http.StatusNotFound, types.ErrMemberNotFound))
+	cluster.members["10.0.0.3:9000"].set(types.MemberOnline, false, func(w http.ResponseWriter) {
+		time.Sleep(500 * time.Millisecond)
+	})
+
+	federation := NewFederation(FederationConfig{})
+	names := []string{"microceph", "microovn", "microcloud"}
+	for i, address := range []string{"10.0.0.1:9000", "10.0.0.2:9000", "10.0.0.3:9000"} {
+		require.NoError(t, federation.AddLocal(names[i], cluster.members[address].socket))
+	}
+
+	queried := map[string]bool{}
+	var mu sync.Mutex
+	result := federation.FanOut(context.Background(), 100*time.Millisecond, func(ctx context.Context, name string, c *Client) error {
+		mu.Lock()
+		queried[name] = true
+		mu.Unlock()
+
+		return c.Query(ctx, "POST", types.EndpointPrefix("1.0"), api.NewURL().Path("query"), nil, nil)
+	})
+
+	require.Len(t, queried, len(names))
+	require.Len(t, result, len(names))
+	for i, deployment := range result {
+		require.Equal(t, names[i], deployment.Name)
+	}
+
+	require.NoError(t, result[0].Error)
+	require.ErrorIs(t, result[1].Error, types.ErrMemberNotFound)
+	require.ErrorIs(t, result[2].Error, context.DeadlineExceeded)
+
+	require.Len(t, result.Failed(), 2)
+	err := result.Err()
+	require.ErrorIs(t, err, types.ErrMemberNotFound)
+	require.ErrorContains(t, err, "Query failed on 2 of 3 deployments")
+	require.ErrorContains(t, err, `Deployment "microovn"`)
+	require.ErrorContains(t, err, `Deployment "microcloud"`)
+
+	require.NoError(t, FederationResult{{Name: "microceph"}}.Err())
+}
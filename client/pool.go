@@ -0,0 +1,299 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// ClusterPool holds clients to every member of a cluster, so that tooling doesn't depend on the address of a single
+// member. Reads are routed to the nearest healthy member, and writes to the dqlite leader.
+// The cluster members, their health and the leader are periodically re-resolved from GET /core/1.0/cluster.
+type ClusterPool struct {
+	connect func(address string) (*Client, error)
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+	order   []string
+	leader  string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewClusterPool connects to the cluster through any of the given member addresses, and resolves the other members
+// from it. The pool is refreshed at the given interval until ctx is cancelled or Close is called. A zero interval
+// disables periodic refreshes.
+// connect returns a client to the cluster member at the given address, such as MicroCluster.RemoteClient.
+func NewClusterPool(ctx context.Context, addresses []string, connect func(address string) (*Client, error), interval time.Duration) (*ClusterPool, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("At least one cluster member address is required")
+	}
+
+	p := &ClusterPool{
+		connect: connect,
+		clients: make(map[string]*Client, len(addresses)),
+		order:   make([]string, 0, len(addresses)),
+	}
+
+	for _, address := range addresses {
+		c, err := connect(address)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create client for %q: %w", address, err)
+		}
+
+		p.clients[address] = c
+		p.order = append(p.order, address)
+	}
+
+	err := p.Refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	if interval > 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+
+				err := p.Refresh(ctx)
+				if err != nil {
+					logger.Warn("Failed to refresh cluster members", logger.Ctx{"error": err})
+				}
+			}
+		}()
+	}
+
+	return p, nil
+}
+
+// Close stops refreshing the pool.
+func (p *ClusterPool) Close() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Refresh re-resolves the cluster members, their health, and the dqlite leader. Healthy members are ordered by the
+// time they take to answer, so that reads go to the nearest one.
+func (p *ClusterPool) Refresh(ctx context.Context) error {
+	p.mu.RLock()
+	order := p.order
+	clients := make(map[string]*Client, len(p.clients))
+	for address, c := range p.clients {
+		clients[address] = c
+	}
+
+	p.mu.RUnlock()
+
+	var members []types.ClusterMember
+	var source *Client
+	var err error
+	for _, address := range order {
+		members, err = clients[address].GetClusterMembers(ctx)
+		if err == nil {
+			source = clients[address]
+			break
+		}
+	}
+
+	if source == nil {
+		return fmt.Errorf("Failed to get cluster members from any known member: %w", err)
+	}
+
+	// Keep the known members rather than leaving the pool without any.
+	if len(members) == 0 {
+		return fmt.Errorf("Found no cluster members")
+	}
+
+	newClients := make(map[string]*Client, len(members))
+	healthy := make([]string, 0, len(members))
+	unhealthy := make([]string, 0, len(members))
+	for _, member := range members {
+		address := member.Address.String()
+		c, ok := clients[address]
+		if !ok {
+			c, err = p.connect(address)
+			if err != nil {
+				return fmt.Errorf("Failed to create client for cluster member %q: %w", member.Name, err)
+			}
+		}
+
+		newClients[address] = c
+		if member.Status == types.MemberOnline {
+			healthy = append(healthy, address)
+		} else {
+			unhealthy = append(unhealthy, address)
+		}
+	}
+
+	// Measure how long each healthy member takes to answer, and consider the ones that don't as unhealthy.
+	latencies := make(map[string]time.Duration, len(healthy))
+	latencyMu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, address := range healthy {
+		wg.Add(1)
+		go func(address string) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := newClients[address].CheckReady(ctx)
+			if err != nil {
+				logger.Debug("Cluster member did not answer", logger.Ctx{"address": address, "error": err})
+				return
+			}
+
+			latencyMu.Lock()
+			latencies[address] = time.Since(start)
+			latencyMu.Unlock()
+		}(address)
+	}
+
+	wg.Wait()
+
+	newOrder := make([]string, 0, len(members))
+	for _, address := range healthy {
+		_, ok := latencies[address]
+		if ok {
+			newOrder = append(newOrder, address)
+		} else {
+			unhealthy = append(unhealthy, address)
+		}
+	}
+
+	sort.SliceStable(newOrder, func(i int, j int) bool {
+		return latencies[newOrder[i]] < latencies[newOrder[j]]
+	})
+
+	newOrder = append(newOrder, unhealthy...)
+
+	var leader string
+	databaseMembers, err := source.GetDatabaseMembers(ctx)
+	if err != nil {
+		logger.Debug("Failed to get dqlite leader", logger.Ctx{"error": err})
+	}
+
+	for _, member := range databaseMembers {
+		if member.Leader {
+			leader = member.Address
+		}
+	}
+
+	p.mu.Lock()
+	p.clients = newClients
+	p.order = newOrder
+	p.leader = leader
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Read returns a client to the nearest healthy cluster member.
+func (p *ClusterPool) Read() (*Client, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.order) == 0 {
+		return nil, fmt.Errorf("No cluster members available")
+	}
+
+	return p.clients[p.order[0]], nil
+}
+
+// Write returns a client to the dqlite leader, or to the nearest healthy cluster member if the leader is unknown.
+func (p *ClusterPool) Write() (*Client, error) {
+	p.mu.RLock()
+	c, ok := p.clients[p.leader]
+	p.mu.RUnlock()
+
+	if ok {
+		return c, nil
+	}
+
+	return p.Read()
+}
+
+// Query runs the query against the dqlite leader if write is true, or against the nearest healthy cluster member
+// otherwise. If the member can't be reached or is unavailable, the query is retried against the other members, in
+// order of preference. Writes are only retried if they could not have been applied, because the member could not be
+// connected to or is not the dqlite leader.
+func (p *ClusterPool) Query(ctx context.Context, write bool, query func(ctx context.Context, c *Client) error) error {
+	p.mu.RLock()
+	clients := make([]*Client, 0, len(p.order))
+	leader, ok := p.clients[p.leader]
+	if write && ok {
+		clients = append(clients, leader)
+	}
+
+	for _, address := range p.order {
+		if !write || address != p.leader {
+			clients = append(clients, p.clients[address])
+		}
+	}
+
+	p.mu.RUnlock()
+
+	if len(clients) == 0 {
+		return fmt.Errorf("No cluster members available")
+	}
+
+	var err error
+	for _, c := range clients {
+		err = query(ctx, c)
+		if err == nil || ctx.Err() != nil || !isQueryRetryable(err, write) {
+			return err
+		}
+
+		logger.Debug("Retrying query against another cluster member", logger.Ctx{"address": c.URL().URL.Host, "error": err})
+	}
+
+	return err
+}
+
+// isQueryRetryable returns whether a query that failed with the given error can be run against another cluster member.
+func isQueryRetryable(err error, write bool) bool {
+	// The query never reached the member.
+	var opErr *net.OpError
+	if (errors.As(err, &opErr) && opErr.Op == "dial") || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	// The member refused the query because it is not the dqlite leader.
+	if errors.Is(err, types.ErrNotLeader) {
+		return true
+	}
+
+	// Any other failure may happen after the write was applied.
+	if write {
+		return false
+	}
+
+	// Errors returned by the member itself are final, unless it is unavailable.
+	status, ok := api.StatusErrorMatch(err)
+	if ok {
+		return status == http.StatusServiceUnavailable
+	}
+
+	return true
+}
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/internal/extensions"
+	"github.com/canonical/microcluster/v3/internal/rest/client"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// testMember is a cluster member served over a unix socket.
+type testMember struct {
+	address string
+	socket  string
+	server  *httptest.Server
+
+	mu      sync.Mutex
+	status  types.MemberStatus
+	leader  bool
+	queries int
+	respond func(w http.ResponseWriter)
+}
+
+// testCluster holds the members of a cluster, and the pool clients connected to each of them.
+type testCluster struct {
+	members map[string]*testMember
+	order   []string
+	clients map[*Client]string
+}
+
+// newTestCluster serves members at the given addresses, which all report the same members.
+func newTestCluster(t *testing.T, addresses ...string) *testCluster {
+	cluster := &testCluster{members: map[string]*testMember{}, order: addresses, clients: map[*Client]string{}}
+	cert := shared.TestingKeyPair()
+	leaf, err := cert.PublicKeyX509()
+	require.NoError(t, err)
+
+	for _, address := range addresses {
+		m := &testMember{address: address, status: types.MemberOnline, socket: filepath.Join(t.TempDir(), "control.socket")}
+		cluster.members[address] = m
+
+		listener, err := net.Listen("unix", m.socket)
+		require.NoError(t, err)
+
+		m.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var metadata any
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/core/1.0/cluster"):
+				members := []types.ClusterMember{}
+				for _, address := range cluster.order {
+					addrPort, err := types.ParseAddrPort(address)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+
+					member := cluster.members[address]
+					member.mu.Lock()
+					members = append(members, types.ClusterMember{
+						ClusterMemberLocal: types.ClusterMemberLocal{Name: address, Address: addrPort, Certificate: types.X509Certificate{Certificate: leaf}},
+						Status:             member.status,
+						Extensions:         extensions.Extensions{},
+					})
+					member.mu.Unlock()
+				}
+
+				metadata = members
+			case strings.HasSuffix(r.URL.Path, "/core/1.0/database"):
+				members := []types.DatabaseMember{}
+				for _, address := range cluster.order {
+					member := cluster.members[address]
+					member.mu.Lock()
+					members = append(members, types.DatabaseMember{Address: address, Leader: member.leader})
+					member.mu.Unlock()
+				}
+
+				metadata = members
+			case strings.HasSuffix(r.URL.Path, "/core/1.0/ready"):
+			default:
+				m.mu.Lock()
+				m.queries++
+				respond := m.respond
+				m.mu.Unlock()
+
+				if respond != nil {
+					respond(w)
+					return
+				}
+			}
+
+			resp := api.ResponseRaw{Type: api.SyncResponse, Status: api.Success.String(), StatusCode: int(api.Success), Metadata: metadata}
+			_ = json.NewEncoder(w).Encode(resp)
+		}))
+
+		m.server.Listener = listener
+		m.server.Start()
+		t.Cleanup(m.server.Close)
+	}
+
+	return cluster
+}
+
+// connect returns a client to the member at the given address, as MicroCluster.RemoteClient does.
+func (c *testCluster) connect(address string) (*Client, error) {
+	member, ok := c.members[address]
+	if !ok {
+		return nil, fmt.Errorf("Unknown cluster member %q", address)
+	}
+
+	internalClient, err := client.New(*api.NewURL().Scheme("http").Host(member.socket), nil, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := &Client{Client: *internalClient}
+	c.clients[pool] = address
+
+	return pool, nil
+}
+
+// stop makes the member unreachable.
+func (m *testMember) stop(t *testing.T) {
+	m.server.Close()
+	require.NoError(t, os.RemoveAll(m.socket))
+}
+
+// set changes the status and leadership the member is reported with, and how it answers queries.
+func (m *testMember) set(status types.MemberStatus, leader bool, respond func(w http.ResponseWriter)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status = status
+	m.leader = leader
+	m.respond = respond
+	m.queries = 0
+}
+
+// count returns the number of queries answered by the member.
+func (m *testMember) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.queries
+}
+
+// failure returns a response that fails the query with the given status code and error.
+func failure(status int, err error) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		code := types.ErrorCode(err)
+		if code != "" {
+			w.Header().Set(types.ErrorCodeHeader, code)
+		}
+
+		w.WriteHeader(status)
+		_, _ = fmt.Fprintf(w, `{"type": "error", "error_code": %d, "error": %q}`, status, err.Error())
+	}
+}
+
+// Ensures the pool resolves the cluster members from any of the given addresses, routes reads to healthy members and
+// writes to the dqlite leader, and keeps the known members if none are found.
+func TestClusterPool(t *testing.T) {
+	cluster := newTestCluster(t, "10.0.0.1:9000", "10.0.0.2:9000", "10.0.0.3:9000")
+	cluster.members["10.0.0.1:9000"].set(types.MemberUnreachable, false, nil)
+	cluster.members["10.0.0.3:9000"].set(types.MemberOnline, true, nil)
+
+	pool, err := NewClusterPool(context.Background(), []string{"10.0.0.1:9000"}, cluster.connect, 0)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	read, err := pool.Read()
+	require.NoError(t, err)
+	require.NotEqual(t, "10.0.0.1:9000", cluster.clients[read])
+
+	write, err := pool.Write()
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.3:9000", cluster.clients[write])
+
+	// Without a known leader, writes go to the nearest healthy member.
+	cluster.members["10.0.0.3:9000"].set(types.MemberOnline, false, nil)
+	require.NoError(t, pool.Refresh(context.Background()))
+	write, err = pool.Write()
+	require.NoError(t, err)
+	require.NotEqual(t, "10.0.0.1:9000", cluster.clients[write])
+
+	// A cluster without members leaves the pool unchanged.
+	cluster.order = nil
+	require.Error(t, pool.Refresh(context.Background()))
+	read, err = pool.Read()
+	require.NoError(t, err)
+	require.NotNil(t, read)
+
+	// A pool without any members returns an error rather than a client.
+	empty := &ClusterPool{}
+	_, err = empty.Read()
+	require.Error(t, err)
+	_, err = empty.Write()
+	require.Error(t, err)
+	require.Error(t, empty.Query(context.Background(), false, func(ctx context.Context, c *Client) error { return nil }))
+
+	_, err = NewClusterPool(context.Background(), nil, cluster.connect, 0)
+	require.Error(t, err)
+}
+
+// Ensures queries are only run against other cluster members if they failed in a way that is safe to retry.
+func TestClusterPoolQuery(t *testing.T) {
+	// The member accepts the query, but the connection is lost before it answers.
+	lost := func(w http.ResponseWriter) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}
+
+	cases := []struct {
+		name      string
+		write     bool
+		respond   func(w http.ResponseWriter)
+		stopped   bool
+		expectErr bool
+		retried   bool
+	}{
+		{name: "Read succeeds", respond: nil},
+		{name: "Write succeeds", write: true, respond: nil},
+		{name: "Read from unreachable member", stopped: true, retried: true},
+		{name: "Write to unreachable member", write: true, stopped: true, retried: true},
+		{name: "Write to member that lost leadership", write: true, respond: failure(http.StatusServiceUnavailable, types.ErrNotLeader), retried: true},
+		{name: "Read from unavailable member", respond: failure(http.StatusServiceUnavailable, fmt.Errorf("Daemon is shutting down")), retried: true},
+		{name: "Write to unavailable member", write: true, respond: failure(http.StatusServiceUnavailable, fmt.Errorf("Daemon is shutting down")), expectErr: true},
+		{name: "Read with lost connection", respond: lost, retried: true},
+		{name: "Write with lost connection", write: true, respond: lost, expectErr: true},
+		{name: "Read rejected by member", respond: failure(http.StatusBadRequest, fmt.Errorf("Invalid request")), expectErr: true},
+		{name: "Write rejected by member", write: true, respond: failure(http.StatusBadRequest, fmt.Errorf("Invalid request")), expectErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := newTestCluster(t, "10.0.0.1:9000", "10.0.0.2:9000")
+			first := cluster.members["10.0.0.1:9000"]
+			second := cluster.members["10.0.0.2:9000"]
+			first.set(types.MemberOnline, true, nil)
+
+			pool, err := NewClusterPool(context.Background(), []string{"10.0.0.2:9000"}, cluster.connect, 0)
+			require.NoError(t, err)
+			defer pool.Close()
+
+			// Make sure the first member is queried first, as the leader and as the nearest member.
+			pool.mu.Lock()
+			pool.order = []string{"10.0.0.1:9000", "10.0.0.2:9000"}
+			pool.mu.Unlock()
+
+			first.set(types.MemberOnline, true, c.respond)
+			second.set(types.MemberOnline, false, nil)
+			if c.stopped {
+				first.stop(t)
+			}
+
+			err = pool.Query(context.Background(), c.write, func(ctx context.Context, c *Client) error {
+				return c.Query(ctx, "POST", types.EndpointPrefix("1.0"), api.NewURL().Path("query"), nil, nil)
+			})
+
+			if c.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if !c.stopped {
+				require.Equal(t, 1, first.count())
+			}
+
+			if c.retried {
+				require.Equal(t, 1, second.count())
+			} else {
+				require.Zero(t, second.count())
+			}
+		})
+	}
+}
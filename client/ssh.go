@@ -0,0 +1,34 @@
+package client
+
+import (
+	"golang.org/x/crypto/ssh"
+
+	"github.com/canonical/microcluster/v3/internal/rest/client"
+)
+
+// NewSSH returns a client to the control socket of a remote daemon, tunneled over SSH, so that the daemon can be
+// managed without exposing its HTTPS endpoint or copying its certificates. The host is given as [user@]host[:port],
+// and socketPath is the path of the control socket on the remote host.
+// The user defaults to the current user and the port to 22. The keys of the running SSH agent are used for
+// authentication, and the host key is verified against the user's ~/.ssh/known_hosts file.
+//
+// The client can be set as the Client of microcluster.Args to run the MicroCluster methods against the remote daemon.
+func NewSSH(host string, socketPath string) (*Client, error) {
+	address, config, err := client.DefaultSSHConfig(host)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSSHWithConfig(address, socketPath, config)
+}
+
+// NewSSHWithConfig is like NewSSH, but connects to the address, given as host:port, with the given SSH client
+// configuration.
+func NewSSHWithConfig(address string, socketPath string, config *ssh.ClientConfig) (*Client, error) {
+	c, err := client.NewSSH(address, socketPath, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{Client: *c}, nil
+}
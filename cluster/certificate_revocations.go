@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// CoreCertificateRevocation is the database representation of a revoked certificate.
+type CoreCertificateRevocation struct {
+	ID          int
+	Fingerprint string
+	Reason      string
+	RevokedAt   time.Time
+}
+
+// ToAPI returns the API representation of the revoked certificate.
+func (c CoreCertificateRevocation) ToAPI() types.CertificateRevocation {
+	return types.CertificateRevocation{
+		Fingerprint: c.Fingerprint,
+		Reason:      c.Reason,
+		RevokedAt:   c.RevokedAt,
+	}
+}
+
+// GetCoreCertificateRevocations returns all revoked certificates, ordered by fingerprint.
+func GetCoreCertificateRevocations(ctx context.Context, tx *sql.Tx) ([]CoreCertificateRevocation, error) {
+	revocations := []CoreCertificateRevocation{}
+	dest := func(scan func(dest ...any) error) error {
+		c := CoreCertificateRevocation{}
+		err := scan(&c.ID, &c.Fingerprint, &c.Reason, &c.RevokedAt)
+		if err != nil {
+			return err
+		}
+
+		revocations = append(revocations, c)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT id, fingerprint, reason, revoked_at FROM core_certificate_revocations ORDER BY fingerprint", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"core_certificate_revocations\" table: %w", err)
+	}
+
+	return revocations, nil
+}
+
+// CreateCoreCertificateRevocation adds a revoked certificate to the database.
+func CreateCoreCertificateRevocation(ctx context.Context, tx *sql.Tx, object CoreCertificateRevocation) (int64, error) {
+	existing, err := GetCoreCertificateRevocations(ctx, tx)
+	if err != nil {
+		return -1, err
+	}
+
+	for _, c := range existing {
+		if c.Fingerprint == object.Fingerprint {
+			return -1, api.StatusErrorf(http.StatusConflict, "Certificate %q is already revoked", object.Fingerprint)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO core_certificate_revocations (fingerprint, reason, revoked_at) VALUES (?, ?, ?)", object.Fingerprint, object.Reason, object.RevokedAt)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create \"core_certificate_revocations\" entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to fetch \"core_certificate_revocations\" entry ID: %w", err)
+	}
+
+	return id, nil
+}
+
+// DeleteCoreCertificateRevocation removes the revocation of the certificate with the given fingerprint.
+func DeleteCoreCertificateRevocation(ctx context.Context, tx *sql.Tx, fingerprint string) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM core_certificate_revocations WHERE fingerprint = ?", fingerprint)
+	if err != nil {
+		return fmt.Errorf("Delete \"core_certificate_revocations\": %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "CoreCertificateRevocation not found")
+	}
+
+	return nil
+}
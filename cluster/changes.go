@@ -0,0 +1,128 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// changeTrackedTableName matches the names of tables whose changes can be tracked.
+var changeTrackedTableName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// CoreChange is the database representation of a row change in a table tracked with TrackChanges.
+type CoreChange struct {
+	ID        int64
+	TableName string
+	Operation string
+	RowID     int64
+	ChangedAt int64
+}
+
+// ToAPI returns the API representation of the change.
+func (c CoreChange) ToAPI() types.TableChange {
+	return types.TableChange{
+		ID:        c.ID,
+		Table:     c.TableName,
+		Operation: types.TableOperation(c.Operation),
+		RowID:     c.RowID,
+		ChangedAt: time.Unix(c.ChangedAt, 0),
+	}
+}
+
+// TrackChanges records every insert, update and delete of a row of the given table in the core_changes table, so
+// that the OnTableChange hook is run for it on every cluster member. It is meant to be called from a schema update,
+// and must be called again if the table is re-created.
+func TrackChanges(ctx context.Context, tx *sql.Tx, table string) error {
+	if !changeTrackedTableName.MatchString(table) {
+		return fmt.Errorf("Invalid table name %q", table)
+	}
+
+	for _, trigger := range []struct {
+		operation types.TableOperation
+		row       string
+	}{
+		{operation: types.TableInsert, row: "NEW"},
+		{operation: types.TableUpdate, row: "NEW"},
+		{operation: types.TableDelete, row: "OLD"},
+	} {
+		stmt := fmt.Sprintf(`
+CREATE TRIGGER IF NOT EXISTS core_changes_%[1]s_%[2]s AFTER %[2]s ON %[1]s BEGIN
+  INSERT INTO core_changes (table_name, operation, row_id, changed_at) VALUES ('%[1]s', '%[2]s', %[3]s.rowid, CAST(strftime('%%s', 'now') AS INTEGER));
+END;
+`, table, trigger.operation, trigger.row)
+
+		_, err := tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("Failed to track changes of table %q: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// UntrackChanges stops recording the changes of the given table. It is meant to be called from the down migration of
+// a schema update that called TrackChanges.
+func UntrackChanges(ctx context.Context, tx *sql.Tx, table string) error {
+	if !changeTrackedTableName.MatchString(table) {
+		return fmt.Errorf("Invalid table name %q", table)
+	}
+
+	for _, operation := range []types.TableOperation{types.TableInsert, types.TableUpdate, types.TableDelete} {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("DROP TRIGGER IF EXISTS core_changes_%s_%s", table, operation))
+		if err != nil {
+			return fmt.Errorf("Failed to stop tracking changes of table %q: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// GetCoreChanges returns up to limit changes recorded after the change with the given ID, in the order they were made.
+func GetCoreChanges(ctx context.Context, tx *sql.Tx, afterID int64, limit int) ([]CoreChange, error) {
+	changes := []CoreChange{}
+	dest := func(scan func(dest ...any) error) error {
+		c := CoreChange{}
+		err := scan(&c.ID, &c.TableName, &c.Operation, &c.RowID, &c.ChangedAt)
+		if err != nil {
+			return err
+		}
+
+		changes = append(changes, c)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT id, table_name, operation, row_id, changed_at FROM core_changes WHERE id > ? ORDER BY id LIMIT ?", dest, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"core_changes\" table: %w", err)
+	}
+
+	return changes, nil
+}
+
+// GetCoreChangesLastID returns the ID of the most recent change, or 0 if there are none.
+func GetCoreChangesLastID(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var lastID int64
+	err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM core_changes").Scan(&lastID)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to fetch from \"core_changes\" table: %w", err)
+	}
+
+	return lastID, nil
+}
+
+// DeleteCoreChangesBefore removes the changes made before the given time.
+func DeleteCoreChangesBefore(ctx context.Context, tx *sql.Tx, before time.Time) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM core_changes WHERE changed_at < ?", before.Unix())
+	if err != nil {
+		return fmt.Errorf("Delete \"core_changes\": %w", err)
+	}
+
+	return nil
+}
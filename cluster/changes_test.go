@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/internal/db/dbtest"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// newChangesTx returns a transaction on an in-memory database with the internal schema, and a services table whose
+// changes can be tracked.
+func newChangesTx(t *testing.T) *sql.Tx {
+	tx := dbtest.NewTx(t)
+	_, err := tx.Exec("CREATE TABLE services (id INTEGER PRIMARY KEY NOT NULL, name TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	return tx
+}
+
+// Ensures inserts, updates and deletes of tracked tables are recorded in order, and no longer once untracked.
+func TestTrackChanges(t *testing.T) {
+	ctx := context.Background()
+	tx := newChangesTx(t)
+
+	require.Error(t, TrackChanges(ctx, tx, "services; DROP TABLE core_changes"))
+	require.Error(t, UntrackChanges(ctx, tx, "1services"))
+
+	// Tracking a table again leaves its triggers as they are.
+	require.NoError(t, TrackChanges(ctx, tx, "services"))
+	require.NoError(t, TrackChanges(ctx, tx, "services"))
+
+	lastID, err := GetCoreChangesLastID(ctx, tx)
+	require.NoError(t, err)
+	require.Zero(t, lastID)
+
+	_, err = tx.Exec("INSERT INTO services (id, name) VALUES (1, 'a'), (2, 'b')")
+	require.NoError(t, err)
+	_, err = tx.Exec("UPDATE services SET name = 'c' WHERE id = 2")
+	require.NoError(t, err)
+	_, err = tx.Exec("DELETE FROM services WHERE id = 1")
+	require.NoError(t, err)
+
+	changes, err := GetCoreChanges(ctx, tx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+
+	expected := []struct {
+		operation types.TableOperation
+		rowID     int64
+	}{
+		{operation: types.TableInsert, rowID: 1},
+		{operation: types.TableInsert, rowID: 2},
+		{operation: types.TableUpdate, rowID: 2},
+		{operation: types.TableDelete, rowID: 1},
+	}
+
+	for i, change := range changes {
+		require.Equal(t, "services", change.TableName)
+		require.Equal(t, string(expected[i].operation), change.Operation)
+		require.Equal(t, expected[i].rowID, change.RowID)
+
+		if i > 0 {
+			require.Greater(t, change.ID, changes[i-1].ID)
+		}
+	}
+
+	// Changes are returned in pages, after the last change that was seen.
+	page, err := GetCoreChanges(ctx, tx, changes[0].ID, 2)
+	require.NoError(t, err)
+	require.Equal(t, changes[1:3], page)
+
+	lastID, err = GetCoreChangesLastID(ctx, tx)
+	require.NoError(t, err)
+	require.Equal(t, changes[3].ID, lastID)
+
+	// Untracked tables no longer record their changes.
+	require.NoError(t, UntrackChanges(ctx, tx, "services"))
+	_, err = tx.Exec("INSERT INTO services (id, name) VALUES (3, 'd')")
+	require.NoError(t, err)
+
+	changes, err = GetCoreChanges(ctx, tx, lastID, 10)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+// Ensures changes are converted to their API representation, and pruned once they are old enough.
+func TestCoreChanges(t *testing.T) {
+	ctx := context.Background()
+	tx := newChangesTx(t)
+
+	now := time.Now()
+	_, err := tx.Exec("INSERT INTO core_changes (table_name, operation, row_id, changed_at) VALUES ('services', 'insert', 1, ?), ('services', 'delete', 1, ?)", now.Add(-time.Hour).Unix(), now.Unix())
+	require.NoError(t, err)
+
+	changes, err := GetCoreChanges(ctx, tx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+
+	change := changes[1].ToAPI()
+	require.Equal(t, changes[1].ID, change.ID)
+	require.Equal(t, "services", change.Table)
+	require.Equal(t, types.TableDelete, change.Operation)
+	require.Equal(t, int64(1), change.RowID)
+	require.Equal(t, now.Unix(), change.ChangedAt.Unix())
+
+	require.NoError(t, DeleteCoreChangesBefore(ctx, tx, now.Add(-time.Minute)))
+	changes, err = GetCoreChanges(ctx, tx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, string(types.TableDelete), changes[0].Operation)
+
+	// Pruning keeps the ID of the last change, so that changes are not delivered twice.
+	lastID, err := GetCoreChangesLastID(ctx, tx)
+	require.NoError(t, err)
+	require.Equal(t, changes[0].ID, lastID)
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+
 	"github.com/canonical/microcluster/v3/internal/db/update"
 	"github.com/canonical/microcluster/v3/internal/extensions"
 	"github.com/canonical/microcluster/v3/rest/types"
@@ -136,3 +140,124 @@ WHERE name IN ('api_extensions');
 
 	return allMembers, awaitingMembers, nil
 }
+
+// GetCoreClusterMembersUnderMaintenance returns the names of the cluster members under maintenance.
+func GetCoreClusterMembersUnderMaintenance(ctx context.Context, tx *sql.Tx) (map[string]bool, error) {
+	names, err := query.SelectStrings(ctx, tx, "SELECT name FROM core_cluster_members WHERE maintenance = 1")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch cluster members under maintenance: %w", err)
+	}
+
+	maintenance := make(map[string]bool, len(names))
+	for _, name := range names {
+		maintenance[name] = true
+	}
+
+	return maintenance, nil
+}
+
+// GetCoreClusterMembersClockSkew returns the clock skew of each cluster member, keyed by name.
+func GetCoreClusterMembersClockSkew(ctx context.Context, tx *sql.Tx) (map[string]time.Duration, error) {
+	skews := map[string]time.Duration{}
+	dest := func(scan func(dest ...any) error) error {
+		var name string
+		var skew int64
+		err := scan(&name, &skew)
+		if err != nil {
+			return err
+		}
+
+		skews[name] = time.Duration(skew)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT name, clock_skew FROM core_cluster_members", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch clock skew of cluster members: %w", err)
+	}
+
+	return skews, nil
+}
+
+// UpdateCoreClusterMemberClockSkew records the clock skew of the cluster member with the given name.
+func UpdateCoreClusterMemberClockSkew(ctx context.Context, tx *sql.Tx, name string, skew time.Duration) error {
+	_, err := tx.ExecContext(ctx, "UPDATE core_cluster_members SET clock_skew = ? WHERE name = ?", int64(skew), name)
+	if err != nil {
+		return fmt.Errorf("Failed to update clock skew of cluster member %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// GetCoreClusterMembersDNSNames returns the DNS name of each cluster member that registered one, keyed by name.
+func GetCoreClusterMembersDNSNames(ctx context.Context, tx *sql.Tx) (map[string]string, error) {
+	dnsNames := map[string]string{}
+	dest := func(scan func(dest ...any) error) error {
+		var name string
+		var dnsName string
+		err := scan(&name, &dnsName)
+		if err != nil {
+			return err
+		}
+
+		dnsNames[name] = dnsName
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT name, dns_name FROM core_cluster_members WHERE dns_name != ''", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch DNS names of cluster members: %w", err)
+	}
+
+	return dnsNames, nil
+}
+
+// UpdateCoreClusterMemberDNSName records the DNS name of the cluster member with the given name.
+func UpdateCoreClusterMemberDNSName(ctx context.Context, tx *sql.Tx, name string, dnsName string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE core_cluster_members SET dns_name = ? WHERE name = ?", dnsName, name)
+	if err != nil {
+		return fmt.Errorf("Failed to update DNS name of cluster member %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// UpdateCoreClusterMemberMaintenance sets whether the cluster member with the given name is under maintenance.
+func UpdateCoreClusterMemberMaintenance(ctx context.Context, tx *sql.Tx, name string, maintenance bool) error {
+	result, err := tx.ExecContext(ctx, "UPDATE core_cluster_members SET maintenance = ? WHERE name = ?", maintenance, name)
+	if err != nil {
+		return fmt.Errorf("Failed to update maintenance of cluster member %q: %w", name, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	if n != 1 {
+		return api.StatusErrorf(http.StatusNotFound, "%w: %q", types.ErrMemberNotFound, name)
+	}
+
+	return nil
+}
+
+// UpdateCoreClusterMemberCertificate replaces the certificate identifying the cluster member with the given name.
+func UpdateCoreClusterMemberCertificate(ctx context.Context, tx *sql.Tx, name string, certificate string) error {
+	result, err := tx.ExecContext(ctx, "UPDATE core_cluster_members SET certificate = ? WHERE name = ?", certificate, name)
+	if err != nil {
+		return fmt.Errorf("Failed to update certificate of cluster member %q: %w", name, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	if n != 1 {
+		return api.StatusErrorf(http.StatusNotFound, "%w: %q", types.ErrMemberNotFound, name)
+	}
+
+	return nil
+}
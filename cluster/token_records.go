@@ -2,11 +2,14 @@ package cluster
 
 import (
 	"context"
+	"crypto"
 	"crypto/x509"
 	"database/sql"
+	"fmt"
+	"net/netip"
+	"strings"
 	"time"
 
-	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/logger"
 
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
@@ -32,11 +35,19 @@ import (
 //go:generate mapper method -e core_token_record DeleteOne-by-Name table=core_token_records
 
 // CoreTokenRecord is the database representation of a join token record.
+// A token with MaxJoins greater than 1 may be used by that many joiners, regardless of their name.
+// AllowedSubnets is a comma-separated list of CIDR subnets, one of which must contain the joiner's address.
+// A Rejoin token lets a reinstalled cluster member with the token's name rejoin with its existing identity.
 type CoreTokenRecord struct {
-	ID         int
-	Secret     string `db:"primary=yes"`
-	Name       string
-	ExpiryDate sql.NullTime
+	ID             int
+	Secret         string `db:"primary=yes"`
+	Name           string
+	ExpiryDate     sql.NullTime
+	MaxJoins       int
+	Joins          int
+	AllowedSubnets string
+	Rejoin         bool
+	IssuedAt       sql.NullTime
 }
 
 // CoreTokenRecordFilter is the filter struct for filtering results from generated methods.
@@ -46,12 +57,37 @@ type CoreTokenRecordFilter struct {
 	Name   *string
 }
 
+// Token returns the join token presented to the joiner, for a cluster of the given project with the given certificate
+// and members. The certificate is fingerprinted with the given hash, which defaults to SHA-256.
+func (t *CoreTokenRecord) Token(clusterCert *x509.Certificate, joinAddresses []types.AddrPort, project string, fingerprintHash crypto.Hash) (*internalTypes.Token, error) {
+	token := &internalTypes.Token{
+		Secret:         t.Secret,
+		JoinAddresses:  joinAddresses,
+		AllowedSubnets: t.Subnets(),
+		Project:        project,
+	}
+
+	err := token.SetFingerprint(clusterCert, fingerprintHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.IssuedAt.Valid {
+		token.IssuedAt = &t.IssuedAt.Time
+	}
+
+	if t.ExpiryDate.Valid {
+		token.ExpiresAt = &t.ExpiryDate.Time
+	}
+
+	return token, nil
+}
+
 // ToAPI converts the CoreTokenRecord to a full token and returns an API compatible struct.
-func (t *CoreTokenRecord) ToAPI(clusterCert *x509.Certificate, joinAddresses []types.AddrPort) (*internalTypes.TokenRecord, error) {
-	token := internalTypes.Token{
-		Secret:        t.Secret,
-		Fingerprint:   shared.CertFingerprint(clusterCert),
-		JoinAddresses: joinAddresses,
+func (t *CoreTokenRecord) ToAPI(clusterCert *x509.Certificate, joinAddresses []types.AddrPort, project string, fingerprintHash crypto.Hash) (*internalTypes.TokenRecord, error) {
+	token, err := t.Token(clusterCert, joinAddresses, project, fingerprintHash)
+	if err != nil {
+		return nil, err
 	}
 
 	tokenString, err := token.String()
@@ -60,12 +96,52 @@ func (t *CoreTokenRecord) ToAPI(clusterCert *x509.Certificate, joinAddresses []t
 	}
 
 	return &internalTypes.TokenRecord{
-		Token:     tokenString,
-		Name:      t.Name,
-		ExpiresAt: t.ExpiryDate.Time,
+		Token:          tokenString,
+		Name:           t.Name,
+		ExpiresAt:      t.ExpiryDate.Time,
+		MaxJoins:       t.MaxJoins,
+		Joins:          t.Joins,
+		AllowedSubnets: t.Subnets(),
+		Rejoin:         t.Rejoin,
 	}, nil
 }
 
+// Reusable returns whether the token may be used by more than one joiner.
+// Reusable tokens are not tied to the name of the joiner.
+func (t *CoreTokenRecord) Reusable() bool {
+	return t.MaxJoins > 1
+}
+
+// Subnets returns the list of subnets that the joiner's address must belong to.
+func (t *CoreTokenRecord) Subnets() []string {
+	if t.AllowedSubnets == "" {
+		return nil
+	}
+
+	return strings.Split(t.AllowedSubnets, ",")
+}
+
+// AllowsAddress returns whether a joiner with the given address may use the token.
+func (t *CoreTokenRecord) AllowsAddress(addr netip.Addr) (bool, error) {
+	subnets := t.Subnets()
+	if len(subnets) == 0 {
+		return true, nil
+	}
+
+	for _, subnet := range subnets {
+		prefix, err := netip.ParsePrefix(subnet)
+		if err != nil {
+			return false, fmt.Errorf("Invalid join token subnet %q: %w", subnet, err)
+		}
+
+		if prefix.Contains(addr.Unmap()) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 // Expired compares the token's expiry date with the current time.
 func (t *CoreTokenRecord) Expired() bool {
 	return t.ExpiryDate.Valid && t.ExpiryDate.Time.Before(time.Now())
@@ -91,3 +167,18 @@ func DeleteExpiredCoreTokenRecords(ctx context.Context, tx *sql.Tx) error {
 
 	return nil
 }
+
+// RecordCoreTokenRecordJoin records that the token has been used by a joiner, and deletes it once it has been used
+// as many times as it allows.
+func RecordCoreTokenRecordJoin(ctx context.Context, tx *sql.Tx, token CoreTokenRecord) error {
+	if token.Joins+1 >= token.MaxJoins {
+		return DeleteCoreTokenRecord(ctx, tx, token.Name)
+	}
+
+	_, err := tx.ExecContext(ctx, "UPDATE core_token_records SET joins = joins + 1 WHERE secret = ?", token.Secret)
+	if err != nil {
+		return fmt.Errorf("Failed to record join token use: %w", err)
+	}
+
+	return nil
+}
@@ -17,13 +17,13 @@ import (
 var _ = api.ServerEnvironment{}
 
 var coreTokenRecordObjects = RegisterStmt(`
-SELECT core_token_records.id, core_token_records.secret, core_token_records.name, core_token_records.expiry_date
+SELECT core_token_records.id, core_token_records.secret, core_token_records.name, core_token_records.expiry_date, core_token_records.max_joins, core_token_records.joins, core_token_records.allowed_subnets, core_token_records.rejoin, core_token_records.issued_at
   FROM core_token_records
   ORDER BY core_token_records.secret
 `)
 
 var coreTokenRecordObjectsBySecret = RegisterStmt(`
-SELECT core_token_records.id, core_token_records.secret, core_token_records.name, core_token_records.expiry_date
+SELECT core_token_records.id, core_token_records.secret, core_token_records.name, core_token_records.expiry_date, core_token_records.max_joins, core_token_records.joins, core_token_records.allowed_subnets, core_token_records.rejoin, core_token_records.issued_at
   FROM core_token_records
   WHERE ( core_token_records.secret = ? )
   ORDER BY core_token_records.secret
@@ -35,8 +35,8 @@ SELECT core_token_records.id FROM core_token_records
 `)
 
 var coreTokenRecordCreate = RegisterStmt(`
-INSERT INTO core_token_records (secret, name, expiry_date)
-  VALUES (?, ?, ?)
+INSERT INTO core_token_records (secret, name, expiry_date, max_joins, joins, allowed_subnets, rejoin, issued_at)
+  VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 `)
 
 var coreTokenRecordDeleteByName = RegisterStmt(`
@@ -104,7 +104,7 @@ func GetCoreTokenRecord(ctx context.Context, tx *sql.Tx, secret string) (*CoreTo
 // coreTokenRecordColumns returns a string of column names to be used with a SELECT statement for the entity.
 // Use this function when building statements to retrieve database entries matching the CoreTokenRecord entity.
 func coreTokenRecordColumns() string {
-	return "core_token_records.id, core_token_records.secret, core_token_records.name, core_token_records.expiry_date"
+	return "core_token_records.id, core_token_records.secret, core_token_records.name, core_token_records.expiry_date, core_token_records.max_joins, core_token_records.joins, core_token_records.allowed_subnets, core_token_records.rejoin, core_token_records.issued_at"
 }
 
 // getCoreTokenRecords can be used to run handwritten sql.Stmts to return a slice of objects.
@@ -113,7 +113,7 @@ func getCoreTokenRecords(ctx context.Context, stmt *sql.Stmt, args ...any) ([]Co
 
 	dest := func(scan func(dest ...any) error) error {
 		c := CoreTokenRecord{}
-		err := scan(&c.ID, &c.Secret, &c.Name, &c.ExpiryDate)
+		err := scan(&c.ID, &c.Secret, &c.Name, &c.ExpiryDate, &c.MaxJoins, &c.Joins, &c.AllowedSubnets, &c.Rejoin, &c.IssuedAt)
 		if err != nil {
 			return err
 		}
@@ -137,7 +137,7 @@ func getCoreTokenRecordsRaw(ctx context.Context, tx *sql.Tx, sql string, args ..
 
 	dest := func(scan func(dest ...any) error) error {
 		c := CoreTokenRecord{}
-		err := scan(&c.ID, &c.Secret, &c.Name, &c.ExpiryDate)
+		err := scan(&c.ID, &c.Secret, &c.Name, &c.ExpiryDate, &c.MaxJoins, &c.Joins, &c.AllowedSubnets, &c.Rejoin, &c.IssuedAt)
 		if err != nil {
 			return err
 		}
@@ -235,12 +235,17 @@ func CreateCoreTokenRecord(ctx context.Context, tx *sql.Tx, object CoreTokenReco
 		return -1, api.StatusErrorf(http.StatusConflict, "This \"core_token_records\" entry already exists")
 	}
 
-	args := make([]any, 3)
+	args := make([]any, 8)
 
 	// Populate the statement arguments.
 	args[0] = object.Secret
 	args[1] = object.Name
 	args[2] = object.ExpiryDate
+	args[3] = object.MaxJoins
+	args[4] = object.Joins
+	args[5] = object.AllowedSubnets
+	args[6] = object.Rejoin
+	args[7] = object.IssuedAt
 
 	// Prepared statement to use.
 	stmt, err := Stmt(tx, coreTokenRecordCreate)
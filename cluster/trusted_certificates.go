@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// CoreTrustedCertificate is the database representation of a client certificate trusted to access the API without
+// being a cluster member.
+type CoreTrustedCertificate struct {
+	ID          int
+	Name        string
+	Fingerprint string
+	Certificate string
+}
+
+// ToAPI returns the API representation of the trusted certificate.
+func (c CoreTrustedCertificate) ToAPI() (*types.TrustedCertificate, error) {
+	certificate, err := types.ParseX509Certificate(c.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.TrustedCertificate{
+		Name:        c.Name,
+		Type:        types.TrustedCertificateClient,
+		Fingerprint: c.Fingerprint,
+		Certificate: *certificate,
+	}, nil
+}
+
+// GetCoreTrustedCertificates returns all trusted client certificates, ordered by name.
+func GetCoreTrustedCertificates(ctx context.Context, tx *sql.Tx) ([]CoreTrustedCertificate, error) {
+	certificates := []CoreTrustedCertificate{}
+	dest := func(scan func(dest ...any) error) error {
+		c := CoreTrustedCertificate{}
+		err := scan(&c.ID, &c.Name, &c.Fingerprint, &c.Certificate)
+		if err != nil {
+			return err
+		}
+
+		certificates = append(certificates, c)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT id, name, fingerprint, certificate FROM core_trusted_certificates ORDER BY name", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"core_trusted_certificates\" table: %w", err)
+	}
+
+	return certificates, nil
+}
+
+// CreateCoreTrustedCertificate adds a trusted client certificate to the database.
+func CreateCoreTrustedCertificate(ctx context.Context, tx *sql.Tx, object CoreTrustedCertificate) (int64, error) {
+	existing, err := GetCoreTrustedCertificates(ctx, tx)
+	if err != nil {
+		return -1, err
+	}
+
+	for _, c := range existing {
+		if c.Name == object.Name || c.Fingerprint == object.Fingerprint {
+			return -1, api.StatusErrorf(http.StatusConflict, "A trusted certificate with name %q or fingerprint %q already exists", object.Name, object.Fingerprint)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO core_trusted_certificates (name, fingerprint, certificate) VALUES (?, ?, ?)", object.Name, object.Fingerprint, object.Certificate)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create \"core_trusted_certificates\" entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to fetch \"core_trusted_certificates\" entry ID: %w", err)
+	}
+
+	return id, nil
+}
+
+// DeleteCoreTrustedCertificate removes the trusted client certificate with the given name.
+func DeleteCoreTrustedCertificate(ctx context.Context, tx *sql.Tx, name string) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM core_trusted_certificates WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("Delete \"core_trusted_certificates\": %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "CoreTrustedCertificate not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/google/uuid"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// CoreWarning is the database representation of a warning about a cluster member.
+type CoreWarning struct {
+	ID          int
+	UUID        string
+	Member      string
+	Type        string
+	Entity      string
+	Message     string
+	Status      types.WarningStatus
+	Count       int
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+// ToAPI returns the API representation of the warning.
+func (w CoreWarning) ToAPI() types.Warning {
+	return types.Warning{
+		UUID:        w.UUID,
+		Member:      w.Member,
+		Type:        w.Type,
+		Entity:      w.Entity,
+		Message:     w.Message,
+		Status:      w.Status,
+		Count:       w.Count,
+		FirstSeenAt: w.FirstSeenAt,
+		LastSeenAt:  w.LastSeenAt,
+	}
+}
+
+const coreWarningColumns = "id, uuid, member, type, entity, message, status, count, first_seen_at, last_seen_at"
+
+// getCoreWarnings returns the warnings matching the given clause, ordered by ID.
+func getCoreWarnings(ctx context.Context, tx *sql.Tx, clause string, args ...any) ([]CoreWarning, error) {
+	warnings := []CoreWarning{}
+	dest := func(scan func(dest ...any) error) error {
+		w := CoreWarning{}
+		err := scan(&w.ID, &w.UUID, &w.Member, &w.Type, &w.Entity, &w.Message, &w.Status, &w.Count, &w.FirstSeenAt, &w.LastSeenAt)
+		if err != nil {
+			return err
+		}
+
+		warnings = append(warnings, w)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, fmt.Sprintf("SELECT %s FROM core_warnings %s ORDER BY id", coreWarningColumns, clause), dest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"core_warnings\" table: %w", err)
+	}
+
+	return warnings, nil
+}
+
+// GetCoreWarnings returns all warnings, in the order they were first filed.
+func GetCoreWarnings(ctx context.Context, tx *sql.Tx) ([]CoreWarning, error) {
+	return getCoreWarnings(ctx, tx, "")
+}
+
+// GetCoreWarning returns the warning with the given UUID.
+func GetCoreWarning(ctx context.Context, tx *sql.Tx, warningUUID string) (*CoreWarning, error) {
+	warnings, err := getCoreWarnings(ctx, tx, "WHERE uuid = ?", warningUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(warnings) == 0 {
+		return nil, api.StatusErrorf(http.StatusNotFound, "CoreWarning not found")
+	}
+
+	return &warnings[0], nil
+}
+
+// FileCoreWarning records a warning about the given cluster member. If a warning with the same member, type and
+// entity already exists, its message is replaced and its count incremented, keeping its status.
+func FileCoreWarning(ctx context.Context, tx *sql.Tx, member string, warningType string, entity string, message string, seenAt time.Time) error {
+	var id int
+	err := tx.QueryRowContext(ctx, "SELECT id FROM core_warnings WHERE member = ? AND type = ? AND entity = ?", member, warningType, entity).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		stmt := "INSERT INTO core_warnings (uuid, member, type, entity, message, status, count, first_seen_at, last_seen_at) VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?)"
+		_, err = tx.ExecContext(ctx, stmt, uuid.New().String(), member, warningType, entity, message, types.WarningStatusNew, seenAt, seenAt)
+		if err != nil {
+			return fmt.Errorf("Failed to create \"core_warnings\" entry: %w", err)
+		}
+
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Failed to fetch from \"core_warnings\" table: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE core_warnings SET message = ?, count = count + 1, last_seen_at = ? WHERE id = ?", message, seenAt, id)
+	if err != nil {
+		return fmt.Errorf("Failed to update \"core_warnings\" entry: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveCoreWarning removes the warning about the given cluster member with the given type and entity, if any.
+func ResolveCoreWarning(ctx context.Context, tx *sql.Tx, member string, warningType string, entity string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM core_warnings WHERE member = ? AND type = ? AND entity = ?", member, warningType, entity)
+	if err != nil {
+		return fmt.Errorf("Delete \"core_warnings\": %w", err)
+	}
+
+	return nil
+}
+
+// UpdateCoreWarningStatus sets the status of the warning with the given UUID.
+func UpdateCoreWarningStatus(ctx context.Context, tx *sql.Tx, warningUUID string, status types.WarningStatus) error {
+	result, err := tx.ExecContext(ctx, "UPDATE core_warnings SET status = ? WHERE uuid = ?", status, warningUUID)
+	if err != nil {
+		return fmt.Errorf("Update \"core_warnings\" entry failed: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "CoreWarning not found")
+	}
+
+	return nil
+}
+
+// DeleteCoreWarning removes the warning with the given UUID.
+func DeleteCoreWarning(ctx context.Context, tx *sql.Tx, warningUUID string) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM core_warnings WHERE uuid = ?", warningUUID)
+	if err != nil {
+		return fmt.Errorf("Delete \"core_warnings\": %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "CoreWarning not found")
+	}
+
+	return nil
+}
+
+// DeleteCoreWarningsByMember removes every warning about the given cluster member.
+func DeleteCoreWarningsByMember(ctx context.Context, tx *sql.Tx, member string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM core_warnings WHERE member = ?", member)
+	if err != nil {
+		return fmt.Errorf("Delete \"core_warnings\": %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/internal/db/dbtest"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures filing a warning again updates it rather than adding another, keeping its status and when it was first
+// seen.
+func TestFileCoreWarning(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.NewTx(t)
+
+	firstSeen := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	lastSeen := firstSeen.Add(time.Minute)
+
+	require.NoError(t, FileCoreWarning(ctx, tx, "m1", types.WarningMemberOffline, "", "First", firstSeen))
+	require.NoError(t, FileCoreWarning(ctx, tx, "m2", types.WarningMemberOffline, "", "Other member", firstSeen))
+
+	warnings, err := GetCoreWarnings(ctx, tx)
+	require.NoError(t, err)
+	require.Len(t, warnings, 2)
+
+	warning := warnings[0]
+	require.NotEmpty(t, warning.UUID)
+	require.NotEqual(t, warning.UUID, warnings[1].UUID)
+	require.Equal(t, types.WarningStatusNew, warning.Status)
+	require.Equal(t, 1, warning.Count)
+
+	require.NoError(t, UpdateCoreWarningStatus(ctx, tx, warning.UUID, types.WarningStatusAcknowledged))
+	require.NoError(t, FileCoreWarning(ctx, tx, "m1", types.WarningMemberOffline, "", "Second", lastSeen))
+
+	updated, err := GetCoreWarning(ctx, tx, warning.UUID)
+	require.NoError(t, err)
+	require.Equal(t, warning.UUID, updated.UUID)
+	require.Equal(t, "Second", updated.Message)
+	require.Equal(t, types.WarningStatusAcknowledged, updated.Status)
+	require.Equal(t, 2, updated.Count)
+	require.True(t, firstSeen.Equal(updated.FirstSeenAt))
+	require.True(t, lastSeen.Equal(updated.LastSeenAt))
+
+	apiWarning := updated.ToAPI()
+	require.Equal(t, updated.UUID, apiWarning.UUID)
+	require.Equal(t, "m1", apiWarning.Member)
+	require.Equal(t, types.WarningMemberOffline, apiWarning.Type)
+	require.Equal(t, 2, apiWarning.Count)
+
+	// Warnings about different entities of the same member are kept apart.
+	require.NoError(t, FileCoreWarning(ctx, tx, "m1", types.WarningMemberOffline, "disk", "Disk", lastSeen))
+	warnings, err = GetCoreWarnings(ctx, tx)
+	require.NoError(t, err)
+	require.Len(t, warnings, 3)
+}
+
+// Ensures warnings are removed when resolved or deleted, and that unknown warnings are reported as not found.
+func TestDeleteCoreWarnings(t *testing.T) {
+	ctx := context.Background()
+	tx := dbtest.NewTx(t)
+	now := time.Now()
+
+	for _, member := range []string{"m1", "m2"} {
+		for _, warningType := range []string{types.WarningMemberOffline, types.WarningSchemaDivergence} {
+			require.NoError(t, FileCoreWarning(ctx, tx, member, warningType, "", "Warning", now))
+		}
+	}
+
+	notFound := func(err error) {
+		t.Helper()
+		require.True(t, api.StatusErrorCheck(err, http.StatusNotFound), err)
+	}
+
+	_, err := GetCoreWarning(ctx, tx, "unknown")
+	notFound(err)
+	notFound(UpdateCoreWarningStatus(ctx, tx, "unknown", types.WarningStatusAcknowledged))
+	notFound(DeleteCoreWarning(ctx, tx, "unknown"))
+
+	// Resolving a warning that is not filed does nothing.
+	require.NoError(t, ResolveCoreWarning(ctx, tx, "m1", types.WarningMemberOffline, "disk"))
+	require.NoError(t, ResolveCoreWarning(ctx, tx, "m1", types.WarningMemberOffline, ""))
+
+	warnings, err := GetCoreWarnings(ctx, tx)
+	require.NoError(t, err)
+	require.Len(t, warnings, 3)
+	require.Equal(t, types.WarningSchemaDivergence, warnings[0].Type)
+
+	require.NoError(t, DeleteCoreWarning(ctx, tx, warnings[0].UUID))
+	notFound(DeleteCoreWarning(ctx, tx, warnings[0].UUID))
+
+	require.NoError(t, DeleteCoreWarningsByMember(ctx, tx, "m2"))
+	warnings, err = GetCoreWarnings(ctx, tx)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+}
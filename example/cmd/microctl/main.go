@@ -7,6 +7,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/canonical/microcluster/v3/example/version"
+	"github.com/canonical/microcluster/v3/microcluster"
+	microclusterCmd "github.com/canonical/microcluster/v3/microcluster/cmd"
 )
 
 // CmdControl has functions that are common to the microctl commands.
@@ -41,21 +43,16 @@ func main() {
 
 	app.SetVersionTemplate("{{.Version}}\n")
 
-	var cmdInit = cmdInit{common: &commonCmd}
-	app.AddCommand(cmdInit.command())
-
-	var cmdPeers = cmdClusterMembers{common: &commonCmd}
-	app.AddCommand(cmdPeers.command())
+	app.AddCommand(microclusterCmd.Commands(microclusterCmd.Options{
+		Name: "microctl",
+		Args: func() microcluster.Args {
+			return microcluster.Args{StateDir: commonCmd.FlagStateDir}
+		},
+	})...)
 
 	var cmdShutdown = cmdShutdown{common: &commonCmd}
 	app.AddCommand(cmdShutdown.command())
 
-	var cmdSQL = cmdSQL{common: &commonCmd}
-	app.AddCommand(cmdSQL.command())
-
-	var cmdSecrets = cmdSecrets{common: &commonCmd}
-	app.AddCommand(cmdSecrets.command())
-
 	var cmdWaitready = cmdWaitready{common: &commonCmd}
 	app.AddCommand(cmdWaitready.command())
 
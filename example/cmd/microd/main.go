@@ -130,6 +130,13 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 
+		// OnShutdown is run when the daemon begins a graceful shutdown.
+		OnShutdown: func(ctx context.Context, s state.State) error {
+			logger.Info("This is a hook that runs before the daemon stops serving requests")
+
+			return nil
+		},
+
 		// PostJoin is run after the daemon is initialized and joins a cluster.
 		PostJoin: func(ctx context.Context, s state.State, initConfig map[string]string) error {
 			logCtx := logger.Ctx{}
@@ -156,6 +163,13 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 
+		// OnJoinRequest is run on the cluster member handling a join request, and can refuse the join.
+		OnJoinRequest: func(ctx context.Context, s state.State, joiner types.ClusterMemberLocal, initConfig map[string]string) error {
+			logger.Infof("This is a hook that is run on peer %q when %q at %q requests to join the cluster", s.Name(), joiner.Name, joiner.Address.String())
+
+			return nil
+		},
+
 		// PostRemove is run after the daemon is removed from a cluster.
 		PostRemove: func(ctx context.Context, s state.State, force bool) error {
 			logger.Infof("This is a hook that is run on peer %q after a cluster member is removed, with the force flag set to %v", s.Name(), force)
@@ -163,6 +177,13 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 
+		// OnMemberRemoved is run on each remaining peer after a cluster member is removed.
+		OnMemberRemoved: func(ctx context.Context, s state.State, member types.ClusterMemberLocal, force bool) error {
+			logger.Infof("This is a hook that is run on peer %q after the cluster member %q at %q is removed", s.Name(), member.Name, member.Address.String())
+
+			return nil
+		},
+
 		// PreRemove is run before the daemon is removed from the cluster.
 		PreRemove: func(ctx context.Context, s state.State, force bool) error {
 			logger.Infof("This is a hook that is run on peer %q just before it is removed, with the force flag set to %v", s.Name(), force)
@@ -186,6 +207,17 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 
+		// OnClusterHealth is run on the dqlite leader after each heartbeat round.
+		OnClusterHealth: func(ctx context.Context, s state.State, health types.ClusterHealth) error {
+			for _, member := range health.Members {
+				if member.Status != types.MemberOnline {
+					logger.Warnf("Cluster member %s is %s, last heartbeat at %s", member.Name, member.Status, member.LastHeartbeat)
+				}
+			}
+
+			return nil
+		},
+
 		// OnNewMember is run after a new member has joined.
 		OnNewMember: func(ctx context.Context, s state.State, newMember types.ClusterMemberLocal) error {
 			logger.Infof("This is a hook that is run on peer %q when the new cluster member %q has joined", s.Name(), newMember.Name)
@@ -199,6 +231,13 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 
 			return nil
 		},
+
+		// OnDqliteLeadershipChange is run when this cluster member gains or loses dqlite leadership.
+		OnDqliteLeadershipChange: func(ctx context.Context, s state.State, isLeader bool, leaderName string, leaderAddress types.AddrPort) error {
+			logger.Infof("Cluster member %q is leader: %t (new leader %q)", s.Name(), isLeader, leaderName)
+
+			return nil
+		},
 	}
 
 	return m.Start(cmd.Context(), dargs)
@@ -0,0 +1,317 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/google/renameio"
+	"golang.org/x/crypto/acme"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+const (
+	// DefaultChallengeAddress is the address on which HTTP-01 challenges are answered by default.
+	DefaultChallengeAddress = ":80"
+
+	// DefaultRenewBefore is how long before expiry certificates are renewed by default.
+	DefaultRenewBefore = 30 * 24 * time.Hour
+
+	// retryInterval is how long to wait before trying again after failing to issue a certificate.
+	retryInterval = time.Hour
+)
+
+// Manager provisions and renews the certificate of an additional listener from an ACME certificate authority.
+// Every cluster member runs a Manager for the listener, but only the issuer obtains certificates, so that the
+// certificate authority is not asked for one per cluster member. The others wait for the issuer to share it.
+type Manager struct {
+	name   string
+	config types.ACMEConfig
+
+	certificatesDir string
+	accountKeyPath  string
+
+	// isIssuer reports whether the local cluster member is the one obtaining certificates.
+	isIssuer func(ctx context.Context) (bool, error)
+
+	// store installs an issued certificate for the listener, on every cluster member.
+	store func(ctx context.Context, keyPair types.KeyPair) error
+}
+
+// NewManager returns a Manager for the listener with the given name. The current certificate is read from the
+// certificates directory under the listener's name, and the ACME account key is kept at the given path.
+func NewManager(name string, config types.ACMEConfig, certificatesDir string, accountKeyPath string, isIssuer func(ctx context.Context) (bool, error), store func(ctx context.Context, keyPair types.KeyPair) error) (*Manager, error) {
+	if config.DNSName == "" {
+		return nil, fmt.Errorf("ACME configuration of server %q requires a DNS name", name)
+	}
+
+	if config.DirectoryURL == "" {
+		config.DirectoryURL = acme.LetsEncryptURL
+	}
+
+	if config.ChallengeAddress == "" {
+		config.ChallengeAddress = DefaultChallengeAddress
+	}
+
+	if config.RenewBefore <= 0 {
+		config.RenewBefore = DefaultRenewBefore
+	}
+
+	return &Manager{
+		name:            name,
+		config:          config,
+		certificatesDir: certificatesDir,
+		accountKeyPath:  accountKeyPath,
+		isIssuer:        isIssuer,
+		store:           store,
+	}, nil
+}
+
+// Run issues a certificate if the listener does not have a valid one yet, and renews it ahead of its expiry,
+// until the context is cancelled.
+func (m *Manager) Run(ctx context.Context) {
+	for {
+		renewAt := m.renewAt()
+		if !time.Now().Before(renewAt) {
+			issuer, err := m.isIssuer(ctx)
+			if err == nil && !issuer {
+				// The certificate is checked again later, in case the local cluster member becomes the issuer.
+				renewAt = time.Now().Add(retryInterval)
+			} else if err == nil {
+				err = m.issue(ctx)
+			}
+
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				logger.Error("Failed to issue ACME certificate", logger.Ctx{"server": m.name, "dns_name": m.config.DNSName, "error": err})
+				renewAt = time.Now().Add(retryInterval)
+			} else if issuer {
+				logger.Info("Issued ACME certificate", logger.Ctx{"server": m.name, "dns_name": m.config.DNSName})
+				renewAt = m.renewAt()
+			}
+		}
+
+		timer := time.NewTimer(time.Until(renewAt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// renewAt returns when the current certificate should be renewed. Certificates that are missing, self signed,
+// or not valid for the DNS name are renewed immediately.
+func (m *Manager) renewAt() time.Time {
+	certPEM, err := os.ReadFile(filepath.Join(m.certificatesDir, fmt.Sprintf("%s.crt", m.name)))
+	if err != nil {
+		return time.Time{}
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return time.Time{}
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}
+	}
+
+	if !slices.Contains(cert.DNSNames, m.config.DNSName) || bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return time.Time{}
+	}
+
+	return cert.NotAfter.Add(-m.config.RenewBefore)
+}
+
+// issue obtains a new certificate for the DNS name and stores it.
+func (m *Manager) issue(ctx context.Context) error {
+	accountKey, err := m.accountKey()
+	if err != nil {
+		return err
+	}
+
+	client := &acme.Client{Key: accountKey, DirectoryURL: m.config.DirectoryURL}
+
+	account := &acme.Account{}
+	if m.config.Email != "" {
+		account.Contact = []string{"mailto:" + m.config.Email}
+	}
+
+	_, err = client.Register(ctx, account, acme.AcceptTOS)
+	if err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("Failed to register ACME account: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(m.config.DNSName))
+	if err != nil {
+		return fmt.Errorf("Failed to create ACME order: %w", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		err = m.authorize(ctx, client, authzURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return fmt.Errorf("Failed to wait for ACME order: %w", err)
+	}
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{m.config.DNSName}}, certKey)
+	if err != nil {
+		return err
+	}
+
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("Failed to finalize ACME order: %w", err)
+	}
+
+	certPEM := []byte{}
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(certKey)
+	if err != nil {
+		return err
+	}
+
+	keyPair := types.KeyPair{Cert: string(certPEM), Key: string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))}
+	err = m.store(ctx, keyPair)
+	if err != nil {
+		return fmt.Errorf("Failed to store ACME certificate: %w", err)
+	}
+
+	return nil
+}
+
+// authorize completes the HTTP-01 challenge of a pending authorization.
+func (m *Manager) authorize(ctx context.Context, client *acme.Client, authzURL string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("Failed to get ACME authorization: %w", err)
+	}
+
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == "http-01" {
+			challenge = c
+			break
+		}
+	}
+
+	if challenge == nil {
+		return fmt.Errorf("ACME authorization for %q does not offer an HTTP-01 challenge", m.config.DNSName)
+	}
+
+	response, err := client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return err
+	}
+
+	stop, err := serveChallenge(m.config.ChallengeAddress, client.HTTP01ChallengePath(challenge.Token), response)
+	if err != nil {
+		return err
+	}
+
+	defer stop()
+
+	_, err = client.Accept(ctx, challenge)
+	if err != nil {
+		return fmt.Errorf("Failed to accept ACME challenge: %w", err)
+	}
+
+	_, err = client.WaitAuthorization(ctx, authz.URI)
+	if err != nil {
+		return fmt.Errorf("Failed to wait for ACME authorization: %w", err)
+	}
+
+	return nil
+}
+
+// accountKey loads the ACME account key, generating it on first use.
+func (m *Manager) accountKey() (crypto.Signer, error) {
+	keyPEM, err := os.ReadFile(m.accountKeyPath)
+	if err == nil {
+		block, _ := pem.Decode(keyPEM)
+		if block == nil {
+			return nil, fmt.Errorf("Failed to decode ACME account key %q", m.accountKeyPath)
+		}
+
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = renameio.WriteFile(m.accountKeyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// serveChallenge answers the HTTP-01 challenge at the given path until the returned function is called.
+func serveChallenge(address string, path string, response string) (func(), error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to listen for ACME challenges on %q: %w", address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(response))
+	})
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = server.Serve(listener) }()
+
+	return func() { _ = server.Close() }, nil
+}
@@ -0,0 +1,205 @@
+package acme
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// newTestManager returns a Manager for the "public" listener with the given issuer check, whose stored certificates
+// are counted.
+func newTestManager(t *testing.T, config types.ACMEConfig, isIssuer func(ctx context.Context) (bool, error)) (*Manager, *int) {
+	stored := 0
+	dir := t.TempDir()
+	m, err := NewManager("public", config, dir, filepath.Join(dir, "acme.key"), isIssuer, func(ctx context.Context, keyPair types.KeyPair) error {
+		stored++
+		return nil
+	})
+	require.NoError(t, err)
+
+	return m, &stored
+}
+
+// Ensures the ACME configuration requires a DNS name, and defaults to Let's Encrypt.
+func TestNewManager(t *testing.T) {
+	_, err := NewManager("public", types.ACMEConfig{}, t.TempDir(), "acme.key", nil, nil)
+	require.Error(t, err)
+
+	m, _ := newTestManager(t, types.ACMEConfig{DNSName: "example.com"}, nil)
+	require.Equal(t, acme.LetsEncryptURL, m.config.DirectoryURL)
+	require.Equal(t, DefaultChallengeAddress, m.config.ChallengeAddress)
+	require.Equal(t, DefaultRenewBefore, m.config.RenewBefore)
+}
+
+// Ensures certificates are only kept until they are due for renewal, if they were issued for the DNS name.
+func TestRenewAt(t *testing.T) {
+	m, _ := newTestManager(t, types.ACMEConfig{DNSName: "example.com", RenewBefore: time.Hour}, nil)
+	certPath := filepath.Join(m.certificatesDir, "public.crt")
+
+	// Certificates that are missing or invalid are renewed immediately.
+	require.True(t, m.renewAt().IsZero())
+	require.NoError(t, os.WriteFile(certPath, []byte("invalid"), 0664))
+	require.True(t, m.renewAt().IsZero())
+
+	issuer, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	issue := func(dnsName string) *x509.Certificate {
+		template := &x509.Certificate{SerialNumber: big.NewInt(1), DNSNames: []string{dnsName}, NotBefore: time.Now(), NotAfter: time.Now().Add(24 * time.Hour)}
+		der, err := x509.CreateCertificate(rand.Reader, template, issuer, issuer.PublicKey, shared.TestingKeyPair().KeyPair().PrivateKey)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0664))
+
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+
+		return cert
+	}
+
+	// The dedicated self signed certificate used until the first certificate is issued is replaced immediately.
+	_, err = shared.KeyPairAndCA(m.certificatesDir, "public", shared.CertServer, shared.CertOptions{AddHosts: true, CommonName: "public"})
+	require.NoError(t, err)
+	require.True(t, m.renewAt().IsZero())
+
+	issue("other.example.com")
+	require.True(t, m.renewAt().IsZero())
+
+	cert := issue("example.com")
+	require.Equal(t, cert.NotAfter.Add(-time.Hour), m.renewAt())
+}
+
+// Ensures the ACME account key is generated on first use, and reused afterwards.
+func TestAccountKey(t *testing.T) {
+	m, _ := newTestManager(t, types.ACMEConfig{DNSName: "example.com"}, nil)
+
+	key, err := m.accountKey()
+	require.NoError(t, err)
+
+	info, err := os.Stat(m.accountKeyPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	reused, err := m.accountKey()
+	require.NoError(t, err)
+	require.Equal(t, key.Public(), reused.Public())
+
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(m.certificatesDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	require.NoError(t, os.WriteFile(m.accountKeyPath, []byte("invalid"), 0600))
+	_, err = m.accountKey()
+	require.Error(t, err)
+}
+
+// Ensures only the issuer requests certificates from the certificate authority, and that failures are retried later
+// rather than stopping the manager.
+func TestRun(t *testing.T) {
+	requests := 0
+	ca := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ca.Close()
+
+	cases := []struct {
+		name           string
+		issuer         bool
+		issuerErr      error
+		expectRequests bool
+	}{
+		{name: "Issuer", issuer: true, expectRequests: true},
+		{name: "Other cluster member", issuer: false},
+		{name: "Unknown issuer", issuerErr: fmt.Errorf("No available dqlite leader")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			requests = 0
+			checked := make(chan struct{}, 1)
+			m, stored := newTestManager(t, types.ACMEConfig{DNSName: "example.com", DirectoryURL: ca.URL}, func(ctx context.Context) (bool, error) {
+				checked <- struct{}{}
+				return c.issuer, c.issuerErr
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				m.Run(ctx)
+				close(done)
+			}()
+
+			<-checked
+
+			// The manager waits before checking again, until it is stopped.
+			select {
+			case <-checked:
+				t.Fatal("Certificate checked again without waiting")
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			cancel()
+			<-done
+
+			require.Equal(t, c.expectRequests, requests > 0)
+			require.Zero(t, *stored)
+		})
+	}
+}
+
+// Ensures the HTTP-01 challenge response is served at its path until the server is stopped.
+func TestServeChallenge(t *testing.T) {
+	stop, err := serveChallenge("127.0.0.1:0", "/.well-known/acme-challenge/token", "response")
+	require.NoError(t, err)
+	stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	stop, err = serveChallenge(address, "/.well-known/acme-challenge/token", "response")
+	require.NoError(t, err)
+
+	resp, err := http.Get("http://" + address + "/.well-known/acme-challenge/token")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, "response", string(body))
+
+	resp, err = http.Get("http://" + address + "/.well-known/acme-challenge/other")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	stop()
+	_, err = http.Get("http://" + address + "/.well-known/acme-challenge/token")
+	require.Error(t, err)
+
+	// The challenge cannot be served if the address is in use.
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	_, err = serveChallenge(listener.Addr().String(), "/", "response")
+	require.Error(t, err)
+}
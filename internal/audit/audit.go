@@ -0,0 +1,214 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+const (
+	// DefaultRetention is how long audit log entries are kept by default.
+	DefaultRetention = 90 * 24 * time.Hour
+
+	// pruneInterval is how often entries past the retention period are removed from the audit log.
+	pruneInterval = time.Hour
+)
+
+// Options configures the audit log of mutating API requests.
+type Options struct {
+	// Enabled records every PUT, POST, PATCH and DELETE request received by the core API and the extension servers.
+	Enabled bool
+
+	// Retention is how long entries are kept. It defaults to DefaultRetention.
+	Retention time.Duration
+}
+
+// Validate checks that the options can be applied to an audit log.
+func (o Options) Validate() error {
+	if o.Retention < 0 {
+		return fmt.Errorf("Audit log retention cannot be negative")
+	}
+
+	return nil
+}
+
+// Log is an append-only file of audit entries, one JSON object per line.
+// Entries older than the retention period are pruned periodically.
+type Log struct {
+	path      string
+	retention time.Duration
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// Open returns the audit log at the given path, pruning expired entries.
+func Open(path string, options Options) (*Log, error) {
+	err := options.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{path: path, retention: options.Retention}
+	if l.retention == 0 {
+		l.retention = DefaultRetention
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err = l.prune(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Record appends the entry to the audit log.
+func (l *Log) Record(entry types.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Since(l.lastPrune) > pruneInterval {
+		err = l.prune(time.Now())
+		if err != nil {
+			logger.Warn("Failed to prune audit log", logger.Ctx{"path": l.path, "error": err})
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to open audit log: %w", err)
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("Failed to write audit log entry: %w", err)
+	}
+
+	return f.Close()
+}
+
+// Entries returns the entries recorded since the given time, most recent first.
+func (l *Log) Entries(since time.Time) ([]types.AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]types.AuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Time.Before(since) {
+			selected = append(selected, entry)
+		}
+	}
+
+	slices.Reverse(selected)
+
+	return selected, nil
+}
+
+// read parses every entry of the audit log, in the order they were recorded.
+func (l *Log) read() ([]types.AuditEntry, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []types.AuditEntry{}, nil
+		}
+
+		return nil, fmt.Errorf("Failed to open audit log: %w", err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	entries := []types.AuditEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry := types.AuditEntry{}
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse audit log entry: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read audit log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// prune rewrites the audit log without the entries older than the retention period.
+func (l *Log) prune(now time.Time) error {
+	l.lastPrune = now
+
+	entries, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-l.retention)
+	index := slices.IndexFunc(entries, func(entry types.AuditEntry) bool { return !entry.Time.Before(cutoff) })
+	if index < 0 {
+		index = len(entries)
+	}
+
+	if index == 0 {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries[index:] {
+		err = encoder.Encode(entry)
+		if err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), l.path)
+}
@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures entries are returned most recent first, and that expired entries are pruned.
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := Open(path, Options{Enabled: true, Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, l.Record(types.AuditEntry{Time: now.Add(-2 * time.Hour), Method: "POST", Path: "/old"}))
+	require.NoError(t, l.Record(types.AuditEntry{Time: now.Add(-time.Minute), Method: "PUT", Path: "/first"}))
+	require.NoError(t, l.Record(types.AuditEntry{Time: now, Method: "DELETE", Path: "/second", StatusCode: 200}))
+
+	entries, err := l.Entries(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "/second", entries[0].Path)
+	require.Equal(t, 200, entries[0].StatusCode)
+
+	entries, err = l.Entries(now.Add(-30 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	require.NoError(t, l.prune(now))
+	entries, err = l.Entries(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "/first", entries[1].Path)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, err = Open(path, Options{Retention: -time.Hour})
+	require.Error(t, err)
+}
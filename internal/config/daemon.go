@@ -94,6 +94,22 @@ func (d *DaemonConfig) GetAddress() types.AddrPort {
 	return d.config.Address
 }
 
+// GetListenAddress returns the address the daemon's API listens on, if it differs from the daemon's address.
+func (d *DaemonConfig) GetListenAddress() types.AddrPort {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.config.ListenAddress
+}
+
+// GetDNSName returns the DNS name resolving to the daemon's address.
+func (d *DaemonConfig) GetDNSName() string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.config.DNSName
+}
+
 // GetServers returns the daemon's additional listener configs.
 func (d *DaemonConfig) GetServers() map[string]types.ServerConfig {
 	d.lock.RLock()
@@ -108,6 +124,30 @@ func (d *DaemonConfig) GetServers() map[string]types.ServerConfig {
 	return serverConfigCopy
 }
 
+// GetHeartbeat returns the daemon's heartbeat settings.
+func (d *DaemonConfig) GetHeartbeat() types.HeartbeatConfig {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.config.Heartbeat
+}
+
+// GetSocket returns the ownership and permissions of the daemon's control socket set at runtime.
+func (d *DaemonConfig) GetSocket() types.SocketConfig {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.config.Socket
+}
+
+// GetLocalOnly returns whether the daemon is only reachable locally, until it is published on a network address.
+func (d *DaemonConfig) GetLocalOnly() bool {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.config.LocalOnly
+}
+
 // SetName sets the daemon's name.
 func (d *DaemonConfig) SetName(name string) {
 	d.lock.Lock()
@@ -124,6 +164,22 @@ func (d *DaemonConfig) SetAddress(address types.AddrPort) {
 	d.config.Address = address
 }
 
+// SetListenAddress sets the address the daemon's API listens on.
+func (d *DaemonConfig) SetListenAddress(address types.AddrPort) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.config.ListenAddress = address
+}
+
+// SetDNSName sets the DNS name resolving to the daemon's address.
+func (d *DaemonConfig) SetDNSName(name string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.config.DNSName = name
+}
+
 // SetServers sets the daemon's additional listener configs.
 func (d *DaemonConfig) SetServers(servers map[string]types.ServerConfig) {
 	d.lock.Lock()
@@ -131,3 +187,27 @@ func (d *DaemonConfig) SetServers(servers map[string]types.ServerConfig) {
 
 	d.config.Servers = servers
 }
+
+// SetHeartbeat sets the daemon's heartbeat settings.
+func (d *DaemonConfig) SetHeartbeat(heartbeat types.HeartbeatConfig) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.config.Heartbeat = heartbeat
+}
+
+// SetSocket sets the ownership and permissions of the daemon's control socket.
+func (d *DaemonConfig) SetSocket(socket types.SocketConfig) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.config.Socket = socket
+}
+
+// SetLocalOnly sets whether the daemon is only reachable locally.
+func (d *DaemonConfig) SetLocalOnly(localOnly bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.config.LocalOnly = localOnly
+}
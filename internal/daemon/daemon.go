@@ -1,14 +1,24 @@
 package daemon
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/x509"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/canonical/lxd/lxd/db/schema"
@@ -23,24 +33,63 @@ import (
 
 	"github.com/canonical/microcluster/v3/client"
 	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/acme"
+	"github.com/canonical/microcluster/v3/internal/audit"
 	internalConfig "github.com/canonical/microcluster/v3/internal/config"
 	"github.com/canonical/microcluster/v3/internal/db"
+	"github.com/canonical/microcluster/v3/internal/db/update"
+	"github.com/canonical/microcluster/v3/internal/discovery"
 	"github.com/canonical/microcluster/v3/internal/endpoints"
 	"github.com/canonical/microcluster/v3/internal/extensions"
+	"github.com/canonical/microcluster/v3/internal/kine"
+	"github.com/canonical/microcluster/v3/internal/operations"
 	"github.com/canonical/microcluster/v3/internal/recover"
 	internalREST "github.com/canonical/microcluster/v3/internal/rest"
+	internalAccess "github.com/canonical/microcluster/v3/internal/rest/access"
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	"github.com/canonical/microcluster/v3/internal/rest/resources"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/tasks"
 	"github.com/canonical/microcluster/v3/internal/trust"
 	"github.com/canonical/microcluster/v3/internal/utils"
+	"github.com/canonical/microcluster/v3/internal/watchdog"
 	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
 	"github.com/canonical/microcluster/v3/rest/types"
 	"github.com/canonical/microcluster/v3/state"
 )
 
+// handoverTimeout is how long a stopping daemon waits to hand off its dqlite roles to other cluster members.
+const handoverTimeout = 30 * time.Second
+
+const (
+	// tableChangesInterval is how often the changes of tracked tables are delivered to the OnTableChange hook.
+	tableChangesInterval = time.Second
+
+	// idleCheckInterval is the longest delay between checks of whether the daemon has been idle for its idle timeout.
+	idleCheckInterval = 10 * time.Second
+
+	// backfillsInterval is how often pending data backfills are attempted, until they are complete.
+	backfillsInterval = 10 * time.Second
+
+	// tableChangesRetention is how long the changes of tracked tables are kept in the database.
+	tableChangesRetention = 10 * time.Minute
+
+	// tableChangesBatch is the maximum number of changes delivered to a single run of the OnTableChange hook.
+	tableChangesBatch = 1000
+
+	// certificateExpiryInterval is how often the expiry of the loaded certificates is checked.
+	certificateExpiryInterval = 12 * time.Hour
+
+	// defaultCertificateExpiryWarning is how long before expiry a certificate is reported, unless configured.
+	defaultCertificateExpiryWarning = 30 * 24 * time.Hour
+
+	// defaultMaxClockSkew is the largest clock skew between cluster members that is tolerated, unless configured.
+	defaultMaxClockSkew = 10 * time.Second
+)
+
 // Args are the data needed to start a MicroCluster daemon.
 type Args struct {
 	Verbose bool
@@ -49,7 +98,8 @@ type Args struct {
 	// Consumers of MicroCluster are required to provide a version to serve at /cluster/1.0.
 	Version string
 
-	// Name of the Unix group of the control socket
+	// Name of the Unix group of the control socket. It can be changed while the daemon is running, and across the
+	// cluster, through the socket settings of the runtime configuration, which take precedence once set.
 	SocketGroup string
 
 	// Address/port to offer the core API and extension servers over before initializing the daemon
@@ -61,18 +111,142 @@ type Args struct {
 	// List of schema updates in the order that they should be applied.
 	ExtensionsSchema []schema.Update
 
+	// Optional list of down migrations, where the entry at index i reverts the schema update at ExtensionsSchema[i].
+	// A nil entry marks an update that cannot be rolled back.
+	ExtensionsSchemaRollback []schema.Update
+
+	// Optional schema updates of components sharing the database, such as extension servers, each versioned
+	// independently of ExtensionsSchema. The tables of each namespace must be prefixed with its name.
+	SchemaNamespaces []update.Namespace
+
+	// Optional data backfills, run in order once across the cluster after the schema is updated, for data
+	// transformations too large for a single schema update. Each backfill commits its progress after every batch, and
+	// resumes from it if interrupted.
+	DatabaseBackfills []update.Backfill
+
 	// List of extensions supported by the endpoints of the core/default cluster API.
 	APIExtensions []string
 
 	// Functions that trigger at various lifecycle events
 	Hooks *state.Hooks
 
+	// HookHandlers are named sets of hooks registered by separate subsystems, run in order of priority for each
+	// lifecycle event. It cannot be used together with Hooks.
+	HookHandlers []state.HookHandler
+
+	// HookTimeouts configures how long hooks may run before their context is cancelled. Hooks that have not returned
+	// after the grace period are abandoned to keep running in the background, and fail with types.ErrHookTimeout, so
+	// that a hanging hook cannot block bootstrapping or joining forever. Unset means no deadline.
+	HookTimeouts state.HookTimeouts
+
 	// Each rest.Server will be initialized and managed by microcluster.
 	ExtensionServers map[string]rest.Server
 
-	// DrainConnectionsTimeout is the amount of time to allow for all core server connections to drain when shutting down.
+	// DrainConnectionsTimeout is the amount of time to allow for all core server connections to drain when shutting down,
+	// or when the core listener is restarted to apply a new address or certificate.
+	// In-flight requests that have not finished by then are aborted.
 	// If it's 0, the connections are not drained when shutting down.
 	DrainConnectionsTimeout time.Duration
+
+	// ArchiveEncryption configures the encryption of database backups taken by the daemon,
+	// and provides the passphrase for decrypting recovery tarballs on start.
+	ArchiveEncryption recover.ArchiveEncryption
+
+	// DqliteOptions tunes the local dqlite node, for instance for large databases or slow disks, and sets how many
+	// voters and stand-bys the cluster keeps and the zone of the member.
+	DqliteOptions db.DqliteOptions
+
+	// ClientTransportOptions tunes the connection pooling, TLS session resumption and proxying of the clients used to
+	// reach other cluster members. The options apply to every daemon in the process.
+	ClientTransportOptions internalClient.TransportOptions
+
+	// AuditLog records every mutating request received by the core API and the extension servers, along with its
+	// caller and result, to an append-only file in the state directory.
+	AuditLog audit.Options
+
+	// ControlTCPAddress, if set, also serves the control API on the given loopback TCP address, for clients that
+	// cannot reach the unix socket, such as Windows tools reaching into WSL. Requests must hold the shared secret
+	// written to the control.secret file of the runtime directory, readable only by the daemon user, which is
+	// regenerated whenever the daemon starts. The ControlSocketPolicy rejects these requests, as they carry no peer
+	// credentials.
+	ControlTCPAddress types.AddrPort
+
+	// ControlSocketPolicy, if set, is applied to every request received over the unix socket, based on the
+	// credentials of the calling process. For instance, access.AllowUIDs(0) restricts the socket to the root user.
+	// It is required if the control socket is in the abstract namespace, as abstract sockets have no file permissions.
+	ControlSocketPolicy access.SocketPolicy
+
+	// Discovery announces the daemon over mDNS on the local network until it is bootstrapped or joins a cluster.
+	// The announced address is the PreInitListenAddress, which must be set.
+	Discovery bool
+
+	// Watchdog notifies systemd through sd_notify when the daemon is ready and when it stops. If the unit sets
+	// WatchdogSec, the systemd watchdog is also fed for as long as the control socket responds, the database can be
+	// queried and the state directory is writable, so that a wedged daemon is restarted.
+	Watchdog bool
+
+	// TLSPolicy, if set, customizes the TLS configuration of every API listener, for instance to raise the minimum
+	// TLS version, restrict the cipher suites or only accept client certificates of cluster members and trusted clients.
+	// It may also pin the certificates of other cluster members.
+	TLSPolicy *endpoints.TLSPolicy
+
+	// TrustStoreBackend, if set, stores the remotes of the truststore instead of the yaml files in the truststore
+	// directory. Recovering from a loss of quorum still operates on the truststore directory.
+	TrustStoreBackend trust.Backend
+
+	// DatabaseBackups takes periodic backups of the database while it's online, and removes the oldest backups
+	// beyond the retention count.
+	DatabaseBackups recover.BackupSchedule
+
+	// DatabaseMaintenance periodically vacuums or analyzes the database on the dqlite leader, to reclaim the space of
+	// deleted data and keep query plans accurate. A vacuum blocks writes to the database while it runs.
+	DatabaseMaintenance db.MaintenanceSchedule
+
+	// Etcd, if set, serves an etcd v3 compatible API backed by the database of the cluster, for components that only
+	// speak the etcd API. The etcd gRPC service itself is provided by the consumer, for instance with kine's server
+	// package, and is served once the daemon is initialized.
+	Etcd *kine.Options
+
+	// CertificateExpiryWarning is how long before its expiry a certificate loaded by the daemon is logged as expiring.
+	// It defaults to 30 days.
+	CertificateExpiryWarning time.Duration
+
+	// RenewSelfSignedCertificates regenerates the self-signed certificates that are about to expire.
+	// The cluster certificate is renewed on every cluster member by the dqlite leader. Once the daemon is initialized,
+	// the server certificate identifies the cluster member, so its renewed certificate is recorded in the database and
+	// trusted by every other cluster member before it is loaded.
+	RenewSelfSignedCertificates bool
+
+	// CoreAccessOverride replaces the access handlers of the actions of core API endpoints, keyed by the full path of
+	// the endpoint such as "core/control/tokens", so that consumers can enforce their own access control over the core API.
+	// Each override receives the access handler of the action, which it can wrap or replace.
+	CoreAccessOverride map[string]rest.AccessOverride
+
+	// PreparedQueries are named, parameterized database statements that clients can run through
+	// /core/1.0/queries/{name}, so that operator tooling can run reports without access to arbitrary SQL.
+	PreparedQueries []internalState.PreparedQuery
+
+	// MaxClockSkew is the largest difference between the clocks of cluster members that is tolerated. Systems whose
+	// clock differs by more are refused from joining, and cluster members whose clock drifts further are reported
+	// during heartbeats. It defaults to 10 seconds.
+	MaxClockSkew time.Duration
+
+	// JoinTokenFingerprintHash is the hash fingerprinting the cluster certificate in issued join tokens, so that
+	// joiners can verify they reach the cluster that issued the token. It must be SHA-256, SHA-384 or SHA-512, and
+	// defaults to SHA-256. Joiners running a version of MicroCluster that only supports SHA-256 reject tokens with
+	// another hash.
+	JoinTokenFingerprintHash crypto.Hash
+
+	// IdleTimeout, if set, stops the daemon once it has received no API request for that long, other than requests
+	// between cluster members, while the local cluster member is not the dqlite leader and no operation is running.
+	// Along with socket activation of the control socket by the service manager, this lets the daemon run on demand.
+	IdleTimeout time.Duration
+
+	// ReadOnlyAddress, if set, serves the core API and the extension servers that are part of it over a second
+	// listener, which only allows GET requests, for clients such as dashboards and monitoring agents that must not
+	// modify the cluster. Requests are authenticated like on the core API, but must be addressed to this listener, and
+	// the listener starts once the daemon is initialized.
+	ReadOnlyAddress types.AddrPort
 }
 
 // Daemon holds information for the microcluster daemon.
@@ -80,6 +254,8 @@ type Daemon struct {
 	project string // The project refers to the name of the go-project that is calling MicroCluster.
 	version string // The version of the go-project that is calling MicroCluster
 
+	startTime time.Time // When the daemon was created.
+
 	config *internalConfig.DaemonConfig // Local daemon's configuration from daemon.yaml file.
 
 	os         *sys.OS
@@ -91,10 +267,12 @@ type Daemon struct {
 	endpoints *endpoints.Endpoints
 	db        *db.DqliteDB
 
-	fsWatcher  *sys.Watcher
-	trustStore *trust.Store
+	fsWatcher         *sys.Watcher
+	trustStore        *trust.Store
+	trustStoreBackend trust.Backend // Stores the remotes of the truststore, if not the truststore directory.
 
-	hooks state.Hooks // Hooks to be called upon various daemon actions.
+	hooks        state.Hooks        // Hooks to be called upon various daemon actions.
+	hookTimeouts state.HookTimeouts // How long each hook may run.
 
 	ReadyChan      chan struct{}      // Closed when the daemon is fully ready.
 	shutdownCtx    context.Context    // Cancelled when shutdown starts.
@@ -109,19 +287,136 @@ type Daemon struct {
 	extensionServersMu sync.RWMutex
 	extensionServers   map[string]rest.Server
 
+	// acmeCancels stops the ACME certificate managers of extension servers, keyed by server name.
+	// It is guarded by extensionServersMu.
+	acmeCancels map[string]context.CancelFunc
+
 	drainConnectionsTimeout time.Duration
+
+	archiveEncryption recover.ArchiveEncryption
+
+	backupSchedule recover.BackupSchedule // Periodic database backups, if enabled.
+
+	dqliteOptions db.DqliteOptions
+
+	schemaNamespaces []update.Namespace
+
+	backfills     []update.Backfill // Data backfills registered by the consumer.
+	backfillsDone atomic.Bool       // Whether every data backfill is complete.
+
+	controlSocketPolicy access.SocketPolicy
+	controlTCPAddress   types.AddrPort // Loopback address of the control API over TCP, if enabled.
+	socketGroup         string         // Group of the control socket the daemon was started with.
+
+	initProgress *internalState.InitProgress // Stages reached while bootstrapping or joining a cluster.
+
+	auditLog *audit.Log // Audit log of mutating API requests, if enabled.
+
+	trustedClients      *trust.Clients     // Certificates of API clients trusted without being cluster members.
+	revokedCertificates *trust.Revocations // Certificates rejected even if they are otherwise trusted.
+
+	maintenance atomic.Bool // Whether the local cluster member is under maintenance.
+
+	tasks      *tasks.Scheduler    // Background tasks registered by the consumer.
+	operations *operations.Manager // Asynchronous operations started by API requests.
+
+	watchdog bool // Whether the service manager is notified of the daemon's state.
+
+	tableChangeID int64 // ID of the last change of a tracked table delivered to the OnTableChange hook, or -1.
+
+	etcd        *kine.Options // Etcd compatible API, if enabled.
+	etcdBackend *kine.Backend // Keys of the etcd compatible API, if enabled.
+
+	tlsPolicy *endpoints.TLSPolicy // Customizes the TLS configuration of the core API listener, if set.
+
+	certificateExpiryWarning    time.Duration // How long before expiry a certificate is logged as expiring.
+	renewSelfSignedCertificates bool          // Whether expiring self-signed certificates are regenerated.
+
+	coreAccessOverrides map[string]rest.AccessOverride // Access handlers of core API endpoints set by the consumer.
+
+	preparedQueries map[string]internalState.PreparedQuery // Database statements registered by the consumer.
+
+	maxClockSkew time.Duration // Largest tolerated clock skew between cluster members.
+
+	tokenFingerprintHash crypto.Hash // Hash fingerprinting the cluster certificate in issued join tokens.
+
+	idleTimeout    time.Duration // How long the daemon runs without API activity before stopping, if set.
+	lastActivity   atomic.Int64  // When the last API request ended, in nanoseconds since the epoch.
+	activeRequests atomic.Int64  // Number of API requests being served.
+	idleStopping   atomic.Bool   // Whether the daemon is stopping as it was idle.
+
+	readOnlyAddress types.AddrPort // Address of the read-only API, if enabled.
+
+	reservedListenerMu sync.Mutex
+	reservedListener   net.Listener // Listener bound to the address of a local-only cluster member until the API starts.
+
+	watchLeadershipOnce sync.Once // Starts watching for dqlite leadership changes once the API has started.
 }
 
 // NewDaemon initializes the Daemon context and channels.
 func NewDaemon(project string) *Daemon {
 	d := &Daemon{
-		shutdownDoneCh:   make(chan error),
-		ReadyChan:        make(chan struct{}),
-		extensionServers: make(map[string]rest.Server),
-		project:          project,
+		shutdownDoneCh:      make(chan error),
+		ReadyChan:           make(chan struct{}),
+		extensionServers:    make(map[string]rest.Server),
+		acmeCancels:         make(map[string]context.CancelFunc),
+		trustedClients:      &trust.Clients{},
+		revokedCertificates: &trust.Revocations{},
+		initProgress:        &internalState.InitProgress{},
+		project:             project,
+		startTime:           time.Now(),
+		tableChangeID:       -1,
 	}
 
 	d.stop = sync.OnceValue(func() error {
+		// Only run the shutdown hook and hand off leadership if the daemon finished starting up.
+		started := false
+		select {
+		case <-d.ReadyChan:
+			started = true
+		default:
+		}
+
+		if d.watchdog {
+			err := watchdog.Notify(watchdog.Stopping)
+			if err != nil {
+				logger.Error("Failed to notify the service manager of shutdown", logger.Ctx{"error": err})
+			}
+		}
+
+		if started {
+			err := d.hooks.OnShutdown(d.shutdownCtx, d.State())
+			if err != nil {
+				logger.Error("Failed to run pre-shutdown hook", logger.Ctx{"error": err})
+			}
+		}
+
+		// Hand off the database roles while the listeners are still up, as the other dqlite nodes reach the local one
+		// through them to complete the handover.
+		if started && d.db != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), handoverTimeout)
+			err := d.db.Handover(ctx)
+			cancel()
+			if err != nil {
+				logger.Error("Failed to hand off database roles", logger.Ctx{"error": err})
+			}
+		}
+
+		// Stop accepting API requests, and let in-flight requests over the network finish.
+		// The control socket is only closed, as the shutdown request itself may be in flight over it.
+		var endpointsErr error
+		if d.endpoints != nil {
+			endpointsErr = d.endpoints.Shutdown(endpoints.EndpointNetwork)
+			if endpointsErr != nil {
+				logger.Error("Failed to drain API servers", logger.Ctx{"error": endpointsErr})
+			}
+
+			err := d.endpoints.Down()
+			if err != nil && endpointsErr == nil {
+				endpointsErr = err
+			}
+		}
+
 		if d.shutdownCancel != nil {
 			d.shutdownCancel()
 		}
@@ -134,15 +429,8 @@ func NewDaemon(project string) *Daemon {
 			}
 		}
 
-		if d.endpoints != nil {
-			err := d.endpoints.Down()
-			if err != nil {
-				return err
-			}
-			err = d.endpoints.Shutdown()
-			if err != nil {
-				return nil
-			}
+		if endpointsErr != nil {
+			return endpointsErr
 		}
 
 		return dqliteErr
@@ -153,8 +441,10 @@ func NewDaemon(project string) *Daemon {
 
 // Run initializes the Daemon with the given configuration, starts the database,
 // and blocks until the daemon is cancelled.
-func (d *Daemon) Run(ctx context.Context, stateDir string, args Args) error {
+func (d *Daemon) Run(ctx context.Context, stateDir string, layout sys.Layout, args Args) error {
 	d.shutdownCtx, d.shutdownCancel = context.WithCancel(ctx)
+	d.tasks = tasks.NewScheduler(d.shutdownCtx, d.isLeader)
+	d.operations = operations.NewManager(d.shutdownCtx)
 	if stateDir == "" {
 		stateDir = os.Getenv(sys.StateDir)
 	}
@@ -168,7 +458,7 @@ func (d *Daemon) Run(ctx context.Context, stateDir string, args Args) error {
 		return fmt.Errorf("Failed to find state directory: %w", err)
 	}
 
-	d.os, err = sys.DefaultOS(stateDir, true)
+	d.os, err = sys.NewOS(stateDir, layout, true)
 	if err != nil {
 		return fmt.Errorf("Failed to initialize directory structure: %w", err)
 	}
@@ -183,6 +473,200 @@ func (d *Daemon) Run(ctx context.Context, stateDir string, args Args) error {
 
 	d.version = args.Version
 	d.drainConnectionsTimeout = args.DrainConnectionsTimeout
+	d.archiveEncryption = args.ArchiveEncryption
+
+	err = args.DqliteOptions.Validate()
+	if err != nil {
+		return fmt.Errorf("Invalid dqlite options: %w", err)
+	}
+
+	d.dqliteOptions = args.DqliteOptions
+
+	err = args.DatabaseBackups.Validate()
+	if err != nil {
+		return fmt.Errorf("Invalid database backup schedule: %w", err)
+	}
+
+	d.backupSchedule = args.DatabaseBackups
+	if d.backupSchedule.Interval > 0 {
+		err = d.tasks.Add(tasks.Task{
+			Name:     "database-backup",
+			Func:     d.backupDatabase,
+			Interval: d.backupSchedule.Interval,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to schedule database backups: %w", err)
+		}
+	}
+
+	err = args.DatabaseMaintenance.Validate()
+	if err != nil {
+		return fmt.Errorf("Invalid database maintenance schedule: %w", err)
+	}
+
+	if args.DatabaseMaintenance.Interval > 0 {
+		maintenance := args.DatabaseMaintenance.DatabaseMaintenance
+		err = d.tasks.Add(tasks.Task{
+			Name: "database-maintenance",
+			Func: func(ctx context.Context) error {
+				if d.db.Status() != types.DatabaseReady {
+					return nil
+				}
+
+				return d.db.Maintain(ctx, maintenance)
+			},
+			Interval: args.DatabaseMaintenance.Interval,
+			Scope:    types.TaskScopeLeader,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to schedule database maintenance: %w", err)
+		}
+	}
+
+	transportOptions := args.ClientTransportOptions
+	if args.TLSPolicy != nil && args.TLSPolicy.PinPeerCertificates {
+		transportOptions.PinPeerCertificates = true
+	}
+
+	if transportOptions.Resolver == nil {
+		transportOptions.Resolver = remoteResolver{d: d}
+	}
+
+	err = internalClient.SetTransportOptions(transportOptions)
+	if err != nil {
+		return fmt.Errorf("Invalid client transport options: %w", err)
+	}
+
+	err = update.ValidateNamespaces(args.SchemaNamespaces)
+	if err != nil {
+		return fmt.Errorf("Invalid schema namespaces: %w", err)
+	}
+
+	d.schemaNamespaces = args.SchemaNamespaces
+
+	err = update.ValidateBackfills(args.DatabaseBackfills)
+	if err != nil {
+		return fmt.Errorf("Invalid data backfills: %w", err)
+	}
+
+	d.backfills = args.DatabaseBackfills
+
+	hooks := args.Hooks
+	if len(args.HookHandlers) > 0 {
+		if hooks != nil {
+			return fmt.Errorf("Hooks and hook handlers cannot both be set")
+		}
+
+		hooks, err = internalState.MergeHooks(args.HookHandlers...)
+		if err != nil {
+			return fmt.Errorf("Invalid hook handlers: %w", err)
+		}
+	}
+
+	err = args.HookTimeouts.Validate()
+	if err != nil {
+		return fmt.Errorf("Invalid hook timeouts: %w", err)
+	}
+
+	d.hookTimeouts = args.HookTimeouts
+
+	// Any process in the network namespace can connect to an abstract socket, so its callers must be restricted.
+	if sys.IsAbstractSocket(d.os.ControlSocketPath()) && args.ControlSocketPolicy == nil {
+		return fmt.Errorf("Abstract control socket %q requires a control socket policy, such as access.AllowUIDs", d.os.ControlSocketPath())
+	}
+
+	d.controlSocketPolicy = args.ControlSocketPolicy
+
+	if args.ControlTCPAddress != (types.AddrPort{}) {
+		if !args.ControlTCPAddress.Addr().IsLoopback() || args.ControlTCPAddress.Port() == 0 {
+			return fmt.Errorf("Control TCP address %q must be a loopback address with a port", args.ControlTCPAddress)
+		}
+
+		d.controlTCPAddress = args.ControlTCPAddress
+	}
+
+	d.watchdog = args.Watchdog
+	d.trustStoreBackend = args.TrustStoreBackend
+
+	if args.TLSPolicy != nil {
+		err = args.TLSPolicy.Validate()
+		if err != nil {
+			return fmt.Errorf("Invalid TLS policy: %w", err)
+		}
+
+		d.tlsPolicy = args.TLSPolicy
+	}
+
+	if args.Etcd != nil {
+		err = args.Etcd.Validate()
+		if err != nil {
+			return fmt.Errorf("Invalid etcd API options: %w", err)
+		}
+
+		d.etcd = args.Etcd
+	}
+
+	if args.CertificateExpiryWarning < 0 {
+		return fmt.Errorf("Certificate expiry warning cannot be negative")
+	}
+
+	d.certificateExpiryWarning = args.CertificateExpiryWarning
+	if d.certificateExpiryWarning == 0 {
+		d.certificateExpiryWarning = defaultCertificateExpiryWarning
+	}
+
+	d.renewSelfSignedCertificates = args.RenewSelfSignedCertificates
+
+	err = resources.ValidateAccessOverrides(args.CoreAccessOverride)
+	if err != nil {
+		return fmt.Errorf("Invalid core API access overrides: %w", err)
+	}
+
+	d.coreAccessOverrides = args.CoreAccessOverride
+
+	d.preparedQueries = make(map[string]internalState.PreparedQuery, len(args.PreparedQueries))
+	for _, query := range args.PreparedQueries {
+		err = query.Validate()
+		if err != nil {
+			return err
+		}
+
+		_, ok := d.preparedQueries[query.Name]
+		if ok {
+			return fmt.Errorf("Duplicate prepared query %q", query.Name)
+		}
+
+		d.preparedQueries[query.Name] = query
+	}
+
+	if args.MaxClockSkew < 0 {
+		return fmt.Errorf("Maximum clock skew cannot be negative")
+	}
+
+	d.maxClockSkew = args.MaxClockSkew
+	if d.maxClockSkew == 0 {
+		d.maxClockSkew = defaultMaxClockSkew
+	}
+
+	d.tokenFingerprintHash = args.JoinTokenFingerprintHash
+	if d.tokenFingerprintHash != 0 && !slices.Contains(internalTypes.TokenFingerprintHashes, d.tokenFingerprintHash) {
+		return fmt.Errorf("Unsupported join token fingerprint hash %q", d.tokenFingerprintHash)
+	}
+
+	if args.IdleTimeout < 0 {
+		return fmt.Errorf("Idle timeout cannot be negative")
+	}
+
+	d.idleTimeout = args.IdleTimeout
+	d.readOnlyAddress = args.ReadOnlyAddress
+	d.lastActivity.Store(time.Now().UnixNano())
+
+	if args.AuditLog.Enabled {
+		d.auditLog, err = audit.Open(d.os.AuditLogPath(), args.AuditLog)
+		if err != nil {
+			return fmt.Errorf("Failed to open audit log: %w", err)
+		}
+	}
 
 	// Setup the deamon's internal config.
 	d.config = internalConfig.NewDaemonConfig(filepath.Join(d.os.StateDir, "daemon.yaml"))
@@ -197,7 +681,7 @@ func (d *Daemon) Run(ctx context.Context, stateDir string, args Args) error {
 		}
 	})
 
-	err = recover.MaybeUnpackRecoveryTarball(d.os)
+	err = recover.MaybeUnpackRecoveryTarball(d.os, d.archiveEncryption)
 	if err != nil {
 		return fmt.Errorf("Database recovery failed: %w", err)
 	}
@@ -211,8 +695,8 @@ func (d *Daemon) Run(ctx context.Context, stateDir string, args Args) error {
 			return fmt.Errorf("Server name %q is not a valid FQDN: %w", k, err)
 		}
 
-		// `core` and `unix` are reserved server names.
-		if shared.ValueInSlice(k, []string{endpoints.EndpointsCore, endpoints.EndpointsUnix}) {
+		// `core`, `unix` and `etcd` are reserved server names.
+		if shared.ValueInSlice(k, []string{endpoints.EndpointsCore, endpoints.EndpointsUnix, endpoints.EndpointsEtcd, endpoints.EndpointsReadOnly}) {
 			return fmt.Errorf("Cannot use the reserved server name %q", k)
 		}
 
@@ -221,11 +705,77 @@ func (d *Daemon) Run(ctx context.Context, stateDir string, args Args) error {
 
 	d.extensionServersMu.Unlock()
 
-	err = d.init(args.PreInitListenAddress, args.SocketGroup, args.HeartbeatInterval, args.ExtensionsSchema, args.APIExtensions, args.Hooks)
+	err = d.init(args.PreInitListenAddress, args.SocketGroup, args.HeartbeatInterval, args.ExtensionsSchema, args.ExtensionsSchemaRollback, args.APIExtensions, hooks)
 	if err != nil {
 		return fmt.Errorf("Daemon failed to start: %w", err)
 	}
 
+	if d.hooks.OnTableChange != nil {
+		err = d.tasks.Add(tasks.Task{
+			Name:     "table-changes",
+			Func:     d.deliverTableChanges,
+			Interval: tableChangesInterval,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to schedule delivery of table changes: %w", err)
+		}
+	}
+
+	err = d.tasks.Add(tasks.Task{
+		Name:     "table-changes-prune",
+		Func:     d.pruneTableChanges,
+		Interval: time.Minute,
+		Scope:    types.TaskScopeLeader,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to schedule pruning of table changes: %w", err)
+	}
+
+	err = d.tasks.Add(tasks.Task{
+		Name:     "certificate-expiry",
+		Func:     d.checkCertificateExpiry,
+		Interval: certificateExpiryInterval,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to schedule certificate expiry checks: %w", err)
+	}
+
+	if len(d.backfills) > 0 {
+		err = d.tasks.Add(tasks.Task{
+			Name:     "database-backfills",
+			Func:     d.runBackfills,
+			Interval: backfillsInterval,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to schedule data backfills: %w", err)
+		}
+	}
+
+	if d.idleTimeout > 0 {
+		err = d.tasks.Add(tasks.Task{
+			Name:     "idle-shutdown",
+			Func:     d.stopIfIdle,
+			Interval: min(d.idleTimeout, idleCheckInterval),
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to schedule idle shutdown: %w", err)
+		}
+	}
+
+	if d.etcd != nil {
+		err = d.scheduleEtcdTasks()
+		if err != nil {
+			return err
+		}
+	}
+
+	if args.Discovery {
+		err = d.startDiscovery()
+		if err != nil {
+			return fmt.Errorf("Failed to start discovery: %w", err)
+		}
+	}
+
 	err = d.hooks.OnStart(d.shutdownCtx, d.State())
 	if err != nil {
 		return fmt.Errorf("Failed to run post-start hook: %w", err)
@@ -233,6 +783,13 @@ func (d *Daemon) Run(ctx context.Context, stateDir string, args Args) error {
 
 	close(d.ReadyChan)
 
+	if d.watchdog {
+		err = d.startWatchdog()
+		if err != nil {
+			return fmt.Errorf("Failed to start watchdog: %w", err)
+		}
+	}
+
 	reverter.Success()
 
 	for {
@@ -245,7 +802,7 @@ func (d *Daemon) Run(ctx context.Context, stateDir string, args Args) error {
 	}
 }
 
-func (d *Daemon) init(listenAddress string, socketGroup string, heartbeatInterval time.Duration, schemaExtensions []schema.Update, apiExtensions []string, hooks *state.Hooks) error {
+func (d *Daemon) init(listenAddress string, socketGroup string, heartbeatInterval time.Duration, schemaExtensions []schema.Update, schemaRollbacks []schema.Update, apiExtensions []string, hooks *state.Hooks) error {
 	d.applyHooks(hooks)
 
 	var err error
@@ -279,6 +836,18 @@ func (d *Daemon) init(listenAddress string, socketGroup string, heartbeatInterva
 	}
 
 	d.db = db.NewDB(d.shutdownCtx, d.ServerCert, d.ClusterCert, d.Name, d.os, heartbeatInterval)
+	d.db.SetDqliteOptions(d.dqliteOptions)
+	d.db.SetUpgradeHook(func(stage types.UpgradeStage) {
+		err := d.hooks.OnUpgradeStage(d.shutdownCtx, d.State(), stage)
+		if err != nil {
+			logger.Error("Failed to run upgrade stage hook", logger.Ctx{"stage": stage, "error": err})
+		}
+	})
+
+	listenAddress, err = d.loadPreInitConfig(listenAddress)
+	if err != nil {
+		return err
+	}
 
 	listenAddr := api.NewURL()
 	if listenAddress != "" {
@@ -300,11 +869,11 @@ func (d *Daemon) init(listenAddress string, socketGroup string, heartbeatInterva
 
 	d.extensionServersMu.RUnlock()
 
-	serverEndpoints := []rest.Resources{
+	serverEndpoints := d.coreResources(append([]rest.Resources{
 		resources.UnixEndpoints,
 		resources.InternalEndpoints,
-		resources.PublicEndpoints,
-	}
+		resources.CoreEndpoints,
+	}, resources.PublicEndpointVersions...)...)
 
 	d.extensionServersMu.RLock()
 	for _, server := range d.extensionServers {
@@ -315,13 +884,14 @@ func (d *Daemon) init(listenAddress string, socketGroup string, heartbeatInterva
 
 	d.extensionServersMu.RUnlock()
 
-	err = d.startUnixServer(serverEndpoints, socketGroup)
+	d.socketGroup = socketGroup
+	err = d.startUnixServer(serverEndpoints)
 	if err != nil {
 		return err
 	}
 
 	if listenAddress != "" {
-		serverEndpoints = []rest.Resources{resources.PublicEndpoints}
+		serverEndpoints = d.coreResources(append([]rest.Resources{resources.CoreEndpoints}, resources.PublicEndpointVersions...)...)
 		err = d.addCoreServers(true, *listenAddr, d.ServerCert(), serverEndpoints)
 		if err != nil {
 			return err
@@ -334,7 +904,7 @@ func (d *Daemon) init(listenAddress string, socketGroup string, heartbeatInterva
 		return err
 	}
 
-	d.db.SetSchema(schemaExtensions, d.Extensions)
+	d.db.SetSchema(schemaExtensions, schemaRollbacks, d.schemaNamespaces, d.Extensions)
 
 	err = d.reloadIfBootstrapped()
 	if err != nil {
@@ -349,63 +919,264 @@ func (d *Daemon) init(listenAddress string, socketGroup string, heartbeatInterva
 	return nil
 }
 
-func (d *Daemon) applyHooks(hooks *state.Hooks) {
-	// Apply a no-op hooks for any missing hooks.
-	noOpHook := func(ctx context.Context, s state.State) error { return nil }
-	noOpRemoveHook := func(ctx context.Context, s state.State, force bool) error { return nil }
-	noOpInitHook := func(ctx context.Context, s state.State, initConfig map[string]string) error { return nil }
-	noOpGenericInitHook := func(ctx context.Context, s state.State, bootstrap bool, initConfig map[string]string) error {
-		return nil
+// startDiscovery announces the daemon over mDNS for as long as it is not part of a cluster.
+func (d *Daemon) startDiscovery() error {
+	if d.config.GetAddress() == (types.AddrPort{}) {
+		return fmt.Errorf("Discovery requires a pre-init listen address")
 	}
-	noOpConfigHook := func(ctx context.Context, s state.State, config types.DaemonConfig) error { return nil }
-	noOpNewMemberHook := func(ctx context.Context, s state.State, newMember types.ClusterMemberLocal) error { return nil }
-	noOpHeartbeatHook := func(ctx context.Context, s state.State, roleStatus map[string]types.RoleStatus) error { return nil }
 
-	if hooks == nil {
-		d.hooks = state.Hooks{}
-	} else {
-		d.hooks = *hooks
-	}
+	announcer := discovery.NewAnnouncer(d.project, func() (types.DiscoveredPeer, bool) {
+		if d.db.Status() != types.DatabaseNotReady {
+			return types.DiscoveredPeer{}, false
+		}
 
-	if d.hooks.PreInit == nil {
-		d.hooks.PreInit = noOpGenericInitHook
-	}
+		return types.DiscoveredPeer{
+			Name:        d.Name(),
+			Address:     d.config.GetAddress(),
+			Fingerprint: d.ServerCert().Fingerprint(),
+			Version:     d.Version(),
+		}, true
+	})
 
-	if d.hooks.PostBootstrap == nil {
-		d.hooks.PostBootstrap = noOpInitHook
-	}
+	go func() {
+		err := announcer.Run(d.shutdownCtx)
+		if err != nil {
+			logger.Error("Stopped announcing daemon over mDNS", logger.Ctx{"error": err})
+		}
+	}()
 
-	if d.hooks.PostJoin == nil {
-		d.hooks.PostJoin = noOpInitHook
-	}
+	return nil
+}
 
-	if d.hooks.PreJoin == nil {
-		d.hooks.PreJoin = noOpInitHook
+// startWatchdog notifies the service manager that the daemon is ready, and feeds its watchdog while the daemon is
+// healthy, if the watchdog is enabled.
+func (d *Daemon) startWatchdog() error {
+	interval, err := watchdog.Interval()
+	if err != nil {
+		return err
 	}
 
-	if d.hooks.OnStart == nil {
-		d.hooks.OnStart = noOpHook
+	err = watchdog.Notify(watchdog.Ready)
+	if err != nil {
+		return err
 	}
 
-	if d.hooks.OnHeartbeat == nil {
-		d.hooks.OnHeartbeat = noOpHeartbeatHook
+	if interval == 0 {
+		return nil
 	}
 
-	if d.hooks.OnNewMember == nil {
-		d.hooks.OnNewMember = noOpNewMemberHook
+	checks := []watchdog.Check{
+		{Name: "control socket", Run: d.checkControlSocket},
+		{Name: "database", Run: d.checkDatabase},
+		{Name: "state directory", Run: d.checkStateDir},
 	}
 
-	if d.hooks.PreRemove == nil {
+	go watchdog.Run(d.shutdownCtx, interval, checks)
+
+	return nil
+}
+
+// checkControlSocket checks that the daemon responds to requests over the control socket.
+func (d *Daemon) checkControlSocket(ctx context.Context) error {
+	c, err := internalClient.New(d.os.ControlSocket(), nil, nil, false)
+	if err != nil {
+		return err
+	}
+
+	return c.CheckDaemonReady(ctx)
+}
+
+// checkDatabase checks that the database can be queried, if it is open.
+// A database that is not yet initialized, or waiting for other cluster members, is not considered unhealthy.
+func (d *Daemon) checkDatabase(ctx context.Context) error {
+	if d.db.Status() != types.DatabaseReady {
+		return nil
+	}
+
+	return d.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "SELECT 1")
+
+		return err
+	})
+}
+
+// checkStateDir checks that files can be written to the state directory.
+func (d *Daemon) checkStateDir(ctx context.Context) error {
+	f, err := os.CreateTemp(d.os.StateDir, ".watchdog-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.WriteString("ok")
+	if err == nil {
+		err = f.Sync()
+	}
+
+	return errors.Join(err, f.Close())
+}
+
+func (d *Daemon) applyHooks(hooks *state.Hooks) {
+	// Apply a no-op hooks for any missing hooks.
+	noOpHook := func(ctx context.Context, s state.State) error { return nil }
+	noOpRemoveHook := func(ctx context.Context, s state.State, force bool) error { return nil }
+	noOpRemovePeerHook := func(ctx context.Context, s state.State, member types.ClusterMemberLocal, force bool) error {
+		return nil
+	}
+	noOpInitHook := func(ctx context.Context, s state.State, initConfig map[string]string) error { return nil }
+	noOpGenericInitHook := func(ctx context.Context, s state.State, bootstrap bool, initConfig map[string]string) error {
+		return nil
+	}
+	noOpConfigHook := func(ctx context.Context, s state.State, config types.DaemonConfig) error { return nil }
+	noOpNewMemberHook := func(ctx context.Context, s state.State, newMember types.ClusterMemberLocal) error { return nil }
+	noOpJoinRequestHook := func(ctx context.Context, s state.State, joiner types.ClusterMemberLocal, initConfig map[string]string) error {
+		return nil
+	}
+
+	noOpHeartbeatHook := func(ctx context.Context, s state.State, roleStatus map[string]types.RoleStatus) error { return nil }
+	noOpClusterHealthHook := func(ctx context.Context, s state.State, health types.ClusterHealth) error { return nil }
+	noOpRenameHook := func(ctx context.Context, s state.State, oldName string, newName string) error { return nil }
+	noOpUpgradeHook := func(ctx context.Context, s state.State, stage types.UpgradeStage) error { return nil }
+	noOpLeadershipHook := func(ctx context.Context, s state.State, isLeader bool, leaderName string, leaderAddress types.AddrPort) error {
+		return nil
+	}
+
+	noOpCertificateHook := func(ctx context.Context, s state.State, name types.CertificateName, fingerprint string) error {
+		return nil
+	}
+
+	if hooks == nil {
+		d.hooks = state.Hooks{}
+	} else {
+		d.hooks = *hooks
+	}
+
+	if d.hooks.PreInit == nil {
+		d.hooks.PreInit = noOpGenericInitHook
+	}
+
+	if d.hooks.PostBootstrap == nil {
+		d.hooks.PostBootstrap = noOpInitHook
+	}
+
+	if d.hooks.PostJoin == nil {
+		d.hooks.PostJoin = noOpInitHook
+	}
+
+	if d.hooks.PreJoin == nil {
+		d.hooks.PreJoin = noOpInitHook
+	}
+
+	if d.hooks.OnJoinRequest == nil {
+		d.hooks.OnJoinRequest = noOpJoinRequestHook
+	}
+
+	if d.hooks.OnStart == nil {
+		d.hooks.OnStart = noOpHook
+	}
+
+	if d.hooks.OnShutdown == nil {
+		d.hooks.OnShutdown = noOpHook
+	}
+
+	if d.hooks.OnHeartbeat == nil {
+		d.hooks.OnHeartbeat = noOpHeartbeatHook
+	}
+
+	if d.hooks.OnClusterHealth == nil {
+		d.hooks.OnClusterHealth = noOpClusterHealthHook
+	}
+
+	if d.hooks.OnNewMember == nil {
+		d.hooks.OnNewMember = noOpNewMemberHook
+	}
+
+	if d.hooks.PreRemove == nil {
 		d.hooks.PreRemove = noOpRemoveHook
 	}
 
+	if d.hooks.PreRemovePeer == nil {
+		d.hooks.PreRemovePeer = noOpRemovePeerHook
+	}
+
 	if d.hooks.PostRemove == nil {
 		d.hooks.PostRemove = noOpRemoveHook
 	}
 
+	if d.hooks.OnMemberRemoved == nil {
+		d.hooks.OnMemberRemoved = noOpRemovePeerHook
+	}
+
+	if d.hooks.OnMemberRename == nil {
+		d.hooks.OnMemberRename = noOpRenameHook
+	}
+
 	if d.hooks.OnDaemonConfigUpdate == nil {
 		d.hooks.OnDaemonConfigUpdate = noOpConfigHook
 	}
+
+	if d.hooks.OnUpgradeStage == nil {
+		d.hooks.OnUpgradeStage = noOpUpgradeHook
+	}
+
+	if d.hooks.OnDqliteLeadershipChange == nil {
+		d.hooks.OnDqliteLeadershipChange = noOpLeadershipHook
+	}
+
+	if d.hooks.OnCertificateRotated == nil {
+		d.hooks.OnCertificateRotated = noOpCertificateHook
+	}
+
+	d.hooks = d.hooks.WithTimeouts(d.hookTimeouts)
+}
+
+// loadPreInitConfig replays the daemon configuration persisted before the daemon was initialized, so that it is not
+// lost on restart. If a pre-init listen address is given, it is persisted. Otherwise the persisted address is returned.
+// Once the daemon is initialized, the configuration is loaded by reloadIfBootstrapped instead.
+func (d *Daemon) loadPreInitConfig(listenAddress string) (string, error) {
+	_, err := os.Stat(filepath.Join(d.os.DatabaseDir, "info.yaml"))
+	if err == nil {
+		return listenAddress, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	_, err = os.Stat(filepath.Join(d.os.StateDir, "daemon.yaml"))
+	if err == nil {
+		err = d.config.Load()
+		if err != nil {
+			return "", fmt.Errorf("Failed to retrieve pre-init daemon configuration yaml: %w", err)
+		}
+
+		d.db.SetHeartbeatConfig(d.config.GetHeartbeat())
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if listenAddress == "" {
+		address := d.config.GetAddress()
+		if address.IsValid() {
+			logger.Info("Using pre-init listen address from daemon configuration", logger.Ctx{"address": address.String()})
+			listenAddress = address.String()
+		}
+
+		return listenAddress, nil
+	}
+
+	addrPort, err := types.ParseAddrPort(listenAddress)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse initial listen address: %w", err)
+	}
+
+	d.config.SetAddress(addrPort)
+
+	err = d.config.Write()
+	if err != nil {
+		return "", err
+	}
+
+	return listenAddress, nil
 }
 
 func (d *Daemon) reloadIfBootstrapped() error {
@@ -434,6 +1205,18 @@ func (d *Daemon) reloadIfBootstrapped() error {
 		return fmt.Errorf("Failed to retrieve daemon configuration yaml: %w", err)
 	}
 
+	// Apply any heartbeat settings changed at runtime before the database is started.
+	d.db.SetHeartbeatConfig(d.config.GetHeartbeat())
+
+	// The control socket is started before the configuration is loaded, so apply any socket settings changed at runtime.
+	// Settings that no longer apply, such as after switching to an abstract socket, are ignored.
+	if d.config.GetSocket() != (types.SocketConfig{}) {
+		err = d.SetSocketAccess(d.config.GetSocket())
+		if err != nil {
+			logger.Warn("Failed to apply control socket access from daemon configuration", logger.Ctx{"error": err})
+		}
+	}
+
 	err = d.StartAPI(d.shutdownCtx, false, nil)
 	if err != nil {
 		return err
@@ -449,7 +1232,12 @@ func (d *Daemon) initStore() error {
 		return err
 	}
 
-	d.trustStore, err = trust.Init(d.fsWatcher, nil, d.os.TrustDir)
+	backend := d.trustStoreBackend
+	if backend == nil {
+		backend = trust.NewFileBackend(d.os.TrustDir, d.fsWatcher)
+	}
+
+	d.trustStore, err = trust.Init(backend, nil)
 	if err != nil {
 		return err
 	}
@@ -497,20 +1285,142 @@ func (d *Daemon) initServer(resources ...rest.Resources) *http.Server {
 	})
 
 	return &http.Server{
-		Handler:     mux,
+		Handler:     d.trackActivity(mux),
 		ConnContext: request.SaveConnectionInContext,
 	}
 }
 
+// trackActivity records the API requests served by the handler, so that the daemon can stop once it is idle.
+// Requests between cluster members, such as heartbeats, are not considered activity.
+func (d *Daemon) trackActivity(handler http.Handler) http.Handler {
+	internalPrefix := "/" + string(internalTypes.InternalEndpoint) + "/"
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.idleTimeout == 0 || strings.HasPrefix(r.URL.Path, internalPrefix) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		d.activeRequests.Add(1)
+		defer func() {
+			d.lastActivity.Store(time.Now().UnixNano())
+			d.activeRequests.Add(-1)
+		}()
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// stopIfIdle stops the daemon if it has received no API request for its idle timeout, unless the local cluster member
+// is the dqlite leader or an operation is running.
+func (d *Daemon) stopIfIdle(ctx context.Context) error {
+	if d.activeRequests.Load() > 0 || time.Since(time.Unix(0, d.lastActivity.Load())) < d.idleTimeout {
+		return nil
+	}
+
+	for _, op := range d.operations.List() {
+		if op.Status == types.OperationRunning {
+			return nil
+		}
+	}
+
+	// Cluster members that are not initialized yet are never the leader.
+	if d.db.IsOpen(ctx) == nil {
+		leader, err := d.isLeader(ctx)
+		if err != nil {
+			return err
+		}
+
+		if leader {
+			return nil
+		}
+	}
+
+	if !d.idleStopping.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	logger.Info("Stopping idle daemon", logger.Ctx{"timeout": d.idleTimeout})
+
+	// Stopping the daemon cancels the context of its tasks, including this one.
+	go func() {
+		d.shutdownDoneCh <- d.stop()
+	}()
+
+	return nil
+}
+
+// remoteResolver finds the cluster members that moved to the address their DNS name resolves to, through the
+// truststore once it is initialized. Cluster members keep the address they were recorded with in the database and
+// in the dqlite raft configuration, which cannot be changed in place, and the dqlite node store is refreshed from the
+// raft configuration. So dqlite traffic is redirected when it is dialed, like any other connection.
+type remoteResolver struct {
+	d *Daemon
+}
+
+// Current returns the address at which the cluster member recorded with the given address was last reached.
+func (r remoteResolver) Current(address string) string {
+	if r.d.trustStore == nil {
+		return address
+	}
+
+	return r.d.trustStore.Remotes().Current(address)
+}
+
+// Resolve returns the address that the DNS name of the cluster member recorded with the given address currently
+// resolves to.
+func (r remoteResolver) Resolve(ctx context.Context, address string) (string, error) {
+	if r.d.trustStore == nil {
+		return "", fmt.Errorf("Truststore is not initialized")
+	}
+
+	return r.d.trustStore.Remotes().Resolve(ctx, address)
+}
+
+// Resolved records in the truststore that the cluster member recorded with the given address moved to the resolved
+// address.
+func (r remoteResolver) Resolved(address string, resolved string) {
+	if r.d.trustStore == nil {
+		return
+	}
+
+	r.d.trustStore.Remotes().Resolved(address, resolved)
+}
+
+// coreResources returns the given core API resources, with the access overrides of the consumer applied.
+func (d *Daemon) coreResources(coreResources ...rest.Resources) []rest.Resources {
+	overridden := make([]rest.Resources, 0, len(coreResources))
+	for _, r := range coreResources {
+		overridden = append(overridden, resources.OverrideAccess(r, d.coreAccessOverrides))
+	}
+
+	return overridden
+}
+
 // setConfig applies and commits to memory the supplied daemon configuration.
-func (d *Daemon) setConfig(newConfig trust.Location) error {
+func (d *Daemon) setConfig(newConfig trust.Location, listenAddress types.AddrPort) error {
 	d.config.SetAddress(newConfig.Address)
+	d.config.SetListenAddress(listenAddress)
 	d.config.SetName(newConfig.Name)
+	d.config.SetDNSName(newConfig.DNSName)
 
 	// Write the latest config to disk.
 	return d.config.Write()
 }
 
+// reserveListener keeps the listener bound to the address of the cluster member until the API starts, so that no
+// other process can bind the address in the meantime. A previously reserved listener is closed.
+func (d *Daemon) reserveListener(listener net.Listener) {
+	d.reservedListenerMu.Lock()
+	defer d.reservedListenerMu.Unlock()
+
+	if d.reservedListener != nil {
+		_ = d.reservedListener.Close()
+	}
+
+	d.reservedListener = listener
+}
+
 // StartAPI starts up the admin and consumer APIs, and generates a cluster cert
 // if we are bootstrapping the first node.
 func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[string]string, joinAddresses ...string) error {
@@ -523,18 +1433,20 @@ func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[st
 		return fmt.Errorf("Failed to parse server certificate when bootstrapping API: %w", err)
 	}
 
+	d.watchLeadershipOnce.Do(func() { go d.watchLeadership(d.shutdownCtx) })
+
 	addrPort, err := types.ParseAddrPort(d.Address().URL.Host)
 	if err != nil {
 		return fmt.Errorf("Failed to parse listen address when bootstrapping API: %w", err)
 	}
 
 	localNode := trust.Remote{
-		Location:    trust.Location{Name: d.config.GetName(), Address: addrPort},
+		Location:    trust.Location{Name: d.config.GetName(), Address: addrPort, DNSName: d.config.GetDNSName()},
 		Certificate: types.X509Certificate{Certificate: serverCert},
 	}
 
 	if bootstrap {
-		err = d.trustStore.Remotes().Add(d.os.TrustDir, localNode)
+		err = d.trustStore.Remotes().Add(localNode)
 		if err != nil {
 			return fmt.Errorf("Failed to initialize local remote entry: %w", err)
 		}
@@ -547,7 +1459,7 @@ func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[st
 
 	// Validate the extension servers again now that we have applied addresses.
 	d.extensionServersMu.RLock()
-	err = resources.ValidateEndpoints(d.extensionServers, d.Address().URL.Host)
+	err = resources.ValidateEndpoints(d.extensionServers, d.listenAddress().URL.Host)
 	if err != nil {
 		return err
 	}
@@ -559,18 +1471,32 @@ func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[st
 		return err
 	}
 
-	serverEndpoints := []rest.Resources{resources.InternalEndpoints, resources.PublicEndpoints}
-	err = d.addCoreServers(false, *d.Address(), d.ClusterCert(), serverEndpoints)
+	serverEndpoints := d.coreResources(append([]rest.Resources{resources.InternalEndpoints, resources.CoreEndpoints}, resources.PublicEndpointVersions...)...)
+	err = d.addCoreServers(false, *d.listenAddress(), d.ClusterCert(), serverEndpoints)
 	if err != nil {
 		return err
 	}
 
 	// Add extension servers before post-join hook.
-	err = d.addExtensionServers(false, d.ClusterCert(), d.Address().URL.Host)
+	err = d.addExtensionServers(false, d.ClusterCert(), d.listenAddress().URL.Host)
 	if err != nil {
 		return err
 	}
 
+	if d.etcd != nil {
+		err = d.startEtcd()
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.readOnlyAddress != (types.AddrPort{}) {
+		err = d.startReadOnlyAPI()
+		if err != nil {
+			return err
+		}
+	}
+
 	// If bootstrapping the first node, just open the database and create an entry for ourselves.
 	if bootstrap {
 		clusterMember := cluster.CoreClusterMember{
@@ -583,16 +1509,27 @@ func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[st
 
 		clusterMember.SchemaInternal, clusterMember.SchemaExternal, _ = d.db.Schema().Version()
 
+		d.initProgress.Report(types.InitStageDatabase, "Creating the database")
 		err = d.db.Bootstrap(d.Extensions, d.project, *d.Address(), clusterMember)
 		if err != nil {
 			return err
 		}
 
+		if localNode.DNSName != "" {
+			err = d.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+				return cluster.UpdateCoreClusterMemberDNSName(ctx, tx, localNode.Name, localNode.DNSName)
+			})
+			if err != nil {
+				return fmt.Errorf("Failed to record DNS name: %w", err)
+			}
+		}
+
 		err = d.trustStore.Refresh()
 		if err != nil {
 			return err
 		}
 
+		d.initProgress.Report(types.InitStageHooks, "Running post-bootstrap hook")
 		ctx, cancel := context.WithCancel(ctx)
 		err = d.hooks.PostBootstrap(ctx, d.State(), initConfig)
 		cancel()
@@ -605,6 +1542,7 @@ func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[st
 	}
 
 	if len(joinAddresses) != 0 {
+		d.initProgress.Report(types.InitStageDatabase, "Joining the database and checking its schema version")
 		err = d.db.Join(d.Extensions, d.project, *d.Address(), joinAddresses...)
 		if err != nil {
 			return fmt.Errorf("Failed to join cluster: %w", err)
@@ -621,6 +1559,21 @@ func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[st
 		return err
 	}
 
+	err = resources.ReloadTrustedClients(ctx, d.State())
+	if err != nil {
+		logger.Warn("Failed to load trusted client certificates", logger.Ctx{"error": err})
+	}
+
+	err = resources.ReloadCertificateRevocations(ctx, d.State())
+	if err != nil {
+		logger.Warn("Failed to load certificate revocations", logger.Ctx{"error": err})
+	}
+
+	err = resources.ReloadMaintenance(ctx, d.State())
+	if err != nil {
+		logger.Warn("Failed to load maintenance status", logger.Ctx{"error": err})
+	}
+
 	// Get a client for every other cluster member in the newly refreshed local store.
 	publicKey, err := d.ClusterCert().PublicKeyX509()
 	if err != nil {
@@ -632,8 +1585,9 @@ func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[st
 		return err
 	}
 
-	localMemberInfo := types.ClusterMemberLocal{Name: localNode.Name, Address: localNode.Address, Certificate: localNode.Certificate}
+	localMemberInfo := types.ClusterMemberLocal{Name: localNode.Name, Address: localNode.Address, Certificate: localNode.Certificate, DNSName: localNode.DNSName}
 	if len(joinAddresses) > 0 {
+		d.initProgress.Report(types.InitStageHooks, "Running join hooks and notifying cluster members")
 		ctx, cancel := context.WithCancel(ctx)
 		err = d.hooks.PreJoin(ctx, d.State(), initConfig)
 		cancel()
@@ -793,7 +1747,7 @@ func (d *Daemon) UpdateServers() error {
 
 	// Start any additional listener.
 	// This operation is idempotent.
-	err := d.addExtensionServers(false, d.ClusterCert(), d.Address().URL.Host)
+	err := d.addExtensionServers(false, d.ClusterCert(), d.listenAddress().URL.Host)
 	if err != nil {
 		return err
 	}
@@ -801,53 +1755,293 @@ func (d *Daemon) UpdateServers() error {
 	return nil
 }
 
-// startUnixServer starts up the core unix listener with the given resources.
-func (d *Daemon) startUnixServer(serverEndpoints []rest.Resources, socketGroup string) error {
-	ctlServer := d.initServer(serverEndpoints...)
-	ctl := endpoints.NewSocket(d.shutdownCtx, ctlServer, d.os.ControlSocket(), socketGroup, d.drainConnectionsTimeout)
-	d.endpoints = endpoints.NewEndpoints(d.shutdownCtx, map[string]endpoints.Endpoint{
-		endpoints.EndpointsUnix: ctl,
-	})
+// AttachServer adds an extension server to the running daemon. Its listener is started right away if it has an
+// address, either set on the server or in the daemon configuration, and it is available in the daemon's current state.
+// Servers extending the core API are merged with its listener, so they can only be supplied when the daemon starts.
+func (d *Daemon) AttachServer(name string, server rest.Server) error {
+	if server.CoreAPI {
+		return fmt.Errorf("Server %q extends the core API and cannot be attached to a running daemon", name)
+	}
 
-	return d.endpoints.Up()
-}
+	err := utils.ValidateFQDN(name)
+	if err != nil {
+		return fmt.Errorf("Server name %q is not a valid FQDN: %w", name, err)
+	}
 
-// addCoreServers initializes the default resources with the default address and certificate.
-// If the default address and certificate may be applied to any extension servers, those will be started as well.
-func (d *Daemon) addCoreServers(preInit bool, defaultURL api.URL, defaultCert *shared.CertInfo, defaultResources []rest.Resources) error {
-	serverEndpoints := []rest.Resources{}
-	serverEndpoints = append(serverEndpoints, defaultResources...)
+	if shared.ValueInSlice(name, []string{endpoints.EndpointsCore, endpoints.EndpointsUnix, endpoints.EndpointsEtcd, endpoints.EndpointsReadOnly}) {
+		return fmt.Errorf("Cannot use the reserved server name %q", name)
+	}
 
-	// Append all extension servers whose address is empty or matches the default URL.
-	d.extensionServersMu.RLock()
-	for _, s := range d.extensionServers {
-		// If the server is not available prior to initialization, then skip it if we are before initialization.
-		if !s.PreInit && preInit {
-			continue
-		}
+	// Apply any listener configuration set for the server at runtime.
+	serverConfig, ok := d.config.GetServers()[name]
+	if ok {
+		server.ServerConfig = serverConfig
+	}
 
-		// If the Server resources are not part of the core API, then skip it.
-		if !s.CoreAPI {
-			continue
-		}
+	d.extensionServersMu.Lock()
+	_, ok = d.extensionServers[name]
+	if ok {
+		d.extensionServersMu.Unlock()
+		return fmt.Errorf("Server %q already exists", name)
+	}
 
-		serverEndpoints = append(serverEndpoints, s.Resources...)
+	extensionServers := make(map[string]rest.Server, len(d.extensionServers)+1)
+	for k, v := range d.extensionServers {
+		extensionServers[k] = v
 	}
 
-	d.extensionServersMu.RUnlock()
+	extensionServers[name] = server
+	err = resources.ValidateEndpoints(extensionServers, d.listenAddress().URL.Host)
+	if err != nil {
+		d.extensionServersMu.Unlock()
+		return err
+	}
 
-	server := d.initServer(serverEndpoints...)
-	network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, defaultURL, defaultCert, d.drainConnectionsTimeout)
+	d.extensionServers[name] = server
+	d.extensionServersMu.Unlock()
 
-	return d.endpoints.Add(map[string]endpoints.Endpoint{
-		endpoints.EndpointsCore: network,
-	})
-}
+	// Before initialization, listeners use the server certificate as there is no cluster certificate yet.
+	preInit := d.db.Status() == types.DatabaseNotReady
+	cert := d.ServerCert()
+	if !preInit {
+		cert = d.ClusterCert()
+	}
 
-// addExtensionServers initialises a new *endpoints.Network for each extension server and adds it to the Daemon endpoints.
-// Only servers with a defined address will be started.
-// If a server lacks a certificate, the fallbackCert will be used instead.
-// The function is idempotent and doesn't start already running extension servers.
+	err = d.addExtensionServers(preInit, cert, d.listenAddress().URL.Host)
+	if err != nil {
+		d.extensionServersMu.Lock()
+		delete(d.extensionServers, name)
+		d.extensionServersMu.Unlock()
+
+		return fmt.Errorf("Failed to start server %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// DetachServer stops the listener of an extension server and removes the server from the daemon.
+func (d *Daemon) DetachServer(name string) error {
+	d.extensionServersMu.Lock()
+	server, ok := d.extensionServers[name]
+	if !ok {
+		d.extensionServersMu.Unlock()
+		return api.StatusErrorf(http.StatusNotFound, "Server %q not found", name)
+	}
+
+	if server.CoreAPI {
+		d.extensionServersMu.Unlock()
+		return fmt.Errorf("Server %q extends the core API and cannot be detached from a running daemon", name)
+	}
+
+	delete(d.extensionServers, name)
+	cancelACME, ok := d.acmeCancels[name]
+	if ok {
+		cancelACME()
+		delete(d.acmeCancels, name)
+	}
+
+	d.extensionServersMu.Unlock()
+
+	return d.endpoints.DownByName(name)
+}
+
+// startUnixServer starts up the core unix listener with the given resources.
+func (d *Daemon) startUnixServer(serverEndpoints []rest.Resources) error {
+	group, mode, err := d.socketAccess(d.config.GetSocket())
+	if err != nil {
+		return err
+	}
+
+	ctlServer := d.initServer(serverEndpoints...)
+	ctl := endpoints.NewSocket(d.shutdownCtx, ctlServer, d.os.ControlSocket(), group, d.drainConnectionsTimeout)
+	ctl.Mode = mode
+	controlEndpoints := map[string]endpoints.Endpoint{
+		endpoints.EndpointsUnix: ctl,
+	}
+
+	if d.controlTCPAddress != (types.AddrPort{}) {
+		secret, err := d.writeControlSecret()
+		if err != nil {
+			return err
+		}
+
+		tcpServer := d.initServer(serverEndpoints...)
+		controlEndpoints[endpoints.EndpointsControlTCP] = endpoints.NewControlTCP(d.shutdownCtx, tcpServer, d.controlTCPAddress, secret, d.drainConnectionsTimeout)
+	}
+
+	d.endpoints = endpoints.NewEndpoints(d.shutdownCtx, controlEndpoints)
+
+	return d.endpoints.Up()
+}
+
+// socketAccess returns the group and file mode of the control socket for the given runtime settings. Unset settings
+// fall back to the group the daemon was started with, and to the default mode.
+func (d *Daemon) socketAccess(config types.SocketConfig) (string, os.FileMode, error) {
+	mode, err := config.FileMode()
+	if err != nil {
+		return "", 0, err
+	}
+
+	group := config.Group
+	if group == "" {
+		group = d.socketGroup
+	}
+
+	return group, mode, nil
+}
+
+// controlSocket returns the running control socket.
+func (d *Daemon) controlSocket() (*endpoints.Socket, error) {
+	socket, ok := d.endpoints.Get(endpoints.EndpointsUnix).(*endpoints.Socket)
+	if !ok {
+		return nil, fmt.Errorf("Control socket is not running")
+	}
+
+	return socket, nil
+}
+
+// CheckSocketAccess checks that the given runtime settings can be applied to the running control socket.
+func (d *Daemon) CheckSocketAccess(config types.SocketConfig) error {
+	group, _, err := d.socketAccess(config)
+	if err != nil {
+		return err
+	}
+
+	socket, err := d.controlSocket()
+	if err != nil {
+		return err
+	}
+
+	return socket.CheckAccess(group)
+}
+
+// SetSocketAccess changes the group and file mode of the running control socket according to the given runtime
+// settings.
+func (d *Daemon) SetSocketAccess(config types.SocketConfig) error {
+	group, mode, err := d.socketAccess(config)
+	if err != nil {
+		return err
+	}
+
+	socket, err := d.controlSocket()
+	if err != nil {
+		return err
+	}
+
+	err = socket.SetAccess(group, mode)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Updated control socket access", logger.Ctx{"group": group, "mode": fmt.Sprintf("%04o", mode)})
+
+	return nil
+}
+
+// writeControlSecret generates a new shared secret for the control API over TCP, and writes it to the runtime
+// directory where only the daemon user can read it.
+func (d *Daemon) writeControlSecret() (string, error) {
+	secret, err := internalAccess.NewControlSecret()
+	if err != nil {
+		return "", err
+	}
+
+	// Remove any previous secret, so that the new one is not written with the permissions of an existing file.
+	err = os.Remove(d.os.ControlSecretPath())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("Failed to remove previous control secret: %w", err)
+	}
+
+	err = os.WriteFile(d.os.ControlSecretPath(), []byte(secret), 0600)
+	if err != nil {
+		return "", fmt.Errorf("Failed to write control secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// addCoreServers initializes the default resources with the default address and certificate.
+// If the default address and certificate may be applied to any extension servers, those will be started as well.
+func (d *Daemon) addCoreServers(preInit bool, defaultURL api.URL, defaultCert *shared.CertInfo, defaultResources []rest.Resources) error {
+	serverEndpoints := []rest.Resources{}
+	serverEndpoints = append(serverEndpoints, defaultResources...)
+
+	// Append all extension servers whose address is empty or matches the default URL.
+	d.extensionServersMu.RLock()
+	for _, s := range d.extensionServers {
+		// If the server is not available prior to initialization, then skip it if we are before initialization.
+		if preInit && !availableBeforeInit(s) {
+			continue
+		}
+
+		// If the Server resources are not part of the core API, then skip it.
+		if !s.CoreAPI {
+			continue
+		}
+
+		serverEndpoints = append(serverEndpoints, s.Resources...)
+	}
+
+	d.extensionServersMu.RUnlock()
+
+	server := d.initServer(serverEndpoints...)
+	network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, defaultURL, defaultCert, d.drainConnectionsTimeout)
+	network.SetRevocationCheck(d.revokedCertificates.IsRevokedCertificate)
+	if d.tlsPolicy != nil {
+		network.SetTLSPolicy(*d.tlsPolicy, d.isTrustedCertificate)
+	}
+
+	// Adopt the listener reserved for the address, rather than binding it again.
+	if !preInit {
+		d.reservedListenerMu.Lock()
+		if d.reservedListener != nil && d.reservedListener.Addr().String() == defaultURL.URL.Host {
+			network.SetListener(d.reservedListener)
+			d.reservedListener = nil
+		}
+
+		d.reservedListenerMu.Unlock()
+	}
+
+	return d.endpoints.Add(map[string]endpoints.Endpoint{
+		endpoints.EndpointsCore: network,
+	})
+}
+
+// availableBeforeInit returns whether the server is started before the daemon is initialized, either because it has
+// PreInit set or because some of its endpoints are allowed before initialization. Its other endpoints return an error
+// until the daemon is initialized.
+func availableBeforeInit(s rest.Server) bool {
+	if s.PreInit {
+		return true
+	}
+
+	for _, resources := range s.Resources {
+		for _, endpoint := range resources.Endpoints {
+			if endpoint.AllowedBeforeInit {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isTrustedCertificate returns whether the certificate belongs to a cluster member or a trusted client.
+func (d *Daemon) isTrustedCertificate(cert *x509.Certificate) bool {
+	fingerprint := shared.CertFingerprint(cert)
+	if d.trustStore != nil && d.trustStore.Remotes().RemoteByCertificateFingerprint(fingerprint) != nil {
+		return true
+	}
+
+	_, ok := d.trustedClients.CertificatesNative()[fingerprint]
+
+	return ok
+}
+
+// addExtensionServers initialises a new *endpoints.Network for each extension server and adds it to the Daemon endpoints.
+// Only servers with a defined address will be started.
+// If a server lacks a certificate, the fallbackCert will be used instead.
+// The function is idempotent and doesn't start already running extension servers.
 func (d *Daemon) addExtensionServers(preInit bool, fallbackCert *shared.CertInfo, coreAddress string) error {
 	var networks = make(map[string]endpoints.Endpoint)
 	d.extensionServersMu.RLock()
@@ -857,8 +2051,9 @@ func (d *Daemon) addExtensionServers(preInit bool, fallbackCert *shared.CertInfo
 			continue
 		}
 
-		// If we are before initialization, only start the servers who have `PreInit` set.
-		if !extensionServer.PreInit && preInit {
+		// If we are before initialization, only start the servers who have `PreInit` set or endpoints allowed before
+		// initialization.
+		if preInit && !availableBeforeInit(extensionServer) {
 			continue
 		}
 
@@ -890,12 +2085,13 @@ func (d *Daemon) addExtensionServers(preInit bool, fallbackCert *shared.CertInfo
 
 		var err error
 		var cert *shared.CertInfo
-		if !extensionServer.DedicatedCertificate && !customCertExists {
+		if !extensionServer.DedicatedCertificate && !customCertExists && extensionServer.ACME == nil {
 			// If there is no certificate defined, apply the default certificate for the core server.
 			cert = fallbackCert
 		} else {
 			// Generate a dedicated certificate or load the custom one if it exists.
 			// When updating the additional listeners the dedicated certificate from before will be reused.
+			// Servers with an ACME managed certificate use a dedicated certificate until one is issued.
 			cert, err = shared.KeyPairAndCA(d.os.CertificatesDir, serverName, shared.CertServer, shared.CertOptions{AddHosts: true, CommonName: serverName})
 			if err != nil {
 				return fmt.Errorf("Failed to setup dedicated certificate for additional server %q: %w", serverName, err)
@@ -903,7 +2099,20 @@ func (d *Daemon) addExtensionServers(preInit bool, fallbackCert *shared.CertInfo
 		}
 
 		server := d.initServer(extensionServer.Resources...)
+		if extensionServer.GRPCServer != nil {
+			server.Handler = internalREST.GRPCHandler(extensionServer.GRPCServer, server.Handler)
+		}
+
 		network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, *url, cert, extensionServer.DrainConnectionsTimeout)
+		network.SetRevocationCheck(d.revokedCertificates.IsRevokedCertificate)
+		if d.tlsPolicy != nil {
+			network.SetTLSPolicy(*d.tlsPolicy, d.isTrustedCertificate)
+		}
+
+		if extensionServer.HTTP2 {
+			network.EnableHTTP2()
+		}
+
 		networks[serverName] = network
 	}
 
@@ -916,6 +2125,60 @@ func (d *Daemon) addExtensionServers(preInit bool, fallbackCert *shared.CertInfo
 		}
 	}
 
+	for serverName := range networks {
+		err := d.startACME(serverName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startACME starts provisioning and renewing the certificate of the named extension server from its ACME
+// certificate authority, if it has one configured and its certificate is not already managed.
+func (d *Daemon) startACME(serverName string) error {
+	d.extensionServersMu.Lock()
+	defer d.extensionServersMu.Unlock()
+
+	server, ok := d.extensionServers[serverName]
+	if !ok || server.ACME == nil {
+		return nil
+	}
+
+	_, ok = d.acmeCancels[serverName]
+	if ok {
+		return nil
+	}
+
+	// Only the dqlite leader obtains certificates, and installs them on every cluster member through the control socket.
+	// Cluster members that are not initialized yet have no one to share their certificate with.
+	isIssuer := func(ctx context.Context) (bool, error) {
+		if d.db.IsOpen(ctx) != nil {
+			return true, nil
+		}
+
+		return d.isLeader(ctx)
+	}
+
+	store := func(ctx context.Context, keyPair types.KeyPair) error {
+		c, err := internalClient.New(d.os.ControlSocket(), nil, nil, false)
+		if err != nil {
+			return err
+		}
+
+		return c.UpdateCertificate(ctx, types.CertificateName(serverName), keyPair)
+	}
+
+	manager, err := acme.NewManager(serverName, *server.ACME, d.os.CertificatesDir, filepath.Join(d.os.StateDir, "acme.key"), isIssuer, store)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(d.shutdownCtx)
+	d.acmeCancels[serverName] = cancel
+	go manager.Run(ctx)
+
 	return nil
 }
 
@@ -982,11 +2245,13 @@ func (d *Daemon) ReloadCert(name types.CertificateName) error {
 	}
 
 	if name == types.ServerCertificateName {
+		d.serverCert = cert
+
+		// Once initialized, the listeners use the cluster certificate, and the server certificate only identifies
+		// the cluster member to its peers.
 		if d.db.Status() != types.DatabaseNotReady {
-			return fmt.Errorf("Cannot replace server certificate after initialization")
+			return nil
 		}
-
-		d.serverCert = cert
 	}
 
 	if name == types.ClusterCertificateName || name == types.ServerCertificateName {
@@ -1008,6 +2273,14 @@ func (d *Daemon) ReloadCert(name types.CertificateName) error {
 		}
 
 		d.extensionServersMu.RUnlock()
+
+		if d.etcd != nil {
+			d.endpoints.UpdateTLSByName(endpoints.EndpointsEtcd, cert)
+		}
+
+		if d.readOnlyAddress != (types.AddrPort{}) {
+			d.endpoints.UpdateTLSByName(endpoints.EndpointsReadOnly, cert)
+		}
 	} else {
 		d.endpoints.UpdateTLSByName(string(name), cert)
 	}
@@ -1015,6 +2288,168 @@ func (d *Daemon) ReloadCert(name types.CertificateName) error {
 	return nil
 }
 
+// certificates returns the expiry of the server and cluster certificates, and of the certificates of the extension
+// servers in the certificates directory.
+func (d *Daemon) certificates() ([]types.CertificateExpiry, error) {
+	certs := []types.CertificateExpiry{}
+	for name, certInfo := range map[types.CertificateName]*shared.CertInfo{types.ServerCertificateName: d.ServerCert(), types.ClusterCertificateName: d.ClusterCert()} {
+		cert, err := certInfo.PublicKeyX509()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %q certificate: %w", name, err)
+		}
+
+		certs = append(certs, certificateExpiry(name, cert))
+	}
+
+	for _, name := range d.ExtensionServers() {
+		certPath := filepath.Join(d.os.CertificatesDir, fmt.Sprintf("%s.crt", name))
+		if !shared.PathExists(certPath) {
+			continue
+		}
+
+		cert, err := shared.ReadCert(certPath)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %q certificate: %w", name, err)
+		}
+
+		certs = append(certs, certificateExpiry(types.CertificateName(name), cert))
+	}
+
+	sort.Slice(certs, func(i, j int) bool { return certs[i].Name < certs[j].Name })
+
+	return certs, nil
+}
+
+// certificateExpiry returns the expiry of the given certificate.
+func certificateExpiry(name types.CertificateName, cert *x509.Certificate) types.CertificateExpiry {
+	selfSigned := bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+
+	return types.CertificateExpiry{
+		Name:          name,
+		Fingerprint:   shared.CertFingerprint(cert),
+		NotAfter:      cert.NotAfter,
+		DaysRemaining: int(time.Until(cert.NotAfter).Hours() / 24),
+		SelfSigned:    selfSigned,
+	}
+}
+
+// checkCertificateExpiry logs the certificates expiring within the configured warning period, and renews them if
+// they are self-signed and renewal is enabled.
+func (d *Daemon) checkCertificateExpiry(ctx context.Context) error {
+	certs, err := d.certificates()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	expiring := map[types.CertificateName]types.CertificateExpiry{}
+	for _, cert := range certs {
+		if time.Until(cert.NotAfter) > d.certificateExpiryWarning {
+			continue
+		}
+
+		logger.Warn("Certificate is about to expire", logger.Ctx{"name": cert.Name, "fingerprint": cert.Fingerprint, "expiry": cert.NotAfter, "days": cert.DaysRemaining})
+
+		expiring[cert.Name] = cert
+		if !d.renewSelfSignedCertificates || !cert.SelfSigned {
+			continue
+		}
+
+		err := d.renewCertificate(ctx, cert.Name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to renew %q certificate: %w", cert.Name, err))
+		} else {
+			delete(expiring, cert.Name)
+		}
+	}
+
+	// Warnings are recorded in the database, so they can only be filed once the daemon is initialized.
+	if d.db.Status() == types.DatabaseReady {
+		s := d.State()
+		for _, cert := range certs {
+			expiry, ok := expiring[cert.Name]
+			if ok {
+				err = s.FileWarning(ctx, types.WarningCertificateExpiring, string(cert.Name), fmt.Sprintf("Certificate %q expires on %s", cert.Name, expiry.NotAfter.Format(time.RFC3339)))
+			} else {
+				err = s.ResolveWarning(ctx, types.WarningCertificateExpiring, string(cert.Name))
+			}
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Failed to update warning for %q certificate: %w", cert.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// renewCertificate replaces the named self-signed certificate with a newly generated one.
+// Once the daemon is initialized, the cluster certificate is rotated on every cluster member by the dqlite leader, and
+// the server certificate is trusted by every cluster member before it is loaded.
+func (d *Daemon) renewCertificate(ctx context.Context, name types.CertificateName) error {
+	dir := d.os.CertificatesDir
+	commonName := string(name)
+	switch name {
+	case types.ServerCertificateName:
+		// The server certificate identifies the cluster member, so its peers must trust the renewed one.
+		if d.db.Status() != types.DatabaseNotReady {
+			if d.db.Status() != types.DatabaseReady {
+				return nil
+			}
+
+			return resources.RenewServerCertificate(ctx, d.State())
+		}
+
+		dir = d.os.StateDir
+		commonName = d.Name()
+	case types.ClusterCertificateName:
+		dir = d.os.StateDir
+		commonName = d.Name()
+	}
+
+	cert, key, err := shared.GenerateMemCert(false, shared.CertOptions{AddHosts: true, CommonName: commonName})
+	if err != nil {
+		return err
+	}
+
+	if name == types.ClusterCertificateName && d.db.Status() != types.DatabaseNotReady {
+		if d.db.Status() != types.DatabaseReady {
+			return nil
+		}
+
+		leader, err := d.isLeader(ctx)
+		if err != nil || !leader {
+			return err
+		}
+
+		c, err := internalClient.New(d.os.ControlSocket(), nil, nil, false)
+		if err != nil {
+			return err
+		}
+
+		return c.UpdateCertificate(ctx, name, types.KeyPair{Cert: string(cert), Key: string(key)})
+	}
+
+	err = os.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.key", name)), key, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.crt", name)), cert, 0664)
+	if err != nil {
+		return err
+	}
+
+	err = d.ReloadCert(name)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Renewed self-signed certificate", logger.Ctx{"name": name})
+
+	return nil
+}
+
 // ServerCert ensures both the daemon and state have the same server cert.
 func (d *Daemon) ServerCert() *shared.CertInfo {
 	d.clusterMu.RLock()
@@ -1023,16 +2458,339 @@ func (d *Daemon) ServerCert() *shared.CertInfo {
 	return shared.NewCertInfo(d.serverCert.KeyPair(), d.serverCert.CA(), d.serverCert.CRL())
 }
 
-// Address is the listen address for the daemon.
+// Address is the address the daemon advertises to other cluster members.
 func (d *Daemon) Address() *api.URL {
 	return api.NewURL().Scheme("https").Host(d.config.GetAddress().String())
 }
 
+// listenAddress is the address the daemon's API listens on, which defaults to the advertised address.
+func (d *Daemon) listenAddress() *api.URL {
+	listenAddress := d.config.GetListenAddress()
+	if listenAddress == (types.AddrPort{}) {
+		return d.Address()
+	}
+
+	return api.NewURL().Scheme("https").Host(listenAddress.String())
+}
+
 // Name is this daemon's cluster member name.
 func (d *Daemon) Name() string {
 	return d.config.GetName()
 }
 
+// backupDatabase takes a periodic backup of the database, and removes the backups beyond the retention count.
+// No backup is taken unless the database is online.
+func (d *Daemon) backupDatabase(ctx context.Context) error {
+	status := d.db.Status()
+	if status != types.DatabaseReady {
+		logger.Debug("Skipping database backup", logger.Ctx{"status": status})
+		return nil
+	}
+
+	dump, err := d.db.Dump(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = recover.CreateDatabaseDumpBackup(d.os, dump, d.archiveEncryption)
+	if err != nil {
+		return err
+	}
+
+	return recover.PruneDatabaseBackups(d.os, d.backupSchedule.Retention)
+}
+
+// runBackfills runs the pending data backfills once the database is online. Every cluster member attempts to run them,
+// but only one runs them at a time, and the others find them complete once they acquire the lock.
+func (d *Daemon) runBackfills(ctx context.Context) error {
+	if d.backfillsDone.Load() || d.db.Status() != types.DatabaseReady {
+		return nil
+	}
+
+	err := d.db.RunBackfills(ctx, d.backfills)
+	if err != nil {
+		return err
+	}
+
+	d.backfillsDone.Store(true)
+
+	return nil
+}
+
+// startReadOnlyAPI starts the listener of the read-only API, serving the GET requests of the public core API and of
+// the extension servers that are part of it.
+func (d *Daemon) startReadOnlyAPI() error {
+	serverEndpoints := []rest.Resources{}
+	for _, r := range d.coreResources(append([]rest.Resources{resources.CoreEndpoints}, resources.PublicEndpointVersions...)...) {
+		serverEndpoints = append(serverEndpoints, resources.ReadOnly(r))
+	}
+
+	d.extensionServersMu.RLock()
+	for _, s := range d.extensionServers {
+		if !s.CoreAPI {
+			continue
+		}
+
+		for _, r := range s.Resources {
+			serverEndpoints = append(serverEndpoints, resources.ReadOnly(r))
+		}
+	}
+
+	d.extensionServersMu.RUnlock()
+
+	server := d.initServer(serverEndpoints...)
+	server.Handler = internalAccess.WithListenerAddress(server.Handler, d.readOnlyAddress.String())
+	url := api.NewURL().Scheme("https").Host(d.readOnlyAddress.String())
+	network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, *url, d.ClusterCert(), d.drainConnectionsTimeout)
+	network.SetRevocationCheck(d.revokedCertificates.IsRevokedCertificate)
+	if d.tlsPolicy != nil {
+		network.SetTLSPolicy(*d.tlsPolicy, d.isTrustedCertificate)
+	}
+
+	err := d.endpoints.Add(map[string]endpoints.Endpoint{endpoints.EndpointsReadOnly: network})
+	if err != nil {
+		return fmt.Errorf("Failed to start read-only API: %w", err)
+	}
+
+	return nil
+}
+
+// startEtcd starts the listener of the etcd compatible API. Only cluster members and trusted clients may use it.
+func (d *Daemon) startEtcd() error {
+	if d.etcdBackend == nil {
+		d.etcdBackend = kine.NewBackend(d.db)
+	}
+
+	handler := d.etcd.Server(d.etcdBackend)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 || !d.isTrustedCertificate(r.TLS.PeerCertificates[0]) {
+				http.Error(w, "Client certificate is not trusted", http.StatusForbidden)
+				return
+			}
+
+			handler.ServeHTTP(w, r)
+		}),
+	}
+
+	url := api.NewURL().Scheme("https").Host(d.etcd.Address.String())
+	network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, *url, d.ClusterCert(), d.drainConnectionsTimeout)
+	network.SetRevocationCheck(d.revokedCertificates.IsRevokedCertificate)
+	network.EnableHTTP2()
+	if d.tlsPolicy != nil {
+		network.SetTLSPolicy(*d.tlsPolicy, d.isTrustedCertificate)
+	}
+
+	err := d.endpoints.Add(map[string]endpoints.Endpoint{endpoints.EndpointsEtcd: network})
+	if err != nil {
+		return fmt.Errorf("Failed to start etcd API: %w", err)
+	}
+
+	return nil
+}
+
+// scheduleEtcdTasks schedules the compaction of the history of the keys of the etcd compatible API, and the expiry
+// of their leases, on the dqlite leader.
+func (d *Daemon) scheduleEtcdTasks() error {
+	if d.etcdBackend == nil {
+		d.etcdBackend = kine.NewBackend(d.db)
+	}
+
+	compactInterval := d.etcd.CompactInterval
+	if compactInterval == 0 {
+		compactInterval = 5 * time.Minute
+	}
+
+	compactRetention := d.etcd.CompactRetention
+	if compactRetention == 0 {
+		compactRetention = 1000
+	}
+
+	err := d.tasks.Add(tasks.Task{
+		Name: "etcd-compact",
+		Func: func(ctx context.Context) error {
+			if d.db.Status() != types.DatabaseReady {
+				return nil
+			}
+
+			current, err := d.etcdBackend.CurrentRevision(ctx)
+			if err != nil {
+				return err
+			}
+
+			if current-compactRetention <= 0 {
+				return nil
+			}
+
+			_, err = d.etcdBackend.Compact(ctx, current-compactRetention)
+
+			return err
+		},
+		Interval: compactInterval,
+		Scope:    types.TaskScopeLeader,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to schedule compaction of etcd keys: %w", err)
+	}
+
+	err = d.tasks.Add(tasks.Task{
+		Name: "etcd-leases",
+		Func: func(ctx context.Context) error {
+			if d.db.Status() != types.DatabaseReady {
+				return nil
+			}
+
+			return d.etcdBackend.ExpireLeases(ctx)
+		},
+		Interval: 5 * time.Second,
+		Scope:    types.TaskScopeLeader,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to schedule expiry of etcd leases: %w", err)
+	}
+
+	return nil
+}
+
+// deliverTableChanges runs the OnTableChange hook with the changes of tracked tables made since its last run.
+// Changes made before the first run are skipped, and changes are not delivered again if the hook fails.
+func (d *Daemon) deliverTableChanges(ctx context.Context) error {
+	if d.db.Status() != types.DatabaseReady {
+		return nil
+	}
+
+	var changes []cluster.CoreChange
+	err := d.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		if d.tableChangeID < 0 {
+			d.tableChangeID, err = cluster.GetCoreChangesLastID(ctx, tx)
+
+			return err
+		}
+
+		changes, err = cluster.GetCoreChanges(ctx, tx, d.tableChangeID, tableChangesBatch)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	d.tableChangeID = changes[len(changes)-1].ID
+
+	apiChanges := make([]types.TableChange, 0, len(changes))
+	for _, change := range changes {
+		apiChanges = append(apiChanges, change.ToAPI())
+	}
+
+	err = d.hooks.OnTableChange(ctx, d.State(), apiChanges)
+	if err != nil {
+		return fmt.Errorf("Failed to run table change hook: %w", err)
+	}
+
+	return nil
+}
+
+// pruneTableChanges removes the changes of tracked tables older than tableChangesRetention.
+func (d *Daemon) pruneTableChanges(ctx context.Context) error {
+	if d.db.Status() != types.DatabaseReady {
+		return nil
+	}
+
+	return d.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.DeleteCoreChangesBefore(ctx, tx, time.Now().Add(-tableChangesRetention))
+	})
+}
+
+// isLeader returns whether the local cluster member is currently the dqlite leader.
+func (d *Daemon) isLeader(ctx context.Context) (bool, error) {
+	leaderAddress, err := d.leaderAddress(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return leaderAddress == d.Address().URL.Host, nil
+}
+
+// leaderAddress returns the address of the current dqlite leader.
+func (d *Daemon) leaderAddress(ctx context.Context) (string, error) {
+	err := d.db.IsOpen(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	leaderClient, err := d.db.Leader(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer leaderClient.Close()
+
+	leaderInfo, err := leaderClient.Leader(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return leaderInfo.Address, nil
+}
+
+// leadershipPollInterval is how often the daemon checks whether it has gained or lost dqlite leadership.
+const leadershipPollInterval = 2 * time.Second
+
+// watchLeadership runs the OnDqliteLeadershipChange hook whenever the local cluster member gains or loses dqlite
+// leadership, until the context is cancelled.
+func (d *Daemon) watchLeadership(ctx context.Context) {
+	wasLeader := false
+	ticker := time.NewTicker(leadershipPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		leaderCtx, cancel := context.WithTimeout(ctx, leadershipPollInterval)
+		leaderAddress, err := d.leaderAddress(leaderCtx)
+		cancel()
+		if err != nil {
+			// Without a reachable leader, this member cannot be the leader either.
+			leaderAddress = ""
+		}
+
+		isLeader := leaderAddress != "" && leaderAddress == d.Address().URL.Host
+		if isLeader == wasLeader {
+			continue
+		}
+
+		wasLeader = isLeader
+
+		var leaderName string
+		var leaderAddrPort types.AddrPort
+		if leaderAddress != "" {
+			leaderAddrPort, err = types.ParseAddrPort(leaderAddress)
+			if err == nil {
+				remote := d.trustStore.Remotes().RemoteByAddress(leaderAddrPort)
+				if remote != nil {
+					leaderName = remote.Name
+				}
+			}
+		}
+
+		logger.Info("Dqlite leadership changed", logger.Ctx{"leader": isLeader, "leader_name": leaderName, "leader_address": leaderAddress})
+
+		err = d.hooks.OnDqliteLeadershipChange(ctx, d.State(), isLeader, leaderName, leaderAddrPort)
+		if err != nil {
+			logger.Error("Failed to run dqlite leadership change hook", logger.Ctx{"leader": isLeader, "error": err})
+		}
+	}
+}
+
 // Version is provided by the MicroCluster consumer. The daemon includes it in
 // its /cluster/1.0 response.
 func (d *Daemon) Version() string {
@@ -1076,13 +2834,21 @@ func (d *Daemon) State() state.State {
 		Hooks:                    &d.hooks,
 		Context:                  d.shutdownCtx,
 		ReadyCh:                  d.ReadyChan,
+		StartTime:                d.startTime,
+		Project:                  d.project,
+		MaxClockSkew:             d.maxClockSkew,
+		TokenFingerprintHash:     d.tokenFingerprintHash,
 		SetConfig:                d.setConfig,
+		ReserveListener:          d.reserveListener,
 		StartAPI:                 d.StartAPI,
 		Extensions:               d.Extensions,
 		Endpoints:                d.endpoints,
 		UpdateServers:            d.UpdateServers,
 		LocalConfig:              d.LocalConfig,
 		ReloadCert:               d.ReloadCert,
+		CheckSocketAccess:        d.CheckSocketAccess,
+		SetSocketAccess:          d.SetSocketAccess,
+		Certificates:             d.certificates,
 		InternalFileSystem:       d.FileSystem,
 		InternalAddress:          d.Address,
 		InternalName:             d.Name,
@@ -1092,6 +2858,16 @@ func (d *Daemon) State() state.State {
 		InternalDatabase:         d.db,
 		InternalRemotes:          d.trustStore.Remotes,
 		InternalExtensionServers: d.ExtensionServers,
+		ArchiveEncryption:        d.archiveEncryption,
+		ControlSocketPolicy:      d.controlSocketPolicy,
+		PreparedQueries:          d.preparedQueries,
+		InitProgress:             d.initProgress,
+		AuditLog:                 d.auditLog,
+		TrustedClients:           d.trustedClients,
+		RevokedCertificates:      d.revokedCertificates,
+		Maintenance:              &d.maintenance,
+		InternalTasks:            d.tasks,
+		InternalOperations:       d.operations,
 		Stop: func() (exit func(), stopErr error) {
 			stopErr = d.stop()
 			exit = func() {
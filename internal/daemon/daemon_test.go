@@ -2,9 +2,19 @@ package daemon
 
 import (
 	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
 	"path/filepath"
+	"strconv"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
@@ -13,11 +23,35 @@ import (
 
 	"github.com/canonical/microcluster/v3/internal/config"
 	"github.com/canonical/microcluster/v3/internal/endpoints"
+	"github.com/canonical/microcluster/v3/internal/kine"
+	"github.com/canonical/microcluster/v3/internal/operations"
 	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/trust"
 	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
 	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
 )
 
+// memBackend stores the remotes in memory.
+type memBackend struct {
+	remotes []trust.Remote
+}
+
+func (b *memBackend) Load() ([]trust.Remote, error) { return b.remotes, nil }
+
+func (b *memBackend) Add(remote trust.Remote) error {
+	b.remotes = append(b.remotes, remote)
+	return nil
+}
+
+func (b *memBackend) Replace(remotes []trust.Remote) error {
+	b.remotes = remotes
+	return nil
+}
+
+func (b *memBackend) Watch(refresh func() error) {}
+
 type daemonsSuite struct {
 	suite.Suite
 }
@@ -236,3 +270,360 @@ func (t *daemonsSuite) Test_UpdateServers() {
 		require.NoError(t.T(), err)
 	}
 }
+
+func (t *daemonsSuite) Test_certificateExpiry() {
+	cert, _, err := shared.GenerateMemCert(false, shared.CertOptions{CommonName: "member01"})
+	require.NoError(t.T(), err)
+
+	x509Cert, err := types.ParseX509Certificate(string(cert))
+	require.NoError(t.T(), err)
+
+	expiry := certificateExpiry(types.ClusterCertificateName, x509Cert.Certificate)
+	require.Equal(t.T(), types.ClusterCertificateName, expiry.Name)
+	require.Equal(t.T(), shared.CertFingerprint(x509Cert.Certificate), expiry.Fingerprint)
+	require.True(t.T(), expiry.SelfSigned)
+
+	// Generated certificates are valid for 10 years.
+	require.InDelta(t.T(), 3650, expiry.DaysRemaining, 3)
+}
+
+// Ensures extension servers are only started before initialization if they have PreInit set, or endpoints allowed
+// before initialization.
+func (t *daemonsSuite) Test_availableBeforeInit() {
+	tests := []struct {
+		name      string
+		server    rest.Server
+		available bool
+	}{
+		{name: "Server without endpoints", server: rest.Server{}},
+		{name: "PreInit server", server: rest.Server{PreInit: true}, available: true},
+		{
+			name: "Server without endpoints allowed before initialization",
+			server: rest.Server{Resources: []rest.Resources{
+				{Endpoints: []rest.Endpoint{{Path: "one"}, {Path: "two", AllowedDuringShutdown: true}}},
+			}},
+		},
+		{
+			name: "Server with an endpoint allowed before initialization",
+			server: rest.Server{Resources: []rest.Resources{
+				{Endpoints: []rest.Endpoint{{Path: "one"}}},
+				{Endpoints: []rest.Endpoint{{Path: "two"}, {Path: "three", AllowedBeforeInit: true}}},
+			}},
+			available: true,
+		},
+	}
+
+	for i, test := range tests {
+		t.T().Logf("%s (case %d)", test.name, i)
+
+		require.Equal(t.T(), test.available, availableBeforeInit(test.server))
+	}
+}
+
+// Ensures requests to the read-only API are authenticated against its own address, so that cluster members can read
+// from it but not modify the cluster, and untrusted clients are refused.
+func (t *daemonsSuite) Test_startReadOnlyAPI() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t.T(), err)
+
+	readOnlyAddress, err := types.ParseAddrPort(listener.Addr().String())
+	require.NoError(t.T(), err)
+	require.NoError(t.T(), listener.Close())
+
+	peerCert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t.T(), err)
+
+	peerAddress, err := types.ParseAddrPort("10.0.0.2:9443")
+	require.NoError(t.T(), err)
+
+	handler := func(s state.State, r *http.Request) response.Response { return response.EmptySyncResponse }
+	server := rest.Server{
+		CoreAPI: true,
+		Resources: []rest.Resources{{
+			PathPrefix: "1.0",
+			Endpoints: []rest.Endpoint{{
+				Path:              "status",
+				AllowedBeforeInit: true,
+				Get:               rest.EndpointAction{Handler: handler, AccessHandler: access.AllowAuthenticated},
+				Post:              rest.EndpointAction{Handler: handler, AccessHandler: access.AllowAuthenticated},
+			}},
+		}},
+	}
+
+	daemon := NewDaemon("project")
+	daemon.config = config.NewDaemonConfig(filepath.Join(t.T().TempDir(), "daemon.yaml"))
+	daemon.extensionServers = map[string]rest.Server{"server": server}
+	daemon.endpoints = endpoints.NewEndpoints(context.TODO(), map[string]endpoints.Endpoint{})
+	daemon.clusterCert = shared.TestingAltKeyPair()
+	daemon.serverCert = shared.TestingAltKeyPair()
+	daemon.shutdownCtx = context.TODO()
+	daemon.readOnlyAddress = readOnlyAddress
+
+	daemon.os, err = sys.DefaultOS(filepath.Join(t.T().TempDir()), false)
+	require.NoError(t.T(), err)
+
+	// The core API listens on another address.
+	coreAddress, err := types.ParseAddrPort("10.0.0.1:9443")
+	require.NoError(t.T(), err)
+	daemon.config.SetAddress(coreAddress)
+
+	daemon.trustStore, err = trust.Init(&memBackend{remotes: []trust.Remote{{
+		Location:    trust.Location{Name: "peer", Address: peerAddress},
+		Certificate: types.X509Certificate{Certificate: peerCert},
+	}}}, nil)
+	require.NoError(t.T(), err)
+
+	require.NoError(t.T(), daemon.startReadOnlyAPI())
+	defer func() { require.NoError(t.T(), daemon.endpoints.Down(endpoints.EndpointNetwork)) }()
+
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs}},
+			Timeout:   5 * time.Second,
+		}
+	}
+
+	url := api.NewURL().Scheme("https").Host(readOnlyAddress.String()).Path("1.0", "status").String()
+	tests := []struct {
+		name   string
+		client *http.Client
+		method string
+		status int
+	}{
+		{name: "Cluster member reading", client: newClient(shared.TestingKeyPair().KeyPair()), method: http.MethodGet, status: http.StatusOK},
+		{name: "Cluster member writing", client: newClient(shared.TestingKeyPair().KeyPair()), method: http.MethodPost, status: http.StatusMethodNotAllowed},
+		{name: "Untrusted client", client: newClient(), method: http.MethodGet, status: http.StatusForbidden},
+	}
+
+	for i, test := range tests {
+		t.T().Logf("%s (case %d)", test.name, i)
+
+		req, err := http.NewRequest(test.method, url, nil)
+		require.NoError(t.T(), err)
+
+		resp, err := test.client.Do(req)
+		require.NoError(t.T(), err)
+		_ = resp.Body.Close()
+
+		require.Equal(t.T(), test.status, resp.StatusCode)
+	}
+}
+
+// Ensures the etcd API serves the handler of the consumer over HTTP/2 to trusted clients only, with the backend of the
+// daemon, and that stopping it ends the streams still open.
+func (t *daemonsSuite) Test_startEtcd() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t.T(), err)
+
+	etcdAddress, err := types.ParseAddrPort(listener.Addr().String())
+	require.NoError(t.T(), err)
+	require.NoError(t.T(), listener.Close())
+
+	peerCert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t.T(), err)
+
+	var backends []*kine.Backend
+	streamEnded := make(chan struct{})
+	daemon := NewDaemon("project")
+	daemon.endpoints = endpoints.NewEndpoints(context.TODO(), map[string]endpoints.Endpoint{})
+	daemon.clusterCert = shared.TestingAltKeyPair()
+	daemon.shutdownCtx = context.TODO()
+	daemon.drainConnectionsTimeout = 100 * time.Millisecond
+	daemon.etcd = &kine.Options{
+		Address: etcdAddress,
+		Server: func(backend *kine.Backend) http.Handler {
+			backends = append(backends, backend)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(r.Proto)) })
+			mux.HandleFunc("/watch", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+
+				<-r.Context().Done()
+				close(streamEnded)
+			})
+
+			return mux
+		},
+	}
+
+	daemon.trustStore, err = trust.Init(&memBackend{remotes: []trust.Remote{{
+		Location:    trust.Location{Name: "peer"},
+		Certificate: types.X509Certificate{Certificate: peerCert},
+	}}}, nil)
+	require.NoError(t.T(), err)
+
+	require.NoError(t.T(), daemon.startEtcd())
+	require.Equal(t.T(), []*kine.Backend{daemon.etcdBackend}, backends)
+
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs}, ForceAttemptHTTP2: true},
+			Timeout:   5 * time.Second,
+		}
+	}
+
+	trusted := newClient(shared.TestingKeyPair().KeyPair())
+	url := api.NewURL().Scheme("https").Host(etcdAddress.String())
+
+	resp, err := trusted.Get(url.Path("version").String())
+	require.NoError(t.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t.T(), err)
+	_ = resp.Body.Close()
+	require.Equal(t.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(t.T(), "HTTP/2.0", string(body))
+
+	resp, err = newClient().Get(url.Path("version").String())
+	require.NoError(t.T(), err)
+	_ = resp.Body.Close()
+	require.Equal(t.T(), http.StatusForbidden, resp.StatusCode)
+
+	// A watch still open when the daemon stops is ended once the drain timeout has passed.
+	watcher := newClient(shared.TestingKeyPair().KeyPair())
+	watcher.Timeout = 0
+	resp, err = watcher.Get(url.Path("watch").String())
+	require.NoError(t.T(), err)
+	defer resp.Body.Close()
+	require.Equal(t.T(), http.StatusOK, resp.StatusCode)
+
+	_ = daemon.endpoints.Shutdown(endpoints.EndpointNetwork)
+
+	select {
+	case <-streamEnded:
+	case <-time.After(5 * time.Second):
+		t.T().Fatal("Watch was not ended when the etcd API stopped")
+	}
+
+	_, err = newClient(shared.TestingKeyPair().KeyPair()).Get(url.Path("version").String())
+	require.Error(t.T(), err)
+	require.Len(t.T(), backends, 1)
+}
+
+// Ensures the daemon only stops once it has been idle for its idle timeout, with no request or operation running,
+// and only stops once.
+func (t *daemonsSuite) Test_stopIfIdle() {
+	tests := []struct {
+		name           string
+		lastActivity   time.Duration
+		activeRequests int64
+		runningOp      bool
+		stopping       bool
+		expectStop     bool
+	}{
+		{name: "Idle daemon", lastActivity: 2 * time.Minute, expectStop: true},
+		{name: "Recent request", lastActivity: 30 * time.Second},
+		{name: "Request being served", lastActivity: 2 * time.Minute, activeRequests: 1},
+		{name: "Operation running", lastActivity: 2 * time.Minute, runningOp: true},
+		{name: "Daemon already stopping", lastActivity: 2 * time.Minute, stopping: true},
+	}
+
+	for i, test := range tests {
+		t.T().Logf("%s (case %d)", test.name, i)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		daemon := NewDaemon("project")
+		daemon.idleTimeout = time.Minute
+		daemon.operations = operations.NewManager(ctx)
+		daemon.lastActivity.Store(time.Now().Add(-test.lastActivity).UnixNano())
+		daemon.activeRequests.Store(test.activeRequests)
+		daemon.idleStopping.Store(test.stopping)
+		daemon.stop = func() error { return nil }
+
+		if test.runningOp {
+			_, err := daemon.operations.Start("Running", func(ctx context.Context, op *operations.Operation) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+			require.NoError(t.T(), err)
+		}
+
+		// Daemons that are not part of a cluster yet are never the dqlite leader.
+		require.NoError(t.T(), daemon.stopIfIdle(ctx))
+
+		select {
+		case err := <-daemon.shutdownDoneCh:
+			require.True(t.T(), test.expectStop)
+			require.NoError(t.T(), err)
+			require.True(t.T(), daemon.idleStopping.Load())
+		case <-time.After(100 * time.Millisecond):
+			require.False(t.T(), test.expectStop)
+			require.Equal(t.T(), test.stopping, daemon.idleStopping.Load())
+		}
+
+		cancel()
+	}
+}
+
+// Ensures the control socket access is applied to the running socket, falls back to the group the daemon was started
+// with, and is left unchanged if it can't be applied.
+func (t *daemonsSuite) Test_SetSocketAccess() {
+	group, err := user.LookupGroupId(strconv.Itoa(os.Getgid()))
+	require.NoError(t.T(), err)
+
+	path := filepath.Join(t.T().TempDir(), "control.socket")
+	socket := endpoints.NewSocket(context.TODO(), &http.Server{}, *api.NewURL().Scheme("http").Host(path), "", 0)
+
+	daemon := NewDaemon("project")
+	daemon.socketGroup = group.Name
+	daemon.endpoints = endpoints.NewEndpoints(context.TODO(), map[string]endpoints.Endpoint{endpoints.EndpointsUnix: socket})
+	require.NoError(t.T(), daemon.endpoints.Up())
+	defer func() { require.NoError(t.T(), daemon.endpoints.Down()) }()
+
+	requireAccess := func(mode os.FileMode) {
+		info, err := os.Stat(path)
+		require.NoError(t.T(), err)
+		require.Equal(t.T(), mode, info.Mode().Perm())
+		require.Equal(t.T(), uint32(os.Getgid()), info.Sys().(*syscall.Stat_t).Gid)
+	}
+
+	requireAccess(types.DefaultSocketMode)
+
+	tests := []struct {
+		name        string
+		config      types.SocketConfig
+		expectErr   bool
+		expectGroup string
+		expectMode  os.FileMode
+	}{
+		{name: "Mode only", config: types.SocketConfig{Mode: "0600"}, expectGroup: group.Name, expectMode: 0600},
+		{name: "Group and mode", config: types.SocketConfig{Group: group.Name, Mode: "0640"}, expectGroup: group.Name, expectMode: 0640},
+		{name: "Unknown group", config: types.SocketConfig{Group: "microcluster-unknown-group", Mode: "0600"}, expectErr: true, expectGroup: group.Name, expectMode: 0640},
+		{name: "Mode granting access to other users", config: types.SocketConfig{Mode: "0666"}, expectErr: true, expectGroup: group.Name, expectMode: 0640},
+		{name: "Default settings", config: types.SocketConfig{}, expectGroup: group.Name, expectMode: types.DefaultSocketMode},
+	}
+
+	for i, test := range tests {
+		t.T().Logf("%s (case %d)", test.name, i)
+
+		err := daemon.CheckSocketAccess(test.config)
+		if test.expectErr {
+			require.Error(t.T(), err)
+			require.Error(t.T(), daemon.SetSocketAccess(test.config))
+		} else {
+			require.NoError(t.T(), err)
+			require.NoError(t.T(), daemon.SetSocketAccess(test.config))
+		}
+
+		require.Equal(t.T(), test.expectGroup, socket.Group)
+		require.Equal(t.T(), test.expectMode, socket.Mode)
+		requireAccess(test.expectMode)
+	}
+
+	// Abstract sockets have no file, so their access can't be changed.
+	abstract := NewDaemon("project")
+	abstract.endpoints = endpoints.NewEndpoints(context.TODO(), map[string]endpoints.Endpoint{
+		endpoints.EndpointsUnix: endpoints.NewSocket(context.TODO(), &http.Server{}, *api.NewURL().Scheme("http").Host("@microcluster-test"), "", 0),
+	})
+
+	require.Error(t.T(), abstract.CheckSocketAccess(types.SocketConfig{Mode: "0600"}))
+	require.Error(t.T(), abstract.SetSocketAccess(types.SocketConfig{Mode: "0600"}))
+
+	// Without a control socket, there is nothing to apply the settings to.
+	stopped := NewDaemon("project")
+	stopped.endpoints = endpoints.NewEndpoints(context.TODO(), map[string]endpoints.Endpoint{})
+	require.Error(t.T(), stopped.CheckSocketAccess(types.SocketConfig{}))
+	require.Error(t.T(), stopped.SetSocketAccess(types.SocketConfig{}))
+}
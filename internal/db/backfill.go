@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/internal/db/update"
+)
+
+// backfillLock is the name of the cluster-wide lock held while running data backfills.
+const backfillLock = "core-backfills"
+
+// RunBackfills runs the pending data backfills in order, each until it is complete. The backfills are run under a
+// cluster-wide lock, so that a single cluster member runs them while the others wait for them to complete.
+func (db *DqliteDB) RunBackfills(ctx context.Context, backfills []update.Backfill) error {
+	if len(backfills) == 0 {
+		return nil
+	}
+
+	var pending []update.Backfill
+	err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		err := update.RegisterBackfills(ctx, tx, backfills)
+		if err != nil {
+			return err
+		}
+
+		pending, err = update.PendingBackfills(ctx, tx, backfills)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	lock, err := db.Lock(ctx, backfillLock)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := lock.Unlock(db.ctx)
+		if err != nil {
+			logger.Warn("Failed to release data backfill lock", logger.Ctx{"error": err})
+		}
+	}()
+
+	for _, backfill := range pending {
+		logger.Info("Running data backfill", logger.Ctx{"name": backfill.Name})
+
+		batches := 0
+		done := false
+		for !done {
+			select {
+			case <-lock.Lost():
+				return fmt.Errorf("Lost the data backfill lock while running %q", backfill.Name)
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+				var err error
+				done, err = update.RunBackfillBatch(ctx, tx, backfill)
+
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			batches++
+			logger.Debug("Committed data backfill batch", logger.Ctx{"name": backfill.Name, "batches": batches})
+		}
+
+		logger.Info("Completed data backfill", logger.Ctx{"name": backfill.Name, "batches": batches})
+	}
+
+	return nil
+}
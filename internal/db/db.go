@@ -1,6 +1,7 @@
 package db
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"errors"
@@ -22,6 +23,7 @@ import (
 	"github.com/canonical/microcluster/v3/internal/db/update"
 	"github.com/canonical/microcluster/v3/internal/extensions"
 	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/tracing"
 	"github.com/canonical/microcluster/v3/rest/types"
 )
 
@@ -50,10 +52,15 @@ func (db *DqliteDB) Open(ext extensions.Extensions, bootstrap bool, project stri
 	}
 
 	if db.db == nil {
-		db.db, err = db.dqlite.Open(db.ctx, db.dbName)
+		sqlDB, err := db.dqlite.Open(db.ctx, db.dbName)
 		if err != nil {
 			return fmt.Errorf("Open dqlite: %w", err)
 		}
+
+		db.db, err = db.instrument(sqlDB)
+		if err != nil {
+			return fmt.Errorf("Failed to instrument dqlite database: %w", err)
+		}
 	}
 
 	err = db.waitUpgrade(bootstrap, ext)
@@ -143,6 +150,33 @@ func (db *DqliteDB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error
 		return nodeIsBehind, nil
 	}
 
+	// checkNamespaceVersions compares the version of each namespace with other members, where a member without
+	// the namespace is at version 0.
+	checkNamespaceVersions := func(namespaceVersions map[string]uint64, clusterMemberVersions []map[string]uint64) (otherNodesBehind bool, err error) {
+		nodeIsBehind := false
+		for _, versions := range clusterMemberVersions {
+			names := make(map[string]bool, len(namespaceVersions)+len(versions))
+			for name := range namespaceVersions {
+				names[name] = true
+			}
+
+			for name := range versions {
+				names[name] = true
+			}
+
+			for name := range names {
+				behind, err := checkSchemaVersion(namespaceVersions[name], []uint64{versions[name]})
+				if err != nil {
+					return false, fmt.Errorf("Schema namespace %q: %w", name, err)
+				}
+
+				nodeIsBehind = nodeIsBehind || behind
+			}
+		}
+
+		return nodeIsBehind, nil
+	}
+
 	otherNodesBehind := false
 	newSchema := db.Schema()
 	newSchema.File(path.Join(db.os.StateDir, "patch.global.sql"))
@@ -177,9 +211,25 @@ func (db *DqliteDB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error
 				return err
 			}
 
-			// Wait until after considering both internal and external schema versions to determine if we should wait for other nodes.
+			namespaceVersions := newSchema.NamespaceVersions()
+			err = update.UpdateClusterMemberNamespaceVersions(ctx, tx, namespaceVersions, db.memberName())
+			if err != nil {
+				return fmt.Errorf("Failed to update namespace schema versions when joining cluster: %w", err)
+			}
+
+			versionsNamespaces, err := update.GetClusterMemberNamespaceVersions(ctx, tx)
+			if err != nil {
+				return fmt.Errorf("Failed to get other members' namespace schema versions: %w", err)
+			}
+
+			otherNodesBehindNamespaces, err := checkNamespaceVersions(namespaceVersions, versionsNamespaces)
+			if err != nil {
+				return err
+			}
+
+			// Wait until after considering internal, external and namespace schema versions to determine if we should wait for other nodes.
 			// This is to prevent nodes accidentally waiting for each other in case of an awkward upgrade.
-			if otherNodesBehindInternal || otherNodesBehindExternal {
+			if otherNodesBehindInternal || otherNodesBehindExternal || otherNodesBehindNamespaces {
 				otherNodesBehind = true
 
 				return schema.ErrGracefulAbort
@@ -231,12 +281,20 @@ func (db *DqliteDB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error
 		return nil
 	})
 
+	// If this member was waiting, all cluster members are now ready and the schema updates have been applied.
+	if err == nil && db.UpgradeStage() == types.UpgradeStagePending {
+		logger.Info("All cluster members are ready, upgrade committed", logger.Ctx{"address": db.listenAddr.String()})
+		db.setUpgradeStage(types.UpgradeStageCommitted)
+	}
+
 	// If we are not bootstrapping, wait for an upgrade notification, or wait a minute before checking again.
 	if otherNodesBehind && !bootstrap {
 		db.statusLock.Lock()
 		db.status = types.DatabaseWaiting
 		db.statusLock.Unlock()
 
+		db.setUpgradeStage(types.UpgradeStagePending)
+
 		logger.Warn("Waiting for other cluster members to upgrade their versions", logger.Ctx{"address": db.listenAddr.String()})
 		select {
 		case <-db.upgradeCh:
@@ -247,14 +305,31 @@ func (db *DqliteDB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error
 	return err
 }
 
+// SchemaRollback reverts external schema updates until the external schema version matches the target version.
+// The rollback is applied in a single transaction, so the schema is left unchanged if any down migration fails.
+func (db *DqliteDB) SchemaRollback(ctx context.Context, target uint64) error {
+	err := db.IsOpen(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to roll back schema, database is not yet open: %w", err)
+	}
+
+	return db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return db.schema.Rollback(ctx, tx, target)
+	})
+}
+
 // Transaction handles performing a transaction on the dqlite database.
+// The transaction is retried with increasing delays if it fails because of a dqlite leadership change or a locked
+// database, for up to DqliteOptions.TransactionRetryTimeout. If it still fails because of one, the returned error wraps
+// types.ErrDatabaseUnavailable.
 func (db *DqliteDB) Transaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error {
 	status := db.Status()
 	if status != types.DatabaseWaiting && status != types.DatabaseReady {
 		return api.StatusErrorf(http.StatusServiceUnavailable, "Database is not ready yet: %v", status)
 	}
 
-	return db.retry(outerCtx, func(ctx context.Context) error {
+	outerCtx, span := tracing.Start(outerCtx, "database transaction")
+	err := db.retry(outerCtx, func(ctx context.Context) error {
 		err := query.Transaction(ctx, db.db, f)
 		if errors.Is(err, context.DeadlineExceeded) {
 			// If the query timed out it likely means that the leader has abruptly become unreachable.
@@ -266,14 +341,21 @@ func (db *DqliteDB) Transaction(outerCtx context.Context, f func(context.Context
 
 		return err
 	})
+	err = unavailableError(err)
+	tracing.End(span, err)
+
+	return err
 }
 
+// retry runs f, retrying it on dqlite leadership changes and locked database errors unless the daemon is shutting down
+// or retries are disabled.
 func (db *DqliteDB) retry(ctx context.Context, f func(context.Context) error) error {
-	if db.ctx.Err() != nil {
+	timeout := cmp.Or(db.dqliteOptions.TransactionRetryTimeout, DefaultTransactionRetryTimeout)
+	if db.ctx.Err() != nil || timeout < 0 {
 		return f(ctx)
 	}
 
-	return query.Retry(ctx, f)
+	return retryWithBackoff(ctx, timeout, f)
 }
 
 // Update attempts to update the database with the executable at the path specified by the SCHEMA_UPDATE variable.
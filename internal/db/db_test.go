@@ -663,7 +663,7 @@ func NewTestDB(extensionsExternal []schema.Update) (*DqliteDB, error) {
 		return nil, err
 	}
 
-	db.SetSchema(extensionsExternal, nil)
+	db.SetSchema(extensionsExternal, nil, nil, nil)
 	_, err = db.schema.Ensure(db.db)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,36 @@
+// Package dbtest provides in-memory databases with the internal schema, for the tests of packages using the database.
+package dbtest
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/internal/db/update"
+)
+
+// NewDB returns an in-memory sqlite database with the internal schema applied, which is closed when the test ends.
+func NewDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	// Every connection to an in-memory database opens a separate database.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = update.NewSchema().Schema().Ensure(db)
+	require.NoError(t, err)
+
+	return db
+}
+
+// NewTx returns a transaction on a database returned by NewDB, which is rolled back when the test ends.
+func NewTx(t *testing.T) *sql.Tx {
+	tx, err := NewDB(t).Begin()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = tx.Rollback() })
+
+	return tx
+}
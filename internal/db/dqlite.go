@@ -3,7 +3,6 @@ package db
 import (
 	"bufio"
 	"context"
-	"crypto/tls"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -24,6 +23,7 @@ import (
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/tcp"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/canonical/microcluster/v3/cluster"
 	"github.com/canonical/microcluster/v3/internal/db/update"
@@ -31,6 +31,7 @@ import (
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/tracing"
 	"github.com/canonical/microcluster/v3/rest/types"
 )
 
@@ -52,14 +53,26 @@ type DqliteDB struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	heartbeatLock     sync.Mutex
-	heartbeatInterval time.Duration
-	maxConns          int64
+	heartbeatLock sync.Mutex
+	maxConns      int64
+
+	heartbeatConfigLock      sync.RWMutex
+	defaultHeartbeatInterval time.Duration // Interval the daemon was started with.
+	heartbeatInterval        time.Duration
+	offlineThreshold         time.Duration
+	heartbeatConfigCh        chan struct{} // Signalled whenever the heartbeat settings change.
+	rolesAdjustmentFrequency time.Duration // Frequency of dqlite role adjustments, fixed when dqlite is started.
 
 	schema *update.SchemaUpdate
 
-	statusLock sync.RWMutex
-	status     types.DatabaseStatus
+	dqliteOptions DqliteOptions // Tuning options applied when the dqlite node is started.
+	queryStats    *queryStats   // Statistics of the statements run against the database.
+
+	statusLock   sync.RWMutex
+	status       types.DatabaseStatus
+	upgradeStage types.UpgradeStage
+
+	upgradeHook func(stage types.UpgradeStage) // Called whenever the upgrade stage changes.
 }
 
 const (
@@ -92,14 +105,22 @@ func NewDB(ctx context.Context, serverCert func() *shared.CertInfo, clusterCert
 		ctx:               shutdownCtx,
 		cancel:            shutdownCancel,
 		status:            types.DatabaseNotReady,
+		upgradeStage:      types.UpgradeStageNone,
 		maxConns:          1,
+		queryStats:        newQueryStats(0),
+		heartbeatConfigCh: make(chan struct{}, 1),
+
+		defaultHeartbeatInterval: heartbeatInterval,
 	}
 }
 
-// SetSchema sets schema and API extensions on the DB.
-func (db *DqliteDB) SetSchema(schemaExtensions []schema.Update, apiExtensions extensions.Extensions) {
+// SetSchema sets schema and API extensions on the DB, along with any down migrations for the schema extensions,
+// and the namespaced schema updates of components sharing the database.
+func (db *DqliteDB) SetSchema(schemaExtensions []schema.Update, schemaRollbacks []schema.Update, namespaces []update.Namespace, apiExtensions extensions.Extensions) {
 	s := update.NewSchema()
 	s.AppendSchema(schemaExtensions, apiExtensions)
+	s.SetExternalRollbacks(schemaRollbacks)
+	s.SetNamespaces(namespaces)
 	db.schema = s.Schema()
 }
 
@@ -117,17 +138,23 @@ func (db *DqliteDB) SchemaVersion() (versionInternal uint64, versionExternal uin
 func (db *DqliteDB) Bootstrap(extensions extensions.Extensions, project string, addr api.URL, clusterRecord cluster.CoreClusterMember) error {
 	var err error
 	db.listenAddr = addr
-	db.dqlite, err = dqlite.New(db.os.DatabaseDir,
+	db.rolesAdjustmentFrequency = db.GetHeartbeatInterval()
+	options := []dqlite.Option{
 		dqlite.WithAddress(db.listenAddr.URL.Host),
-		dqlite.WithRolesAdjustmentFrequency(db.heartbeatInterval),
+		dqlite.WithRolesAdjustmentFrequency(db.rolesAdjustmentFrequency),
 		dqlite.WithRolesAdjustmentHook(db.heartbeat),
 		dqlite.WithConcurrentLeaderConns(&db.maxConns),
 		dqlite.WithExternalConn(db.dialFunc(), db.acceptCh),
-		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)))
+		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)),
+	}
+
+	db.dqlite, err = dqlite.New(db.os.DatabaseDir, append(options, db.dqliteOptions.appOptions()...)...)
 	if err != nil {
 		return fmt.Errorf("Failed to bootstrap dqlite: %w", err)
 	}
 
+	go db.heartbeatLoop(db.initiateHeartbeat)
+
 	err = db.Open(extensions, true, project)
 	if err != nil {
 		return err
@@ -137,8 +164,11 @@ func (db *DqliteDB) Bootstrap(extensions extensions.Extensions, project string,
 	clusterRecord.APIExtensions = extensions
 	err = db.Transaction(db.ctx, func(ctx context.Context, tx *sql.Tx) error {
 		_, err := cluster.CreateCoreClusterMember(ctx, tx, clusterRecord)
+		if err != nil {
+			return err
+		}
 
-		return err
+		return update.UpdateClusterMemberNamespaceVersions(ctx, tx, db.schema.NamespaceVersions(), clusterRecord.Name)
 	})
 	if err != nil {
 		return err
@@ -151,18 +181,24 @@ func (db *DqliteDB) Bootstrap(extensions extensions.Extensions, project string,
 func (db *DqliteDB) Join(extensions extensions.Extensions, project string, addr api.URL, joinAddresses ...string) error {
 	var err error
 	db.listenAddr = addr
-	db.dqlite, err = dqlite.New(db.os.DatabaseDir,
+	db.rolesAdjustmentFrequency = db.GetHeartbeatInterval()
+	options := []dqlite.Option{
 		dqlite.WithCluster(joinAddresses),
-		dqlite.WithRolesAdjustmentFrequency(db.heartbeatInterval),
+		dqlite.WithRolesAdjustmentFrequency(db.rolesAdjustmentFrequency),
 		dqlite.WithRolesAdjustmentHook(db.heartbeat),
 		dqlite.WithAddress(db.listenAddr.URL.Host),
 		dqlite.WithConcurrentLeaderConns(&db.maxConns),
 		dqlite.WithExternalConn(db.dialFunc(), db.acceptCh),
-		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)))
+		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)),
+	}
+
+	db.dqlite, err = dqlite.New(db.os.DatabaseDir, append(options, db.dqliteOptions.appOptions()...)...)
 	if err != nil {
 		return fmt.Errorf("Failed to join dqlite cluster %w", err)
 	}
 
+	go db.heartbeatLoop(db.initiateHeartbeat)
+
 	for {
 		err := db.Open(extensions, false, project)
 		if err == nil {
@@ -198,6 +234,33 @@ func (db *DqliteDB) Leader(ctx context.Context) (*dqliteClient.Client, error) {
 	return db.dqlite.Leader(ctx, dqliteClient.WithConcurrentLeaderConns(1))
 }
 
+// Dump returns the files of the database as dumped by the dqlite leader, which are consistent even while the
+// database is in use, unlike the files of the database directory.
+func (db *DqliteDB) Dump(ctx context.Context) ([]dqliteClient.File, error) {
+	client, err := db.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = client.Close() }()
+
+	files, err := client.Dump(ctx, db.dbName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to dump database: %w", err)
+	}
+
+	return files, nil
+}
+
+// Handover transfers the voting roles and leadership of the local dqlite node to other cluster members, if any.
+func (db *DqliteDB) Handover(ctx context.Context) error {
+	if db.dqlite == nil {
+		return nil
+	}
+
+	return db.dqlite.Handover(ctx)
+}
+
 // Cluster returns information about dqlite cluster members.
 func (db *DqliteDB) Cluster(ctx context.Context, client *dqliteClient.Client) ([]dqliteClient.NodeInfo, error) {
 	members, err := client.Cluster(ctx)
@@ -226,7 +289,7 @@ func (db *DqliteDB) Status() types.DatabaseStatus {
 // The returned error may have the http status 503, indicating that the database is in a valid but unavailable state.
 func (db *DqliteDB) IsOpen(ctx context.Context) error {
 	if db == nil {
-		return api.StatusErrorf(http.StatusServiceUnavailable, string(types.DatabaseNotReady))
+		return api.StatusErrorf(http.StatusServiceUnavailable, "%w", types.ErrNotBootstrapped)
 	}
 
 	db.statusLock.RLock()
@@ -237,7 +300,7 @@ func (db *DqliteDB) IsOpen(ctx context.Context) error {
 	case types.DatabaseReady:
 		return nil
 	case types.DatabaseNotReady:
-		fallthrough
+		return api.StatusErrorf(http.StatusServiceUnavailable, "%w", types.ErrNotBootstrapped)
 	case types.DatabaseOffline:
 		fallthrough
 	case types.DatabaseStarting:
@@ -283,10 +346,35 @@ func (db *DqliteDB) NotifyUpgraded() {
 	}
 }
 
+// UpgradeStage returns the progress of this cluster member through a coordinated upgrade.
+func (db *DqliteDB) UpgradeStage() types.UpgradeStage {
+	db.statusLock.RLock()
+	defer db.statusLock.RUnlock()
+
+	return db.upgradeStage
+}
+
+// SetUpgradeHook sets a function to be called whenever this cluster member moves to a new upgrade stage.
+func (db *DqliteDB) SetUpgradeHook(hook func(stage types.UpgradeStage)) {
+	db.upgradeHook = hook
+}
+
+// setUpgradeStage records the upgrade stage, and calls the upgrade hook if it has changed.
+func (db *DqliteDB) setUpgradeStage(stage types.UpgradeStage) {
+	db.statusLock.Lock()
+	changed := db.upgradeStage != stage
+	db.upgradeStage = stage
+	db.statusLock.Unlock()
+
+	if changed && db.upgradeHook != nil {
+		db.upgradeHook(stage)
+	}
+}
+
 // dialFunc to be passed to dqlite.
 func (db *DqliteDB) dialFunc() dqliteClient.DialFunc {
 	return func(ctx context.Context, address string) (net.Conn, error) {
-		conn, err := dqliteNetworkDial(ctx, address, db)
+		conn, err := dqliteNetworkDial(ctx, address, db.serverCert(), db.clusterCert())
 		if err != nil {
 			return nil, fmt.Errorf("Failed to dial https socket: %w", err)
 		}
@@ -297,17 +385,103 @@ func (db *DqliteDB) dialFunc() dqliteClient.DialFunc {
 
 // GetHeartbeatInterval returns the current database heartbeat interval.
 func (db *DqliteDB) GetHeartbeatInterval() time.Duration {
+	db.heartbeatConfigLock.RLock()
+	defer db.heartbeatConfigLock.RUnlock()
+
 	return db.heartbeatInterval
 }
 
+// GetOfflineThreshold returns how long an unreachable cluster member may go without a heartbeat before it is considered offline.
+func (db *DqliteDB) GetOfflineThreshold() time.Duration {
+	db.heartbeatConfigLock.RLock()
+	defer db.heartbeatConfigLock.RUnlock()
+
+	return db.offlineThreshold
+}
+
+// SetHeartbeatConfig applies the given heartbeat settings right away. An interval of 0 restores the interval the daemon
+// was started with. Dqlite role adjustments keep the frequency they were started with until the daemon is restarted.
+func (db *DqliteDB) SetHeartbeatConfig(config types.HeartbeatConfig) {
+	db.heartbeatConfigLock.Lock()
+	db.heartbeatInterval = config.Interval
+	if db.heartbeatInterval == 0 {
+		db.heartbeatInterval = db.defaultHeartbeatInterval
+	}
+
+	db.offlineThreshold = config.OfflineThreshold
+	db.heartbeatConfigLock.Unlock()
+
+	select {
+	case db.heartbeatConfigCh <- struct{}{}:
+	default:
+	}
+}
+
+// heartbeatLoop calls initiate at the heartbeat interval whenever it is shorter than the frequency of dqlite role
+// adjustments, which also initiate heartbeat rounds but can't be changed once dqlite is started. Longer intervals need
+// no loop, as heartbeat rounds are skipped until the interval has elapsed since the last one.
+func (db *DqliteDB) heartbeatLoop(initiate func()) {
+	for {
+		interval := db.GetHeartbeatInterval()
+		timer := time.NewTimer(interval)
+		tick := timer.C
+		if interval >= db.rolesAdjustmentFrequency {
+			tick = nil
+		}
+
+		select {
+		case <-db.ctx.Done():
+			timer.Stop()
+			return
+		case <-db.heartbeatConfigCh:
+			timer.Stop()
+			continue
+		case <-tick:
+		}
+
+		initiate()
+	}
+}
+
+// initiateHeartbeat initiates a heartbeat round if the local dqlite node is the leader, like a dqlite role adjustment would.
+func (db *DqliteDB) initiateHeartbeat() {
+	ctx, cancel := context.WithTimeout(db.ctx, db.GetHeartbeatInterval())
+	defer cancel()
+
+	client, err := db.dqlite.Client(ctx)
+	if err != nil {
+		logger.Debug("Failed to connect to the local dqlite node, skipping heartbeat", logger.Ctx{"address": db.listenAddr.String(), "error": err})
+		return
+	}
+
+	defer func() { _ = client.Close() }()
+
+	leader, err := client.Leader(ctx)
+	if err != nil || leader == nil || leader.Address != db.listenAddr.URL.Host {
+		return
+	}
+
+	servers, err := db.Cluster(ctx, client)
+	if err != nil {
+		logger.Debug("Skipping heartbeat", logger.Ctx{"address": db.listenAddr.String(), "error": err})
+		return
+	}
+
+	_ = db.heartbeat(*leader, servers)
+}
+
 // SendHeartbeat initiates a new heartbeat sequence if this is a leader node.
-func (db *DqliteDB) SendHeartbeat(ctx context.Context, c *internalClient.Client, hbInfo internalTypes.HeartbeatInfo) error {
+// The response is empty if the cluster member does not report its time.
+func (db *DqliteDB) SendHeartbeat(ctx context.Context, c *internalClient.Client, hbInfo internalTypes.HeartbeatInfo) (internalTypes.HeartbeatResponse, error) {
 	// set the heartbeat timeout to twice the heartbeat interval.
-	heartbeatTimeout := db.heartbeatInterval * 2
+	heartbeatTimeout := db.GetHeartbeatInterval() * 2
 	queryCtx, cancel := context.WithTimeout(ctx, heartbeatTimeout)
 	defer cancel()
 
-	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("heartbeat"), hbInfo, nil)
+	var resp internalTypes.HeartbeatResponse
+	err := c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("heartbeat"), hbInfo, &resp)
+
+	return resp, err
 }
 
 func (db *DqliteDB) heartbeat(leaderInfo dqliteClient.NodeInfo, servers []dqliteClient.NodeInfo) error {
@@ -342,8 +516,8 @@ func (db *DqliteDB) heartbeat(leaderInfo dqliteClient.NodeInfo, servers []dqlite
 		hbInfo.DqliteRoles[server.Address] = server.Role.String()
 	}
 
-	err = db.SendHeartbeat(db.ctx, client, hbInfo)
-	if err != nil && err.Error() != "Attempt to initiate heartbeat from non-leader" {
+	_, err = db.SendHeartbeat(db.ctx, client, hbInfo)
+	if err != nil && !errors.Is(err, types.ErrNotLeader) {
 		logger.Error("Failed to initiate heartbeat round", logger.Ctx{"address": db.dqlite.Address(), "error": err})
 		return nil
 	}
@@ -352,13 +526,23 @@ func (db *DqliteDB) heartbeat(leaderInfo dqliteClient.NodeInfo, servers []dqlite
 }
 
 // dqliteNetworkDial creates a connection to the internal database endpoint.
-func dqliteNetworkDial(ctx context.Context, addr string, db *DqliteDB) (net.Conn, error) {
-	peerCert, err := db.clusterCert().PublicKeyX509()
+func dqliteNetworkDial(ctx context.Context, addr string, serverCert *shared.CertInfo, clusterCert *shared.CertInfo) (net.Conn, error) {
+	ctx, span := tracing.Start(ctx, "dqlite dial", attribute.String("server.address", addr))
+	conn, err := dqliteNetworkDialTLS(ctx, addr, serverCert, clusterCert)
+	tracing.End(span, err)
+
+	return conn, err
+}
+
+// dqliteNetworkDialTLS establishes a TLS connection to the database endpoint of the given address, authenticated with
+// the given server certificate, and upgrades it to a dqlite connection.
+func dqliteNetworkDialTLS(ctx context.Context, addr string, serverCert *shared.CertInfo, clusterCert *shared.CertInfo) (net.Conn, error) {
+	peerCert, err := clusterCert.PublicKeyX509()
 	if err != nil {
 		return nil, err
 	}
 
-	config, err := internalClient.TLSClientConfig(db.serverCert(), peerCert)
+	config, err := internalClient.TLSClientConfig(serverCert, peerCert)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to parse TLS config: %w", err)
 	}
@@ -381,15 +565,13 @@ func dqliteNetworkDial(ctx context.Context, addr string, db *DqliteDB) (net.Conn
 
 	request.Header.Set("Upgrade", "dqlite")
 	request.Header.Set("X-Dqlite-Version", fmt.Sprintf("%d", 1))
+	tracing.Inject(ctx, request.Header)
 	request = request.WithContext(ctx)
 
 	revert := revert.New()
 	defer revert.Fail()
 
-	deadline, _ := ctx.Deadline()
-	dialer := &net.Dialer{Timeout: time.Until(deadline)}
-	tlsDialer := tls.Dialer{NetDialer: dialer, Config: config}
-	conn, err := tlsDialer.DialContext(ctx, "tcp", addr)
+	conn, err := internalClient.DialTLS(ctx, addr, config)
 	if err != nil {
 		return nil, fmt.Errorf("Failed connecting to HTTP endpoint %q: %w", addr, err)
 	}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures heartbeat rounds are only initiated by the loop while the interval is shorter than the frequency of dqlite
+// role adjustments, and that a changed interval applies right away.
+func TestHeartbeatLoop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db := &DqliteDB{
+		ctx:                      ctx,
+		heartbeatConfigCh:        make(chan struct{}, 1),
+		defaultHeartbeatInterval: time.Hour,
+		heartbeatInterval:        time.Hour,
+		rolesAdjustmentFrequency: time.Hour,
+	}
+
+	var rounds atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		db.heartbeatLoop(func() { rounds.Add(1) })
+	}()
+
+	// The role adjustments initiate the heartbeat rounds at the interval the daemon was started with.
+	time.Sleep(50 * time.Millisecond)
+	require.Zero(t, rounds.Load())
+
+	db.SetHeartbeatConfig(types.HeartbeatConfig{Interval: 5 * time.Millisecond})
+	require.Eventually(t, func() bool { return rounds.Load() >= 3 }, 5*time.Second, 5*time.Millisecond)
+
+	// Restoring the interval the daemon was started with stops the loop from initiating heartbeat rounds.
+	db.SetHeartbeatConfig(types.HeartbeatConfig{})
+	time.Sleep(20 * time.Millisecond)
+	stopped := rounds.Load()
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, stopped, rounds.Load())
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Heartbeat loop did not stop with the database")
+	}
+}
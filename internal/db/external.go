@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"path/filepath"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	dqliteDriver "github.com/canonical/go-dqlite/driver"
+
+	"github.com/canonical/microcluster/v3/internal/sys"
+)
+
+// OpenExternal opens the database of the cluster from a process other than the daemon, through the dqlite driver.
+// Connections are made to the dqlite leader, found from the dqlite members recorded by the local daemon, and are
+// authenticated with the certificates in its state directory.
+// If readOnly is set, every connection sets the query_only pragma, so that statements cannot modify the database.
+func OpenExternal(ctx context.Context, filesystem *sys.OS, readOnly bool) (*sql.DB, error) {
+	serverCert, err := filesystem.ServerCert()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterCert, err := filesystem.ClusterCert()
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the recorded members, so that the record of the daemon is never modified.
+	yamlStore, err := dqliteClient.NewYamlNodeStore(filepath.Join(filesystem.DatabaseDir, "cluster.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read dqlite members: %w", err)
+	}
+
+	nodes, err := yamlStore.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read dqlite members: %w", err)
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("No dqlite members are recorded, the daemon may not be initialized")
+	}
+
+	store := dqliteClient.NewInmemNodeStore()
+	err = store.Set(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	dial := func(ctx context.Context, address string) (net.Conn, error) {
+		return dqliteNetworkDial(ctx, address, serverCert, clusterCert)
+	}
+
+	d, err := dqliteDriver.New(store, dqliteDriver.WithDialFunc(dial))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create dqlite driver: %w", err)
+	}
+
+	var connector driver.Connector
+	connector, err = d.OpenConnector(filepath.Base(filesystem.DatabasePath()))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open database connector: %w", err)
+	}
+
+	if readOnly {
+		connector = readOnlyConnector{connector: connector}
+	}
+
+	sqlDB := sql.OpenDB(connector)
+	err = sqlDB.PingContext(ctx)
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("Failed to connect to the database: %w", err)
+	}
+
+	return sqlDB, nil
+}
+
+// readOnlyConnector opens connections that cannot modify the database.
+type readOnlyConnector struct {
+	connector driver.Connector
+}
+
+// Connect opens a connection and sets the query_only pragma on it.
+func (c readOnlyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		_ = conn.Close()
+		return nil, fmt.Errorf("Database driver cannot execute statements")
+	}
+
+	_, err = execer.ExecContext(ctx, "PRAGMA query_only = ON", nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("Failed to make connection read-only: %w", err)
+	}
+
+	return conn, nil
+}
+
+// Driver returns the underlying driver.
+func (c readOnlyConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
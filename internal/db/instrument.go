@@ -0,0 +1,460 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// DefaultSlowQueryThreshold is the duration above which database statements are logged as slow by default.
+const DefaultSlowQueryThreshold = time.Second
+
+// maxQueryStats is the number of distinct statements for which statistics are kept.
+// Further statements are only counted in the totals.
+const maxQueryStats = 1024
+
+// instrumentFile is the source file of the driver wrappers, whose frames are skipped when looking for the caller of a statement.
+var instrumentFile = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return file
+}()
+
+// callerSkipPrefixes are the packages between the caller of a statement and the driver wrappers.
+var callerSkipPrefixes = []string{
+	"database/sql.",
+	"github.com/canonical/lxd/lxd/db/query.",
+}
+
+// queryKey identifies a statement run from a particular caller.
+type queryKey struct {
+	query  string
+	caller string
+}
+
+// queryStats aggregates the duration of the statements run against the database.
+type queryStats struct {
+	mu         sync.Mutex
+	threshold  time.Duration
+	totals     types.DatabaseStats
+	statements map[queryKey]*types.DatabaseQueryStats
+}
+
+// newQueryStats returns an empty set of statistics logging statements slower than the given threshold.
+func newQueryStats(threshold time.Duration) *queryStats {
+	s := &queryStats{statements: map[queryKey]*types.DatabaseQueryStats{}}
+	s.setThreshold(threshold)
+
+	return s
+}
+
+// setThreshold sets the duration above which statements are logged as slow.
+// A zero threshold uses DefaultSlowQueryThreshold, and a negative one disables the slow query log.
+func (s *queryStats) setThreshold(threshold time.Duration) {
+	if threshold == 0 {
+		threshold = DefaultSlowQueryThreshold
+	}
+
+	s.mu.Lock()
+	s.threshold = threshold
+	s.mu.Unlock()
+}
+
+// observe records a statement that started at the given time and completed with the given error.
+func (s *queryStats) observe(query string, start time.Time, err error) {
+	// The driver does not support the call, so database/sql falls back to another one which is recorded instead.
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	s.record(strings.Join(strings.Fields(query), " "), queryCaller(), time.Since(start), err)
+}
+
+// record adds a statement run by the given caller to the statistics, and logs it if it was slow.
+func (s *queryStats) record(query string, caller string, duration time.Duration, err error) {
+	s.mu.Lock()
+	slow := s.threshold > 0 && duration > s.threshold
+
+	s.totals.Queries++
+	s.totals.TotalDuration += duration
+	if err != nil {
+		s.totals.Errors++
+	}
+
+	if slow {
+		s.totals.SlowQueries++
+	}
+
+	stat := s.statement(queryKey{query: query, caller: caller})
+	if stat != nil {
+		stat.Count++
+		stat.TotalDuration += duration
+		stat.MaxDuration = max(stat.MaxDuration, duration)
+		if err != nil {
+			stat.Errors++
+		}
+
+		if slow {
+			stat.SlowQueries++
+		}
+	}
+
+	threshold := s.threshold
+	s.mu.Unlock()
+
+	if slow {
+		logger.Warn("Slow database query", logger.Ctx{"query": query, "caller": caller, "duration": duration, "threshold": threshold, "error": err})
+	}
+}
+
+// statement returns the statistics of the given statement, or nil if there is no room to track it.
+// It must be called with the lock held.
+func (s *queryStats) statement(key queryKey) *types.DatabaseQueryStats {
+	stat, ok := s.statements[key]
+	if ok {
+		return stat
+	}
+
+	if len(s.statements) >= maxQueryStats {
+		return nil
+	}
+
+	stat = &types.DatabaseQueryStats{Query: key.query, Caller: key.caller}
+	s.statements[key] = stat
+
+	return stat
+}
+
+// snapshot returns a copy of the statistics, with the statements that took the most time in total first.
+func (s *queryStats) snapshot() types.DatabaseStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.totals
+	stats.SlowQueryThreshold = s.threshold
+	stats.Statements = make([]types.DatabaseQueryStats, 0, len(s.statements))
+	for _, stat := range s.statements {
+		stats.Statements = append(stats.Statements, *stat)
+	}
+
+	slices.SortFunc(stats.Statements, func(a types.DatabaseQueryStats, b types.DatabaseQueryStats) int {
+		return cmp.Or(cmp.Compare(b.TotalDuration, a.TotalDuration), strings.Compare(a.Query, b.Query), strings.Compare(a.Caller, b.Caller))
+	})
+
+	return stats
+}
+
+// queryCaller returns the name of the function that ran the current statement.
+func queryCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		skip := frame.File == instrumentFile
+		for _, prefix := range callerSkipPrefixes {
+			if strings.HasPrefix(frame.Function, prefix) {
+				skip = true
+				break
+			}
+		}
+
+		if !skip {
+			return frame.Function
+		}
+
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// instrument returns a database handle that opens its connections through the driver of the given handle,
+// and records every statement in the query statistics. The given handle is closed.
+func (db *DqliteDB) instrument(sqlDB *sql.DB) (*sql.DB, error) {
+	connector, err := newInstrumentedConnector(sqlDB.Driver(), db.dbName, db.queryStats)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sqlDB.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.OpenDB(connector), nil
+}
+
+// QueryStats returns aggregate statistics about the statements run against the database since it was opened.
+func (db *DqliteDB) QueryStats() types.DatabaseStats {
+	return db.queryStats.snapshot()
+}
+
+// dsnConnector opens connections to a data source name through a driver that does not implement driver.DriverContext.
+type dsnConnector struct {
+	driver driver.Driver
+	name   string
+}
+
+// Connect opens a connection to the data source.
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+// Driver returns the underlying driver.
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// instrumentedConnector opens connections that record their statements in the query statistics.
+type instrumentedConnector struct {
+	connector driver.Connector
+	stats     *queryStats
+}
+
+// newInstrumentedConnector returns a connector to the named data source of the given driver.
+func newInstrumentedConnector(d driver.Driver, name string, stats *queryStats) (driver.Connector, error) {
+	var connector driver.Connector = dsnConnector{driver: d, name: name}
+	driverCtx, ok := d.(driver.DriverContext)
+	if ok {
+		var err error
+		connector, err = driverCtx.OpenConnector(name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open database connector: %w", err)
+		}
+	}
+
+	return &instrumentedConnector{connector: connector, stats: stats}, nil
+}
+
+// Connect opens an instrumented connection.
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedConn{conn: conn, stats: c.stats}, nil
+}
+
+// Driver returns the underlying driver.
+func (c *instrumentedConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// instrumentedConn records the statements run on a driver connection.
+// Optional driver interfaces are forwarded if the underlying connection implements them.
+type instrumentedConn struct {
+	conn  driver.Conn
+	stats *queryStats
+}
+
+// Prepare implements driver.Conn.
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext.
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	preparer, ok := c.conn.(driver.ConnPrepareContext)
+	if ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedStmt{stmt: stmt, conn: c.conn, query: query, stats: c.stats}, nil
+}
+
+// Close implements driver.Conn.
+func (c *instrumentedConn) Close() error {
+	return c.conn.Close()
+}
+
+// Begin implements driver.Conn.
+func (c *instrumentedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx.
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	beginner, ok := c.conn.(driver.ConnBeginTx)
+	if ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+
+	return c.conn.Begin() //nolint:staticcheck
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.stats.observe(query, start, err)
+
+	return result, err
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.stats.observe(query, start, err)
+
+	return rows, err
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (c *instrumentedConn) CheckNamedValue(value *driver.NamedValue) error {
+	checker, ok := c.conn.(driver.NamedValueChecker)
+	if !ok {
+		return driver.ErrSkip
+	}
+
+	return checker.CheckNamedValue(value)
+}
+
+// ResetSession implements driver.SessionResetter.
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	resetter, ok := c.conn.(driver.SessionResetter)
+	if !ok {
+		return nil
+	}
+
+	return resetter.ResetSession(ctx)
+}
+
+// IsValid implements driver.Validator.
+func (c *instrumentedConn) IsValid() bool {
+	validator, ok := c.conn.(driver.Validator)
+	if !ok {
+		return true
+	}
+
+	return validator.IsValid()
+}
+
+// instrumentedStmt records the executions of a prepared statement.
+type instrumentedStmt struct {
+	stmt  driver.Stmt
+	conn  driver.Conn
+	query string
+	stats *queryStats
+}
+
+// Close implements driver.Stmt.
+func (s *instrumentedStmt) Close() error {
+	return s.stmt.Close()
+}
+
+// NumInput implements driver.Stmt.
+func (s *instrumentedStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+// Exec implements driver.Stmt.
+func (s *instrumentedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.stmt.Exec(args) //nolint:staticcheck
+}
+
+// Query implements driver.Stmt.
+func (s *instrumentedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.stmt.Query(args) //nolint:staticcheck
+}
+
+// ExecContext implements driver.StmtExecContext.
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	execer, ok := s.stmt.(driver.StmtExecContext)
+	if ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		values, err = namedValuesToValues(args)
+		if err == nil {
+			result, err = s.stmt.Exec(values) //nolint:staticcheck
+		}
+	}
+
+	s.stats.observe(s.query, start, err)
+
+	return result, err
+}
+
+// QueryContext implements driver.StmtQueryContext.
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	queryer, ok := s.stmt.(driver.StmtQueryContext)
+	if ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		values, err = namedValuesToValues(args)
+		if err == nil {
+			rows, err = s.stmt.Query(values) //nolint:staticcheck
+		}
+	}
+
+	s.stats.observe(s.query, start, err)
+
+	return rows, err
+}
+
+// CheckNamedValue implements driver.NamedValueChecker, deferring to the statement or else its connection.
+func (s *instrumentedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	checker, ok := s.stmt.(driver.NamedValueChecker)
+	if !ok {
+		checker, ok = s.conn.(driver.NamedValueChecker)
+	}
+
+	if !ok {
+		return driver.ErrSkip
+	}
+
+	return checker.CheckNamedValue(value)
+}
+
+// namedValuesToValues converts the arguments of a statement for drivers that do not support named parameters.
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, 0, len(args))
+	for _, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("Driver does not support the use of named parameters")
+		}
+
+		values = append(values, arg.Value)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver is a driver whose statements do nothing, or fail if their text is "fail".
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "fail" {
+		return nil, errors.New("Statement failed")
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+type fakeStmt struct {
+	query string
+}
+
+func (fakeStmt) Close() error                                    { return nil }
+func (fakeStmt) NumInput() int                                   { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error)  { return fakeRows{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+// Ensures statements run through an instrumented database are recorded along with their caller.
+func TestInstrumentedConnector(t *testing.T) {
+	stats := newQueryStats(-1)
+	connector, err := newInstrumentedConnector(fakeDriver{}, "test", stats)
+	require.NoError(t, err)
+
+	sqlDB := sql.OpenDB(connector)
+	defer sqlDB.Close()
+
+	_, err = sqlDB.Exec("INSERT INTO   test\n VALUES (1)")
+	require.NoError(t, err)
+
+	_, err = sqlDB.Exec("fail")
+	require.Error(t, err)
+
+	stmt, err := sqlDB.Prepare("SELECT id FROM test")
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		rows, err := stmt.Query()
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+	}
+
+	require.NoError(t, stmt.Close())
+
+	snapshot := stats.snapshot()
+	require.Equal(t, uint64(4), snapshot.Queries)
+	require.Equal(t, uint64(1), snapshot.Errors)
+	require.Equal(t, uint64(0), snapshot.SlowQueries)
+	require.Len(t, snapshot.Statements, 3)
+
+	counts := map[string]uint64{}
+	for _, stat := range snapshot.Statements {
+		require.Equal(t, "github.com/canonical/microcluster/v3/internal/db.TestInstrumentedConnector", stat.Caller)
+		counts[stat.Query] = stat.Count
+	}
+
+	require.Equal(t, map[string]uint64{"INSERT INTO test VALUES (1)": 1, "fail": 1, "SELECT id FROM test": 2}, counts)
+}
+
+// Ensures slow statements are counted, and that statistics stop growing past the statement limit.
+func TestQueryStatsRecord(t *testing.T) {
+	stats := newQueryStats(time.Second)
+	stats.record("slow", "caller", 2*time.Second, nil)
+	stats.record("slow", "caller", time.Millisecond, nil)
+
+	for i := 0; i < maxQueryStats+10; i++ {
+		stats.record("fast", string(rune('a'+i)), time.Millisecond, nil)
+	}
+
+	snapshot := stats.snapshot()
+	require.Equal(t, uint64(maxQueryStats+12), snapshot.Queries)
+	require.Equal(t, uint64(1), snapshot.SlowQueries)
+	require.Len(t, snapshot.Statements, maxQueryStats)
+	require.Equal(t, time.Second, snapshot.SlowQueryThreshold)
+
+	slowest := snapshot.Statements[0]
+	require.Equal(t, "slow", slowest.Query)
+	require.Equal(t, uint64(2), slowest.Count)
+	require.Equal(t, uint64(1), slowest.SlowQueries)
+	require.Equal(t, 2*time.Second, slowest.MaxDuration)
+	require.Equal(t, 2*time.Second+time.Millisecond, slowest.TotalDuration)
+}
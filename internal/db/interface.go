@@ -13,6 +13,8 @@ import (
 // DB exposes the internal database for use with external projects.
 type DB interface {
 	// Transaction handles performing a transaction on the dqlite database.
+	// The transaction is retried with increasing delays if it fails because of a dqlite leadership change or a
+	// locked database, so f must be safe to run more than once.
 	Transaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error
 
 	// Leader returns a client connected to the leader of the dqlite cluster.
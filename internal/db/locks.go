@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+const (
+	// LockTTL is how long a cluster-wide lock is held without being renewed.
+	// Clocks of cluster members are expected to be skewed by less than this.
+	LockTTL = 30 * time.Second
+
+	// lockRetryInterval is how often a held lock is polled while waiting to acquire it.
+	lockRetryInterval = 500 * time.Millisecond
+)
+
+// Lock is a cluster-wide lock held by the local cluster member.
+// It is backed by a lease in the database which is renewed in the background until the lock is released.
+type Lock struct {
+	db    *DqliteDB
+	name  string
+	token string
+
+	stop     context.CancelFunc
+	done     chan struct{}
+	lost     chan struct{}
+	lostOnce sync.Once
+}
+
+// Lock acquires the cluster-wide lock with the given name, waiting until it is released by its current holder,
+// its lease expires, or the context is cancelled.
+func (db *DqliteDB) Lock(ctx context.Context, name string) (*Lock, error) {
+	if name == "" {
+		return nil, fmt.Errorf("Lock name cannot be empty")
+	}
+
+	tokenBytes := make([]byte, 16)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate lock token: %w", err)
+	}
+
+	token := hex.EncodeToString(tokenBytes)
+	for {
+		acquired, err := db.tryLock(ctx, name, token)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to acquire lock %q: %w", name, err)
+		}
+
+		if acquired {
+			break
+		}
+
+		timer := time.NewTimer(lockRetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("Failed to acquire lock %q: %w", name, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	renewCtx, stop := context.WithCancel(db.ctx)
+	lock := &Lock{
+		db:    db,
+		name:  name,
+		token: token,
+		stop:  stop,
+		done:  make(chan struct{}),
+		lost:  make(chan struct{}),
+	}
+
+	go lock.renew(renewCtx)
+
+	return lock, nil
+}
+
+// tryLock records a lease for the lock if it is free or its previous lease has expired.
+func (db *DqliteDB) tryLock(ctx context.Context, name string, token string) (bool, error) {
+	acquired := false
+	err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		now := time.Now()
+		_, err := tx.ExecContext(ctx, "DELETE FROM core_locks WHERE name = ? AND expiry < ?", name, now.UnixNano())
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO core_locks (name, owner, token, expiry) VALUES (?, ?, ?, ?)", name, db.memberName(), token, now.Add(LockTTL).UnixNano())
+		if err != nil {
+			return err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		acquired = n == 1
+
+		return nil
+	})
+
+	return acquired, err
+}
+
+// renew extends the lease of the lock until it is released, or marks it as lost if the lease expires first.
+func (l *Lock) renew(ctx context.Context) {
+	defer close(l.done)
+
+	expiry := time.Now().Add(LockTTL)
+	ticker := time.NewTicker(LockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		renewed := false
+		err := l.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			result, err := tx.ExecContext(ctx, "UPDATE core_locks SET expiry = ? WHERE name = ? AND token = ?", now.Add(LockTTL).UnixNano(), l.name, l.token)
+			if err != nil {
+				return err
+			}
+
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			renewed = n == 1
+
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil && renewed {
+			expiry = now.Add(LockTTL)
+			continue
+		}
+
+		if err == nil || time.Now().After(expiry) {
+			logger.Warn("Lost cluster-wide lock", logger.Ctx{"lock": l.name, "error": err})
+			l.lostOnce.Do(func() { close(l.lost) })
+
+			return
+		}
+
+		logger.Warn("Failed to renew cluster-wide lock", logger.Ctx{"lock": l.name, "error": err})
+	}
+}
+
+// Lost returns a channel which is closed if the lease of the lock expired before it could be renewed,
+// in which case another cluster member may have acquired it.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.stop()
+	<-l.done
+
+	err := l.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM core_locks WHERE name = ? AND token = ?", l.name, l.token)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to release lock %q: %w", l.name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// MaintenanceSchedule configures the periodic maintenance of the database by the dqlite leader.
+type MaintenanceSchedule struct {
+	types.DatabaseMaintenance
+
+	// Interval is the delay between periodic maintenance runs. If it's 0, no periodic maintenance is run.
+	Interval time.Duration
+}
+
+// Validate checks that the interval is not negative, and that the schedule runs some maintenance.
+func (s MaintenanceSchedule) Validate() error {
+	if s.Interval < 0 {
+		return fmt.Errorf("Maintenance interval cannot be negative")
+	}
+
+	if s.Interval > 0 && !s.Vacuum && !s.Analyze {
+		return fmt.Errorf("Periodic maintenance must vacuum or analyze the database")
+	}
+
+	return nil
+}
+
+// Maintain runs the given maintenance on the database. It must run on the dqlite leader.
+// The statements run outside of any transaction, as a vacuum cannot run within one.
+func (db *DqliteDB) Maintain(ctx context.Context, maintenance types.DatabaseMaintenance) error {
+	err := db.IsOpen(ctx)
+	if err != nil {
+		return err
+	}
+
+	if maintenance.Analyze {
+		err = db.retry(ctx, func(ctx context.Context) error {
+			_, err := db.db.ExecContext(ctx, "ANALYZE")
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to analyze the database: %w", err)
+		}
+	}
+
+	if maintenance.Vacuum {
+		err = db.retry(ctx, func(ctx context.Context) error {
+			_, err := db.db.ExecContext(ctx, "VACUUM")
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to vacuum the database: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Usage returns the space used by the database, without the disk usage of cluster members.
+func (db *DqliteDB) Usage(ctx context.Context) (types.DatabaseUsage, error) {
+	usage := types.DatabaseUsage{}
+	err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for pragma, dest := range map[string]*int64{"page_size": &usage.PageSize, "page_count": &usage.PageCount, "freelist_count": &usage.FreePages} {
+			err := tx.QueryRowContext(ctx, "PRAGMA "+pragma).Scan(dest)
+			if err != nil {
+				return fmt.Errorf("Failed to read %q pragma: %w", pragma, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return types.DatabaseUsage{}, err
+	}
+
+	return usage, nil
+}
+
+// DiskUsage returns the size in bytes of the local dqlite data directory.
+func (db *DqliteDB) DiskUsage() (int64, error) {
+	var size int64
+	err := filepath.WalkDir(db.os.DatabaseDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		// Raft segments and snapshots may be removed while the directory is walked.
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to measure the database directory: %w", err)
+	}
+
+	return size, nil
+}
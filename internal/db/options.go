@@ -0,0 +1,118 @@
+package db
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/canonical/go-dqlite"
+	dqliteApp "github.com/canonical/go-dqlite/app"
+)
+
+// DqliteOptions tunes the local dqlite node. The zero value keeps the dqlite defaults.
+type DqliteOptions struct {
+	// SnapshotThreshold is the number of raft log entries after which a snapshot is taken.
+	// SnapshotTrailing is the number of entries kept in the log after a snapshot.
+	// Both must be set together.
+	SnapshotThreshold uint64
+	SnapshotTrailing  uint64
+
+	// NetworkLatency is the expected average one-way latency between cluster members, which dqlite uses to
+	// derive its raft heartbeat and election timeouts.
+	NetworkLatency time.Duration
+
+	// DiskMode stores the database on disk rather than in memory. This is an experimental dqlite feature.
+	DiskMode bool
+
+	// SlowQueryThreshold is the duration above which database statements are logged as slow.
+	// It defaults to DefaultSlowQueryThreshold, and a negative value disables the slow query log.
+	SlowQueryThreshold time.Duration
+
+	// TransactionRetryTimeout is how long transactions are retried when they fail because of a dqlite leadership
+	// change or a locked database. It defaults to DefaultTransactionRetryTimeout, and a negative value disables retries.
+	TransactionRetryTimeout time.Duration
+
+	// Voters is the number of cluster members with a voting role, kept by promoting other members when one is lost
+	// or removed. It must be an odd number greater than one, and defaults to 3.
+	// StandBys is the number of cluster members kept replicating the database without voting, ready to be promoted.
+	// It defaults to 3.
+	// Every cluster member must be started with the same Voters and StandBys.
+	Voters   int
+	StandBys int
+
+	// Zone is the failure domain of the cluster member, such as its availability zone or rack. When promoting members,
+	// voting and stand-by roles are spread over as many zones as possible, so that losing a single zone does not lose
+	// every voter. Members without a zone share the same failure domain.
+	Zone string
+}
+
+// Validate checks that the options can be applied to a dqlite node.
+func (o DqliteOptions) Validate() error {
+	if (o.SnapshotThreshold == 0) != (o.SnapshotTrailing == 0) {
+		return fmt.Errorf("Dqlite snapshot threshold and trailing must be set together")
+	}
+
+	if o.NetworkLatency < 0 {
+		return fmt.Errorf("Dqlite network latency cannot be negative")
+	}
+
+	if o.Voters != 0 && (o.Voters < 3 || o.Voters%2 == 0) {
+		return fmt.Errorf("Dqlite voters must be an odd number greater than one")
+	}
+
+	if o.StandBys < 0 {
+		return fmt.Errorf("Dqlite stand-bys cannot be negative")
+	}
+
+	return nil
+}
+
+// appOptions returns the dqlite app options corresponding to the tuning options.
+func (o DqliteOptions) appOptions() []dqliteApp.Option {
+	options := []dqliteApp.Option{}
+	if o.SnapshotThreshold != 0 {
+		options = append(options, dqliteApp.WithSnapshotParams(dqlite.SnapshotParams{Threshold: o.SnapshotThreshold, Trailing: o.SnapshotTrailing}))
+	}
+
+	if o.NetworkLatency != 0 {
+		options = append(options, dqliteApp.WithNetworkLatency(o.NetworkLatency))
+	}
+
+	if o.DiskMode {
+		options = append(options, dqliteApp.WithDiskMode(true))
+	}
+
+	if o.Voters != 0 {
+		options = append(options, dqliteApp.WithVoters(o.Voters))
+	}
+
+	if o.StandBys != 0 {
+		options = append(options, dqliteApp.WithStandBys(o.StandBys))
+	}
+
+	if o.Zone != "" {
+		options = append(options, dqliteApp.WithFailureDomain(failureDomain(o.Zone)))
+	}
+
+	return options
+}
+
+// failureDomain returns the dqlite failure domain code of the zone. Members in the same zone share the same code.
+func failureDomain(zone string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(zone))
+
+	// Zero is the failure domain of members without a zone.
+	code := h.Sum64()
+	if code == 0 {
+		code = 1
+	}
+
+	return code
+}
+
+// SetDqliteOptions sets the tuning options applied when the dqlite node is started.
+func (db *DqliteDB) SetDqliteOptions(options DqliteOptions) {
+	db.dqliteOptions = options
+	db.queryStats.setThreshold(options.SlowQueryThreshold)
+}
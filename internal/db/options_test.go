@@ -0,0 +1,23 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ensures the voter policy is validated, and that members in the same zone share a failure domain.
+func TestDqliteOptionsVoters(t *testing.T) {
+	assert.NoError(t, DqliteOptions{}.Validate())
+	assert.NoError(t, DqliteOptions{Voters: 5, StandBys: 2, Zone: "zone-a"}.Validate())
+	assert.Error(t, DqliteOptions{Voters: 1}.Validate())
+	assert.Error(t, DqliteOptions{Voters: 4}.Validate())
+	assert.Error(t, DqliteOptions{StandBys: -1}.Validate())
+
+	assert.Len(t, DqliteOptions{}.appOptions(), 0)
+	assert.Len(t, DqliteOptions{Voters: 5, StandBys: 2, Zone: "zone-a"}.appOptions(), 3)
+
+	assert.Equal(t, failureDomain("zone-a"), failureDomain("zone-a"))
+	assert.NotEqual(t, failureDomain("zone-a"), failureDomain("zone-b"))
+	assert.NotZero(t, failureDomain(""))
+}
@@ -0,0 +1,345 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// raftMetadataFormat is the version of the format of the raft metadata files.
+const raftMetadataFormat = 1
+
+// raftMetadataSize is the size of a raft metadata file: its format, version, term and vote, as little endian uint64s.
+const raftMetadataSize = 32
+
+// raftSegmentFormat is the version of the format of the raft segment files.
+const raftSegmentFormat = 1
+
+// raftSnapshotsKept is the number of most recent snapshots that raft keeps.
+const raftSnapshotsKept = 2
+
+// defaultSnapshotTrailing is the number of raft log entries that dqlite keeps after a snapshot by default.
+const defaultSnapshotTrailing = 8192
+
+// RaftState returns the raft state persisted by the local dqlite node.
+func (db *DqliteDB) RaftState() (types.DatabaseRaftState, error) {
+	return readRaftState(db.os.DatabaseDir)
+}
+
+// readRaftState reads the raft state from the metadata, snapshot and segment files in the given dqlite data directory.
+func readRaftState(dir string) (types.DatabaseRaftState, error) {
+	state := types.DatabaseRaftState{}
+
+	// Raft alternates between two metadata files, and the one with the highest version is current.
+	var version uint64
+	for _, name := range []string{"metadata1", "metadata2"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return types.DatabaseRaftState{}, err
+		}
+
+		if len(data) != raftMetadataSize || binary.LittleEndian.Uint64(data[0:8]) != raftMetadataFormat {
+			return types.DatabaseRaftState{}, fmt.Errorf("Invalid raft metadata file %q", name)
+		}
+
+		fileVersion := binary.LittleEndian.Uint64(data[8:16])
+		if fileVersion > version {
+			version = fileVersion
+			state.Term = binary.LittleEndian.Uint64(data[16:24])
+		}
+	}
+
+	files, err := readRaftFiles(dir)
+	if err != nil {
+		return types.DatabaseRaftState{}, err
+	}
+
+	var lastClosed *types.DatabaseRaftFile
+	openSegments := []types.DatabaseRaftFile{}
+	for i, file := range files {
+		switch file.Type {
+		case types.DatabaseRaftSnapshot:
+			if file.LastIndex > state.SnapshotIndex {
+				state.SnapshotTerm = file.Term
+				state.SnapshotIndex = file.LastIndex
+			}
+		case types.DatabaseRaftClosedSegment:
+			if lastClosed == nil || file.LastIndex > lastClosed.LastIndex {
+				lastClosed = &files[i]
+			}
+		case types.DatabaseRaftOpenSegment:
+			openSegments = append(openSegments, file)
+		}
+	}
+
+	state.LastIndex = state.SnapshotIndex
+	state.LastTerm = state.SnapshotTerm
+	if lastClosed != nil && lastClosed.LastIndex > state.LastIndex {
+		_, term, err := readRaftSegment(filepath.Join(dir, lastClosed.Name))
+		if err != nil {
+			return types.DatabaseRaftState{}, err
+		}
+
+		state.LastIndex = lastClosed.LastIndex
+		state.LastTerm = term
+	}
+
+	// Open segments are named "open-<counter>", and hold the entries following the last closed segment in the order
+	// they were created. Raft preallocates open segments, so the ones not written yet hold no entries.
+	sort.Slice(openSegments, func(i, j int) bool {
+		return openSegmentCounter(openSegments[i].Name) < openSegmentCounter(openSegments[j].Name)
+	})
+
+	for _, file := range openSegments {
+		entries, term, err := readRaftSegment(filepath.Join(dir, file.Name))
+		if err != nil {
+			return types.DatabaseRaftState{}, err
+		}
+
+		if entries == 0 {
+			continue
+		}
+
+		state.LastIndex += entries
+		state.LastTerm = term
+	}
+
+	return state, nil
+}
+
+// openSegmentCounter returns the counter of the open segment with the given name.
+func openSegmentCounter(name string) uint64 {
+	counter, _ := strconv.ParseUint(strings.TrimPrefix(name, "open-"), 10, 64)
+
+	return counter
+}
+
+// readRaftSegment returns the number of entries in the raft segment file at the given path, and the term of the last
+// one. A segment holds its format version, followed by batches of entries, each made of two checksums, the number of
+// entries, a header for each entry with its term, type and size, and the data of the entries padded to 8 bytes.
+// Reading stops at the first batch that is not fully written, as raft does when it loads an open segment, and at the
+// zeroed space left by preallocation.
+func readRaftSegment(path string) (entries uint64, lastTerm uint64, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, 0, nil
+		}
+
+		return 0, 0, err
+	}
+
+	if len(data) < 8 || binary.LittleEndian.Uint64(data[0:8]) == 0 {
+		return 0, 0, nil
+	}
+
+	if binary.LittleEndian.Uint64(data[0:8]) != raftSegmentFormat {
+		return 0, 0, fmt.Errorf("Invalid raft segment file %q", filepath.Base(path))
+	}
+
+	size := uint64(len(data))
+	offset := uint64(8)
+	for offset+16 <= size {
+		n := binary.LittleEndian.Uint64(data[offset+8 : offset+16])
+		if n == 0 || n > (size-offset-16)/16 {
+			break
+		}
+
+		header := data[offset+8 : offset+16+n*16]
+		if crc32.ChecksumIEEE(header) != binary.LittleEndian.Uint32(data[offset:offset+4]) {
+			break
+		}
+
+		var dataSize uint64
+		for i := uint64(0); i < n; i++ {
+			entrySize := uint64(binary.LittleEndian.Uint32(header[8+i*16+12 : 8+i*16+16]))
+			dataSize += (entrySize + 7) / 8 * 8
+		}
+
+		end := offset + 16 + n*16 + dataSize
+		if end > size {
+			break
+		}
+
+		entries += n
+		lastTerm = binary.LittleEndian.Uint64(header[8+(n-1)*16 : 8+(n-1)*16+8])
+		offset = end
+	}
+
+	return entries, lastTerm, nil
+}
+
+// RaftDump returns the raft state persisted by the local dqlite node, along with the raft files it was read from.
+func (db *DqliteDB) RaftDump() (types.DatabaseRaftDump, error) {
+	return ReadRaftDump(db.os.DatabaseDir)
+}
+
+// ReadRaftDump reads the raft state and lists the raft files of the given dqlite data directory. It does not need the
+// dqlite node to be running, so it can be used to find the member with the most up-to-date raft log before recovering
+// from a quorum loss.
+func ReadRaftDump(dir string) (types.DatabaseRaftDump, error) {
+	state, err := readRaftState(dir)
+	if err != nil {
+		return types.DatabaseRaftDump{}, err
+	}
+
+	files, err := readRaftFiles(dir)
+	if err != nil {
+		return types.DatabaseRaftDump{}, err
+	}
+
+	return types.DatabaseRaftDump{DatabaseRaftState: state, Files: files}, nil
+}
+
+// readRaftFiles lists the raft metadata, segment and snapshot files in the given dqlite data directory, sorted by name.
+func readRaftFiles(dir string) ([]types.DatabaseRaftFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []types.DatabaseRaftFile{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		file := types.DatabaseRaftFile{Name: entry.Name()}
+		switch {
+		case file.Name == "metadata1" || file.Name == "metadata2":
+			file.Type = types.DatabaseRaftMetadata
+		case strings.HasPrefix(file.Name, "open-"):
+			file.Type = types.DatabaseRaftOpenSegment
+		case strings.HasPrefix(file.Name, "snapshot-"):
+			var timestamp uint64
+			_, err := fmt.Sscanf(strings.TrimSuffix(file.Name, ".meta"), "snapshot-%d-%d-%d", &file.Term, &file.LastIndex, &timestamp)
+			if err != nil {
+				continue
+			}
+
+			file.Type = types.DatabaseRaftSnapshot
+			file.FirstIndex = file.LastIndex
+		case len(file.Name) == 33 && file.Name[16] == '-':
+			_, err := fmt.Sscanf(file.Name, "%d-%d", &file.FirstIndex, &file.LastIndex)
+			if err != nil {
+				continue
+			}
+
+			file.Type = types.DatabaseRaftClosedSegment
+		default:
+			continue
+		}
+
+		// Segments and snapshots may be removed by raft while the directory is read.
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		file.Size = info.Size()
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	return files, nil
+}
+
+// CompactRaftFiles removes the raft files that raft no longer needs from the given dqlite data directory: snapshots
+// older than the ones raft keeps, and closed segments whose entries all precede the given number of trailing entries
+// kept after the latest snapshot, or the dqlite default if it is 0. Raft removes these files itself whenever it takes a
+// snapshot, but they can be left behind if the daemon stopped in the meantime.
+// The files are in use by raft while the dqlite node runs, so they must only be compacted while it is stopped.
+func CompactRaftFiles(dir string, trailing uint64) (types.DatabaseRaftCompaction, error) {
+	if trailing == 0 {
+		trailing = defaultSnapshotTrailing
+	}
+
+	return compactRaftFiles(dir, trailing)
+}
+
+// compactRaftFiles removes the raft files that raft no longer needs from the given dqlite data directory, keeping the
+// given number of trailing entries before the latest snapshot.
+func compactRaftFiles(dir string, trailing uint64) (types.DatabaseRaftCompaction, error) {
+	files, err := readRaftFiles(dir)
+	if err != nil {
+		return types.DatabaseRaftCompaction{}, err
+	}
+
+	// Snapshot files come in pairs, so the kept snapshots are identified by their index and term.
+	type snapshotID struct {
+		term  uint64
+		index uint64
+	}
+
+	snapshots := []snapshotID{}
+	seen := map[snapshotID]bool{}
+	for _, file := range files {
+		id := snapshotID{term: file.Term, index: file.LastIndex}
+		if file.Type == types.DatabaseRaftSnapshot && !seen[id] {
+			seen[id] = true
+			snapshots = append(snapshots, id)
+		}
+	}
+
+	compaction := types.DatabaseRaftCompaction{Removed: []types.DatabaseRaftFile{}}
+	if len(snapshots) == 0 {
+		return compaction, nil
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].index > snapshots[j].index })
+	kept := snapshots[:min(len(snapshots), raftSnapshotsKept)]
+	oldestKept := kept[len(kept)-1].index
+	latest := kept[0].index
+
+	for _, file := range files {
+		var stale bool
+		switch file.Type {
+		case types.DatabaseRaftSnapshot:
+			stale = file.LastIndex < oldestKept
+		case types.DatabaseRaftClosedSegment:
+			stale = latest > trailing && file.LastIndex < latest-trailing
+		}
+
+		if !stale {
+			continue
+		}
+
+		err := os.Remove(filepath.Join(dir, file.Name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return compaction, fmt.Errorf("Failed to remove raft file %q: %w", file.Name, err)
+		}
+
+		compaction.Removed = append(compaction.Removed, file)
+		compaction.Freed += file.Size
+	}
+
+	if len(compaction.Removed) > 0 {
+		logger.Info("Removed stale raft files", logger.Ctx{"files": len(compaction.Removed), "freed": compaction.Freed})
+	}
+
+	return compaction, nil
+}
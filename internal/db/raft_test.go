@@ -0,0 +1,224 @@
+package db
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// raftBatch encodes a batch of raft entries with the given terms, each holding 5 bytes of data.
+func raftBatch(terms ...uint64) []byte {
+	header := make([]byte, 8+16*len(terms))
+	binary.LittleEndian.PutUint64(header[0:8], uint64(len(terms)))
+	for i, term := range terms {
+		binary.LittleEndian.PutUint64(header[8+i*16:], term)
+		header[8+i*16+8] = 1
+		binary.LittleEndian.PutUint32(header[8+i*16+12:], 5)
+	}
+
+	data := make([]byte, 8*len(terms))
+	batch := make([]byte, 8, 8+len(header)+len(data))
+	binary.LittleEndian.PutUint32(batch[0:4], crc32.ChecksumIEEE(header))
+	binary.LittleEndian.PutUint32(batch[4:8], crc32.ChecksumIEEE(data))
+
+	return append(append(batch, header...), data...)
+}
+
+// raftSegment encodes a raft segment holding the given batches, followed by the given number of preallocated bytes.
+func raftSegment(prealloc int, batches ...[]byte) []byte {
+	segment := binary.LittleEndian.AppendUint64(nil, raftSegmentFormat)
+	for _, batch := range batches {
+		segment = append(segment, batch...)
+	}
+
+	return append(segment, make([]byte, prealloc)...)
+}
+
+// Ensures the raft state is read from the files of a dqlite data directory.
+func TestReadRaftState(t *testing.T) {
+	writeMetadata := func(dir string, name string, version uint64, term uint64) {
+		data := make([]byte, raftMetadataSize)
+		binary.LittleEndian.PutUint64(data[0:8], raftMetadataFormat)
+		binary.LittleEndian.PutUint64(data[8:16], version)
+		binary.LittleEndian.PutUint64(data[16:24], term)
+		binary.LittleEndian.PutUint64(data[24:32], 1)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+
+	write := func(dir string, name string, data []byte) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+
+	touch := func(dir string, names ...string) {
+		for _, name := range names {
+			write(dir, name, nil)
+		}
+	}
+
+	t.Run("Empty directory", func(t *testing.T) {
+		state, err := readRaftState(t.TempDir())
+		require.NoError(t, err)
+		require.Equal(t, types.DatabaseRaftState{}, state)
+	})
+
+	t.Run("Segments and snapshots", func(t *testing.T) {
+		dir := t.TempDir()
+		writeMetadata(dir, "metadata1", 5, 4)
+		writeMetadata(dir, "metadata2", 4, 2)
+		write(dir, "0000000000000001-0000000000000100", raftSegment(0, raftBatch(1, 1)))
+		write(dir, "0000000000000101-0000000000000250", raftSegment(0, raftBatch(2, 3), raftBatch(3)))
+		write(dir, "open-2", raftSegment(0, raftBatch(4)))
+		write(dir, "open-1", raftSegment(64, raftBatch(3, 3), raftBatch(4)))
+		write(dir, "open-3", make([]byte, 128))
+		touch(dir,
+			"snapshot-2-120-1700000000",
+			"snapshot-2-120-1700000000.meta",
+			"snapshot-3-200-1700000100",
+			"snapshot-3-200-1700000100.meta",
+			"info.yaml",
+			"cluster.yaml",
+		)
+
+		state, err := readRaftState(dir)
+		require.NoError(t, err)
+		require.Equal(t, types.DatabaseRaftState{Term: 4, SnapshotTerm: 3, SnapshotIndex: 200, LastIndex: 254, LastTerm: 4}, state)
+	})
+
+	t.Run("Snapshot ahead of segments", func(t *testing.T) {
+		dir := t.TempDir()
+		writeMetadata(dir, "metadata2", 7, 4)
+		write(dir, "0000000000000001-0000000000000100", raftSegment(0, raftBatch(1)))
+		touch(dir, "snapshot-4-300-1700000000.meta")
+
+		state, err := readRaftState(dir)
+		require.NoError(t, err)
+		require.Equal(t, types.DatabaseRaftState{Term: 4, SnapshotTerm: 4, SnapshotIndex: 300, LastIndex: 300, LastTerm: 4}, state)
+	})
+
+	t.Run("Partially written open segment", func(t *testing.T) {
+		dir := t.TempDir()
+		torn := raftBatch(5, 5)
+		corrupt := raftBatch(6)
+		corrupt[0]++
+
+		write(dir, "0000000000000001-0000000000000010", raftSegment(0, raftBatch(1)))
+		write(dir, "open-1", raftSegment(0, raftBatch(2, 3), torn[:len(torn)-4]))
+		write(dir, "open-2", raftSegment(0, raftBatch(4), corrupt))
+
+		state, err := readRaftState(dir)
+		require.NoError(t, err)
+		require.Equal(t, types.DatabaseRaftState{LastIndex: 13, LastTerm: 4}, state)
+	})
+
+	t.Run("Invalid metadata", func(t *testing.T) {
+		dir := t.TempDir()
+		touch(dir, "metadata1")
+
+		_, err := readRaftState(dir)
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid segment", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "open-1", binary.LittleEndian.AppendUint64(nil, 7))
+
+		_, err := readRaftState(dir)
+		require.Error(t, err)
+	})
+}
+
+// Ensures the raft files of a dqlite data directory are listed with their index ranges.
+func TestReadRaftFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0600))
+	}
+
+	write("metadata1", raftMetadataSize)
+	write("0000000000000101-0000000000000250", 20)
+	write("0000000000000001-0000000000000100", 10)
+	write("open-1", 30)
+	write("snapshot-3-200-1700000100", 40)
+	write("snapshot-3-200-1700000100.meta", 5)
+	write("info.yaml", 1)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "backups"), 0700))
+
+	files, err := readRaftFiles(dir)
+	require.NoError(t, err)
+	require.Equal(t, []types.DatabaseRaftFile{
+		{Name: "0000000000000001-0000000000000100", Type: types.DatabaseRaftClosedSegment, FirstIndex: 1, LastIndex: 100, Size: 10},
+		{Name: "0000000000000101-0000000000000250", Type: types.DatabaseRaftClosedSegment, FirstIndex: 101, LastIndex: 250, Size: 20},
+		{Name: "metadata1", Type: types.DatabaseRaftMetadata, Size: raftMetadataSize},
+		{Name: "open-1", Type: types.DatabaseRaftOpenSegment, Size: 30},
+		{Name: "snapshot-3-200-1700000100", Type: types.DatabaseRaftSnapshot, FirstIndex: 200, LastIndex: 200, Term: 3, Size: 40},
+		{Name: "snapshot-3-200-1700000100.meta", Type: types.DatabaseRaftSnapshot, FirstIndex: 200, LastIndex: 200, Term: 3, Size: 5},
+	}, files)
+}
+
+// Ensures only the raft files that raft no longer needs are removed when compacting.
+func TestCompactRaftFiles(t *testing.T) {
+	touch := func(dir string, names ...string) {
+		for _, name := range names {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("data"), 0600))
+		}
+	}
+
+	names := func(files []types.DatabaseRaftFile) []string {
+		result := []string{}
+		for _, file := range files {
+			result = append(result, file.Name)
+		}
+
+		return result
+	}
+
+	t.Run("No snapshots", func(t *testing.T) {
+		dir := t.TempDir()
+		touch(dir, "0000000000000001-0000000000000100", "open-1")
+
+		compaction, err := compactRaftFiles(dir, 10)
+		require.NoError(t, err)
+		require.Empty(t, compaction.Removed)
+	})
+
+	t.Run("Stale snapshots and segments", func(t *testing.T) {
+		dir := t.TempDir()
+		touch(dir,
+			"metadata1",
+			"0000000000000001-0000000000000100",
+			"0000000000000101-0000000000000200",
+			"0000000000000201-0000000000000300",
+			"open-1",
+			"snapshot-1-100-1700000000",
+			"snapshot-1-100-1700000000.meta",
+			"snapshot-2-200-1700000100",
+			"snapshot-2-200-1700000100.meta",
+			"snapshot-2-300-1700000200",
+			"snapshot-2-300-1700000200.meta",
+		)
+
+		compaction, err := compactRaftFiles(dir, 150)
+		require.NoError(t, err)
+		require.Equal(t, []string{"0000000000000001-0000000000000100", "snapshot-1-100-1700000000", "snapshot-1-100-1700000000.meta"}, names(compaction.Removed))
+		require.Equal(t, int64(12), compaction.Freed)
+
+		files, err := readRaftFiles(dir)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"0000000000000101-0000000000000200",
+			"0000000000000201-0000000000000300",
+			"metadata1",
+			"open-1",
+			"snapshot-2-200-1700000100",
+			"snapshot-2-200-1700000100.meta",
+			"snapshot-2-300-1700000200",
+			"snapshot-2-300-1700000200.meta",
+		}, names(files))
+	})
+}
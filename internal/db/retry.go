@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	dqliteDriver "github.com/canonical/go-dqlite/driver"
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+const (
+	// DefaultTransactionRetryTimeout is how long transactions are retried by default.
+	DefaultTransactionRetryTimeout = 30 * time.Second
+
+	// retryMinDelay is the delay before the first retry. It doubles with each attempt, up to retryMaxDelay.
+	retryMinDelay = 50 * time.Millisecond
+	retryMaxDelay = 2 * time.Second
+)
+
+// retryWithBackoff runs f until it succeeds or fails with an error that is not caused by a dqlite leadership change or
+// a locked database, waiting longer between each attempt. It gives up once the timeout has elapsed, or if the context
+// is cancelled, and returns the last error.
+func retryWithBackoff(ctx context.Context, timeout time.Duration, f func(ctx context.Context) error) error {
+	deadline := time.Now().Add(timeout)
+	delay := retryMinDelay
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || !isTransientError(err) {
+			return err
+		}
+
+		// Add up to 50% of jitter so that cluster members competing for the database do not retry in lockstep.
+		wait := delay + time.Duration(rand.Int63n(int64(delay/2)))
+		if time.Now().Add(wait).After(deadline) {
+			return err
+		}
+
+		logger.Debug("Retrying database transaction", logger.Ctx{"attempt": attempt, "wait": wait, "error": err})
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// isTransientError returns whether err is caused by a dqlite leadership change or a locked database, such that the
+// transaction can be attempted again.
+func isTransientError(err error) bool {
+	return query.IsRetriableError(err) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, dqliteDriver.ErrNoAvailableLeader)
+}
+
+// unavailableError marks a transient error that outlasted the retries as types.ErrDatabaseUnavailable, so that clients
+// can retry the request later. Other errors are returned unchanged.
+func unavailableError(err error) error {
+	if err == nil || !isTransientError(err) {
+		return err
+	}
+
+	return api.StatusErrorf(http.StatusServiceUnavailable, "%w: %w", types.ErrDatabaseUnavailable, err)
+}
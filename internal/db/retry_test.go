@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	dqliteDriver "github.com/canonical/go-dqlite/driver"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures only retriable errors are retried, and that retries stop once the timeout elapses.
+func TestRetryWithBackoff(t *testing.T) {
+	locked := errors.New("database is locked")
+
+	attempts := 0
+	err := retryWithBackoff(context.Background(), time.Second, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return locked
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = retryWithBackoff(context.Background(), time.Second, func(ctx context.Context) error {
+		attempts++
+		return errors.New("constraint failed")
+	})
+	assert.EqualError(t, err, "constraint failed")
+	assert.Equal(t, 1, attempts)
+
+	attempts = 0
+	start := time.Now()
+	err = retryWithBackoff(context.Background(), 200*time.Millisecond, func(ctx context.Context) error {
+		attempts++
+		return locked
+	})
+	assert.ErrorIs(t, err, locked)
+	assert.Greater(t, attempts, 1)
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+}
+
+// Ensures transient errors that outlast the retries are identified as types.ErrDatabaseUnavailable.
+func TestUnavailableError(t *testing.T) {
+	cases := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{name: "No error"},
+		{name: "Locked database", err: errors.New("database is locked"), unavailable: true},
+		{name: "Leadership lost", err: fmt.Errorf("Failed to commit: %w", driver.ErrBadConn), unavailable: true},
+		{name: "No leader", err: fmt.Errorf("Failed to begin transaction: %w", dqliteDriver.ErrNoAvailableLeader), unavailable: true},
+		{name: "Other error", err: errors.New("constraint failed")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := unavailableError(c.err)
+			if !c.unavailable {
+				assert.Equal(t, c.err, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, types.ErrDatabaseUnavailable)
+			assert.ErrorIs(t, err, c.err)
+			assert.True(t, api.StatusErrorCheck(err, http.StatusServiceUnavailable))
+		})
+	}
+}
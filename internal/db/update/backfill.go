@@ -0,0 +1,141 @@
+package update
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Backfill is a data migration, run once across the cluster after the schema is updated. Large transformations of
+// existing data are split into batches, each committed in its own transaction, so that an interrupted backfill
+// resumes from the last committed batch rather than starting over.
+type Backfill struct {
+	// Name identifies the backfill, and records its progress. It must be unique, and cannot change once the
+	// backfill has started.
+	Name string
+
+	// Run transforms the next batch of data. It receives the progress returned by the previous batch, which is empty
+	// for the first batch, and returns the progress to resume from along with whether the backfill is complete.
+	// The returned progress is committed along with the changes of the batch, and can hold any cursor, such as the
+	// last processed row ID.
+	Run func(ctx context.Context, tx *sql.Tx, progress string) (next string, done bool, err error)
+}
+
+// ValidateBackfills checks that the backfills are named uniquely and can be run.
+func ValidateBackfills(backfills []Backfill) error {
+	names := make(map[string]bool, len(backfills))
+	for _, backfill := range backfills {
+		if backfill.Name == "" {
+			return fmt.Errorf("Data backfill name cannot be empty")
+		}
+
+		if backfill.Run == nil {
+			return fmt.Errorf("Data backfill %q has no function to run", backfill.Name)
+		}
+
+		if names[backfill.Name] {
+			return fmt.Errorf("Duplicate data backfill %q", backfill.Name)
+		}
+
+		names[backfill.Name] = true
+	}
+
+	return nil
+}
+
+// RegisterBackfills records the backfills that aren't recorded yet, so that their progress is reported before they
+// start.
+func RegisterBackfills(ctx context.Context, tx *sql.Tx, backfills []Backfill) error {
+	for _, backfill := range backfills {
+		_, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO core_backfills (name, updated_at) VALUES (?, ?)", backfill.Name, time.Now())
+		if err != nil {
+			return fmt.Errorf("Failed to create \"core_backfills\" entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// PendingBackfills returns the backfills that aren't complete, in order.
+func PendingBackfills(ctx context.Context, tx *sql.Tx, backfills []Backfill) ([]Backfill, error) {
+	done, err := query.SelectStrings(ctx, tx, "SELECT name FROM core_backfills WHERE done = 1")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"core_backfills\" table: %w", err)
+	}
+
+	completed := make(map[string]bool, len(done))
+	for _, name := range done {
+		completed[name] = true
+	}
+
+	pending := []Backfill{}
+	for _, backfill := range backfills {
+		if !completed[backfill.Name] {
+			pending = append(pending, backfill)
+		}
+	}
+
+	return pending, nil
+}
+
+// RunBackfillBatch runs the next batch of the backfill from its recorded progress, and records the progress it
+// returns. It returns whether the backfill is complete.
+func RunBackfillBatch(ctx context.Context, tx *sql.Tx, backfill Backfill) (bool, error) {
+	var progress string
+	var done bool
+	err := tx.QueryRowContext(ctx, "SELECT progress, done FROM core_backfills WHERE name = ?", backfill.Name).Scan(&progress, &done)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = tx.ExecContext(ctx, "INSERT INTO core_backfills (name, updated_at) VALUES (?, ?)", backfill.Name, time.Now())
+		if err != nil {
+			return false, fmt.Errorf("Failed to create \"core_backfills\" entry: %w", err)
+		}
+	} else if err != nil {
+		return false, fmt.Errorf("Failed to fetch from \"core_backfills\" table: %w", err)
+	}
+
+	// Another cluster member may have completed the backfill.
+	if done {
+		return true, nil
+	}
+
+	next, done, err := backfill.Run(ctx, tx, progress)
+	if err != nil {
+		return false, fmt.Errorf("Failed to run data backfill %q: %w", backfill.Name, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE core_backfills SET progress = ?, batches = batches + 1, done = ?, updated_at = ? WHERE name = ?", next, done, time.Now(), backfill.Name)
+	if err != nil {
+		return false, fmt.Errorf("Failed to update \"core_backfills\" entry: %w", err)
+	}
+
+	return done, nil
+}
+
+// GetBackfills returns the progress of every recorded backfill.
+func GetBackfills(ctx context.Context, tx *sql.Tx) ([]types.DatabaseBackfill, error) {
+	backfills := []types.DatabaseBackfill{}
+	dest := func(scan func(dest ...any) error) error {
+		backfill := types.DatabaseBackfill{}
+		err := scan(&backfill.Name, &backfill.Progress, &backfill.Batches, &backfill.Done, &backfill.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		backfills = append(backfills, backfill)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT name, progress, batches, done, updated_at FROM core_backfills ORDER BY id", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"core_backfills\" table: %w", err)
+	}
+
+	return backfills, nil
+}
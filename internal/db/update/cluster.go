@@ -3,6 +3,7 @@ package update
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 
 	"github.com/canonical/lxd/lxd/db/query"
@@ -224,6 +225,101 @@ func GetClusterMemberAPIExtensions(ctx context.Context, tx *sql.Tx) ([]extension
 	return results, nil
 }
 
+// hasNamespaceVersionsColumn returns whether the cluster members table records namespace schema versions,
+// which is not the case until updateFromV8 has been applied.
+func hasNamespaceVersionsColumn(ctx context.Context, tx *sql.Tx, table string) (bool, error) {
+	stmt := fmt.Sprintf("SELECT count(name) FROM pragma_table_info('%s') WHERE name IN ('schema_namespaces')", table)
+
+	var count int
+	err := tx.QueryRowContext(ctx, stmt).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count == 1, nil
+}
+
+// UpdateClusterMemberNamespaceVersions sets the namespace schema versions supported by the cluster member with the given name.
+// This helper is non-generated to work before generated statements are loaded, as we update the schema.
+func UpdateClusterMemberNamespaceVersions(ctx context.Context, tx *sql.Tx, versions map[string]uint64, memberName string) error {
+	table, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	ok, err := hasNamespaceVersionsColumn(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		logger.Warn("Skipping namespace schema version update, schema does not yet support it", logger.Ctx{"memberName": memberName})
+		return nil
+	}
+
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+
+	stmt := fmt.Sprintf("UPDATE %s SET schema_namespaces=? WHERE name=?", table)
+	result, err := tx.ExecContext(ctx, stmt, string(data), memberName)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("Updated %d rows instead of 1", n)
+	}
+
+	return nil
+}
+
+// GetClusterMemberNamespaceVersions returns the namespace schema versions from all cluster members that are not pending.
+// It returns no versions if the schema does not yet record them.
+// This helper is non-generated to work before generated statements are loaded, as we update the schema.
+func GetClusterMemberNamespaceVersions(ctx context.Context, tx *sql.Tx) ([]map[string]uint64, error) {
+	table, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := hasNamespaceVersionsColumn(ctx, tx, table)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	results := []map[string]uint64{}
+	dest := func(scan func(dest ...any) error) error {
+		var data string
+		err := scan(&data)
+		if err != nil {
+			return err
+		}
+
+		versions := map[string]uint64{}
+		err = json.Unmarshal([]byte(data), &versions)
+		if err != nil {
+			return fmt.Errorf("Failed to parse namespace schema versions: %w", err)
+		}
+
+		results = append(results, versions)
+
+		return nil
+	}
+
+	err = query.Scan(ctx, tx, fmt.Sprintf("SELECT schema_namespaces FROM %s WHERE NOT role='pending'", table), dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 // getClusterTableName returns the name of the table that holds the record of cluster members from sqlite_master.
 // Prior to updateFromV4, this table was called `internal_cluster_members`, but now it is `core_cluster_members`.
 // Since we need to check this table to perform the update that renames it, we can use this function to dynamically determine its name.
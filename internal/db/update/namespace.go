@@ -0,0 +1,166 @@
+package update
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/lxd/db/schema"
+	"github.com/canonical/lxd/shared"
+)
+
+// Namespace is a series of schema updates owned by a single component sharing the daemon, such as an extension server.
+// Every table, index, view and trigger created, altered or dropped by its updates must be prefixed with the namespace
+// name followed by an underscore. Its schema version is tracked separately from the internal and external schema
+// versions, and from other namespaces.
+type Namespace struct {
+	// Name identifies the namespace, and prefixes its tables. It must be lowercase alphanumeric, starting with a letter.
+	Name string
+
+	// Updates is the ordered series of schema updates making up the namespace's schema.
+	Updates []schema.Update
+}
+
+// namespaceNameRegex matches valid namespace names. Underscores are excluded so that the prefix of one namespace
+// cannot be the start of another's.
+var namespaceNameRegex = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// reservedNamespaces cannot be used as namespace names, as their prefixes are used by microcluster or sqlite.
+var reservedNamespaces = []string{"core", "internal", "sqlite", "schemas"}
+
+// ValidateNamespaces checks that the namespace names are valid and unique.
+func ValidateNamespaces(namespaces []Namespace) error {
+	names := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		if !namespaceNameRegex.MatchString(namespace.Name) {
+			return fmt.Errorf("Invalid schema namespace name %q, must be lowercase alphanumeric and start with a letter", namespace.Name)
+		}
+
+		if shared.ValueInSlice(namespace.Name, reservedNamespaces) {
+			return fmt.Errorf("Schema namespace name %q is reserved", namespace.Name)
+		}
+
+		if names[namespace.Name] {
+			return fmt.Errorf("Duplicate schema namespace %q", namespace.Name)
+		}
+
+		names[namespace.Name] = true
+	}
+
+	return nil
+}
+
+// namespaceVersions returns the number of updates in each namespace.
+func namespaceVersions(namespaces []Namespace) map[string]uint64 {
+	versions := make(map[string]uint64, len(namespaces))
+	for _, namespace := range namespaces {
+		versions[namespace.Name] = uint64(len(namespace.Updates))
+	}
+
+	return versions
+}
+
+// ensureNamespaceUpdatesAreApplied applies any pending update of the namespace, checking that each update only
+// changes objects within the namespace.
+func ensureNamespaceUpdatesAreApplied(ctx context.Context, tx *sql.Tx, namespace Namespace) error {
+	versions, err := query.SelectIntegers(ctx, tx, "SELECT COALESCE(MAX(version), 0) FROM core_schema_namespaces WHERE namespace = ?", namespace.Name)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) != 1 {
+		return fmt.Errorf("Invalid schema version structure")
+	}
+
+	version := versions[0]
+	if version > len(namespace.Updates) {
+		return fmt.Errorf("Schema version %d of namespace %q is more recent than expected %d", version, namespace.Name, len(namespace.Updates))
+	}
+
+	for _, update := range namespace.Updates[version:] {
+		before, err := schemaObjects(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		err = update(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("Failed to apply update %d of namespace %q: %w", version, namespace.Name, err)
+		}
+
+		after, err := schemaObjects(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		err = checkNamespaceChanges(namespace.Name, before, after)
+		if err != nil {
+			return fmt.Errorf("Invalid update %d of namespace %q: %w", version, namespace.Name, err)
+		}
+
+		version++
+
+		statement := `INSERT INTO core_schema_namespaces (namespace, version, updated_at) VALUES (?, ?, strftime("%s"))`
+		_, err = tx.ExecContext(ctx, statement, namespace.Name, version)
+		if err != nil {
+			return fmt.Errorf("Failed to insert version %d of namespace %q: %w", version, namespace.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// schemaObjects returns the definition of each table, index, view and trigger in the database, by name.
+// Indexes created automatically by sqlite are left out, as they have no definition.
+func schemaObjects(ctx context.Context, tx *sql.Tx) (map[string]string, error) {
+	objects := map[string]string{}
+	dest := func(scan func(dest ...any) error) error {
+		var name, definition string
+		err := scan(&name, &definition)
+		if err != nil {
+			return err
+		}
+
+		objects[name] = definition
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT name, COALESCE(sql, '') FROM sqlite_master WHERE name NOT LIKE 'sqlite_%'", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list schema objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+// checkNamespaceChanges returns an error if any object outside of the namespace was created, changed or dropped.
+func checkNamespaceChanges(namespace string, before map[string]string, after map[string]string) error {
+	prefix := namespace + "_"
+	for name, definition := range after {
+		if strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		previous, ok := before[name]
+		if !ok {
+			return fmt.Errorf("%q was created without the %q prefix", name, prefix)
+		}
+
+		if previous != definition {
+			return fmt.Errorf("%q is outside of the namespace and was modified", name)
+		}
+	}
+
+	for name := range before {
+		_, ok := after[name]
+		if !ok && !strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("%q is outside of the namespace and was dropped", name)
+		}
+	}
+
+	return nil
+}
@@ -27,7 +27,9 @@ const (
 // SchemaUpdate holds the configuration for executing schema updates.
 type SchemaUpdate struct {
 	updates       map[updateType][]schema.Update // Ordered series of internal and external updates making up the schema
+	rollbacks     []schema.Update                // Optional down migrations for each external update
 	apiExtensions extensions.Extensions
+	namespaces    []Namespace  // Independently versioned updates of components sharing the database
 	hook          schema.Hook  // Optional hook to execute whenever a update gets applied
 	fresh         string       // Optional SQL statement used to create schema from scratch
 	check         schema.Check // Optional callback invoked before doing any update
@@ -60,6 +62,11 @@ func (s *SchemaUpdate) Version() (internalVersion uint64, externalVersion uint64
 	return uint64(len(s.updates[updateInternal])), uint64(len(s.updates[updateExternal])), s.apiExtensions
 }
 
+// NamespaceVersions returns the schema version of each namespace, corresponding to the number of its updates.
+func (s *SchemaUpdate) NamespaceVersions() map[string]uint64 {
+	return namespaceVersions(s.namespaces)
+}
+
 // Ensure makes sure that the actual schema in the given database matches the
 // one defined by our updates.
 //
@@ -248,9 +255,75 @@ func (s *SchemaUpdate) Ensure(db *sql.DB) (int, error) {
 		return -1, err
 	}
 
+	if len(s.namespaces) > 0 {
+		err = query.Transaction(context.TODO(), db, func(ctx context.Context, tx *sql.Tx) error {
+			for _, namespace := range s.namespaces {
+				err := ensureNamespaceUpdatesAreApplied(ctx, tx, namespace)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return -1, err
+		}
+	}
+
 	return current, nil
 }
 
+// Rollback reverts external schema updates in reverse order, until the external schema version matches the target version.
+// The schema version of every cluster member is lowered to the target, so that members still running
+// the newer updates will wait for an upgrade instead of re-applying them when they next start.
+func (s *SchemaUpdate) Rollback(ctx context.Context, tx *sql.Tx, target uint64) error {
+	versions, err := query.SelectIntegers(ctx, tx, "SELECT COALESCE(MAX(version), 0) FROM schemas WHERE type = ?", updateExternal)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) != 1 {
+		return fmt.Errorf("Invalid schema version structure")
+	}
+
+	current := uint64(versions[0])
+	if target > current {
+		return fmt.Errorf("Cannot roll back external schema version %d to newer version %d", current, target)
+	}
+
+	// Ensure every update can be reverted before touching the schema.
+	for version := current; version > target; version-- {
+		if version > uint64(len(s.rollbacks)) || s.rollbacks[version-1] == nil {
+			return fmt.Errorf("External schema update %d has no rollback", version)
+		}
+	}
+
+	for version := current; version > target; version-- {
+		err := s.rollbacks[version-1](ctx, tx)
+		if err != nil {
+			return fmt.Errorf("Failed to roll back update %d: %w", version, err)
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM schemas WHERE version = ? AND type = ?", version, updateExternal)
+		if err != nil {
+			return fmt.Errorf("Failed to remove version %d: %w", version, err)
+		}
+	}
+
+	tableName, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET schema_external=?", tableName), target)
+	if err != nil {
+		return fmt.Errorf("Failed to update cluster member schema versions: %w", err)
+	}
+
+	return nil
+}
+
 // Apply any pending update that was not yet applied.
 func ensureUpdatesAreApplied(ctx context.Context, tx *sql.Tx, updateType updateType, version int, updates []schema.Update, hook schema.Hook) error {
 	if version > len(updates) {
@@ -24,7 +24,13 @@ CREATE TABLE schemas (
 type SchemaUpdateManager struct {
 	updates map[updateType][]schema.Update
 
+	// rollbacks are the optional down migrations for external schema updates.
+	rollbacks []schema.Update
+
 	apiExtensions extensions.Extensions
+
+	// namespaces are the independently versioned schema updates of components sharing the database.
+	namespaces []Namespace
 }
 
 // NewSchema returns a new SchemaUpdateManager containing microcluster schema updates.
@@ -38,6 +44,20 @@ func NewSchema() *SchemaUpdateManager {
 			mgr.updateFromV3,
 			updateFromV4,
 			updateFromV5,
+			updateFromV6,
+			updateFromV7,
+			updateFromV8,
+			updateFromV9,
+			updateFromV10,
+			updateFromV11,
+			updateFromV12,
+			updateFromV13,
+			updateFromV14,
+			updateFromV15,
+			updateFromV16,
+			updateFromV17,
+			updateFromV18,
+			updateFromV19,
 		},
 	}
 
@@ -62,9 +82,20 @@ func (s *SchemaUpdateManager) SetExternalUpdates(updates []schema.Update) {
 	s.updates[updateExternal] = updates
 }
 
+// SetExternalRollbacks sets the down migrations for external schema updates.
+// The entry at index i reverts the external update at index i. A nil entry marks an update that cannot be rolled back.
+func (s *SchemaUpdateManager) SetExternalRollbacks(rollbacks []schema.Update) {
+	s.rollbacks = rollbacks
+}
+
+// SetNamespaces sets the namespaced schema updates, which are applied after all internal and external updates.
+func (s *SchemaUpdateManager) SetNamespaces(namespaces []Namespace) {
+	s.namespaces = namespaces
+}
+
 // Schema returns a SchemaUpdate from the SchemaUpdateManager config.
 func (s *SchemaUpdateManager) Schema() *SchemaUpdate {
-	schema := &SchemaUpdate{updates: s.updates, apiExtensions: s.apiExtensions}
+	schema := &SchemaUpdate{updates: s.updates, rollbacks: s.rollbacks, apiExtensions: s.apiExtensions, namespaces: s.namespaces}
 	schema.Fresh("")
 	return schema
 }
@@ -75,6 +106,238 @@ func (s *SchemaUpdateManager) AppendSchema(schemaExtensions []schema.Update, api
 	s.apiExtensions = apiExtensions
 }
 
+// updateFromV19 records the DNS name of each cluster member, which other cluster members resolve again when they fail
+// to reach it at its recorded address.
+func updateFromV19(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN dns_name TEXT NOT NULL DEFAULT '';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV18 adds the table of warnings filed by the daemon and its consumers about cluster members.
+func updateFromV18(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_warnings (
+  id             INTEGER   PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  uuid           TEXT      NOT      NULL,
+  member         TEXT      NOT      NULL,
+  type           TEXT      NOT      NULL,
+  entity         TEXT      NOT      NULL   DEFAULT '',
+  message        TEXT      NOT      NULL,
+  status         TEXT      NOT      NULL,
+  count          INTEGER   NOT      NULL   DEFAULT 1,
+  first_seen_at  DATETIME  NOT      NULL,
+  last_seen_at   DATETIME  NOT      NULL,
+  UNIQUE         (uuid),
+  UNIQUE         (member, type, entity)
+);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV17 adds the table of revoked certificates, which are rejected even if they are otherwise trusted.
+func updateFromV17(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_certificate_revocations (
+  id           INTEGER   PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  fingerprint  TEXT      NOT      NULL,
+  reason       TEXT      NOT      NULL   DEFAULT '',
+  revoked_at   DATETIME  NOT      NULL,
+  UNIQUE       (fingerprint)
+);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV16 adds the table recording the progress of data backfills.
+func updateFromV16(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_backfills (
+  id          INTEGER   PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  name        TEXT      NOT      NULL,
+  progress    TEXT      NOT      NULL   DEFAULT '',
+  batches     INTEGER   NOT      NULL   DEFAULT 0,
+  done        BOOLEAN   NOT      NULL   DEFAULT 0,
+  updated_at  DATETIME  NOT      NULL,
+  UNIQUE      (name)
+);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV15 records the clock skew of each cluster member, as measured by the dqlite leader during heartbeats.
+func updateFromV15(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN clock_skew INTEGER NOT NULL DEFAULT 0;
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV14 records when join tokens are issued.
+func updateFromV14(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_token_records ADD COLUMN issued_at DATETIME;
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV13 adds the table holding the revisions of the keys of the etcd compatible API.
+func updateFromV13(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_kine (
+  id               INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  name             TEXT     NOT      NULL,
+  created          INTEGER  NOT      NULL,
+  deleted          INTEGER  NOT      NULL,
+  create_revision  INTEGER  NOT      NULL,
+  prev_revision    INTEGER  NOT      NULL,
+  lease            INTEGER  NOT      NULL,
+  expiry           INTEGER  NOT      NULL,
+  value            BLOB,
+  old_value        BLOB
+);
+
+CREATE INDEX core_kine_name_id ON core_kine (name, id);
+CREATE INDEX core_kine_prev_revision ON core_kine (prev_revision);
+CREATE UNIQUE INDEX core_kine_name_prev_revision ON core_kine (name, prev_revision);
+
+INSERT INTO core_kine (name, created, deleted, create_revision, prev_revision, lease, expiry) VALUES ('compact_rev_key', 0, 0, 0, 0, 0, 0);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV12 adds the table recording the row changes of the tables whose changes are tracked.
+func updateFromV12(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_changes (
+  id          INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  table_name  TEXT     NOT      NULL,
+  operation   TEXT     NOT      NULL,
+  row_id      INTEGER  NOT      NULL,
+  changed_at  INTEGER  NOT      NULL
+);
+
+CREATE INDEX core_changes_changed_at ON core_changes (changed_at);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV11 marks join tokens that let a reinstalled cluster member rejoin with its existing identity.
+func updateFromV11(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_token_records ADD COLUMN rejoin BOOLEAN NOT NULL DEFAULT 0;
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV10 records whether each cluster member is under maintenance.
+func updateFromV10(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN maintenance BOOLEAN NOT NULL DEFAULT 0;
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV9 adds the table of client certificates trusted to access the API without being cluster members.
+func updateFromV9(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_trusted_certificates (
+  id           INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  name         TEXT     NOT      NULL,
+  fingerprint  TEXT     NOT      NULL,
+  certificate  TEXT     NOT      NULL,
+  UNIQUE       (name),
+  UNIQUE       (fingerprint)
+);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV8 adds the table tracking the schema version of each namespace, and records the namespace versions
+// supported by each cluster member.
+func updateFromV8(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_schema_namespaces (
+  id          INTEGER    PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  namespace   TEXT       NOT      NULL,
+  version     INTEGER    NOT      NULL,
+  updated_at  DATETIME   NOT      NULL,
+  UNIQUE      (namespace, version)
+);
+
+ALTER TABLE core_cluster_members ADD COLUMN schema_namespaces TEXT NOT NULL DEFAULT '{}';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV7 adds usage limits and subnet restrictions to join tokens, so that a token may be used by more than one joiner.
+func updateFromV7(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_token_records ADD COLUMN max_joins INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE core_token_records ADD COLUMN joins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE core_token_records ADD COLUMN allowed_subnets TEXT NOT NULL DEFAULT '';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV6 adds the table holding the leases of cluster-wide locks.
+func updateFromV6(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_locks (
+  id       INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  name     TEXT     NOT      NULL,
+  owner    TEXT     NOT      NULL,
+  token    TEXT     NOT      NULL,
+  expiry   INTEGER  NOT      NULL,
+  UNIQUE   (name)
+);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
 // updateFromV5 adds an expiration column for join tokens.
 func updateFromV5(ctx context.Context, tx *sql.Tx) error {
 	stmt := `CREATE TABLE core_token_records_new (
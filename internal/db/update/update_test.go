@@ -10,6 +10,8 @@ import (
 	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/lxd/db/schema"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcluster/v3/rest/types"
 )
 
 type updateSuite struct {
@@ -241,3 +243,235 @@ func NewTestDBWithSchema(schemaManager *SchemaUpdateManager) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// Ensures external schema updates are reverted in reverse order, and refused if any lacks a rollback.
+func (s *updateSuite) Test_rollback() {
+	db, err := sql.Open("sqlite3", ":memory:")
+	s.NoError(err)
+
+	createTable := func(name string) schema.Update {
+		return func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (id INTEGER)", name))
+			return err
+		}
+	}
+
+	dropTable := func(name string) schema.Update {
+		return func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("DROP TABLE %s", name))
+			return err
+		}
+	}
+
+	schemaMgr := NewSchema()
+	schemaMgr.AppendSchema([]schema.Update{createTable("one"), createTable("two"), createTable("three")}, nil)
+	schemaMgr.SetExternalRollbacks([]schema.Update{nil, dropTable("two"), dropTable("three")})
+	updates := schemaMgr.Schema()
+
+	_, err = updates.Ensure(db)
+	s.NoError(err)
+
+	_, err = db.Exec(`INSERT INTO core_cluster_members (name, address, certificate, schema_internal, schema_external, heartbeat, role) VALUES ("member", "10.0.0.1:8443", "cert", 1, 3, ?, "voter")`, time.Time{})
+	s.NoError(err)
+
+	rollback := func(target uint64) error {
+		return query.Transaction(context.Background(), db, func(ctx context.Context, tx *sql.Tx) error {
+			return updates.Rollback(ctx, tx, target)
+		})
+	}
+
+	// Update 1 has no rollback, and versions newer than the current one are invalid.
+	s.Error(rollback(0))
+	s.Error(rollback(4))
+
+	s.NoError(rollback(1))
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	s.NoError(err)
+
+	tables, err := query.SelectStrings(ctx, tx, "SELECT name FROM sqlite_master WHERE name IN ('one', 'two', 'three')")
+	s.NoError(err)
+
+	versions, err := query.SelectIntegers(ctx, tx, "SELECT version FROM schemas WHERE type = 1")
+	s.NoError(err)
+
+	memberVersions, err := query.SelectIntegers(ctx, tx, "SELECT schema_external FROM core_cluster_members")
+	s.NoError(err)
+	s.NoError(tx.Commit())
+
+	s.Equal([]string{"one"}, tables)
+	s.Equal([]int{1}, versions)
+	s.Equal([]int{1}, memberVersions)
+
+	s.NoError(db.Close())
+}
+
+// Ensures namespaced schema updates are versioned independently, and may only change objects with the namespace prefix.
+func (s *updateSuite) Test_namespaces() {
+	db, err := sql.Open("sqlite3", ":memory:")
+	s.NoError(err)
+
+	exec := func(stmt string) schema.Update {
+		return func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, stmt)
+			return err
+		}
+	}
+
+	ensure := func(namespaces ...Namespace) error {
+		schemaMgr := NewSchema()
+		schemaMgr.AppendSchema([]schema.Update{exec("CREATE TABLE services (id INTEGER)")}, nil)
+		schemaMgr.SetNamespaces(namespaces)
+		_, err := schemaMgr.Schema().Ensure(db)
+		return err
+	}
+
+	namespaceVersions := func() map[string]int {
+		ctx := context.Background()
+		versions := map[string]int{}
+		err := query.Transaction(ctx, db, func(ctx context.Context, tx *sql.Tx) error {
+			return query.Scan(ctx, tx, "SELECT namespace, MAX(version) FROM core_schema_namespaces GROUP BY namespace", func(scan func(dest ...any) error) error {
+				var name string
+				var version int
+				err := scan(&name, &version)
+				versions[name] = version
+				return err
+			})
+		})
+		s.NoError(err)
+		return versions
+	}
+
+	storage := Namespace{Name: "storage", Updates: []schema.Update{exec("CREATE TABLE storage_pools (id INTEGER)")}}
+	network := Namespace{Name: "network", Updates: []schema.Update{exec("CREATE TABLE network_services (id INTEGER); CREATE INDEX network_services_id ON network_services (id)")}}
+	s.NoError(ensure(storage, network))
+	s.Equal(map[string]int{"storage": 1, "network": 1}, namespaceVersions())
+
+	// Adding an update to one namespace does not affect the other.
+	storage.Updates = append(storage.Updates, exec("ALTER TABLE storage_pools ADD COLUMN name TEXT"))
+	s.NoError(ensure(storage, network))
+	s.Equal(map[string]int{"storage": 2, "network": 1}, namespaceVersions())
+
+	// Namespaces cannot create, alter or drop objects outside of their prefix.
+	invalid := []schema.Update{
+		exec("CREATE TABLE pools (id INTEGER)"),
+		exec("ALTER TABLE services ADD COLUMN name TEXT"),
+		exec("ALTER TABLE network_services ADD COLUMN name TEXT"),
+		exec("DROP TABLE services"),
+	}
+
+	for _, update := range invalid {
+		s.Error(ensure(storage, network, Namespace{Name: "other", Updates: []schema.Update{update}}))
+	}
+
+	s.Equal(map[string]int{"storage": 2, "network": 1}, namespaceVersions())
+
+	// A namespace cannot be at a newer version than the daemon knows about.
+	s.Error(ensure(Namespace{Name: "storage"}, network))
+
+	s.Error(ValidateNamespaces([]Namespace{{Name: "core"}}))
+	s.Error(ValidateNamespaces([]Namespace{{Name: "my_namespace"}}))
+	s.Error(ValidateNamespaces([]Namespace{{Name: "storage"}, {Name: "storage"}}))
+	s.NoError(ValidateNamespaces([]Namespace{{Name: "storage"}, {Name: "network2"}}))
+
+	s.NoError(db.Close())
+}
+
+// Ensures data backfills resume from their recorded progress, and are not run again once complete.
+func (s *updateSuite) Test_backfills() {
+	db, err := sql.Open("sqlite3", ":memory:")
+	s.NoError(err)
+	db.SetMaxOpenConns(1)
+
+	_, err = NewSchema().Schema().Ensure(db)
+	s.NoError(err)
+
+	// The backfill counts up to 3, one batch at a time.
+	runs := 0
+	fail := false
+	backfill := Backfill{
+		Name: "count",
+		Run: func(ctx context.Context, tx *sql.Tx, progress string) (string, bool, error) {
+			if fail {
+				return "", false, fmt.Errorf("Failed")
+			}
+
+			runs++
+			next := fmt.Sprintf("%d", len(progress)+1)
+			return progress + next, len(progress)+1 == 3, nil
+		},
+	}
+
+	batch := func() (bool, error) {
+		var done bool
+		err := query.Transaction(context.Background(), db, func(ctx context.Context, tx *sql.Tx) error {
+			var err error
+			done, err = RunBackfillBatch(ctx, tx, backfill)
+			return err
+		})
+
+		return done, err
+	}
+
+	backfills := func() []types.DatabaseBackfill {
+		var backfills []types.DatabaseBackfill
+		err := query.Transaction(context.Background(), db, func(ctx context.Context, tx *sql.Tx) error {
+			var err error
+			backfills, err = GetBackfills(ctx, tx)
+			return err
+		})
+		s.NoError(err)
+
+		return backfills
+	}
+
+	s.NoError(query.Transaction(context.Background(), db, func(ctx context.Context, tx *sql.Tx) error {
+		return RegisterBackfills(ctx, tx, []Backfill{backfill})
+	}))
+
+	s.Len(backfills(), 1)
+	s.Equal("", backfills()[0].Progress)
+
+	for i := 1; i <= 2; i++ {
+		done, err := batch()
+		s.NoError(err)
+		s.False(done)
+	}
+
+	s.Equal("12", backfills()[0].Progress)
+	s.Equal(int64(2), backfills()[0].Batches)
+
+	// A failed batch does not record any progress.
+	fail = true
+	_, err = batch()
+	s.Error(err)
+	fail = false
+
+	s.Equal("12", backfills()[0].Progress)
+
+	done, err := batch()
+	s.NoError(err)
+	s.True(done)
+	s.True(backfills()[0].Done)
+	s.Equal(3, runs)
+
+	// Complete backfills are not run again.
+	done, err = batch()
+	s.NoError(err)
+	s.True(done)
+	s.Equal(3, runs)
+
+	s.NoError(query.Transaction(context.Background(), db, func(ctx context.Context, tx *sql.Tx) error {
+		pending, err := PendingBackfills(ctx, tx, []Backfill{backfill, {Name: "other"}})
+		s.Len(pending, 1)
+		s.Equal("other", pending[0].Name)
+		return err
+	}))
+
+	s.Error(ValidateBackfills([]Backfill{{Name: "count"}}))
+	s.Error(ValidateBackfills([]Backfill{backfill, backfill}))
+	s.NoError(ValidateBackfills([]Backfill{backfill}))
+
+	s.NoError(db.Close())
+}
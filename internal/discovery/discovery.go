@@ -0,0 +1,155 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// DefaultBrowseTimeout is how long Browse waits for answers if the context has no deadline.
+const DefaultBrowseTimeout = 3 * time.Second
+
+// Announcer answers mDNS queries on behalf of the local daemon.
+type Announcer struct {
+	service []string
+
+	// info returns the details to announce, and whether the daemon should currently be announced at all.
+	info func() (types.DiscoveredPeer, bool)
+}
+
+// NewAnnouncer returns an Announcer for the given project.
+// The info function is consulted for every query, so that the daemon stops being announced once it joins a cluster.
+func NewAnnouncer(project string, info func() (types.DiscoveredPeer, bool)) *Announcer {
+	return &Announcer{
+		service: ServiceName(project),
+		info:    info,
+	}
+}
+
+// Run listens for mDNS queries and answers them until the context is cancelled.
+func (a *Announcer) Run(ctx context.Context) error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return fmt.Errorf("Failed to listen for mDNS queries: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("Failed to read mDNS query: %w", err)
+		}
+
+		id, ok := isQuery(buf[:n], a.service)
+		if !ok {
+			continue
+		}
+
+		peer, ok := a.info()
+		if !ok {
+			continue
+		}
+
+		resp, err := encodeResponse(id, a.service, peer)
+		if err != nil {
+			logger.Warn("Failed to build mDNS response", logger.Ctx{"error": err})
+			continue
+		}
+
+		// Queries not sent from the mDNS port expect a direct unicast answer.
+		dest := mdnsGroup
+		if src.Port != mdnsPort {
+			dest = src
+		}
+
+		_, err = conn.WriteToUDP(resp, dest)
+		if err != nil {
+			logger.Warn("Failed to send mDNS response", logger.Ctx{"error": err, "destination": dest.String()})
+		}
+	}
+}
+
+// Browse queries the local network for daemons of the given project that are announcing themselves,
+// and collects answers until the context is done, or DefaultBrowseTimeout elapses if it has no deadline.
+func Browse(ctx context.Context, project string) ([]types.DiscoveredPeer, error) {
+	service := ServiceName(project)
+	query, err := encodeQuery(service)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open mDNS socket: %w", err)
+	}
+
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultBrowseTimeout)
+	}
+
+	err = conn.SetReadDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	_, err = conn.WriteToUDP(query, mdnsGroup)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to send mDNS query: %w", err)
+	}
+
+	// Deduplicate answers by certificate fingerprint, as a daemon may answer more than once.
+	found := map[string]types.DiscoveredPeer{}
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+
+			return nil, fmt.Errorf("Failed to read mDNS response: %w", err)
+		}
+
+		peers, err := parseResponse(buf[:n], service)
+		if err != nil {
+			logger.Debug("Ignoring malformed mDNS response", logger.Ctx{"error": err})
+			continue
+		}
+
+		for _, peer := range peers {
+			found[peer.Fingerprint] = peer
+		}
+	}
+
+	peers := make([]types.DiscoveredPeer, 0, len(found))
+	for _, peer := range found {
+		peers = append(peers, peer)
+	}
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+
+	return peers, nil
+}
@@ -0,0 +1,328 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+const (
+	// mdnsPort is the well-known mDNS port.
+	mdnsPort = 5353
+
+	// recordTTL is the time-to-live, in seconds, of announced records.
+	recordTTL = 120
+
+	typePTR = 12
+	typeTXT = 16
+	typeANY = 255
+	classIN = 1
+
+	// classMask strips the unicast-response (questions) or cache-flush (answers) bit from the class field.
+	classMask  = 0x7fff
+	cacheFlush = 0x8000
+
+	flagResponse      = 0x8000
+	flagAuthoritative = 0x0400
+
+	headerSize = 12
+)
+
+// mdnsGroup is the IPv4 mDNS multicast group.
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: mdnsPort}
+
+// Keys of the TXT record announced for each daemon.
+const (
+	txtName        = "name"
+	txtAddress     = "address"
+	txtFingerprint = "fingerprint"
+	txtVersion     = "version"
+)
+
+// ServiceName returns the DNS-SD service labels under which daemons of the given project are announced.
+func ServiceName(project string) []string {
+	label := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+
+		return '-'
+	}, project)
+
+	// Leave room for the leading underscore.
+	if len(label) > 62 {
+		label = label[:62]
+	}
+
+	return []string{"_" + label, "_tcp", "local"}
+}
+
+// encodeName appends the wire format of the given labels to the buffer.
+func encodeName(buf []byte, labels []string) ([]byte, error) {
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("Invalid DNS label %q", label)
+		}
+
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+
+	return append(buf, 0), nil
+}
+
+// readName parses a possibly compressed name at the given offset, and returns its labels and the offset just past it.
+func readName(msg []byte, off int) ([]string, int, error) {
+	labels := []string{}
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return nil, 0, fmt.Errorf("Truncated DNS name")
+		}
+
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = off + 1
+			}
+
+			return labels, end, nil
+		case length&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return nil, 0, fmt.Errorf("Truncated DNS name pointer")
+			}
+
+			jumps++
+			if jumps > 16 {
+				return nil, 0, fmt.Errorf("Too many DNS name compression pointers")
+			}
+
+			if end < 0 {
+				end = off + 2
+			}
+
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		case length&0xc0 != 0:
+			return nil, 0, fmt.Errorf("Unsupported DNS label type")
+		default:
+			if off+1+length > len(msg) {
+				return nil, 0, fmt.Errorf("Truncated DNS label")
+			}
+
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
+
+// equalLabels compares DNS labels case-insensitively.
+func equalLabels(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// appendRecord appends a resource record with the given owner name, type, class and data.
+func appendRecord(buf []byte, name []string, rrType uint16, class uint16, data []byte) ([]byte, error) {
+	buf, err := encodeName(buf, name)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = binary.BigEndian.AppendUint16(buf, rrType)
+	buf = binary.BigEndian.AppendUint16(buf, class)
+	buf = binary.BigEndian.AppendUint32(buf, recordTTL)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(data)))
+
+	return append(buf, data...), nil
+}
+
+// encodeQuery builds an mDNS query for the PTR records of the given service.
+func encodeQuery(service []string) ([]byte, error) {
+	buf := make([]byte, headerSize)
+	binary.BigEndian.PutUint16(buf[4:], 1)
+
+	buf, err := encodeName(buf, service)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = binary.BigEndian.AppendUint16(buf, typePTR)
+	buf = binary.BigEndian.AppendUint16(buf, classIN)
+
+	return buf, nil
+}
+
+// isQuery returns the ID of the given message, and whether it is a query asking for the given service.
+func isQuery(msg []byte, service []string) (uint16, bool) {
+	if len(msg) < headerSize {
+		return 0, false
+	}
+
+	id := binary.BigEndian.Uint16(msg)
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&flagResponse != 0 {
+		return 0, false
+	}
+
+	off := headerSize
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	for i := 0; i < questions; i++ {
+		name, next, err := readName(msg, off)
+		if err != nil || next+4 > len(msg) {
+			return 0, false
+		}
+
+		qType := binary.BigEndian.Uint16(msg[next:])
+		qClass := binary.BigEndian.Uint16(msg[next+2:]) & classMask
+		off = next + 4
+
+		if qClass == classIN && (qType == typePTR || qType == typeANY) && equalLabels(name, service) {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// encodeResponse builds an mDNS response announcing the given peer under the given service.
+func encodeResponse(id uint16, service []string, peer types.DiscoveredPeer) ([]byte, error) {
+	instance := append([]string{peer.Name}, service...)
+
+	ptrData, err := encodeName(nil, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	txtData := []byte{}
+	for _, entry := range []string{
+		txtName + "=" + peer.Name,
+		txtAddress + "=" + peer.Address.String(),
+		txtFingerprint + "=" + peer.Fingerprint,
+		txtVersion + "=" + peer.Version,
+	} {
+		if len(entry) > 255 {
+			return nil, fmt.Errorf("TXT record entry %q is too long", entry)
+		}
+
+		txtData = append(txtData, byte(len(entry)))
+		txtData = append(txtData, entry...)
+	}
+
+	buf := make([]byte, headerSize)
+	binary.BigEndian.PutUint16(buf, id)
+	binary.BigEndian.PutUint16(buf[2:], flagResponse|flagAuthoritative)
+	binary.BigEndian.PutUint16(buf[6:], 2)
+
+	buf, err = appendRecord(buf, service, typePTR, classIN, ptrData)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendRecord(buf, instance, typeTXT, classIN|cacheFlush, txtData)
+}
+
+// parseResponse extracts the peers announced under the given service from an mDNS response.
+func parseResponse(msg []byte, service []string) ([]types.DiscoveredPeer, error) {
+	if len(msg) < headerSize {
+		return nil, fmt.Errorf("Truncated DNS header")
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&flagResponse == 0 {
+		return nil, nil
+	}
+
+	off := headerSize
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	for i := 0; i < questions; i++ {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+
+		off = next + 4
+	}
+
+	records := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+	peers := []types.DiscoveredPeer{}
+	for i := 0; i < records; i++ {
+		name, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+
+		if next+10 > len(msg) {
+			return nil, fmt.Errorf("Truncated DNS record")
+		}
+
+		rrType := binary.BigEndian.Uint16(msg[next:])
+		dataLen := int(binary.BigEndian.Uint16(msg[next+8:]))
+		dataStart := next + 10
+		off = dataStart + dataLen
+		if off > len(msg) {
+			return nil, fmt.Errorf("Truncated DNS record data")
+		}
+
+		if rrType != typeTXT || len(name) != len(service)+1 || !equalLabels(name[1:], service) {
+			continue
+		}
+
+		peer, ok := parseTXT(msg[dataStart:off])
+		if ok {
+			peers = append(peers, peer)
+		}
+	}
+
+	return peers, nil
+}
+
+// parseTXT builds a peer from the key=value strings of a TXT record. Records missing mandatory keys are ignored.
+func parseTXT(data []byte) (types.DiscoveredPeer, bool) {
+	values := map[string]string{}
+	for len(data) > 0 {
+		length := int(data[0])
+		if 1+length > len(data) {
+			return types.DiscoveredPeer{}, false
+		}
+
+		key, value, ok := strings.Cut(string(data[1:1+length]), "=")
+		if ok {
+			values[key] = value
+		}
+
+		data = data[1+length:]
+	}
+
+	if values[txtName] == "" || values[txtFingerprint] == "" {
+		return types.DiscoveredPeer{}, false
+	}
+
+	addrPort, err := types.ParseAddrPort(values[txtAddress])
+	if err != nil {
+		return types.DiscoveredPeer{}, false
+	}
+
+	return types.DiscoveredPeer{
+		Name:        values[txtName],
+		Address:     addrPort,
+		Fingerprint: values[txtFingerprint],
+		Version:     values[txtVersion],
+	}, true
+}
@@ -0,0 +1,65 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+func TestServiceName(t *testing.T) {
+	assert.Equal(t, []string{"_microcluster", "_tcp", "local"}, ServiceName("microcluster"))
+	assert.Equal(t, []string{"_my-app-v2", "_tcp", "local"}, ServiceName("My.App_v2"))
+}
+
+func TestQueryResponseRoundTrip(t *testing.T) {
+	service := ServiceName("microcluster")
+
+	query, err := encodeQuery(service)
+	require.NoError(t, err)
+
+	id, ok := isQuery(query, service)
+	assert.True(t, ok)
+
+	_, ok = isQuery(query, ServiceName("other"))
+	assert.False(t, ok)
+
+	addr, err := types.ParseAddrPort("10.0.0.1:9000")
+	require.NoError(t, err)
+
+	peer := types.DiscoveredPeer{Name: "c1.example", Address: addr, Fingerprint: "abcdef", Version: "1.0"}
+	resp, err := encodeResponse(id, service, peer)
+	require.NoError(t, err)
+
+	// A response is never mistaken for a query.
+	_, ok = isQuery(resp, service)
+	assert.False(t, ok)
+
+	peers, err := parseResponse(resp, service)
+	require.NoError(t, err)
+	assert.Equal(t, []types.DiscoveredPeer{peer}, peers)
+
+	peers, err = parseResponse(resp, ServiceName("other"))
+	require.NoError(t, err)
+	assert.Empty(t, peers)
+}
+
+func TestReadNameCompression(t *testing.T) {
+	msg, err := encodeName(nil, []string{"_microcluster", "_tcp", "local"})
+	require.NoError(t, err)
+
+	// Append "c1" followed by a pointer back to offset 0.
+	start := len(msg)
+	msg = append(msg, 2, 'c', '1', 0xc0, 0x00)
+
+	labels, next, err := readName(msg, start)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c1", "_microcluster", "_tcp", "local"}, labels)
+	assert.Equal(t, len(msg), next)
+
+	// A pointer loop must not hang.
+	_, _, err = readName([]byte{0xc0, 0x00}, 0)
+	assert.Error(t, err)
+}
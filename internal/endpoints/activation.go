@@ -0,0 +1,64 @@
+package endpoints
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenFDsStart is the first file descriptor passed by the service manager for socket activation.
+const listenFDsStart = 3
+
+// activatedListener returns the unix socket listener bound to the given path that was passed by the service manager
+// with socket activation, as described by $LISTEN_PID and $LISTEN_FDS. It returns nil if no such socket was passed.
+func activatedListener(path string) (*net.UnixListener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		// Other file descriptors are left untouched, as they may be meant for something else.
+		addr, err := unix.Getsockname(fd)
+		if err != nil {
+			continue
+		}
+
+		unixAddr, ok := addr.(*unix.SockaddrUnix)
+		if !ok || unixAddr.Name != path {
+			continue
+		}
+
+		file := os.NewFile(uintptr(fd), path)
+		listener, err := net.FileListener(file)
+
+		// The listener holds its own copy of the file descriptor.
+		closeErr := file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to use activated socket %q: %w", path, err)
+		}
+
+		if closeErr != nil {
+			_ = listener.Close()
+			return nil, fmt.Errorf("Failed to close activated socket %q: %w", path, closeErr)
+		}
+
+		unixListener, ok := listener.(*net.UnixListener)
+		if !ok {
+			_ = listener.Close()
+			return nil, fmt.Errorf("Activated socket %q is not a unix socket listener", path)
+		}
+
+		return unixListener, nil
+	}
+
+	return nil, nil
+}
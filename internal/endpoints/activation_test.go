@@ -0,0 +1,79 @@
+package endpoints
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// activatedSocketEnv holds the path of the socket the test process expects to be passed with socket activation.
+const activatedSocketEnv = "TEST_ACTIVATED_SOCKET"
+
+// Ensures the socket bound to the given path is picked among the file descriptors passed by the service manager, and
+// that other file descriptors are left untouched.
+func TestActivatedListener(t *testing.T) {
+	path := os.Getenv(activatedSocketEnv)
+	if path != "" {
+		// The service manager sets $LISTEN_PID to the PID of the process it started.
+		require.NoError(t, os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid())))
+
+		listener, err := activatedListener(filepath.Join(filepath.Dir(path), "other.socket"))
+		require.NoError(t, err)
+		require.Nil(t, listener)
+
+		listener, err = activatedListener(path)
+		require.NoError(t, err)
+		require.NotNil(t, listener)
+		require.Equal(t, path, listener.Addr().String())
+		require.NoError(t, listener.Close())
+
+		// The regular file passed before the socket is still open.
+		_, err = os.NewFile(listenFDsStart, "file").Stat()
+		require.NoError(t, err)
+
+		// Sockets passed to another process are ignored.
+		require.NoError(t, os.Setenv("LISTEN_PID", "1"))
+		listener, err = activatedListener(path)
+		require.NoError(t, err)
+		require.Nil(t, listener)
+
+		return
+	}
+
+	// Without socket activation, no listener is returned.
+	t.Setenv("LISTEN_PID", "")
+	listener, err := activatedListener("control.socket")
+	require.NoError(t, err)
+	require.Nil(t, listener)
+
+	dir := t.TempDir()
+	file, err := os.Create(filepath.Join(dir, "file"))
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	files := []*os.File{file}
+	for _, name := range []string{"control.socket", "api.socket"} {
+		listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: filepath.Join(dir, name), Net: "unix"})
+		require.NoError(t, err)
+		defer func() { _ = listener.Close() }()
+
+		socket, err := listener.File()
+		require.NoError(t, err)
+		defer func() { _ = socket.Close() }()
+
+		files = append(files, socket)
+	}
+
+	// Run this test in a new process, which is passed the sockets as the service manager would.
+	cmd := exec.Command(os.Args[0], "-test.run=^TestActivatedListener$")
+	cmd.Env = append(os.Environ(), activatedSocketEnv+"="+filepath.Join(dir, "control.socket"), "LISTEN_FDS="+strconv.Itoa(len(files)))
+	cmd.ExtraFiles = files
+
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
@@ -0,0 +1,133 @@
+package endpoints
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/internal/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// controlTCPReadHeaderTimeout is how long a client may take to send the headers of its request, so that local
+// processes cannot hold connections to the control API open without presenting the secret.
+const controlTCPReadHeaderTimeout = 10 * time.Second
+
+// ControlTCP serves the control API over a loopback TCP address, for clients that cannot reach the unix socket.
+// Requests must hold the shared secret of the daemon, and are then handled like requests over the unix socket.
+type ControlTCP struct {
+	address types.AddrPort
+
+	listener net.Listener
+	server   *http.Server
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	drainConnectionsTimeout time.Duration
+}
+
+// NewControlTCP returns a ControlTCP with no listener attached yet. Requests without the given secret are rejected.
+func NewControlTCP(ctx context.Context, server *http.Server, address types.AddrPort, secret string, drainConnTimeout time.Duration) *ControlTCP {
+	ctx, cancel := context.WithCancel(ctx)
+
+	handler := server.Handler
+	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !access.HasControlSecret(r, secret) {
+			err := response.Forbidden(fmt.Errorf("Missing or invalid control secret")).Render(w)
+			if err != nil {
+				logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			}
+
+			return
+		}
+
+		// Requests holding the secret are trusted like requests over the unix socket.
+		r = access.SetControlSecretVerified(r)
+		r.RemoteAddr = "@"
+		handler.ServeHTTP(w, r)
+	})
+
+	if server.ReadHeaderTimeout == 0 {
+		server.ReadHeaderTimeout = controlTCPReadHeaderTimeout
+	}
+
+	return &ControlTCP{
+		address: address,
+
+		server: server,
+		ctx:    ctx,
+		cancel: cancel,
+
+		drainConnectionsTimeout: drainConnTimeout,
+	}
+}
+
+// Type returns the type of the Endpoint.
+func (c *ControlTCP) Type() EndpointType {
+	return EndpointControl
+}
+
+// Listen on the loopback TCP address.
+func (c *ControlTCP) Listen() error {
+	if !c.address.Addr().IsLoopback() {
+		return fmt.Errorf("Control API address %q is not a loopback address", c.address)
+	}
+
+	var err error
+	c.listener, err = net.Listen("tcp", c.address.String())
+	if err != nil {
+		return fmt.Errorf("Cannot listen on control API address %q: %w", c.address, err)
+	}
+
+	return nil
+}
+
+// Serve binds to the ControlTCP's server.
+func (c *ControlTCP) Serve() {
+	if c.listener == nil {
+		return
+	}
+
+	ctx := logger.Ctx{"address": c.listener.Addr()}
+	logger.Info(" - binding control TCP socket", ctx)
+
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			logger.Infof("Received shutdown signal - aborting control TCP server startup")
+		default:
+			err := c.server.Serve(c.listener)
+			if err != nil {
+				select {
+				case <-c.ctx.Done():
+					logger.Infof("Received shutdown signal - aborting control TCP server startup")
+				default:
+					logger.Error("Failed to start server", logger.Ctx{"err": err})
+				}
+			}
+		}
+	}()
+}
+
+// Close the ControlTCP's listener.
+func (c *ControlTCP) Close() error {
+	if c.listener == nil {
+		return nil
+	}
+
+	logger.Info("Stopping REST API handler - closing control TCP socket", logger.Ctx{"address": c.listener.Addr()})
+	c.cancel()
+
+	return c.listener.Close()
+}
+
+// ShutdownServer shuts down the server.
+func (c *ControlTCP) ShutdownServer() error {
+	return shutdownServer(context.Background(), c.server, c.drainConnectionsTimeout)
+}
@@ -0,0 +1,68 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/internal/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures the control API over TCP only serves requests holding the secret, and does not wait indefinitely for the
+// headers of a request.
+func TestControlTCP(t *testing.T) {
+	address, err := types.ParseAddrPort("127.0.0.1:0")
+	require.NoError(t, err)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	})
+
+	// Servers are given a timeout to read the request headers, unless they already have one.
+	server := &http.Server{Handler: handler}
+	NewControlTCP(context.Background(), server, address, "secret", time.Second)
+	require.Equal(t, controlTCPReadHeaderTimeout, server.ReadHeaderTimeout)
+
+	server = &http.Server{Handler: handler, ReadHeaderTimeout: 100 * time.Millisecond}
+	control := NewControlTCP(context.Background(), server, address, "secret", time.Second)
+	require.Equal(t, 100*time.Millisecond, server.ReadHeaderTimeout)
+
+	require.NoError(t, control.Listen())
+	control.Serve()
+	t.Cleanup(func() { _ = control.Close() })
+
+	url := "http://" + control.listener.Addr().String()
+	for secret, status := range map[string]int{"": http.StatusForbidden, "other": http.StatusForbidden, "secret": http.StatusOK} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		req.Header.Set(access.ControlSecretHeader, secret)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, status, resp.StatusCode, secret)
+	}
+
+	// Connections that never send their headers are closed.
+	conn, err := net.Dial("tcp", control.listener.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1024))
+	require.Error(t, err)
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		require.False(t, netErr.Timeout())
+	}
+}
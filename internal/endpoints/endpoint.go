@@ -24,8 +24,17 @@ const (
 	// EndpointsUnix represents the name of the Unix endpoints.
 	EndpointsUnix string = "unix"
 
+	// EndpointsControlTCP represents the name of the control API endpoint over a loopback TCP address.
+	EndpointsControlTCP string = "control-tcp"
+
 	// EndpointsCore represents the name of the core API endpoints.
 	EndpointsCore string = "core"
+
+	// EndpointsEtcd represents the name of the etcd compatible API endpoint.
+	EndpointsEtcd string = "etcd"
+
+	// EndpointsReadOnly represents the name of the read-only API endpoint.
+	EndpointsReadOnly string = "read-only"
 )
 
 // String labels EndpointTypes for logging purposes.
@@ -97,6 +97,7 @@ func (e *Endpoints) up(listeners map[string]Endpoint) error {
 }
 
 // Down closes all of the configured listeners, or any for the type specifically supplied.
+// The servers of closed network listeners are drained in the background.
 func (e *Endpoints) Down(types ...EndpointType) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -115,6 +116,8 @@ func (e *Endpoints) Down(types ...EndpointType) error {
 				return err
 			}
 
+			drainStopped(name, endpoint)
+
 			// Delete the stopped endpoint from the slice.
 			delete(e.listeners, name)
 		}
@@ -124,6 +127,7 @@ func (e *Endpoints) Down(types ...EndpointType) error {
 }
 
 // DownByName closes the configured listeners based on its name.
+// The server of a closed network listener is drained in the background.
 func (e *Endpoints) DownByName(name string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -135,6 +139,8 @@ func (e *Endpoints) DownByName(name string) error {
 				return err
 			}
 
+			drainStopped(name, endpoint)
+
 			// Delete the stopped endpoint from the slice.
 			delete(e.listeners, name)
 		}
@@ -143,12 +149,34 @@ func (e *Endpoints) DownByName(name string) error {
 	return nil
 }
 
-// Shutdown closes all of the configured listeners and their servers.
-func (e *Endpoints) Shutdown() error {
+// drainStopped gracefully shuts down the server of a network listener that was closed without being shut down, for
+// instance to apply a new address or certificate, so that the requests it accepted can finish within its drain
+// timeout instead of keeping their connections open indefinitely. Servers without a drain timeout are left as is, as
+// closing them would abort the request that may have caused the listener to be closed.
+func drainStopped(name string, endpoint Endpoint) {
+	network, ok := endpoint.(*Network)
+	if !ok || network.drainConnectionsTimeout == 0 {
+		return
+	}
+
+	go func() {
+		err := network.ShutdownServer()
+		if err != nil {
+			logger.Warn("Failed to drain connections of stopped listener", logger.Ctx{"name": name, "error": err})
+		}
+	}()
+}
+
+// Shutdown closes all of the configured listeners and their servers, or any for the type specifically supplied.
+func (e *Endpoints) Shutdown(types ...EndpointType) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	for name, endpoint := range e.listeners {
+		if types != nil && !shared.ValueInSlice(endpoint.Type(), types) {
+			continue
+		}
+
 		err := endpoint.Close()
 		if err != nil {
 			return err
@@ -2,6 +2,7 @@ package endpoints
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"net/http"
@@ -24,7 +25,13 @@ type Network struct {
 	networkType EndpointType
 
 	listener net.Listener
+	reserved net.Listener
 	server   *http.Server
+	http2    bool
+
+	tlsPolicy *TLSPolicy
+	isTrusted func(cert *x509.Certificate) bool
+	isRevoked func(cert *x509.Certificate) bool
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -49,6 +56,31 @@ func NewNetwork(ctx context.Context, endpointType EndpointType, server *http.Ser
 	}
 }
 
+// EnableHTTP2 makes the listener advertise HTTP/2 through TLS ALPN alongside HTTP/1.1.
+// It must be called before Listen.
+func (n *Network) EnableHTTP2() {
+	n.http2 = true
+}
+
+// SetTLSPolicy applies the policy to the TLS configuration of the listener. isTrusted reports whether a client
+// certificate belongs to a cluster member or a trusted client. It must be called before Listen.
+func (n *Network) SetTLSPolicy(policy TLSPolicy, isTrusted func(cert *x509.Certificate) bool) {
+	n.tlsPolicy = &policy
+	n.isTrusted = isTrusted
+}
+
+// SetRevocationCheck rejects clients presenting a certificate for which isRevoked returns true during the TLS
+// handshake. It must be called before Listen.
+func (n *Network) SetRevocationCheck(isRevoked func(cert *x509.Certificate) bool) {
+	n.isRevoked = isRevoked
+}
+
+// SetListener makes Listen use the given TCP listener, already bound to the address, instead of binding it again.
+// It must be called before Listen.
+func (n *Network) SetListener(listener net.Listener) {
+	n.reserved = listener
+}
+
 // Type returns the type of the Endpoint.
 func (n *Network) Type() EndpointType {
 	return n.networkType
@@ -63,24 +95,37 @@ func (n *Network) Listen() error {
 		protocol = "tcp4"
 	}
 
-	_, err := net.Dial(protocol, listenAddress)
-	if err == nil {
-		return fmt.Errorf("%q listener with address %q is already running", protocol, listenAddress)
-	}
+	listener := n.reserved
+	n.reserved = nil
+	if listener == nil {
+		_, err := net.Dial(protocol, listenAddress)
+		if err == nil {
+			return fmt.Errorf("%q listener with address %q is already running", protocol, listenAddress)
+		}
 
-	listener, err := net.Listen(protocol, listenAddress)
-	if err != nil {
-		return fmt.Errorf("Failed to listen on https socket: %w", err)
+		listener, err = net.Listen(protocol, listenAddress)
+		if err != nil {
+			return fmt.Errorf("Failed to listen on https socket: %w", err)
+		}
 	}
 
-	n.listener = listeners.NewFancyTLSListener(listener, n.cert)
+	if n.http2 || n.tlsPolicy != nil || n.isRevoked != nil {
+		var nextProtos []string
+		if n.http2 {
+			nextProtos = http2Protocols
+		}
+
+		n.listener = newTLSListener(listener, n.cert, nextProtos, n.tlsPolicy, n.isTrusted, n.isRevoked)
+	} else {
+		n.listener = listeners.NewFancyTLSListener(listener, n.cert)
+	}
 
 	return nil
 }
 
 // UpdateTLS updates the TLS configuration of the network listener.
 func (n *Network) UpdateTLS(cert *shared.CertInfo) {
-	l, ok := n.listener.(*listeners.FancyTLSListener)
+	l, ok := n.listener.(interface{ Config(cert *shared.CertInfo) })
 	if ok {
 		n.certMu.Lock()
 		n.cert = cert
@@ -90,6 +135,11 @@ func (n *Network) UpdateTLS(cert *shared.CertInfo) {
 	}
 }
 
+// Address returns the address the network listens on.
+func (n *Network) Address() api.URL {
+	return n.address
+}
+
 // TLS returns the network's certificate information.
 func (n *Network) TLS() *shared.CertInfo {
 	n.certMu.RLock()
@@ -141,6 +191,7 @@ func (n *Network) Close() error {
 }
 
 // Shutdown the server.
+// The listener context is already cancelled by Close, so it does not bound how long connections may drain.
 func (n *Network) ShutdownServer() error {
-	return shutdownServer(n.ctx, n.server, n.drainConnectionsTimeout)
+	return shutdownServer(context.Background(), n.server, n.drainConnectionsTimeout)
 }
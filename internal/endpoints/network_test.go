@@ -0,0 +1,55 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures the network endpoint serves on a listener reserved for its address, which it cannot bind again itself.
+func TestNetworkSetListener(t *testing.T) {
+	reserved, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	address := *api.NewURL().Scheme("https").Host(reserved.Addr().String())
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	// The address is already bound by the reserved listener.
+	network := NewNetwork(context.Background(), EndpointNetwork, server, address, shared.TestingKeyPair(), time.Second)
+	require.Error(t, network.Listen())
+
+	network.SetListener(reserved)
+	require.NoError(t, network.Listen())
+	network.Serve()
+	t.Cleanup(func() { _ = network.Close() })
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		Timeout:   5 * time.Second,
+	}
+
+	resp, err := client.Get(address.String())
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(body))
+
+	// The reserved listener is only adopted once, so the address is bound again once it is closed.
+	require.NoError(t, network.Close())
+	require.NoError(t, network.Listen())
+}
@@ -13,12 +13,17 @@ import (
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/internal/rest/access"
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/rest/types"
 )
 
-// Socket represents a unix socket with a given path.
+// Socket represents a unix socket with a given path, or a name in the abstract namespace if it starts with "@".
 type Socket struct {
 	Path  string
 	Group string
+	Mode  os.FileMode
 
 	listener *net.UnixListener
 	server   *http.Server
@@ -32,9 +37,14 @@ type Socket struct {
 // NewSocket returns a Socket struct with no listener attached yet.
 func NewSocket(ctx context.Context, server *http.Server, path api.URL, group string, drainConnTimeout time.Duration) *Socket {
 	ctx, cancel := context.WithCancel(ctx)
+
+	// Record the credentials of the calling process so that they can be used for authorization.
+	server.ConnContext = access.PeerCredentialsContext
+
 	return &Socket{
 		Path:  path.Hostname(),
 		Group: group,
+		Mode:  types.DefaultSocketMode,
 
 		server: server,
 		ctx:    ctx,
@@ -49,16 +59,33 @@ func (s *Socket) Type() EndpointType {
 	return EndpointControl
 }
 
-// Listen on the unix socket path.
+// Listen on the unix socket path. If the socket was passed by the service manager with socket activation, it is used
+// as is, leaving its ownership and permissions to the service manager.
 func (s *Socket) Listen() error {
-	_, err := net.Dial("unix", s.Path)
+	listener, err := activatedListener(s.Path)
+	if err != nil {
+		return err
+	}
+
+	if listener != nil {
+		logger.Info("Using socket passed by the service manager", logger.Ctx{"socket": s.Path})
+		s.listener = listener
+
+		return nil
+	}
+
+	_, err = net.Dial("unix", s.Path)
 	if err == nil {
 		return fmt.Errorf("Unix socket at %q is already running", s.Path)
 	}
 
-	err = s.removeStale()
-	if err != nil {
-		return err
+	// Abstract sockets have no file to clean up or restrict access to, and are removed along with their listener.
+	abstract := sys.IsAbstractSocket(s.Path)
+	if !abstract {
+		err = s.removeStale()
+		if err != nil {
+			return err
+		}
 	}
 
 	addr, err := net.ResolveUnixAddr("unix", s.Path)
@@ -71,7 +98,11 @@ func (s *Socket) Listen() error {
 		return fmt.Errorf("Cannot bind socket: %w", err)
 	}
 
-	err = localSetAccess(s.Path, s.Group)
+	if abstract {
+		return nil
+	}
+
+	err = localSetAccess(s.Path, s.Group, s.Mode)
 	if err != nil {
 		closeErr := s.listener.Close()
 		if closeErr != nil {
@@ -84,6 +115,41 @@ func (s *Socket) Listen() error {
 	return nil
 }
 
+// CheckAccess checks that the socket file can be given to the given group. Abstract sockets have no file, so their
+// access cannot be changed.
+func (s *Socket) CheckAccess(group string) error {
+	if sys.IsAbstractSocket(s.Path) {
+		return fmt.Errorf("Cannot change the access of abstract socket %q", s.Path)
+	}
+
+	if group != "" {
+		_, err := lookupGroupID(group)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetAccess changes the group and file mode of the socket file once the socket is listening, including sockets passed
+// by the service manager.
+func (s *Socket) SetAccess(group string, mode os.FileMode) error {
+	err := s.CheckAccess(group)
+	if err != nil {
+		return err
+	}
+
+	s.Group = group
+	s.Mode = mode
+
+	if s.listener == nil {
+		return nil
+	}
+
+	return localSetAccess(s.Path, group, mode)
+}
+
 // Serve binds to the Socket's server.
 func (s *Socket) Serve() {
 	if s.listener == nil {
@@ -127,7 +193,7 @@ func (s *Socket) Close() error {
 
 // Shutdown the server.
 func (s *Socket) ShutdownServer() error {
-        return shutdownServer(s.ctx, s.server, s.drainConnectionsTimeout)
+	return shutdownServer(context.Background(), s.server, s.drainConnectionsTimeout)
 }
 
 // Remove any stale socket file at the given path.
@@ -147,10 +213,10 @@ func (s *Socket) removeStale() error {
 }
 
 // Change the file mode and ownership of the local endpoint control socket file,
-// so access is granted only to the process user and to the given group (or the
-// process group if group is empty).
-func localSetAccess(path string, group string) error {
-	err := socketControlSetPermissions(path, 0660)
+// so access is granted according to the mode to the process user and to the
+// given group (or the process group if group is empty).
+func localSetAccess(path string, group string, mode os.FileMode) error {
+	err := socketControlSetPermissions(path, mode)
 	if err != nil {
 		return err
 	}
@@ -179,12 +245,7 @@ func socketControlSetOwnership(path string, groupName string) error {
 	var err error
 
 	if groupName != "" {
-		g, err := user.LookupGroup(groupName)
-		if err != nil {
-			return fmt.Errorf("Cannot get group ID of '%s': %w", groupName, err)
-		}
-
-		gid, err = strconv.Atoi(g.Gid)
+		gid, err = lookupGroupID(groupName)
 		if err != nil {
 			return err
 		}
@@ -199,3 +260,13 @@ func socketControlSetOwnership(path string, groupName string) error {
 
 	return nil
 }
+
+// lookupGroupID returns the ID of the group with the given name.
+func lookupGroupID(groupName string) (int, error) {
+	g, err := user.LookupGroup(groupName)
+	if err != nil {
+		return 0, fmt.Errorf("Cannot get group ID of '%s': %w", groupName, err)
+	}
+
+	return strconv.Atoi(g.Gid)
+}
@@ -0,0 +1,228 @@
+package endpoints
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
+)
+
+// http2Protocols are the application protocols advertised through ALPN by listeners serving HTTP/2, in order of
+// preference.
+var http2Protocols = []string{"h2", "http/1.1"}
+
+// TLSPolicy customizes the TLS configuration of the network listeners of a daemon, and of its connections to other
+// cluster members.
+type TLSPolicy struct {
+	// MinVersion is the minimum TLS version accepted. It must be at least TLS 1.2. The default is TLS 1.3, unless
+	// LXD_INSECURE_TLS is set.
+	MinVersion uint16
+
+	// CipherSuites restricts the cipher suites accepted for TLS 1.2 connections. TLS 1.3 cipher suites are not
+	// configurable. It defaults to the suites used by LXD.
+	CipherSuites []uint16
+
+	// RequireClientCertificate rejects connections from clients that do not present a certificate.
+	RequireClientCertificate bool
+
+	// VerifyClientCertificates rejects client certificates during the handshake, unless they belong to a cluster
+	// member or a trusted client, or are signed by one of ClientCAs. As the certificate of a new member is not
+	// trusted until it has joined, members cannot join the cluster while this is set.
+	VerifyClientCertificates bool
+
+	// ClientCAs are the certificate authorities whose client certificates pass the handshake when
+	// VerifyClientCertificates is set. Such clients are still untrusted by the API unless their certificate is
+	// added to the trust store.
+	ClientCAs *x509.CertPool
+
+	// PinPeerCertificates makes the daemon only accept the exact certificate expected of other cluster members when
+	// connecting to them, rather than any certificate signed with its key.
+	PinPeerCertificates bool
+
+	// Customize, if set, is called with the resulting configuration every time it is built, to change any other setting.
+	Customize func(config *tls.Config)
+}
+
+// Validate checks that the policy does not weaken the default TLS configuration.
+func (p TLSPolicy) Validate() error {
+	if p.MinVersion != 0 && p.MinVersion < tls.VersionTLS12 {
+		return fmt.Errorf("Minimum TLS version cannot be lower than TLS 1.2")
+	}
+
+	for _, id := range p.CipherSuites {
+		secure := slices.ContainsFunc(tls.CipherSuites(), func(suite *tls.CipherSuite) bool { return suite.ID == id })
+		if !secure {
+			return fmt.Errorf("Cipher suite %q is not supported or insecure", tls.CipherSuiteName(id))
+		}
+	}
+
+	if p.ClientCAs != nil && !p.VerifyClientCertificates {
+		return fmt.Errorf("Client certificate authorities require client certificate verification")
+	}
+
+	return nil
+}
+
+// apply changes the configuration according to the policy.
+// isTrusted reports whether a client certificate belongs to a cluster member or a trusted client.
+func (p *TLSPolicy) apply(config *tls.Config, isTrusted func(cert *x509.Certificate) bool) {
+	if p.MinVersion != 0 {
+		config.MinVersion = p.MinVersion
+	}
+
+	if len(p.CipherSuites) > 0 {
+		config.CipherSuites = p.CipherSuites
+	}
+
+	if p.RequireClientCertificate {
+		config.ClientAuth = tls.RequireAnyClientCert
+	}
+
+	if p.VerifyClientCertificates {
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return p.verifyClientCertificate(rawCerts, isTrusted)
+		}
+	}
+
+	if p.Customize != nil {
+		p.Customize(config)
+	}
+}
+
+// verifyClientCertificate checks that the client certificate is trusted, or is signed by one of the client CAs.
+// Clients without a certificate are left to the ClientAuth setting.
+func (p *TLSPolicy) verifyClientCertificate(rawCerts [][]byte, isTrusted func(cert *x509.Certificate) bool) error {
+	if len(rawCerts) == 0 {
+		return nil
+	}
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("Failed to parse client certificate: %w", err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if isTrusted != nil && isTrusted(certs[0]) {
+		return nil
+	}
+
+	if p.ClientCAs != nil {
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         p.ClientCAs,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Client certificate %q is not trusted", shared.CertFingerprint(certs[0]))
+}
+
+// verifyNotRevoked checks that no certificate presented by the client, including its intermediates, is revoked.
+func verifyNotRevoked(rawCerts [][]byte, isRevoked func(cert *x509.Certificate) bool) error {
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("Failed to parse client certificate: %w", err)
+		}
+
+		if isRevoked(cert) {
+			return fmt.Errorf("Client certificate %q is revoked", shared.CertFingerprint(cert))
+		}
+	}
+
+	return nil
+}
+
+// tlsListener is a TLS listener that advertises the given application protocols through ALPN, and applies an
+// optional TLS policy and revocation check.
+type tlsListener struct {
+	net.Listener
+
+	nextProtos []string
+	policy     *TLSPolicy
+	isTrusted  func(cert *x509.Certificate) bool
+	isRevoked  func(cert *x509.Certificate) bool
+
+	configMu sync.RWMutex
+	config   *tls.Config
+}
+
+// newTLSListener wraps the listener to serve TLS with the given certificate, application protocols and policy.
+// Clients presenting a certificate for which isRevoked returns true are rejected during the handshake.
+func newTLSListener(inner net.Listener, cert *shared.CertInfo, nextProtos []string, policy *TLSPolicy, isTrusted func(cert *x509.Certificate) bool, isRevoked func(cert *x509.Certificate) bool) *tlsListener {
+	listener := &tlsListener{
+		Listener:   inner,
+		nextProtos: nextProtos,
+		policy:     policy,
+		isTrusted:  isTrusted,
+		isRevoked:  isRevoked,
+	}
+
+	listener.Config(cert)
+
+	return listener
+}
+
+// Accept waits for the next connection, and wraps it in a TLS server connection.
+func (l *tlsListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	l.configMu.RLock()
+	config := l.config
+	l.configMu.RUnlock()
+
+	return tls.Server(conn, config), nil
+}
+
+// Config sets the certificate served to new connections.
+func (l *tlsListener) Config(cert *shared.CertInfo) {
+	config := util.ServerTLSConfig(cert)
+	if l.nextProtos != nil {
+		config.NextProtos = l.nextProtos
+	}
+
+	if l.policy != nil {
+		l.policy.apply(config, l.isTrusted)
+	}
+
+	// Revoked certificates are rejected regardless of the policy, including any customization of the configuration.
+	if l.isRevoked != nil {
+		verify := config.VerifyPeerCertificate
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, chains [][]*x509.Certificate) error {
+			err := verifyNotRevoked(rawCerts, l.isRevoked)
+			if err != nil {
+				return err
+			}
+
+			if verify != nil {
+				return verify(rawCerts, chains)
+			}
+
+			return nil
+		}
+	}
+
+	l.configMu.Lock()
+	l.config = config
+	l.configMu.Unlock()
+}
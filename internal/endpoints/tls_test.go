@@ -0,0 +1,179 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+)
+
+// serveHandshakes completes the TLS handshake of every connection accepted by the listener, and writes a byte to the
+// clients that pass it, so that they learn whether their certificate was accepted.
+func serveHandshakes(t *testing.T, listener net.Listener) {
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer func() { _ = conn.Close() }()
+
+				err := conn.(*tls.Conn).Handshake()
+				if err == nil {
+					_, _ = conn.Write([]byte{1})
+				}
+			}()
+		}
+	}()
+}
+
+// handshake connects to the listener with the given client configuration, and returns whether the server accepted it.
+func handshake(listener net.Listener, config *tls.Config) error {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", listener.Addr().String(), config)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	// The byte may be returned along with the closure of the connection.
+	n, err := conn.Read(make([]byte, 1))
+	if n == 1 {
+		return nil
+	}
+
+	return err
+}
+
+// newTestListener returns a TLS listener on a loopback address, serving the testing certificate.
+func newTestListener(t *testing.T, policy *TLSPolicy, isTrusted func(cert *x509.Certificate) bool, isRevoked func(cert *x509.Certificate) bool) *tlsListener {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	listener := newTLSListener(inner, shared.TestingKeyPair(), nil, policy, isTrusted, isRevoked)
+	serveHandshakes(t, listener)
+
+	return listener
+}
+
+// Ensures the TLS policy is enforced during the handshake.
+func TestTLSListenerPolicy(t *testing.T) {
+	trusted := shared.TestingKeyPair().KeyPair()
+	untrusted := shared.TestingAltKeyPair().KeyPair()
+	trustedFingerprint := shared.TestingKeyPair().Fingerprint()
+	isTrusted := func(cert *x509.Certificate) bool { return shared.CertFingerprint(cert) == trustedFingerprint }
+
+	clientConfig := func(version uint16, certs ...tls.Certificate) *tls.Config {
+		return &tls.Config{InsecureSkipVerify: true, MinVersion: version, MaxVersion: version, Certificates: certs}
+	}
+
+	withCipherSuites := func(config *tls.Config, suites ...uint16) *tls.Config {
+		config.CipherSuites = suites
+		return config
+	}
+
+	aes128 := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	aes256 := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}
+
+	cases := []struct {
+		name      string
+		policy    *TLSPolicy
+		client    *tls.Config
+		expectErr bool
+	}{
+		{name: "Default configuration", client: clientConfig(tls.VersionTLS13)},
+		{name: "Default minimum version not met", client: clientConfig(tls.VersionTLS12), expectErr: true},
+		{name: "Lowered minimum version", policy: &TLSPolicy{MinVersion: tls.VersionTLS12}, client: clientConfig(tls.VersionTLS12)},
+		{name: "Cipher suite allowed", policy: &TLSPolicy{MinVersion: tls.VersionTLS12, CipherSuites: aes256}, client: withCipherSuites(clientConfig(tls.VersionTLS12), aes256...)},
+		{name: "Cipher suite not allowed", policy: &TLSPolicy{MinVersion: tls.VersionTLS12, CipherSuites: aes256}, client: withCipherSuites(clientConfig(tls.VersionTLS12), aes128...), expectErr: true},
+		{name: "Client certificate required", policy: &TLSPolicy{RequireClientCertificate: true}, client: clientConfig(tls.VersionTLS13), expectErr: true},
+		{name: "Client certificate provided", policy: &TLSPolicy{RequireClientCertificate: true}, client: clientConfig(tls.VersionTLS13, untrusted)},
+		{name: "Trusted client certificate", policy: &TLSPolicy{VerifyClientCertificates: true}, client: clientConfig(tls.VersionTLS13, trusted)},
+		{name: "Untrusted client certificate", policy: &TLSPolicy{VerifyClientCertificates: true}, client: clientConfig(tls.VersionTLS13, untrusted), expectErr: true},
+		{name: "Customized configuration", policy: &TLSPolicy{Customize: func(config *tls.Config) { config.ClientAuth = tls.RequireAnyClientCert }}, client: clientConfig(tls.VersionTLS13), expectErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			listener := newTestListener(t, c.policy, isTrusted, nil)
+
+			err := handshake(listener, c.client)
+			if c.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// Ensures the TLS policy set on a network listener applies to its connections.
+func TestNetworkTLSPolicy(t *testing.T) {
+	url := api.NewURL().Scheme("https").Host("127.0.0.1:0")
+	network := NewNetwork(context.Background(), EndpointNetwork, nil, *url, shared.TestingKeyPair(), 0)
+	network.SetTLSPolicy(TLSPolicy{RequireClientCertificate: true}, nil)
+	require.NoError(t, network.Listen())
+	serveHandshakes(t, network.listener)
+
+	require.Error(t, handshake(network.listener, &tls.Config{InsecureSkipVerify: true}))
+	require.NoError(t, handshake(network.listener, &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{shared.TestingAltKeyPair().KeyPair()}}))
+}
+
+// Ensures a client is rejected if any certificate it presents is revoked.
+func TestVerifyNotRevoked(t *testing.T) {
+	cert := shared.TestingKeyPair().KeyPair()
+	altCert := shared.TestingAltKeyPair().KeyPair()
+	revokedFingerprint := shared.TestingAltKeyPair().Fingerprint()
+	isRevoked := func(cert *x509.Certificate) bool { return shared.CertFingerprint(cert) == revokedFingerprint }
+
+	require.NoError(t, verifyNotRevoked(nil, isRevoked))
+	require.NoError(t, verifyNotRevoked([][]byte{cert.Certificate[0]}, isRevoked))
+	require.Error(t, verifyNotRevoked([][]byte{altCert.Certificate[0]}, isRevoked))
+
+	// Revoked intermediates are rejected too.
+	require.Error(t, verifyNotRevoked([][]byte{cert.Certificate[0], altCert.Certificate[0]}, isRevoked))
+
+	// Certificates that can't be parsed are rejected.
+	require.Error(t, verifyNotRevoked([][]byte{[]byte("invalid")}, isRevoked))
+}
+
+// Ensures revoked client certificates are rejected during the handshake, regardless of the TLS policy.
+func TestTLSListenerRevocation(t *testing.T) {
+	trusted := shared.TestingKeyPair().KeyPair()
+	revoked := shared.TestingAltKeyPair().KeyPair()
+	revokedFingerprint := shared.TestingAltKeyPair().Fingerprint()
+	isRevoked := func(cert *x509.Certificate) bool { return shared.CertFingerprint(cert) == revokedFingerprint }
+	isTrusted := func(cert *x509.Certificate) bool { return true }
+
+	policies := map[string]*TLSPolicy{
+		"No policy":                   nil,
+		"Verified certificates":       {VerifyClientCertificates: true},
+		"Customized verification":     {Customize: func(config *tls.Config) { config.VerifyPeerCertificate = nil }},
+		"Required client certificate": {RequireClientCertificate: true},
+	}
+
+	for name, policy := range policies {
+		t.Run(name, func(t *testing.T) {
+			listener := newTestListener(t, policy, isTrusted, isRevoked)
+
+			require.NoError(t, handshake(listener, &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{trusted}}))
+			require.Error(t, handshake(listener, &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{revoked}}))
+		})
+	}
+
+	// Clients without a certificate are left to the policy.
+	listener := newTestListener(t, nil, isTrusted, isRevoked)
+	require.NoError(t, handshake(listener, &tls.Config{InsecureSkipVerify: true}))
+}
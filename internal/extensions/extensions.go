@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 
 	"github.com/canonical/lxd/shared"
 	"gopkg.in/yaml.v3"
@@ -72,6 +73,53 @@ func (e Extensions) HasExtension(ext string) bool {
 	return false
 }
 
+// Missing returns the extensions of the given registry that are not in the registry.
+func (e Extensions) Missing(t Extensions) Extensions {
+	missing := Extensions{}
+	for _, extension := range t {
+		if !e.HasExtension(extension) {
+			missing = append(missing, extension)
+		}
+	}
+
+	return missing
+}
+
+// Negotiate compares the registries of several cluster members, keyed by member name. It returns the extensions
+// supported by every member, and the extensions that each member lacks but another member supports.
+// Members that do not lack any extension are left out of the returned map.
+func Negotiate(registries map[string]Extensions) (supported Extensions, missing map[string]Extensions) {
+	names := make([]string, 0, len(registries))
+	for name := range registries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	all := Extensions{}
+	for _, name := range names {
+		all = append(all, all.Missing(registries[name])...)
+	}
+
+	supported = Extensions{}
+	missing = map[string]Extensions{}
+	for _, extension := range all {
+		everywhere := true
+		for _, name := range names {
+			if !registries[name].HasExtension(extension) {
+				everywhere = false
+				missing[name] = append(missing[name], extension)
+			}
+		}
+
+		if everywhere {
+			supported = append(supported, extension)
+		}
+	}
+
+	return supported, missing
+}
+
 // Version returns the number of extensions in the set, representing its version number.
 func (e Extensions) Version() int {
 	return len(e)
@@ -115,6 +115,25 @@ func TestIsSameVersion(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestNegotiate(t *testing.T) {
+	registries := map[string]Extensions{
+		"member1": {"internal:runtime_extension_v1", "ext_a", "ext_b"},
+		"member2": {"internal:runtime_extension_v1", "ext_a"},
+		"member3": {"internal:runtime_extension_v1", "ext_a", "ext_b", "ext_c"},
+	}
+
+	supported, missing := Negotiate(registries)
+	assert.Equal(t, Extensions{"internal:runtime_extension_v1", "ext_a"}, supported)
+	assert.Equal(t, map[string]Extensions{
+		"member1": {"ext_c"},
+		"member2": {"ext_b", "ext_c"},
+	}, missing)
+
+	supported, missing = Negotiate(map[string]Extensions{"member1": registries["member1"]})
+	assert.Equal(t, registries["member1"], supported)
+	assert.Empty(t, missing)
+}
+
 func TestRegisterALotOfExtensions(t *testing.T) {
 	registry, _ := NewExtensionRegistry(false)
 	for i := 0; i < 10000; i++ {
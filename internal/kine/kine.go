@@ -0,0 +1,422 @@
+// Package kine stores keys with the revision semantics of etcd v3 in the dqlite database, in the manner of kine, so
+// that an etcd v3 gRPC service can be served from the database of the cluster.
+package kine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// compactRevisionKey is the name of the row holding the revision up to which the history of keys was compacted.
+const compactRevisionKey = "compact_rev_key"
+
+var (
+	// ErrKeyExists is returned when creating a key that already exists.
+	ErrKeyExists = errors.New("Key exists")
+
+	// ErrCompacted is returned when reading a revision that was compacted.
+	ErrCompacted = errors.New("Required revision has been compacted")
+
+	// ErrFutureRevision is returned when reading a revision that does not exist yet.
+	ErrFutureRevision = errors.New("Required revision is a future revision")
+)
+
+// Database runs transactions against the database storing the keys.
+type Database interface {
+	Transaction(ctx context.Context, f func(context.Context, *sql.Tx) error) error
+}
+
+// Options enables an etcd v3 compatible API backed by the database of the cluster.
+type Options struct {
+	// Address is the address the etcd API is served at. Clients must present the certificate of a cluster member or
+	// of a trusted client.
+	Address types.AddrPort
+
+	// Server returns the handler of the etcd v3 gRPC service, served over HTTP/2, for the given backend. It is
+	// typically kine's server package registered with a grpc.Server, wrapped around an adapter of the backend.
+	// It is called once per daemon run, when the daemon starts serving its API. The daemon owns the backend and the
+	// listener the handler is served on, and closes the connections still open once it has drained them on shutdown,
+	// which cancels their request contexts. The handler must end its streams once their request context is done.
+	Server func(backend *Backend) http.Handler
+
+	// CompactInterval is how often the history of keys is compacted. It defaults to 5 minutes.
+	CompactInterval time.Duration
+
+	// CompactRetention is the number of revisions kept in the history of keys when compacting. It defaults to 1000.
+	CompactRetention int64
+}
+
+// Validate checks that the options can be used to serve the etcd API.
+func (o Options) Validate() error {
+	if o.Address == (types.AddrPort{}) {
+		return fmt.Errorf("Etcd API must have a defined address")
+	}
+
+	if o.Server == nil {
+		return fmt.Errorf("Etcd API must have a server")
+	}
+
+	if o.CompactInterval < 0 || o.CompactRetention < 0 {
+		return fmt.Errorf("Etcd API compaction interval and retention cannot be negative")
+	}
+
+	return nil
+}
+
+// KeyValue is a revision of a key.
+type KeyValue struct {
+	Key            string
+	CreateRevision int64
+	ModRevision    int64
+	Value          []byte
+	Lease          int64
+}
+
+// Event is a change to a key.
+type Event struct {
+	Create bool
+	Delete bool
+	KV     *KeyValue
+	PrevKV *KeyValue
+}
+
+// Backend reads and writes keys in the database with the revision semantics of etcd v3. Its methods mirror the
+// Backend interface of kine's server package.
+type Backend struct {
+	db Database
+}
+
+// NewBackend returns a Backend storing keys in the given database.
+func NewBackend(db Database) *Backend {
+	return &Backend{db: db}
+}
+
+// Start prepares the backend for use.
+func (b *Backend) Start(ctx context.Context) error {
+	return nil
+}
+
+// CurrentRevision returns the revision of the most recent change.
+func (b *Backend) CurrentRevision(ctx context.Context) (int64, error) {
+	var revision int64
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		revision, err = currentRevision(ctx, tx)
+
+		return err
+	})
+
+	return revision, err
+}
+
+// Get returns the given key at the given revision, or at the current revision if it is 0.
+// The returned KeyValue is nil if the key does not exist.
+func (b *Backend) Get(ctx context.Context, key string, rangeEnd string, limit int64, revision int64) (int64, *KeyValue, error) {
+	var current int64
+	var kv *KeyValue
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		current, err = checkRevision(ctx, tx, revision)
+		if err != nil {
+			return err
+		}
+
+		if revision == 0 {
+			revision = current
+		}
+
+		row, err := latest(ctx, tx, key, revision)
+		if err != nil {
+			return err
+		}
+
+		kv = nil
+		if row != nil && !row.deleted {
+			kv = row.keyValue()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return current, kv, nil
+}
+
+// List returns up to limit keys with the given prefix, from startKey onwards, at the given revision or at the current
+// revision if it is 0. A prefix not ending with a slash matches that key only. If limit is 0, all keys are returned.
+func (b *Backend) List(ctx context.Context, prefix string, startKey string, limit int64, revision int64) (int64, []*KeyValue, error) {
+	var current int64
+	var kvs []*KeyValue
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		current, err = checkRevision(ctx, tx, revision)
+		if err != nil {
+			return err
+		}
+
+		if revision == 0 {
+			revision = current
+		}
+
+		rows, err := list(ctx, tx, prefix, startKey, limit, revision)
+		if err != nil {
+			return err
+		}
+
+		kvs = make([]*KeyValue, 0, len(rows))
+		for _, row := range rows {
+			kvs = append(kvs, row.keyValue())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return current, kvs, nil
+}
+
+// Count returns the number of keys with the given prefix, from startKey onwards, at the given revision or at the
+// current revision if it is 0.
+func (b *Backend) Count(ctx context.Context, prefix string, startKey string, revision int64) (int64, int64, error) {
+	current, kvs, err := b.List(ctx, prefix, startKey, 0, revision)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return current, int64(len(kvs)), nil
+}
+
+// Create adds the given key, and returns the revision of the change. If lease is set, the key is deleted once it has
+// not been updated for that many seconds.
+func (b *Backend) Create(ctx context.Context, key string, value []byte, lease int64) (int64, error) {
+	var revision int64
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		row, err := latest(ctx, tx, key, 0)
+		if err != nil {
+			return err
+		}
+
+		if row != nil && !row.deleted {
+			return ErrKeyExists
+		}
+
+		newRow := revisionRow{name: key, created: true, lease: lease, value: value}
+		if row != nil {
+			newRow.prevRevision = row.id
+			newRow.oldValue = row.value
+		}
+
+		revision, err = insert(ctx, tx, newRow)
+
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return revision, nil
+}
+
+// Update changes the value of the given key if it was last modified at the given revision. It returns the revision of
+// the change and the updated key, or the current revision, the current key and false if the key was not updated.
+func (b *Backend) Update(ctx context.Context, key string, value []byte, revision int64, lease int64) (int64, *KeyValue, bool, error) {
+	var current int64
+	var kv *KeyValue
+	var updated bool
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		kv = nil
+		updated = false
+
+		var err error
+		current, err = currentRevision(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		row, err := latest(ctx, tx, key, 0)
+		if err != nil {
+			return err
+		}
+
+		if row == nil || row.deleted {
+			return nil
+		}
+
+		if row.id != revision {
+			kv = row.keyValue()
+
+			return nil
+		}
+
+		newRow := revisionRow{
+			name:           key,
+			createRevision: row.createRevision,
+			prevRevision:   row.id,
+			lease:          lease,
+			value:          value,
+			oldValue:       row.value,
+		}
+
+		current, err = insert(ctx, tx, newRow)
+		if err != nil {
+			return err
+		}
+
+		newRow.id = current
+		kv = newRow.keyValue()
+		updated = true
+
+		return nil
+	})
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	return current, kv, updated, nil
+}
+
+// Delete removes the given key if it was last modified at the given revision, or regardless of its revision if it
+// is 0. It returns the revision of the change and the deleted key, or the current revision, the current key and
+// false if the key was not deleted.
+func (b *Backend) Delete(ctx context.Context, key string, revision int64) (int64, *KeyValue, bool, error) {
+	var current int64
+	var kv *KeyValue
+	var deleted bool
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		kv = nil
+		deleted = false
+
+		var err error
+		current, err = currentRevision(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		row, err := latest(ctx, tx, key, 0)
+		if err != nil {
+			return err
+		}
+
+		if row == nil || row.deleted {
+			deleted = true
+
+			return nil
+		}
+
+		kv = row.keyValue()
+		if revision != 0 && row.id != revision {
+			return nil
+		}
+
+		current, err = insert(ctx, tx, revisionRow{
+			name:           key,
+			deleted:        true,
+			createRevision: row.createRevision,
+			prevRevision:   row.id,
+			lease:          row.lease,
+			value:          row.value,
+			oldValue:       row.value,
+		})
+		if err != nil {
+			return err
+		}
+
+		deleted = true
+
+		return nil
+	})
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	return current, kv, deleted, nil
+}
+
+// Compact removes the history of keys up to the given revision, keeping the latest revision of each key that still
+// exists. It returns the current revision.
+func (b *Backend) Compact(ctx context.Context, revision int64) (int64, error) {
+	var current int64
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		current, err = currentRevision(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		if revision > current {
+			return ErrFutureRevision
+		}
+
+		compacted, err := compactRevision(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		if revision <= compacted {
+			return nil
+		}
+
+		// Remove the revisions superseded by a later revision, and deleted keys.
+		_, err = tx.ExecContext(ctx, `
+DELETE FROM core_kine WHERE name != ? AND id <= ? AND (deleted = 1 OR id IN (SELECT prev_revision FROM core_kine WHERE name != ? AND id <= ?))
+`, compactRevisionKey, revision, compactRevisionKey, revision)
+		if err != nil {
+			return fmt.Errorf("Failed to compact keys: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE core_kine SET prev_revision = ? WHERE name = ?", revision, compactRevisionKey)
+		if err != nil {
+			return fmt.Errorf("Failed to record compacted revision: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return current, nil
+}
+
+// DbSize returns the size of the database, in bytes.
+func (b *Backend) DbSize(ctx context.Context) (int64, error) {
+	var size int64
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&size)
+	})
+
+	return size, err
+}
+
+// ExpireLeases deletes the keys whose lease has expired.
+func (b *Backend) ExpireLeases(ctx context.Context) error {
+	var expired []*revisionRow
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		expired, err = expiredRows(ctx, tx, time.Now())
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range expired {
+		_, _, _, err := b.Delete(ctx, row.name, row.id)
+		if err != nil {
+			return fmt.Errorf("Failed to delete expired key %q: %w", row.name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package kine
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/internal/db/dbtest"
+)
+
+// testDB runs transactions against an in-memory sqlite database.
+type testDB struct {
+	db *sql.DB
+}
+
+func (t testDB) Transaction(ctx context.Context, f func(context.Context, *sql.Tx) error) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = f(ctx, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// newTestBackend returns a Backend over an in-memory sqlite database with the internal schema applied.
+func newTestBackend(t *testing.T) *Backend {
+	return NewBackend(testDB{db: dbtest.NewDB(t)})
+}
+
+// Ensures keys are created, updated and deleted with the revision semantics of etcd.
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBackend(t)
+
+	createRev, err := b.Create(ctx, "/registry/a", []byte("1"), 0)
+	require.NoError(t, err)
+
+	_, err = b.Create(ctx, "/registry/a", []byte("1"), 0)
+	require.ErrorIs(t, err, ErrKeyExists)
+
+	_, err = b.Create(ctx, "/registry/b", []byte("2"), 0)
+	require.NoError(t, err)
+
+	_, err = b.Create(ctx, "/other", []byte("3"), 0)
+	require.NoError(t, err)
+
+	// Updates only apply to the latest revision of the key.
+	_, kv, updated, err := b.Update(ctx, "/registry/a", []byte("1.1"), createRev-1, 0)
+	require.NoError(t, err)
+	require.False(t, updated)
+	require.Equal(t, []byte("1"), kv.Value)
+
+	updateRev, kv, updated, err := b.Update(ctx, "/registry/a", []byte("1.1"), createRev, 0)
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Equal(t, createRev, kv.CreateRevision)
+	require.Equal(t, updateRev, kv.ModRevision)
+
+	// Older revisions of the key can still be read.
+	_, kv, err = b.Get(ctx, "/registry/a", "", 0, createRev)
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), kv.Value)
+
+	_, kvs, err := b.List(ctx, "/registry/", "", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, kvs, 2)
+	require.Equal(t, "/registry/a", kvs[0].Key)
+	require.Equal(t, []byte("1.1"), kvs[0].Value)
+
+	deleteRev, _, deleted, err := b.Delete(ctx, "/registry/b", 0)
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	_, kv, err = b.Get(ctx, "/registry/b", "", 0, 0)
+	require.NoError(t, err)
+	require.Nil(t, kv)
+
+	_, count, err := b.Count(ctx, "/registry/", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	// Compacted revisions can no longer be read, but the latest revision of each key is kept.
+	_, err = b.Compact(ctx, deleteRev)
+	require.NoError(t, err)
+
+	_, _, err = b.Get(ctx, "/registry/a", "", 0, createRev)
+	require.ErrorIs(t, err, ErrCompacted)
+
+	_, kv, err = b.Get(ctx, "/registry/a", "", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte("1.1"), kv.Value)
+
+	_, _, err = b.Get(ctx, "/registry/a", "", 0, deleteRev+100)
+	require.ErrorIs(t, err, ErrFutureRevision)
+}
+
+// Ensures watches receive the changes of the watched keys in order.
+func TestBackendWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := newTestBackend(t)
+	result := b.Watch(ctx, "/registry/", 0)
+
+	createRev, err := b.Create(ctx, "/registry/a", []byte("1"), 0)
+	require.NoError(t, err)
+
+	_, err = b.Create(ctx, "/other", []byte("2"), 0)
+	require.NoError(t, err)
+
+	_, _, _, err = b.Update(ctx, "/registry/a", []byte("1.1"), createRev, 0)
+	require.NoError(t, err)
+
+	var events []*Event
+	for len(events) < 2 {
+		select {
+		case batch := <-result.Events:
+			events = append(events, batch...)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for watch events")
+		}
+	}
+
+	require.Len(t, events, 2)
+	require.True(t, events[0].Create)
+	require.Equal(t, []byte("1.1"), events[1].KV.Value)
+	require.Equal(t, []byte("1"), events[1].PrevKV.Value)
+}
@@ -0,0 +1,234 @@
+package kine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+)
+
+// rowColumns are the columns of the core_kine table, in the order they are scanned by scanRow.
+const rowColumns = "id, name, created, deleted, create_revision, prev_revision, lease, expiry, value, old_value"
+
+// revisionRow is a revision of a key in the core_kine table. Each change to a key adds a row, whose ID is the revision
+// of the change.
+type revisionRow struct {
+	id             int64
+	name           string
+	created        bool
+	deleted        bool
+	createRevision int64
+	prevRevision   int64
+	lease          int64
+	expiry         int64
+	value          []byte
+	oldValue       []byte
+}
+
+// keyValue returns the key at the revision of the row.
+func (r revisionRow) keyValue() *KeyValue {
+	return &KeyValue{
+		Key:            r.name,
+		CreateRevision: r.createRevision,
+		ModRevision:    r.id,
+		Value:          r.value,
+		Lease:          r.lease,
+	}
+}
+
+// event returns the change to the key made by the row.
+func (r revisionRow) event() *Event {
+	event := &Event{
+		Create: r.created,
+		Delete: r.deleted,
+		KV:     r.keyValue(),
+	}
+
+	if !r.created && r.prevRevision > 0 {
+		event.PrevKV = &KeyValue{
+			Key:            r.name,
+			CreateRevision: r.createRevision,
+			ModRevision:    r.prevRevision,
+			Value:          r.oldValue,
+			Lease:          r.lease,
+		}
+	}
+
+	return event
+}
+
+// scanRows runs the given query, and returns the rows of the core_kine table it selects.
+func scanRows(ctx context.Context, tx *sql.Tx, stmt string, args ...any) ([]*revisionRow, error) {
+	rows := []*revisionRow{}
+	dest := func(scan func(dest ...any) error) error {
+		r := revisionRow{}
+		err := scan(&r.id, &r.name, &r.created, &r.deleted, &r.createRevision, &r.prevRevision, &r.lease, &r.expiry, &r.value, &r.oldValue)
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, &r)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"core_kine\" table: %w", err)
+	}
+
+	return rows, nil
+}
+
+// keyFilter returns the condition matching the keys with the given prefix, along with its arguments.
+// A prefix ending with a slash matches every key starting with it, an empty prefix matches every key, and any other
+// prefix matches that key only.
+func keyFilter(prefix string) (string, []any) {
+	switch {
+	case prefix == "":
+		return "name != ?", []any{compactRevisionKey}
+	case strings.HasSuffix(prefix, "/"):
+		// The keys starting with the prefix sort between the prefix and the prefix with its last byte incremented.
+		end := prefix[:len(prefix)-1] + string(prefix[len(prefix)-1]+1)
+
+		return "name >= ? AND name < ?", []any{prefix, end}
+	default:
+		return "name = ?", []any{prefix}
+	}
+}
+
+// latest returns the most recent revision of the given key up to the given revision, or regardless of revision if it
+// is 0. It returns nil if the key has no revision.
+func latest(ctx context.Context, tx *sql.Tx, key string, revision int64) (*revisionRow, error) {
+	stmt := fmt.Sprintf("SELECT %s FROM core_kine WHERE name = ? AND (? = 0 OR id <= ?) ORDER BY id DESC LIMIT 1", rowColumns)
+	rows, err := scanRows(ctx, tx, stmt, key, revision, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return rows[0], nil
+}
+
+// list returns the most recent revision up to the given revision of up to limit existing keys with the given prefix,
+// from startKey onwards, ordered by key. If limit is 0, all keys are returned.
+func list(ctx context.Context, tx *sql.Tx, prefix string, startKey string, limit int64, revision int64) ([]*revisionRow, error) {
+	filter, args := keyFilter(prefix)
+	if startKey != "" {
+		filter += " AND name >= ?"
+		args = append(args, startKey)
+	}
+
+	args = append(args, revision)
+	stmt := fmt.Sprintf("SELECT %s FROM core_kine WHERE id IN (SELECT MAX(id) FROM core_kine WHERE %s AND id <= ? GROUP BY name) AND deleted = 0 ORDER BY name", rowColumns, filter)
+	if limit > 0 {
+		stmt += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	return scanRows(ctx, tx, stmt, args...)
+}
+
+// changes returns up to limit revisions of the keys with the given prefix made after the given revision, in order.
+func changes(ctx context.Context, tx *sql.Tx, prefix string, after int64, limit int64) ([]*revisionRow, error) {
+	filter, args := keyFilter(prefix)
+	args = append(args, compactRevisionKey, after, limit)
+	stmt := fmt.Sprintf("SELECT %s FROM core_kine WHERE %s AND name != ? AND id > ? ORDER BY id LIMIT ?", rowColumns, filter)
+
+	return scanRows(ctx, tx, stmt, args...)
+}
+
+// expiredRows returns the most recent revision of the existing keys whose lease expired by the given time.
+func expiredRows(ctx context.Context, tx *sql.Tx, now time.Time) ([]*revisionRow, error) {
+	stmt := fmt.Sprintf("SELECT %s FROM core_kine WHERE id IN (SELECT MAX(id) FROM core_kine WHERE name != ? GROUP BY name) AND deleted = 0 AND lease > 0 AND expiry <= ?", rowColumns)
+
+	return scanRows(ctx, tx, stmt, compactRevisionKey, now.Unix())
+}
+
+// insert adds the given revision of a key, and returns its revision.
+func insert(ctx context.Context, tx *sql.Tx, row revisionRow) (int64, error) {
+	if row.lease > 0 {
+		row.expiry = time.Now().Unix() + row.lease
+	}
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO core_kine (name, created, deleted, create_revision, prev_revision, lease, expiry, value, old_value) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		row.name, row.created, row.deleted, row.createRevision, row.prevRevision, row.lease, row.expiry, row.value, row.oldValue)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to create \"core_kine\" entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to fetch \"core_kine\" entry ID: %w", err)
+	}
+
+	// A key is created at the revision of its creation.
+	if row.created {
+		_, err = tx.ExecContext(ctx, "UPDATE core_kine SET create_revision = id WHERE id = ?", id)
+		if err != nil {
+			return 0, fmt.Errorf("Failed to update \"core_kine\" entry: %w", err)
+		}
+	}
+
+	return id, nil
+}
+
+// currentRevision returns the revision of the most recent change.
+func currentRevision(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var revision int64
+	err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM core_kine").Scan(&revision)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to fetch from \"core_kine\" table: %w", err)
+	}
+
+	return revision, nil
+}
+
+// compactRevision returns the revision up to which the history of keys was compacted.
+func compactRevision(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var revision int64
+	err := tx.QueryRowContext(ctx, "SELECT prev_revision FROM core_kine WHERE name = ?", compactRevisionKey).Scan(&revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to fetch from \"core_kine\" table: %w", err)
+	}
+
+	return revision, nil
+}
+
+// checkRevision returns the current revision, or an error if the given revision is compacted or in the future.
+func checkRevision(ctx context.Context, tx *sql.Tx, revision int64) (int64, error) {
+	current, err := currentRevision(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if revision == 0 {
+		return current, nil
+	}
+
+	if revision > current {
+		return 0, ErrFutureRevision
+	}
+
+	compacted, err := compactRevision(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if revision < compacted {
+		return 0, ErrCompacted
+	}
+
+	return current, nil
+}
@@ -0,0 +1,120 @@
+package kine
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	// watchPollInterval is how often watches check the database for new changes.
+	watchPollInterval = 250 * time.Millisecond
+
+	// watchBatch is the maximum number of changes sent to a watch at once.
+	watchBatch = 500
+)
+
+// WatchResult holds the changes of a watch.
+type WatchResult struct {
+	// CurrentRevision is the revision when the watch started.
+	CurrentRevision int64
+
+	// CompactRevision is set if the watch started from a compacted revision, in which case no events are sent.
+	CompactRevision int64
+
+	// Events receives the changes to the watched keys, in order. It is closed when the watch ends.
+	Events <-chan []*Event
+
+	// Errorc receives the error that ended the watch, if any.
+	Errorc <-chan error
+}
+
+// Watch sends the changes to the keys with the given prefix made from the given revision onwards, or from the current
+// revision if it is 0, until the context is cancelled. A prefix not ending with a slash watches that key only.
+func (b *Backend) Watch(ctx context.Context, prefix string, revision int64) WatchResult {
+	events := make(chan []*Event, 100)
+	errorc := make(chan error, 1)
+
+	var current int64
+	var compacted int64
+	err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		current, err = currentRevision(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		compacted, err = compactRevision(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		errorc <- err
+		close(events)
+
+		return WatchResult{Events: events, Errorc: errorc}
+	}
+
+	if revision > 0 && revision <= compacted {
+		close(events)
+
+		return WatchResult{CurrentRevision: current, CompactRevision: compacted, Events: events, Errorc: errorc}
+	}
+
+	after := current
+	if revision > 0 {
+		after = revision - 1
+	}
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+
+		for {
+			var rows []*revisionRow
+			err := b.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+				var err error
+				rows, err = changes(ctx, tx, prefix, after, watchBatch)
+
+				return err
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					errorc <- err
+				}
+
+				return
+			}
+
+			if len(rows) > 0 {
+				batch := make([]*Event, 0, len(rows))
+				for _, row := range rows {
+					batch = append(batch, row.event())
+				}
+
+				select {
+				case events <- batch:
+				case <-ctx.Done():
+					return
+				}
+
+				after = rows[len(rows)-1].id
+
+				// Send the remaining changes right away if the batch was full.
+				if len(rows) == watchBatch {
+					continue
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return WatchResult{CurrentRevision: current, Events: events, Errorc: errorc}
+}
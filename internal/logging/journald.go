@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// journalSocket is the socket of the systemd journal's native protocol.
+const journalSocket = "/run/systemd/journal/socket"
+
+// journalHook is a logrus hook sending log entries to the systemd journal.
+type journalHook struct {
+	conn       *net.UnixConn
+	identifier string
+	levels     []logrus.Level
+}
+
+// newJournalHook connects to the systemd journal, and returns a hook sending it the entries of the given levels.
+func newJournalHook(identifier string, levels []logrus.Level) (*journalHook, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to the systemd journal: %w", err)
+	}
+
+	return &journalHook{conn: conn, identifier: identifier, levels: levels}, nil
+}
+
+// Levels returns the levels of the entries sent to the journal.
+func (h *journalHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire sends the entry to the journal. Its context is appended to the message.
+func (h *journalHook) Fire(entry *logrus.Entry) error {
+	message := entry.Message
+	if len(entry.Data) > 0 {
+		keys := make([]string, 0, len(entry.Data))
+		for key := range entry.Data {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		fields := make([]string, 0, len(keys))
+		for _, key := range keys {
+			fields = append(fields, fmt.Sprintf("%s=%v", key, entry.Data[key]))
+		}
+
+		message = message + " " + strings.Join(fields, " ")
+	}
+
+	buf := &bytes.Buffer{}
+	writeJournalField(buf, "MESSAGE", message)
+	writeJournalField(buf, "PRIORITY", strconv.Itoa(journalPriority(entry.Level)))
+	writeJournalField(buf, "SYSLOG_IDENTIFIER", h.identifier)
+
+	_, err := h.conn.Write(buf.Bytes())
+
+	return err
+}
+
+// Close closes the connection to the journal.
+func (h *journalHook) Close() error {
+	return h.conn.Close()
+}
+
+// writeJournalField encodes a field in the journal's native protocol. Values spanning multiple lines are prefixed with
+// their length rather than terminated by a newline.
+func writeJournalField(buf *bytes.Buffer, name string, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+
+	buf.WriteString(name + "\n")
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
+
+// journalPriority returns the syslog priority of the log level.
+func journalPriority(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
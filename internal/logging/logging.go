@@ -0,0 +1,191 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"sync"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/termios"
+	"github.com/sirupsen/logrus"
+	lSyslog "github.com/sirupsen/logrus/hooks/syslog"
+	lWriter "github.com/sirupsen/logrus/hooks/writer"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+var (
+	mu         sync.Mutex
+	logFile    string
+	identifier string
+	current    *types.LogConfig
+	log        *logrus.Logger
+	logHandle  *os.File
+	journal    *journalHook
+)
+
+// Init sets up the daemon's logger with the given configuration. The log file is used by the file target, and the
+// identifier names the daemon in syslog and the systemd journal.
+// Init replaces the global logger, so it must be called before the logger is in use by other goroutines. The
+// configuration can be changed afterwards with Set.
+func Init(file string, name string, config types.LogConfig) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	newLog := logrus.New()
+	newLog.Level = logrus.TraceLevel
+	newLog.SetOutput(io.Discard)
+	newLog.Formatter = &logrus.TextFormatter{PadLevelText: true, FullTimestamp: true, ForceColors: termios.IsTerminal(int(os.Stderr.Fd()))}
+
+	prevLog, prevFile, prevIdentifier := log, logFile, identifier
+	log, logFile, identifier = newLog, file, name
+	err := apply(config)
+	if err != nil {
+		log, logFile, identifier = prevLog, prevFile, prevIdentifier
+		return err
+	}
+
+	logger.Log = &logWrapper{target: logrus.NewEntry(log)}
+
+	return nil
+}
+
+// Config returns the current logging configuration.
+func Config() (*types.LogConfig, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if current == nil {
+		return nil, fmt.Errorf("Logger is not initialized")
+	}
+
+	config := *current
+
+	return &config, nil
+}
+
+// Set changes the level and target of the logger. Unset fields keep their current value.
+// The logger itself is kept, so that it can be changed while in use.
+func Set(config types.LogConfig) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if current == nil {
+		return fmt.Errorf("Logger is not initialized")
+	}
+
+	if config.Level == "" {
+		config.Level = current.Level
+	}
+
+	if config.Target == "" {
+		config.Target = current.Target
+	}
+
+	return apply(config)
+}
+
+// apply replaces the hooks of the logger with those writing to the target of the given configuration, and records it
+// as the current one. The log file and the connection to the journal are kept open while they remain in use.
+func apply(config types.LogConfig) error {
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	if config.Level == "" || config.Target == "" {
+		return fmt.Errorf("Log level and target are required")
+	}
+
+	verbose := config.Level != types.LogLevelWarn
+	debug := config.Level == types.LogLevelDebug
+	logLevels := levels(verbose, debug)
+
+	writers := []io.Writer{os.Stderr}
+	hooks := logrus.LevelHooks{}
+	var newFile *os.File
+	var newJournal *journalHook
+	switch config.Target {
+	case types.LogTargetFile:
+		if logFile == "" {
+			return fmt.Errorf("No log file is configured")
+		}
+
+		newFile = logHandle
+		if newFile == nil || newFile.Name() != logFile {
+			newFile, err = os.OpenFile(logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+			if err != nil {
+				return fmt.Errorf("Failed to open log file: %w", err)
+			}
+		}
+
+		writers = append(writers, newFile)
+	case types.LogTargetSyslog:
+		syslogHook, err := lSyslog.NewSyslogHook("", "", syslog.LOG_INFO, identifier)
+		if err != nil {
+			return fmt.Errorf("Failed to connect to syslog: %w", err)
+		}
+
+		// As with the logger of LXD, debug messages are not sent to syslog.
+		hooks.Add(&levelHook{Hook: syslogHook, levels: levels(true, false)})
+	case types.LogTargetJournald:
+		if journal != nil && journal.identifier == identifier {
+			newJournal = &journalHook{conn: journal.conn, identifier: identifier, levels: logLevels}
+		} else {
+			newJournal, err = newJournalHook(identifier, logLevels)
+			if err != nil {
+				return err
+			}
+		}
+
+		hooks.Add(newJournal)
+	}
+
+	hooks.Add(&lWriter.Hook{Writer: io.MultiWriter(writers...), LogLevels: logLevels})
+
+	// Replacing the hooks is safe while the logger is in use.
+	log.ReplaceHooks(hooks)
+
+	// Release the log file and the connection to the journal if the new hooks no longer use them.
+	if logHandle != nil && logHandle != newFile {
+		_ = logHandle.Close()
+	}
+
+	if journal != nil && (newJournal == nil || journal.conn != newJournal.conn) {
+		_ = journal.Close()
+	}
+
+	logHandle = newFile
+	journal = newJournal
+	current = &config
+
+	return nil
+}
+
+// levelHook fires the hook for the given levels only.
+type levelHook struct {
+	logrus.Hook
+
+	levels []logrus.Level
+}
+
+// Levels returns the levels the hook fires for.
+func (h *levelHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// levels returns the log levels that are recorded at the given verbosity.
+func levels(verbose bool, debug bool) []logrus.Level {
+	levels := []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}
+	if debug {
+		return append(levels, logrus.InfoLevel, logrus.DebugLevel)
+	}
+
+	if verbose {
+		return append(levels, logrus.InfoLevel)
+	}
+
+	return levels
+}
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures the file target writes to the log file, and can't be used without one.
+func TestFileTarget(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "daemon.log")
+	err := Init(logFile, "test", types.LogConfig{Level: types.LogLevelInfo, Target: types.LogTargetFile})
+	require.NoError(t, err)
+
+	logger.Info("Written to the log file")
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "Written to the log file")
+
+	err = Init("", "test", types.LogConfig{Level: types.LogLevelInfo, Target: types.LogTargetFile})
+	require.Error(t, err)
+
+	err = Init("", "test", types.LogConfig{Level: types.LogLevelInfo, Target: types.LogTargetStderr})
+	require.NoError(t, err)
+}
+
+// Ensures changes of the level and target apply to the logger in use, and keep the fields that are not set.
+func TestSet(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "daemon.log")
+	err := Init(logFile, "test", types.LogConfig{Level: types.LogLevelWarn, Target: types.LogTargetStderr})
+	require.NoError(t, err)
+
+	contains := func(msg string) bool {
+		content, err := os.ReadFile(logFile)
+		if errors.Is(err, os.ErrNotExist) {
+			return false
+		}
+
+		require.NoError(t, err)
+
+		return strings.Contains(string(content), msg)
+	}
+
+	require.NoError(t, Set(types.LogConfig{Target: types.LogTargetFile}))
+	logger.Info("Info message at the warn level")
+	logger.Warn("Warn message at the warn level")
+	require.False(t, contains("Info message at the warn level"))
+	require.True(t, contains("Warn message at the warn level"))
+
+	require.NoError(t, Set(types.LogConfig{Level: types.LogLevelDebug}))
+	config, err := Config()
+	require.NoError(t, err)
+	require.Equal(t, types.LogConfig{Level: types.LogLevelDebug, Target: types.LogTargetFile}, *config)
+
+	logger.Debug("Debug message at the debug level")
+	require.True(t, contains("Debug message at the debug level"))
+
+	require.NoError(t, Set(types.LogConfig{Target: types.LogTargetStderr}))
+	logger.Warn("Warn message to stderr")
+	require.False(t, contains("Warn message to stderr"))
+
+	// Invalid configurations leave the logger unchanged.
+	require.Error(t, Set(types.LogConfig{Target: "unknown"}))
+	config, err = Config()
+	require.NoError(t, err)
+	require.Equal(t, types.LogConfig{Level: types.LogLevelDebug, Target: types.LogTargetStderr}, *config)
+}
+
+// Ensures the configuration can be changed while other goroutines are logging.
+func TestSetConcurrent(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "daemon.log")
+	err := Init(logFile, "test", types.LogConfig{Level: types.LogLevelWarn, Target: types.LogTargetStderr})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				logger.Debug("Concurrent message", logger.Ctx{"goroutine": i})
+			}
+		}()
+	}
+
+	targets := []types.LogTarget{types.LogTargetFile, types.LogTargetStderr}
+	for i := 0; i < 20; i++ {
+		require.NoError(t, Set(types.LogConfig{Level: types.LogLevelDebug, Target: targets[i%len(targets)]}))
+		require.NoError(t, Set(types.LogConfig{Level: types.LogLevelWarn}))
+	}
+
+	cancel()
+	wg.Wait()
+}
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// logWrapper implements logger.Logger on top of a logrus entry, as the logger of LXD does.
+type logWrapper struct {
+	target *logrus.Entry
+}
+
+// withContext returns the entry with all provided contexts applied.
+func (lw *logWrapper) withContext(ctx ...logger.Ctx) *logrus.Entry {
+	entry := lw.target
+	for _, c := range ctx {
+		entry = entry.WithFields(logrus.Fields(c))
+	}
+
+	return entry
+}
+
+// Panic logs a message at the panic level, and panics.
+func (lw *logWrapper) Panic(msg string, ctx ...logger.Ctx) {
+	lw.withContext(ctx...).Panic(msg)
+}
+
+// Fatal logs a message at the fatal level, and exits.
+func (lw *logWrapper) Fatal(msg string, ctx ...logger.Ctx) {
+	lw.withContext(ctx...).Fatal(msg)
+}
+
+// Error logs a message at the error level.
+func (lw *logWrapper) Error(msg string, ctx ...logger.Ctx) {
+	lw.withContext(ctx...).Error(msg)
+}
+
+// Warn logs a message at the warning level.
+func (lw *logWrapper) Warn(msg string, ctx ...logger.Ctx) {
+	lw.withContext(ctx...).Warn(msg)
+}
+
+// Info logs a message at the info level.
+func (lw *logWrapper) Info(msg string, ctx ...logger.Ctx) {
+	lw.withContext(ctx...).Info(msg)
+}
+
+// Debug logs a message at the debug level.
+func (lw *logWrapper) Debug(msg string, ctx ...logger.Ctx) {
+	lw.withContext(ctx...).Debug(msg)
+}
+
+// Trace logs a message at the trace level.
+func (lw *logWrapper) Trace(msg string, ctx ...logger.Ctx) {
+	lw.withContext(ctx...).Trace(msg)
+}
+
+// AddContext returns a logger adding the context to every message.
+func (lw *logWrapper) AddContext(ctx logger.Ctx) logger.Logger {
+	return &logWrapper{target: lw.withContext(ctx)}
+}
@@ -0,0 +1,283 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/google/uuid"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// DefaultRetention is how long finished operations can still be queried.
+const DefaultRetention = 5 * time.Minute
+
+// Func is the work of an operation. Its context is cancelled when the operation is cancelled or the daemon shuts down.
+// The returned value is recorded as the result of the operation if there is no error.
+type Func func(ctx context.Context, op *Operation) (any, error)
+
+// Operation is a long-running action run in the background by the Manager.
+type Operation struct {
+	manager *Manager
+	cancel  context.CancelFunc
+
+	// status and changed are guarded by the manager's lock. changed is closed and replaced on every update.
+	status  types.Operation
+	changed chan struct{}
+
+	// collected is closed once the final status of the operation has been returned to a caller.
+	collected chan struct{}
+}
+
+// ID returns the unique identifier of the operation.
+func (op *Operation) ID() string {
+	return op.status.ID
+}
+
+// SetProgress replaces the progress information reported by the operation, and notifies anyone waiting on it.
+func (op *Operation) SetProgress(progress map[string]any) {
+	op.manager.mu.Lock()
+	defer op.manager.mu.Unlock()
+
+	op.status.Progress = maps.Clone(progress)
+	op.updated()
+}
+
+// Status returns the current status of the operation.
+func (op *Operation) Status() types.Operation {
+	op.manager.mu.Lock()
+	defer op.manager.mu.Unlock()
+
+	return op.snapshot()
+}
+
+// snapshot returns a copy of the operation's status. The manager's lock must be held.
+func (op *Operation) snapshot() types.Operation {
+	status := op.status
+	status.Progress = maps.Clone(op.status.Progress)
+
+	return status
+}
+
+// Collected returns a channel that is closed once a caller has received the final status of the operation, either
+// by getting it or by watching the operation until it finished.
+func (op *Operation) Collected() <-chan struct{} {
+	return op.collected
+}
+
+// collect records that the final status of the operation was returned to a caller. The manager's lock must be held.
+func (op *Operation) collect() {
+	select {
+	case <-op.collected:
+	default:
+		close(op.collected)
+	}
+}
+
+// updated records the time of an update and wakes up the watchers. The manager's lock must be held.
+func (op *Operation) updated() {
+	op.status.UpdatedAt = time.Now()
+	close(op.changed)
+	op.changed = make(chan struct{})
+}
+
+// Manager runs the operations of the local cluster member, and keeps track of them until some time after they finish.
+type Manager struct {
+	ctx       context.Context
+	retention time.Duration
+
+	mu         sync.Mutex
+	operations map[string]*Operation
+}
+
+// NewManager returns a Manager whose operations are cancelled along with the given context.
+func NewManager(ctx context.Context) *Manager {
+	return &Manager{
+		ctx:        ctx,
+		retention:  DefaultRetention,
+		operations: map[string]*Operation{},
+	}
+}
+
+// Start runs the function in the background as a new operation.
+func (m *Manager) Start(description string, f Func) (*Operation, error) {
+	if f == nil {
+		return nil, fmt.Errorf("Operation %q has no function", description)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx.Err() != nil {
+		return nil, fmt.Errorf("Cannot start operation %q, the daemon is shutting down", description)
+	}
+
+	m.prune()
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	now := time.Now()
+	op := &Operation{
+		manager:   m,
+		cancel:    cancel,
+		changed:   make(chan struct{}),
+		collected: make(chan struct{}),
+		status: types.Operation{
+			ID:          uuid.New().String(),
+			Description: description,
+			Status:      types.OperationRunning,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		},
+	}
+
+	m.operations[op.status.ID] = op
+
+	go m.run(ctx, op, f)
+
+	return op, nil
+}
+
+// run runs the operation's function and records its outcome.
+func (m *Manager) run(ctx context.Context, op *Operation, f Func) {
+	defer op.cancel()
+
+	result, err := f(ctx, op)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case err == nil:
+		op.status.Status = types.OperationSuccess
+		op.status.Result = result
+	case ctx.Err() != nil:
+		op.status.Status = types.OperationCancelled
+		op.status.Err = err.Error()
+	default:
+		op.status.Status = types.OperationFailure
+		op.status.Err = err.Error()
+		logger.Warn("Operation failed", logger.Ctx{"id": op.status.ID, "description": op.status.Description, "error": err})
+	}
+
+	op.updated()
+}
+
+// get returns the operation with the given ID. The manager's lock must be held.
+func (m *Manager) get(id string) (*Operation, error) {
+	m.prune()
+
+	op, ok := m.operations[id]
+	if !ok {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Operation %q not found", id)
+	}
+
+	return op, nil
+}
+
+// Get returns the status of the operation with the given ID.
+func (m *Manager) Get(id string) (types.Operation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op, err := m.get(id)
+	if err != nil {
+		return types.Operation{}, err
+	}
+
+	status := op.snapshot()
+	if status.Done() {
+		op.collect()
+	}
+
+	return status, nil
+}
+
+// List returns the status of all running and recently finished operations, oldest first.
+func (m *Manager) List() []types.Operation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune()
+
+	statuses := make([]types.Operation, 0, len(m.operations))
+	for _, op := range m.operations {
+		statuses = append(statuses, op.snapshot())
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].CreatedAt.Before(statuses[j].CreatedAt) })
+
+	return statuses
+}
+
+// Cancel cancels the context of the running operation with the given ID. The operation is marked as cancelled
+// once its function returns.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op, err := m.get(id)
+	if err != nil {
+		return err
+	}
+
+	if op.status.Done() {
+		return api.StatusErrorf(http.StatusBadRequest, "Operation %q has already finished", id)
+	}
+
+	op.cancel()
+
+	return nil
+}
+
+// Watch calls fn with the status of the operation with the given ID, and again after every update until the
+// operation finishes or the context is cancelled.
+func (m *Manager) Watch(ctx context.Context, id string, fn func(status types.Operation) error) error {
+	m.mu.Lock()
+	op, err := m.get(id)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for {
+		m.mu.Lock()
+		status := op.snapshot()
+		changed := op.changed
+		m.mu.Unlock()
+
+		err := fn(status)
+		if err != nil {
+			return err
+		}
+
+		if status.Done() {
+			m.mu.Lock()
+			op.collect()
+			m.mu.Unlock()
+
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// prune forgets about operations that finished longer ago than the retention period. The manager's lock must be held.
+func (m *Manager) prune() {
+	for id, op := range m.operations {
+		if op.status.Done() && time.Since(op.status.UpdatedAt) > m.retention {
+			delete(m.operations, id)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+func TestManagerLifecycle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewManager(ctx)
+
+	proceed := make(chan struct{})
+	op, err := m.Start("succeed", func(ctx context.Context, op *Operation) (any, error) {
+		op.SetProgress(map[string]any{"step": 1})
+		<-proceed
+		return "done", nil
+	})
+	require.NoError(t, err)
+
+	var statuses []types.Operation
+	watched := make(chan error)
+	go func() {
+		watched <- m.Watch(ctx, op.ID(), func(status types.Operation) error {
+			statuses = append(statuses, status)
+			return nil
+		})
+	}()
+
+	require.Eventually(t, func() bool { return op.Status().Progress["step"] == 1 }, time.Second, time.Millisecond)
+	close(proceed)
+	require.NoError(t, <-watched)
+
+	last := statuses[len(statuses)-1]
+	assert.Equal(t, types.OperationSuccess, last.Status)
+	assert.Equal(t, "done", last.Result)
+
+	// Finished operations cannot be cancelled, but can still be queried until they are pruned.
+	assert.Error(t, m.Cancel(op.ID()))
+	assert.Len(t, m.List(), 1)
+
+	m.retention = 0
+	_, err = m.Get(op.ID())
+	assert.Error(t, err)
+	assert.Empty(t, m.List())
+}
+
+func TestManagerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewManager(ctx)
+
+	op, err := m.Start("block", func(ctx context.Context, op *Operation) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.NoError(t, err)
+
+	failed, err := m.Start("fail", func(ctx context.Context, op *Operation) (any, error) {
+		return nil, errors.New("failed")
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, m.Cancel(op.ID()))
+	require.Eventually(t, func() bool { return op.Status().Done() }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return failed.Status().Done() }, time.Second, time.Millisecond)
+
+	assert.Equal(t, types.OperationCancelled, op.Status().Status)
+	assert.Equal(t, types.OperationFailure, failed.Status().Status)
+	assert.Equal(t, "failed", failed.Status().Err)
+
+	// No operation can start once the daemon is shutting down.
+	cancel()
+	_, err = m.Start("late", func(ctx context.Context, op *Operation) (any, error) { return nil, nil })
+	assert.Error(t, err)
+}
+
+func TestOperationCollected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewManager(ctx)
+
+	proceed := make(chan struct{})
+	op, err := m.Start("collect", func(ctx context.Context, op *Operation) (any, error) {
+		<-proceed
+		return nil, errors.New("failed")
+	})
+	require.NoError(t, err)
+
+	// The status of a running operation is not its final status.
+	_, err = m.Get(op.ID())
+	require.NoError(t, err)
+	assert.Len(t, m.List(), 1)
+
+	close(proceed)
+	require.Eventually(t, func() bool { return op.Status().Done() }, time.Second, time.Millisecond)
+
+	// Neither is the status of an operation that was only listed.
+	m.List()
+	select {
+	case <-op.Collected():
+		t.Fatal("Operation was collected before its final status was returned")
+	default:
+	}
+
+	require.NoError(t, m.Watch(ctx, op.ID(), func(status types.Operation) error { return nil }))
+	select {
+	case <-op.Collected():
+	default:
+		t.Fatal("Operation was not collected once its final status was returned")
+	}
+
+	// Getting the final status again is fine.
+	_, err = m.Get(op.ID())
+	require.NoError(t, err)
+}
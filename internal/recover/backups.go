@@ -0,0 +1,135 @@
+package recover
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// BackupSchedule configures the periodic database backups taken by the daemon.
+type BackupSchedule struct {
+	// Interval is the delay between periodic backups. If it's 0, no periodic backups are taken.
+	Interval time.Duration
+
+	// Retention is the number of most recent database backups kept in the state directory, including those taken
+	// before schema rollbacks. Older backups are removed after each periodic backup. If it's 0, all backups are kept.
+	Retention int
+}
+
+// Validate checks that the interval and retention are not negative.
+func (s BackupSchedule) Validate() error {
+	if s.Interval < 0 {
+		return fmt.Errorf("Backup interval cannot be negative")
+	}
+
+	if s.Retention < 0 {
+		return fmt.Errorf("Backup retention cannot be negative")
+	}
+
+	return nil
+}
+
+// isDatabaseBackup returns whether the file name is that of a backup written by CreateDatabaseBackup.
+func isDatabaseBackup(name string) bool {
+	return strings.HasPrefix(name, "db_backup.") && strings.HasSuffix(name, ".tar.gz")
+}
+
+// ListDatabaseBackups returns the database backups in filesystem.StateDir, from oldest to newest.
+func ListDatabaseBackups(filesystem *sys.OS) ([]types.DatabaseBackup, error) {
+	entries, err := os.ReadDir(filesystem.StateDir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read state directory: %w", err)
+	}
+
+	backups := []types.DatabaseBackup{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isDatabaseBackup(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// The backup may have been removed since the directory was read.
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("Failed to inspect database backup %q: %w", entry.Name(), err)
+		}
+
+		backups = append(backups, types.DatabaseBackup{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			return backups[i].Name < backups[j].Name
+		}
+
+		return backups[i].CreatedAt.Before(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// DatabaseBackupPath returns the path of the database backup with the given name in filesystem.StateDir.
+// A not found error is returned if there is no such backup.
+func DatabaseBackupPath(filesystem *sys.OS, name string) (string, error) {
+	if filepath.Base(name) != name || !isDatabaseBackup(name) {
+		return "", api.StatusErrorf(http.StatusBadRequest, "Invalid database backup name %q", name)
+	}
+
+	backupPath := filepath.Join(filesystem.StateDir, name)
+	_, err := os.Stat(backupPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", api.StatusErrorf(http.StatusNotFound, "Database backup %q not found", name)
+		}
+
+		return "", err
+	}
+
+	return backupPath, nil
+}
+
+// PruneDatabaseBackups removes the oldest database backups in filesystem.StateDir, so that only the given number of
+// backups remain. If retention is 0, all backups are kept.
+func PruneDatabaseBackups(filesystem *sys.OS, retention int) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	backups, err := ListDatabaseBackups(filesystem)
+	if err != nil {
+		return err
+	}
+
+	if len(backups) <= retention {
+		return nil
+	}
+
+	for _, backup := range backups[:len(backups)-retention] {
+		logger.Info("Removing database backup", logger.Ctx{"archive": backup.Name})
+
+		err := os.Remove(filepath.Join(filesystem.StateDir, backup.Name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("Failed to remove database backup %q: %w", backup.Name, err)
+		}
+	}
+
+	return nil
+}
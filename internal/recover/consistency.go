@@ -0,0 +1,277 @@
+package recover
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/canonical/go-dqlite"
+	"github.com/canonical/lxd/shared"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/trust"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// ClusterState is the cluster membership against which the local state of a cluster member is checked.
+type ClusterState struct {
+	// Members are the cluster members recorded in the core_cluster_members table, including pending ones.
+	Members []types.ClusterMember
+
+	// Nodes are the members of the dqlite raft configuration, as reported by the dqlite leader.
+	Nodes []dqlite.NodeInfo
+}
+
+// CheckLocalState cross-checks the trust store, and the go-dqlite cluster.yaml and info.yaml files of the local
+// cluster member against the given cluster state, and reports any mismatch.
+// If repair is true, the trust store and cluster.yaml are rewritten from the cluster state. Since info.yaml is only
+// read by dqlite on startup, and mismatches in the database require members to be added or removed, those are
+// reported but never repaired.
+func CheckLocalState(filesystem *sys.OS, remotes *trust.Remotes, localAddress string, clusterState ClusterState, repair bool) ([]types.ConsistencyIssue, error) {
+	names := make(map[string]string, len(clusterState.Members))
+	for _, member := range clusterState.Members {
+		names[member.Address.String()] = member.Name
+	}
+
+	issues := checkDatabase(clusterState.Members, clusterState.Nodes)
+
+	trustIssues := checkTrustStore(remotes.RemotesByName(), clusterState.Members)
+	if repair && len(trustIssues) > 0 {
+		err := remotes.Replace(clusterState.Members...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to repair trust store: %w", err)
+		}
+
+		for i := range trustIssues {
+			trustIssues[i].Repaired = true
+		}
+	}
+
+	issues = append(issues, trustIssues...)
+
+	clusterYamlPath := path.Join(filesystem.DatabaseDir, "cluster.yaml")
+
+	var storedNodes []dqlite.NodeInfo
+	err := readYaml(clusterYamlPath, &storedNodes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read dqlite cluster configuration: %w", err)
+	}
+
+	clusterIssues := checkDqliteCluster(storedNodes, clusterState.Nodes, names)
+	if repair && len(clusterIssues) > 0 {
+		err := writeYaml(clusterYamlPath, &clusterState.Nodes)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to repair dqlite cluster configuration: %w", err)
+		}
+
+		for i := range clusterIssues {
+			clusterIssues[i].Repaired = true
+		}
+	}
+
+	issues = append(issues, clusterIssues...)
+
+	var localInfo dqlite.NodeInfo
+	err = readYaml(path.Join(filesystem.DatabaseDir, "info.yaml"), &localInfo)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read local dqlite member information: %w", err)
+	}
+
+	issues = append(issues, checkDqliteInfo(localInfo, clusterState.Nodes, localAddress, names)...)
+
+	return issues, nil
+}
+
+// checkDatabase reports cluster members missing from the dqlite raft configuration, and dqlite members missing from
+// the core_cluster_members table. Pending cluster members are not yet expected to be dqlite members.
+func checkDatabase(members []types.ClusterMember, nodes []dqlite.NodeInfo) []types.ConsistencyIssue {
+	issues := []types.ConsistencyIssue{}
+	nodesByAddress := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		nodesByAddress[node.Address] = true
+	}
+
+	membersByAddress := make(map[string]bool, len(members))
+	for _, member := range members {
+		address := member.Address.String()
+		membersByAddress[address] = true
+		if member.Role == string(cluster.Pending) || nodesByAddress[address] {
+			continue
+		}
+
+		issues = append(issues, types.ConsistencyIssue{
+			Source:      types.ConsistencyDatabase,
+			Member:      member.Name,
+			Description: "Cluster member is not a dqlite member",
+			Found:       address,
+		})
+	}
+
+	for _, node := range nodes {
+		if membersByAddress[node.Address] {
+			continue
+		}
+
+		issues = append(issues, types.ConsistencyIssue{
+			Source:      types.ConsistencyDatabase,
+			Description: fmt.Sprintf("Dqlite member with ID %d is not a cluster member", node.ID),
+			Expected:    node.Address,
+		})
+	}
+
+	return issues
+}
+
+// checkTrustStore reports cluster members missing from the trust store or recorded with a stale address or
+// certificate, and remotes in the trust store that are not cluster members.
+func checkTrustStore(remotes map[string]trust.Remote, members []types.ClusterMember) []types.ConsistencyIssue {
+	issues := []types.ConsistencyIssue{}
+	membersByName := make(map[string]bool, len(members))
+	for _, member := range members {
+		membersByName[member.Name] = true
+		remote, ok := remotes[member.Name]
+		if !ok {
+			issues = append(issues, types.ConsistencyIssue{
+				Source:      types.ConsistencyTrustStore,
+				Member:      member.Name,
+				Description: "Cluster member is missing from the trust store",
+				Expected:    member.Address.String(),
+			})
+
+			continue
+		}
+
+		if remote.Address != member.Address {
+			issues = append(issues, types.ConsistencyIssue{
+				Source:      types.ConsistencyTrustStore,
+				Member:      member.Name,
+				Description: "Trust store has a stale address for the cluster member",
+				Expected:    member.Address.String(),
+				Found:       remote.Address.String(),
+			})
+		}
+
+		if remote.Certificate.Certificate != nil && member.Certificate.Certificate != nil && !remote.Certificate.Equal(member.Certificate.Certificate) {
+			issues = append(issues, types.ConsistencyIssue{
+				Source:      types.ConsistencyTrustStore,
+				Member:      member.Name,
+				Description: "Trust store has a stale certificate for the cluster member",
+				Expected:    shared.CertFingerprint(member.Certificate.Certificate),
+				Found:       shared.CertFingerprint(remote.Certificate.Certificate),
+			})
+		}
+	}
+
+	orphaned := []string{}
+	for name := range remotes {
+		if !membersByName[name] {
+			orphaned = append(orphaned, name)
+		}
+	}
+
+	sort.Strings(orphaned)
+	for _, name := range orphaned {
+		issues = append(issues, types.ConsistencyIssue{
+			Source:      types.ConsistencyTrustStore,
+			Member:      name,
+			Description: "Trust store has a remote that is not a cluster member",
+			Found:       remotes[name].Address.String(),
+		})
+	}
+
+	return issues
+}
+
+// checkDqliteCluster reports differences between the dqlite members recorded in cluster.yaml and the dqlite raft
+// configuration.
+func checkDqliteCluster(storedNodes []dqlite.NodeInfo, nodes []dqlite.NodeInfo, names map[string]string) []types.ConsistencyIssue {
+	issues := []types.ConsistencyIssue{}
+	storedByID := make(map[uint64]dqlite.NodeInfo, len(storedNodes))
+	for _, node := range storedNodes {
+		storedByID[node.ID] = node
+	}
+
+	nodesByID := make(map[uint64]bool, len(nodes))
+	for _, node := range nodes {
+		nodesByID[node.ID] = true
+		stored, ok := storedByID[node.ID]
+		if !ok {
+			issues = append(issues, types.ConsistencyIssue{
+				Source:      types.ConsistencyDqliteCluster,
+				Member:      names[node.Address],
+				Description: fmt.Sprintf("Dqlite member with ID %d is missing", node.ID),
+				Expected:    node.Address,
+			})
+
+			continue
+		}
+
+		if stored.Address != node.Address {
+			issues = append(issues, types.ConsistencyIssue{
+				Source:      types.ConsistencyDqliteCluster,
+				Member:      names[node.Address],
+				Description: fmt.Sprintf("Dqlite member with ID %d has a stale address", node.ID),
+				Expected:    node.Address,
+				Found:       stored.Address,
+			})
+		}
+	}
+
+	for _, node := range storedNodes {
+		if nodesByID[node.ID] {
+			continue
+		}
+
+		issues = append(issues, types.ConsistencyIssue{
+			Source:      types.ConsistencyDqliteCluster,
+			Member:      names[node.Address],
+			Description: fmt.Sprintf("Dqlite member with ID %d is not part of the raft configuration", node.ID),
+			Found:       node.Address,
+		})
+	}
+
+	return issues
+}
+
+// checkDqliteInfo reports whether the local dqlite member recorded in info.yaml is missing from the dqlite raft
+// configuration, or has a different address than the one the raft configuration and the daemon use.
+func checkDqliteInfo(localInfo dqlite.NodeInfo, nodes []dqlite.NodeInfo, localAddress string, names map[string]string) []types.ConsistencyIssue {
+	issues := []types.ConsistencyIssue{}
+	if localInfo.Address != localAddress {
+		issues = append(issues, types.ConsistencyIssue{
+			Source:      types.ConsistencyDqliteInfo,
+			Member:      names[localAddress],
+			Description: "Local dqlite member address does not match the daemon address",
+			Expected:    localAddress,
+			Found:       localInfo.Address,
+		})
+	}
+
+	for _, node := range nodes {
+		if node.ID != localInfo.ID {
+			continue
+		}
+
+		if node.Address != localInfo.Address {
+			issues = append(issues, types.ConsistencyIssue{
+				Source:      types.ConsistencyDqliteInfo,
+				Member:      names[localAddress],
+				Description: fmt.Sprintf("Local dqlite member with ID %d has a different address in the raft configuration", node.ID),
+				Expected:    node.Address,
+				Found:       localInfo.Address,
+			})
+		}
+
+		return issues
+	}
+
+	issues = append(issues, types.ConsistencyIssue{
+		Source:      types.ConsistencyDqliteInfo,
+		Member:      names[localAddress],
+		Description: fmt.Sprintf("Local dqlite member with ID %d is not part of the raft configuration", localInfo.ID),
+		Found:       localInfo.Address,
+	})
+
+	return issues
+}
@@ -0,0 +1,333 @@
+package recover
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/canonical/go-dqlite"
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/trust"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// newTestMember returns a cluster member with the given name and address, and the testing certificate.
+func newTestMember(t *testing.T, name string, address string, role string) types.ClusterMember {
+	addrPort, err := types.ParseAddrPort(address)
+	require.NoError(t, err)
+
+	cert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	return types.ClusterMember{
+		ClusterMemberLocal: types.ClusterMemberLocal{Name: name, Address: addrPort, Certificate: types.X509Certificate{Certificate: cert}},
+		Role:               role,
+	}
+}
+
+// Ensures cluster members and dqlite members are reported unless they are both, pending members aside.
+func TestCheckDatabase(t *testing.T) {
+	members := []types.ClusterMember{
+		newTestMember(t, "m1", "10.0.0.1:9000", "voter"),
+		newTestMember(t, "m2", "10.0.0.2:9000", "spare"),
+		newTestMember(t, "m3", "10.0.0.3:9000", string(cluster.Pending)),
+	}
+
+	nodes := []dqlite.NodeInfo{{ID: 1, Address: "10.0.0.1:9000"}, {ID: 2, Address: "10.0.0.2:9000"}}
+
+	cases := []struct {
+		name     string
+		members  []types.ClusterMember
+		nodes    []dqlite.NodeInfo
+		expected []types.ConsistencyIssue
+	}{
+		{
+			name:     "Consistent",
+			members:  members,
+			nodes:    nodes,
+			expected: []types.ConsistencyIssue{},
+		},
+		{
+			name:    "Missing dqlite member",
+			members: members,
+			nodes:   nodes[:1],
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyDatabase, Member: "m2", Description: "Cluster member is not a dqlite member", Found: "10.0.0.2:9000"},
+			},
+		},
+		{
+			name:    "Extra dqlite member",
+			members: members,
+			nodes:   append(nodes, dqlite.NodeInfo{ID: 4, Address: "10.0.0.4:9000"}),
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyDatabase, Description: "Dqlite member with ID 4 is not a cluster member", Expected: "10.0.0.4:9000"},
+			},
+		},
+		{
+			name:    "Address mismatch",
+			members: members,
+			nodes:   []dqlite.NodeInfo{nodes[0], {ID: 2, Address: "10.0.1.2:9000"}},
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyDatabase, Member: "m2", Description: "Cluster member is not a dqlite member", Found: "10.0.0.2:9000"},
+				{Source: types.ConsistencyDatabase, Description: "Dqlite member with ID 2 is not a cluster member", Expected: "10.0.1.2:9000"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, checkDatabase(c.members, c.nodes))
+		})
+	}
+}
+
+// Ensures the trust store is reported if it misses a cluster member, has an extra remote, or has a stale address or
+// certificate for a cluster member.
+func TestCheckTrustStore(t *testing.T) {
+	members := []types.ClusterMember{
+		newTestMember(t, "m1", "10.0.0.1:9000", "voter"),
+		newTestMember(t, "m2", "10.0.0.2:9000", "voter"),
+	}
+
+	remotes := func(members ...types.ClusterMember) map[string]trust.Remote {
+		remotes := make(map[string]trust.Remote, len(members))
+		for _, member := range members {
+			remotes[member.Name] = trust.Remote{
+				Location:    trust.Location{Name: member.Name, Address: member.Address},
+				Certificate: member.Certificate,
+			}
+		}
+
+		return remotes
+	}
+
+	altCert, err := shared.TestingAltKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	moved := newTestMember(t, "m2", "10.0.1.2:9000", "voter")
+	renewed := newTestMember(t, "m2", "10.0.0.2:9000", "voter")
+	renewed.Certificate = types.X509Certificate{Certificate: altCert}
+
+	cases := []struct {
+		name     string
+		remotes  map[string]trust.Remote
+		expected []types.ConsistencyIssue
+	}{
+		{
+			name:     "Consistent",
+			remotes:  remotes(members...),
+			expected: []types.ConsistencyIssue{},
+		},
+		{
+			name:    "Missing remote",
+			remotes: remotes(members[0]),
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyTrustStore, Member: "m2", Description: "Cluster member is missing from the trust store", Expected: "10.0.0.2:9000"},
+			},
+		},
+		{
+			name:    "Extra remote",
+			remotes: remotes(append(members, newTestMember(t, "m3", "10.0.0.3:9000", "voter"))...),
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyTrustStore, Member: "m3", Description: "Trust store has a remote that is not a cluster member", Found: "10.0.0.3:9000"},
+			},
+		},
+		{
+			name:    "Address mismatch",
+			remotes: remotes(members[0], moved),
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyTrustStore, Member: "m2", Description: "Trust store has a stale address for the cluster member", Expected: "10.0.0.2:9000", Found: "10.0.1.2:9000"},
+			},
+		},
+		{
+			name:    "Certificate mismatch",
+			remotes: remotes(members[0], renewed),
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyTrustStore, Member: "m2", Description: "Trust store has a stale certificate for the cluster member", Expected: shared.CertFingerprint(members[1].Certificate.Certificate), Found: shared.CertFingerprint(altCert)},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, checkTrustStore(c.remotes, members))
+		})
+	}
+}
+
+// Ensures cluster.yaml is reported if it misses a dqlite member, has an extra one, or has a stale address for one.
+func TestCheckDqliteCluster(t *testing.T) {
+	nodes := []dqlite.NodeInfo{{ID: 1, Address: "10.0.0.1:9000"}, {ID: 2, Address: "10.0.0.2:9000"}}
+	names := map[string]string{"10.0.0.1:9000": "m1", "10.0.0.2:9000": "m2"}
+
+	cases := []struct {
+		name        string
+		storedNodes []dqlite.NodeInfo
+		expected    []types.ConsistencyIssue
+	}{
+		{
+			name:        "Consistent",
+			storedNodes: nodes,
+			expected:    []types.ConsistencyIssue{},
+		},
+		{
+			name:        "Missing dqlite member",
+			storedNodes: nodes[:1],
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyDqliteCluster, Member: "m2", Description: "Dqlite member with ID 2 is missing", Expected: "10.0.0.2:9000"},
+			},
+		},
+		{
+			name:        "Extra dqlite member",
+			storedNodes: append(nodes, dqlite.NodeInfo{ID: 3, Address: "10.0.0.3:9000"}),
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyDqliteCluster, Description: "Dqlite member with ID 3 is not part of the raft configuration", Found: "10.0.0.3:9000"},
+			},
+		},
+		{
+			name:        "Address mismatch",
+			storedNodes: []dqlite.NodeInfo{nodes[0], {ID: 2, Address: "10.0.1.2:9000"}},
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyDqliteCluster, Member: "m2", Description: "Dqlite member with ID 2 has a stale address", Expected: "10.0.0.2:9000", Found: "10.0.1.2:9000"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, checkDqliteCluster(c.storedNodes, nodes, names))
+		})
+	}
+}
+
+// Ensures info.yaml is reported if the local dqlite member is missing from the raft configuration, or if its address
+// differs from the daemon address or the raft configuration.
+func TestCheckDqliteInfo(t *testing.T) {
+	nodes := []dqlite.NodeInfo{{ID: 1, Address: "10.0.0.1:9000"}, {ID: 2, Address: "10.0.0.2:9000"}}
+	names := map[string]string{"10.0.0.1:9000": "m1", "10.0.0.2:9000": "m2"}
+
+	cases := []struct {
+		name         string
+		localInfo    dqlite.NodeInfo
+		localAddress string
+		expected     []types.ConsistencyIssue
+	}{
+		{
+			name:         "Consistent",
+			localInfo:    nodes[1],
+			localAddress: "10.0.0.2:9000",
+			expected:     []types.ConsistencyIssue{},
+		},
+		{
+			name:         "Missing from the raft configuration",
+			localInfo:    dqlite.NodeInfo{ID: 3, Address: "10.0.0.2:9000"},
+			localAddress: "10.0.0.2:9000",
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyDqliteInfo, Member: "m2", Description: "Local dqlite member with ID 3 is not part of the raft configuration", Found: "10.0.0.2:9000"},
+			},
+		},
+		{
+			name:         "Address mismatch with the daemon",
+			localInfo:    nodes[1],
+			localAddress: "10.0.1.2:9000",
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyDqliteInfo, Description: "Local dqlite member address does not match the daemon address", Expected: "10.0.1.2:9000", Found: "10.0.0.2:9000"},
+			},
+		},
+		{
+			name:         "Address mismatch with the raft configuration",
+			localInfo:    dqlite.NodeInfo{ID: 2, Address: "10.0.1.2:9000"},
+			localAddress: "10.0.1.2:9000",
+			expected: []types.ConsistencyIssue{
+				{Source: types.ConsistencyDqliteInfo, Description: "Local dqlite member with ID 2 has a different address in the raft configuration", Expected: "10.0.0.2:9000", Found: "10.0.1.2:9000"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, checkDqliteInfo(c.localInfo, nodes, c.localAddress, names))
+		})
+	}
+}
+
+// Ensures repairing the local state rewrites the trust store and cluster.yaml from the cluster state, and leaves an
+// already consistent state untouched.
+func TestCheckLocalState(t *testing.T) {
+	clusterState := ClusterState{
+		Members: []types.ClusterMember{
+			newTestMember(t, "m1", "10.0.0.1:9000", "voter"),
+			newTestMember(t, "m2", "10.0.0.2:9000", "voter"),
+		},
+		Nodes: []dqlite.NodeInfo{{ID: 1, Address: "10.0.0.1:9000"}, {ID: 2, Address: "10.0.0.2:9000"}},
+	}
+
+	// readState returns the contents of every file in the trust store and database directories.
+	readState := func(t *testing.T, filesystem *sys.OS) map[string]string {
+		contents := map[string]string{}
+		for _, dir := range []string{filesystem.TrustDir, filesystem.DatabaseDir} {
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+
+			for _, entry := range entries {
+				content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+				require.NoError(t, err)
+
+				contents[filepath.Join(filepath.Base(dir), entry.Name())] = string(content)
+			}
+		}
+
+		return contents
+	}
+
+	cases := []struct {
+		name           string
+		remotes        []types.ClusterMember
+		storedNodes    []dqlite.NodeInfo
+		expectedIssues int
+	}{
+		{
+			name:        "Consistent",
+			remotes:     clusterState.Members,
+			storedNodes: clusterState.Nodes,
+		},
+		{
+			name:           "Inconsistent",
+			remotes:        []types.ClusterMember{clusterState.Members[0], newTestMember(t, "m3", "10.0.0.3:9000", "voter")},
+			storedNodes:    []dqlite.NodeInfo{{ID: 1, Address: "10.0.0.1:9000"}, {ID: 2, Address: "10.0.1.2:9000"}},
+			expectedIssues: 3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			filesystem := &sys.OS{TrustDir: t.TempDir(), DatabaseDir: t.TempDir()}
+			remotes := trust.NewRemotes(trust.NewFileBackend(filesystem.TrustDir, nil))
+			require.NoError(t, remotes.Replace(c.remotes...))
+			require.NoError(t, writeYaml(filepath.Join(filesystem.DatabaseDir, "cluster.yaml"), c.storedNodes))
+			require.NoError(t, writeYaml(filepath.Join(filesystem.DatabaseDir, "info.yaml"), clusterState.Nodes[0]))
+
+			before := readState(t, filesystem)
+
+			issues, err := CheckLocalState(filesystem, remotes, "10.0.0.1:9000", clusterState, true)
+			require.NoError(t, err)
+			require.Len(t, issues, c.expectedIssues)
+			for _, issue := range issues {
+				require.True(t, issue.Repaired)
+			}
+
+			if c.expectedIssues == 0 {
+				require.Equal(t, before, readState(t, filesystem))
+			}
+
+			// The repaired state is consistent.
+			issues, err = CheckLocalState(filesystem, remotes, "10.0.0.1:9000", clusterState, false)
+			require.NoError(t, err)
+			require.Empty(t, issues)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package recover
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/canonical/go-dqlite"
+	"gopkg.in/yaml.v3"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/sys"
+)
+
+// recoveryYamlComment is prepended to the dqlite cluster members rendered for editing.
+const recoveryYamlComment = `# Member roles can be modified. Unrecoverable nodes should be given the role "spare".
+#
+# "voter" - Voting member of the database. A majority of voters is a quorum.
+# "stand-by" - Non-voting member of the database; can be promoted to voter.
+# "spare" - Not a member of the database.
+#
+# The edit is aborted if:
+# - the number of members changes
+# - the name of any member changes
+# - the ID of any member changes
+# - this member is no longer a voter
+# - no changes are made
+`
+
+// RenderMembersYaml renders the dqlite cluster members as commented YAML for editing, ordered by dqlite ID.
+func RenderMembersYaml(members []cluster.DqliteMember) ([]byte, error) {
+	sorted := make([]cluster.DqliteMember, len(members))
+	copy(sorted, members)
+	sort.Slice(sorted, func(i int, j int) bool { return sorted[i].DqliteID < sorted[j].DqliteID })
+
+	membersYaml, err := yaml.Marshal(sorted)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(recoveryYamlComment), membersYaml...), nil
+}
+
+// ParseMembersYaml parses dqlite cluster members edited from the output of RenderMembersYaml, and validates them
+// against the current members with ValidateMemberChanges. The local member, which holds the most up-to-date raft
+// log, must remain a voter so that the recovered cluster can reach quorum.
+func ParseMembersYaml(filesystem *sys.OS, oldMembers []cluster.DqliteMember, content []byte) ([]cluster.DqliteMember, error) {
+	newMembers := []cluster.DqliteMember{}
+	err := yaml.Unmarshal(content, &newMembers)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse cluster members: %w", err)
+	}
+
+	err = ValidateMemberChanges(oldMembers, newMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	oldByID := make(map[uint64]cluster.DqliteMember, len(oldMembers))
+	for _, member := range oldMembers {
+		oldByID[member.DqliteID] = member
+	}
+
+	changed := false
+	for _, member := range newMembers {
+		if oldByID[member.DqliteID] != member {
+			changed = true
+			break
+		}
+	}
+
+	if !changed {
+		return nil, fmt.Errorf("No changes made to the cluster members")
+	}
+
+	var localInfo dqlite.NodeInfo
+	err = readYaml(path.Join(filesystem.DatabaseDir, "info.yaml"), &localInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range newMembers {
+		if member.DqliteID == localInfo.ID && member.Role != "voter" {
+			return nil, fmt.Errorf("Local member %q must remain a voter", member.Name)
+		}
+	}
+
+	return newMembers, nil
+}
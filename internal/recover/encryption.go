@@ -0,0 +1,296 @@
+package recover
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/canonical/microcluster/v3/internal/sys"
+)
+
+// ArchiveEncryption configures the encryption of database backups and recovery tarballs.
+// The zero value leaves archives unencrypted.
+type ArchiveEncryption struct {
+	// Passphrase derives the archive key from the given passphrase.
+	// The same passphrase must be supplied to every member that loads a recovery tarball.
+	Passphrase string
+
+	// ClusterKey derives the archive key from the private key of the cluster certificate, which is shared by all
+	// cluster members. It is ignored if a Passphrase is set.
+	ClusterKey bool
+}
+
+// enabled returns whether archives should be encrypted.
+func (e ArchiveEncryption) enabled() bool {
+	return e.Passphrase != "" || e.ClusterKey
+}
+
+// archiveMagic prefixes encrypted archives so that they can be told apart from plain gzip tarballs.
+var archiveMagic = []byte("MCENC\x00\x01\n")
+
+const (
+	// Sources of the secret that the archive key is derived from.
+	archiveKeyPassphrase byte = 1
+	archiveKeyCluster    byte = 2
+
+	archiveSaltSize        = 16
+	archiveNoncePrefixSize = 4
+
+	// archiveChunkSize is the amount of plaintext sealed in each chunk of the archive.
+	archiveChunkSize = 64 * 1024
+
+	// Chunk flags, which are authenticated so that a truncated archive is detected.
+	archiveChunkMore  byte = 0
+	archiveChunkFinal byte = 1
+)
+
+// archiveSecret returns the secret that the archive key is derived from.
+func archiveSecret(filesystem *sys.OS, source byte, passphrase string) ([]byte, error) {
+	switch source {
+	case archiveKeyPassphrase:
+		if passphrase == "" {
+			return nil, fmt.Errorf("Archive is encrypted with a passphrase but none was provided")
+		}
+
+		return []byte(passphrase), nil
+	case archiveKeyCluster:
+		clusterCert, err := filesystem.ClusterCert()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load cluster certificate for archive encryption: %w", err)
+		}
+
+		return clusterCert.PrivateKey(), nil
+	default:
+		return nil, fmt.Errorf("Unknown archive key source %d", source)
+	}
+}
+
+// newArchiveAEAD derives an AES-256-GCM cipher from the secret and salt.
+func newArchiveAEAD(secret []byte, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(secret, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive archive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// archiveNonce returns the nonce of the chunk with the given index.
+func archiveNonce(aead cipher.AEAD, prefix []byte, counter uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], counter)
+
+	return nonce
+}
+
+// archiveWriter seals everything written to it in fixed-size chunks.
+type archiveWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint64
+	buf     []byte
+}
+
+// newArchiveWriter returns a writer that encrypts everything written to w, or w itself if encryption is disabled.
+// The returned writer must be closed to write the final chunk, which does not close w.
+func newArchiveWriter(w io.Writer, filesystem *sys.OS, encryption ArchiveEncryption) (io.WriteCloser, error) {
+	if !encryption.enabled() {
+		return nopWriteCloser{Writer: w}, nil
+	}
+
+	source := archiveKeyCluster
+	if encryption.Passphrase != "" {
+		source = archiveKeyPassphrase
+	}
+
+	secret, err := archiveSecret(filesystem, source, encryption.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(archiveMagic)+1+archiveSaltSize+archiveNoncePrefixSize)
+	header = append(header, archiveMagic...)
+	header = append(header, source)
+
+	random := make([]byte, archiveSaltSize+archiveNoncePrefixSize)
+	_, err = rand.Read(random)
+	if err != nil {
+		return nil, err
+	}
+
+	header = append(header, random...)
+	salt := random[:archiveSaltSize]
+	prefix := random[archiveSaltSize:]
+
+	aead, err := newArchiveAEAD(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &archiveWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, archiveChunkSize)}, nil
+}
+
+// Write buffers p, sealing a chunk whenever the buffer is full and more data follows.
+func (a *archiveWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if len(a.buf) == archiveChunkSize {
+			err := a.seal(archiveChunkMore)
+			if err != nil {
+				return written, err
+			}
+		}
+
+		n := min(archiveChunkSize-len(a.buf), len(p))
+		a.buf = append(a.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+// Close seals the remaining buffered data as the final chunk.
+func (a *archiveWriter) Close() error {
+	return a.seal(archiveChunkFinal)
+}
+
+// seal writes the buffered data as a chunk with the given flag.
+func (a *archiveWriter) seal(flag byte) error {
+	sealed := a.aead.Seal(nil, archiveNonce(a.aead, a.prefix, a.counter), a.buf, []byte{flag})
+	a.counter++
+	a.buf = a.buf[:0]
+
+	header := make([]byte, 5)
+	header[0] = flag
+	binary.BigEndian.PutUint32(header[1:], uint32(len(sealed)))
+
+	_, err := a.w.Write(append(header, sealed...))
+
+	return err
+}
+
+// archiveReader opens the chunks written by archiveWriter.
+type archiveReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint64
+	buf     []byte
+	final   bool
+}
+
+// newArchiveReader returns a reader that decrypts r if it holds an encrypted archive, or reads r as-is otherwise.
+func newArchiveReader(r io.Reader, filesystem *sys.OS, passphrase string) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(len(archiveMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if !bytes.Equal(magic, archiveMagic) {
+		return buffered, nil
+	}
+
+	header := make([]byte, len(archiveMagic)+1+archiveSaltSize+archiveNoncePrefixSize)
+	_, err = io.ReadFull(buffered, header)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read encrypted archive header: %w", err)
+	}
+
+	header = header[len(archiveMagic):]
+	secret, err := archiveSecret(filesystem, header[0], passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := header[1 : 1+archiveSaltSize]
+	prefix := header[1+archiveSaltSize:]
+
+	aead, err := newArchiveAEAD(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &archiveReader{r: buffered, aead: aead, prefix: prefix}, nil
+}
+
+// Read returns decrypted data, opening the next chunk whenever the previous one has been consumed.
+func (a *archiveReader) Read(p []byte) (int, error) {
+	for len(a.buf) == 0 {
+		if a.final {
+			return 0, io.EOF
+		}
+
+		err := a.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, a.buf)
+	a.buf = a.buf[n:]
+
+	return n, nil
+}
+
+// open reads and decrypts the next chunk.
+func (a *archiveReader) open() error {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(a.r, header)
+	if err != nil {
+		return fmt.Errorf("Encrypted archive is truncated: %w", err)
+	}
+
+	flag := header[0]
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > archiveChunkSize+uint32(a.aead.Overhead()) {
+		return fmt.Errorf("Encrypted archive chunk is too large")
+	}
+
+	sealed := make([]byte, size)
+	_, err = io.ReadFull(a.r, sealed)
+	if err != nil {
+		return fmt.Errorf("Encrypted archive is truncated: %w", err)
+	}
+
+	a.buf, err = a.aead.Open(nil, archiveNonce(a.aead, a.prefix, a.counter), sealed, []byte{flag})
+	if err != nil {
+		return fmt.Errorf("Failed to decrypt archive, the key may be incorrect: %w", err)
+	}
+
+	a.counter++
+	a.final = flag == archiveChunkFinal
+
+	return nil
+}
+
+// nopWriteCloser wraps a writer with a no-op Close.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,153 @@
+package recover
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// encryptArchive returns the data encrypted with the passphrase, as written by the archive writer.
+func encryptArchive(t *testing.T, data []byte, passphrase string) []byte {
+	var archive bytes.Buffer
+	w, err := newArchiveWriter(&archive, nil, ArchiveEncryption{Passphrase: passphrase})
+	require.NoError(t, err)
+
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return archive.Bytes()
+}
+
+// decryptArchive returns the data read from the archive with the passphrase.
+func decryptArchive(archive []byte, passphrase string) ([]byte, error) {
+	r, err := newArchiveReader(bytes.NewReader(archive), nil, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+// archiveChunks splits the encrypted archive into its header and its chunks.
+func archiveChunks(t *testing.T, archive []byte) ([]byte, [][]byte) {
+	headerSize := len(archiveMagic) + 1 + archiveSaltSize + archiveNoncePrefixSize
+	header := archive[:headerSize]
+	rest := archive[headerSize:]
+
+	var chunks [][]byte
+	for len(rest) > 0 {
+		require.GreaterOrEqual(t, len(rest), 5)
+		size := 5 + int(binary.BigEndian.Uint32(rest[1:5]))
+		require.GreaterOrEqual(t, len(rest), size)
+
+		chunks = append(chunks, rest[:size])
+		rest = rest[size:]
+	}
+
+	return header, chunks
+}
+
+// Ensures archives of any size are read back as they were written, whether encrypted or not.
+func TestArchiveEncryptionRoundTrip(t *testing.T) {
+	cases := []struct {
+		name   string
+		size   int
+		chunks int
+	}{
+		{name: "Empty archive", size: 0, chunks: 1},
+		{name: "Partial chunk", size: 100, chunks: 1},
+		{name: "Exactly one chunk", size: archiveChunkSize, chunks: 1},
+		{name: "One byte over a chunk", size: archiveChunkSize + 1, chunks: 2},
+		{name: "Exact multiple of the chunk size", size: 3 * archiveChunkSize, chunks: 3},
+		{name: "Several chunks", size: 3*archiveChunkSize + 1000, chunks: 4},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data := make([]byte, c.size)
+			_, err := rand.Read(data)
+			require.NoError(t, err)
+
+			archive := encryptArchive(t, data, "secret")
+			require.True(t, bytes.HasPrefix(archive, archiveMagic))
+			if c.size > 0 {
+				require.False(t, bytes.Contains(archive, data))
+			}
+
+			_, chunks := archiveChunks(t, archive)
+			require.Len(t, chunks, c.chunks)
+			for i, chunk := range chunks {
+				require.Equal(t, i == len(chunks)-1, chunk[0] == archiveChunkFinal)
+			}
+
+			decrypted, err := decryptArchive(archive, "secret")
+			require.NoError(t, err)
+			require.Equal(t, data, decrypted)
+
+			// Archives written without encryption are read as-is.
+			var plain bytes.Buffer
+			w, err := newArchiveWriter(&plain, nil, ArchiveEncryption{})
+			require.NoError(t, err)
+			_, err = w.Write(data)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			decrypted, err = decryptArchive(plain.Bytes(), "")
+			require.NoError(t, err)
+			require.Equal(t, data, decrypted)
+		})
+	}
+}
+
+// Ensures archives that were tampered with, truncated, or are read with the wrong passphrase fail to be read rather
+// than returning altered or partial data.
+func TestArchiveEncryptionTampering(t *testing.T) {
+	data := make([]byte, 3*archiveChunkSize+1000)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	archive := encryptArchive(t, data, "secret")
+	header, chunks := archiveChunks(t, archive)
+	require.Len(t, chunks, 4)
+
+	join := func(chunks ...[]byte) []byte {
+		return bytes.Join(append([][]byte{header}, chunks...), nil)
+	}
+
+	flipped := bytes.Clone(chunks[1])
+	flipped[len(flipped)/2] ^= 0xff
+
+	// The flag of a chunk is authenticated along with its content.
+	unflagged := bytes.Clone(chunks[2])
+	unflagged[0] = archiveChunkFinal
+
+	cases := []struct {
+		name       string
+		archive    []byte
+		passphrase string
+	}{
+		{name: "Wrong passphrase", archive: archive, passphrase: "other"},
+		{name: "Missing passphrase", archive: archive, passphrase: ""},
+		{name: "Flipped byte in a chunk", archive: join(chunks[0], flipped, chunks[2], chunks[3]), passphrase: "secret"},
+		{name: "Reordered chunks", archive: join(chunks[1], chunks[0], chunks[2], chunks[3]), passphrase: "secret"},
+		{name: "Dropped chunk", archive: join(chunks[0], chunks[2], chunks[3]), passphrase: "secret"},
+		{name: "Truncated at a chunk boundary", archive: join(chunks[0], chunks[1], chunks[2]), passphrase: "secret"},
+		{name: "Chunk marked as final", archive: join(chunks[0], chunks[1], unflagged), passphrase: "secret"},
+		{name: "Truncated within a chunk", archive: archive[:len(archive)-10], passphrase: "secret"},
+		{name: "Truncated header", archive: archive[:len(archiveMagic)+4], passphrase: "secret"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// The chunks read before the failure may have been returned, but the read never ends without an error.
+			decrypted, err := decryptArchive(c.archive, c.passphrase)
+			require.Error(t, err)
+			require.Less(t, len(decrypted), len(data))
+		})
+	}
+}
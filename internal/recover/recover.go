@@ -2,6 +2,7 @@ package recover
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"errors"
@@ -17,13 +18,18 @@ import (
 	"time"
 
 	"github.com/canonical/go-dqlite"
+	dqliteClient "github.com/canonical/go-dqlite/client"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
+	"github.com/google/renameio"
+	"golang.org/x/sys/unix"
 	"gopkg.in/yaml.v3"
 
 	"github.com/canonical/microcluster/v3/client"
 	"github.com/canonical/microcluster/v3/cluster"
 	"github.com/canonical/microcluster/v3/internal/config"
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	"github.com/canonical/microcluster/v3/internal/sys"
 	"github.com/canonical/microcluster/v3/internal/trust"
@@ -69,15 +75,14 @@ func GetDqliteClusterMembers(filesystem *sys.OS) ([]cluster.DqliteMember, error)
 // files, modifies the daemon and trust store, and writes a recovery tarball.
 // It does not check members to ensure that the new configuration is valid; use
 // ValidateMemberChanges to ensure that the inputs to this function are correct.
-func RecoverFromQuorumLoss(filesystem *sys.OS, members []cluster.DqliteMember) (string, error) {
-	// Set up our new cluster configuration
-	nodeInfo := make([]dqlite.NodeInfo, 0, len(members))
+// The database backup and recovery tarball are encrypted according to the given ArchiveEncryption.
+func RecoverFromQuorumLoss(filesystem *sys.OS, members []cluster.DqliteMember, encryption ArchiveEncryption) (string, error) {
+	// Check our new cluster configuration before making any changes
 	for _, member := range members {
-		info, err := member.NodeInfo()
+		_, err := member.NodeInfo()
 		if err != nil {
 			return "", err
 		}
-		nodeInfo = append(nodeInfo, *info)
 	}
 
 	// Ensure that the daemon is not running
@@ -132,12 +137,95 @@ func RecoverFromQuorumLoss(filesystem *sys.OS, members []cluster.DqliteMember) (
 		return "", err
 	}
 
-	err = CreateDatabaseBackup(filesystem)
+	err = CreateDatabaseBackup(filesystem, encryption)
 	if err != nil {
 		return "", err
 	}
 
-	err = dqlite.ReconfigureMembershipExt(filesystem.DatabaseDir, nodeInfo)
+	localAddress, err := reconfigureMembership(filesystem, members)
+	if err != nil {
+		return "", err
+	}
+
+	// Tar up the m.FileSystem.DatabaseDir and write to `dbExportPath`
+	recoveryTarballPath, err := createRecoveryTarball(filesystem, members, encryption)
+	if err != nil {
+		return "", err
+	}
+
+	err = updateMemberAddresses(filesystem, localAddress, members)
+	if err != nil {
+		return recoveryTarballPath, err
+	}
+
+	return recoveryTarballPath, nil
+}
+
+// PublishMember moves a cluster member that was bootstrapped without a network address to the given address, so
+// that it can issue join tokens. The member must be the only one in the cluster, and its daemon must not be running.
+// A database backup is taken before the dqlite configuration is changed.
+func PublishMember(filesystem *sys.OS, address types.AddrPort, encryption ArchiveEncryption) error {
+	if !address.IsValid() || address.Addr().IsUnspecified() || address.Addr().IsLoopback() {
+		return fmt.Errorf("Cannot publish cluster member on %q, a reachable network address is required", address)
+	}
+
+	isSocketPresent, err := filesystem.IsControlSocketPresent()
+	if err != nil {
+		return err
+	}
+
+	if isSocketPresent {
+		return fmt.Errorf("Daemon is running (socket path exists: %q)", filesystem.ControlSocketPath())
+	}
+
+	daemonConfig := config.NewDaemonConfig(path.Join(filesystem.StateDir, "daemon.yaml"))
+	err = daemonConfig.Load()
+	if err != nil {
+		return err
+	}
+
+	if !daemonConfig.GetLocalOnly() {
+		return fmt.Errorf("Cluster member %q is already published on %q", daemonConfig.GetName(), daemonConfig.GetAddress())
+	}
+
+	members, err := GetDqliteClusterMembers(filesystem)
+	if err != nil {
+		return err
+	}
+
+	if len(members) != 1 {
+		return fmt.Errorf("Expected a single local-only cluster member, found %d", len(members))
+	}
+
+	members[0].Address = address.String()
+
+	err = CreateDatabaseBackup(filesystem, encryption)
+	if err != nil {
+		return err
+	}
+
+	localAddress, err := reconfigureMembership(filesystem, members)
+	if err != nil {
+		return err
+	}
+
+	return updateMemberAddresses(filesystem, localAddress, members)
+}
+
+// reconfigureMembership resets the dqlite raft configuration to the given members, and rewrites the go-dqlite yaml
+// files accordingly. It returns the new address of the local member.
+func reconfigureMembership(filesystem *sys.OS, members []cluster.DqliteMember) (string, error) {
+	nodeInfo := make([]dqlite.NodeInfo, 0, len(members))
+	for _, member := range members {
+		info, err := member.NodeInfo()
+		if err != nil {
+			return "", err
+		}
+
+		nodeInfo = append(nodeInfo, *info)
+	}
+
+	err := dqlite.ReconfigureMembershipExt(filesystem.DatabaseDir, nodeInfo)
 	if err != nil {
 		return "", fmt.Errorf("Dqlite recovery: %w", err)
 	}
@@ -168,28 +256,47 @@ func RecoverFromQuorumLoss(filesystem *sys.OS, members []cluster.DqliteMember) (
 		return "", err
 	}
 
-	// Tar up the m.FileSystem.DatabaseDir and write to `dbExportPath`
-	recoveryTarballPath, err := createRecoveryTarball(filesystem, members)
+	return localInfo.Address, nil
+}
+
+// WriteRejoinMembership writes the go-dqlite info.yaml and cluster.yaml files of a reinstalled cluster member that
+// rejoins the cluster, so that its dqlite node starts with its existing dqlite ID and finds the dqlite leader from the
+// given raft configuration, instead of being added to the cluster as a new node.
+func WriteRejoinMembership(filesystem *sys.OS, dqliteID uint64, address string, members []cluster.DqliteMember) error {
+	err := os.MkdirAll(filesystem.DatabaseDir, 0o700)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	err = updateDaemonAddress(filesystem, localInfo.Address)
+	// The node was demoted to spare before rejoining, and is promoted again by the dqlite leader.
+	localInfo := dqlite.NodeInfo{ID: dqliteID, Address: address, Role: dqliteClient.Spare}
+	err = writeYaml(path.Join(filesystem.DatabaseDir, "info.yaml"), &localInfo)
 	if err != nil {
-		return recoveryTarballPath, err
+		return err
+	}
+
+	return writeDqliteClusterYaml(path.Join(filesystem.DatabaseDir, "cluster.yaml"), members)
+}
+
+// updateMemberAddresses records the new member addresses in the daemon configuration, the trust store, and a
+// patch applied to the global database on the next start.
+func updateMemberAddresses(filesystem *sys.OS, localAddress string, members []cluster.DqliteMember) error {
+	err := updateDaemonAddress(filesystem, localAddress)
+	if err != nil {
+		return err
 	}
 
 	err = updateTrustStore(filesystem.TrustDir, members)
 	if err != nil {
-		return recoveryTarballPath, fmt.Errorf("Failed to update trust store: %w", err)
+		return fmt.Errorf("Failed to update trust store: %w", err)
 	}
 
 	err = writeGlobalMembersPatch(filesystem, members)
 	if err != nil {
-		return recoveryTarballPath, fmt.Errorf("Failed to write global DB update: %w", err)
+		return fmt.Errorf("Failed to write global DB update: %w", err)
 	}
 
-	return recoveryTarballPath, nil
+	return nil
 }
 
 func readYaml(path string, v any) error {
@@ -246,6 +353,7 @@ func updateDaemonAddress(filesystem *sys.OS, address string) error {
 	}
 
 	daemonConfig.SetAddress(newAddress)
+	daemonConfig.SetLocalOnly(false)
 	err = daemonConfig.Write()
 	if err != nil {
 		return fmt.Errorf("Failed to update daemon.yaml: %w", err)
@@ -256,8 +364,8 @@ func updateDaemonAddress(filesystem *sys.OS, address string) error {
 
 // ReadTrustStore parses the trust store. This is not thread safe!
 func readTrustStore(dir string) (*trust.Remotes, error) {
-	remotes := &trust.Remotes{}
-	err := remotes.Load(dir)
+	remotes := trust.NewRemotes(trust.NewFileBackend(dir, nil))
+	err := remotes.Load()
 
 	return remotes, err
 }
@@ -273,22 +381,20 @@ func updateTrustStore(dir string, members []cluster.DqliteMember) error {
 
 	trustMembers := make([]types.ClusterMember, 0, len(members))
 	for _, member := range members {
-		cert := remotesByName[member.Name].Certificate
 		addr, err := netip.ParseAddrPort(member.Address)
 		if err != nil {
 			return fmt.Errorf("Invalid address %q: %w", member.Address, err)
 		}
 
-		trustMembers = append(trustMembers, types.ClusterMember{
-			ClusterMemberLocal: types.ClusterMemberLocal{
-				Name:        member.Name,
-				Address:     types.AddrPort{AddrPort: addr},
-				Certificate: cert,
-			},
-		})
+		remote := remotesByName[member.Name]
+		trustMember := remote.ClusterMember()
+		trustMember.Name = member.Name
+		trustMember.Address = types.AddrPort{AddrPort: addr}
+
+		trustMembers = append(trustMembers, trustMember)
 	}
 
-	err = remotes.Replace(dir, trustMembers...)
+	err = remotes.Replace(trustMembers...)
 	if err != nil {
 		return fmt.Errorf("Update trust store at %q: %w", dir, err)
 	}
@@ -299,6 +405,7 @@ func updateTrustStore(dir string, members []cluster.DqliteMember) error {
 // ValidateMemberChanges compares two arrays of members to ensure:
 // - Their lengths are the same.
 // - Members with the same name also use the same ID.
+// - All the newMembers roles are valid dqlite roles.
 // - There is at least one voter in newMembers.
 // - All the newMembers addresses can be parsed to a netip.AddrPort.
 // - There are no duplicate addresses.
@@ -311,6 +418,11 @@ func ValidateMemberChanges(oldMembers []cluster.DqliteMember, newMembers []clust
 	addrs := make(map[netip.AddrPort]bool)
 
 	for _, newMember := range newMembers {
+		_, err := newMember.NodeInfo()
+		if err != nil {
+			return fmt.Errorf("Invalid member %q: %w", newMember.Name, err)
+		}
+
 		if newMember.Role == "voter" {
 			countVoters += 1
 		}
@@ -382,8 +494,8 @@ func writeGlobalMembersPatch(filesystem *sys.OS, members []cluster.DqliteMember)
 // go-dqlite's info.yaml is excluded from the tarball.
 // The new cluster configuration is included as `recovery.yaml`.
 // This function returns the path to the tarball.
-func createRecoveryTarball(filesystem *sys.OS, members []cluster.DqliteMember) (string, error) {
-	tarballPath := path.Join(filesystem.StateDir, "recovery_db.tar.gz")
+func createRecoveryTarball(filesystem *sys.OS, members []cluster.DqliteMember, encryption ArchiveEncryption) (string, error) {
+	tarballPath := filesystem.RecoveryTarballPath()
 	recoveryYamlPath := path.Join(filesystem.DatabaseDir, "recovery.yaml")
 
 	err := writeYaml(recoveryYamlPath, members)
@@ -394,18 +506,107 @@ func createRecoveryTarball(filesystem *sys.OS, members []cluster.DqliteMember) (
 	// info.yaml is used by go-dqlite to keep track of the current cluster member's
 	// ID and address. We shouldn't replicate the recovery member's info.yaml
 	// to all other members, so exclude it from the tarball:
-	err = createTarball(tarballPath, filesystem.DatabaseDir, ".", []string{"info.yaml"})
+	err = createTarball(tarballPath, filesystem.DatabaseDir, ".", []string{"info.yaml"}, filesystem, encryption)
 
 	return tarballPath, err
 }
 
+// MaxRecoveryTarballSize is the largest recovery tarball that cluster members accept over the network.
+const MaxRecoveryTarballSize = 1 << 30
+
+// WriteRecoveryTarball stores a recovery tarball received from another cluster member in filesystem.StateDir,
+// replacing any previous one. It is loaded by MaybeUnpackRecoveryTarball when the daemon next starts.
+func WriteRecoveryTarball(filesystem *sys.OS, tarball io.Reader) error {
+	tarballFile, err := renameio.TempFile("", filesystem.RecoveryTarballPath())
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = tarballFile.Cleanup() }()
+
+	err = tarballFile.Chmod(0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tarballFile, tarball)
+	if err != nil {
+		return fmt.Errorf("Failed to receive recovery tarball: %w", err)
+	}
+
+	return tarballFile.CloseAtomicallyReplace()
+}
+
+// DistributeRecoveryTarball sends the recovery tarball created by RecoverFromQuorumLoss to the cluster members at
+// the given addresses, so that it does not need to be copied to each of them manually. The members must be running,
+// and load the tarball when they are next restarted.
+// The request is authenticated with the local server certificate, and the members must present the cluster
+// certificate. All addresses are attempted, and the errors of those that could not be reached are returned together.
+func DistributeRecoveryTarball(ctx context.Context, filesystem *sys.OS, addresses []string) error {
+	tarball, err := os.ReadFile(filesystem.RecoveryTarballPath())
+	if err != nil {
+		return fmt.Errorf("Failed to read recovery tarball: %w", err)
+	}
+
+	if len(tarball) > MaxRecoveryTarballSize {
+		return fmt.Errorf("Recovery tarball of %d bytes exceeds the limit of %d bytes, it must be copied to the cluster members manually", len(tarball), MaxRecoveryTarballSize)
+	}
+
+	serverCert, err := filesystem.ServerCert()
+	if err != nil {
+		return err
+	}
+
+	clusterCert, err := filesystem.ClusterCert()
+	if err != nil {
+		return err
+	}
+
+	clusterKey, err := clusterCert.PublicKeyX509()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, address := range addresses {
+		addrPort, err := types.ParseAddrPort(address)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Invalid address %q: %w", address, err))
+			continue
+		}
+
+		c, err := internalClient.New(*api.NewURL().Scheme("https").Host(addrPort.String()), serverCert, clusterKey, false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to create client for %q: %w", address, err))
+			continue
+		}
+
+		err = internalClient.PostRecoveryTarball(ctx, c, bytes.NewReader(tarball))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to send recovery tarball to %q: %w", address, err))
+			continue
+		}
+
+		logger.Info("Sent recovery tarball to cluster member", logger.Ctx{"address": address})
+	}
+
+	return errors.Join(errs...)
+}
+
+// recoveryUnpackDir is the directory within filesystem.DatabaseDir that recovery tarballs are unpacked into.
+const recoveryUnpackDir = ".recovery_db"
+
 // MaybeUnpackRecoveryTarball checks for the presence of a recovery tarball in
-// fiesystem.StateDir. If it exists, unpack it into a temporary directory,
-// ensure that it is a valid microcluster recovery tarball, and replace the
-// existing filesystem.DatabaseDir.
-func MaybeUnpackRecoveryTarball(filesystem *sys.OS) error {
-	tarballPath := path.Join(filesystem.StateDir, "recovery_db.tar.gz")
-	unpackDir := path.Join(filesystem.StateDir, "recovery_db")
+// fiesystem.StateDir. If it exists, back up the existing database, unpack the
+// tarball into a temporary directory, ensure that it is a valid microcluster
+// recovery tarball, and replace the contents of filesystem.DatabaseDir.
+// Encrypted tarballs are decrypted transparently, and the database backup taken beforehand is encrypted according to
+// the given ArchiveEncryption.
+func MaybeUnpackRecoveryTarball(filesystem *sys.OS, encryption ArchiveEncryption) error {
+	tarballPath := filesystem.RecoveryTarballPath()
+	// Unpack within the database directory, so that the files can be moved into place without leaving its filesystem,
+	// even if it is outside the state directory.
+	unpackDir := path.Join(filesystem.DatabaseDir, recoveryUnpackDir)
 	recoveryYamlPath := path.Join(unpackDir, "recovery.yaml")
 
 	// Determine if the recovery tarball exists
@@ -415,7 +616,18 @@ func MaybeUnpackRecoveryTarball(filesystem *sys.OS) error {
 
 	logger.Warn("Recovery tarball located; attempting DB recovery", logger.Ctx{"tarball": tarballPath})
 
-	err := unpackTarball(tarballPath, unpackDir)
+	// Remove what is left of an earlier attempt, so that it is not backed up or mixed with the tarball.
+	err := os.RemoveAll(unpackDir)
+	if err != nil {
+		return err
+	}
+
+	err = CreateDatabaseBackup(filesystem, encryption)
+	if err != nil {
+		return err
+	}
+
+	err = unpackTarball(tarballPath, unpackDir, filesystem, encryption.Passphrase)
 	if err != nil {
 		return err
 	}
@@ -434,7 +646,7 @@ func MaybeUnpackRecoveryTarball(filesystem *sys.OS) error {
 	var incomingMembers []cluster.DqliteMember
 	err = readYaml(recoveryYamlPath, &incomingMembers)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	found := false
@@ -462,72 +674,182 @@ func MaybeUnpackRecoveryTarball(filesystem *sys.OS) error {
 		return err
 	}
 
-	err = CreateDatabaseBackup(filesystem)
+	err = os.Remove(recoveryYamlPath)
 	if err != nil {
 		return err
 	}
 
 	// Now that we're as sure as we can be that the recovery DB is valid, we can
 	// replace the existing DB
-	err = os.RemoveAll(filesystem.DatabaseDir)
+	err = replaceDirContents(filesystem.DatabaseDir, unpackDir)
 	if err != nil {
 		return err
 	}
 
-	err = os.Remove(recoveryYamlPath)
+	// Prevent the database being restored again after subsequent restarts
+	err = os.Remove(tarballPath)
 	if err != nil {
 		return err
 	}
 
-	err = os.Rename(unpackDir, filesystem.DatabaseDir)
+	// Update daemon.yaml
+	err = updateDaemonAddress(filesystem, localInfo.Address)
 	if err != nil {
 		return err
 	}
 
-	// Prevent the database being restored again after subsequent restarts
-	err = os.Remove(tarballPath)
+	return nil
+}
+
+// replacedEntriesDir is the directory within a directory whose contents are being replaced that its previous
+// entries are set aside in.
+const replacedEntriesDir = ".replaced"
+
+// replaceDirContents replaces the entries of dir with those of src, a subdirectory of dir, which is then removed.
+// The entries are moved rather than dir itself, as it may be a mount point. The previous entries are set aside until
+// all of the new ones are in place, and are moved back if any of them can't be.
+func replaceDirContents(dir string, src string) error {
+	oldDir := filepath.Join(dir, replacedEntriesDir)
+	err := os.RemoveAll(oldDir)
 	if err != nil {
 		return err
 	}
 
-	// Update daemon.yaml
-	err = updateDaemonAddress(filesystem, localInfo.Address)
+	err = os.Mkdir(oldDir, 0o700)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	reverter.Add(func() { _ = os.Remove(oldDir) })
+
+	moveEntries := func(from string, to string, skip ...string) error {
+		entries, err := os.ReadDir(from)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			fromPath := filepath.Join(from, entry.Name())
+			if slices.Contains(skip, fromPath) {
+				continue
+			}
+
+			toPath := filepath.Join(to, entry.Name())
+			err = os.Rename(fromPath, toPath)
+			if err != nil {
+				return err
+			}
+
+			reverter.Add(func() { _ = os.Rename(toPath, fromPath) })
+		}
+
+		return nil
+	}
+
+	err = moveEntries(dir, oldDir, filepath.Clean(src), oldDir)
+	if err != nil {
+		return err
+	}
+
+	err = moveEntries(src, dir)
+	if err != nil {
+		return err
+	}
+
+	reverter.Success()
+
+	err = os.RemoveAll(oldDir)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(src)
 }
 
-// CreateDatabaseBackup writes a tarball of filesystem.DatabaseDir to
-// filesystem.StateDir as db_backup.TIMESTAMP.tar.gz. It does not check to
-// to ensure that the database is stopped.
-func CreateDatabaseBackup(filesystem *sys.OS) error {
+// databaseBackupPath returns the path of a new database backup in filesystem.StateDir, named
+// db_backup.TIMESTAMP.tar.gz.
+func databaseBackupPath(filesystem *sys.OS) string {
 	// tar interprets `:` as a remote drive; ISO8601 allows a 'basic format'
 	// with the colons omitted (as opposed to time.RFC3339)
 	// https://en.wikipedia.org/wiki/ISO_8601
 	backupFileName := fmt.Sprintf("db_backup.%s.tar.gz", time.Now().Format("2006-01-02T150405Z0700"))
 
-	backupFilePath := path.Join(filesystem.StateDir, backupFileName)
-
-	logger.Info("Creating database backup", logger.Ctx{"archive": backupFilePath})
+	return path.Join(filesystem.StateDir, backupFileName)
+}
 
-	// For DB backups the tarball should contain the subdirs (usually `database/`)
-	// so that the user can easily untar the backup from the state dir.
-	rootDir := filesystem.StateDir
+// databaseBackupRoot returns the directory database backups are rooted at, and the database directory relative to
+// it. For DB backups the tarball should contain the subdirs (usually `database/`) so that the user can easily untar
+// the backup from the state dir. The database directory is archived on its own if it is not inside StateDir.
+func databaseBackupRoot(filesystem *sys.OS) (rootDir string, walkDir string) {
 	walkDir, err := filepath.Rel(filesystem.StateDir, filesystem.DatabaseDir)
-
-	// Don't bother if DatabaseDir is not inside StateDir
-	if err != nil {
-		logger.Warn("DB backup: DatabaseDir (%q) not in StateDir (%q)", logger.Ctx{
+	if err != nil || !filepath.IsLocal(walkDir) {
+		logger.Debug("Database directory is outside of the state directory", logger.Ctx{
 			"databaseDir": filesystem.DatabaseDir,
 			"stateDir":    filesystem.StateDir,
 		})
-		rootDir = filesystem.DatabaseDir
-		walkDir = "."
+
+		return filesystem.DatabaseDir, "."
 	}
 
-	err = createTarball(backupFilePath, rootDir, walkDir, []string{})
+	return filesystem.StateDir, walkDir
+}
+
+// CreateDatabaseBackup writes a tarball of filesystem.DatabaseDir to
+// filesystem.StateDir as db_backup.TIMESTAMP.tar.gz. The files are archived
+// as they are, so the database must be stopped. Backups of a running database
+// are taken with CreateDatabaseDumpBackup instead.
+// The backup is encrypted according to the given ArchiveEncryption.
+func CreateDatabaseBackup(filesystem *sys.OS, encryption ArchiveEncryption) error {
+	backupFilePath := databaseBackupPath(filesystem)
+
+	logger.Info("Creating database backup", logger.Ctx{"archive": backupFilePath})
+
+	rootDir, walkDir := databaseBackupRoot(filesystem)
+	err := createTarball(backupFilePath, rootDir, walkDir, []string{}, filesystem, encryption)
+	if err != nil {
+		return fmt.Errorf("database backup: %w", err)
+	}
+
+	return nil
+}
+
+// CreateDatabaseDumpBackup writes a tarball of the files of a database dump taken through dqlite to
+// filesystem.StateDir as db_backup.TIMESTAMP.tar.gz, at their place in filesystem.DatabaseDir. Unlike the files of
+// the database directory, a dump is consistent while the database is running. It holds the SQLite database file and
+// its write-ahead log rather than the raft log, so it restores the data of the cluster rather than the membership.
+// The backup is encrypted according to the given ArchiveEncryption.
+func CreateDatabaseDumpBackup(filesystem *sys.OS, dump []dqliteClient.File, encryption ArchiveEncryption) error {
+	backupFilePath := databaseBackupPath(filesystem)
+
+	logger.Info("Creating database backup from dump", logger.Ctx{"archive": backupFilePath})
+
+	_, walkDir := databaseBackupRoot(filesystem)
+	err := writeTarball(backupFilePath, filesystem, encryption, func(tarWriter *tar.Writer) error {
+		for _, file := range dump {
+			header := &tar.Header{
+				Name:     path.Join(walkDir, file.Name),
+				Mode:     0o600,
+				Size:     int64(len(file.Data)),
+				ModTime:  time.Now(),
+				Typeflag: tar.TypeReg,
+			}
+
+			err := tarWriter.WriteHeader(header)
+			if err != nil {
+				return err
+			}
+
+			_, err = tarWriter.Write(file.Data)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("database backup: %w", err)
 	}
@@ -538,24 +860,70 @@ func CreateDatabaseBackup(filesystem *sys.OS) error {
 // createTarball creates tarball at tarballPath, rooted at rootDir and including
 // all files in walkDir except those paths found in excludeFiles.
 // walkDir and excludeFiles elements are relative to rootDir.
-func createTarball(tarballPath string, rootDir string, walkDir string, excludeFiles []string) error {
-	tarball, err := os.Create(tarballPath)
+// The tarball is encrypted if the given ArchiveEncryption is enabled.
+func createTarball(tarballPath string, rootDir string, walkDir string, excludeFiles []string, filesystem *sys.OS, encryption ArchiveEncryption) error {
+	return writeTarball(tarballPath, filesystem, encryption, func(tarWriter *tar.Writer) error {
+		return addTarballEntries(tarWriter, rootDir, walkDir, excludeFiles, "")
+	})
+}
+
+// writeTarball creates tarball at tarballPath with the entries written by addEntries, encrypted if the given
+// ArchiveEncryption is enabled. The tarball is only readable by its owner, and is written to a temporary file that
+// is renamed into place once it is complete, so that an incomplete tarball is never found at tarballPath.
+func writeTarball(tarballPath string, filesystem *sys.OS, encryption ArchiveEncryption, addEntries func(tarWriter *tar.Writer) error) error {
+	tarball, err := renameio.TempFile("", tarballPath)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = tarball.Cleanup() }()
+
+	err = tarball.Chmod(0600)
+	if err != nil {
+		return err
+	}
+
+	encWriter, err := newArchiveWriter(tarball, filesystem, encryption)
 	if err != nil {
 		return err
 	}
 
-	gzWriter := gzip.NewWriter(tarball)
+	gzWriter := gzip.NewWriter(encWriter)
 	tarWriter := tar.NewWriter(gzWriter)
 
+	err = addEntries(tarWriter)
+	if err != nil {
+		return err
+	}
+
+	for _, closer := range []io.Closer{tarWriter, gzWriter, encWriter} {
+		err = closer.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return tarball.CloseAtomicallyReplace()
+}
+
+// addTarballEntries writes the directory tree in walkDir to the tarball, except those paths found in excludeFiles.
+// walkDir and excludeFiles elements are relative to rootDir, and entries are named relative to rootDir, under prefix
+// if it is set. Excluding a directory excludes everything below it. Symlinks are archived as links rather than
+// followed, and other special files such as sockets are skipped.
+func addTarballEntries(tarWriter *tar.Writer, rootDir string, walkDir string, excludeFiles []string, prefix string) error {
 	filesys := os.DirFS(rootDir)
 
-	err = fs.WalkDir(filesys, walkDir, func(filepath string, stat fs.DirEntry, err error) error {
+	return fs.WalkDir(filesys, walkDir, func(filepath string, stat fs.DirEntry, err error) error {
 		if err != nil {
 			logger.Warn("Failed to read file while creating tarball; skipping", logger.Ctx{"file": filepath, "err": err})
 			return nil
 		}
 
 		if slices.Contains(excludeFiles, filepath) {
+			if stat.IsDir() {
+				return fs.SkipDir
+			}
+
 			return nil
 		}
 
@@ -564,13 +932,34 @@ func createTarball(tarballPath string, rootDir string, walkDir string, excludeFi
 			return err
 		}
 
-		header, err := tar.FileInfoHeader(info, filepath)
+		var link string
+		switch {
+		case info.Mode().IsRegular(), info.IsDir():
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err = os.Readlink(path.Join(rootDir, filepath))
+			if err != nil {
+				return err
+			}
+
+		default:
+			logger.Warn("Skipping special file while creating tarball", logger.Ctx{"file": filepath, "mode": info.Mode().String()})
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
 		if err != nil {
 			return fmt.Errorf("create tar header for %q: %w", filepath, err)
 		}
 
 		// header.Name is the basename of `stat` by default
 		header.Name = filepath
+		if prefix != "" {
+			header.Name = path.Join(prefix, filepath)
+		}
+
+		if info.IsDir() {
+			header.Name += "/"
+		}
 
 		err = tarWriter.WriteHeader(header)
 		if err != nil {
@@ -586,6 +975,7 @@ func createTarball(tarballPath string, rootDir string, walkDir string, excludeFi
 
 			_, err = io.Copy(tarWriter, file)
 			if err != nil {
+				_ = file.Close()
 				return err
 			}
 
@@ -597,35 +987,24 @@ func createTarball(tarballPath string, rootDir string, walkDir string, excludeFi
 
 		return nil
 	})
-	if err != nil {
-		return err
-	}
-
-	err = tarWriter.Close()
-	if err != nil {
-		return err
-	}
-
-	err = gzWriter.Close()
-	if err != nil {
-		return err
-	}
+}
 
-	err = tarball.Close()
+// unpackTarball restores the directory tree archived in the tarball under destRoot, keeping the permissions of its
+// files and directories. Entries escaping destRoot, either through their name or through a symlink, are rejected.
+func unpackTarball(tarballPath string, destRoot string, filesystem *sys.OS, passphrase string) error {
+	tarball, err := os.Open(tarballPath)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	defer func() { _ = tarball.Close() }()
 
-func unpackTarball(tarballPath string, destRoot string) error {
-	tarball, err := os.Open(tarballPath)
+	encReader, err := newArchiveReader(tarball, filesystem, passphrase)
 	if err != nil {
 		return err
 	}
 
-	gzReader, err := gzip.NewReader(tarball)
+	gzReader, err := gzip.NewReader(encReader)
 	if err != nil {
 		return err
 	}
@@ -637,6 +1016,8 @@ func unpackTarball(tarballPath string, destRoot string) error {
 		return err
 	}
 
+	// Directory permissions are applied once everything is unpacked, so that read-only directories can be filled.
+	dirModes := map[string]fs.FileMode{}
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
@@ -646,30 +1027,136 @@ func unpackTarball(tarballPath string, destRoot string) error {
 		}
 
 		// CWE-22
-		if strings.Contains(header.Name, "..") {
+		if slices.Contains(strings.Split(filepath.ToSlash(header.Name), "/"), "..") {
 			return fmt.Errorf("Invalid sequence `..` in recovery tarball entry %q", header.Name)
 		}
 
-		filepath := path.Join(destRoot, header.Name)
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if !filepath.IsLocal(name) && name != "." {
+			return fmt.Errorf("Recovery tarball entry %q is not within the archive", header.Name)
+		}
 
+		entryPath := filepath.Join(destRoot, name)
+		err = checkUnpackParents(destRoot, name)
+		if err != nil {
+			return fmt.Errorf("Invalid recovery tarball entry %q: %w", header.Name, err)
+		}
+
+		mode := fs.FileMode(header.Mode & int64(fs.ModePerm))
 		switch header.Typeflag {
 		case tar.TypeReg:
-			file, err := os.Create(filepath)
+			err = os.MkdirAll(filepath.Dir(entryPath), 0o755)
+			if err != nil {
+				return err
+			}
+
+			// A symlink unpacked from an earlier entry with the same name is replaced rather than followed.
+			info, err := os.Lstat(entryPath)
+			if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+				err = os.Remove(entryPath)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Keep the permissions of the archived file, as it may be a private key. Never write through a symlink.
+			file, err := os.OpenFile(entryPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|unix.O_NOFOLLOW, mode)
 			if err != nil {
 				return err
 			}
 
 			countWritten, err := io.Copy(file, tarReader)
+			if err == nil {
+				err = file.Chmod(mode)
+			}
+
+			closeErr := file.Close()
 			if countWritten != header.Size {
 				return fmt.Errorf("Mismatched written (%d) and size (%d) for entry %q in %q", countWritten, header.Size, header.Name, tarballPath)
 			} else if err != nil {
 				return err
+			} else if closeErr != nil {
+				return closeErr
+			}
+
+			err = os.Chtimes(entryPath, header.ModTime, header.ModTime)
+			if err != nil {
+				return err
 			}
+
 		case tar.TypeDir:
-			err = os.MkdirAll(filepath, fs.FileMode(header.Mode&int64(fs.ModePerm)))
+			err = os.MkdirAll(entryPath, 0o700)
+			if err != nil {
+				return err
+			}
+
+			dirModes[entryPath] = mode
+		case tar.TypeSymlink:
+			// Symlinks may only point within the archive, so that they can't expose or overwrite other files.
+			target := filepath.FromSlash(header.Linkname)
+			if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(name), target)) {
+				return fmt.Errorf("Recovery tarball symlink %q points outside of the archive", header.Name)
+			}
+
+			err = os.MkdirAll(filepath.Dir(entryPath), 0o755)
+			if err != nil {
+				return err
+			}
+
+			// A later entry with the same name replaces an earlier one, as when extracting with tar.
+			info, err := os.Lstat(entryPath)
+			if err == nil && info.IsDir() {
+				return fmt.Errorf("Recovery tarball symlink %q replaces a directory", header.Name)
+			} else if err == nil {
+				err = os.Remove(entryPath)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = os.Symlink(target, entryPath)
 			if err != nil {
 				return err
 			}
+
+		default:
+			logger.Warn("Skipping unsupported recovery tarball entry", logger.Ctx{"entry": header.Name, "type": string(header.Typeflag)})
+		}
+	}
+
+	for dir, mode := range dirModes {
+		err = os.Chmod(dir, mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkUnpackParents checks that no existing parent directory of the entry with the given name, relative to destRoot,
+// is a symlink, so that unpacking the entry can't write outside of destRoot.
+func checkUnpackParents(destRoot string, name string) error {
+	parent := destRoot
+	for _, component := range strings.Split(filepath.Dir(name), string(filepath.Separator)) {
+		if component == "." {
+			continue
+		}
+
+		parent = filepath.Join(parent, component)
+		info, err := os.Lstat(parent)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("Parent %q is a symlink", parent)
+		}
+
+		if !info.IsDir() {
+			return fmt.Errorf("Parent %q is not a directory", parent)
 		}
 	}
 
@@ -0,0 +1,339 @@
+package recover
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/canonical/go-dqlite"
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/trust"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures a rejoining member starts as a spare with its existing dqlite ID, and knows the raft configuration.
+func TestWriteRejoinMembership(t *testing.T) {
+	filesystem := &sys.OS{DatabaseDir: filepath.Join(t.TempDir(), "database")}
+
+	members := []cluster.DqliteMember{
+		{DqliteID: 1, Address: "10.0.0.1:9000", Role: "voter", Name: "m1"},
+		{DqliteID: 2, Address: "10.0.0.2:9000", Role: "spare", Name: "m2"},
+		{DqliteID: 3, Address: "10.0.0.3:9000", Role: "stand-by", Name: "m3"},
+	}
+
+	err := WriteRejoinMembership(filesystem, 2, "10.0.0.2:9000", members)
+	require.NoError(t, err)
+
+	info := dqlite.NodeInfo{}
+	err = readYaml(filepath.Join(filesystem.DatabaseDir, "info.yaml"), &info)
+	require.NoError(t, err)
+	require.Equal(t, dqlite.NodeInfo{ID: 2, Address: "10.0.0.2:9000", Role: dqliteClient.Spare}, info)
+
+	nodes := []dqlite.NodeInfo{}
+	err = readYaml(filepath.Join(filesystem.DatabaseDir, "cluster.yaml"), &nodes)
+	require.NoError(t, err)
+	require.Equal(t, []dqlite.NodeInfo{
+		{ID: 1, Address: "10.0.0.1:9000", Role: dqliteClient.Voter},
+		{ID: 2, Address: "10.0.0.2:9000", Role: dqliteClient.Spare},
+		{ID: 3, Address: "10.0.0.3:9000", Role: dqliteClient.StandBy},
+	}, nodes)
+
+	// Invalid roles are rejected.
+	members[0].Role = "leader"
+	err = WriteRejoinMembership(filesystem, 2, "10.0.0.2:9000", members)
+	require.Error(t, err)
+}
+
+// Ensures the database directory keeps its own inode when its contents are replaced by an unpacked tarball.
+func TestReplaceDirContents(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, recoveryUnpackDir)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.yaml"), []byte("old"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "snapshots"), 0o700))
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "segments"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "cluster.yaml"), []byte("new"), 0o600))
+
+	before, err := os.Stat(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, replaceDirContents(dir, src))
+
+	after, err := os.Stat(dir)
+	require.NoError(t, err)
+	require.True(t, os.SameFile(before, after))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	require.ElementsMatch(t, []string{"cluster.yaml", "segments"}, names)
+}
+
+// Ensures the previous contents of a directory are kept if its new contents can't be moved into place.
+func TestReplaceDirContentsRevert(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.yaml"), []byte("old"), 0o600))
+
+	require.Error(t, replaceDirContents(dir, filepath.Join(dir, "missing")))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "old.yaml", entries[0].Name())
+}
+
+// Ensures backups of a running database hold the dumped database files, at their place in the database directory.
+func TestCreateDatabaseDumpBackup(t *testing.T) {
+	for _, external := range []bool{false, true} {
+		stateDir := t.TempDir()
+		filesystem := &sys.OS{StateDir: stateDir, DatabaseDir: filepath.Join(stateDir, "database")}
+		if external {
+			filesystem.DatabaseDir = filepath.Join(t.TempDir(), "database")
+		}
+
+		dump := []dqliteClient.File{{Name: "db.bin", Data: []byte("database")}, {Name: "db.bin-wal", Data: []byte("wal")}}
+		require.NoError(t, CreateDatabaseDumpBackup(filesystem, dump, ArchiveEncryption{}))
+
+		backups, err := ListDatabaseBackups(filesystem)
+		require.NoError(t, err)
+		require.Len(t, backups, 1)
+
+		// Nothing but the backup is left in the state directory.
+		entries, err := os.ReadDir(stateDir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		unpackDir := filepath.Join(t.TempDir(), "unpack")
+		require.NoError(t, os.Mkdir(unpackDir, 0o700))
+		require.NoError(t, unpackTarball(filepath.Join(stateDir, backups[0].Name), unpackDir, filesystem, ""))
+
+		dumpDir := filepath.Join(unpackDir, "database")
+		if external {
+			dumpDir = unpackDir
+		}
+
+		for _, file := range dump {
+			data, err := os.ReadFile(filepath.Join(dumpDir, file.Name))
+			require.NoError(t, err)
+			require.Equal(t, file.Data, data)
+		}
+	}
+}
+
+// Ensures a snapshot restores the state of a cluster member in place, including a database directory outside of the
+// state directory, and that nothing is left of the staged contents.
+func TestRestoreSnapshot(t *testing.T) {
+	newFilesystem := func() *sys.OS {
+		stateDir := t.TempDir()
+
+		return &sys.OS{
+			StateDir:        stateDir,
+			DatabaseDir:     filepath.Join(t.TempDir(), "database"),
+			TrustDir:        filepath.Join(stateDir, "truststore"),
+			CertificatesDir: filepath.Join(stateDir, "certificates"),
+		}
+	}
+
+	source := newFilesystem()
+	for _, dir := range []string{source.DatabaseDir, source.TrustDir, source.CertificatesDir} {
+		require.NoError(t, os.MkdirAll(dir, 0o700))
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(source.DatabaseDir, "info.yaml"), []byte("id: 1\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(source.TrustDir, "m1.yaml"), []byte("name: m1\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(source.StateDir, "daemon.yaml"), []byte("name: m1\n"), 0o600))
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	require.NoError(t, CreateSnapshot(source, snapshotPath, ArchiveEncryption{}))
+
+	target := newFilesystem()
+	require.NoError(t, os.MkdirAll(target.DatabaseDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(target.DatabaseDir, "stale"), []byte("stale"), 0o600))
+
+	require.NoError(t, RestoreSnapshot(target, snapshotPath, ArchiveEncryption{}))
+
+	for path, expected := range map[string]string{
+		filepath.Join(target.DatabaseDir, "info.yaml"): "id: 1\n",
+		filepath.Join(target.TrustDir, "m1.yaml"):      "name: m1\n",
+		filepath.Join(target.StateDir, "daemon.yaml"):  "name: m1\n",
+	} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(data))
+	}
+
+	entries, err := os.ReadDir(target.DatabaseDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	require.NoDirExists(t, filepath.Join(target.StateDir, "snapshot_restore"))
+	require.NoDirExists(t, filepath.Join(target.TrustDir, snapshotStagingDir))
+
+	// A restored cluster member can't be restored over.
+	require.Error(t, RestoreSnapshot(target, snapshotPath, ArchiveEncryption{}))
+}
+
+// tarEntry is an entry of a test tarball.
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	link     string
+}
+
+// writeTestTarball writes a gzipped tarball with the given entries, and returns its path.
+func writeTestTarball(t *testing.T, entries []tarEntry) string {
+	buf := &bytes.Buffer{}
+	gzWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Mode: 0o600, Linkname: entry.link, Size: int64(len(entry.body))}
+		if entry.typeflag == tar.TypeDir {
+			header.Mode = 0o700
+		}
+
+		require.NoError(t, tarWriter.WriteHeader(header))
+		_, err := tarWriter.Write([]byte(entry.body))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzWriter.Close())
+
+	tarballPath := filepath.Join(t.TempDir(), "test.tar.gz")
+	require.NoError(t, os.WriteFile(tarballPath, buf.Bytes(), 0o600))
+
+	return tarballPath
+}
+
+// Ensures tarball entries are only unpacked within the destination, without writing through symlinks.
+func TestUnpackTarball(t *testing.T) {
+	file := func(name string, body string) tarEntry {
+		return tarEntry{name: name, typeflag: tar.TypeReg, body: body}
+	}
+	dir := func(name string) tarEntry { return tarEntry{name: name, typeflag: tar.TypeDir} }
+	symlink := func(name string, link string) tarEntry {
+		return tarEntry{name: name, typeflag: tar.TypeSymlink, link: link}
+	}
+
+	cases := []struct {
+		name      string
+		entries   []tarEntry
+		expectErr bool
+		files     map[string]string
+		links     map[string]string
+	}{
+		{
+			name:    "Nested directories",
+			entries: []tarEntry{dir("./"), dir("a/"), dir("a/b/"), file("a/b/c.yaml", "c"), file("d/e.yaml", "e")},
+			files:   map[string]string{"a/b/c.yaml": "c", "d/e.yaml": "e"},
+		},
+		{
+			name:    "Dots within names",
+			entries: []tarEntry{file("a..b", "ab"), file("..c", "c")},
+			files:   map[string]string{"a..b": "ab", "..c": "c"},
+		},
+		{name: "Parent reference", entries: []tarEntry{file("../escape", "x")}, expectErr: true},
+		{name: "Nested parent reference", entries: []tarEntry{file("a/../../escape", "x")}, expectErr: true},
+		{name: "Absolute name", entries: []tarEntry{file("/escape", "x")}, expectErr: true},
+		{
+			name:    "Symlink within the archive",
+			entries: []tarEntry{file("a/target", "t"), symlink("a/link", "target"), symlink("b", "a/target")},
+			files:   map[string]string{"a/target": "t"},
+			links:   map[string]string{"a/link": "target", "b": "a/target"},
+		},
+		{name: "Absolute symlink", entries: []tarEntry{symlink("link", "/etc/passwd")}, expectErr: true},
+		{name: "Escaping symlink", entries: []tarEntry{symlink("a/link", "../../escape")}, expectErr: true},
+		{name: "Entry below a symlinked parent", entries: []tarEntry{dir("a/"), symlink("b", "a"), file("b/c", "c")}, expectErr: true},
+		{name: "Entry below a file", entries: []tarEntry{file("a", "a"), file("a/b", "b")}, expectErr: true},
+		{
+			name:    "Symlink replacing an earlier entry",
+			entries: []tarEntry{file("target", "t"), file("link", "l"), symlink("link", "target")},
+			files:   map[string]string{"target": "t"},
+			links:   map[string]string{"link": "target"},
+		},
+		{
+			name:    "File replacing an earlier symlink",
+			entries: []tarEntry{file("target", "t"), symlink("link", "target"), file("link", "l")},
+			files:   map[string]string{"target": "t", "link": "l"},
+		},
+		{name: "Symlink replacing a directory", entries: []tarEntry{dir("a/"), symlink("a", "b")}, expectErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			destRoot := filepath.Join(t.TempDir(), "unpack")
+			err := unpackTarball(writeTestTarball(t, c.entries), destRoot, &sys.OS{}, "")
+			if c.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			for name, body := range c.files {
+				data, err := os.ReadFile(filepath.Join(destRoot, name))
+				require.NoError(t, err)
+				require.Equal(t, body, string(data))
+			}
+
+			for name, target := range c.links {
+				link, err := os.Readlink(filepath.Join(destRoot, name))
+				require.NoError(t, err)
+				require.Equal(t, target, link)
+			}
+		})
+	}
+}
+
+// Ensures the parents of an entry can't be symlinks or files, and may not exist yet.
+func TestCheckUnpackParents(t *testing.T) {
+	destRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(destRoot, "a", "b"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(destRoot, "file"), nil, 0o600))
+	require.NoError(t, os.Symlink("a", filepath.Join(destRoot, "link")))
+
+	require.NoError(t, checkUnpackParents(destRoot, "entry"))
+	require.NoError(t, checkUnpackParents(destRoot, filepath.Join("a", "b", "entry")))
+	require.NoError(t, checkUnpackParents(destRoot, filepath.Join("a", "missing", "entry")))
+	require.Error(t, checkUnpackParents(destRoot, filepath.Join("link", "entry")))
+	require.Error(t, checkUnpackParents(destRoot, filepath.Join("a", "..", "link", "entry")))
+	require.Error(t, checkUnpackParents(destRoot, filepath.Join("file", "entry")))
+}
+
+// Ensures recovery updates the addresses of the cluster members in the trust store, and keeps their DNS names.
+func TestUpdateTrustStore(t *testing.T) {
+	dir := t.TempDir()
+	cert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	address, err := types.ParseAddrPort("10.0.0.1:9000")
+	require.NoError(t, err)
+
+	remotes := trust.NewRemotes(trust.NewFileBackend(dir, nil))
+	require.NoError(t, remotes.Add(trust.Remote{Location: trust.Location{Name: "m1", Address: address, DNSName: "m1.example.com"}, Certificate: types.X509Certificate{Certificate: cert}}))
+
+	err = updateTrustStore(dir, []cluster.DqliteMember{{DqliteID: 1, Address: "10.0.1.1:9000", Role: "voter", Name: "m1"}})
+	require.NoError(t, err)
+
+	remotes, err = readTrustStore(dir)
+	require.NoError(t, err)
+
+	remote := remotes.RemotesByName()["m1"]
+	require.Equal(t, "10.0.1.1:9000", remote.Address.String())
+	require.Equal(t, "m1.example.com", remote.DNSName)
+	require.True(t, remote.Certificate.Equal(cert))
+}
@@ -0,0 +1,262 @@
+package recover
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+	"gopkg.in/yaml.v3"
+
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// snapshotVersion is the version of the layout of member state snapshots.
+const snapshotVersion = 1
+
+// snapshotManifest describes a member state snapshot. It is stored as snapshot.yaml at the root of the archive.
+type snapshotManifest struct {
+	Version   int       `yaml:"version"`
+	CreatedAt time.Time `yaml:"created_at"`
+}
+
+// snapshotStagingDir is the directory within each directory restored from a snapshot that its contents are staged in.
+const snapshotStagingDir = ".snapshot_restore"
+
+// snapshotDirs returns the directories included in a member state snapshot, keyed by their name in the archive.
+func snapshotDirs(filesystem *sys.OS) map[string]string {
+	return map[string]string{
+		"database":     filesystem.DatabaseDir,
+		"truststore":   filesystem.TrustDir,
+		"certificates": filesystem.CertificatesDir,
+	}
+}
+
+// snapshotFiles returns the files of the state directory included in a member state snapshot.
+func snapshotFiles() []string {
+	cluster := string(types.ClusterCertificateName)
+
+	return []string{"daemon.yaml", "server.crt", "server.key", cluster + ".crt", cluster + ".key"}
+}
+
+// CreateSnapshot writes an archive of the state of the cluster member to snapshotPath: its database, trust store,
+// certificates and daemon configuration, so that the member can be rebuilt from it with RestoreSnapshot.
+// The archive contains the private keys of the member, so it is only readable by its owner, and is encrypted if a
+// passphrase is set. It can't be encrypted with the cluster key, as that key is part of the snapshot.
+// The files are archived as they are, so the daemon must be stopped.
+func CreateSnapshot(filesystem *sys.OS, snapshotPath string, encryption ArchiveEncryption) error {
+	manifest, err := yaml.Marshal(snapshotManifest{Version: snapshotVersion, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := os.OpenFile(snapshotPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	encWriter, err := newArchiveWriter(snapshot, filesystem, ArchiveEncryption{Passphrase: encryption.Passphrase})
+	if err != nil {
+		_ = snapshot.Close()
+		return err
+	}
+
+	gzWriter := gzip.NewWriter(encWriter)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	err = tarWriter.WriteHeader(&tar.Header{Name: "snapshot.yaml", Mode: 0o600, Size: int64(len(manifest)), ModTime: time.Now(), Typeflag: tar.TypeReg})
+	if err == nil {
+		_, err = tarWriter.Write(manifest)
+	}
+
+	for name, dir := range snapshotDirs(filesystem) {
+		if err != nil {
+			break
+		}
+
+		err = addTarballEntries(tarWriter, dir, ".", nil, name)
+	}
+
+	for _, name := range snapshotFiles() {
+		if err != nil {
+			break
+		}
+
+		_, statErr := os.Stat(path.Join(filesystem.StateDir, name))
+		if errors.Is(statErr, fs.ErrNotExist) {
+			continue
+		}
+
+		err = addTarballEntries(tarWriter, filesystem.StateDir, name, nil, "")
+	}
+
+	for _, closer := range []io.Closer{tarWriter, gzWriter, encWriter, snapshot} {
+		closeErr := closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil {
+		_ = os.Remove(snapshotPath)
+		return fmt.Errorf("Failed to create member state snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreSnapshot rebuilds the state of a cluster member from the snapshot at snapshotPath, created by CreateSnapshot.
+// The daemon must not be running, and the state directory must not belong to an initialized cluster member.
+// Encrypted snapshots are decrypted with the given passphrase.
+func RestoreSnapshot(filesystem *sys.OS, snapshotPath string, encryption ArchiveEncryption) error {
+	_, err := os.Stat(path.Join(filesystem.DatabaseDir, "info.yaml"))
+	if err == nil {
+		return fmt.Errorf("State directory %q already belongs to a cluster member", filesystem.StateDir)
+	}
+
+	// Unpack within the state directory so that the files can be renamed into place.
+	unpackDir := path.Join(filesystem.StateDir, "snapshot_restore")
+	err = os.RemoveAll(unpackDir)
+	if err != nil {
+		return err
+	}
+
+	err = os.Mkdir(unpackDir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.RemoveAll(unpackDir) }()
+
+	err = unpackTarball(snapshotPath, unpackDir, filesystem, encryption.Passphrase)
+	if err != nil {
+		return fmt.Errorf("Failed to unpack member state snapshot: %w", err)
+	}
+
+	var manifest snapshotManifest
+	err = readYaml(path.Join(unpackDir, "snapshot.yaml"), &manifest)
+	if err != nil {
+		return fmt.Errorf("Invalid member state snapshot: %w", err)
+	}
+
+	if manifest.Version != snapshotVersion {
+		return fmt.Errorf("Unsupported member state snapshot version %d", manifest.Version)
+	}
+
+	logger.Warn("Restoring member state snapshot", logger.Ctx{"snapshot": snapshotPath, "created": manifest.CreatedAt})
+
+	// Stage the contents of each directory within it first, so that they are on its filesystem even if it is a mount
+	// point outside of the state directory. The current contents are only replaced once every directory is staged.
+	stagedDirs := map[string]string{}
+	for name, dir := range snapshotDirs(filesystem) {
+		stagedDir := path.Join(dir, snapshotStagingDir)
+		err = os.RemoveAll(stagedDir)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = os.RemoveAll(stagedDir) }()
+
+		err = stageDir(path.Join(unpackDir, name), stagedDir)
+		if err != nil {
+			return fmt.Errorf("Failed to stage %q: %w", name, err)
+		}
+
+		stagedDirs[name] = stagedDir
+	}
+
+	for name, dir := range snapshotDirs(filesystem) {
+		err = replaceDirContents(dir, stagedDirs[name])
+		if err != nil {
+			return fmt.Errorf("Failed to restore %q: %w", name, err)
+		}
+	}
+
+	for _, name := range snapshotFiles() {
+		src := path.Join(unpackDir, name)
+		_, err := os.Stat(src)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		err = os.Rename(src, path.Join(filesystem.StateDir, name))
+		if err != nil {
+			return fmt.Errorf("Failed to restore %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// stageDir moves the contents of src to dst, which is created along with its parents if needed. Files are renamed
+// when possible, and copied when dst is on another filesystem. An empty dst is created if src does not exist.
+func stageDir(src string, dst string) error {
+	err := os.MkdirAll(dst, 0o700)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return filepath.WalkDir(src, func(srcPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, srcPath)
+		if err != nil || relPath == "." {
+			return err
+		}
+
+		dstPath := filepath.Join(dst, relPath)
+		if entry.IsDir() {
+			return os.MkdirAll(dstPath, 0o700)
+		}
+
+		err = os.Rename(srcPath, dstPath)
+		if err == nil {
+			return nil
+		}
+
+		return copyFile(srcPath, dstPath)
+	})
+}
+
+// copyFile copies the file at src to dst, keeping its permissions.
+func copyFile(src string, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,49 @@
+package access
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// ControlSecretHeader is the header holding the shared secret of requests sent to the control API over TCP.
+const ControlSecretHeader = "X-Microcluster-Control-Secret"
+
+// NewControlSecret returns a random shared secret for the control API over TCP.
+func NewControlSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate control secret: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+// HasControlSecret returns whether the request holds the given shared secret of the control API.
+func HasControlSecret(r *http.Request, secret string) bool {
+	received := r.Header.Get(ControlSecretHeader)
+	if received == "" || secret == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(received), []byte(secret)) == 1
+}
+
+// controlSecretKey is the context key marking requests that held the shared secret of the control API.
+type controlSecretKey struct{}
+
+// SetControlSecretVerified marks the request as having held the shared secret of the control API.
+func SetControlSecretVerified(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), controlSecretKey{}, true))
+}
+
+// ControlSecretVerified returns whether the request held the shared secret of the control API.
+func ControlSecretVerified(r *http.Request) bool {
+	verified, _ := r.Context().Value(controlSecretKey{}).(bool)
+
+	return verified
+}
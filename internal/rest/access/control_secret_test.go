@@ -0,0 +1,29 @@
+package access
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures only requests holding the exact control secret are accepted.
+func TestHasControlSecret(t *testing.T) {
+	secret, err := NewControlSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 64)
+
+	other, err := NewControlSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret, other)
+
+	r := httptest.NewRequest("GET", "/core/control", nil)
+	require.False(t, HasControlSecret(r, secret))
+
+	r.Header.Set(ControlSecretHeader, other)
+	require.False(t, HasControlSecret(r, secret))
+
+	r.Header.Set(ControlSecretHeader, secret)
+	require.True(t, HasControlSecret(r, secret))
+	require.False(t, HasControlSecret(r, ""))
+}
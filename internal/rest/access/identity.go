@@ -0,0 +1,65 @@
+package access
+
+import (
+	"context"
+	"net/http"
+)
+
+// AuthMethod is the way the caller of a request was authenticated.
+type AuthMethod string
+
+const (
+	// AuthMethodNone is used for callers that were not authenticated.
+	AuthMethodNone AuthMethod = "none"
+
+	// AuthMethodUnix is used for callers connected over the unix socket.
+	AuthMethodUnix AuthMethod = "unix"
+
+	// AuthMethodControlSecret is used for callers of the control API over TCP holding its shared secret.
+	AuthMethodControlSecret AuthMethod = "control-secret"
+
+	// AuthMethodPreInit is used for callers of a daemon that is not initialized yet, which are all trusted.
+	AuthMethodPreInit AuthMethod = "pre-init"
+
+	// AuthMethodTLS is used for callers presenting a trusted TLS client certificate.
+	AuthMethodTLS AuthMethod = "tls"
+
+	// AuthMethodBearer is used for callers presenting a bearer token standing for a trusted certificate.
+	AuthMethodBearer AuthMethod = "bearer"
+)
+
+// Identity identifies the caller of a request, as verified by the authentication layer.
+type Identity struct {
+	// Method is the way the caller was authenticated.
+	Method AuthMethod
+
+	// Trusted is whether the caller is trusted.
+	Trusted bool
+
+	// Fingerprint is the fingerprint of the certificate identifying the caller, either presented over TLS or
+	// standing behind a bearer token. It is empty for callers over the unix socket.
+	Fingerprint string
+
+	// Name is the name of the cluster member owning the certificate, if the caller is a trusted cluster member.
+	Name string
+
+	// PeerCredentials identifies the process connected over the unix socket, if known.
+	PeerCredentials *PeerCredentials
+}
+
+// identityKey is the context key under which the Identity of the caller of a request is stored.
+type identityKey struct{}
+
+// SetRequestIdentity records the identity of the caller in the request context, along with its trusted status.
+func SetRequestIdentity(r *http.Request, identity Identity) *http.Request {
+	r = SetRequestAuthentication(r, identity.Trusted)
+
+	return r.WithContext(context.WithValue(r.Context(), identityKey{}, identity))
+}
+
+// GetRequestIdentity returns the identity of the caller of the request, if it went through authentication.
+func GetRequestIdentity(r *http.Request) (Identity, bool) {
+	identity, ok := r.Context().Value(identityKey{}).(Identity)
+
+	return identity, ok
+}
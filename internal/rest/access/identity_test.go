@@ -0,0 +1,36 @@
+package access
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures the identity of the caller is recorded along with its trusted status.
+func TestSetRequestIdentity(t *testing.T) {
+	r := httptest.NewRequest("GET", "/core/1.0", nil)
+	_, ok := GetRequestIdentity(r)
+	require.False(t, ok)
+
+	identity := Identity{Method: AuthMethodTLS, Trusted: true, Fingerprint: "abcd", Name: "member01"}
+	r = SetRequestIdentity(r, identity)
+
+	got, ok := GetRequestIdentity(r)
+	require.True(t, ok)
+	require.Equal(t, identity, got)
+	require.Equal(t, TrustedRequest{Trusted: true}, r.Context().Value(request.CtxAccess))
+
+	r = SetRequestIdentity(r, Identity{Method: AuthMethodNone})
+	require.Equal(t, TrustedRequest{Trusted: false}, r.Context().Value(request.CtxAccess))
+}
+
+// Ensures requests are only marked as holding the control secret once verified.
+func TestControlSecretVerified(t *testing.T) {
+	r := httptest.NewRequest("GET", "/core/control", nil)
+	require.False(t, ControlSecretVerified(r))
+
+	r = SetControlSecretVerified(r)
+	require.True(t, ControlSecretVerified(r))
+}
@@ -0,0 +1,25 @@
+package access
+
+import (
+	"context"
+	"net/http"
+)
+
+// listenerAddressKey is the context key of the address of the listener that accepted a request, for listeners other
+// than the core API listener.
+type listenerAddressKey struct{}
+
+// WithListenerAddress returns a handler that records the given address of its listener in the context of each request,
+// so that requests are authenticated against it rather than against the core API address.
+func WithListenerAddress(handler http.Handler, address string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), listenerAddressKey{}, address)))
+	})
+}
+
+// ListenerAddress returns the address of the listener that accepted the request, if it was recorded.
+func ListenerAddress(r *http.Request) (string, bool) {
+	address, ok := r.Context().Value(listenerAddressKey{}).(string)
+
+	return address, ok
+}
@@ -0,0 +1,25 @@
+package access
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures the address of the listener is only recorded for requests passing through its handler.
+func TestListenerAddress(t *testing.T) {
+	r := httptest.NewRequest("GET", "/core/1.0", nil)
+	_, ok := ListenerAddress(r)
+	require.False(t, ok)
+
+	var address string
+	handler := WithListenerAddress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		address, ok = ListenerAddress(r)
+	}), "127.0.0.1:9444")
+
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	require.True(t, ok)
+	require.Equal(t, "127.0.0.1:9444", address)
+}
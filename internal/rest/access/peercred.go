@@ -0,0 +1,51 @@
+package access
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"golang.org/x/sys/unix"
+)
+
+// PeerCredentials identifies the process on the other end of a unix socket connection.
+type PeerCredentials struct {
+	UID uint32
+	GID uint32
+	PID int32
+}
+
+// peerCredentialsKey is the context key under which the PeerCredentials of a connection are stored.
+type peerCredentialsKey struct{}
+
+// PeerCredentialsContext records the credentials of the process connected over the given unix socket connection
+// in the connection context. It is meant to be used as the ConnContext of an http.Server.
+func PeerCredentialsContext(ctx context.Context, conn net.Conn) context.Context {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return ctx
+	}
+
+	var ucred *unix.Ucred
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		ucred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil || credErr != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, peerCredentialsKey{}, PeerCredentials{UID: ucred.Uid, GID: ucred.Gid, PID: ucred.Pid})
+}
+
+// GetPeerCredentials returns the credentials of the process that sent the request over the unix socket, if known.
+func GetPeerCredentials(r *http.Request) (PeerCredentials, bool) {
+	creds, ok := r.Context().Value(peerCredentialsKey{}).(PeerCredentials)
+
+	return creds, ok
+}
@@ -0,0 +1,93 @@
+package access
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sessionToken is the payload of a bearer token.
+type sessionToken struct {
+	Fingerprint string `json:"fingerprint"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
+
+// SessionKey derives the key signing bearer tokens from the private key of the cluster certificate, so that tokens
+// issued by any cluster member are accepted by all of them.
+func SessionKey(clusterKey []byte) []byte {
+	mac := hmac.New(sha256.New, clusterKey)
+	mac.Write([]byte("microcluster-session"))
+
+	return mac.Sum(nil)
+}
+
+// NewSessionToken returns a bearer token signed with the key, standing for the client certificate with the given
+// fingerprint until the expiry.
+func NewSessionToken(key []byte, fingerprint string, expiresAt time.Time) (string, error) {
+	payload, err := json.Marshal(sessionToken{Fingerprint: fingerprint, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signSession(key, encoded)), nil
+}
+
+// ParseSessionToken checks that the bearer token was signed with the key and has not expired, and returns the
+// fingerprint of the client certificate it stands for.
+func ParseSessionToken(key []byte, token string, now time.Time) (string, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", fmt.Errorf("Malformed bearer token")
+	}
+
+	rawSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", fmt.Errorf("Malformed bearer token signature: %w", err)
+	}
+
+	if !hmac.Equal(rawSignature, signSession(key, encoded)) {
+		return "", fmt.Errorf("Invalid bearer token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("Malformed bearer token payload: %w", err)
+	}
+
+	session := sessionToken{}
+	err = json.Unmarshal(payload, &session)
+	if err != nil {
+		return "", fmt.Errorf("Malformed bearer token payload: %w", err)
+	}
+
+	if now.Unix() >= session.ExpiresAt {
+		return "", fmt.Errorf("Bearer token has expired")
+	}
+
+	return session.Fingerprint, nil
+}
+
+// GetBearerToken returns the bearer token from the Authorization header of the request, if any.
+func GetBearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
+
+// signSession returns the signature of the encoded token payload.
+func signSession(key []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+
+	return mac.Sum(nil)
+}
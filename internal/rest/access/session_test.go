@@ -0,0 +1,51 @@
+package access
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionToken(t *testing.T) {
+	key := SessionKey([]byte("cluster key"))
+	now := time.Now()
+
+	token, err := NewSessionToken(key, "abcd", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	fingerprint, err := ParseSessionToken(key, token, now)
+	require.NoError(t, err)
+	require.Equal(t, "abcd", fingerprint)
+
+	// Expired tokens are rejected.
+	_, err = ParseSessionToken(key, token, now.Add(2*time.Hour))
+	require.Error(t, err)
+
+	// Tokens signed by another cluster are rejected.
+	_, err = ParseSessionToken(SessionKey([]byte("other key")), token, now)
+	require.Error(t, err)
+
+	// Tampered tokens are rejected.
+	forged, err := NewSessionToken(key, "efgh", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	_, err = ParseSessionToken(key, forged[:len(forged)/2]+token[len(token)/2:], now)
+	require.Error(t, err)
+}
+
+func TestGetBearerToken(t *testing.T) {
+	r := &http.Request{Header: http.Header{}}
+	_, ok := GetBearerToken(r)
+	require.False(t, ok)
+
+	r.Header.Set("Authorization", "Basic abcd")
+	_, ok = GetBearerToken(r)
+	require.False(t, ok)
+
+	r.Header.Set("Authorization", "Bearer abcd")
+	token, ok := GetBearerToken(r)
+	require.True(t, ok)
+	require.Equal(t, "abcd", token)
+}
@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/logger"
+
+	internalAccess "github.com/canonical/microcluster/v3/internal/rest/access"
+	"github.com/canonical/microcluster/v3/internal/rest/client"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// auditResponseWriter records the status code of the response.
+type auditResponseWriter struct {
+	http.ResponseWriter
+
+	statusCode int
+}
+
+// WriteHeader records the status code before writing it.
+func (w *auditResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records an implicit 200 status code if no header was written yet.
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends any buffered data to the client, so that handlers can flush a response before acting on it.
+func (w *auditResponseWriter) Flush() {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer, so that the deadlines of the connection can be set through it.
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack lets handlers take over the connection, as when upgrading it.
+func (w *auditResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Webserver does not support hijacking")
+	}
+
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusSwitchingProtocols
+	}
+
+	return hijacker.Hijack()
+}
+
+// isAudited returns whether the request changes state and must be recorded in the audit log.
+func isAudited(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPut, http.MethodPost, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// auditRequest records the request and its result in the audit log of the daemon, if enabled.
+func auditRequest(s *internalState.InternalState, r *http.Request, statusCode int, start time.Time) {
+	if s.AuditLog == nil {
+		return
+	}
+
+	entry := types.AuditEntry{
+		Time:        start,
+		Caller:      auditCaller(s, r),
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Query:       r.URL.RawQuery,
+		RequestSize: r.ContentLength,
+		StatusCode:  statusCode,
+		Duration:    time.Since(start),
+	}
+
+	err := s.AuditLog.Record(entry)
+	if err != nil {
+		logger.Error("Failed to record request in audit log", logger.Ctx{"method": r.Method, "url": r.URL.Path, "error": err})
+	}
+}
+
+// auditCaller identifies the caller of the request.
+func auditCaller(s *internalState.InternalState, r *http.Request) types.AuditCaller {
+	caller := types.AuditCaller{}
+	if r.RemoteAddr == "@" {
+		caller.Protocol = types.AuditCallerUnix
+
+		creds, ok := internalAccess.GetPeerCredentials(r)
+		if ok {
+			caller.UID = &creds.UID
+			caller.PID = &creds.PID
+		}
+	} else {
+		caller.Protocol = types.AuditCallerTLS
+		caller.Address = r.RemoteAddr
+
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			caller.Fingerprint = shared.CertFingerprint(r.TLS.PeerCertificates[0])
+
+			remote := s.Remotes().RemoteByCertificateFingerprint(caller.Fingerprint)
+			if remote != nil {
+				caller.Name = remote.Name
+			}
+		}
+	}
+
+	if client.IsForwardedRequest(r) {
+		caller.ForwardedAddress = r.Header.Get(request.HeaderForwardedAddress)
+		caller.ForwardedUsername = r.Header.Get(request.HeaderForwardedUsername)
+	}
+
+	return caller
+}
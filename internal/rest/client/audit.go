@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetAuditLog returns the audit log entries of the cluster member recorded since the given time, most recent first.
+// Supported filters are method, path and status_code.
+func (c *Client) GetAuditLog(ctx context.Context, since time.Time, opts types.ListOptions) ([]types.AuditEntry, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	values := opts.Values()
+	if !since.IsZero() {
+		values.Set("since", since.Format(time.RFC3339))
+	}
+
+	endpoint := api.NewURL().Path("audit")
+	endpoint.URL.RawQuery = values.Encode()
+
+	entries := []types.AuditEntry{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, endpoint, nil, &entries)
+
+	return entries, err
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -24,6 +25,9 @@ import (
 	"github.com/canonical/lxd/shared/tcp"
 	"github.com/gorilla/websocket"
 
+	"github.com/canonical/microcluster/v3/internal/rest/access"
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/internal/tracing"
 	"github.com/canonical/microcluster/v3/rest/types"
 )
 
@@ -31,6 +35,22 @@ import (
 type Client struct {
 	*http.Client
 	url api.URL
+
+	retryPolicy *RetryPolicy
+
+	// interceptors wrap the requests sent by the client.
+	interceptors []Interceptor
+
+	// clientCert and remoteCert identify the shared transport used by the client.
+	clientCert *shared.CertInfo
+	remoteCert *x509.Certificate
+
+	// extensions caches the API extensions supported by the cluster, and is shared with clients derived from this one.
+	extensions *extensionsCache
+
+	// publicEndpoint is the version of the public core API negotiated with the daemon, used in place of
+	// /core/1.0. It is empty until negotiated.
+	publicEndpoint types.EndpointPrefix
 }
 
 // New returns a new client configured with the given url and certificates.
@@ -39,16 +59,15 @@ func New(url api.URL, clientCert *shared.CertInfo, remoteCert *x509.Certificate,
 	var httpClient *http.Client
 
 	// If the url is an absolute path to the control.socket, return a client to the local unix socket.
+	// Abstract sockets are not files, so their name is used as is.
 	if strings.HasSuffix(url.String(), "control.socket") && path.IsAbs(url.Hostname()) {
 		httpClient, err = unixHTTPClient(shared.HostPath(url.Hostname()))
 		url.Host(filepath.Base(url.Hostname()))
+	} else if strings.HasPrefix(url.Hostname(), "@") {
+		httpClient, err = unixHTTPClient(url.Hostname())
+		url.Host("control.socket")
 	} else {
-		proxy := shared.ProxyFromEnvironment
-		if forwarding {
-			proxy = forwardingProxy
-		}
-
-		httpClient, err = tlsHTTPClient(clientCert, remoteCert, proxy)
+		httpClient, err = tlsHTTPClient(clientCert, remoteCert, forwarding)
 	}
 
 	if err != nil {
@@ -56,8 +75,11 @@ func New(url api.URL, clientCert *shared.CertInfo, remoteCert *x509.Certificate,
 	}
 
 	return &Client{
-		Client: httpClient,
-		url:    url,
+		Client:     httpClient,
+		url:        url,
+		clientCert: clientCert,
+		remoteCert: remoteCert,
+		extensions: &extensionsCache{},
 	}, nil
 }
 
@@ -93,7 +115,28 @@ func unixHTTPClient(path string) (*http.Client, error) {
 	return client, nil
 }
 
-func tlsHTTPClient(clientCert *shared.CertInfo, remoteCert *x509.Certificate, proxy func(req *http.Request) (*url.URL, error)) (*http.Client, error) {
+func tlsHTTPClient(clientCert *shared.CertInfo, remoteCert *x509.Certificate, forwarding bool) (*http.Client, error) {
+	transport, err := sharedTransport(clientCert, remoteCert, forwarding)
+	if err != nil {
+		return nil, err
+	}
+
+	// Define the http client
+	client := &http.Client{Transport: transport}
+
+	// Setup redirect policy
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		// Replicate the headers
+		req.Header = via[len(via)-1].Header
+
+		return nil
+	}
+
+	return client, nil
+}
+
+// tlsTransport returns a new transport for connections with the given certificates, tuned according to the options.
+func tlsTransport(clientCert *shared.CertInfo, remoteCert *x509.Certificate, forwarding bool, options TransportOptions) (*http.Transport, error) {
 	var tlsConfig *tls.Config
 	if remoteCert != nil {
 		var err error
@@ -105,69 +148,124 @@ func tlsHTTPClient(clientCert *shared.CertInfo, remoteCert *x509.Certificate, pr
 
 	tlsDialContext := func(t *http.Transport) func(context.Context, string, string) (net.Conn, error) {
 		return func(ctx context.Context, network string, addr string) (net.Conn, error) {
-			host, port, err := net.SplitHostPort(addr)
+			conn, err := dialResolved(ctx, addr, options.Resolver, func(ctx context.Context, addr string) (net.Conn, error) {
+				return dialTLS(ctx, network, addr, t.TLSClientConfig, options.Proxy)
+			})
 			if err != nil {
 				return nil, err
 			}
 
-			addrs, err := net.LookupHost(host)
+			tcpConn, err := tcp.ExtractConn(conn)
 			if err != nil {
 				return nil, err
 			}
 
-			var lastErr error
-			for _, a := range addrs {
-				dialer := tls.Dialer{NetDialer: &net.Dialer{}, Config: t.TLSClientConfig}
-				conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(a, port))
-				if err != nil {
-					lastErr = err
-					continue
-				}
-
-				tcpConn, err := tcp.ExtractConn(conn)
-				if err != nil {
-					return nil, err
-				}
-
-				err = tcp.SetTimeouts(tcpConn, 0)
-				if err != nil {
-					return nil, err
-				}
-
-				return conn, nil
+			err = tcp.SetTimeouts(tcpConn, 0)
+			if err != nil {
+				return nil, err
 			}
 
-			return nil, fmt.Errorf("Unable to connect to %q: %w", addr, lastErr)
+			return conn, nil
+		}
+	}
+
+	proxy := shared.ProxyFromEnvironment
+	if options.Proxy != nil {
+		// Connections through the configured proxy are made by the dialer, so the transport itself connects directly.
+		proxy = func(r *http.Request) (*url.URL, error) { return nil, nil }
+	}
+
+	if forwarding {
+		next := proxy
+		proxy = func(r *http.Request) (*url.URL, error) {
+			setForwardingHeaders(r)
+			return next(r)
 		}
 	}
 
+	if tlsConfig != nil && options.TLSSessionCacheSize >= 0 {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(cmp.Or(options.TLSSessionCacheSize, DefaultTLSSessionCacheSize))
+	}
+
 	transport := &http.Transport{
-		TLSClientConfig:   tlsConfig,
-		DisableKeepAlives: true,
-		Proxy:             proxy,
+		TLSClientConfig:     tlsConfig,
+		DisableKeepAlives:   options.MaxIdleConnsPerHost < 0,
+		MaxIdleConnsPerHost: max(cmp.Or(options.MaxIdleConnsPerHost, DefaultMaxIdleConnsPerHost), 0),
+		IdleConnTimeout:     cmp.Or(options.IdleConnTimeout, DefaultIdleConnTimeout),
+		Proxy:               proxy,
 	}
 
-	// Define the http client
-	client := &http.Client{Transport: transport}
 	transport.DialTLSContext = tlsDialContext(transport)
 
-	// Setup redirect policy
-	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		// Replicate the headers
-		req.Header = via[len(via)-1].Header
+	return transport, nil
+}
 
-		return nil
+// dialTLS establishes a TLS connection with the given address, through the given proxy if it is set.
+func dialTLS(ctx context.Context, network string, addr string, config *tls.Config, proxyURL *url.URL) (net.Conn, error) {
+	if proxyURL != nil {
+		proxyConn, err := dialProxy(ctx, proxyURL, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := tlsClient(ctx, proxyConn, config, addr)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to connect to %q: %w", addr, err)
+		}
+
+		return conn, nil
 	}
 
-	return client, nil
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := net.LookupHost(host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Race the resolved addresses, alternating between IPv6 and IPv4, so that dual-stack hosts are
+	// reachable even if one address family is unroutable.
+	conn, err := dialParallel(ctx, interleaveAddresses(addrs), func(ctx context.Context, a string) (net.Conn, error) {
+		dialer := tls.Dialer{NetDialer: &net.Dialer{}, Config: config}
+		return dialer.DialContext(ctx, network, net.JoinHostPort(a, port))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to %q: %w", addr, err)
+	}
+
+	return conn, nil
 }
 
 // SetClusterNotification sets the client's proxy to apply the forwarding headers to a request.
 func (c *Client) SetClusterNotification() {
-	c.Transport.(*http.Transport).Proxy = forwardingProxy
+	// Transports over the unix socket are not shared, so they can be modified directly.
+	transport := c.Transport.(*http.Transport)
+	if transport.DialTLSContext == nil {
+		transport.Proxy = forwardingProxy
+		return
+	}
+
+	// The transport is shared with other clients, so switch to the forwarding one instead of modifying it.
+	transport, err := sharedTransport(c.clientCert, c.remoteCert, true)
+	if err != nil {
+		logger.Error("Failed to set up forwarding transport", logger.Ctx{"address": c.url.URL.Host, "error": err})
+		return
+	}
+
+	c.Client = &http.Client{Transport: transport, CheckRedirect: c.CheckRedirect}
 }
 
 func forwardingProxy(r *http.Request) (*url.URL, error) {
+	setForwardingHeaders(r)
+
+	return shared.ProxyFromEnvironment(r)
+}
+
+// setForwardingHeaders marks the request as forwarded from another cluster member, on behalf of its original caller.
+func setForwardingHeaders(r *http.Request) {
 	r.Header.Set("User-Agent", clusterRequest.UserAgentNotifier)
 
 	ctx := r.Context()
@@ -182,8 +280,6 @@ func forwardingProxy(r *http.Request) (*url.URL, error) {
 	}
 
 	r.Header.Add(request.HeaderForwardedAddress, r.RemoteAddr)
-
-	return shared.ProxyFromEnvironment(r)
 }
 
 // IsForwardedRequest determines if this request has been forwarded from another cluster member.
@@ -244,13 +340,33 @@ func (c *Client) rawQuery(ctx context.Context, method string, url *api.URL, data
 		}
 	}
 
-	return c.MakeRequest(req)
+	return c.makeRequestWithRetry(req)
 }
 
 // MakeRequest performs a request and parses the response into an api.Response.
+// The request is traced, and the trace is propagated to the daemon in the request headers.
 func (c *Client) MakeRequest(r *http.Request) (*api.Response, error) {
+	r, span := tracing.StartClientSpan(r, r.Method+" "+r.URL.Path)
+	resp, err := c.makeRequest(r)
+	tracing.End(span, err)
+
+	return resp, err
+}
+
+// MakeRawRequest performs a request and returns the response as received, without parsing it.
+// The request is traced as with MakeRequest. The caller must close the body of the response.
+func (c *Client) MakeRawRequest(r *http.Request) (*http.Response, error) {
+	r, span := tracing.StartClientSpan(r, r.Method+" "+r.URL.Path)
+	resp, err := c.do(r)
+	tracing.End(span, err)
+
+	return resp, err
+}
+
+// makeRequest sends the request and parses the response.
+func (c *Client) makeRequest(r *http.Request) (*api.Response, error) {
 	// Send the request
-	resp, err := c.Do(r)
+	resp, err := c.do(r)
 	if err != nil {
 		return nil, err
 	}
@@ -277,6 +393,11 @@ func (c *Client) mergeURL(endpointType types.EndpointPrefix, endpoint *api.URL)
 		localURL = &newURL
 	}
 
+	// Requests to the public core API use the version negotiated with the daemon.
+	if endpointType == internalTypes.PublicEndpoint && c.publicEndpoint != "" {
+		endpointType = c.publicEndpoint
+	}
+
 	localURL.URL.Host = c.url.URL.Host
 	localURL.URL.Scheme = c.url.URL.Scheme
 	localURL.URL.Path = filepath.Join("/", string(endpointType), localURL.URL.Path)
@@ -325,8 +446,17 @@ func (c *Client) RawWebsocket(ctx context.Context, endpointType types.EndpointPr
 	// Merge the provided URL with the one we have for the client.
 	localURL := c.mergeURL(endpointType, endpoint)
 
+	return c.DialWebsocket(ctx, localURL)
+}
+
+// DialWebsocket dials the provided URL, including its scheme and host, and tries to upgrade the connection.
+func (c *Client) DialWebsocket(ctx context.Context, endpoint *api.URL) (*websocket.Conn, error) {
+	// Get a new local struct to avoid modifying the provided one.
+	newURL := *endpoint
+	localURL := &newURL
+
 	// Pick the right scheme based on the client configuration.
-	if c.url.URL.Scheme == "http" {
+	if localURL.URL.Scheme == "http" {
 		localURL.URL.Scheme = "ws"
 	} else {
 		localURL.URL.Scheme = "wss"
@@ -356,7 +486,9 @@ func (c *Client) RawWebsocket(ctx context.Context, endpointType types.EndpointPr
 	}
 
 	// Establish the connection
-	conn, resp, err := dialer.DialContext(ctx, localURL.String(), nil)
+	header := http.Header{}
+	tracing.Inject(ctx, header)
+	conn, resp, err := dialer.DialContext(ctx, localURL.String(), header)
 	if err != nil {
 		if resp != nil {
 			_, err := parseResponse(resp)
@@ -388,7 +520,31 @@ func (c *Client) UseTarget(name string) *Client {
 	localURL = localURL.WithQuery("target", name)
 
 	return &Client{
-		Client: c.Client,
-		url:    *localURL,
+		Client:       c.Client,
+		url:          *localURL,
+		retryPolicy:  c.retryPolicy,
+		interceptors: c.interceptors,
+		clientCert:   c.clientCert,
+		remoteCert:   c.remoteCert,
+		extensions:   c.extensions,
+
+		publicEndpoint: c.publicEndpoint,
 	}
 }
+
+// NewControlTCP returns a client to the control API served by the daemon over the given loopback TCP address, which
+// sends the shared secret of the daemon with every request.
+func NewControlTCP(address types.AddrPort, secret string) (*Client, error) {
+	c, err := New(*api.NewURL().Scheme("http").Host(address.String()), nil, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.AddInterceptors(func(r *http.Request, next func(r *http.Request) (*http.Response, error)) (*http.Response, error) {
+		r.Header.Set(access.ControlSecretHeader, secret)
+
+		return next(r)
+	})
+
+	return c, nil
+}
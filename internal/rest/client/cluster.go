@@ -24,6 +24,21 @@ func AddClusterMember(ctx context.Context, c *Client, args types.ClusterMember)
 	return &tokenResponse, nil
 }
 
+// PreflightClusterMember runs the checks of a join request against the cluster member, without recording the
+// prospective member.
+func PreflightClusterMember(ctx context.Context, c *Client, args types.ClusterMember) (*internalTypes.JoinPreflightResponse, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	preflight := internalTypes.JoinPreflightResponse{}
+	err := c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("preflight"), args, &preflight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preflight, nil
+}
+
 // ResetClusterMember clears the state directory of the cluster member, and re-execs its daemon.
 func ResetClusterMember(ctx context.Context, c *Client, name string, force bool) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -37,13 +52,31 @@ func ResetClusterMember(ctx context.Context, c *Client, name string, force bool)
 	return c.QueryStruct(queryCtx, "PUT", internalTypes.InternalEndpoint, endpoint, nil, nil)
 }
 
+// UpdateClusterMemberCertificate notifies the cluster member that the certificate identifying the named cluster member
+// has been renewed. Only the named cluster member can send the notification.
+func UpdateClusterMemberCertificate(ctx context.Context, c *Client, name string, cert types.X509Certificate) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "PUT", internalTypes.InternalEndpoint, api.NewURL().Path("cluster", name, "certificate"), cert, nil)
+}
+
 // GetClusterMembers returns the database record of cluster members.
 func (c *Client) GetClusterMembers(ctx context.Context) ([]types.ClusterMember, error) {
+	return c.GetClusterMembersWithOptions(ctx, types.ListOptions{})
+}
+
+// GetClusterMembersWithOptions returns the cluster members selected by the list options.
+// Supported filters are name, address, role and status. Fields not selected by the options are left unset.
+func (c *Client) GetClusterMembersWithOptions(ctx context.Context, opts types.ListOptions) ([]types.ClusterMember, error) {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
+	endpoint := api.NewURL().Path("cluster")
+	endpoint.URL.RawQuery = opts.Values().Encode()
+
 	clusterMembers := []types.ClusterMember{}
-	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("cluster"), nil, &clusterMembers)
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, endpoint, nil, &clusterMembers)
 
 	return clusterMembers, err
 }
@@ -61,7 +94,24 @@ func (c *Client) DeleteClusterMember(ctx context.Context, name string, force boo
 	return c.QueryStruct(queryCtx, "DELETE", internalTypes.PublicEndpoint, endpoint, nil, nil)
 }
 
-// UpdateCertificate sets a new keypair and CA.
+// RenameClusterMember changes the name of the cluster member with the given name.
+func (c *Client) RenameClusterMember(ctx context.Context, name string, newName string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, api.NewURL().Path("cluster", name), types.ClusterMemberRename{Name: newName}, nil)
+}
+
+// SetClusterMemberMaintenance puts the cluster member with the given name under maintenance, or takes it out of maintenance.
+func (c *Client) SetClusterMemberMaintenance(ctx context.Context, name string, enabled bool) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "PUT", internalTypes.PublicEndpoint, api.NewURL().Path("cluster", name, "maintenance"), types.ClusterMemberMaintenance{Enabled: enabled}, nil)
+}
+
+// UpdateCertificate sets a new keypair and CA. Unless the request is a cluster notification, the keypair is rotated
+// on all cluster members in two phases: it is staged and verified everywhere before being committed.
 func (c *Client) UpdateCertificate(ctx context.Context, name types.CertificateName, args types.KeyPair) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -69,3 +119,37 @@ func (c *Client) UpdateCertificate(ctx context.Context, name types.CertificateNa
 	endpoint := api.NewURL().Path("cluster", "certificates", string(name))
 	return c.QueryStruct(queryCtx, "PUT", internalTypes.PublicEndpoint, endpoint, args, nil)
 }
+
+// StageCertificate stages a new keypair and CA on the cluster member without loading it, and returns the fingerprint
+// of the staged certificate.
+func (c *Client) StageCertificate(ctx context.Context, name types.CertificateName, args types.KeyPair) (string, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	status := types.CertificateRotationStatus{}
+	endpoint := api.NewURL().Path("cluster", "certificates", string(name), "rotation")
+	err := c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, endpoint, types.CertificateRotation{Phase: types.CertificateRotationStage, KeyPair: args}, &status)
+	if err != nil {
+		return "", err
+	}
+
+	return status.Fingerprint, nil
+}
+
+// CommitCertificate replaces the active keypair with the staged one, provided the staged certificate has the given fingerprint.
+func (c *Client) CommitCertificate(ctx context.Context, name types.CertificateName, fingerprint string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	endpoint := api.NewURL().Path("cluster", "certificates", string(name), "rotation")
+	return c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, endpoint, types.CertificateRotation{Phase: types.CertificateRotationCommit, Fingerprint: fingerprint}, nil)
+}
+
+// AbortCertificate discards the staged keypair.
+func (c *Client) AbortCertificate(ctx context.Context, name types.CertificateName) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	endpoint := api.NewURL().Path("cluster", "certificates", string(name), "rotation")
+	return c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, endpoint, types.CertificateRotation{Phase: types.CertificateRotationAbort}, nil)
+}
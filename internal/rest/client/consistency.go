@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/v3/rest/types"
+)
+
+// CheckConsistency cross-checks the trust store and go-dqlite yaml files of the cluster member against the cluster
+// membership, and reports any mismatch. If repair is true, the mismatches are repaired where possible.
+func CheckConsistency(ctx context.Context, c *Client, repair bool) (*apiTypes.ConsistencyReport, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	method := "GET"
+	if repair {
+		method = "POST"
+	}
+
+	report := &apiTypes.ConsistencyReport{}
+	err := c.QueryStruct(queryCtx, method, types.InternalEndpoint, api.NewURL().Path("consistency"), nil, report)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
@@ -2,11 +2,64 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 
-	"github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
 )
 
-// ControlDaemon posts control data to the daemon.
-func (c *Client) ControlDaemon(ctx context.Context, args types.Control) error {
-	return c.QueryStruct(ctx, "POST", types.ControlEndpoint, nil, args, nil)
+// ControlDaemon posts control data to the daemon, and waits for the daemon to bootstrap or join the cluster.
+// The initialization is cancelled if the context is cancelled.
+func (c *Client) ControlDaemon(ctx context.Context, args internalTypes.Control) error {
+	_, err := c.queryOperation(ctx, "POST", internalTypes.ControlEndpoint, nil, args)
+
+	return err
+}
+
+// PreflightJoin asks the daemon to check whether it can join a cluster with the name, address and join token of the
+// control data, without joining it.
+func (c *Client) PreflightJoin(ctx context.Context, args internalTypes.Control) (*types.JoinPreflight, error) {
+	preflight := types.JoinPreflight{}
+	err := c.QueryStruct(ctx, "POST", internalTypes.ControlEndpoint, api.NewURL().Path("preflight"), args, &preflight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preflight, nil
+}
+
+// WatchInitProgress streams the stages reached by the daemon while it bootstraps or joins a cluster to handler, until
+// the initialization completes or the context is cancelled. subscribed is called once the daemon reports the stages to
+// the stream, after which the initialization request can be sent.
+func (c *Client) WatchInitProgress(ctx context.Context, subscribed func(), handler func(progress types.InitProgress)) error {
+	_, err := c.RawEventStream(ctx, internalTypes.ControlEndpoint, api.NewURL().Path("progress"), "", func(event types.ServerSentEvent) error {
+		switch event.Type {
+		case "subscribed":
+			subscribed()
+		case "progress":
+			progress := types.InitProgress{}
+			err := json.Unmarshal(event.Data, &progress)
+			if err != nil {
+				return fmt.Errorf("Failed to parse initialization progress: %w", err)
+			}
+
+			handler(progress)
+		case "error":
+			var msg string
+			err := json.Unmarshal(event.Data, &msg)
+			if err != nil {
+				return fmt.Errorf("Failed to parse event stream error: %w", err)
+			}
+
+			return errors.New(msg)
+		}
+
+		return nil
+	})
+
+	return err
 }
@@ -10,6 +10,34 @@ import (
 	apiTypes "github.com/canonical/microcluster/v3/rest/types"
 )
 
+// GetDaemonInfo returns runtime information about the daemon of the cluster member.
+func GetDaemonInfo(ctx context.Context, c *Client) (*apiTypes.DaemonInfo, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	info := &apiTypes.DaemonInfo{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("daemon"), nil, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// GetClusterDaemonInfo returns runtime information about the daemon of every cluster member.
+func GetClusterDaemonInfo(ctx context.Context, c *Client) ([]apiTypes.DaemonInfo, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	infos := []apiTypes.DaemonInfo{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("daemon").WithQuery("target", "all"), nil, &infos)
+	if err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
 // UpdateServers updates the additional servers config.
 func (c *Client) UpdateServers(ctx context.Context, config map[string]apiTypes.ServerConfig) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -18,3 +46,47 @@ func (c *Client) UpdateServers(ctx context.Context, config map[string]apiTypes.S
 	endpoint := api.NewURL().Path("daemon", "servers")
 	return c.QueryStruct(queryCtx, "PUT", types.PublicEndpoint, endpoint, config, nil)
 }
+
+// GetRuntimeConfig returns the runtime-tunable daemon config.
+func (c *Client) GetRuntimeConfig(ctx context.Context) (*apiTypes.RuntimeConfig, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	config := &apiTypes.RuntimeConfig{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("daemon", "config"), nil, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// UpdateRuntimeConfig updates the runtime-tunable daemon config.
+func (c *Client) UpdateRuntimeConfig(ctx context.Context, config apiTypes.RuntimeConfig) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "PUT", types.PublicEndpoint, api.NewURL().Path("daemon", "config"), config, nil)
+}
+
+// GetLogConfig returns the logging configuration of the daemon.
+func (c *Client) GetLogConfig(ctx context.Context) (*apiTypes.LogConfig, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	config := &apiTypes.LogConfig{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("daemon", "log"), nil, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// UpdateLogConfig changes the level and target of the daemon's log. Unset fields are left unchanged.
+func (c *Client) UpdateLogConfig(ctx context.Context, config apiTypes.LogConfig) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "PUT", types.PublicEndpoint, api.NewURL().Path("daemon", "log"), config, nil)
+}
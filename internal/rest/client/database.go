@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/v3/rest/types"
+)
+
+// RollbackSchema reverts external schema updates until the given external schema version is reached.
+func RollbackSchema(ctx context.Context, c *Client, args types.SchemaRollback) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", types.InternalEndpoint, api.NewURL().Path("database", "rollback"), args, nil)
+}
+
+// GetDatabaseStats returns statistics about the statements run against the database by the cluster member.
+func GetDatabaseStats(ctx context.Context, c *Client) (*apiTypes.DatabaseStats, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	stats := &apiTypes.DatabaseStats{}
+	err := c.QueryStruct(queryCtx, "GET", types.InternalEndpoint, api.NewURL().Path("database", "stats"), nil, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetDatabaseSchema returns the schema versions of the cluster member and of every cluster member recorded in the
+// database, along with the statements creating the database schema.
+func GetDatabaseSchema(ctx context.Context, c *Client) (*apiTypes.DatabaseSchema, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	schema := &apiTypes.DatabaseSchema{}
+	err := c.QueryStruct(queryCtx, "GET", types.InternalEndpoint, api.NewURL().Path("database", "schema"), nil, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// GetDatabaseBackfills returns the progress of the data backfills of the cluster.
+func GetDatabaseBackfills(ctx context.Context, c *Client) ([]apiTypes.DatabaseBackfill, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	backfills := []apiTypes.DatabaseBackfill{}
+	err := c.QueryStruct(queryCtx, "GET", types.InternalEndpoint, api.NewURL().Path("database", "backfills"), nil, &backfills)
+	if err != nil {
+		return nil, err
+	}
+
+	return backfills, nil
+}
+
+// GetDatabaseUsage returns the space used by the database, and its disk usage on each cluster member.
+// If the client is a notifier, only the disk usage of the cluster member it is connected to is returned.
+func GetDatabaseUsage(ctx context.Context, c *Client) (*apiTypes.DatabaseUsage, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	usage := &apiTypes.DatabaseUsage{}
+	err := c.QueryStruct(queryCtx, "GET", types.InternalEndpoint, api.NewURL().Path("database", "maintenance"), nil, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// MaintainDatabase runs the given maintenance on the database through the dqlite leader, and returns the space used
+// by the database afterwards. A vacuum rewrites the whole database, so the request is only bound by the context.
+func MaintainDatabase(ctx context.Context, c *Client, maintenance apiTypes.DatabaseMaintenance) (*apiTypes.DatabaseUsage, error) {
+	usage := &apiTypes.DatabaseUsage{}
+	err := c.QueryStruct(ctx, "POST", types.InternalEndpoint, api.NewURL().Path("database", "maintenance"), maintenance, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// GetRaftState returns the raft state persisted by the dqlite node of the cluster member.
+func GetRaftState(ctx context.Context, c *Client) (*apiTypes.DatabaseRaftState, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	raftState := &apiTypes.DatabaseRaftState{}
+	err := c.QueryStruct(queryCtx, "GET", types.InternalEndpoint, api.NewURL().Path("database", "raft"), nil, raftState)
+	if err != nil {
+		return nil, err
+	}
+
+	return raftState, nil
+}
+
+// GetRaftFiles returns the raft metadata, segment and snapshot files of the dqlite node of the cluster member.
+func GetRaftFiles(ctx context.Context, c *Client) ([]apiTypes.DatabaseRaftFile, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	files := []apiTypes.DatabaseRaftFile{}
+	err := c.QueryStruct(queryCtx, "GET", types.InternalEndpoint, api.NewURL().Path("database", "raft", "files"), nil, &files)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// GetDatabaseMembers returns the dqlite role and raft state of each cluster member, as seen from the dqlite leader.
+func (c *Client) GetDatabaseMembers(ctx context.Context) ([]apiTypes.DatabaseMember, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	members := []apiTypes.DatabaseMember{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("database"), nil, &members)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// GetDatabaseBackups returns the database backups in the state directory of the cluster member, from oldest to newest.
+func (c *Client) GetDatabaseBackups(ctx context.Context) ([]apiTypes.DatabaseBackup, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	backups := []apiTypes.DatabaseBackup{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("database", "backups"), nil, &backups)
+	if err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
+
+// DownloadDatabaseBackup writes the database backup with the given name, from the state directory of the cluster
+// member, to w.
+func (c *Client) DownloadDatabaseBackup(ctx context.Context, name string, w io.Writer) error {
+	url := c.mergeURL(types.PublicEndpoint, api.NewURL().Path("database", "backups", name))
+	req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, err := parseResponse(resp)
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("Failed to download database backup %q: %q", name, resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		return fmt.Errorf("Failed to download database backup %q: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// happyEyeballsDelay is how long a connection attempt may take before the next address is tried in parallel,
+// as recommended by RFC 8305.
+const happyEyeballsDelay = 250 * time.Millisecond
+
+// interleaveAddresses orders the addresses so that IPv6 and IPv4 addresses alternate, starting with the family of the
+// first address. This keeps an unreachable address family from delaying connections over the other.
+func interleaveAddresses(addrs []string) []string {
+	if len(addrs) == 0 {
+		return addrs
+	}
+
+	isIPv4 := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		return ip != nil && ip.To4() != nil
+	}
+
+	preferred := []string{}
+	other := []string{}
+	for _, addr := range addrs {
+		if isIPv4(addr) == isIPv4(addrs[0]) {
+			preferred = append(preferred, addr)
+		} else {
+			other = append(other, addr)
+		}
+	}
+
+	ordered := make([]string, 0, len(addrs))
+	for i := 0; i < len(preferred) || i < len(other); i++ {
+		if i < len(preferred) {
+			ordered = append(ordered, preferred[i])
+		}
+
+		if i < len(other) {
+			ordered = append(ordered, other[i])
+		}
+	}
+
+	return ordered
+}
+
+// dialParallel connects to the first reachable address. A new attempt is started whenever the previous one fails,
+// or has not succeeded within happyEyeballsDelay. Connections established after the first one are closed.
+func dialParallel(ctx context.Context, addrs []string, dial func(ctx context.Context, addr string) (net.Conn, error)) (net.Conn, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("No addresses to connect to")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	results := make(chan result, len(addrs))
+	next := 0
+	pending := 0
+	start := func() {
+		addr := addrs[next]
+		next++
+		pending++
+
+		go func() {
+			conn, err := dial(ctx, addr)
+			results <- result{conn: conn, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(happyEyeballsDelay)
+	defer timer.Stop()
+
+	resetTimer := func() {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		timer.Reset(happyEyeballsDelay)
+	}
+
+	start()
+
+	var lastErr error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if next < len(addrs) {
+				start()
+				timer.Reset(happyEyeballsDelay)
+			}
+
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				// Close any connection established by the attempts still in flight.
+				go func(remaining int) {
+					for ; remaining > 0; remaining-- {
+						res := <-results
+						if res.conn != nil {
+							_ = res.conn.Close()
+						}
+					}
+				}(pending)
+
+				return res.conn, nil
+			}
+
+			lastErr = res.err
+			if next < len(addrs) {
+				start()
+				resetTimer()
+			}
+		}
+	}
+
+	return nil, lastErr
+}
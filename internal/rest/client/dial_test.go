@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterleaveAddresses(t *testing.T) {
+	addrs := []string{"2001:db8::1", "2001:db8::2", "2001:db8::3", "10.0.0.1"}
+	assert.Equal(t, []string{"2001:db8::1", "10.0.0.1", "2001:db8::2", "2001:db8::3"}, interleaveAddresses(addrs))
+
+	addrs = []string{"10.0.0.1", "10.0.0.2", "2001:db8::1"}
+	assert.Equal(t, []string{"10.0.0.1", "2001:db8::1", "10.0.0.2"}, interleaveAddresses(addrs))
+
+	assert.Empty(t, interleaveAddresses(nil))
+}
+
+func TestDialParallel(t *testing.T) {
+	// A hanging address does not hold back the next one.
+	conn, err := dialParallel(context.Background(), []string{"hang", "ok"}, func(ctx context.Context, addr string) (net.Conn, error) {
+		if addr == "hang" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	})
+	require.NoError(t, err)
+	_ = conn.Close()
+
+	// A failing address is skipped right away.
+	start := time.Now()
+	conn, err = dialParallel(context.Background(), []string{"fail", "ok"}, func(ctx context.Context, addr string) (net.Conn, error) {
+		if addr == "fail" {
+			return nil, fmt.Errorf("unreachable")
+		}
+
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	})
+	require.NoError(t, err)
+	_ = conn.Close()
+	assert.Less(t, time.Since(start), happyEyeballsDelay)
+
+	// The last error is returned if no address is reachable.
+	_, err = dialParallel(context.Background(), []string{"a", "b"}, func(ctx context.Context, addr string) (net.Conn, error) {
+		return nil, fmt.Errorf("unreachable %s", addr)
+	})
+	assert.EqualError(t, err, "unreachable b")
+}
+
+// testResolver moves cluster members to the addresses of its DNS records.
+type testResolver struct {
+	records map[string]string
+	current map[string]string
+}
+
+func (r *testResolver) Current(address string) string {
+	current, ok := r.current[address]
+	if ok {
+		return current
+	}
+
+	return address
+}
+
+func (r *testResolver) Resolve(ctx context.Context, address string) (string, error) {
+	resolved, ok := r.records[address]
+	if !ok {
+		return "", fmt.Errorf("no DNS name")
+	}
+
+	return resolved, nil
+}
+
+func (r *testResolver) Resolved(address string, resolved string) {
+	r.current[address] = resolved
+}
+
+func TestDialResolved(t *testing.T) {
+	var dialed []string
+	reachable := "10.0.0.2:9000"
+	dial := func(ctx context.Context, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		if addr != reachable {
+			return nil, fmt.Errorf("unreachable %s", addr)
+		}
+
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	}
+
+	resolver := &testResolver{records: map[string]string{"10.0.0.1:9000": "10.0.0.2:9000"}, current: map[string]string{}}
+
+	// A failed connection is retried at the resolved address, which is then recorded as the current address.
+	conn, err := dialResolved(context.Background(), "10.0.0.1:9000", resolver, dial)
+	require.NoError(t, err)
+	_ = conn.Close()
+	assert.Equal(t, []string{"10.0.0.1:9000", "10.0.0.2:9000"}, dialed)
+	assert.Equal(t, "10.0.0.2:9000", resolver.Current("10.0.0.1:9000"))
+
+	// Later connections go to the current address directly.
+	dialed = nil
+	conn, err = dialResolved(context.Background(), "10.0.0.1:9000", resolver, dial)
+	require.NoError(t, err)
+	_ = conn.Close()
+	assert.Equal(t, []string{"10.0.0.2:9000"}, dialed)
+
+	// The cluster member moves again.
+	reachable = "10.0.0.3:9000"
+	resolver.records["10.0.0.1:9000"] = reachable
+	dialed = nil
+	conn, err = dialResolved(context.Background(), "10.0.0.1:9000", resolver, dial)
+	require.NoError(t, err)
+	_ = conn.Close()
+	assert.Equal(t, []string{"10.0.0.2:9000", "10.0.0.3:9000"}, dialed)
+	assert.Equal(t, "10.0.0.3:9000", resolver.Current("10.0.0.1:9000"))
+
+	// The original error is returned if the address cannot be resolved, or resolves to the current address.
+	_, err = dialResolved(context.Background(), "10.0.0.4:9000", resolver, dial)
+	assert.EqualError(t, err, "unreachable 10.0.0.4:9000")
+
+	reachable = ""
+	_, err = dialResolved(context.Background(), "10.0.0.1:9000", resolver, dial)
+	assert.EqualError(t, err, "unreachable 10.0.0.3:9000")
+
+	_, err = dialResolved(context.Background(), "10.0.0.1:9000", nil, dial)
+	assert.EqualError(t, err, "unreachable 10.0.0.1:9000")
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/internal/tracing"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// maxEventSize is the maximum size of a line of a server-sent event stream.
+const maxEventSize = 1024 * 1024
+
+// RawEventStream opens the server-sent event stream at the provided endpoint, and calls handler with each event
+// until the stream ends, the context is cancelled or handler returns an error. If lastEventID is set, the server
+// is asked to resume the stream after that event. It returns the ID of the last event received.
+//
+// The final URL is that provided as the endpoint combined with the applicable prefix for the endpointType and the scheme and host from the client.
+func (c *Client) RawEventStream(ctx context.Context, endpointType types.EndpointPrefix, endpoint *api.URL, lastEventID string, handler func(event types.ServerSentEvent) error) (string, error) {
+	localURL := c.mergeURL(endpointType, endpoint)
+	req, err := http.NewRequestWithContext(ctx, "GET", localURL.String(), nil)
+	if err != nil {
+		return lastEventID, err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return lastEventID, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		_, err := parseResponse(resp)
+		if err != nil {
+			return lastEventID, err
+		}
+
+		return lastEventID, fmt.Errorf("Endpoint %q did not return an event stream", localURL.String())
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize)
+
+	event := types.ServerSentEvent{}
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// An empty line dispatches the event.
+		if line == "" {
+			if data == nil {
+				continue
+			}
+
+			if event.ID != "" {
+				lastEventID = event.ID
+			}
+
+			if event.Type == "" {
+				event.Type = "message"
+			}
+
+			event.Data = []byte(strings.Join(data, "\n"))
+			err := handler(event)
+			if err != nil {
+				return lastEventID, err
+			}
+
+			event = types.ServerSentEvent{}
+			data = nil
+
+			continue
+		}
+
+		// Lines starting with a colon are comments, such as heartbeats.
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil && ctx.Err() == nil {
+		return lastEventID, fmt.Errorf("Failed to read event stream: %w", err)
+	}
+
+	return lastEventID, nil
+}
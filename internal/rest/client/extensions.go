@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/v3/rest/types"
+)
+
+// extensionsCacheTTL is how long a client relies on the API extensions it last retrieved from the cluster.
+const extensionsCacheTTL = time.Minute
+
+// extensionsCache holds the API extensions supported by the cluster, as last retrieved by a client.
+type extensionsCache struct {
+	mu         sync.Mutex
+	extensions *apiTypes.ClusterExtensions
+	expiry     time.Time
+}
+
+// GetClusterExtensions returns the API extensions supported by every cluster member, and the members lagging behind.
+func (c *Client) GetClusterExtensions(ctx context.Context) (*apiTypes.ClusterExtensions, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	extensions := &apiTypes.ClusterExtensions{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("extensions"), nil, extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	return extensions, nil
+}
+
+// HasExtension returns whether the given API extension is supported by every cluster member, so that features can
+// be gated on it while the cluster runs mixed versions. The extensions are retrieved from the cluster at most once a
+// minute, and the result is shared with clients derived from this one.
+func (c *Client) HasExtension(ctx context.Context, name string) (bool, error) {
+	if c.extensions == nil {
+		extensions, err := c.GetClusterExtensions(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		return extensions.HasExtension(name), nil
+	}
+
+	c.extensions.mu.Lock()
+	defer c.extensions.mu.Unlock()
+
+	if c.extensions.extensions == nil || time.Now().After(c.extensions.expiry) {
+		extensions, err := c.GetClusterExtensions(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		c.extensions.extensions = extensions
+		c.extensions.expiry = time.Now().Add(extensionsCacheTTL)
+	}
+
+	return c.extensions.extensions.HasExtension(name), nil
+}
@@ -18,6 +18,14 @@ func RunPreRemoveHook(ctx context.Context, c *Client, config internalTypes.HookR
 	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.PreRemove)), config, nil)
 }
 
+// RunPreRemovePeerHook executes the PreRemovePeer hook with the given configuration on the cluster member targeted by this client.
+func RunPreRemovePeerHook(ctx context.Context, c *Client, config internalTypes.HookRemoveMemberOptions) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.PreRemovePeer)), config, nil)
+}
+
 // RunPostRemoveHook executes the PostRemove hook with the given configuration on the cluster member targeted by this client.
 func RunPostRemoveHook(ctx context.Context, c *Client, config internalTypes.HookRemoveMemberOptions) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -26,6 +34,14 @@ func RunPostRemoveHook(ctx context.Context, c *Client, config internalTypes.Hook
 	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.PostRemove)), config, nil)
 }
 
+// RunMemberRemovedHook executes the OnMemberRemoved hook with the given configuration on the cluster member targeted by this client.
+func RunMemberRemovedHook(ctx context.Context, c *Client, config internalTypes.HookRemoveMemberOptions) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.OnMemberRemoved)), config, nil)
+}
+
 // RunNewMemberHook executes the OnNewMember hook with the given configuration on the cluster member targeted by this client.
 func RunNewMemberHook(ctx context.Context, c *Client, config internalTypes.HookNewMemberOptions) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Interceptor wraps the requests sent by a client. It can modify the request, such as to add headers, before sending it
+// with next, and inspect or replace the response and error returned by next. Requests that upgrade to a websocket
+// are not intercepted.
+type Interceptor func(r *http.Request, next func(r *http.Request) (*http.Response, error)) (*http.Response, error)
+
+// AddInterceptors adds interceptors for the requests sent by the client and the clients derived from it from now on.
+// Interceptors run in the order they are added, after those set in the TransportOptions.
+func (c *Client) AddInterceptors(interceptors ...Interceptor) {
+	// Clients derived from this one keep the interceptors they were created with.
+	c.interceptors = append(slices.Clip(c.interceptors), interceptors...)
+}
+
+// do sends the request through the interceptors set in the TransportOptions and those added to the client.
+func (c *Client) do(r *http.Request) (*http.Response, error) {
+	transports.Lock()
+	interceptors := transports.options.Interceptors
+	transports.Unlock()
+
+	interceptors = append(slices.Clip(interceptors), c.interceptors...)
+
+	send := c.Do
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor := interceptors[i]
+		next := send
+		send = func(r *http.Request) (*http.Response, error) {
+			return interceptor(r, next)
+		}
+	}
+
+	return send(r)
+}
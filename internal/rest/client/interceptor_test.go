@@ -0,0 +1,65 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures interceptors from the transport options run before those of the client, and apply to derived clients.
+func TestInterceptors(t *testing.T) {
+	var order []string
+	var tenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Tenant")
+		_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200}`))
+	}))
+	defer server.Close()
+
+	intercept := func(name string) Interceptor {
+		return func(r *http.Request, next func(r *http.Request) (*http.Response, error)) (*http.Response, error) {
+			order = append(order, name)
+			r.Header.Set("X-Tenant", name)
+
+			return next(r)
+		}
+	}
+
+	require.NoError(t, SetTransportOptions(TransportOptions{Interceptors: []Interceptor{intercept("global")}}))
+	defer func() { require.NoError(t, SetTransportOptions(TransportOptions{})) }()
+
+	c := &Client{Client: server.Client(), extensions: &extensionsCache{}}
+	c.url = *api.NewURL().Scheme("http").Host(server.Listener.Addr().String())
+	c.AddInterceptors(intercept("first"), intercept("second"))
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = c.MakeRequest(req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"global", "first", "second"}, order)
+	require.Equal(t, "second", tenant)
+
+	order = nil
+	target := c.UseTarget("n1")
+	target.AddInterceptors(intercept("target"))
+
+	req, err = http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = c.MakeRequest(req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"global", "first", "second"}, order)
+
+	order = nil
+	req, err = http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = target.MakeRequest(req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"global", "first", "second", "target"}, order)
+	require.Equal(t, "target", tenant)
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetOperations returns the running and recently finished operations of the cluster member.
+func (c *Client) GetOperations(ctx context.Context) ([]types.Operation, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	operations := []types.Operation{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("operations"), nil, &operations)
+
+	return operations, err
+}
+
+// GetOperation returns the status of the operation with the given ID.
+func (c *Client) GetOperation(ctx context.Context, id string) (*types.Operation, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	operation := types.Operation{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("operations", id), nil, &operation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &operation, nil
+}
+
+// CancelOperation cancels the running operation with the given ID.
+func (c *Client) CancelOperation(ctx context.Context, id string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "DELETE", internalTypes.PublicEndpoint, api.NewURL().Path("operations", id), nil, nil)
+}
+
+// WaitOperation blocks until the operation with the given ID finishes or the context is cancelled, and returns its
+// final status. An error is returned if the operation did not succeed.
+func (c *Client) WaitOperation(ctx context.Context, id string) (*types.Operation, error) {
+	conn, err := c.RawWebsocket(ctx, internalTypes.PublicEndpoint, api.NewURL().Path("operations", id, "websocket"))
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	// Unblock the read below if the context is cancelled before the operation finishes.
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	var operation *types.Operation
+	for {
+		status := types.Operation{}
+		err := conn.ReadJSON(&status)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			// The final status may be followed by a normal closure, or by the connection going away, for
+			// instance if the daemon restarts once the operation finished.
+			if operation != nil && operation.Done() {
+				break
+			}
+
+			return nil, fmt.Errorf("Failed to wait for operation %q: %w", id, err)
+		}
+
+		operation = &status
+	}
+
+	if operation.Status != types.OperationSuccess {
+		return operation, fmt.Errorf("Operation %q did not succeed (%s): %s", id, operation.Status, operation.Err)
+	}
+
+	return operation, nil
+}
+
+// queryOperation sends a request that starts an operation, and waits for the operation to finish. If the context is
+// cancelled while waiting, the operation is cancelled too. A request answered synchronously, for instance by a daemon
+// that does not run the action as an operation, returns without waiting, and without an operation.
+func (c *Client) queryOperation(ctx context.Context, method string, endpointType types.EndpointPrefix, endpoint *api.URL, data any) (*types.Operation, error) {
+	resp, err := c.rawQuery(ctx, method, c.mergeURL(endpointType, endpoint), data)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Type != api.AsyncResponse {
+		return nil, nil
+	}
+
+	id := path.Base(resp.Operation)
+	operation, err := c.WaitOperation(ctx, id)
+	if err != nil && ctx.Err() != nil {
+		cancelErr := c.CancelOperation(context.WithoutCancel(ctx), id)
+		if cancelErr != nil {
+			return nil, fmt.Errorf("%w (failed to cancel operation %q: %w)", err, id, cancelErr)
+		}
+	}
+
+	return operation, err
+}
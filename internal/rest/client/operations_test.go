@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures requests starting an operation wait for it to finish, that daemons answering synchronously are not waited
+// for, and that operations are cancelled along with the context of the caller.
+func TestQueryOperation(t *testing.T) {
+	var mu sync.Mutex
+	var final types.OperationStatus
+	var async bool
+	var cancelled bool
+	hold := make(chan struct{})
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && async:
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"type": "async", "status": "Operation created", "status_code": 100, "operation": "/core/1.0/operations/op1"}`))
+		case r.Method == http.MethodPost:
+			_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200}`))
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/operations/op1"):
+			cancelled = true
+			close(hold)
+			_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200}`))
+		case strings.HasSuffix(r.URL.Path, "/operations/op1/websocket"):
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+
+			defer conn.Close()
+
+			_ = conn.WriteJSON(types.Operation{ID: "op1", Status: types.OperationRunning})
+			if final == types.OperationRunning {
+				mu.Unlock()
+				<-hold
+				mu.Lock()
+
+				return
+			}
+
+			// The daemon may go away without closing the websocket once it sent the final status.
+			result, _ := json.Marshal(types.UpgradeStageCommitted)
+			_ = conn.WriteJSON(types.Operation{ID: "op1", Status: final, Result: json.RawMessage(result), Err: "failed"})
+		}
+	}))
+	defer server.Close()
+
+	c := &Client{Client: server.Client(), extensions: &extensionsCache{}}
+	c.url = *api.NewURL().Scheme("http").Host(server.Listener.Addr().String())
+
+	set := func(isAsync bool, status types.OperationStatus) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		async = isAsync
+		final = status
+	}
+
+	// Daemons running the action synchronously are not waited for.
+	set(false, types.OperationSuccess)
+	require.NoError(t, c.ControlDaemon(context.Background(), internalTypes.Control{}))
+
+	set(true, types.OperationSuccess)
+	require.NoError(t, c.ControlDaemon(context.Background(), internalTypes.Control{}))
+
+	stage, err := c.WaitUpgrade(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, types.UpgradeStageCommitted, stage)
+
+	set(true, types.OperationFailure)
+	err = c.ControlDaemon(context.Background(), internalTypes.Control{})
+	require.ErrorContains(t, err, "failed")
+
+	// The operation is cancelled if the caller stops waiting for it.
+	set(true, types.OperationRunning)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err = c.ControlDaemon(ctx, internalTypes.Control{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.True(t, cancelled)
+}
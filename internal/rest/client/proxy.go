@@ -0,0 +1,290 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+	"golang.org/x/net/proxy"
+)
+
+// validateProxy checks that connections can be made through the proxy at the given URL.
+func validateProxy(proxyURL *url.URL) error {
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return fmt.Errorf("Unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+
+	if proxyURL.Hostname() == "" {
+		return fmt.Errorf("Proxy URL %q has no host", proxyURL.Redacted())
+	}
+
+	if proxyURL.User != nil && proxyURL.Scheme != "http" {
+		password, _ := proxyURL.User.Password()
+		if len(proxyURL.User.Username()) > 255 || len(password) > 255 {
+			return fmt.Errorf("SOCKS proxy credentials cannot be longer than 255 bytes")
+		}
+	}
+
+	return nil
+}
+
+// DialTLS establishes a TLS connection with a cluster member, through the proxy set with SetTransportOptions if there
+// is one.
+func DialTLS(ctx context.Context, addr string, config *tls.Config) (net.Conn, error) {
+	transports.Lock()
+	proxyURL := transports.options.Proxy
+	resolver := transports.options.Resolver
+	transports.Unlock()
+
+	return dialResolved(ctx, addr, resolver, func(ctx context.Context, addr string) (net.Conn, error) {
+		var conn net.Conn
+		var err error
+		if proxyURL != nil {
+			conn, err = dialProxy(ctx, proxyURL, addr)
+		} else {
+			dialer := &net.Dialer{}
+			conn, err = dialer.DialContext(ctx, "tcp", addr)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return tlsClient(ctx, conn, config, addr)
+	})
+}
+
+// dialResolved dials the current address of the cluster member recorded with the given address, and if that fails,
+// the address returned for it by the resolver, if set and different. Once the resolved address is reached, it is
+// recorded as the current address of the cluster member. The error of the first attempt is returned if the address
+// cannot be resolved.
+func dialResolved(ctx context.Context, addr string, resolver Resolver, dial func(ctx context.Context, addr string) (net.Conn, error)) (net.Conn, error) {
+	if resolver == nil {
+		return dial(ctx, addr)
+	}
+
+	current := resolver.Current(addr)
+	conn, err := dial(ctx, current)
+	if err == nil || ctx.Err() != nil {
+		return conn, err
+	}
+
+	resolved, resolveErr := resolver.Resolve(ctx, addr)
+	if resolveErr != nil || resolved == "" || resolved == current {
+		return nil, err
+	}
+
+	logger.Debug("Retrying connection at resolved address", logger.Ctx{"address": addr, "resolved": resolved, "error": err})
+
+	conn, err = dial(ctx, resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver.Resolved(addr, resolved)
+
+	return conn, nil
+}
+
+// tlsClient performs the TLS handshake over an established connection to the given address. As with tls.Dialer, the
+// server name defaults to the host of the address.
+func tlsClient(ctx context.Context, conn net.Conn, config *tls.Config, addr string) (net.Conn, error) {
+	if config == nil || config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+
+		if config == nil {
+			config = &tls.Config{}
+		} else {
+			config = config.Clone()
+		}
+
+		config.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, config)
+	err := tlsConn.HandshakeContext(ctx)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// dialProxy connects to the given address through the proxy at the given URL. SOCKS5 proxies are reached with the
+// socks5 scheme, which resolves the address locally, or the socks5h scheme, which lets the proxy resolve it. HTTP
+// proxies are reached with the http scheme, and asked to tunnel the connection with the CONNECT method. Credentials
+// in the URL are used to authenticate with the proxy.
+func dialProxy(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "1080"
+		if proxyURL.Scheme == "http" {
+			port = "80"
+		}
+
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	var conn net.Conn
+	var err error
+	if proxyURL.Scheme == "http" {
+		conn, err = dialHTTP(ctx, proxyURL, proxyAddr, addr)
+	} else {
+		conn, err = dialSOCKS5(ctx, proxyURL, proxyAddr, addr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to %q through proxy %q: %w", addr, proxyAddr, err)
+	}
+
+	return conn, nil
+}
+
+// proxyConnDialer dials the connection to a SOCKS5 proxy, and keeps it so that the TCP connection can be returned
+// instead of the wrapper of the SOCKS5 dialer, as the TCP timeouts of connections to cluster members are set on it.
+type proxyConnDialer struct {
+	conn net.Conn
+}
+
+// Dial connects to the proxy at the given address.
+func (d *proxyConnDialer) Dial(network string, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext connects to the proxy at the given address using the provided context.
+func (d *proxyConnDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	d.conn = conn
+
+	return conn, nil
+}
+
+// dialSOCKS5 connects to the given address through the SOCKS5 proxy at the given proxy address, authenticating with
+// a username and password if the proxy URL has any.
+func dialSOCKS5(ctx context.Context, proxyURL *url.URL, proxyAddr string, addr string) (net.Conn, error) {
+	if proxyURL.Scheme == "socks5" {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if net.ParseIP(host) == nil {
+			addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+
+			addr = net.JoinHostPort(addrs[0], port)
+		}
+	}
+
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+	}
+
+	forward := &proxyConnDialer{}
+	dialer, err := proxy.SOCKS5("tcp", proxyAddr, auth, forward)
+	if err != nil {
+		return nil, err
+	}
+
+	// The SOCKS5 dialer closes the connection to the proxy if the exchange fails.
+	_, err = dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return forward.conn, nil
+}
+
+// dialHTTP connects to the given address through the HTTP proxy at the given proxy address.
+func dialHTTP(ctx context.Context, proxyURL *url.URL, proxyAddr string, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to proxy: %w", err)
+	}
+
+	// Interrupt the exchange with the proxy if the context is done before it completes.
+	deadline, ok := ctx.Deadline()
+	if ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+
+	err = connectHTTP(conn, proxyURL, addr)
+	if !stop() && err == nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+// connectHTTP asks the HTTP proxy on the other end of the connection to tunnel it to the given address.
+func connectHTTP(conn net.Conn, proxyURL *url.URL, addr string) error {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	err := req.Write(conn)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Proxy refused the connection: %s", resp.Status)
+	}
+
+	// The TLS handshake is started by the client, so the proxy has nothing to relay from the member yet.
+	if reader.Buffered() > 0 {
+		return fmt.Errorf("Unexpected data received from the proxy")
+	}
+
+	return nil
+}
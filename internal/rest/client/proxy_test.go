@@ -0,0 +1,396 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// listen serves each connection to a new listener with the given handler until the test ends.
+func listen(t *testing.T, handler func(conn net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				handler(conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// relay connects the client to the given address, and copies data between them.
+func relay(client net.Conn, addr string) {
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		return
+	}
+
+	defer target.Close()
+
+	go func() { _, _ = io.Copy(target, client) }()
+	_, _ = io.Copy(client, target)
+}
+
+// readSOCKS5String reads a string prefixed by its length, as sent in SOCKS5 requests.
+func readSOCKS5String(conn net.Conn) (string, error) {
+	length := make([]byte, 1)
+	_, err := io.ReadFull(conn, length)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, length[0])
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+// readSOCKS5Greeting reads the authentication methods offered by a SOCKS5 client.
+func readSOCKS5Greeting(conn net.Conn) error {
+	buf := make([]byte, 2)
+	_, err := io.ReadFull(conn, buf)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.ReadFull(conn, make([]byte, buf[1]))
+	return err
+}
+
+// readSOCKS5Request reads the CONNECT request of a SOCKS5 client, and returns the requested address.
+func readSOCKS5Request(conn net.Conn) (string, error) {
+	header := make([]byte, 4)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return "", err
+	}
+
+	var host string
+	switch header[3] {
+	case 1, 4:
+		ip := make(net.IP, net.IPv4len)
+		if header[3] == 4 {
+			ip = make(net.IP, net.IPv6len)
+		}
+
+		_, err = io.ReadFull(conn, ip)
+		host = ip.String()
+	case 3:
+		host, err = readSOCKS5String(conn)
+	default:
+		return "", fmt.Errorf("Unexpected address type %d", header[3])
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	port := make([]byte, 2)
+	_, err = io.ReadFull(conn, port)
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// socks5Proxy serves a SOCKS5 proxy that requires the given credentials, sends the address of each CONNECT request to
+// the given channel, and echoes the data sent through it instead of relaying it.
+func socks5Proxy(t *testing.T, username string, password string, requested chan<- string) string {
+	return listen(t, func(conn net.Conn) {
+		err := readSOCKS5Greeting(conn)
+		if err != nil {
+			return
+		}
+
+		_, _ = conn.Write([]byte{5, 2})
+
+		_, err = io.ReadFull(conn, make([]byte, 1))
+		if err != nil {
+			return
+		}
+
+		gotUsername, _ := readSOCKS5String(conn)
+		gotPassword, _ := readSOCKS5String(conn)
+		if gotUsername != username || gotPassword != password {
+			_, _ = conn.Write([]byte{1, 1})
+			return
+		}
+
+		_, _ = conn.Write([]byte{1, 0})
+
+		addr, err := readSOCKS5Request(conn)
+		if err != nil {
+			return
+		}
+
+		requested <- addr
+
+		_, _ = conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+		_, _ = io.Copy(conn, conn)
+	})
+}
+
+// connectProxy serves an HTTP proxy that requires the given credentials and tunnels CONNECT requests.
+func connectProxy(t *testing.T, authorization string) string {
+	return listen(t, func(conn net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil || req.Method != http.MethodConnect {
+			return
+		}
+
+		if req.Header.Get("Proxy-Authorization") != authorization {
+			_, _ = conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+			return
+		}
+
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		relay(conn, req.Host)
+	})
+}
+
+// Ensures connections are tunneled through SOCKS5 and HTTP proxies, with the address of the cluster member sent to
+// SOCKS5 proxies as a host name, or resolved locally, and fail if the proxy rejects the credentials.
+func TestDialProxy(t *testing.T) {
+	target := listen(t, func(conn net.Conn) { _, _ = io.Copy(conn, conn) })
+	_, port, err := net.SplitHostPort(target)
+	require.NoError(t, err)
+
+	addrs, err := net.DefaultResolver.LookupHost(context.Background(), "localhost")
+	require.NoError(t, err)
+
+	requested := make(chan string, 1)
+	socksProxy := socks5Proxy(t, "user", "secret", requested)
+
+	tests := []struct {
+		name      string
+		proxyURL  string
+		addr      string
+		requested string
+		wantErr   bool
+	}{
+		{
+			name:      "SOCKS5 proxy resolving host names",
+			proxyURL:  "socks5h://user:secret@" + socksProxy,
+			addr:      "localhost:8443",
+			requested: "localhost:8443",
+		},
+		{
+			name:      "SOCKS5 proxy with host names resolved locally",
+			proxyURL:  "socks5://user:secret@" + socksProxy,
+			addr:      "localhost:8443",
+			requested: net.JoinHostPort(addrs[0], "8443"),
+		},
+		{
+			name:      "SOCKS5 proxy with an IPv4 address",
+			proxyURL:  "socks5h://user:secret@" + socksProxy,
+			addr:      "10.0.0.1:8443",
+			requested: "10.0.0.1:8443",
+		},
+		{
+			name:      "SOCKS5 proxy with an IPv6 address",
+			proxyURL:  "socks5://user:secret@" + socksProxy,
+			addr:      "[fd00::1]:8443",
+			requested: "[fd00::1]:8443",
+		},
+		{
+			name:     "SOCKS5 proxy with wrong credentials",
+			proxyURL: "socks5h://user:wrong@" + socksProxy,
+			addr:     "localhost:8443",
+			wantErr:  true,
+		},
+		{
+			name:     "HTTP proxy",
+			proxyURL: "http://user:secret@" + connectProxy(t, "Basic dXNlcjpzZWNyZXQ="),
+			addr:     net.JoinHostPort("localhost", port),
+		},
+		{
+			name:     "HTTP proxy with wrong credentials",
+			proxyURL: "http://user:wrong@" + connectProxy(t, "Basic dXNlcjpzZWNyZXQ="),
+			addr:     net.JoinHostPort("localhost", port),
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proxyURL, err := url.Parse(test.proxyURL)
+			require.NoError(t, err)
+			require.NoError(t, validateProxy(proxyURL))
+
+			conn, err := dialProxy(context.Background(), proxyURL, test.addr)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			defer conn.Close()
+
+			// The TCP connection is returned, so that its timeouts can be set.
+			_, ok := conn.(*net.TCPConn)
+			require.True(t, ok)
+
+			if test.requested != "" {
+				require.Equal(t, test.requested, <-requested)
+			}
+
+			_, err = conn.Write([]byte("ping"))
+			require.NoError(t, err)
+
+			reply := make([]byte, 4)
+			_, err = io.ReadFull(conn, reply)
+			require.NoError(t, err)
+			require.Equal(t, "ping", string(reply))
+		})
+	}
+
+	require.Error(t, validateProxy(&url.URL{Scheme: "ftp", Host: "proxy:21"}))
+}
+
+// Ensures connections fail if the proxy sends truncated or malformed replies, or stops replying.
+func TestDialProxyMalformedReplies(t *testing.T) {
+	tests := []struct {
+		name    string
+		scheme  string
+		handler func(conn net.Conn)
+	}{
+		{
+			name:   "Truncated SOCKS5 method selection",
+			scheme: "socks5h",
+			handler: func(conn net.Conn) {
+				_ = readSOCKS5Greeting(conn)
+				_, _ = conn.Write([]byte{5})
+			},
+		},
+		{
+			name:   "Unexpected SOCKS version",
+			scheme: "socks5h",
+			handler: func(conn net.Conn) {
+				_ = readSOCKS5Greeting(conn)
+				_, _ = conn.Write([]byte{4, 0})
+			},
+		},
+		{
+			name:   "No acceptable SOCKS5 authentication method",
+			scheme: "socks5h",
+			handler: func(conn net.Conn) {
+				_ = readSOCKS5Greeting(conn)
+				_, _ = conn.Write([]byte{5, 0xff})
+			},
+		},
+		{
+			name:   "Truncated SOCKS5 authentication reply",
+			scheme: "socks5h",
+			handler: func(conn net.Conn) {
+				_ = readSOCKS5Greeting(conn)
+				_, _ = conn.Write([]byte{5, 2})
+				_, _ = io.ReadFull(conn, make([]byte, 1))
+				_, _ = readSOCKS5String(conn)
+				_, _ = readSOCKS5String(conn)
+				_, _ = conn.Write([]byte{1})
+			},
+		},
+		{
+			name:   "SOCKS5 connection refused",
+			scheme: "socks5h",
+			handler: func(conn net.Conn) {
+				_ = readSOCKS5Greeting(conn)
+				_, _ = conn.Write([]byte{5, 0})
+				_, _ = readSOCKS5Request(conn)
+				_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+			},
+		},
+		{
+			name:   "Truncated SOCKS5 connect reply",
+			scheme: "socks5h",
+			handler: func(conn net.Conn) {
+				_ = readSOCKS5Greeting(conn)
+				_, _ = conn.Write([]byte{5, 0})
+				_, _ = readSOCKS5Request(conn)
+				_, _ = conn.Write([]byte{5, 0, 0, 1, 127, 0})
+			},
+		},
+		{
+			name:   "Unknown SOCKS5 bound address type",
+			scheme: "socks5h",
+			handler: func(conn net.Conn) {
+				_ = readSOCKS5Greeting(conn)
+				_, _ = conn.Write([]byte{5, 0})
+				_, _ = readSOCKS5Request(conn)
+				_, _ = conn.Write([]byte{5, 0, 0, 9, 0, 0, 0, 0, 0, 0})
+			},
+		},
+		{
+			name:   "Malformed HTTP status line",
+			scheme: "http",
+			handler: func(conn net.Conn) {
+				_, _ = http.ReadRequest(bufio.NewReader(conn))
+				_, _ = conn.Write([]byte("garbage\r\n\r\n"))
+			},
+		},
+		{
+			name:   "Truncated HTTP response",
+			scheme: "http",
+			handler: func(conn net.Conn) {
+				_, _ = http.ReadRequest(bufio.NewReader(conn))
+				_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n"))
+			},
+		},
+		{
+			name:   "Unexpected data after the HTTP response",
+			scheme: "http",
+			handler: func(conn net.Conn) {
+				_, _ = http.ReadRequest(bufio.NewReader(conn))
+				_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\nextra"))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proxyURL := &url.URL{Scheme: test.scheme, Host: listen(t, test.handler)}
+			_, err := dialProxy(context.Background(), proxyURL, "127.0.0.1:8443")
+			require.Error(t, err)
+		})
+	}
+
+	// A proxy that stops replying is given up on once the context is done.
+	for _, scheme := range []string{"socks5h", "http"} {
+		t.Run("Unresponsive "+scheme+" proxy", func(t *testing.T) {
+			done := make(chan struct{})
+			proxyURL := &url.URL{Scheme: scheme, Host: listen(t, func(conn net.Conn) { <-done })}
+			defer close(done)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			_, err := dialProxy(ctx, proxyURL, "127.0.0.1:8443")
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetPreparedQueries returns the database queries registered by the MicroCluster consumer.
+func (c *Client) GetPreparedQueries(ctx context.Context) ([]types.PreparedQuery, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	queries := []types.PreparedQuery{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("queries"), nil, &queries)
+
+	return queries, err
+}
+
+// RunPreparedQuery runs the named database query registered by the MicroCluster consumer with the given arguments,
+// keyed by parameter name.
+func (c *Client) RunPreparedQuery(ctx context.Context, name string, args map[string]any) (*types.PreparedQueryResult, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	result := types.PreparedQueryResult{}
+	err := c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, api.NewURL().Path("queries", name), types.PreparedQueryPost{Args: args}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
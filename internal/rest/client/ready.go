@@ -2,14 +2,17 @@ package client
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/canonical/lxd/shared/api"
 
 	"github.com/canonical/microcluster/v3/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/v3/rest/types"
 )
 
-// CheckReady returns once the daemon has signalled to the ready channel that it is done setting up.
+// CheckReady returns an error unless the cluster member is ready to serve requests, with its database open and the
+// dqlite leader reachable.
 func (c *Client) CheckReady(ctx context.Context) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -18,3 +21,32 @@ func (c *Client) CheckReady(ctx context.Context) error {
 
 	return err
 }
+
+// CheckDaemonReady returns an error unless the daemon has signalled to the ready channel that it is done setting up,
+// whether or not it is part of a cluster. It is only served over the control socket.
+func (c *Client) CheckDaemonReady(ctx context.Context) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	err := c.QueryStruct(queryCtx, "GET", types.ControlEndpoint, api.NewURL().Path("ready"), nil, nil)
+	if api.StatusErrorCheck(err, http.StatusNotFound) {
+		// Daemons that predate the control endpoint report their readiness at the public one.
+		return c.CheckReady(ctx)
+	}
+
+	return err
+}
+
+// GetHealth returns the health of the cluster member, or an error with the failed check if it is unhealthy.
+func (c *Client) GetHealth(ctx context.Context) (*apiTypes.Health, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	health := &apiTypes.Health{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("healthz"), nil, health)
+	if err != nil {
+		return nil, err
+	}
+
+	return health, nil
+}
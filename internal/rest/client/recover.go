@@ -0,0 +1,19 @@
+package client
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+)
+
+// PostRecoveryTarball sends a recovery tarball to the cluster member, to be loaded when its daemon next starts.
+func PostRecoveryTarball(ctx context.Context, c *Client, tarball io.Reader) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("recovery", "tarball"), tarball, nil)
+}
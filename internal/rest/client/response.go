@@ -6,6 +6,8 @@ import (
 	"net/http"
 
 	"github.com/canonical/lxd/shared/api"
+
+	apiTypes "github.com/canonical/microcluster/v3/rest/types"
 )
 
 func parseResponse(resp *http.Response) (*api.Response, error) {
@@ -25,7 +27,7 @@ func parseResponse(resp *http.Response) (*api.Response, error) {
 
 	// Handle errors
 	if response.Type == api.ErrorResponse {
-		return nil, api.StatusErrorf(resp.StatusCode, "%s", response.Error)
+		return nil, api.StatusErrorf(resp.StatusCode, "%w", apiTypes.IdentifyError(resp.Header.Get(apiTypes.ErrorCodeHeader), response.Error))
 	}
 
 	return &response, nil
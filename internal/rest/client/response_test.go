@@ -0,0 +1,53 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+func TestParseResponseErrors(t *testing.T) {
+	parse := func(status int, code string, message string) error {
+		body := `{"type": "error", "error_code": 0, "error": "` + message + `"}`
+		header := http.Header{}
+		if code != "" {
+			header.Set(types.ErrorCodeHeader, code)
+		}
+
+		_, err := parseResponse(&http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body))})
+		require.Error(t, err)
+
+		return err
+	}
+
+	// Errors forwarded through other cluster members are identified along with their status.
+	err := parse(http.StatusBadRequest, types.ErrorCode(types.ErrTokenExpired), `2 join attempts were unsuccessful. Last error: Join token expired at 2024-01-01T00:00:00Z`)
+	assert.ErrorIs(t, err, types.ErrTokenExpired)
+	assert.True(t, api.StatusErrorCheck(err, http.StatusBadRequest))
+	assert.Equal(t, "2 join attempts were unsuccessful. Last error: Join token expired at 2024-01-01T00:00:00Z", err.Error())
+
+	err = parse(http.StatusServiceUnavailable, types.ErrorCode(types.ErrNotBootstrapped), string(types.DatabaseNotReady))
+	assert.ErrorIs(t, err, types.ErrNotBootstrapped)
+
+	err = parse(http.StatusNotFound, types.ErrorCode(types.ErrMemberNotFound), `Cluster member not found: \"member\"`)
+	assert.ErrorIs(t, err, types.ErrMemberNotFound)
+	assert.False(t, errors.Is(err, types.ErrNotLeader))
+
+	// Errors are only identified by their error code, not by their message.
+	err = parse(http.StatusInternalServerError, "", "Cluster member not found")
+	assert.False(t, errors.Is(err, types.ErrMemberNotFound))
+
+	err = parse(http.StatusInternalServerError, "unknown", "Something else")
+	assert.Equal(t, "Something else", err.Error())
+	for _, sentinel := range []error{types.ErrNotBootstrapped, types.ErrAlreadyBootstrapped, types.ErrNotLeader, types.ErrDatabaseUnavailable, types.ErrMemberNotFound, types.ErrTokenExpired} {
+		assert.False(t, errors.Is(err, sentinel))
+	}
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// RetryPolicy configures how a client retries requests that failed with a transient error, such as a refused
+// connection, an unavailable daemon, or a dqlite leadership change.
+// Only idempotent requests are retried, unless the request never reached the daemon.
+// Redirects to the current leader are followed by the underlying HTTP client.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent, including the first attempt.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. It doubles with every further retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy returns a RetryPolicy suitable for riding out a daemon restart or a leader election.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+}
+
+// SetRetryPolicy sets the policy for retrying requests that failed with a transient error.
+// A nil policy disables retries, which is the default.
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// makeRequestWithRetry performs the request, retrying it with exponential backoff according to the client's RetryPolicy.
+func (c *Client) makeRequestWithRetry(r *http.Request) (*api.Response, error) {
+	policy := c.retryPolicy
+	if policy == nil || policy.MaxAttempts <= 1 {
+		return c.MakeRequest(r)
+	}
+
+	backoff := policy.InitialBackoff
+	req := r
+	for attempt := 1; ; attempt++ {
+		resp, err := c.MakeRequest(req)
+		if err == nil || attempt >= policy.MaxAttempts || !isRetryable(r, err) {
+			return resp, err
+		}
+
+		logger.Debug("Retrying request after transient error", logger.Ctx{"url": r.URL.String(), "method": r.Method, "attempt": attempt, "error": err})
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, policy.MaxBackoff)
+
+		// Rewind the request body for the next attempt.
+		req = r.Clone(r.Context())
+		if r.GetBody != nil {
+			req.Body, err = r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// isRetryable returns whether the request can be sent again after failing with the given error.
+func isRetryable(r *http.Request, err error) bool {
+	// Requests with a body that cannot be rewound are only sent once.
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return false
+	}
+
+	// The request never reached the daemon, either because it is not listening yet or its control socket does not exist.
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT) {
+		return true
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || api.StatusErrorCheck(err, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout) {
+		return true
+	}
+
+	// The request reached a member that lost dqlite leadership, or the cluster has no leader at the moment.
+	return errors.Is(err, types.ErrNotLeader) || errors.Is(err, types.ErrDatabaseUnavailable)
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures requests are only retried for errors that are known to be transient, and only if sending them again is safe.
+func TestIsRetryable(t *testing.T) {
+	newRequest := func(method string, body io.Reader) *http.Request {
+		req, err := http.NewRequest(method, "http://localhost/1.0", body)
+		require.NoError(t, err)
+
+		return req
+	}
+
+	// Leadership errors are identified by the error code of the response, not by their message.
+	notLeader := types.IdentifyError(types.ErrorCode(types.ErrNotLeader), "Attempt to initiate heartbeat from non-leader")
+	unavailable := api.StatusErrorf(http.StatusServiceUnavailable, "%w", types.IdentifyError(types.ErrorCode(types.ErrDatabaseUnavailable), "Database is temporarily unavailable: driver: bad connection"))
+	leaderMessage := api.StatusErrorf(http.StatusInternalServerError, "not leader")
+
+	unrewindable := newRequest(http.MethodPut, strings.NewReader("{}"))
+	unrewindable.GetBody = nil
+
+	cases := []struct {
+		name      string
+		req       *http.Request
+		err       error
+		retryable bool
+	}{
+		{name: "Refused connection", req: newRequest(http.MethodPost, nil), err: fmt.Errorf("Dial: %w", syscall.ECONNREFUSED), retryable: true},
+		{name: "Missing control socket", req: newRequest(http.MethodPost, nil), err: fmt.Errorf("Dial: %w", syscall.ENOENT), retryable: true},
+		{name: "Reset connection", req: newRequest(http.MethodGet, nil), err: fmt.Errorf("Read: %w", syscall.ECONNRESET), retryable: true},
+		{name: "Reset connection on write", req: newRequest(http.MethodPost, nil), err: fmt.Errorf("Read: %w", syscall.ECONNRESET)},
+		{name: "Unavailable daemon", req: newRequest(http.MethodDelete, nil), err: api.StatusErrorf(http.StatusServiceUnavailable, "Daemon is shutting down"), retryable: true},
+		{name: "Unavailable daemon on write", req: newRequest(http.MethodPost, nil), err: api.StatusErrorf(http.StatusServiceUnavailable, "Daemon is shutting down")},
+		{name: "Not leader", req: newRequest(http.MethodPut, strings.NewReader("{}")), err: api.StatusErrorf(http.StatusInternalServerError, "%w", notLeader), retryable: true},
+		{name: "Database unavailable", req: newRequest(http.MethodGet, nil), err: unavailable, retryable: true},
+		{name: "Database unavailable on write", req: newRequest(http.MethodPost, nil), err: unavailable},
+		{name: "Leadership error message without error code", req: newRequest(http.MethodGet, nil), err: leaderMessage},
+		{name: "Client error", req: newRequest(http.MethodGet, nil), err: api.StatusErrorf(http.StatusBadRequest, "Invalid request")},
+		{name: "Body cannot be rewound", req: unrewindable, err: fmt.Errorf("Dial: %w", syscall.ECONNREFUSED)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.retryable, isRetryable(c.req, c.err))
+		})
+	}
+}
+
+// Ensures requests are sent again with their body until they succeed, a non-transient error is returned, or the
+// attempts of the retry policy run out.
+func TestMakeRequestWithRetry(t *testing.T) {
+	var attempts int
+	var bodies []string
+	var responses []func(w http.ResponseWriter)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		respond := responses[min(attempts, len(responses)-1)]
+		attempts++
+		respond(w)
+	}))
+	defer server.Close()
+
+	success := func(w http.ResponseWriter) {
+		_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200}`))
+	}
+
+	failure := func(status int, err error) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			code := types.ErrorCode(err)
+			if code != "" {
+				w.Header().Set(types.ErrorCodeHeader, code)
+			}
+
+			w.WriteHeader(status)
+			_, _ = fmt.Fprintf(w, `{"type": "error", "error_code": %d, "error": %q}`, status, err.Error())
+		}
+	}
+
+	c := &Client{Client: server.Client(), extensions: &extensionsCache{}}
+	c.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	send := func(method string) error {
+		req, err := http.NewRequest(method, server.URL, strings.NewReader("payload"))
+		require.NoError(t, err)
+
+		attempts = 0
+		bodies = nil
+		_, err = c.makeRequestWithRetry(req)
+
+		return err
+	}
+
+	notLeader := failure(http.StatusInternalServerError, types.ErrNotLeader)
+	unavailable := failure(http.StatusServiceUnavailable, fmt.Errorf("%w: driver: bad connection", types.ErrDatabaseUnavailable))
+
+	// The request is sent again with the same body after a leadership change.
+	responses = []func(w http.ResponseWriter){notLeader, success}
+	require.NoError(t, send(http.MethodPut))
+	require.Equal(t, []string{"payload", "payload"}, bodies)
+
+	// The request is sent at most as many times as the retry policy allows.
+	responses = []func(w http.ResponseWriter){unavailable}
+	err := send(http.MethodGet)
+	require.ErrorIs(t, err, types.ErrDatabaseUnavailable)
+	require.Equal(t, 3, attempts)
+
+	// Requests that are not idempotent are only sent once, as they may have been applied.
+	err = send(http.MethodPost)
+	require.ErrorIs(t, err, types.ErrDatabaseUnavailable)
+	require.Equal(t, 1, attempts)
+
+	// Other errors are returned right away.
+	responses = []func(w http.ResponseWriter){failure(http.StatusInternalServerError, errors.New("not leader"))}
+	err = send(http.MethodGet)
+	require.EqualError(t, err, "not leader")
+	require.Equal(t, 1, attempts)
+
+	// Without a retry policy, requests are only sent once.
+	c.SetRetryPolicy(nil)
+	responses = []func(w http.ResponseWriter){notLeader, success}
+	require.ErrorIs(t, send(http.MethodGet), types.ErrNotLeader)
+	require.Equal(t, 1, attempts)
+}
@@ -42,3 +42,17 @@ func PostSQL(ctx context.Context, c *Client, query types.SQLQuery) (*types.SQLBa
 
 	return batch, nil
 }
+
+// PostSQLTransaction executes a list of SQL statements with bind parameters in a single transaction.
+func PostSQLTransaction(ctx context.Context, c *Client, transaction types.SQLTransaction) (*types.SQLBatch, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	batch := &types.SQLBatch{}
+	err := c.QueryStruct(reqCtx, "POST", types.InternalEndpoint, api.NewURL().Path("sql", "transaction"), transaction, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
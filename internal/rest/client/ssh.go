@@ -0,0 +1,301 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshIdleTimeout is how long connections to the remote unix socket are kept open for further requests. The SSH
+// connection is closed along with the last of them.
+const sshIdleTimeout = time.Minute
+
+// NewSSH returns a client to the unix socket at socketPath on the remote host, tunneled over SSH.
+// The address is given as host:port. The HTTP connections of the client share a single SSH connection, which is
+// established when the first of them is opened, and closed along with the last of them.
+func NewSSH(address string, socketPath string, config *ssh.ClientConfig) (*Client, error) {
+	if config == nil {
+		return nil, fmt.Errorf("Missing SSH client configuration")
+	}
+
+	tunnel := &sshTunnel{address: address, socketPath: socketPath, config: config}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			return tunnel.dial(ctx)
+		},
+		IdleConnTimeout: sshIdleTimeout,
+	}
+
+	client := &http.Client{Transport: transport}
+
+	// Setup redirect policy
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		// Replicate the headers
+		req.Header = via[len(via)-1].Header
+
+		return nil
+	}
+
+	return &Client{
+		Client:     client,
+		url:        *api.NewURL().Scheme("http").Host(filepath.Base(socketPath)),
+		extensions: &extensionsCache{},
+	}, nil
+}
+
+// DefaultSSHConfig returns the address and SSH client configuration used to reach a destination given as
+// [user@]host[:port]. The user defaults to the current user and the port to 22. The keys of the running SSH agent are
+// used for authentication, and the host key is verified against the user's ~/.ssh/known_hosts file.
+func DefaultSSHConfig(destination string) (string, *ssh.ClientConfig, error) {
+	username, host, ok := strings.Cut(destination, "@")
+	if !ok {
+		host = destination
+
+		currentUser, err := user.Current()
+		if err != nil {
+			return "", nil, fmt.Errorf("Failed to get current user: %w", err)
+		}
+
+		username = currentUser.Username
+	}
+
+	_, _, err := net.SplitHostPort(host)
+	if err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+
+	agentSocket := os.Getenv("SSH_AUTH_SOCK")
+	if agentSocket == "" {
+		return "", nil, fmt.Errorf("No SSH agent found, SSH_AUTH_SOCK is not set")
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to get home directory: %w", err)
+	}
+
+	hostKeyCallback, err := knownhosts.New(filepath.Join(homeDir, ".ssh", "known_hosts"))
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to load known SSH hosts: %w", err)
+	}
+
+	config := &ssh.ClientConfig{
+		User:            username,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeysCallback(agentSigners(agentSocket))},
+		HostKeyCallback: hostKeyCallback,
+	}
+
+	return host, config, nil
+}
+
+// agentSigners returns a function listing the keys of the SSH agent listening on the given unix socket.
+// The agent is only connected to while listing keys and signing with them, so that no connection to it is left open.
+func agentSigners(socket string) func() ([]ssh.Signer, error) {
+	return func() ([]ssh.Signer, error) {
+		var keys []*agent.Key
+		err := withAgent(socket, func(sshAgent agent.ExtendedAgent) error {
+			var err error
+			keys, err = sshAgent.List()
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		signers := make([]ssh.Signer, 0, len(keys))
+		for _, key := range keys {
+			signers = append(signers, &agentSigner{socket: socket, key: key})
+		}
+
+		return signers, nil
+	}
+}
+
+// withAgent calls fn with a client to the SSH agent listening on the given unix socket, and disconnects from it once
+// fn returns.
+func withAgent(socket string, fn func(sshAgent agent.ExtendedAgent) error) error {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to SSH agent: %w", err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	return fn(agent.NewClient(conn))
+}
+
+// agentSigner signs data with a key held by the SSH agent.
+type agentSigner struct {
+	socket string
+	key    ssh.PublicKey
+}
+
+// PublicKey returns the public key of the agent's key.
+func (s *agentSigner) PublicKey() ssh.PublicKey {
+	return s.key
+}
+
+// Sign signs the data with the agent's key.
+func (s *agentSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.SignWithAlgorithm(rand, data, "")
+}
+
+// SignWithAlgorithm signs the data with the agent's key, using the given signature algorithm.
+func (s *agentSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	var signature *ssh.Signature
+	err := withAgent(s.socket, func(sshAgent agent.ExtendedAgent) error {
+		signers, err := sshAgent.Signers()
+		if err != nil {
+			return err
+		}
+
+		for _, signer := range signers {
+			if !bytes.Equal(signer.PublicKey().Marshal(), s.key.Marshal()) {
+				continue
+			}
+
+			algorithmSigner, ok := signer.(ssh.AlgorithmSigner)
+			if !ok {
+				return fmt.Errorf("SSH agent cannot sign with algorithm %q", algorithm)
+			}
+
+			signature, err = algorithmSigner.SignWithAlgorithm(rand, data, algorithm)
+
+			return err
+		}
+
+		return fmt.Errorf("Key %q is no longer held by the SSH agent", ssh.FingerprintSHA256(s.key))
+	})
+
+	return signature, err
+}
+
+// sshTunnel shares a single SSH connection between the connections to the remote unix socket, so that the SSH
+// handshake only runs when no SSH connection is open. The SSH connection is closed along with the last connection
+// using it.
+type sshTunnel struct {
+	address    string
+	socketPath string
+	config     *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+	conns  int
+}
+
+// dial connects to the remote unix socket over the SSH connection, establishing it if needed.
+func (t *sshTunnel) dial(ctx context.Context) (net.Conn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		socket, err := t.client.DialContext(ctx, "unix", t.socketPath)
+		if err == nil {
+			return t.track(socket), nil
+		}
+
+		// The remote host refused to connect to the socket, so the SSH connection is still usable.
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) {
+			return nil, fmt.Errorf("Failed to connect to %q over SSH: %w", t.socketPath, err)
+		}
+
+		// Otherwise the SSH connection was lost, so a new one is established.
+		_ = t.client.Close()
+		t.client = nil
+		t.conns = 0
+	}
+
+	client, err := dialSSH(ctx, t.address, t.config)
+	if err != nil {
+		return nil, err
+	}
+
+	socket, err := client.DialContext(ctx, "unix", t.socketPath)
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("Failed to connect to %q over SSH: %w", t.socketPath, err)
+	}
+
+	t.client = client
+
+	return t.track(socket), nil
+}
+
+// track records a connection opened over the current SSH connection. The caller must hold the lock.
+func (t *sshTunnel) track(socket net.Conn) net.Conn {
+	t.conns++
+
+	return &sshConn{Conn: socket, tunnel: t, client: t.client}
+}
+
+// release records that a connection opened over the given SSH connection was closed, and closes the SSH connection
+// if no other connection uses it.
+func (t *sshTunnel) release(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Connections over an SSH connection that was since replaced are not counted.
+	if t.client != client {
+		return
+	}
+
+	t.conns--
+	if t.conns == 0 {
+		_ = t.client.Close()
+		t.client = nil
+	}
+}
+
+// sshConn is a connection to the remote unix socket over a shared SSH connection.
+type sshConn struct {
+	net.Conn
+
+	tunnel *sshTunnel
+	client *ssh.Client
+	once   sync.Once
+}
+
+// Close closes the connection to the remote unix socket, and the SSH connection if no other connection uses it.
+func (c *sshConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { c.tunnel.release(c.client) })
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// dialSSH opens an SSH connection to the address.
+func dialSSH(ctx context.Context, address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	dialer := net.Dialer{Timeout: config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to %q: %w", address, err)
+	}
+
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("Failed to establish SSH connection to %q: %w", address, err)
+	}
+
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
@@ -0,0 +1,265 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer is an SSH server forwarding connections to unix sockets on the local host, as OpenSSH does.
+type testSSHServer struct {
+	listener   net.Listener
+	hostKey    ssh.Signer
+	handshakes atomic.Int64
+	wg         sync.WaitGroup
+}
+
+// newTestSSHServer starts an SSH server accepting the given client key.
+func newTestSSHServer(t *testing.T, clientKey ssh.PublicKey) *testSSHServer {
+	_, hostPrivKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	hostKey, err := ssh.NewSignerFromKey(hostPrivKey)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, io.EOF
+			}
+
+			return nil, nil
+		},
+	}
+
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &testSSHServer{listener: listener, hostKey: hostKey}
+	t.Cleanup(func() {
+		_ = listener.Close()
+		s.wg.Wait()
+	})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(conn, config)
+			}()
+		}
+	}()
+
+	return s
+}
+
+// serve handles an SSH connection, forwarding its streamlocal channels to the requested unix sockets.
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	defer func() { _ = conn.Close() }()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+
+	s.handshakes.Add(1)
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-streamlocal@openssh.com" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		msg := struct {
+			SocketPath string
+			Reserved0  string
+			Reserved1  uint32
+		}{}
+
+		err := ssh.Unmarshal(newChannel.ExtraData(), &msg)
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		socket, err := net.Dial("unix", msg.SocketPath)
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			_ = socket.Close()
+			continue
+		}
+
+		go ssh.DiscardRequests(requests)
+		go func() {
+			_, _ = io.Copy(channel, socket)
+			_ = channel.CloseWrite()
+		}()
+
+		go func() {
+			_, _ = io.Copy(socket, channel)
+			_ = socket.Close()
+		}()
+	}
+}
+
+// countingListener counts the connections it accepted that are still open.
+type countingListener struct {
+	net.Listener
+
+	open atomic.Int64
+}
+
+// Accept accepts a connection, and counts it until it is closed.
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	l.open.Add(1)
+
+	return &countingConn{Conn: conn, listener: l}, nil
+}
+
+// countingConn is a connection counted by its listener until it is closed.
+type countingConn struct {
+	net.Conn
+
+	listener *countingListener
+	once     sync.Once
+}
+
+// Close closes the connection, and stops counting it.
+func (c *countingConn) Close() error {
+	c.once.Do(func() { c.listener.open.Add(-1) })
+
+	return c.Conn.Close()
+}
+
+// Ensures requests tunneled over SSH share a single SSH connection, which is closed along with the last connection to
+// the remote socket, and that the SSH agent is not kept connected to.
+func TestSSH(t *testing.T) {
+	dir := t.TempDir()
+
+	// Serve the remote control socket.
+	socketPath := filepath.Join(dir, "control.socket")
+	socketListener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200, "metadata": {}}`))
+	}))
+	server.Listener = socketListener
+	server.Start()
+	defer server.Close()
+
+	// Serve an SSH agent holding the client key.
+	_, clientPrivKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: clientPrivKey}))
+
+	inner, err := net.Listen("unix", filepath.Join(dir, "agent.socket"))
+	require.NoError(t, err)
+
+	agentListener := &countingListener{Listener: inner}
+	defer func() { _ = agentListener.Close() }()
+
+	go func() {
+		for {
+			conn, err := agentListener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				_ = agent.ServeAgent(keyring, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	clientSigner, err := ssh.NewSignerFromKey(clientPrivKey)
+	require.NoError(t, err)
+
+	sshServer := newTestSSHServer(t, clientSigner.PublicKey())
+	address := sshServer.listener.Addr().String()
+
+	// The host key of the server is known to the user.
+	home := filepath.Join(dir, "home")
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".ssh"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte(knownhosts.Line([]string{address}, sshServer.hostKey.PublicKey())+"\n"), 0600))
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", filepath.Join(dir, "agent.socket"))
+
+	sshAddress, config, err := DefaultSSHConfig("user@" + address)
+	require.NoError(t, err)
+	require.Equal(t, address, sshAddress)
+
+	c, err := NewSSH(sshAddress, socketPath, config)
+	require.NoError(t, err)
+
+	query := func() error {
+		return c.QueryStruct(context.Background(), "GET", "core/1.0", api.NewURL().Path("ready"), nil, nil)
+	}
+
+	for range 3 {
+		require.NoError(t, query())
+	}
+
+	require.Equal(t, int64(1), sshServer.handshakes.Load())
+	require.Eventually(t, func() bool { return agentListener.open.Load() == 0 }, time.Second, 10*time.Millisecond)
+
+	// The SSH connection is closed along with the idle connections, and established again when needed.
+	c.CloseIdleConnections()
+	require.NoError(t, query())
+	require.Equal(t, int64(2), sshServer.handshakes.Load())
+
+	// Connections to missing remote sockets fail.
+	missing, err := NewSSH(sshAddress, filepath.Join(dir, "missing.socket"), config)
+	require.NoError(t, err)
+	require.Error(t, missing.QueryStruct(context.Background(), "GET", "core/1.0", api.NewURL().Path("ready"), nil, nil))
+
+	// Hosts with an unknown key are rejected.
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), nil, 0600))
+	_, config, err = DefaultSSHConfig("user@" + address)
+	require.NoError(t, err)
+
+	c, err = NewSSH(sshAddress, socketPath, config)
+	require.NoError(t, err)
+	require.Error(t, query())
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetTasks returns the status of the background tasks registered on the cluster member.
+func (c *Client) GetTasks(ctx context.Context) ([]types.TaskStatus, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tasks := []types.TaskStatus{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("tasks"), nil, &tasks)
+
+	return tasks, err
+}
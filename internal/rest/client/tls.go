@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -41,5 +42,20 @@ func TLSClientConfig(clientCert *shared.CertInfo, remoteCert *x509.Certificate)
 		config.ServerName = remoteCert.DNSNames[0]
 	}
 
+	// With peer certificates pinned, only the remote certificate itself is accepted, rather than any certificate
+	// signed with its key.
+	pinned := remoteCert.Raw
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		transports.Lock()
+		pin := transports.options.PinPeerCertificates
+		transports.Unlock()
+
+		if !pin || (len(rawCerts) > 0 && bytes.Equal(rawCerts[0], pinned)) {
+			return nil
+		}
+
+		return fmt.Errorf("Remote certificate does not match the pinned certificate %q", shared.CertFingerprint(remoteCert))
+	}
+
 	return config, nil
 }
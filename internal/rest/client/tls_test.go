@@ -0,0 +1,91 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCertificate returns a certificate for the given DNS name, signed by the parent, or self-signed if it is nil.
+func newTestCertificate(t *testing.T, serial int64, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "microcluster"},
+		DNSNames:              []string{"microcluster"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+
+	signer := template
+	var signerKey any = key
+	if parent != nil {
+		signer = parent.Leaf
+		signerKey = parent.PrivateKey
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key, Leaf: leaf}
+}
+
+// clientHandshake performs a TLS handshake between a client using the configuration and a server presenting the certificate.
+func clientHandshake(config *tls.Config, serverCert tls.Certificate) error {
+	clientConn, serverConn := net.Pipe()
+	defer func() { _ = clientConn.Close() }()
+
+	go func() {
+		defer func() { _ = serverConn.Close() }()
+
+		_ = tls.Server(serverConn, &tls.Config{Certificates: []tls.Certificate{serverCert}}).Handshake()
+	}()
+
+	return tls.Client(clientConn, config).Handshake()
+}
+
+// Ensures pinned peer certificates only accept the exact certificate expected of the remote, rather than any
+// certificate signed with its key.
+func TestTLSClientConfigPinning(t *testing.T) {
+	remote := newTestCertificate(t, 1, nil)
+	signed := newTestCertificate(t, 2, &remote)
+
+	newConfig := func() *tls.Config {
+		remoteCert, err := x509.ParseCertificate(remote.Certificate[0])
+		require.NoError(t, err)
+
+		config, err := TLSClientConfig(shared.TestingKeyPair(), remoteCert)
+		require.NoError(t, err)
+
+		return config
+	}
+
+	require.NoError(t, SetTransportOptions(TransportOptions{}))
+	require.NoError(t, clientHandshake(newConfig(), remote))
+	require.NoError(t, clientHandshake(newConfig(), signed))
+
+	require.NoError(t, SetTransportOptions(TransportOptions{PinPeerCertificates: true}))
+	defer func() { require.NoError(t, SetTransportOptions(TransportOptions{})) }()
+
+	require.NoError(t, clientHandshake(newConfig(), remote))
+	require.Error(t, clientHandshake(newConfig(), signed))
+}
@@ -7,16 +7,21 @@ import (
 	"github.com/canonical/lxd/shared/api"
 
 	"github.com/canonical/microcluster/v3/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/v3/rest/types"
 )
 
 // RequestToken requests a join token with the given name.
 func (c *Client) RequestToken(ctx context.Context, name string, expireAfter time.Duration) (string, error) {
+	return c.RequestTokenWithOptions(ctx, types.TokenRequest{Name: name, ExpireAfter: expireAfter})
+}
+
+// RequestTokenWithOptions requests a join token, which may be reused and restricted to joiners from the given subnets.
+func (c *Client) RequestTokenWithOptions(ctx context.Context, tokenRequest types.TokenRequest) (string, error) {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	var token string
-	tokenRecord := types.TokenRequest{Name: name, ExpireAfter: expireAfter}
-	err := c.QueryStruct(queryCtx, "POST", types.ControlEndpoint, api.NewURL().Path("tokens"), tokenRecord, &token)
+	err := c.QueryStruct(queryCtx, "POST", types.ControlEndpoint, api.NewURL().Path("tokens"), tokenRequest, &token)
 
 	return token, err
 }
@@ -33,11 +38,20 @@ func (c *Client) DeleteTokenRecord(ctx context.Context, name string) error {
 
 // GetTokenRecords returns the token records.
 func (c *Client) GetTokenRecords(ctx context.Context) ([]types.TokenRecord, error) {
+	return c.GetTokenRecordsWithOptions(ctx, apiTypes.ListOptions{})
+}
+
+// GetTokenRecordsWithOptions returns the token records selected by the list options.
+// The only supported filter is name. Fields not selected by the options are left unset.
+func (c *Client) GetTokenRecordsWithOptions(ctx context.Context, opts apiTypes.ListOptions) ([]types.TokenRecord, error) {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
+	endpoint := api.NewURL().Path("tokens")
+	endpoint.URL.RawQuery = opts.Values().Encode()
+
 	tokenRecords := []types.TokenRecord{}
-	err := c.QueryStruct(queryCtx, "GET", types.ControlEndpoint, api.NewURL().Path("tokens"), nil, &tokenRecords)
+	err := c.QueryStruct(queryCtx, "GET", types.ControlEndpoint, endpoint, nil, &tokenRecords)
 
 	return tokenRecords, err
 }
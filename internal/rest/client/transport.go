@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+)
+
+const (
+	// DefaultMaxIdleConnsPerHost is the number of idle connections kept open to each cluster member by default.
+	DefaultMaxIdleConnsPerHost = 4
+
+	// DefaultIdleConnTimeout is how long idle connections are kept open by default.
+	DefaultIdleConnTimeout = 90 * time.Second
+
+	// DefaultTLSSessionCacheSize is the number of TLS sessions kept for resumption by default.
+	DefaultTLSSessionCacheSize = 64
+)
+
+// TransportOptions tunes the connections made by clients to other cluster members.
+// Clients created with the same certificates share their transport, so that connections and TLS sessions are reused
+// across requests, including those fanned out to the whole cluster.
+type TransportOptions struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept open to each cluster member.
+	// It defaults to DefaultMaxIdleConnsPerHost, and a negative value disables keep-alives.
+	MaxIdleConnsPerHost int
+
+	// IdleConnTimeout is how long an idle connection is kept open before being closed.
+	// It defaults to DefaultIdleConnTimeout.
+	IdleConnTimeout time.Duration
+
+	// TLSSessionCacheSize is the number of TLS sessions kept for resumption, so that new connections to a cluster
+	// member can skip the full handshake. It defaults to DefaultTLSSessionCacheSize, and a negative value disables
+	// session resumption.
+	TLSSessionCacheSize int
+
+	// Proxy is the proxy through which connections to other cluster members are made, including heartbeats,
+	// notifications and dqlite traffic. The socks5 scheme reaches a SOCKS5 proxy that connects to resolved addresses,
+	// the socks5h scheme lets the SOCKS5 proxy resolve them, and the http scheme tunnels connections through an HTTP
+	// proxy with the CONNECT method. A username and password in the URL authenticate with the proxy.
+	// If unset, the proxy is taken from the environment, which doesn't apply to dqlite traffic.
+	Proxy *url.URL
+
+	// PinPeerCertificates makes connections to other cluster members only accept the exact certificate expected of
+	// them, rather than any certificate signed with its key.
+	PinPeerCertificates bool
+
+	// Resolver, if set, finds the cluster members that are no longer reachable at their recorded address.
+	// It applies to heartbeats, notifications and dqlite traffic alike.
+	Resolver Resolver
+
+	// Interceptors wrap every request sent by clients in the process, including the local control socket and the
+	// notifications sent to other cluster members, for instance to add tracing headers or log requests.
+	// They run in order, before the interceptors added to each client.
+	Interceptors []Interceptor
+}
+
+// Resolver finds the cluster members whose address changed since it was recorded, so that connections to their
+// recorded address are redirected to their current address.
+type Resolver interface {
+	// Current returns the address at which the cluster member recorded with the given address was last reached, or the
+	// given address if it has not moved.
+	Current(address string) string
+
+	// Resolve is called with the recorded address of a cluster member that could not be reached at its current
+	// address, and returns another address to try, such as the address its DNS name currently resolves to.
+	Resolve(ctx context.Context, address string) (string, error)
+
+	// Resolved is called once the cluster member recorded with the given address was reached at the resolved address,
+	// so that it is returned by Current from then on.
+	Resolved(address string, resolved string)
+}
+
+// Validate checks that the options can be applied to a transport.
+func (o TransportOptions) Validate() error {
+	if o.IdleConnTimeout < 0 {
+		return fmt.Errorf("Idle connection timeout cannot be negative")
+	}
+
+	if o.Proxy != nil {
+		err := validateProxy(o.Proxy)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transportKey identifies the clients that can share a transport.
+type transportKey struct {
+	clientFingerprint string
+	remoteFingerprint string
+	forwarding        bool
+}
+
+// transports holds the transports shared by clients, along with the options they were created with.
+var transports = struct {
+	sync.Mutex
+
+	options TransportOptions
+	cache   map[transportKey]*http.Transport
+}{
+	cache: map[transportKey]*http.Transport{},
+}
+
+// SetTransportOptions sets the options of the transports used by clients created from now on, by any daemon in the
+// process. Idle connections of the transports created with the previous options are closed.
+func SetTransportOptions(options TransportOptions) error {
+	err := options.Validate()
+	if err != nil {
+		return err
+	}
+
+	transports.Lock()
+	defer transports.Unlock()
+
+	for _, transport := range transports.cache {
+		transport.CloseIdleConnections()
+	}
+
+	transports.options = options
+	transports.cache = map[transportKey]*http.Transport{}
+
+	return nil
+}
+
+// sharedTransport returns the transport used for connections with the given certificates, creating it if needed.
+// Forwarding transports set the forwarding headers on every request.
+func sharedTransport(clientCert *shared.CertInfo, remoteCert *x509.Certificate, forwarding bool) (*http.Transport, error) {
+	key := transportKey{forwarding: forwarding}
+	if clientCert != nil {
+		key.clientFingerprint = clientCert.Fingerprint()
+	}
+
+	if remoteCert != nil {
+		key.remoteFingerprint = shared.CertFingerprint(remoteCert)
+	}
+
+	transports.Lock()
+	defer transports.Unlock()
+
+	transport, ok := transports.cache[key]
+	if ok {
+		return transport, nil
+	}
+
+	transport, err := tlsTransport(clientCert, remoteCert, forwarding, transports.options)
+	if err != nil {
+		return nil, err
+	}
+
+	transports.cache[key] = transport
+
+	return transport, nil
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures clients share transports unless their forwarding mode differs, and that new options replace the transports.
+func TestSharedTransport(t *testing.T) {
+	require.NoError(t, SetTransportOptions(TransportOptions{}))
+
+	first, err := sharedTransport(nil, nil, false)
+	require.NoError(t, err)
+	require.False(t, first.DisableKeepAlives)
+	require.Equal(t, DefaultMaxIdleConnsPerHost, first.MaxIdleConnsPerHost)
+	require.Equal(t, DefaultIdleConnTimeout, first.IdleConnTimeout)
+
+	second, err := sharedTransport(nil, nil, false)
+	require.NoError(t, err)
+	require.Same(t, first, second)
+
+	forwarding, err := sharedTransport(nil, nil, true)
+	require.NoError(t, err)
+	require.NotSame(t, first, forwarding)
+
+	require.Error(t, SetTransportOptions(TransportOptions{IdleConnTimeout: -time.Second}))
+	require.NoError(t, SetTransportOptions(TransportOptions{MaxIdleConnsPerHost: -1, IdleConnTimeout: time.Second}))
+	defer func() { require.NoError(t, SetTransportOptions(TransportOptions{})) }()
+
+	third, err := sharedTransport(nil, nil, false)
+	require.NoError(t, err)
+	require.NotSame(t, first, third)
+	require.True(t, third.DisableKeepAlives)
+	require.Equal(t, 0, third.MaxIdleConnsPerHost)
+	require.Equal(t, time.Second, third.IdleConnTimeout)
+}
@@ -25,3 +25,75 @@ func DeleteTrustStoreEntry(ctx context.Context, c *Client, name string) error {
 
 	return c.QueryStruct(queryCtx, "DELETE", internalTypes.InternalEndpoint, api.NewURL().Path("truststore", name), nil, nil)
 }
+
+// GetTrustedCertificates returns the certificates of the cluster members and of the API clients trusted by the cluster.
+// Supported filters are name, type and fingerprint.
+func (c *Client) GetTrustedCertificates(ctx context.Context, opts types.ListOptions) ([]types.TrustedCertificate, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	endpoint := api.NewURL().Path("truststore")
+	endpoint.URL.RawQuery = opts.Values().Encode()
+
+	certificates := []types.TrustedCertificate{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, endpoint, nil, &certificates)
+
+	return certificates, err
+}
+
+// AddTrustedCertificate trusts the certificate of an API client that is not a cluster member, on all cluster members.
+func (c *Client) AddTrustedCertificate(ctx context.Context, args types.TrustedCertificatePost) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, api.NewURL().Path("truststore"), args, nil)
+}
+
+// DeleteTrustedCertificate revokes the certificate of the API client with the given name, on all cluster members.
+func (c *Client) DeleteTrustedCertificate(ctx context.Context, name string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "DELETE", internalTypes.PublicEndpoint, api.NewURL().Path("truststore", name), nil, nil)
+}
+
+// CreateSession returns a bearer token standing for the certificate of a trusted API client.
+func (c *Client) CreateSession(ctx context.Context, args types.SessionPost) (*types.Session, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	session := &types.Session{}
+	err := c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, api.NewURL().Path("sessions"), args, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// GetCertificateRevocations returns the certificates revoked across the cluster.
+func (c *Client) GetCertificateRevocations(ctx context.Context) ([]types.CertificateRevocation, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	revocations := []types.CertificateRevocation{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("revocations"), nil, &revocations)
+
+	return revocations, err
+}
+
+// AddCertificateRevocation revokes the certificate with the given fingerprint, on all cluster members.
+func (c *Client) AddCertificateRevocation(ctx context.Context, args types.CertificateRevocationPost) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, api.NewURL().Path("revocations"), args, nil)
+}
+
+// DeleteCertificateRevocation lifts the revocation of the certificate with the given fingerprint, on all cluster members.
+func (c *Client) DeleteCertificateRevocation(ctx context.Context, fingerprint string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "DELETE", internalTypes.PublicEndpoint, api.NewURL().Path("revocations", fingerprint), nil, nil)
+}
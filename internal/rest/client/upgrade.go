@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetUpgradeStatus returns the progress of a coordinated upgrade, as seen by the cluster member.
+func (c *Client) GetUpgradeStatus(ctx context.Context) (*types.ClusterUpgradeStatus, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	upgradeStatus := types.ClusterUpgradeStatus{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("upgrade"), nil, &upgradeStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upgradeStatus, nil
+}
+
+// WaitUpgrade waits for the coordinated upgrade of the cluster member to complete, and returns its final upgrade stage.
+func (c *Client) WaitUpgrade(ctx context.Context) (types.UpgradeStage, error) {
+	operation, err := c.queryOperation(ctx, "POST", internalTypes.PublicEndpoint, api.NewURL().Path("upgrade"), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if operation == nil {
+		return "", fmt.Errorf("Cluster member did not start waiting for the upgrade")
+	}
+
+	stage, _ := operation.Result.(string)
+
+	return types.UpgradeStage(stage), nil
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetAPIVersions returns the versions of the core API served by the daemon.
+func (c *Client) GetAPIVersions(ctx context.Context) (*types.APIVersions, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	versions := &types.APIVersions{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.CoreEndpoint, nil, nil, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// NegotiateAPIVersion picks the newest version of the public core API supported by both the client and the daemon,
+// and sends the requests of the client to the public core API with that version from then on.
+// Daemons that do not list their versions only serve /core/1.0.
+func (c *Client) NegotiateAPIVersion(ctx context.Context) (types.EndpointPrefix, error) {
+	served := []types.EndpointPrefix{internalTypes.PublicEndpoint}
+	versions, err := c.GetAPIVersions(ctx)
+	if err == nil {
+		served = versions.Versions
+	} else if !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return "", err
+	}
+
+	for i := len(internalTypes.PublicEndpointVersions) - 1; i >= 0; i-- {
+		version := internalTypes.PublicEndpointVersions[i]
+		if slices.Contains(served, version) {
+			c.publicEndpoint = version
+
+			return version, nil
+		}
+	}
+
+	return "", api.StatusErrorf(http.StatusNotImplemented, "Daemon serves none of the supported core API versions %v", internalTypes.PublicEndpointVersions)
+}
+
+// APIVersion returns the version of the public core API used by the client.
+func (c *Client) APIVersion() types.EndpointPrefix {
+	if c.publicEndpoint == "" {
+		return internalTypes.PublicEndpoint
+	}
+
+	return c.publicEndpoint
+}
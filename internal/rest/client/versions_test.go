@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+)
+
+// Ensures the client uses the newest public core API version served by the daemon, and falls back to /core/1.0 for
+// daemons that do not list their versions.
+func TestNegotiateAPIVersion(t *testing.T) {
+	var versions string
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/core" {
+			if versions == "" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"type": "error", "error_code": 404, "error": "not found"}`))
+
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200, "metadata": {"versions": ` + versions + `}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200, "metadata": {}}`))
+	}))
+	defer server.Close()
+
+	newClient := func() *Client {
+		c := &Client{Client: server.Client(), extensions: &extensionsCache{}}
+		c.url = *api.NewURL().Scheme("http").Host(server.Listener.Addr().String())
+
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		versions string
+		expected string
+	}{
+		{name: "Newest common version", versions: `["core/1.0", "core/2.0", "core/3.0"]`, expected: "core/2.0"},
+		{name: "Older daemon", versions: `["core/1.0"]`, expected: "core/1.0"},
+		{name: "Unversioned daemon", versions: "", expected: "core/1.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			versions = test.versions
+			paths = nil
+
+			c := newClient()
+			require.Equal(t, internalTypes.PublicEndpoint, c.APIVersion())
+
+			version, err := c.NegotiateAPIVersion(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, test.expected, string(version))
+			require.Equal(t, version, c.APIVersion())
+
+			err = c.UseTarget("n1").QueryStruct(context.Background(), "GET", internalTypes.PublicEndpoint, api.NewURL().Path("cluster"), nil, nil)
+			require.NoError(t, err)
+			require.Equal(t, "/"+test.expected+"/cluster", paths[len(paths)-1])
+		})
+	}
+
+	versions = `["core/3.0"]`
+	_, err := newClient().NegotiateAPIVersion(context.Background())
+	require.Error(t, err)
+}
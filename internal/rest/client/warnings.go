@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetWarnings returns the warnings filed about every cluster member.
+// Supported filters are member, type, entity and status.
+func (c *Client) GetWarnings(ctx context.Context, opts types.ListOptions) ([]types.Warning, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	endpoint := api.NewURL().Path("warnings")
+	endpoint.URL.RawQuery = opts.Values().Encode()
+
+	warnings := []types.Warning{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, endpoint, nil, &warnings)
+
+	return warnings, err
+}
+
+// GetWarning returns the warning with the given UUID.
+func (c *Client) GetWarning(ctx context.Context, uuid string) (*types.Warning, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	warning := types.Warning{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("warnings", uuid), nil, &warning)
+	if err != nil {
+		return nil, err
+	}
+
+	return &warning, nil
+}
+
+// UpdateWarning sets the status of the warning with the given UUID, such as to acknowledge it.
+func (c *Client) UpdateWarning(ctx context.Context, uuid string, args types.WarningPut) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "PUT", internalTypes.PublicEndpoint, api.NewURL().Path("warnings", uuid), args, nil)
+}
+
+// DeleteWarning removes the warning with the given UUID.
+func (c *Client) DeleteWarning(ctx context.Context, uuid string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "DELETE", internalTypes.PublicEndpoint, api.NewURL().Path("warnings", uuid), nil, nil)
+}
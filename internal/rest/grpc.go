@@ -0,0 +1,20 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+)
+
+// GRPCHandler returns a handler that hands gRPC requests to grpcServer and all other requests to handler.
+// gRPC requests are those made over HTTP/2 with a content type of application/grpc, optionally followed by a
+// subtype such as +proto.
+func GRPCHandler(grpcServer http.Handler, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
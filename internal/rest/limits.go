@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/gorilla/websocket"
+
+	"github.com/canonical/microcluster/v3/rest"
+)
+
+// limitedBody is a request body of bounded size, which records whether the handler tried to read past the limit.
+type limitedBody struct {
+	io.ReadCloser
+
+	exceeded bool
+}
+
+// Read reads from the bounded body, and records whether the limit was reached.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+	}
+
+	return n, err
+}
+
+// requestTooLarge returns the error response for a request body larger than the given limit.
+func requestTooLarge(limit int64) response.Response {
+	return rest.SmartError(api.StatusErrorf(http.StatusRequestEntityTooLarge, "Request body exceeds the limit of %d bytes", limit))
+}
+
+// applyEndpointLimits bounds the size of the request body, the time allowed to read it and to write the response, and
+// sets the deadline of the request context, according to the limits of the endpoint.
+// It returns the updated request, the bounded body if any, and a function to release the request context. If the
+// request is rejected, the response to render is returned instead.
+// Websocket upgrades are only subject to the size limit, as the connection outlives the request.
+func applyEndpointLimits(e rest.Endpoint, w http.ResponseWriter, r *http.Request) (*http.Request, *limitedBody, context.CancelFunc, response.Response) {
+	var body *limitedBody
+	if e.MaxRequestBytes > 0 {
+		if r.ContentLength > e.MaxRequestBytes {
+			return r, nil, nil, requestTooLarge(e.MaxRequestBytes)
+		}
+
+		body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, e.MaxRequestBytes)}
+		r.Body = body
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		return r, body, func() {}, nil
+	}
+
+	controller := http.NewResponseController(w)
+	if e.ReadTimeout > 0 {
+		err := controller.SetReadDeadline(time.Now().Add(e.ReadTimeout))
+		if err != nil {
+			logger.Warn("Failed to set read deadline of request", logger.Ctx{"url": r.URL, "error": err})
+		}
+	}
+
+	if e.WriteTimeout > 0 {
+		err := controller.SetWriteDeadline(time.Now().Add(e.WriteTimeout))
+		if err != nil {
+			logger.Warn("Failed to set write deadline of request", logger.Ctx{"url": r.URL, "error": err})
+		}
+	}
+
+	cancel := func() {}
+	if e.HandlerTimeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeoutCause(r.Context(), e.HandlerTimeout, fmt.Errorf("Request exceeded the handler timeout of %s", e.HandlerTimeout))
+		r = r.WithContext(ctx)
+	}
+
+	return r, body, cancel, nil
+}
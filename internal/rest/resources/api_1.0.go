@@ -1,13 +1,18 @@
 package resources
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"net/http"
 
 	"github.com/canonical/lxd/lxd/response"
 
+	"github.com/canonical/microcluster/v3/cluster"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
 	"github.com/canonical/microcluster/v3/rest/types"
 	"github.com/canonical/microcluster/v3/state"
 )
@@ -26,14 +31,98 @@ func api10Get(s state.State, r *http.Request) response.Response {
 
 	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
-	return response.SyncResponse(true, internalTypes.Server{
+	server := internalTypes.Server{
 		Name:       s.Name(),
 		Address:    addrPort,
 		Version:    s.Version(),
 		Ready:      s.Database().IsOpen(r.Context()) == nil,
 		Extensions: intState.Extensions,
+	}
+
+	// Only reveal the cluster members to trusted clients.
+	trusted, _ := access.AllowAuthenticated(s, r)
+	if trusted && server.Ready {
+		server.Members, err = memberHealth(r.Context(), s)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		server.Warnings, err = newWarnings(r.Context(), s)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+	}
+
+	if trusted {
+		server.Certificates, err = intState.Certificates()
+		if err != nil {
+			return rest.SmartError(err)
+		}
+	}
+
+	return response.SyncResponse(true, server)
+}
+
+// memberHealth probes all cluster members and returns their status.
+func memberHealth(ctx context.Context, s state.State) ([]types.MemberHealth, error) {
+	var members []types.ClusterMember
+	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		clusterMembers, err := cluster.GetCoreClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		members = make([]types.ClusterMember, 0, len(clusterMembers))
+		for _, clusterMember := range clusterMembers {
+			member, err := clusterMember.ToAPI()
+			if err != nil {
+				return err
+			}
+
+			members = append(members, *member)
+		}
+
+		return setMaintenanceStatus(ctx, tx, members)
 	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get cluster members: %w", err)
+	}
+
+	err = probeClusterMembers(ctx, s, members)
+	if err != nil {
+		return nil, err
+	}
+
+	health := make([]types.MemberHealth, 0, len(members))
+	for _, member := range members {
+		health = append(health, types.MemberHealth{
+			Name:          member.Name,
+			Address:       member.Address,
+			Status:        member.Status,
+			LastHeartbeat: member.LastHeartbeat,
+			Latency:       member.Latency,
+		})
+	}
+
+	return health, nil
+}
+
+// newWarnings returns the warnings about every cluster member that have not been acknowledged.
+func newWarnings(ctx context.Context, s state.State) ([]types.Warning, error) {
+	warnings, err := getWarnings(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get warnings: %w", err)
+	}
+
+	result := []types.Warning{}
+	for _, warning := range warnings {
+		if warning.Status == types.WarningStatusNew {
+			result = append(result, warning)
+		}
+	}
+
+	return result, nil
 }
@@ -0,0 +1,55 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var auditCmd = rest.Endpoint{
+	Path:              "audit",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: auditGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+// auditGet returns the audit log entries of the cluster member, most recent first.
+// Entries can be restricted to those recorded since an RFC 3339 timestamp, and filtered by method, path and status code.
+func auditGet(s state.State, r *http.Request) response.Response {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	if intState.AuditLog == nil {
+		return response.NotFound(fmt.Errorf("Audit log is not enabled"))
+	}
+
+	opts, err := types.ParseListOptions(r.URL.Query(), "method", "path", "status_code")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var since time.Time
+	if r.URL.Query().Has("since") {
+		since, err = time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid since timestamp %q: %w", r.URL.Query().Get("since"), err))
+		}
+	}
+
+	entries, err := intState.AuditLog.Entries(since)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return listResponse(entries, opts)
+}
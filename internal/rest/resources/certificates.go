@@ -2,20 +2,30 @@ package resources
 
 import (
 	"context"
+	"crypto/tls"
+	"database/sql"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
 	"github.com/gorilla/mux"
 
 	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/cluster"
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/rest"
 	"github.com/canonical/microcluster/v3/rest/access"
@@ -23,6 +33,12 @@ import (
 	"github.com/canonical/microcluster/v3/state"
 )
 
+// stagedSuffix is appended to the files of a keypair that is staged for rotation.
+const stagedSuffix = ".staged"
+
+// notificationTimeout is how long each cluster member is given to respond to a notification fanned out to the cluster.
+const notificationTimeout = 30 * time.Second
+
 var clusterCertificatesCmd = rest.Endpoint{
 	AllowedBeforeInit: true,
 	Path:              "cluster/certificates/{name}",
@@ -30,10 +46,22 @@ var clusterCertificatesCmd = rest.Endpoint{
 	Put: rest.EndpointAction{Handler: clusterCertificatesPut, AccessHandler: access.AllowAuthenticated},
 }
 
+var clusterMemberCertificateCmd = rest.Endpoint{
+	Path: "cluster/{name}/certificate",
+
+	Put: rest.EndpointAction{Handler: clusterMemberCertificatePut, AccessHandler: access.AllowAuthenticated},
+}
+
+var clusterCertificatesRotationCmd = rest.Endpoint{
+	Path: "cluster/certificates/{name}/rotation",
+
+	Post: rest.EndpointAction{Handler: clusterCertificatesRotationPost, AccessHandler: access.AllowAuthenticated},
+}
+
 func clusterCertificatesPut(s state.State, r *http.Request) response.Response {
 	certificateName, err := url.PathUnescape(mux.Vars(r)["name"])
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	req := types.KeyPair{}
@@ -44,101 +72,687 @@ func clusterCertificatesPut(s state.State, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	err = validateKeyPair(req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, err = certificateDir(s, certificateName)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
 	err = s.Database().IsOpen(r.Context())
 	if err != nil {
 		logger.Warn(fmt.Sprintf("Database is offline, only updating local %q certificate", certificateName), logger.Ctx{"error": err})
 	}
 
-	// Forward the request to all other nodes if we are the first.
+	// Rotate the certificate on all cluster members if we are the first.
 	if !client.IsNotification(r) && err == nil {
-		cluster, err := s.Cluster(true)
+		err = rotateCertificate(r.Context(), s, types.CertificateName(certificateName), req)
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
-		err = cluster.Query(r.Context(), true, func(ctx context.Context, c *client.Client) error {
-			return c.UpdateCertificate(ctx, types.CertificateName(certificateName), req)
-		})
+		return response.EmptySyncResponse
+	}
+
+	// Stage the keypair before replacing the active one, so that the key and certificate are never left mismatched.
+	fingerprint, err := stageCertificate(s, certificateName, req)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = commitCertificate(r.Context(), s, certificateName, fingerprint)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func clusterCertificatesRotationPost(s state.State, r *http.Request) response.Response {
+	certificateName, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	req := types.CertificateRotation{}
+
+	// Parse the request.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	switch req.Phase {
+	case types.CertificateRotationStage:
+		fingerprint, err := stageCertificate(s, certificateName, req.KeyPair)
 		if err != nil {
-			return response.SmartError(fmt.Errorf("Failed to update %q certificate on peers: %w", certificateName, err))
+			return rest.SmartError(err)
 		}
+
+		return response.SyncResponse(true, types.CertificateRotationStatus{Fingerprint: fingerprint})
+	case types.CertificateRotationCommit:
+		err = commitCertificate(r.Context(), s, certificateName, req.Fingerprint)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		return response.SyncResponse(true, types.CertificateRotationStatus{Fingerprint: req.Fingerprint})
+	case types.CertificateRotationAbort:
+		err = abortCertificate(s, certificateName)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	default:
+		return response.BadRequest(fmt.Errorf("Invalid certificate rotation phase %q", req.Phase))
 	}
+}
 
-	certBlock, _ := pem.Decode([]byte(req.Cert))
-	if certBlock == nil {
-		return response.BadRequest(fmt.Errorf("Certificate must be base64 encoded PEM certificate"))
+// rotateCertificate stages the keypair on every cluster member and verifies that each of them reports the expected
+// fingerprint before committing it anywhere, so that no member loads the new certificate unless all of them can.
+// Peers commit first, and the local member last, as it holds the connections to the peers.
+func rotateCertificate(ctx context.Context, s state.State, name types.CertificateName, keyPair types.KeyPair) error {
+	dir, err := certificateDir(s, string(name))
+	if err != nil {
+		return err
 	}
 
-	keyBlock, _ := pem.Decode([]byte(req.Key))
-	if keyBlock == nil {
-		return response.BadRequest(fmt.Errorf("Private key must be base64 encoded PEM key"))
+	previous, err := readKeyPair(dir, string(name))
+	if err != nil {
+		return fmt.Errorf("Failed to read active %q certificate: %w", name, err)
 	}
 
-	// Validate the certificate's name.
-	if strings.Contains(certificateName, "/") || strings.Contains(certificateName, "\\") || strings.Contains(certificateName, "..") {
-		return response.BadRequest(fmt.Errorf("Certificate name cannot be a path"))
+	fingerprint, err := stageCertificate(s, string(name), keyPair)
+	if err != nil {
+		return err
 	}
 
-	var certificateDir string
-	if certificateName == string(types.ClusterCertificateName) {
-		certificateDir = s.FileSystem().StateDir
-	} else if certificateName == string(types.ServerCertificateName) {
-		certificateDir = s.FileSystem().StateDir
-		if s.Database().Status() != types.DatabaseNotReady {
-			return response.SmartError(fmt.Errorf("Cannot replace server certificate after initialization"))
+	cluster, err := s.Cluster(true)
+	if err != nil {
+		return errors.Join(err, abortCertificate(s, string(name)))
+	}
+
+	err = cluster.FanOut(ctx, notificationTimeout, func(ctx context.Context, c *client.Client) error {
+		stagedFingerprint, err := c.StageCertificate(ctx, name, keyPair)
+		if err != nil {
+			return err
+		}
+
+		if stagedFingerprint != fingerprint {
+			return fmt.Errorf("Staged certificate with fingerprint %q, expected %q", stagedFingerprint, fingerprint)
+		}
+
+		return nil
+	}).Err()
+	if err != nil {
+		abortErr := cluster.FanOut(ctx, notificationTimeout, func(ctx context.Context, c *client.Client) error {
+			return c.AbortCertificate(ctx, name)
+		}).Err()
+		if abortErr != nil {
+			logger.Warn("Failed to discard staged certificate on peers", logger.Ctx{"name": name, "error": abortErr})
+		}
+
+		return errors.Join(fmt.Errorf("Failed to stage %q certificate on peers: %w", name, err), abortCertificate(s, string(name)))
+	}
+
+	err = commitRotation(ctx, s, cluster, name, fingerprint, keyPair, previous)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Completed certificate rotation on all cluster members", logger.Ctx{"name": name, "fingerprint": fingerprint})
+
+	return nil
+}
+
+// commitRotation commits the keypair staged on every cluster member, on the peers first and on the local member last.
+// If any member fails to commit it, the members that did are rolled back to the previous keypair and the others discard
+// the staged one, so that the cluster is not left split between the two certificates. The returned error then lists
+// the members that could not be rolled back, if any.
+func commitRotation(ctx context.Context, s state.State, peers client.Cluster, name types.CertificateName, fingerprint string, keyPair types.KeyPair, previous *types.KeyPair) error {
+	result := peers.FanOut(ctx, notificationTimeout, func(ctx context.Context, c *client.Client) error {
+		return c.CommitCertificate(ctx, name, fingerprint)
+	})
+
+	committed := make(client.Cluster, 0, len(peers))
+	pending := make(client.Cluster, 0, len(peers))
+	for i, member := range result {
+		if member.Error == nil {
+			committed = append(committed, peers[i])
+		} else {
+			pending = append(pending, peers[i])
+		}
+	}
+
+	var localErr error
+	err := result.Err()
+	if err == nil {
+		err = commitCertificate(ctx, s, string(name), fingerprint)
+		if err == nil {
+			return nil
 		}
+
+		// The local member may have replaced some of its files before failing, so the previous keypair is restored.
+		err = fmt.Errorf("Failed to commit %q certificate locally: %w", name, err)
+		localErr = restoreCertificate(ctx, s, name, previous)
 	} else {
-		certificateDir = s.FileSystem().CertificatesDir
-
-		// Check if an additional listener exists for that name.
-		// We cannot query the daemon's config of the additional listeners as
-		// they might not yet be confiugred on every cluster member.
-		found := false
-		for _, name := range s.ExtensionServers() {
-			if name == certificateName {
-				found = true
+		err = fmt.Errorf("Failed to commit %q certificate on peers: %w", name, err)
+		localErr = abortCertificate(s, string(name))
+	}
+
+	if localErr != nil {
+		localErr = fmt.Errorf("Failed to restore previous %q certificate locally: %w", name, localErr)
+	}
+
+	// Peers that failed to commit may not be reachable, so they are only told to discard the staged keypair.
+	abortErr := pending.FanOut(ctx, notificationTimeout, func(ctx context.Context, c *client.Client) error {
+		return c.AbortCertificate(ctx, name)
+	}).Err()
+	if abortErr != nil {
+		logger.Warn("Failed to discard staged certificate on peers", logger.Ctx{"name": name, "error": abortErr})
+	}
+
+	return errors.Join(err, localErr, rollbackCertificate(ctx, s, committed, name, keyPair, previous))
+}
+
+// rollbackCertificate has the peers that committed the new keypair load the previous one again. Peers that committed a
+// new cluster certificate serve it, so they are reached trusting it instead of the active one.
+func rollbackCertificate(ctx context.Context, s state.State, peers client.Cluster, name types.CertificateName, keyPair types.KeyPair, previous *types.KeyPair) error {
+	if len(peers) == 0 {
+		return nil
+	}
+
+	if previous == nil {
+		addresses := make([]string, 0, len(peers))
+		for _, peer := range peers {
+			addresses = append(addresses, peer.URL().URL.Host)
+		}
+
+		return fmt.Errorf("No previous %q certificate to restore, cluster members %q kept the new one", name, addresses)
+	}
+
+	if name == types.ClusterCertificateName {
+		newCert, err := types.ParseX509Certificate(keyPair.Cert)
+		if err != nil {
+			return err
+		}
+
+		rotated := make(client.Cluster, 0, len(peers))
+		for _, peer := range peers {
+			c, err := internalClient.New(*api.NewURL().Scheme("https").Host(peer.URL().URL.Host), s.ServerCert(), newCert.Certificate, true)
+			if err != nil {
+				return err
 			}
+
+			rotated = append(rotated, client.Client{Client: *c})
 		}
 
-		if !found {
-			return response.BadRequest(fmt.Errorf("No matching additional server found for %q", certificateName))
+		peers = rotated
+	}
+
+	previousFingerprint, err := shared.CertFingerprintStr(previous.Cert)
+	if err != nil {
+		return err
+	}
+
+	err = peers.FanOut(ctx, notificationTimeout, func(ctx context.Context, c *client.Client) error {
+		stagedFingerprint, err := c.StageCertificate(ctx, name, *previous)
+		if err != nil {
+			return err
+		}
+
+		if stagedFingerprint != previousFingerprint {
+			return fmt.Errorf("Staged certificate with fingerprint %q, expected %q", stagedFingerprint, previousFingerprint)
 		}
+
+		return c.CommitCertificate(ctx, name, previousFingerprint)
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("Failed to restore previous %q certificate on peers, which kept the new one: %w", name, err)
 	}
 
-	// If a CA was specified, validate that as well.
-	if req.CA != "" {
-		caBlock, _ := pem.Decode([]byte(req.CA))
-		if caBlock == nil {
-			return response.BadRequest(fmt.Errorf("CA must be base64 encoded PEM key"))
+	return nil
+}
+
+// restoreCertificate replaces the local keypair with the previous one, or discards the staged keypair if there was
+// none.
+func restoreCertificate(ctx context.Context, s state.State, name types.CertificateName, previous *types.KeyPair) error {
+	if previous == nil {
+		return abortCertificate(s, string(name))
+	}
+
+	fingerprint, err := stageCertificate(s, string(name), *previous)
+	if err != nil {
+		return err
+	}
+
+	return commitCertificate(ctx, s, string(name), fingerprint)
+}
+
+// clusterMemberCertificatePut trusts the renewed certificate of a cluster member. Only the cluster member itself can
+// renew its certificate, and the certificate must match the one recorded in the database.
+func clusterMemberCertificatePut(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	identity, ok := access.GetIdentity(r)
+	if !ok || identity.Name != name {
+		return response.Forbidden(fmt.Errorf("Only cluster member %q can renew its certificate", name))
+	}
+
+	req := types.X509Certificate{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Certificate == nil {
+		return response.BadRequest(fmt.Errorf("Cluster member %q has no certificate", name))
+	}
+
+	var recorded string
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		member, err := cluster.GetCoreClusterMember(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		recorded = member.Certificate
+
+		return nil
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	fingerprint := shared.CertFingerprint(req.Certificate)
+	recordedFingerprint, err := shared.CertFingerprintStr(recorded)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	if recordedFingerprint != fingerprint {
+		return rest.SmartError(api.StatusErrorf(http.StatusConflict, "Certificate of cluster member %q has fingerprint %q, expected %q", name, fingerprint, recordedFingerprint))
+	}
+
+	remote, ok := s.Remotes().RemotesByName()[name]
+	if !ok {
+		return rest.SmartError(api.StatusErrorf(http.StatusNotFound, "%w: %q", types.ErrMemberNotFound, name))
+	}
+
+	remote.Certificate = req
+	err = s.Remotes().Update(remote)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	logger.Info("Trusting renewed cluster member certificate", logger.Ctx{"name": name, "fingerprint": fingerprint})
+
+	return response.EmptySyncResponse
+}
+
+// RenewServerCertificate replaces the certificate identifying the local cluster member with a newly generated one.
+// The new certificate is recorded in the database and trusted by the other cluster members before it is loaded, so
+// that they keep trusting the local member. If any member can't be notified, the renewal is reverted on every member,
+// as the member would otherwise reject the local member until the next heartbeat.
+func RenewServerCertificate(ctx context.Context, s state.State) error {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return err
+	}
+
+	name := s.Name()
+	certName := string(types.ServerCertificateName)
+	dir := s.FileSystem().StateDir
+
+	oldCert, err := s.ServerCert().PublicKeyX509()
+	if err != nil {
+		return fmt.Errorf("Failed to parse server certificate: %w", err)
+	}
+
+	cert, key, err := shared.GenerateMemCert(false, shared.CertOptions{AddHosts: true, CommonName: name})
+	if err != nil {
+		return err
+	}
+
+	newCert, err := types.ParseX509Certificate(string(cert))
+	if err != nil {
+		return err
+	}
+
+	fingerprint := shared.CertFingerprint(newCert.Certificate)
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	err = writeKeyPair(dir, certName, stagedSuffix, types.KeyPair{Cert: string(cert), Key: string(key)})
+	if err != nil {
+		return err
+	}
+
+	reverter.Add(func() {
+		for _, ext := range []string{"key", "crt"} {
+			_ = os.Remove(filepath.Join(dir, fmt.Sprintf("%s.%s%s", certName, ext, stagedSuffix)))
 		}
+	})
 
-		err = os.WriteFile(filepath.Join(certificateDir, fmt.Sprintf("%s.ca", certificateName)), []byte(req.CA), 0664)
+	setCertificate := func(ctx context.Context, cert types.X509Certificate) error {
+		return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			return cluster.UpdateCoreClusterMemberCertificate(ctx, tx, name, cert.String())
+		})
+	}
+
+	err = setCertificate(ctx, *newCert)
+	if err != nil {
+		return err
+	}
+
+	reverter.Add(func() {
+		err := setCertificate(context.Background(), types.X509Certificate{Certificate: oldCert})
 		if err != nil {
-			return response.SmartError(err)
+			logger.Error("Failed to restore cluster member certificate", logger.Ctx{"name": name, "error": err})
 		}
+	})
+
+	remote, ok := s.Remotes().RemotesByName()[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", types.ErrMemberNotFound, name)
+	}
+
+	oldRemote := remote
+	remote.Certificate = *newCert
+	err = s.Remotes().Update(remote)
+	if err != nil {
+		return err
+	}
+
+	reverter.Add(func() { _ = s.Remotes().Update(oldRemote) })
+
+	peers, err := s.Cluster(true)
+	if err != nil {
+		return err
+	}
+
+	accepted, err := notifyRenewedCertificate(ctx, peers, name, *newCert)
+	if err != nil {
+		// Peers that trusted the renewed certificate no longer accept the old one, so they are told to trust the old
+		// certificate again over connections presenting the renewed one, once its database record is restored.
+		restoreErr := setCertificate(ctx, types.X509Certificate{Certificate: oldCert})
+		if restoreErr == nil {
+			restoreErr = restoreRenewedCertificate(ctx, s, accepted, cert, key, name, types.X509Certificate{Certificate: oldCert})
+		}
+
+		if restoreErr != nil {
+			logger.Error("Failed to restore cluster member certificate on peers", logger.Ctx{"name": name, "error": restoreErr})
+		}
+
+		return fmt.Errorf("Failed to notify cluster members of renewed certificate: %w", err)
+	}
+
+	for _, ext := range []string{"key", "crt"} {
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s", certName, ext))
+		err = os.Rename(path+stagedSuffix, path)
+		if err != nil {
+			return fmt.Errorf("Failed to commit renewed %q certificate: %w", certName, err)
+		}
+	}
+
+	reverter.Success()
+
+	err = intState.ReloadCert(types.ServerCertificateName)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Renewed cluster member certificate", logger.Ctx{"name": name, "fingerprint": fingerprint})
+
+	return intState.Hooks.OnCertificateRotated(ctx, s, types.ServerCertificateName, fingerprint)
+}
+
+// notifyRenewedCertificate has every peer trust the renewed certificate of the named cluster member. If any peer fails
+// to, it returns the peers that trusted the renewed certificate along with the error.
+func notifyRenewedCertificate(ctx context.Context, peers client.Cluster, name string, cert types.X509Certificate) (client.Cluster, error) {
+	result := peers.FanOut(ctx, notificationTimeout, func(ctx context.Context, c *client.Client) error {
+		return internalClient.UpdateClusterMemberCertificate(ctx, &c.Client, name, cert)
+	})
+
+	accepted := make(client.Cluster, 0, len(peers))
+	for i, member := range result {
+		if member.Error == nil {
+			accepted = append(accepted, peers[i])
+		}
+	}
+
+	return accepted, result.Err()
+}
+
+// restoreRenewedCertificate has the peers that trusted the renewed certificate of the named cluster member trust its
+// old certificate again. The peers are reached with the renewed keypair, as they no longer trust the old one.
+func restoreRenewedCertificate(ctx context.Context, s state.State, peers client.Cluster, cert []byte, key []byte, name string, oldCert types.X509Certificate) error {
+	if len(peers) == 0 {
+		return nil
+	}
+
+	keyPair, err := shared.KeyPairFromRaw(cert, key)
+	if err != nil {
+		return err
+	}
+
+	clusterCert, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return err
+	}
+
+	renewed := make(client.Cluster, 0, len(peers))
+	for _, peer := range peers {
+		c, err := internalClient.New(*api.NewURL().Scheme("https").Host(peer.URL().URL.Host), keyPair, clusterCert, true)
+		if err != nil {
+			return err
+		}
+
+		renewed = append(renewed, client.Client{Client: *c})
+	}
+
+	return renewed.FanOut(ctx, notificationTimeout, func(ctx context.Context, c *client.Client) error {
+		return internalClient.UpdateClusterMemberCertificate(ctx, &c.Client, name, oldCert)
+	}).Err()
+}
+
+// stageCertificate writes the keypair next to the active one without loading it, and returns the certificate's fingerprint.
+func stageCertificate(s state.State, certificateName string, keyPair types.KeyPair) (string, error) {
+	err := validateKeyPair(keyPair)
+	if err != nil {
+		return "", api.StatusErrorf(http.StatusBadRequest, "%w", err)
+	}
+
+	dir, err := certificateDir(s, certificateName)
+	if err != nil {
+		return "", err
+	}
+
+	// Remove any leftover CA from an earlier rotation, so that it does not get committed along with this keypair.
+	err = os.Remove(filepath.Join(dir, fmt.Sprintf("%s.ca%s", certificateName, stagedSuffix)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
 	}
 
-	// Write the keypair to the state directory.
-	err = os.WriteFile(filepath.Join(certificateDir, fmt.Sprintf("%s.crt", certificateName)), []byte(req.Cert), 0664)
+	err = writeKeyPair(dir, certificateName, stagedSuffix, keyPair)
 	if err != nil {
-		return response.SmartError(err)
+		return "", err
 	}
 
-	err = os.WriteFile(filepath.Join(certificateDir, fmt.Sprintf("%s.key", certificateName)), []byte(req.Key), 0600)
+	return shared.CertFingerprintStr(keyPair.Cert)
+}
+
+// commitCertificate replaces the active keypair with the staged one if the staged certificate has the given fingerprint,
+// reloads it, and runs the OnCertificateRotated hook.
+func commitCertificate(ctx context.Context, s state.State, certificateName string, fingerprint string) error {
+	dir, err := certificateDir(s, certificateName)
+	if err != nil {
+		return err
+	}
+
+	stagedCert, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("%s.crt%s", certificateName, stagedSuffix)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return api.StatusErrorf(http.StatusNotFound, "No staged %q certificate", certificateName)
+		}
+
+		return err
+	}
+
+	stagedFingerprint, err := shared.CertFingerprintStr(string(stagedCert))
 	if err != nil {
-		return response.SmartError(err)
+		return err
+	}
+
+	if stagedFingerprint != fingerprint {
+		return api.StatusErrorf(http.StatusConflict, "Staged %q certificate has fingerprint %q, expected %q", certificateName, stagedFingerprint, fingerprint)
+	}
+
+	// The CA is optional, so only replace it if one was staged.
+	for _, ext := range []string{"ca", "key", "crt"} {
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s", certificateName, ext))
+		err = os.Rename(path+stagedSuffix, path)
+		if err != nil && (ext != "ca" || !errors.Is(err, fs.ErrNotExist)) {
+			return fmt.Errorf("Failed to commit staged %q certificate: %w", certificateName, err)
+		}
 	}
 
 	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
-	// Load the new cert from the state directory on this node.
 	err = intState.ReloadCert(types.CertificateName(certificateName))
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
-	return response.EmptySyncResponse
+	return intState.Hooks.OnCertificateRotated(ctx, s, types.CertificateName(certificateName), fingerprint)
+}
+
+// abortCertificate removes the staged keypair, if any.
+func abortCertificate(s state.State, certificateName string) error {
+	dir, err := certificateDir(s, certificateName)
+	if err != nil {
+		return err
+	}
+
+	for _, ext := range []string{"ca", "key", "crt"} {
+		err = os.Remove(filepath.Join(dir, fmt.Sprintf("%s.%s%s", certificateName, ext, stagedSuffix)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateKeyPair checks that the keypair and optional CA are PEM encoded, and that the key matches the certificate.
+func validateKeyPair(keyPair types.KeyPair) error {
+	certBlock, _ := pem.Decode([]byte(keyPair.Cert))
+	if certBlock == nil {
+		return fmt.Errorf("Certificate must be base64 encoded PEM certificate")
+	}
+
+	keyBlock, _ := pem.Decode([]byte(keyPair.Key))
+	if keyBlock == nil {
+		return fmt.Errorf("Private key must be base64 encoded PEM key")
+	}
+
+	// If a CA was specified, validate that as well.
+	if keyPair.CA != "" {
+		caBlock, _ := pem.Decode([]byte(keyPair.CA))
+		if caBlock == nil {
+			return fmt.Errorf("CA must be base64 encoded PEM key")
+		}
+	}
+
+	_, err := tls.X509KeyPair([]byte(keyPair.Cert), []byte(keyPair.Key))
+	if err != nil {
+		return fmt.Errorf("Invalid keypair: %w", err)
+	}
+
+	return nil
+}
+
+// certificateDir returns the directory holding the certificate with the given name.
+func certificateDir(s state.State, certificateName string) (string, error) {
+	// Validate the certificate's name.
+	if strings.Contains(certificateName, "/") || strings.Contains(certificateName, "\\") || strings.Contains(certificateName, "..") {
+		return "", api.StatusErrorf(http.StatusBadRequest, "Certificate name cannot be a path")
+	}
+
+	if certificateName == string(types.ClusterCertificateName) {
+		return s.FileSystem().StateDir, nil
+	}
+
+	if certificateName == string(types.ServerCertificateName) {
+		if s.Database().Status() != types.DatabaseNotReady {
+			return "", fmt.Errorf("Cannot replace server certificate after initialization")
+		}
+
+		return s.FileSystem().StateDir, nil
+	}
+
+	// Check if an additional listener exists for that name.
+	// We cannot query the daemon's config of the additional listeners as
+	// they might not yet be confiugred on every cluster member.
+	for _, name := range s.ExtensionServers() {
+		if name == certificateName {
+			return s.FileSystem().CertificatesDir, nil
+		}
+	}
+
+	return "", api.StatusErrorf(http.StatusBadRequest, "No matching additional server found for %q", certificateName)
+}
+
+// readKeyPair reads the active keypair and optional CA from the given directory, or returns nil if there is none.
+func readKeyPair(dir string, certificateName string) (*types.KeyPair, error) {
+	keyPair := &types.KeyPair{}
+	for _, file := range []struct {
+		ext   string
+		value *string
+	}{{ext: "ca", value: &keyPair.CA}, {ext: "crt", value: &keyPair.Cert}, {ext: "key", value: &keyPair.Key}} {
+		content, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("%s.%s", certificateName, file.ext)))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && file.ext == "ca" {
+				continue
+			}
+
+			if errors.Is(err, fs.ErrNotExist) && file.ext == "crt" {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		*file.value = string(content)
+	}
+
+	return keyPair, nil
+}
+
+// writeKeyPair writes the keypair and optional CA to the given directory, appending the suffix to each file name.
+func writeKeyPair(dir string, certificateName string, suffix string, keyPair types.KeyPair) error {
+	if keyPair.CA != "" {
+		err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.ca%s", certificateName, suffix)), []byte(keyPair.CA), 0664)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.crt%s", certificateName, suffix)), []byte(keyPair.Cert), 0664)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.key%s", certificateName, suffix)), []byte(keyPair.Key), 0600)
 }
@@ -0,0 +1,437 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/db"
+	internalAccess "github.com/canonical/microcluster/v3/internal/rest/access"
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/trust"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+// testDB runs transactions against a sqlite database.
+type testDB struct {
+	db.DB
+
+	sqlDB *sql.DB
+}
+
+func (d *testDB) Transaction(ctx context.Context, f func(context.Context, *sql.Tx) error) error {
+	return query.Transaction(ctx, d.sqlDB, f)
+}
+
+// testState holds the database and truststore of a cluster member.
+type testState struct {
+	state.State
+
+	db      db.DB
+	remotes *trust.Remotes
+}
+
+func (s *testState) Database() db.DB { return s.db }
+
+func (s *testState) Remotes() *trust.Remotes { return s.remotes }
+
+// memRemotes stores the remotes in memory.
+type memRemotes struct {
+	remotes []trust.Remote
+}
+
+func (b *memRemotes) Load() ([]trust.Remote, error) { return b.remotes, nil }
+
+func (b *memRemotes) Add(remote trust.Remote) error {
+	b.remotes = append(b.remotes, remote)
+	return nil
+}
+
+func (b *memRemotes) Replace(remotes []trust.Remote) error {
+	b.remotes = remotes
+	return nil
+}
+
+func (b *memRemotes) Watch(refresh func() error) {}
+
+// Ensures only the renewing cluster member can have its renewed certificate trusted, and only once it is recorded in
+// the database.
+func TestClusterMemberCertificatePut(t *testing.T) {
+	ctx := context.Background()
+	oldCert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	newCert, err := shared.TestingAltKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	address, err := types.ParseAddrPort("10.0.0.1:9000")
+	require.NoError(t, err)
+
+	sqlDB := newTestDB(t)
+	s := &testState{db: &testDB{sqlDB: sqlDB}, remotes: trust.NewRemotes(&memRemotes{})}
+	err = s.remotes.Add(trust.Remote{Location: trust.Location{Name: "m1", Address: address}, Certificate: types.X509Certificate{Certificate: oldCert}})
+	require.NoError(t, err)
+
+	setRecorded := func(cert types.X509Certificate) {
+		err := s.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			return cluster.UpdateCoreClusterMemberCertificate(ctx, tx, "m1", cert.String())
+		})
+		require.NoError(t, err)
+	}
+
+	err = s.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.CreateCoreClusterMember(ctx, tx, cluster.CoreClusterMember{Name: "m1", Address: address.String(), Certificate: types.X509Certificate{Certificate: oldCert}.String(), Role: "voter"})
+		return err
+	})
+	require.NoError(t, err)
+
+	put := func(identity internalAccess.Identity, cert types.X509Certificate) int {
+		body, err := json.Marshal(cert)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPut, "/core/internal/cluster/m1/certificate", bytes.NewReader(body))
+		r = mux.SetURLVars(r, map[string]string{"name": "m1"})
+		r = internalAccess.SetRequestIdentity(r, identity)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, clusterMemberCertificatePut(s, r).Render(w))
+
+		return w.Code
+	}
+
+	member := internalAccess.Identity{Method: internalAccess.AuthMethodTLS, Trusted: true, Name: "m1"}
+	other := internalAccess.Identity{Method: internalAccess.AuthMethodTLS, Trusted: true, Name: "m2"}
+	renewed := types.X509Certificate{Certificate: newCert}
+
+	// Only the cluster member itself can renew its certificate.
+	require.Equal(t, http.StatusForbidden, put(other, renewed))
+
+	// The renewed certificate must be recorded in the database first.
+	require.Equal(t, http.StatusConflict, put(member, renewed))
+	require.Same(t, oldCert, s.remotes.RemotesByName()["m1"].Certificate.Certificate)
+
+	setRecorded(renewed)
+	require.Equal(t, http.StatusOK, put(member, renewed))
+	require.Equal(t, shared.CertFingerprint(newCert), shared.CertFingerprint(s.remotes.RemotesByName()["m1"].Certificate.Certificate))
+
+	// A reverted renewal restores the old certificate.
+	setRecorded(types.X509Certificate{Certificate: oldCert})
+	require.Equal(t, http.StatusOK, put(member, types.X509Certificate{Certificate: oldCert}))
+	require.Equal(t, shared.CertFingerprint(oldCert), shared.CertFingerprint(s.remotes.RemotesByName()["m1"].Certificate.Certificate))
+}
+
+// Ensures a renewed certificate is reported as failed unless every peer trusted it, along with the peers that did.
+func TestNotifyRenewedCertificate(t *testing.T) {
+	cert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	var received atomic.Int64
+	accept := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/core/internal/cluster/m1/certificate", r.URL.Path)
+
+		req := types.X509Certificate{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, shared.CertFingerprint(cert), shared.CertFingerprint(req.Certificate))
+		received.Add(1)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.ResponseRaw{Type: api.SyncResponse, Status: api.Success.String(), StatusCode: int(api.Success)})
+	}))
+	defer accept.Close()
+
+	reject := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(api.ResponseRaw{Type: api.ErrorResponse, Error: "Not authorized", Code: http.StatusForbidden})
+	}))
+	defer reject.Close()
+
+	newCluster := func(servers ...*httptest.Server) client.Cluster {
+		peers := client.Cluster{}
+		for _, server := range servers {
+			c, err := internalClient.New(*api.NewURL().Scheme("http").Host(server.Listener.Addr().String()), nil, nil, true)
+			require.NoError(t, err)
+
+			peers = append(peers, client.Client{Client: *c})
+		}
+
+		return peers
+	}
+
+	peers := newCluster(accept, accept)
+	accepted, err := notifyRenewedCertificate(context.Background(), peers, "m1", types.X509Certificate{Certificate: cert})
+	require.NoError(t, err)
+	require.Len(t, accepted, 2)
+	require.Equal(t, int64(2), received.Load())
+
+	peers = newCluster(reject, accept)
+	accepted, err = notifyRenewedCertificate(context.Background(), peers, "m1", types.X509Certificate{Certificate: cert})
+	require.Error(t, err)
+	require.Len(t, accepted, 1)
+	require.Equal(t, accept.Listener.Addr().String(), accepted[0].URL().URL.Host)
+}
+
+// newRotationState returns a state whose "ext" extension server certificate is stored in a temporary directory, and
+// records the certificates reloaded and the fingerprints passed to the OnCertificateRotated hook.
+func newRotationState(t *testing.T, reloaded *[]types.CertificateName, rotated *[]string) *internalState.InternalState {
+	filesystem := &sys.OS{StateDir: t.TempDir(), CertificatesDir: t.TempDir()}
+
+	return &internalState.InternalState{
+		InternalFileSystem:       func() *sys.OS { return filesystem },
+		InternalExtensionServers: func() []string { return []string{"ext"} },
+		ReloadCert: func(name types.CertificateName) error {
+			*reloaded = append(*reloaded, name)
+			return nil
+		},
+		Hooks: &internalState.Hooks{
+			OnCertificateRotated: func(ctx context.Context, s state.State, name types.CertificateName, fingerprint string) error {
+				*rotated = append(*rotated, fingerprint)
+				return nil
+			},
+		},
+	}
+}
+
+// newTestKeyPair returns the PEM encoded keypair of the given certificate.
+func newTestKeyPair(cert *shared.CertInfo) types.KeyPair {
+	return types.KeyPair{Cert: string(cert.PublicKey()), Key: string(cert.PrivateKey())}
+}
+
+// postRotation sends the rotation request for the "ext" certificate to the handler, and returns the response.
+func postRotation(t *testing.T, s state.State, rotation types.CertificateRotation) *httptest.ResponseRecorder {
+	body, err := json.Marshal(rotation)
+	require.NoError(t, err)
+
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)), map[string]string{"name": "ext"})
+	w := httptest.NewRecorder()
+	require.NoError(t, clusterCertificatesRotationPost(s, r).Render(w))
+
+	return w
+}
+
+// Ensures each phase of a certificate rotation only touches the staged keypair until it is committed with the
+// fingerprint of the staged certificate.
+func TestClusterCertificatesRotationPost(t *testing.T) {
+	var reloaded []types.CertificateName
+	var rotated []string
+	s := newRotationState(t, &reloaded, &rotated)
+	dir := s.FileSystem().CertificatesDir
+
+	previous := newTestKeyPair(shared.TestingKeyPair())
+	require.NoError(t, writeKeyPair(dir, "ext", "", previous))
+
+	keyPair := newTestKeyPair(shared.TestingAltKeyPair())
+	fingerprint, err := shared.CertFingerprintStr(keyPair.Cert)
+	require.NoError(t, err)
+
+	requireActive := func(expected types.KeyPair) {
+		active, err := readKeyPair(dir, "ext")
+		require.NoError(t, err)
+		require.Equal(t, expected, *active)
+	}
+
+	requireStaged := func(staged bool) {
+		_, err := os.Stat(filepath.Join(dir, "ext.crt"+stagedSuffix))
+		require.Equal(t, staged, err == nil)
+	}
+
+	w := postRotation(t, s, types.CertificateRotation{Phase: types.CertificateRotationCommit, Fingerprint: fingerprint})
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = postRotation(t, s, types.CertificateRotation{Phase: types.CertificateRotationStage, KeyPair: types.KeyPair{Cert: keyPair.Cert, Key: previous.Key}})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	requireStaged(false)
+
+	w = postRotation(t, s, types.CertificateRotation{Phase: types.CertificateRotationStage, KeyPair: keyPair})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp := api.ResponseRaw{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	status, ok := resp.Metadata.(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, fingerprint, status["fingerprint"])
+	requireStaged(true)
+	requireActive(previous)
+
+	w = postRotation(t, s, types.CertificateRotation{Phase: types.CertificateRotationCommit, Fingerprint: shared.TestingKeyPair().Fingerprint()})
+	require.Equal(t, http.StatusConflict, w.Code)
+	requireStaged(true)
+	requireActive(previous)
+
+	w = postRotation(t, s, types.CertificateRotation{Phase: types.CertificateRotationAbort})
+	require.Equal(t, http.StatusOK, w.Code)
+	requireStaged(false)
+	requireActive(previous)
+
+	w = postRotation(t, s, types.CertificateRotation{Phase: types.CertificateRotationStage, KeyPair: keyPair})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, reloaded)
+
+	w = postRotation(t, s, types.CertificateRotation{Phase: types.CertificateRotationCommit, Fingerprint: fingerprint})
+	require.Equal(t, http.StatusOK, w.Code)
+	requireStaged(false)
+	requireActive(keyPair)
+	require.Equal(t, []types.CertificateName{"ext"}, reloaded)
+	require.Equal(t, []string{fingerprint}, rotated)
+
+	w = postRotation(t, s, types.CertificateRotation{Phase: "unknown"})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// Ensures a rotation that fails to commit on any cluster member restores the previous keypair on the members that
+// committed the new one, and discards it everywhere else.
+func TestCommitRotation(t *testing.T) {
+	previous := newTestKeyPair(shared.TestingKeyPair())
+	keyPair := newTestKeyPair(shared.TestingAltKeyPair())
+	fingerprint, err := shared.CertFingerprintStr(keyPair.Cert)
+	require.NoError(t, err)
+
+	// newPeer returns a cluster member with the previous keypair and the new one staged, which fails to commit the new
+	// one if failCommit is set.
+	newPeer := func(t *testing.T, failCommit bool) (*internalState.InternalState, *httptest.Server) {
+		var reloaded []types.CertificateName
+		var rotated []string
+		s := newRotationState(t, &reloaded, &rotated)
+		require.NoError(t, writeKeyPair(s.FileSystem().CertificatesDir, "ext", "", previous))
+		_, err := stageCertificate(s, "ext", keyPair)
+		require.NoError(t, err)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+
+			rotation := types.CertificateRotation{}
+			assert.NoError(t, json.Unmarshal(body, &rotation))
+			if failCommit && rotation.Phase == types.CertificateRotationCommit && rotation.Fingerprint == fingerprint {
+				assert.NoError(t, response.InternalError(fmt.Errorf("Failed to reload certificate")).Render(w))
+				return
+			}
+
+			r = mux.SetURLVars(r, map[string]string{"name": "ext"})
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			assert.NoError(t, clusterCertificatesRotationPost(s, r).Render(w))
+		}))
+
+		t.Cleanup(server.Close)
+
+		return s, server
+	}
+
+	newCluster := func(t *testing.T, servers ...*httptest.Server) client.Cluster {
+		peers := client.Cluster{}
+		for _, server := range servers {
+			c, err := internalClient.New(*api.NewURL().Scheme("http").Host(server.Listener.Addr().String()), nil, nil, true)
+			require.NoError(t, err)
+
+			peers = append(peers, client.Client{Client: *c})
+		}
+
+		return peers
+	}
+
+	requireKeyPair := func(t *testing.T, s state.State, expected types.KeyPair) {
+		active, err := readKeyPair(s.FileSystem().CertificatesDir, "ext")
+		require.NoError(t, err)
+		require.Equal(t, expected, *active)
+
+		_, err = os.Stat(filepath.Join(s.FileSystem().CertificatesDir, "ext.crt"+stagedSuffix))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	}
+
+	cases := []struct {
+		name          string
+		failPeer      bool
+		failLocal     bool
+		expectedError bool
+	}{
+		{name: "Committed on every member"},
+		{name: "Failed to commit on a peer", failPeer: true, expectedError: true},
+		{name: "Failed to commit locally", failLocal: true, expectedError: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var reloaded []types.CertificateName
+			var rotated []string
+			local := newRotationState(t, &reloaded, &rotated)
+			require.NoError(t, writeKeyPair(local.FileSystem().CertificatesDir, "ext", "", previous))
+			_, err := stageCertificate(local, "ext", keyPair)
+			require.NoError(t, err)
+
+			if c.failLocal {
+				local.ReloadCert = func(name types.CertificateName) error {
+					active, err := readKeyPair(local.FileSystem().CertificatesDir, "ext")
+					if err != nil || active.Cert == keyPair.Cert {
+						return fmt.Errorf("Failed to reload certificate")
+					}
+
+					return nil
+				}
+			}
+
+			committed, committedServer := newPeer(t, false)
+			failed, failedServer := newPeer(t, c.failPeer)
+
+			err = commitRotation(context.Background(), local, newCluster(t, committedServer, failedServer), "ext", fingerprint, keyPair, &previous)
+			if !c.expectedError {
+				require.NoError(t, err)
+				for _, s := range []state.State{local, committed, failed} {
+					requireKeyPair(t, s, keyPair)
+				}
+
+				return
+			}
+
+			require.Error(t, err)
+			for _, s := range []state.State{local, committed, failed} {
+				requireKeyPair(t, s, previous)
+			}
+		})
+	}
+}
+
+// Ensures a rotation that cannot be rolled back lists the cluster members that kept the new certificate.
+func TestCommitRotationWithoutPreviousKeyPair(t *testing.T) {
+	var reloaded []types.CertificateName
+	var rotated []string
+	local := newRotationState(t, &reloaded, &rotated)
+	local.ReloadCert = func(name types.CertificateName) error { return fmt.Errorf("Failed to reload certificate") }
+
+	keyPair := newTestKeyPair(shared.TestingAltKeyPair())
+	fingerprint, err := stageCertificate(local, "ext", keyPair)
+	require.NoError(t, err)
+
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, response.EmptySyncResponse.Render(w))
+	}))
+	defer peer.Close()
+
+	c, err := internalClient.New(*api.NewURL().Scheme("http").Host(peer.Listener.Addr().String()), nil, nil, true)
+	require.NoError(t, err)
+
+	err = commitRotation(context.Background(), local, client.Cluster{{Client: *c}}, "ext", fingerprint, keyPair, nil)
+	require.ErrorContains(t, err, peer.Listener.Addr().String())
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"math/rand"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -16,10 +18,12 @@ import (
 	"time"
 
 	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
 	"github.com/gorilla/mux"
 	"golang.org/x/sys/unix"
 
@@ -28,6 +32,7 @@ import (
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/internal/sys"
 	"github.com/canonical/microcluster/v3/internal/trust"
 	"github.com/canonical/microcluster/v3/internal/utils"
 	"github.com/canonical/microcluster/v3/rest"
@@ -53,6 +58,7 @@ var clusterInternalCmd = rest.Endpoint{
 var clusterMemberCmd = rest.Endpoint{
 	Path: "cluster/{name}",
 
+	Post:   rest.EndpointAction{Handler: clusterMemberPost, AccessHandler: access.AllowAuthenticated},
 	Delete: rest.EndpointAction{Handler: clusterMemberDelete, AccessHandler: access.AllowAuthenticated},
 }
 
@@ -65,7 +71,7 @@ var clusterMemberInternalCmd = rest.Endpoint{
 func clusterPost(s state.State, r *http.Request) response.Response {
 	err := s.Database().IsOpen(r.Context())
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	req := types.ClusterMember{}
@@ -81,35 +87,60 @@ func clusterPost(s state.State, r *http.Request) response.Response {
 
 	leaderClient, err := s.Database().Leader(ctx)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	leaderInfo, err := leaderClient.Leader(ctx)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	err = utils.ValidateFQDN(req.Name)
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Cluster member name %q is not a valid FQDN: %w", req.Name, err))
+		return rest.SmartError(fmt.Errorf("Cluster member name %q is not a valid FQDN: %w", req.Name, err))
+	}
+
+	joinerAddr, err := verifyJoiner(r, req)
+	if err != nil {
+		return rest.SmartError(err)
 	}
 
 	// Check if any of the remote's addresses are currently in use.
+	// A reinstalled cluster member rejoins at its recorded address, so its address is only in use by its own record.
 	existingRemote := s.Remotes().RemoteByAddress(req.Address)
 	if existingRemote != nil {
-		return response.SmartError(fmt.Errorf("Remote with address %q exists", req.Address.String()))
+		var rejoin bool
+		err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+			rejoin = isRejoin(ctx, tx, req, joinerAddr, existingRemote.Name)
+
+			return nil
+		})
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		if !rejoin {
+			return rest.SmartError(fmt.Errorf("Remote with address %q exists", req.Address.String()))
+		}
 	}
 
 	// Forward request to leader.
 	if leaderInfo.Address != s.Address().URL.Host {
 		client, err := s.Leader()
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
+		// The leader can't see the connection of the joiner, so pass along the address it was verified from.
+		client.AddInterceptors(func(r *http.Request, next func(r *http.Request) (*http.Response, error)) (*http.Response, error) {
+			r.Header.Set(request.HeaderForwardedAddress, joinerAddr.String())
+
+			return next(r)
+		})
+
 		tokenResponse, err := internalClient.AddClusterMember(r.Context(), &client.Client, req)
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
 		return response.SyncResponse(true, tokenResponse)
@@ -117,13 +148,49 @@ func clusterPost(s state.State, r *http.Request) response.Response {
 
 	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
+	}
+
+	// Skewed clocks break the expiry of join tokens and the validity of certificates.
+	if !req.Time.IsZero() {
+		skew := time.Since(req.Time).Abs()
+		if skew > intState.MaxClockSkew {
+			return response.BadRequest(fmt.Errorf("Clock of the joining system differs from the cluster's by %s, synchronize it before joining", skew.Round(time.Second)))
+		}
 	}
 
 	// Check if the joining node's extensions are compatible with the leader's.
 	err = intState.Extensions.IsSameVersion(req.Extensions)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
+	}
+
+	// Validate the join token before handing the request over to the consumer's hook.
+	var tokenRecord *cluster.CoreTokenRecord
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		tokenRecord, err = validateJoinToken(ctx, tx, req, req.Certificate.DNSNames, joinerAddr)
+
+		return err
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = intState.Hooks.OnJoinRequest(r.Context(), s, req.ClusterMemberLocal, req.InitConfig)
+	if err != nil {
+		return rest.SmartError(api.StatusErrorf(http.StatusForbidden, "Join request from %q was rejected: %w", req.Name, err))
+	}
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	var rejoin *internalTypes.RejoinInfo
+	if tokenRecord.Rejoin {
+		rejoin, err = prepareRejoin(ctx, s, leaderClient, req, reverter)
+		if err != nil {
+			return rest.SmartError(err)
+		}
 	}
 
 	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
@@ -138,37 +205,75 @@ func clusterPost(s state.State, r *http.Request) response.Response {
 			Role:           cluster.Pending,
 		}
 
-		record, err := cluster.GetCoreTokenRecord(ctx, tx, req.Secret)
+		record, err := validateJoinToken(ctx, tx, req, req.Certificate.DNSNames, joinerAddr)
 		if err != nil {
 			return err
 		}
 
-		if record.Expired() {
-			return fmt.Errorf("Token expired")
+		// A rejoining member keeps its record, with the certificate and versions of the reinstalled system.
+		// Its dqlite node was demoted to spare by prepareRejoin.
+		if record.Rejoin {
+			oldMember, err := cluster.GetCoreClusterMember(ctx, tx, req.Name)
+			if err != nil {
+				return err
+			}
+
+			dnsNames, err := cluster.GetCoreClusterMembersDNSNames(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			dbClusterMember.Role = cluster.Role(dqliteClient.Spare.String())
+			err = cluster.UpdateCoreClusterMember(ctx, tx, req.Name, dbClusterMember)
+			if err != nil {
+				return err
+			}
+
+			reverter.Add(func() {
+				err := s.Database().Transaction(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+					err := cluster.UpdateCoreClusterMember(ctx, tx, req.Name, *oldMember)
+					if err != nil {
+						return err
+					}
+
+					return cluster.UpdateCoreClusterMemberDNSName(ctx, tx, req.Name, dnsNames[req.Name])
+				})
+				if err != nil {
+					logger.Error("Failed to restore record of rejoining cluster member", logger.Ctx{"name": req.Name, "error": err})
+				}
+			})
+		} else {
+			_, err = cluster.CreateCoreClusterMember(ctx, tx, dbClusterMember)
 		}
 
-		if !shared.ValueInSlice(record.Name, req.Certificate.DNSNames) {
-			return fmt.Errorf("Joining server certificate SAN does not contain join token name")
+		if err != nil {
+			return err
 		}
 
-		_, err = cluster.CreateCoreClusterMember(ctx, tx, dbClusterMember)
+		err = cluster.UpdateCoreClusterMemberDNSName(ctx, tx, req.Name, req.DNSName)
 		if err != nil {
 			return err
 		}
 
-		return cluster.DeleteCoreTokenRecord(ctx, tx, record.Name)
+		return cluster.RecordCoreTokenRecordJoin(ctx, tx, *record)
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	remotes := s.Remotes()
 	clusterMembers := make([]types.ClusterMemberLocal, 0, remotes.Count())
 	for _, clusterMember := range remotes.RemotesByName() {
+		// The record of a rejoining member is replaced by the joiner's own.
+		if rejoin != nil && clusterMember.Name == req.Name {
+			continue
+		}
+
 		clusterMember := types.ClusterMemberLocal{
 			Name:        clusterMember.Name,
 			Address:     clusterMember.Address,
 			Certificate: clusterMember.Certificate,
+			DNSName:     clusterMember.DNSName,
 		}
 
 		clusterMembers = append(clusterMembers, clusterMember)
@@ -176,7 +281,7 @@ func clusterPost(s state.State, r *http.Request) response.Response {
 
 	clusterCert, err := s.ClusterCert().PublicKeyX509()
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	localRemote := remotes.RemotesByName()[s.Name()]
@@ -184,19 +289,34 @@ func clusterPost(s state.State, r *http.Request) response.Response {
 		ClusterCert: types.X509Certificate{Certificate: clusterCert},
 		ClusterKey:  string(s.ClusterCert().PrivateKey()),
 
-		TrustedMember:  types.ClusterMemberLocal{Name: s.Name(), Address: localRemote.Address, Certificate: localRemote.Certificate},
+		TrustedMember:  types.ClusterMemberLocal{Name: s.Name(), Address: localRemote.Address, Certificate: localRemote.Certificate, DNSName: localRemote.DNSName},
 		ClusterMembers: clusterMembers,
+		Rejoin:         rejoin,
 	}
 
 	newRemote := trust.Remote{
-		Location:    trust.Location{Name: req.Name, Address: req.Address},
+		Location:    trust.Location{Name: req.Name, Address: req.Address, DNSName: req.DNSName},
 		Certificate: req.Certificate,
 	}
 
 	// Add the cluster member to our local store for authentication.
-	err = s.Remotes().Add(s.FileSystem().TrustDir, newRemote)
-	if err != nil {
-		return response.SmartError(err)
+	if rejoin != nil {
+		oldRemote, ok := remotes.RemotesByName()[req.Name]
+		if !ok {
+			return rest.SmartError(fmt.Errorf("%w: %q", types.ErrMemberNotFound, req.Name))
+		}
+
+		err = s.Remotes().Update(newRemote)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		reverter.Add(func() { _ = s.Remotes().Update(oldRemote) })
+	} else {
+		err = s.Remotes().Add(newRemote)
+		if err != nil {
+			return rest.SmartError(err)
+		}
 	}
 
 	tokenResponse.ClusterAdditionalCerts = make(map[string]types.KeyPair)
@@ -232,22 +352,177 @@ func clusterPost(s state.State, r *http.Request) response.Response {
 		return nil
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
+	reverter.Success()
+
 	return response.SyncResponse(true, tokenResponse)
 }
 
+// isRejoin returns whether the join request holds a valid rejoin token for the cluster member with the given name.
+func isRejoin(ctx context.Context, tx *sql.Tx, req types.ClusterMember, addr netip.Addr, name string) bool {
+	record, err := validateJoinToken(ctx, tx, req, req.Certificate.DNSNames, addr)
+	if err != nil {
+		return false
+	}
+
+	return record.Rejoin && record.Name == name
+}
+
+// verifyJoiner checks that the join request was sent by the system it describes, and returns the address it was sent
+// from. The joiner must present the certificate of the request on the connection. Cluster members forwarding a join
+// request to the dqlite leader verify it themselves, and pass along the address they received it from.
+func verifyJoiner(r *http.Request, req types.ClusterMember) (netip.Addr, error) {
+	remoteAddr := r.RemoteAddr
+	identity, _ := access.GetIdentity(r)
+	forwarded := r.Header.Get(request.HeaderForwardedAddress)
+	if forwarded != "" && identity.Method == access.AuthMethodTLS && identity.Name != "" {
+		addr, err := netip.ParseAddr(forwarded)
+		if err != nil {
+			return netip.Addr{}, api.StatusErrorf(http.StatusBadRequest, "Invalid forwarded address of joining system %q: %w", forwarded, err)
+		}
+
+		return addr, nil
+	}
+
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return netip.Addr{}, api.StatusErrorf(http.StatusForbidden, "Joining system must present its certificate")
+	}
+
+	if req.Certificate.Certificate == nil || !req.Certificate.Equal(r.TLS.PeerCertificates[0]) {
+		return netip.Addr{}, api.StatusErrorf(http.StatusForbidden, "Joining system must present the certificate of its join request")
+	}
+
+	addrPort, err := netip.ParseAddrPort(remoteAddr)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("Failed to parse address of joining system %q: %w", remoteAddr, err)
+	}
+
+	return addrPort.Addr().Unmap(), nil
+}
+
+// validateJoinToken returns the token record matching the join request's secret, if it is valid for the joining system
+// with the given names, connecting from the given address. The name of the joiner must be one of its names, and tokens
+// for a single join are only valid if their name is one of them too.
+func validateJoinToken(ctx context.Context, tx *sql.Tx, req types.ClusterMember, names []string, addr netip.Addr) (*cluster.CoreTokenRecord, error) {
+	record, err := cluster.GetCoreTokenRecord(ctx, tx, req.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Expired() {
+		return nil, types.ErrTokenExpired
+	}
+
+	// Reusable tokens are shared by many joiners, so they are bound to the name the joiner proves instead of their own.
+	if !shared.ValueInSlice(req.Name, names) {
+		return nil, fmt.Errorf("Joining server certificate SAN does not contain the name of the joining system")
+	}
+
+	if !record.Reusable() && !shared.ValueInSlice(record.Name, names) {
+		return nil, fmt.Errorf("Joining server certificate SAN does not contain join token name")
+	}
+
+	if record.Rejoin && req.Name != record.Name {
+		return nil, api.StatusErrorf(http.StatusForbidden, "Rejoin token for %q can't be used by %q", record.Name, req.Name)
+	}
+
+	allowed, err := record.AllowsAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, api.StatusErrorf(http.StatusForbidden, "Joining system address %q is not allowed to use the token", addr.String())
+	}
+
+	return record, nil
+}
+
+// prepareRejoin checks that a reinstalled cluster member may rejoin with its existing identity, and demotes its dqlite
+// node to spare, so that the empty raft log of the reinstalled system can't affect the quorum. The regular role
+// adjustment promotes it again once it is back. If the join fails afterwards, the reverter restores the node's role.
+// It returns the dqlite identity that the member keeps.
+func prepareRejoin(ctx context.Context, s state.State, leader *dqliteClient.Client, req types.ClusterMember, reverter *revert.Reverter) (*internalTypes.RejoinInfo, error) {
+	var member *cluster.CoreClusterMember
+	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		member, err = cluster.GetCoreClusterMember(ctx, tx, req.Name)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get cluster member %q to rejoin: %w", req.Name, err)
+	}
+
+	if member.Address != req.Address.String() {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Rejoining cluster member %q must keep its address %q", req.Name, member.Address)
+	}
+
+	if member.Address == s.Address().URL.Host {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Cluster member %q is still running", req.Name)
+	}
+
+	nodes, err := s.Database().Cluster(ctx, leader)
+	if err != nil {
+		return nil, err
+	}
+
+	rejoin := &internalTypes.RejoinInfo{DqliteNodes: make([]internalTypes.DqliteNode, 0, len(nodes))}
+	for _, node := range nodes {
+		if node.Address == member.Address {
+			if node.Role != dqliteClient.Spare {
+				err = leader.Assign(ctx, node.ID, dqliteClient.Spare)
+				if err != nil {
+					return nil, fmt.Errorf("Failed to demote dqlite node of rejoining cluster member %q: %w", req.Name, err)
+				}
+
+				nodeID := node.ID
+				role := node.Role
+				reverter.Add(func() {
+					ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+					defer cancel()
+
+					err := leader.Assign(ctx, nodeID, role)
+					if err != nil {
+						logger.Error("Failed to restore dqlite role of rejoining cluster member", logger.Ctx{"name": req.Name, "role": role.String(), "error": err})
+					}
+				})
+
+				node.Role = dqliteClient.Spare
+			}
+
+			rejoin.DqliteID = node.ID
+		}
+
+		rejoin.DqliteNodes = append(rejoin.DqliteNodes, internalTypes.DqliteNode{ID: node.ID, Address: node.Address, Role: node.Role.String()})
+	}
+
+	if rejoin.DqliteID == 0 {
+		return nil, fmt.Errorf("Rejoining cluster member %q is not a dqlite member", req.Name)
+	}
+
+	logger.Info("Cluster member is rejoining with its existing identity", logger.Ctx{"name": req.Name, "dqlite_id": rejoin.DqliteID})
+
+	return rejoin, nil
+}
+
 func clusterGet(s state.State, r *http.Request) response.Response {
+	opts, err := types.ParseListOptions(r.URL.Query(), "name", "address", "role", "status")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	status := s.Database().Status()
 
 	// If the database is not in a ready or waiting state, we can't be sure it's available for use.
 	if status != types.DatabaseReady && status != types.DatabaseWaiting {
-		return response.SmartError(api.StatusErrorf(http.StatusServiceUnavailable, "%s", string(status)))
+		return rest.SmartError(api.StatusErrorf(http.StatusServiceUnavailable, "%s", string(status)))
 	}
 
 	var apiClusterMembers []types.ClusterMember
-	err := s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
 		var err error
 		var clusterMembers []cluster.CoreClusterMember
 		var awaitingUpgrade map[string]bool
@@ -281,36 +556,49 @@ func clusterGet(s state.State, r *http.Request) response.Response {
 			apiClusterMembers = append(apiClusterMembers, *apiClusterMember)
 		}
 
+		// The maintenance status, clock skew and DNS names are only recorded once the schema is up to date.
+		if status == types.DatabaseReady {
+			err = setMaintenanceStatus(ctx, tx, apiClusterMembers)
+			if err != nil {
+				return err
+			}
+
+			err = setClockSkew(ctx, tx, apiClusterMembers)
+			if err != nil {
+				return err
+			}
+
+			return setDNSNames(ctx, tx, apiClusterMembers)
+		}
+
 		return nil
 	})
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Failed to get cluster members: %w", err))
+		return rest.SmartError(fmt.Errorf("Failed to get cluster members: %w", err))
 	}
 
-	// Send a small request to each node to ensure they are reachable if the database is fully online.
-	if status == types.DatabaseReady {
-		clusterCert, err := s.ClusterCert().PublicKeyX509()
-		if err != nil {
-			return response.SmartError(err)
+	// Apply all filters other than the status before checking the status of the remaining cluster members.
+	preFilters := make(map[string]string, len(opts.Filters))
+	for key, value := range opts.Filters {
+		if key != "status" {
+			preFilters[key] = value
 		}
+	}
 
-		for i, clusterMember := range apiClusterMembers {
-			addr := api.NewURL().Scheme("https").Host(clusterMember.Address.String())
-			d, err := internalClient.New(*addr, s.ServerCert(), clusterCert, false)
-			if err != nil {
-				return response.SmartError(fmt.Errorf("Failed to create HTTPS client for cluster member with address %q: %w", addr.String(), err))
-			}
+	apiClusterMembers, err = filterItems(apiClusterMembers, preFilters)
+	if err != nil {
+		return rest.SmartError(err)
+	}
 
-			err = d.CheckReady(r.Context())
-			if err == nil {
-				apiClusterMembers[i].Status = types.MemberOnline
-			} else {
-				logger.Warnf("Failed to get status of cluster member with address %q: %v", addr.String(), err)
-			}
+	// Send a small request to each node to ensure they are reachable if the database is fully online.
+	if status == types.DatabaseReady {
+		err = probeClusterMembers(r.Context(), s, apiClusterMembers)
+		if err != nil {
+			return rest.SmartError(err)
 		}
 	}
 
-	return response.SyncResponse(true, apiClusterMembers)
+	return listResponse(apiClusterMembers, opts)
 }
 
 // clusterDisableMu is used to prevent the daemon process from being replaced/stopped during removal from the
@@ -318,11 +606,15 @@ func clusterGet(s state.State, r *http.Request) response.Response {
 // from the cluster when not the leader.
 var clusterDisableMu sync.Mutex
 
+// unreachableMemberTimeout is how long a cluster member that is forcefully removed has to respond before it is
+// considered unreachable and evicted without its involvement.
+const unreachableMemberTimeout = 5 * time.Second
+
 func clusterMemberPut(s state.State, r *http.Request) response.Response {
 	force := r.URL.Query().Get("force") == "1"
 	reExec, err := resetClusterMember(r.Context(), s, force)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	go reExec()
@@ -362,9 +654,9 @@ func resetClusterMember(ctx context.Context, s state.State, force bool) (reExec
 		return nil, fmt.Errorf("Failed shutting down listeners: %w", err)
 	}
 
-	err = os.RemoveAll(s.FileSystem().StateDir)
+	err = removeMemberData(s.FileSystem())
 	if err != nil && !force {
-		return nil, fmt.Errorf("Failed to remove the s directory: %w", err)
+		return nil, err
 	}
 
 	reExec = func() {
@@ -399,18 +691,180 @@ func resetClusterMember(ctx context.Context, s state.State, force bool) (reExec
 	return reExec, nil
 }
 
-// clusterMemberDelete Removes a cluster member from dqlite and re-execs its daemon.
+// removeMemberData removes the state directory of the cluster member, along with the contents of its database directory
+// if it is placed outside of the state directory. The database directory itself is kept, as it may be a mount point.
+func removeMemberData(filesystem *sys.OS) error {
+	err := os.RemoveAll(filesystem.StateDir)
+	if err != nil {
+		return fmt.Errorf("Failed to remove the state directory: %w", err)
+	}
+
+	rel, err := filepath.Rel(filesystem.StateDir, filesystem.DatabaseDir)
+	if err == nil && filepath.IsLocal(rel) {
+		return nil
+	}
+
+	entries, err := os.ReadDir(filesystem.DatabaseDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("Failed to read the database directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		err = os.RemoveAll(filepath.Join(filesystem.DatabaseDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("Failed to remove the database directory: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// clusterMemberPost renames a cluster member. The rename is recorded in the database and then applied to the
+// truststore of every cluster member, each of which runs its OnMemberRename hook.
+func clusterMemberPost(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	req := types.ClusterMemberRename{}
+
+	// Parse the request.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = utils.ValidateFQDN(req.Name)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Cluster member name %q is not a valid FQDN: %w", req.Name, err))
+	}
+
+	if req.Name == name {
+		return response.EmptySyncResponse
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	if !client.IsNotification(r) {
+		remotes := s.Remotes().RemotesByName()
+		_, ok := remotes[name]
+		if !ok {
+			return rest.SmartError(api.StatusErrorf(http.StatusNotFound, "%w: %q", types.ErrMemberNotFound, name))
+		}
+
+		_, ok = remotes[req.Name]
+		if ok {
+			return rest.SmartError(api.StatusErrorf(http.StatusConflict, "A cluster member with name %q already exists", req.Name))
+		}
+
+		err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			exists, err := cluster.CoreClusterMemberExists(ctx, tx, req.Name)
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				return api.StatusErrorf(http.StatusConflict, "A cluster member with name %q already exists", req.Name)
+			}
+
+			member, err := cluster.GetCoreClusterMember(ctx, tx, name)
+			if err != nil {
+				return err
+			}
+
+			member.Name = req.Name
+
+			return cluster.UpdateCoreClusterMember(ctx, tx, name, *member)
+		})
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		cluster, err := s.Cluster(true)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		err = cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+			// No need to send a request to ourselves.
+			if s.Address().URL.Host == c.URL().URL.Host {
+				return nil
+			}
+
+			return c.RenameClusterMember(ctx, name, req.Name)
+		})
+		if err != nil {
+			return rest.SmartError(err)
+		}
+	}
+
+	err = renameLocalClusterMember(ctx, s, name, req.Name)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// renameLocalClusterMember applies the rename of a cluster member to the local truststore, and to the daemon
+// configuration if the renamed member is this one. It then runs the OnMemberRename hook.
+func renameLocalClusterMember(ctx context.Context, s state.State, oldName string, newName string) error {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return err
+	}
+
+	isSelf := s.Name() == oldName
+
+	remotes := s.Remotes()
+	remotesMap := remotes.RemotesByName()
+	newRemotes := make([]types.ClusterMember, 0, len(remotesMap))
+	for _, remote := range remotesMap {
+		if remote.Name == oldName {
+			remote.Name = newName
+		}
+
+		newRemotes = append(newRemotes, remote.ClusterMember())
+	}
+
+	err = remotes.Replace(newRemotes...)
+	if err != nil {
+		return fmt.Errorf("Failed to rename truststore entry %q to %q: %w", oldName, newName, err)
+	}
+
+	if isSelf {
+		daemonConfig := intState.LocalConfig()
+		daemonConfig.SetName(newName)
+
+		err = daemonConfig.Write()
+		if err != nil {
+			return fmt.Errorf("Failed to update local cluster member name: %w", err)
+		}
+	}
+
+	err = intState.Hooks.OnMemberRename(ctx, s, oldName, newName)
+	if err != nil {
+		return fmt.Errorf("Failed to run member rename hook: %w", err)
+	}
+
+	return nil
+}
+
+// clusterMemberDelete removes a cluster member from dqlite and re-execs its daemon.
+// With force, a member that cannot be reached is evicted from the remaining cluster instead.
 func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 	force := r.URL.Query().Get("force") == "1"
 	name, err := url.PathUnescape(mux.Vars(r)["name"])
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	allRemotes := s.Remotes().RemotesByName()
 	remote, ok := allRemotes[name]
 	if !ok {
-		return response.SmartError(fmt.Errorf("No remote exists with the given name %q", name))
+		return rest.SmartError(api.StatusErrorf(http.StatusNotFound, "%w: %q", types.ErrMemberNotFound, name))
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
@@ -418,12 +872,12 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 
 	leader, err := s.Database().Leader(ctx)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	leaderInfo, err := leader.Leader(ctx)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// If we are not the leader, just forward the request.
@@ -446,12 +900,12 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 
 		client, err := s.Leader()
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
 		err = client.DeleteClusterMember(r.Context(), name, force)
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
 		return response.ManualResponse(func(w http.ResponseWriter) error {
@@ -473,7 +927,7 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 
 	info, err := leader.Cluster(r.Context())
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	index := -1
@@ -497,7 +951,7 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 		return err
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	numPending := 0
@@ -508,11 +962,11 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 	}
 
 	if len(clusterMembers)-numPending < 1 {
-		return response.SmartError(fmt.Errorf("Cannot remove cluster members, there are no remaining non-pending members"))
+		return rest.SmartError(fmt.Errorf("Cannot remove cluster members, there are no remaining non-pending members"))
 	}
 
 	if len(info) < 2 {
-		return response.SmartError(fmt.Errorf("Cannot leave a cluster with %d members", len(info)))
+		return rest.SmartError(fmt.Errorf("Cannot leave a cluster with %d members", len(info)))
 	}
 
 	// If we are removing the leader of a 2-node cluster, ensure the remaining node is a voter.
@@ -521,7 +975,7 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 			if node.Address != leaderInfo.Address && node.Role != dqliteClient.Voter {
 				err = leader.Assign(ctx, node.ID, dqliteClient.Voter)
 				if err != nil {
-					return response.SmartError(err)
+					return rest.SmartError(err)
 				}
 			}
 		}
@@ -530,7 +984,7 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 	// Refresh members information since we may have changed roles.
 	info, err = leader.Cluster(r.Context())
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// If we are the leader and removing ourselves, reassign the leader role and perform the removal from there.
@@ -543,18 +997,18 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 		}
 
 		if len(otherNodes) == 0 {
-			return response.SmartError(fmt.Errorf("Found no voters to transfer leadership to"))
+			return rest.SmartError(fmt.Errorf("Found no voters to transfer leadership to"))
 		}
 
 		randomID := otherNodes[rand.Intn(len(otherNodes))]
 		err = leader.Transfer(ctx, randomID)
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
 		client, err := s.Leader()
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
 		clusterDisableMu.Lock()
@@ -569,7 +1023,7 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 
 		err = client.DeleteClusterMember(r.Context(), name, force)
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
 		return response.ManualResponse(func(w http.ResponseWriter) error {
@@ -591,76 +1045,116 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 
 	publicKey, err := s.ClusterCert().PublicKeyX509()
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// Set the forwarded flag so that the the system to be removed knows the removal is in progress.
 	c, err := internalClient.New(remote.URL(), s.ServerCert(), publicKey, true)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
+	}
+
+	// Only check whether a forcefully removed member is reachable, so that an unreachable one does not hold up its eviction.
+	reachable := true
+	if force {
+		reachCtx, reachCancel := context.WithTimeout(ctx, unreachableMemberTimeout)
+		err = c.CheckReady(reachCtx)
+		reachCancel()
+		if err != nil {
+			logger.Warn("Evicting unreachable cluster member", logger.Ctx{"member": name, "error": err})
+			reachable = false
+		}
 	}
 
 	// Tell the cluster member to run its PreRemove hook and return.
-	err = internalClient.RunPreRemoveHook(ctx, c.UseTarget(name), internalTypes.HookRemoveMemberOptions{Force: force})
+	if reachable {
+		err = internalClient.RunPreRemoveHook(ctx, c.UseTarget(name), internalTypes.HookRemoveMemberOptions{Force: force})
+		if err != nil && !force {
+			return rest.SmartError(err)
+		}
+	}
+
+	// Run the PreRemovePeer hook on the remaining members, whether or not the member to remove is reachable.
+	removed := remote.ClusterMember().ClusterMemberLocal
+	err = runPreRemovePeerHooks(ctx, s, removed, force)
 	if err != nil && !force {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// Remove the cluster member from the database.
 	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		err := cluster.DeleteCoreWarningsByMember(ctx, tx, remote.Name)
+		if err != nil {
+			return err
+		}
+
 		return cluster.DeleteCoreClusterMember(ctx, tx, remote.Address.String())
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// Remove the node from dqlite, if it has a record there.
 	if index >= 0 {
 		err = leader.Remove(r.Context(), info[index].ID)
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
+		}
+
+		// Replace an evicted voter right away, rather than waiting for the next roles adjustment.
+		if force && info[index].Role == dqliteClient.Voter {
+			err = replaceVoter(ctx, leader, info[index].ID)
+			if err != nil {
+				logger.Warn("Failed to replace evicted voter", logger.Ctx{"member": name, "error": err})
+			}
 		}
 	}
 
 	localClient, err := internalClient.New(s.FileSystem().ControlSocket(), nil, nil, false)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	err = internalClient.DeleteTrustStoreEntry(ctx, localClient, name)
 	if err != nil && !force {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
-	c, err = internalClient.New(remote.URL(), s.ServerCert(), publicKey, false)
-	if err != nil {
-		return response.SmartError(err)
-	}
+	if reachable {
+		c, err = internalClient.New(remote.URL(), s.ServerCert(), publicKey, false)
+		if err != nil {
+			return rest.SmartError(err)
+		}
 
-	err = internalClient.ResetClusterMember(r.Context(), c, name, force)
-	if err != nil && !force {
-		return response.SmartError(err)
+		err = internalClient.ResetClusterMember(r.Context(), c, name, force)
+		if err != nil && !force {
+			return rest.SmartError(err)
+		}
 	}
 
 	cluster, err := s.Cluster(false)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
-	// Run the PostRemove hook locally.
+	// Run the PostRemove and OnMemberRemoved hooks locally.
 	hookCtx, hookCancel := context.WithCancel(r.Context())
 	err = intState.Hooks.PostRemove(hookCtx, s, force)
+	if err == nil {
+		err = intState.Hooks.OnMemberRemoved(hookCtx, s, removed, force)
+	}
+
 	hookCancel()
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
-	// Run the PostRemove hook on all other members.
+	// Run the PostRemove and OnMemberRemoved hooks on all other members.
 	remotes := s.Remotes()
 	err = cluster.Query(r.Context(), true, func(ctx context.Context, c *client.Client) error {
 		c.SetClusterNotification()
@@ -674,11 +1168,83 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 			return fmt.Errorf("No remote found at address %q run the post-remove hook", c.URL().URL.Host)
 		}
 
-		return internalClient.RunPostRemoveHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookRemoveMemberOptions{Force: force})
+		err = internalClient.RunPostRemoveHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookRemoveMemberOptions{Force: force})
+		if err != nil {
+			return err
+		}
+
+		return internalClient.RunMemberRemovedHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookRemoveMemberOptions{Force: force, Member: removed})
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	return response.EmptySyncResponse
 }
+
+// runPreRemovePeerHooks runs the PreRemovePeer hook locally and on every other cluster member, except the one being removed.
+func runPreRemovePeerHooks(ctx context.Context, s state.State, member types.ClusterMemberLocal, force bool) error {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return err
+	}
+
+	err = intState.Hooks.PreRemovePeer(ctx, s, member, force)
+	if err != nil {
+		return fmt.Errorf("Failed to run pre-remove-peer hook on cluster member %q: %w", s.Name(), err)
+	}
+
+	cluster, err := s.Cluster(true)
+	if err != nil {
+		return err
+	}
+
+	remotes := s.Remotes()
+	return cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+		if c.URL().URL.Host == member.Address.String() {
+			return nil
+		}
+
+		addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+		if err != nil {
+			return err
+		}
+
+		remote := remotes.RemoteByAddress(addrPort)
+		if remote == nil {
+			return fmt.Errorf("No remote found at address %q to run the pre-remove-peer hook", c.URL().URL.Host)
+		}
+
+		return internalClient.RunPreRemovePeerHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookRemoveMemberOptions{Force: force, Member: member})
+	})
+}
+
+// replaceVoter promotes a stand-by, or failing that a spare, dqlite member to voter in place of the removed voter.
+// Members that cannot be promoted, for instance because they are offline as well, are skipped.
+func replaceVoter(ctx context.Context, leader *dqliteClient.Client, removedID uint64) error {
+	info, err := leader.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	candidates := make([]dqliteClient.NodeInfo, 0, len(info))
+	for _, role := range []dqliteClient.NodeRole{dqliteClient.StandBy, dqliteClient.Spare} {
+		for _, node := range info {
+			if node.ID != removedID && node.Role == role {
+				candidates = append(candidates, node)
+			}
+		}
+	}
+
+	for _, node := range candidates {
+		err = leader.Assign(ctx, node.ID, dqliteClient.Voter)
+		if err == nil {
+			logger.Info("Promoted dqlite member to voter", logger.Ctx{"address": node.Address})
+			return nil
+		}
+
+		logger.Warn("Failed to promote dqlite member to voter", logger.Ctx{"address": node.Address, "error": err})
+	}
+
+	return nil
+}
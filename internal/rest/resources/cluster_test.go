@@ -0,0 +1,530 @@
+package resources
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/config"
+	"github.com/canonical/microcluster/v3/internal/db"
+	"github.com/canonical/microcluster/v3/internal/db/dbtest"
+	"github.com/canonical/microcluster/v3/internal/extensions"
+	internalAccess "github.com/canonical/microcluster/v3/internal/rest/access"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/trust"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// newTestDB returns an in-memory sqlite database with the internal schema applied and its statements prepared.
+func newTestDB(t *testing.T) *sql.DB {
+	db := dbtest.NewDB(t)
+	err := cluster.PrepareStmts(db, cluster.GetCallerProject(), false)
+	require.NoError(t, err)
+
+	return db
+}
+
+// newTestTx returns a transaction on an in-memory sqlite database with the internal schema applied.
+func newTestTx(t *testing.T) *sql.Tx {
+	tx, err := newTestDB(t).Begin()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = tx.Rollback() })
+
+	return tx
+}
+
+// Ensures join tokens are bound to the name and address of the joiner, and rejoin tokens are only valid for the cluster
+// member they were issued for.
+func TestValidateJoinToken(t *testing.T) {
+	ctx := context.Background()
+	tx := newTestTx(t)
+
+	tokens := []cluster.CoreTokenRecord{
+		{Secret: "rejoin", Name: "m1", MaxJoins: 1, Rejoin: true},
+		{Secret: "join", Name: "m2", MaxJoins: 1},
+		{Secret: "expired", Name: "m3", MaxJoins: 1, Rejoin: true, ExpiryDate: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}},
+		{Secret: "subnet", Name: "m4", MaxJoins: 1, Rejoin: true, AllowedSubnets: "10.0.1.0/24"},
+		{Secret: "reusable", Name: "reusable", MaxJoins: 5},
+	}
+
+	for _, token := range tokens {
+		_, err := cluster.CreateCoreTokenRecord(ctx, tx, token)
+		require.NoError(t, err)
+	}
+
+	address, err := types.ParseAddrPort("10.0.0.1:9000")
+	require.NoError(t, err)
+
+	// Tokens are checked against the address the joiner connected from, not the address it claims.
+	peerAddr := netip.MustParseAddr("10.0.1.1")
+
+	cases := []struct {
+		name       string
+		secret     string
+		member     string
+		sans       []string
+		peer       string
+		rejoinOf   string
+		expectErr  bool
+		expectCode int
+		expectIs   bool
+	}{
+		{name: "Rejoin of the bound member", secret: "rejoin", member: "m1", sans: []string{"m1"}, rejoinOf: "m1", expectIs: true},
+		{name: "Rejoin at the address of another member", secret: "rejoin", member: "m1", sans: []string{"m1"}, rejoinOf: "m2"},
+		{name: "Rejoin by another member", secret: "rejoin", member: "m2", sans: []string{"m1", "m2"}, rejoinOf: "m1", expectErr: true, expectCode: http.StatusForbidden},
+		{name: "Rejoin without the bound SAN", secret: "rejoin", member: "m1", sans: []string{"m2"}, rejoinOf: "m1", expectErr: true},
+		{name: "Regular join token", secret: "join", member: "m2", sans: []string{"m2"}, rejoinOf: "m2"},
+		{name: "Regular join token without its SAN", secret: "join", member: "m1", sans: []string{"m1"}, rejoinOf: "m1", expectErr: true},
+		{name: "Expired rejoin token", secret: "expired", member: "m3", sans: []string{"m3"}, rejoinOf: "m3", expectErr: true},
+		{name: "Rejoin from an allowed subnet", secret: "subnet", member: "m4", sans: []string{"m4"}, peer: "10.0.1.1", rejoinOf: "m4", expectIs: true},
+		{name: "Rejoin from a disallowed subnet", secret: "subnet", member: "m4", sans: []string{"m4"}, peer: "10.0.0.1", rejoinOf: "m4", expectErr: true, expectCode: http.StatusForbidden},
+		{name: "Reusable token for a name outside the SAN", secret: "reusable", member: "m3", sans: []string{"m2"}, rejoinOf: "m3", expectErr: true},
+		{name: "Reusable token", secret: "reusable", member: "m2", sans: []string{"m2"}, rejoinOf: "m2"},
+		{name: "Unknown token", secret: "unknown", member: "m1", sans: []string{"m1"}, rejoinOf: "m1", expectErr: true, expectCode: http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cert := types.X509Certificate{Certificate: &x509.Certificate{DNSNames: c.sans}}
+			req := types.ClusterMember{ClusterMemberLocal: types.ClusterMemberLocal{Name: c.member, Address: address, Certificate: cert}, Secret: c.secret}
+
+			addr := peerAddr
+			if c.peer != "" {
+				addr = netip.MustParseAddr(c.peer)
+			}
+
+			_, err := validateJoinToken(ctx, tx, req, c.sans, addr)
+			if c.expectErr {
+				require.Error(t, err)
+				if c.expectCode != 0 {
+					require.True(t, api.StatusErrorCheck(err, c.expectCode), "Unexpected error: %v", err)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, c.expectIs, isRejoin(ctx, tx, req, addr, c.rejoinOf))
+		})
+	}
+}
+
+// Ensures the joiner must present the certificate of its join request, and only cluster members can forward the
+// address of a joiner.
+func TestVerifyJoiner(t *testing.T) {
+	joinerCert := &x509.Certificate{Raw: []byte("joiner")}
+	otherCert := &x509.Certificate{Raw: []byte("other")}
+	req := types.ClusterMember{ClusterMemberLocal: types.ClusterMemberLocal{Certificate: types.X509Certificate{Certificate: joinerCert}}}
+
+	newRequest := func(peerCert *x509.Certificate, forwarded string, identity internalAccess.Identity) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/core/internal/cluster", nil)
+		r.RemoteAddr = "[::ffff:10.0.0.1]:51234"
+		if peerCert != nil {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{peerCert}}
+		}
+
+		if forwarded != "" {
+			r.Header.Set(request.HeaderForwardedAddress, forwarded)
+		}
+
+		return internalAccess.SetRequestIdentity(r, identity)
+	}
+
+	member := internalAccess.Identity{Method: internalAccess.AuthMethodTLS, Trusted: true, Name: "m1"}
+	client := internalAccess.Identity{Method: internalAccess.AuthMethodTLS, Trusted: true}
+	untrusted := internalAccess.Identity{Method: internalAccess.AuthMethodNone}
+
+	addr, err := verifyJoiner(newRequest(joinerCert, "", untrusted), req)
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParseAddr("10.0.0.1"), addr)
+
+	_, err = verifyJoiner(newRequest(otherCert, "", untrusted), req)
+	require.True(t, api.StatusErrorCheck(err, http.StatusForbidden), "Unexpected error: %v", err)
+
+	_, err = verifyJoiner(newRequest(nil, "", untrusted), req)
+	require.True(t, api.StatusErrorCheck(err, http.StatusForbidden), "Unexpected error: %v", err)
+
+	// The address forwarded by a cluster member replaces the address of the connection.
+	addr, err = verifyJoiner(newRequest(otherCert, "10.0.1.1", member), req)
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParseAddr("10.0.1.1"), addr)
+
+	// Other callers can't forward an address.
+	addr, err = verifyJoiner(newRequest(joinerCert, "10.0.1.1", untrusted), req)
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParseAddr("10.0.0.1"), addr)
+
+	addr, err = verifyJoiner(newRequest(joinerCert, "10.0.1.1", client), req)
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParseAddr("10.0.0.1"), addr)
+}
+
+// Ensures resetting a cluster member removes its database, even if it is outside of the state directory.
+func TestRemoveMemberData(t *testing.T) {
+	for _, external := range []bool{false, true} {
+		stateDir := filepath.Join(t.TempDir(), "state")
+		layout := sys.Layout{}
+		if external {
+			layout.DatabaseDir = filepath.Join(t.TempDir(), "database")
+		}
+
+		filesystem, err := sys.NewOS(stateDir, layout, true)
+		require.NoError(t, err)
+
+		err = os.WriteFile(filepath.Join(filesystem.DatabaseDir, "info.yaml"), []byte("id: 1\n"), 0o600)
+		require.NoError(t, err)
+
+		require.NoError(t, removeMemberData(filesystem))
+		require.NoDirExists(t, stateDir)
+		require.NoFileExists(t, filepath.Join(filesystem.DatabaseDir, "info.yaml"))
+
+		// The external database directory is kept, as it may be a mount point.
+		if external {
+			require.DirExists(t, filesystem.DatabaseDir)
+		}
+	}
+}
+
+// Ensures renaming a cluster member only changes its name in the trust store, and keeps the DNS names of every member.
+func TestRenameLocalClusterMember(t *testing.T) {
+	cert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	for _, oldName := range []string{"m1", "m2"} {
+		remotes := trust.NewRemotes(trust.NewFileBackend(t.TempDir(), nil))
+		for i, name := range []string{"m1", "m2"} {
+			address, err := types.ParseAddrPort(fmt.Sprintf("10.0.0.%d:9000", i+1))
+			require.NoError(t, err)
+
+			remote := trust.Remote{Location: trust.Location{Name: name, Address: address, DNSName: name + ".example.com"}, Certificate: types.X509Certificate{Certificate: cert}}
+			require.NoError(t, remotes.Add(remote))
+		}
+
+		var renamed []string
+		daemonConfig := config.NewDaemonConfig(filepath.Join(t.TempDir(), "daemon.yaml"))
+		daemonConfig.SetName("m1")
+		s := &internalState.InternalState{
+			InternalName:    func() string { return "m1" },
+			InternalRemotes: func() *trust.Remotes { return remotes },
+			LocalConfig:     func() *config.DaemonConfig { return daemonConfig },
+			Hooks: &internalState.Hooks{
+				OnMemberRename: func(ctx context.Context, s internalState.State, oldName string, newName string) error {
+					renamed = append(renamed, oldName, newName)
+					return nil
+				},
+			},
+		}
+
+		require.NoError(t, renameLocalClusterMember(context.Background(), s, oldName, "m3"))
+		require.Equal(t, []string{oldName, "m3"}, renamed)
+
+		byName := remotes.RemotesByName()
+		require.Len(t, byName, 2)
+		require.NotContains(t, byName, oldName)
+		require.Equal(t, oldName+".example.com", byName["m3"].DNSName)
+		for name, remote := range byName {
+			if name != "m3" {
+				require.Equal(t, name+".example.com", remote.DNSName)
+			}
+		}
+
+		// Only the local cluster member changes its own name.
+		if oldName == "m1" {
+			require.Equal(t, "m3", daemonConfig.GetName())
+		} else {
+			require.Equal(t, "m1", daemonConfig.GetName())
+		}
+	}
+}
+
+// statusDB reports the given status, and runs transactions against a sqlite database.
+type statusDB struct {
+	testDB
+
+	status types.DatabaseStatus
+}
+
+func (d *statusDB) Status() types.DatabaseStatus { return d.status }
+
+func (d *statusDB) SchemaVersion() (uint64, uint64, extensions.Extensions) { return 1, 0, nil }
+
+func (d *statusDB) IsOpen(ctx context.Context) error {
+	if d.status != types.DatabaseReady {
+		return api.StatusErrorf(http.StatusServiceUnavailable, "%s", string(d.status))
+	}
+
+	return nil
+}
+
+// Ensures the join checks are only run for holders of a valid join token, bound to the name and address of the joiner.
+func TestPreflightPost(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := newTestDB(t)
+	err := query.Transaction(ctx, sqlDB, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.CreateCoreTokenRecord(ctx, tx, cluster.CoreTokenRecord{Secret: "subnet", Name: "m2", MaxJoins: 1, AllowedSubnets: "10.0.1.0/24"})
+		return err
+	})
+	require.NoError(t, err)
+
+	address, err := types.ParseAddrPort("10.0.1.2:9000")
+	require.NoError(t, err)
+
+	cert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	exts, err := extensions.NewExtensionRegistry(true)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		status     types.DatabaseStatus
+		body       string
+		member     string
+		secret     string
+		remoteAddr string
+		expectCode int
+	}{
+		{name: "Database not ready", status: types.DatabaseNotReady, member: "m2", secret: "subnet", remoteAddr: "10.0.1.2:51234", expectCode: http.StatusServiceUnavailable},
+		{name: "Malformed request", status: types.DatabaseReady, body: "{", expectCode: http.StatusBadRequest},
+		{name: "Unknown join token", status: types.DatabaseReady, member: "m2", secret: "unknown", remoteAddr: "10.0.1.2:51234", expectCode: http.StatusForbidden},
+		{name: "Join token of another name", status: types.DatabaseReady, member: "m3", secret: "subnet", remoteAddr: "10.0.1.2:51234", expectCode: http.StatusForbidden},
+		{name: "Join token from a disallowed address", status: types.DatabaseReady, member: "m2", secret: "subnet", remoteAddr: "10.0.0.2:51234", expectCode: http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			body := c.body
+			if body == "" {
+				req := types.ClusterMember{ClusterMemberLocal: types.ClusterMemberLocal{Name: c.member, Address: address, Certificate: types.X509Certificate{Certificate: cert}}, Secret: c.secret, Extensions: exts}
+				data, err := json.Marshal(req)
+				require.NoError(t, err)
+
+				body = string(data)
+			}
+
+			s := &testState{db: &statusDB{testDB: testDB{sqlDB: sqlDB}, status: c.status}}
+			r := httptest.NewRequest(http.MethodPost, "/core/internal/preflight", strings.NewReader(body))
+			r.RemoteAddr = c.remoteAddr
+			w := httptest.NewRecorder()
+			require.NoError(t, preflightPost(s, r).Render(w))
+			require.Equal(t, c.expectCode, w.Code, w.Body.String())
+		})
+	}
+}
+
+// Ensures the join checks report each reason the cluster would reject the join request, and only those.
+func TestJoinPreflightChecks(t *testing.T) {
+	exts, err := extensions.NewExtensionRegistry(true)
+	require.NoError(t, err)
+
+	database := db.NewDB(context.Background(), nil, nil, nil, &sys.OS{}, 0)
+	database.SetSchema(nil, nil, nil, exts)
+	internalVersion, externalVersion, _ := database.SchemaVersion()
+
+	cert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	existing, err := types.ParseAddrPort("10.0.0.1:9000")
+	require.NoError(t, err)
+
+	remotes := trust.NewRemotes(&memRemotes{})
+	require.NoError(t, remotes.Add(trust.Remote{Location: trust.Location{Name: "m1", Address: existing}, Certificate: types.X509Certificate{Certificate: cert}}))
+
+	s := &internalState.InternalState{
+		InternalDatabase: database,
+		InternalRemotes:  func() *trust.Remotes { return remotes },
+		Extensions:       exts,
+	}
+
+	address, err := types.ParseAddrPort("10.0.0.2:9000")
+	require.NoError(t, err)
+
+	newRequest := func() types.ClusterMember {
+		return types.ClusterMember{
+			ClusterMemberLocal:    types.ClusterMemberLocal{Name: "m2", Address: address},
+			SchemaInternalVersion: internalVersion,
+			SchemaExternalVersion: externalVersion,
+			Extensions:            exts,
+		}
+	}
+
+	cases := []struct {
+		name        string
+		update      func(req *types.ClusterMember)
+		expectError string
+	}{
+		{name: "Valid join request", update: func(req *types.ClusterMember) {}},
+		{name: "Invalid name", update: func(req *types.ClusterMember) { req.Name = "m_2" }, expectError: "name"},
+		{name: "Existing name", update: func(req *types.ClusterMember) { req.Name = "m1" }, expectError: "name"},
+		{name: "Existing address", update: func(req *types.ClusterMember) { req.Address = existing }, expectError: "address"},
+		{name: "Different extensions", update: func(req *types.ClusterMember) { req.Extensions = exts[:len(exts)-1] }, expectError: "extensions"},
+		{name: "Different internal schema", update: func(req *types.ClusterMember) { req.SchemaInternalVersion++ }, expectError: "schema"},
+		{name: "Different external schema", update: func(req *types.ClusterMember) { req.SchemaExternalVersion++ }, expectError: "schema"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := newRequest()
+			c.update(&req)
+
+			checks := joinPreflightChecks(s, req)
+			names := make([]string, 0, len(checks))
+			for _, check := range checks {
+				names = append(names, check.Name)
+				if check.Name == c.expectError {
+					require.NotEmpty(t, check.Error)
+				} else {
+					require.Empty(t, check.Error)
+				}
+			}
+
+			require.Equal(t, []string{"name", "address", "extensions", "schema"}, names)
+
+			err := types.JoinPreflight{Checks: checks}.Err()
+			if c.expectError == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, c.expectError+": ")
+			}
+		})
+	}
+}
+
+// Ensures the join checks are only run from a system that is not part of a cluster, with a well-formed join request.
+func TestControlPreflightPost(t *testing.T) {
+	cases := []struct {
+		name       string
+		status     types.DatabaseStatus
+		body       string
+		expectCode int
+	}{
+		{name: "Already bootstrapped", status: types.DatabaseReady, body: `{"join_token": "token", "address": "10.0.0.2:9000"}`, expectCode: http.StatusInternalServerError},
+		{name: "Malformed request", status: types.DatabaseNotReady, body: "{", expectCode: http.StatusBadRequest},
+		{name: "Missing join token", status: types.DatabaseNotReady, body: `{"address": "10.0.0.2:9000"}`, expectCode: http.StatusBadRequest},
+		{name: "Missing address", status: types.DatabaseNotReady, body: `{"join_token": "token"}`, expectCode: http.StatusBadRequest},
+		{name: "Invalid join token", status: types.DatabaseNotReady, body: `{"join_token": "token", "address": "10.0.0.2:9000"}`, expectCode: http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &testState{db: &statusDB{status: c.status}}
+			r := httptest.NewRequest(http.MethodPost, "/core/control/preflight", strings.NewReader(c.body))
+			w := httptest.NewRecorder()
+			require.NoError(t, controlPreflightPost(s, r).Render(w))
+			require.Equal(t, c.expectCode, w.Code, w.Body.String())
+		})
+	}
+}
+
+// Ensures cluster members are filtered before they are paginated, and only the selected fields are returned.
+func TestClusterGetListOptions(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := newTestDB(t)
+
+	// Cluster members behind the schema version of the database need to be upgraded.
+	members := []cluster.CoreClusterMember{
+		{Name: "m1", Address: "10.0.0.1:9000", Role: "voter", SchemaInternal: 1},
+		{Name: "m2", Address: "10.0.0.2:9000", Role: "spare", SchemaInternal: 0},
+		{Name: "m3", Address: "10.0.0.3:9000", Role: "voter", SchemaInternal: 1},
+	}
+
+	err := query.Transaction(ctx, sqlDB, func(ctx context.Context, tx *sql.Tx) error {
+		for _, member := range members {
+			// Each cluster member has its own certificate.
+			cert, _, err := shared.GenerateMemCert(false, shared.CertOptions{CommonName: member.Name})
+			if err != nil {
+				return err
+			}
+
+			member.Certificate = string(cert)
+			_, err = cluster.CreateCoreClusterMember(ctx, tx, member)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	s := &testState{db: &statusDB{testDB: testDB{sqlDB: sqlDB}, status: types.DatabaseWaiting}}
+
+	cases := []struct {
+		name         string
+		query        string
+		expectCode   int
+		expectNames  []string
+		expectFields []string
+	}{
+		{name: "No options", expectCode: http.StatusOK, expectNames: []string{"m1", "m2", "m3"}},
+		{name: "Filter ignoring case", query: "role=VOTER", expectCode: http.StatusOK, expectNames: []string{"m1", "m3"}},
+		{name: "Filter by status", query: "status=needs+upgrade", expectCode: http.StatusOK, expectNames: []string{"m2"}},
+		{name: "Filter by several fields", query: "role=voter&address=10.0.0.3:9000", expectCode: http.StatusOK, expectNames: []string{"m3"}},
+		{name: "Unsupported filter", query: "certificate=none", expectCode: http.StatusOK, expectNames: []string{"m1", "m2", "m3"}},
+		{name: "Limit and offset", query: "limit=1&offset=1", expectCode: http.StatusOK, expectNames: []string{"m2"}},
+		{name: "Offset after filtering", query: "role=voter&offset=1", expectCode: http.StatusOK, expectNames: []string{"m3"}},
+		{name: "Limit beyond the last member", query: "offset=1&limit=5", expectCode: http.StatusOK, expectNames: []string{"m2", "m3"}},
+		{name: "Offset beyond the last member", query: "offset=5", expectCode: http.StatusOK, expectNames: []string{}},
+		{name: "Selected fields", query: "fields=name,role", expectCode: http.StatusOK, expectNames: []string{"m1", "m2", "m3"}, expectFields: []string{"name", "role"}},
+		{name: "Unknown field", query: "fields=name,unknown", expectCode: http.StatusBadRequest},
+		{name: "Negative limit", query: "limit=-1", expectCode: http.StatusBadRequest},
+		{name: "Invalid offset", query: "offset=first", expectCode: http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/core/1.0/cluster?"+c.query, nil)
+			w := httptest.NewRecorder()
+			require.NoError(t, clusterGet(s, r).Render(w))
+			require.Equal(t, c.expectCode, w.Code, w.Body.String())
+			if c.expectCode != http.StatusOK {
+				return
+			}
+
+			resp := struct {
+				Metadata []map[string]any `json:"metadata"`
+			}{}
+
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+			names := make([]string, 0, len(resp.Metadata))
+			for _, fields := range resp.Metadata {
+				names = append(names, fields["name"].(string))
+				if c.expectFields != nil {
+					keys := make([]string, 0, len(fields))
+					for key := range fields {
+						keys = append(keys, key)
+					}
+
+					require.ElementsMatch(t, c.expectFields, keys)
+				}
+			}
+
+			require.Equal(t, c.expectNames, names)
+		})
+	}
+
+	// The options sent by clients are parsed back as they were given.
+	opts := types.ListOptions{Limit: 2, Offset: 1, Filters: map[string]string{"role": "voter"}, Fields: []string{"name", "role"}}
+	parsed, err := types.ParseListOptions(opts.Values(), "name", "address", "role", "status")
+	require.NoError(t, err)
+	require.Equal(t, opts, parsed)
+}
@@ -0,0 +1,81 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/recover"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var consistencyCmd = rest.Endpoint{
+	Path: "consistency",
+
+	Get:  rest.EndpointAction{Handler: consistencyGet, AccessHandler: access.AllowAuthenticated},
+	Post: rest.EndpointAction{Handler: consistencyPost, AccessHandler: access.AllowAuthenticated},
+}
+
+// consistencyGet reports mismatches between the local state of the cluster member and the cluster membership.
+func consistencyGet(s state.State, r *http.Request) response.Response {
+	return checkConsistency(s, r, false)
+}
+
+// consistencyPost reports mismatches between the local state of the cluster member and the cluster membership, and
+// repairs them where possible.
+func consistencyPost(s state.State, r *http.Request) response.Response {
+	return checkConsistency(s, r, true)
+}
+
+// checkConsistency cross-checks the trust store and go-dqlite yaml files of the local cluster member against the
+// core_cluster_members table and the dqlite raft configuration.
+func checkConsistency(s state.State, r *http.Request, repair bool) response.Response {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	var dbMembers []cluster.CoreClusterMember
+	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		dbMembers, err = cluster.GetCoreClusterMembers(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	members := make([]types.ClusterMember, 0, len(dbMembers))
+	for _, dbMember := range dbMembers {
+		member, err := dbMember.ToAPI()
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		members = append(members, *member)
+	}
+
+	leaderClient, err := s.Database().Leader(ctx)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	nodes, err := s.Database().Cluster(ctx, leaderClient)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	clusterState := recover.ClusterState{Members: members, Nodes: nodes}
+	issues, err := recover.CheckLocalState(s.FileSystem(), s.Remotes(), s.Address().URL.Host, clusterState, repair)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, types.ConsistencyReport{Member: s.Name(), Issues: issues})
+}
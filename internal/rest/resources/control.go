@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/lxd/util"
@@ -15,6 +19,8 @@ import (
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/recover"
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
@@ -32,10 +38,17 @@ var controlCmd = rest.Endpoint{
 	Post: rest.EndpointAction{Handler: controlPost, AccessHandler: access.AllowAuthenticated},
 }
 
-func controlPost(state state.State, r *http.Request) response.Response {
-	status := state.Database().Status()
+var controlProgressCmd = rest.Endpoint{
+	Path:              "progress",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: controlProgressGet, AccessHandler: access.AllowAuthenticated},
+}
+
+func controlPost(s state.State, r *http.Request) response.Response {
+	status := s.Database().Status()
 	if status != types.DatabaseNotReady {
-		return response.SmartError(fmt.Errorf("Unable to initialize cluster: %s", status))
+		return rest.SmartError(fmt.Errorf("Unable to initialize cluster: %w: %s", types.ErrAlreadyBootstrapped, status))
 	}
 
 	req := &internalTypes.Control{}
@@ -46,50 +59,140 @@ func controlPost(state state.State, r *http.Request) response.Response {
 	}
 
 	if req.Bootstrap && req.JoinToken != "" {
-		return response.SmartError(fmt.Errorf("Invalid options - received join token and bootstrap flag"))
+		return rest.SmartError(fmt.Errorf("Invalid options - received join token and bootstrap flag"))
 	}
 
 	err = utils.ValidateFQDN(req.Name)
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Cluster member name %q is not a valid FQDN: %w", req.Name, err))
+		return rest.SmartError(fmt.Errorf("Cluster member name %q is not a valid FQDN: %w", req.Name, err))
 	}
 
-	intState, err := internalState.ToInternal(state)
+	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
+	}
+
+	intState.InitProgress.Report(types.InitStageValidating, "Validating the initialization request")
+
+	// Bootstrapping without an address starts a single-node cluster that is only reachable locally.
+	localOnly := req.Bootstrap && req.Address == (types.AddrPort{})
+	if localOnly {
+		if req.ListenAddress != (types.AddrPort{}) {
+			return response.BadRequest(fmt.Errorf("A listen address cannot be set without an address to advertise"))
+		}
+
+		var listener net.Listener
+		req.Address, listener, err = loopbackAddress()
+		if err != nil {
+			return rest.SmartError(fmt.Errorf("Failed to find a free loopback address: %w", err))
+		}
+
+		// The listener is kept open until the API adopts it, so that the port cannot be taken in the meantime.
+		intState.ReserveListener(listener)
+
+		logger.Info("Bootstrapping local-only cluster member", logger.Ctx{"name": req.Name, "address": req.Address.String()})
 	}
 
-	daemonConfig := trust.Location{Address: req.Address, Name: req.Name}
-	err = intState.SetConfig(daemonConfig)
+	if !req.Address.IsValid() {
+		return response.BadRequest(fmt.Errorf("Address %q is not a valid address and port", req.Address))
+	}
+
+	// Other cluster members cannot reach a wildcard address, so it can only be listened on.
+	if req.Address.Addr().IsUnspecified() {
+		return response.BadRequest(fmt.Errorf("Cannot advertise the wildcard address %q, set it as the listen address instead", req.Address))
+	}
+
+	// The DNS name resolves to the address, and the port of the address is kept.
+	if req.DNSName != "" && (localOnly || strings.ContainsAny(req.DNSName, ":/ ")) {
+		return response.BadRequest(fmt.Errorf("Invalid DNS name %q", req.DNSName))
+	}
+
+	listenAddress := req.ListenAddress
+	if listenAddress != (types.AddrPort{}) && listenAddress.Port() == 0 {
+		listenAddress = types.AddrPort{AddrPort: netip.AddrPortFrom(listenAddress.Addr(), req.Address.Port())}
+	}
+
+	daemonConfig := trust.Location{Address: req.Address, Name: req.Name, DNSName: req.DNSName}
+	intState.LocalConfig().SetLocalOnly(localOnly)
+	err = intState.SetConfig(daemonConfig, listenAddress)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
-	ctx, cancel := context.WithCancel(r.Context())
-	err = intState.Hooks.PreInit(ctx, state, req.Bootstrap, req.InitConfig)
-	cancel()
+	// The token is checked before starting the operation, so that invalid tokens are reported to the caller right away.
+	var token *internalTypes.Token
+	if req.JoinToken != "" {
+		token, err = internalTypes.DecodeToken(req.JoinToken)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		err = token.Check(req.Address, intState.Project, time.Now())
+		if err != nil {
+			return rest.SmartError(err)
+		}
+	}
+
+	description := "Bootstrapping the cluster"
+	if token != nil {
+		description = "Joining the cluster"
+	}
+
+	// Bootstrapping and joining can take a while, so they run as an operation that the caller waits for.
+	op, err := s.Operations().Start(description, func(ctx context.Context, op *state.Operation) (any, error) {
+		return nil, initialize(ctx, s, op, req, token)
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return rest.OperationResponse(op)
+}
+
+// initFailureReportTimeout is how long a failed initialization waits for the caller to receive the failure of its
+// operation before the cluster member is reset.
+const initFailureReportTimeout = 30 * time.Second
+
+// initialize bootstraps a new cluster, or joins the cluster of the token, as requested. If it fails, the cluster
+// member is reset once the caller has received the failure, so that the listeners it is waiting on are not closed
+// before then.
+func initialize(ctx context.Context, s state.State, op *state.Operation, req *internalTypes.Control, token *internalTypes.Token) (err error) {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return err
+	}
+
+	intState.InitProgress.Report(types.InitStagePreInitHook, "Running pre-init hook")
+	err = intState.Hooks.PreInit(ctx, s, req.Bootstrap, req.InitConfig)
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Failed to run pre-init hook before starting the API: %w", err))
+		return fmt.Errorf("Failed to run pre-init hook before starting the API: %w", err)
 	}
 
 	reverter := revert.New()
 	defer func() {
+		if err == nil {
+			return
+		}
+
 		// NOTE(claudiub): In the case we fail to bootstrap / join the cluster, we'll be resetting a few
 		// things, including the cluster membership. This includes the HTTPS and unix socket servers we have
 		// open by closing them.
-		// However, we cannot gracefully shutdown the servers, as there's at least one connection that is
-		// still open: the bootstrap / join request. Forcing the connection to close before we're able
-		// to write the request response will result in the client getting an EOF error, and no information
-		// regarding the failure.
-		// Running the revert actions in a goroutine will address this issue: while the revert happens,
-		// we'll be able to return and write the HTTP response and then close the connection, finally
-		// allowing the Servers to gracefully shutdown, and the clients to be happy.
-		go reverter.Fail()
+		// The failure is only recorded in the operation once we return, and the caller can only receive it
+		// through the listeners we are about to close. Wait for the caller to receive it, or to give up on it,
+		// before running the revert actions.
+		go func() {
+			select {
+			case <-op.Collected():
+			case <-time.After(initFailureReportTimeout):
+			}
+
+			reverter.Fail()
+		}()
 	}()
 
-	serverCert, err := state.ServerCert().PublicKeyX509()
+	serverCert, err := s.ServerCert().PublicKeyX509()
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
 	certNameMatches := shared.ValueInSlice(req.Name, serverCert.DNSNames)
@@ -101,13 +204,14 @@ func controlPost(state state.State, r *http.Request) response.Response {
 			return
 		}
 
-		// Run the pre-remove hook like we do for cluster node removals.
-		err := intState.Hooks.PreRemove(r.Context(), state, true)
+		// Run the pre-remove hook like we do for cluster node removals. The operation has finished by now, so
+		// its context is no longer usable.
+		err := intState.Hooks.PreRemove(context.WithoutCancel(ctx), s, true)
 		if err != nil {
 			logger.Error("Failed to run pre-remove hook on initialization error", logger.Ctx{"error": err})
 		}
 
-		reExec, err := resetClusterMember(r.Context(), state, true)
+		reExec, err := resetClusterMember(ctx, s, true)
 		if err != nil {
 			logger.Error("Failed to reset cluster member on bootstrap error", logger.Ctx{"error": err})
 			return
@@ -117,7 +221,8 @@ func controlPost(state state.State, r *http.Request) response.Response {
 		go reExec()
 
 		// Only send a request to delete the cluster member record if we are joining an existing cluster.
-		if joinInfo == nil || req.JoinToken == "" {
+		// The record of a rejoining member is kept, so that it can try again with a new rejoin token.
+		if joinInfo == nil || req.JoinToken == "" || joinInfo.Rejoin != nil {
 			return
 		}
 
@@ -127,7 +232,7 @@ func controlPost(state state.State, r *http.Request) response.Response {
 			return
 		}
 
-		client, err := internalClient.New(*url, state.ServerCert(), cert, false)
+		client, err := internalClient.New(*url, s.ServerCert(), cert, false)
 		if err != nil {
 			return
 		}
@@ -141,84 +246,103 @@ func controlPost(state state.State, r *http.Request) response.Response {
 
 	// Replace the server keypair if the cluster member name has changed upon initialization.
 	if !certNameMatches {
-		err := os.Remove(filepath.Join(state.FileSystem().StateDir, "server.crt"))
+		err := os.Remove(filepath.Join(s.FileSystem().StateDir, "server.crt"))
 		if err != nil {
-			return response.SmartError(err)
+			return err
 		}
 
-		err = os.Remove(filepath.Join(state.FileSystem().StateDir, "server.key"))
+		err = os.Remove(filepath.Join(s.FileSystem().StateDir, "server.key"))
 		if err != nil {
-			return response.SmartError(err)
+			return err
 		}
 
 		// Generate a new keypair with the new subject name.
-		_, err = shared.KeyPairAndCA(state.FileSystem().StateDir, string(types.ServerCertificateName), shared.CertServer, shared.CertOptions{AddHosts: true, CommonName: req.Name})
+		_, err = shared.KeyPairAndCA(s.FileSystem().StateDir, string(types.ServerCertificateName), shared.CertServer, shared.CertOptions{AddHosts: true, CommonName: req.Name})
 		if err != nil {
-			return response.SmartError(err)
+			return err
 		}
 
 		err = intState.ReloadCert(types.ServerCertificateName)
 		if err != nil {
-			return response.SmartError(err)
+			return err
 		}
 	}
 
-	if req.JoinToken != "" {
-		joinInfo, err = joinWithToken(state, r, req)
+	if token != nil {
+		joinInfo, err = joinWithToken(ctx, s, req, token)
 		if err != nil {
-			return response.SmartError(err)
+			return err
 		}
 
 		reverter.Success()
+		intState.InitProgress.Report(types.InitStageComplete, "Joined the cluster")
 
-		return response.EmptySyncResponse
+		return nil
 	}
 
-	err = intState.StartAPI(r.Context(), req.Bootstrap, req.InitConfig)
+	err = intState.StartAPI(ctx, req.Bootstrap, req.InitConfig)
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
 	reverter.Success()
+	intState.InitProgress.Report(types.InitStageComplete, "Bootstrapped the cluster")
 
-	return response.EmptySyncResponse
+	return nil
 }
 
-func joinWithToken(state state.State, r *http.Request, req *internalTypes.Control) (*internalTypes.TokenResponse, error) {
-	token, err := internalTypes.DecodeToken(req.JoinToken)
+// controlProgressGet streams the stages reached while the daemon bootstraps or joins a cluster as "progress" events.
+// A "subscribed" event is sent first, so that clients can wait for it before sending the initialization request.
+func controlProgressGet(s state.State, r *http.Request) response.Response {
+	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return nil, err
+		return rest.SmartError(err)
 	}
 
-	serverCert, err := state.ServerCert().PublicKeyX509()
+	return rest.EventStreamResponse(r, func(ctx context.Context, lastEventID string, send func(event rest.Event) error) error {
+		progress, unsubscribe := intState.InitProgress.Subscribe()
+		defer unsubscribe()
+
+		err := send(rest.Event{Type: "subscribed", Data: map[string]any{}})
+		if err != nil {
+			return err
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case p := <-progress:
+				err := send(rest.Event{Type: "progress", Data: p})
+				if err != nil {
+					return err
+				}
+
+				if p.Stage == types.InitStageComplete {
+					return nil
+				}
+			}
+		}
+	})
+}
+
+// joinWithToken joins the cluster of the token, which the caller has already checked.
+func joinWithToken(ctx context.Context, s state.State, req *internalTypes.Control, token *internalTypes.Token) (*internalTypes.TokenResponse, error) {
+	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to parse server certificate when bootstrapping API: %w", err)
+		return nil, err
 	}
 
-	intState, err := internalState.ToInternal(state)
+	// Prepare the cluster for the incoming dqlite request by creating a database entry.
+	newClusterMember, err := newJoinRequest(intState, req, token)
 	if err != nil {
 		return nil, err
 	}
 
 	// Add the local node to the list of clusterMembers.
-	daemonConfig := &trust.Location{Address: req.Address, Name: req.Name}
 	localClusterMember := trust.Remote{
-		Location:    *daemonConfig,
-		Certificate: types.X509Certificate{Certificate: serverCert},
-	}
-
-	// Prepare the cluster for the incoming dqlite request by creating a database entry.
-	internalVersion, externalVersion, _ := state.Database().SchemaVersion()
-	newClusterMember := types.ClusterMember{
-		ClusterMemberLocal: types.ClusterMemberLocal{
-			Name:        localClusterMember.Name,
-			Address:     localClusterMember.Address,
-			Certificate: localClusterMember.Certificate,
-		},
-		SchemaInternalVersion: internalVersion,
-		SchemaExternalVersion: externalVersion,
-		Secret:                token.Secret,
-		Extensions:            intState.Extensions,
+		Location:    trust.Location{Address: req.Address, Name: req.Name, DNSName: req.DNSName},
+		Certificate: newClusterMember.Certificate,
 	}
 
 	// Get a client to the target address.
@@ -233,18 +357,23 @@ func joinWithToken(state state.State, r *http.Request, req *internalTypes.Contro
 			continue
 		}
 
-		fingerprint := shared.CertFingerprint(cert)
-		if fingerprint != token.Fingerprint {
-			logger.Warn("Cluster certificate token does not match that of cluster member", logger.Ctx{"address": url.String(), "fingerprint": fingerprint, "expected": token.Fingerprint})
+		matches, err := token.MatchesCertificate(cert)
+		if err != nil {
+			return nil, err
+		}
+
+		if !matches {
+			logger.Warn("Cluster certificate token does not match that of cluster member", logger.Ctx{"address": url.String(), "fingerprint": shared.CertFingerprint(cert), "expected": token.Fingerprint})
 			continue
 		}
 
-		d, err := internalClient.New(*url, state.ServerCert(), cert, false)
+		d, err := internalClient.New(*url, s.ServerCert(), cert, false)
 		if err != nil {
 			return nil, err
 		}
 
-		joinInfo, err = internalClient.AddClusterMember(context.Background(), d, newClusterMember)
+		intState.InitProgress.Report(types.InitStageJoinRequest, fmt.Sprintf("Requesting to join the cluster through %q", addr.String()))
+		joinInfo, err = internalClient.AddClusterMember(ctx, d, newClusterMember)
 		if err == nil {
 			break
 		}
@@ -258,7 +387,8 @@ func joinWithToken(state state.State, r *http.Request, req *internalTypes.Contro
 	}
 
 	// Set up cluster certificate.
-	err = util.WriteCert(state.FileSystem().StateDir, string(types.ClusterCertificateName), []byte(joinInfo.ClusterCert.String()), []byte(joinInfo.ClusterKey), nil)
+	intState.InitProgress.Report(types.InitStageCertificates, "Installing the cluster certificates")
+	err = util.WriteCert(s.FileSystem().StateDir, string(types.ClusterCertificateName), []byte(joinInfo.ClusterCert.String()), []byte(joinInfo.ClusterKey), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -271,7 +401,7 @@ func joinWithToken(state state.State, r *http.Request, req *internalTypes.Contro
 			ca = []byte(cert.CA)
 		}
 
-		err := util.WriteCert(state.FileSystem().CertificatesDir, name, []byte(cert.Cert), []byte(cert.Key), ca)
+		err := util.WriteCert(s.FileSystem().CertificatesDir, name, []byte(cert.Cert), []byte(cert.Key), ca)
 		if err != nil {
 			return nil, err
 		}
@@ -281,7 +411,7 @@ func joinWithToken(state state.State, r *http.Request, req *internalTypes.Contro
 	clusterMembers := make([]trust.Remote, 0, len(joinInfo.ClusterMembers))
 	for _, clusterMember := range joinInfo.ClusterMembers {
 		remote := trust.Remote{
-			Location:    trust.Location{Name: clusterMember.Name, Address: clusterMember.Address},
+			Location:    trust.Location{Name: clusterMember.Name, Address: clusterMember.Address, DNSName: clusterMember.DNSName},
 			Certificate: clusterMember.Certificate,
 		}
 
@@ -290,16 +420,71 @@ func joinWithToken(state state.State, r *http.Request, req *internalTypes.Contro
 	}
 
 	clusterMembers = append(clusterMembers, localClusterMember)
-	err = state.Remotes().Add(state.FileSystem().TrustDir, clusterMembers...)
+	err = s.Remotes().Add(clusterMembers...)
 	if err != nil {
 		return nil, err
 	}
 
+	// A rejoining member keeps its dqlite ID, so it is not added to the dqlite cluster again.
+	if joinInfo.Rejoin != nil {
+		nodes := make([]cluster.DqliteMember, 0, len(joinInfo.Rejoin.DqliteNodes))
+		for _, node := range joinInfo.Rejoin.DqliteNodes {
+			nodes = append(nodes, cluster.DqliteMember{DqliteID: node.ID, Address: node.Address, Role: node.Role})
+		}
+
+		err = recover.WriteRejoinMembership(s.FileSystem(), joinInfo.Rejoin.DqliteID, req.Address.String(), nodes)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to restore dqlite identity: %w", err)
+		}
+	}
+
 	// Start the HTTPS listeners and join Dqlite.
-	err = intState.StartAPI(r.Context(), false, req.InitConfig, joinAddrs.Strings()...)
+	err = intState.StartAPI(ctx, false, req.InitConfig, joinAddrs.Strings()...)
 	if err != nil {
 		return nil, err
 	}
 
 	return joinInfo, nil
 }
+
+// newJoinRequest returns the request sent by the local system to join the cluster with the given token.
+func newJoinRequest(intState *internalState.InternalState, req *internalTypes.Control, token *internalTypes.Token) (types.ClusterMember, error) {
+	serverCert, err := intState.ServerCert().PublicKeyX509()
+	if err != nil {
+		return types.ClusterMember{}, fmt.Errorf("Failed to parse server certificate when bootstrapping API: %w", err)
+	}
+
+	internalVersion, externalVersion, _ := intState.Database().SchemaVersion()
+
+	return types.ClusterMember{
+		ClusterMemberLocal: types.ClusterMemberLocal{
+			Name:        req.Name,
+			Address:     req.Address,
+			Certificate: types.X509Certificate{Certificate: serverCert},
+			DNSName:     req.DNSName,
+		},
+		SchemaInternalVersion: internalVersion,
+		SchemaExternalVersion: externalVersion,
+		Secret:                token.Secret,
+		Extensions:            intState.Extensions,
+		InitConfig:            req.InitConfig,
+		Time:                  time.Now(),
+	}, nil
+}
+
+// loopbackAddress returns an address on the loopback interface with a free port, along with a listener bound to it.
+func loopbackAddress() (types.AddrPort, net.Listener, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return types.AddrPort{}, nil, err
+	}
+
+	address, err := types.ParseAddrPort(listener.Addr().String())
+	if err != nil {
+		_ = listener.Close()
+
+		return types.AddrPort{}, nil, err
+	}
+
+	return address, listener, nil
+}
@@ -5,11 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared"
 
 	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/internal/endpoints"
+	"github.com/canonical/microcluster/v3/internal/logging"
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
@@ -19,6 +28,16 @@ import (
 	"github.com/canonical/microcluster/v3/state"
 )
 
+// microclusterModule is the path of the microcluster Go module.
+const microclusterModule = "github.com/canonical/microcluster/v3"
+
+var daemonInfoCmd = rest.Endpoint{
+	Path:              "daemon",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: daemonInfoGet, AccessHandler: access.AllowAuthenticated},
+}
+
 var daemonCmd = rest.Endpoint{
 	Path: "daemon/servers",
 
@@ -26,10 +45,163 @@ var daemonCmd = rest.Endpoint{
 	Put: rest.EndpointAction{Handler: daemonServersPut, AccessHandler: access.AllowAuthenticated},
 }
 
+var daemonConfigCmd = rest.Endpoint{
+	Path:              "daemon/config",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: daemonConfigGet, AccessHandler: access.AllowAuthenticated},
+	Put: rest.EndpointAction{Handler: daemonConfigPut, AccessHandler: access.AllowAuthenticated},
+}
+
+var daemonLogCmd = rest.Endpoint{
+	Path:              "daemon/log",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: daemonLogGet, AccessHandler: access.AllowAuthenticated},
+	Put: rest.EndpointAction{Handler: daemonLogPut, AccessHandler: access.AllowAuthenticated},
+}
+
+// daemonInfoGet returns runtime information about the daemon of the local cluster member.
+// With "?target=<name>" the information of that cluster member is returned instead, and with "?target=all" the
+// information of every cluster member is returned as a list.
+func daemonInfoGet(s state.State, r *http.Request) response.Response {
+	target := r.URL.Query().Get("target")
+	if target != "all" {
+		if target != "" {
+			resp := s.ForwardToMember(r, target)
+			if resp != nil {
+				return resp
+			}
+		}
+
+		info, err := daemonInfo(s)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		return response.SyncResponse(true, info)
+	}
+
+	localInfo, err := daemonInfo(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	cluster, err := s.Cluster(false)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	// Members that cannot be reached are reported with an error rather than failing the whole request.
+	var infoMu sync.Mutex
+	infos := []types.DaemonInfo{*localInfo}
+	remotes := s.Remotes()
+	_ = cluster.Query(r.Context(), true, func(ctx context.Context, c *client.Client) error {
+		info, err := internalClient.GetDaemonInfo(ctx, &c.Client)
+		if err != nil {
+			info = &types.DaemonInfo{Address: c.URL().URL.Host, Error: err.Error()}
+
+			addrPort, parseErr := types.ParseAddrPort(c.URL().URL.Host)
+			if parseErr == nil {
+				remote := remotes.RemoteByAddress(addrPort)
+				if remote != nil {
+					info.Name = remote.Name
+				}
+			}
+		}
+
+		infoMu.Lock()
+		infos = append(infos, *info)
+		infoMu.Unlock()
+
+		return nil
+	})
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	return response.SyncResponse(true, infos)
+}
+
+// daemonInfo collects runtime information about the local daemon.
+func daemonInfo(s state.State) (*types.DaemonInfo, error) {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &types.DaemonInfo{
+		Name:                s.Name(),
+		Address:             s.Address().URL.Host,
+		PID:                 os.Getpid(),
+		StartedAt:           intState.StartTime,
+		Uptime:              time.Since(intState.StartTime),
+		GoVersion:           runtime.Version(),
+		MicroclusterVersion: microclusterVersion(),
+		Version:             s.Version(),
+		Servers:             map[string]string{},
+	}
+
+	for _, name := range s.ExtensionServers() {
+		info.Servers[name] = ""
+
+		network, ok := intState.Endpoints.Get(name).(*endpoints.Network)
+		if ok {
+			address := network.Address()
+			info.Servers[name] = address.URL.Host
+		}
+	}
+
+	var usage syscall.Rusage
+	err = syscall.Getrusage(syscall.RUSAGE_SELF, &usage)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get resource usage of the daemon: %w", err)
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	info.Resources = types.DaemonResources{
+		CPUUser:      time.Duration(usage.Utime.Nano()),
+		CPUSystem:    time.Duration(usage.Stime.Nano()),
+		MaxRSS:       uint64(usage.Maxrss) * 1024,
+		MemoryHeap:   memStats.HeapAlloc,
+		MemorySystem: memStats.Sys,
+		Goroutines:   runtime.NumGoroutine(),
+	}
+
+	return info, nil
+}
+
+// microclusterVersion returns the version of the microcluster module the daemon was built with, if known.
+func microclusterVersion() string {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	if buildInfo.Main.Path == microclusterModule {
+		return buildInfo.Main.Version
+	}
+
+	for _, dep := range buildInfo.Deps {
+		if dep.Path != microclusterModule {
+			continue
+		}
+
+		if dep.Replace != nil {
+			return dep.Replace.Version
+		}
+
+		return dep.Version
+	}
+
+	return ""
+}
+
 func daemonServersGet(s state.State, r *http.Request) response.Response {
 	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	return response.SyncResponse(true, intState.LocalConfig().GetServers())
@@ -73,7 +245,7 @@ func daemonServersPut(s state.State, r *http.Request) response.Response {
 
 	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	daemonConfig := intState.LocalConfig()
@@ -82,23 +254,23 @@ func daemonServersPut(s state.State, r *http.Request) response.Response {
 	// Persist the configuration changes to file.
 	err = daemonConfig.Write()
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// Update the additional listeners.
 	err = intState.UpdateServers()
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	cluster, err := s.Cluster(false)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// Run the OnDaemonConfigUpdate hook on all other members.
 	remotes := s.Remotes()
-	err = cluster.Query(r.Context(), true, func(ctx context.Context, c *client.Client) error {
+	err = cluster.FanOut(r.Context(), notificationTimeout, func(ctx context.Context, c *client.Client) error {
 		c.SetClusterNotification()
 		addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
 		if err != nil {
@@ -111,9 +283,140 @@ func daemonServersPut(s state.State, r *http.Request) response.Response {
 		}
 
 		return internalClient.RunOnDaemonConfigUpdateHook(ctx, c.Client.UseTarget(remote.Name), daemonConfig.Dump())
-	})
+	}).Err()
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func daemonConfigGet(s state.State, r *http.Request) response.Response {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	socket := intState.LocalConfig().GetSocket()
+	config := types.RuntimeConfig{
+		Heartbeat: intState.LocalConfig().GetHeartbeat(),
+		Socket:    &socket,
+	}
+
+	return response.SyncResponse(true, config)
+}
+
+// daemonConfigPut changes the runtime configuration on every cluster member. Settings missing from the request are
+// left unchanged.
+func daemonConfigPut(s state.State, r *http.Request) response.Response {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	req := types.RuntimeConfig{Heartbeat: intState.LocalConfig().GetHeartbeat()}
+
+	// Parse the request.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Heartbeat.Interval < 0 || req.Heartbeat.OfflineThreshold < 0 {
+		return response.BadRequest(fmt.Errorf("Heartbeat interval and offline threshold cannot be negative"))
+	}
+
+	if req.Socket != nil {
+		// Callers of the control socket are fully trusted, so its access can only be changed by one of them, either
+		// directly or through the notification of another cluster member.
+		identity, _ := access.GetIdentity(r)
+		fromControlSocket := identity.Method == access.AuthMethodUnix
+		fromClusterMember := client.IsNotification(r) && identity.Method == access.AuthMethodTLS && identity.Name != ""
+		if !fromControlSocket && !fromClusterMember {
+			return response.Forbidden(fmt.Errorf("Control socket access can only be changed over the control socket"))
+		}
+
+		_, err = req.Socket.FileMode()
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		// Check the settings apply locally before notifying other cluster members, which persist them.
+		err = intState.CheckSocketAccess(*req.Socket)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
+	// Heartbeats are driven by the dqlite leader, and management agents expect the same control socket access on every
+	// cluster member, so apply the settings on every cluster member.
+	// Before the daemon is initialized, the settings are only persisted locally and applied once it starts its database.
+	if !client.IsNotification(r) && s.Database().IsOpen(r.Context()) == nil {
+		cluster, err := s.Cluster(true)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		err = cluster.FanOut(r.Context(), notificationTimeout, func(ctx context.Context, c *client.Client) error {
+			return c.UpdateRuntimeConfig(ctx, req)
+		}).Err()
+		if err != nil {
+			return rest.SmartError(err)
+		}
+	}
+
+	daemonConfig := intState.LocalConfig()
+	if req.Socket != nil {
+		// Change the control socket access first, so that the settings are only persisted if they can be applied.
+		err = intState.SetSocketAccess(*req.Socket)
+		if err != nil {
+			return rest.SmartError(fmt.Errorf("Failed to update control socket access: %w", err))
+		}
+
+		daemonConfig.SetSocket(*req.Socket)
+	}
+
+	daemonConfig.SetHeartbeat(req.Heartbeat)
+
+	// Persist the configuration changes to file.
+	err = daemonConfig.Write()
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	intState.InternalDatabase.SetHeartbeatConfig(req.Heartbeat)
+
+	return response.EmptySyncResponse
+}
+
+// daemonLogGet returns the logging configuration of the daemon.
+func daemonLogGet(s state.State, r *http.Request) response.Response {
+	config, err := logging.Config()
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, config)
+}
+
+// daemonLogPut changes the level and target of the daemon's log, without restarting it.
+func daemonLogPut(s state.State, r *http.Request) response.Response {
+	req := types.LogConfig{}
+
+	// Parse the request.
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = req.Validate()
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = logging.Set(req)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	return response.EmptySyncResponse
@@ -1,14 +1,35 @@
 package resources
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/gorilla/mux"
 
+	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/db/update"
+	"github.com/canonical/microcluster/v3/internal/recover"
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	"github.com/canonical/microcluster/v3/internal/rest/types"
 	"github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	apiTypes "github.com/canonical/microcluster/v3/rest/types"
 )
 
 var databaseCmd = rest.Endpoint{
@@ -19,6 +40,67 @@ var databaseCmd = rest.Endpoint{
 	Patch: rest.EndpointAction{Handler: databasePatch},
 }
 
+var databaseRollbackCmd = rest.Endpoint{
+	Path: "database/rollback",
+
+	Post: rest.EndpointAction{Handler: databaseRollbackPost, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseRaftCmd = rest.Endpoint{
+	Path: "database/raft",
+
+	Get: rest.EndpointAction{Handler: databaseRaftGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseRaftFilesCmd = rest.Endpoint{
+	Path: "database/raft/files",
+
+	Get: rest.EndpointAction{Handler: databaseRaftFilesGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseMembersCmd = rest.Endpoint{
+	Path: "database",
+
+	Get: rest.EndpointAction{Handler: databaseMembersGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseSchemaCmd = rest.Endpoint{
+	Path: "database/schema",
+
+	Get: rest.EndpointAction{Handler: databaseSchemaGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseBackfillsCmd = rest.Endpoint{
+	Path: "database/backfills",
+
+	Get: rest.EndpointAction{Handler: databaseBackfillsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseMaintenanceCmd = rest.Endpoint{
+	Path: "database/maintenance",
+
+	Get:  rest.EndpointAction{Handler: databaseMaintenanceGet, AccessHandler: access.AllowAuthenticated},
+	Post: rest.EndpointAction{Handler: databaseMaintenancePost, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseStatsCmd = rest.Endpoint{
+	Path: "database/stats",
+
+	Get: rest.EndpointAction{Handler: databaseStatsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseBackupsCmd = rest.Endpoint{
+	Path: "database/backups",
+
+	Get: rest.EndpointAction{Handler: databaseBackupsGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+var databaseBackupCmd = rest.Endpoint{
+	Path: "database/backups/{name}",
+
+	Get: rest.EndpointAction{Handler: databaseBackupGet, AccessHandler: access.AllowAuthenticated},
+}
+
 func databasePost(state state.State, r *http.Request) response.Response {
 	// Compare the dqlite version of the connecting client with our own.
 	versionHeader := r.Header.Get("X-Dqlite-Version")
@@ -55,7 +137,7 @@ func databasePatch(s state.State, r *http.Request) response.Response {
 
 	intState, err := state.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// Notify this node that a schema upgrade has occurred, in case we are waiting on one.
@@ -63,3 +145,490 @@ func databasePatch(s state.State, r *http.Request) response.Response {
 
 	return response.EmptySyncResponse
 }
+
+// databaseRollbackPost takes a local backup of the database and then reverts external schema updates
+// until the requested version is reached.
+func databaseRollbackPost(s state.State, r *http.Request) response.Response {
+	req := types.SchemaRollback{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	dump, err := intState.InternalDatabase.Dump(r.Context())
+	if err != nil {
+		return rest.SmartError(fmt.Errorf("Failed to back up database before schema rollback: %w", err))
+	}
+
+	err = recover.CreateDatabaseDumpBackup(s.FileSystem(), dump, intState.ArchiveEncryption)
+	if err != nil {
+		return rest.SmartError(fmt.Errorf("Failed to back up database before schema rollback: %w", err))
+	}
+
+	err = intState.InternalDatabase.SchemaRollback(r.Context(), req.Version)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// databaseSchemaGet returns the schema versions of the local cluster member and of every cluster member recorded in
+// the database, along with the statements creating the database schema.
+func databaseSchemaGet(s state.State, r *http.Request) response.Response {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	internalVersion, externalVersion, _ := intState.InternalDatabase.SchemaVersion()
+	schema := apiTypes.DatabaseSchema{
+		Internal:  internalVersion,
+		External:  externalVersion,
+		Converged: true,
+	}
+
+	statements := []string{}
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		members, err := cluster.GetCoreClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		schema.Members = make([]apiTypes.DatabaseSchemaMember, 0, len(members))
+		for _, member := range members {
+			schema.Members = append(schema.Members, apiTypes.DatabaseSchemaMember{
+				Name:     member.Name,
+				Address:  member.Address,
+				Role:     string(member.Role),
+				Internal: member.SchemaInternal,
+				External: member.SchemaExternal,
+			})
+
+			if member.Role != cluster.Pending && (member.SchemaInternal != internalVersion || member.SchemaExternal != externalVersion) {
+				schema.Converged = false
+			}
+		}
+
+		statements, err = query.SelectStrings(ctx, tx, "SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY rowid")
+
+		return err
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	schema.Dump = strings.Join(statements, ";\n")
+	if schema.Dump != "" {
+		schema.Dump += ";\n"
+	}
+
+	return response.SyncResponse(true, schema)
+}
+
+// databaseBackfillsGet returns the progress of the data backfills.
+func databaseBackfillsGet(s state.State, r *http.Request) response.Response {
+	var backfills []apiTypes.DatabaseBackfill
+	err := s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		backfills, err = update.GetBackfills(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, backfills)
+}
+
+// databaseMaintenanceGet returns the space used by the database, and its disk usage on each cluster member.
+// Notifications from other cluster members only get the disk usage of the local member.
+func databaseMaintenanceGet(s state.State, r *http.Request) response.Response {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	if client.IsNotification(r) {
+		size, err := intState.InternalDatabase.DiskUsage()
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		member := apiTypes.DatabaseMemberUsage{Name: s.Name(), Address: s.Address().URL.Host, Reachable: true, Size: size}
+
+		return response.SyncResponse(true, apiTypes.DatabaseUsage{Members: []apiTypes.DatabaseMemberUsage{member}})
+	}
+
+	usage, err := databaseUsage(r.Context(), s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, usage)
+}
+
+// databaseMaintenancePost runs maintenance, such as a vacuum, on the database. Requests received by other members are
+// forwarded to the dqlite leader.
+func databaseMaintenancePost(s state.State, r *http.Request) response.Response {
+	req := apiTypes.DatabaseMaintenance{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	leaderClient, err := s.Database().Leader(r.Context())
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	defer func() { _ = leaderClient.Close() }()
+
+	leaderInfo, err := leaderClient.Leader(r.Context())
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	// Forward request to leader, unless it was already forwarded to us by a member that thought we were the leader.
+	if leaderInfo.Address != s.Address().URL.Host {
+		if client.IsNotification(r) {
+			return rest.SmartError(api.StatusErrorf(http.StatusServiceUnavailable, "Dqlite leadership changed while forwarding the request: %w", apiTypes.ErrNotLeader))
+		}
+
+		clusterCert, err := s.ClusterCert().PublicKeyX509()
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		url := api.NewURL().Scheme("https").Host(leaderInfo.Address)
+		leader, err := internalClient.New(*url, s.ServerCert(), clusterCert, true)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		usage, err := internalClient.MaintainDatabase(r.Context(), leader, req)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		return response.SyncResponse(true, usage)
+	}
+
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	start := time.Now()
+	err = intState.InternalDatabase.Maintain(r.Context(), req)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	logger.Info("Completed database maintenance", logger.Ctx{"vacuum": req.Vacuum, "analyze": req.Analyze, "duration": time.Since(start)})
+
+	usage, err := databaseUsage(r.Context(), s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, usage)
+}
+
+// databaseUsage returns the space used by the database, along with the disk usage of each cluster member, retrieved
+// concurrently. Members that cannot be reached are reported as such.
+func databaseUsage(ctx context.Context, s state.State) (apiTypes.DatabaseUsage, error) {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return apiTypes.DatabaseUsage{}, err
+	}
+
+	usage, err := intState.InternalDatabase.Usage(ctx)
+	if err != nil {
+		return apiTypes.DatabaseUsage{}, err
+	}
+
+	clusterCert, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return apiTypes.DatabaseUsage{}, err
+	}
+
+	remotes := s.Remotes().RemotesByName()
+	usage.Members = make([]apiTypes.DatabaseMemberUsage, 0, len(remotes))
+	for _, remote := range remotes {
+		usage.Members = append(usage.Members, apiTypes.DatabaseMemberUsage{Name: remote.Name, Address: remote.Address.String()})
+	}
+
+	sort.Slice(usage.Members, func(i, j int) bool { return usage.Members[i].Name < usage.Members[j].Name })
+
+	// Set up the clients to every other member before querying any of them, so that no query is left running if a
+	// client cannot be set up.
+	clients := make([]*internalClient.Client, len(usage.Members))
+	for i := range usage.Members {
+		member := &usage.Members[i]
+		if member.Address == s.Address().URL.Host {
+			size, err := intState.InternalDatabase.DiskUsage()
+			if err != nil {
+				return apiTypes.DatabaseUsage{}, err
+			}
+
+			member.Size = size
+			member.Reachable = true
+
+			continue
+		}
+
+		url := api.NewURL().Scheme("https").Host(member.Address)
+		clients[i], err = internalClient.New(*url, s.ServerCert(), clusterCert, true)
+		if err != nil {
+			return apiTypes.DatabaseUsage{}, fmt.Errorf("Failed to create HTTPS client for cluster member with address %q: %w", member.Address, err)
+		}
+	}
+
+	getMemberDiskUsage(ctx, usage.Members, clients)
+
+	return usage, nil
+}
+
+// getMemberDiskUsage concurrently records the disk usage of each member that has a client, and whether it could be
+// retrieved. It returns once every member has answered, or the requests timed out.
+func getMemberDiskUsage(ctx context.Context, members []apiTypes.DatabaseMemberUsage, clients []*internalClient.Client) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+	for i, c := range clients {
+		if c == nil {
+			continue
+		}
+
+		member := &members[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			memberUsage, err := internalClient.GetDatabaseUsage(ctx, c)
+			if err != nil || len(memberUsage.Members) != 1 {
+				logger.Warn("Failed to get database disk usage of cluster member", logger.Ctx{"name": member.Name, "error": err})
+				return
+			}
+
+			member.Size = memberUsage.Members[0].Size
+			member.Reachable = true
+		}()
+	}
+
+	wg.Wait()
+}
+
+// databaseStatsGet returns statistics about the statements run against the database by this cluster member.
+func databaseStatsGet(s state.State, r *http.Request) response.Response {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, intState.InternalDatabase.QueryStats())
+}
+
+// databaseRaftGet returns the raft state persisted by the local dqlite node.
+func databaseRaftGet(s state.State, r *http.Request) response.Response {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	raftState, err := intState.InternalDatabase.RaftState()
+	if err != nil {
+		return rest.SmartError(fmt.Errorf("Failed to read raft state: %w", err))
+	}
+
+	return response.SyncResponse(true, raftState)
+}
+
+// databaseRaftFilesGet returns the raft metadata, segment and snapshot files of the local dqlite node.
+func databaseRaftFilesGet(s state.State, r *http.Request) response.Response {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	dump, err := intState.InternalDatabase.RaftDump()
+	if err != nil {
+		return rest.SmartError(fmt.Errorf("Failed to read raft files: %w", err))
+	}
+
+	return response.SyncResponse(true, dump.Files)
+}
+
+// databaseMembersGet returns the dqlite role and raft state of each cluster member, as seen from the dqlite leader.
+// Requests received by other members are forwarded to the leader.
+func databaseMembersGet(s state.State, r *http.Request) response.Response {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	leaderClient, err := s.Database().Leader(ctx)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	leaderInfo, err := leaderClient.Leader(ctx)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	// Forward request to leader, unless it was already forwarded to us by a member that thought we were the leader.
+	if leaderInfo.Address != s.Address().URL.Host {
+		if client.IsNotification(r) {
+			return rest.SmartError(api.StatusErrorf(http.StatusServiceUnavailable, "Dqlite leadership changed while forwarding the request: %w", apiTypes.ErrNotLeader))
+		}
+
+		clusterCert, err := s.ClusterCert().PublicKeyX509()
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		url := api.NewURL().Scheme("https").Host(leaderInfo.Address)
+		leader, err := internalClient.New(*url, s.ServerCert(), clusterCert, true)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		members, err := leader.GetDatabaseMembers(ctx)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		return response.SyncResponse(true, members)
+	}
+
+	nodes, err := s.Database().Cluster(ctx, leaderClient)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	members, err := databaseMembers(ctx, s, nodes, leaderInfo.Address)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, members)
+}
+
+// databaseMembers retrieves the raft state of each dqlite node concurrently, and computes how far each one trails the leader.
+func databaseMembers(ctx context.Context, s state.State, nodes []dqliteClient.NodeInfo, leaderAddress string) ([]apiTypes.DatabaseMember, error) {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterCert, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]apiTypes.DatabaseMember, len(nodes))
+	wg := sync.WaitGroup{}
+	for i, node := range nodes {
+		members[i] = apiTypes.DatabaseMember{
+			Address:  node.Address,
+			DqliteID: node.ID,
+			Role:     node.Role.String(),
+			Leader:   node.Address == leaderAddress,
+		}
+
+		addrPort, err := apiTypes.ParseAddrPort(node.Address)
+		if err == nil {
+			remote := s.Remotes().RemoteByAddress(addrPort)
+			if remote != nil {
+				members[i].Name = remote.Name
+			}
+		}
+
+		if members[i].Leader {
+			raftState, err := intState.InternalDatabase.RaftState()
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read raft state: %w", err)
+			}
+
+			members[i].DatabaseRaftState = raftState
+			members[i].Reachable = true
+
+			continue
+		}
+
+		url := api.NewURL().Scheme("https").Host(node.Address)
+		c, err := internalClient.New(*url, s.ServerCert(), clusterCert, false)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create HTTPS client for cluster member with address %q: %w", node.Address, err)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			raftState, err := internalClient.GetRaftState(ctx, c)
+			if err != nil {
+				logger.Warn("Failed to get raft state of dqlite member", logger.Ctx{"address": members[i].Address, "error": err})
+				return
+			}
+
+			members[i].DatabaseRaftState = *raftState
+			members[i].Reachable = true
+		}(i)
+	}
+
+	wg.Wait()
+
+	var leaderIndex uint64
+	for _, member := range members {
+		if member.Leader {
+			leaderIndex = member.LastIndex
+		}
+	}
+
+	for i := range members {
+		if members[i].Reachable && members[i].LastIndex < leaderIndex {
+			members[i].Lag = leaderIndex - members[i].LastIndex
+		}
+	}
+
+	return members, nil
+}
+
+// databaseBackupsGet lists the database backups in the state directory of the cluster member.
+func databaseBackupsGet(s state.State, r *http.Request) response.Response {
+	backups, err := recover.ListDatabaseBackups(s.FileSystem())
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, backups)
+}
+
+// databaseBackupGet downloads a database backup from the state directory of the local cluster member.
+func databaseBackupGet(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	backupPath, err := recover.DatabaseBackupPath(s.FileSystem(), name)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		http.ServeFile(w, r, backupPath)
+
+		return nil
+	})
+}
@@ -0,0 +1,63 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures the disk usage of each cluster member is retrieved, and that members that cannot answer are reported as
+// unreachable without failing the others.
+func TestGetMemberDiskUsage(t *testing.T) {
+	newMember := func(body string) *internalClient.Client {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if body == "" {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"type": "error", "error_code": 500, "error": "Failed to get disk usage"}`))
+
+				return
+			}
+
+			_, _ = fmt.Fprintf(w, `{"type": "sync", "status": "Success", "status_code": 200, "metadata": %s}`, body)
+		}))
+		t.Cleanup(server.Close)
+
+		url := api.NewURL().Scheme("https").Host(server.Listener.Addr().String())
+		c, err := internalClient.New(*url, shared.TestingKeyPair(), server.Certificate(), false)
+		require.NoError(t, err)
+
+		return c
+	}
+
+	members := []types.DatabaseMemberUsage{
+		{Name: "local", Size: 10, Reachable: true},
+		{Name: "healthy"},
+		{Name: "failing"},
+		{Name: "confused"},
+	}
+
+	clients := []*internalClient.Client{
+		nil,
+		newMember(`{"members": [{"name": "healthy", "size": 20}]}`),
+		newMember(""),
+		newMember(`{"members": []}`),
+	}
+
+	getMemberDiskUsage(context.Background(), members, clients)
+
+	require.Equal(t, []types.DatabaseMemberUsage{
+		{Name: "local", Size: 10, Reachable: true},
+		{Name: "healthy", Size: 20, Reachable: true},
+		{Name: "failing"},
+		{Name: "confused"},
+	}, members)
+}
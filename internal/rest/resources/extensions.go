@@ -0,0 +1,28 @@
+package resources
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var extensionsCmd = rest.Endpoint{
+	Path:              "extensions",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: extensionsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+// extensionsGet returns the API extensions supported by every cluster member, and the members lagging behind.
+func extensionsGet(s state.State, r *http.Request) response.Response {
+	clusterExtensions, err := s.ClusterExtensions(r.Context())
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, clusterExtensions)
+}
@@ -0,0 +1,97 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+// heartbeatGracePeriods is the number of heartbeat intervals a member may miss before it is considered to have
+// stopped responding to heartbeats.
+const heartbeatGracePeriods = 3
+
+// probeClusterMembers sends a small request to each cluster member to check that it is reachable,
+// and records its status and the roundtrip latency of the request. Members under maintenance are skipped.
+func probeClusterMembers(ctx context.Context, s state.State, members []types.ClusterMember) error {
+	clusterCert, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return err
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return err
+	}
+
+	offlineThreshold := intState.InternalDatabase.GetOfflineThreshold()
+	heartbeatInterval := time.Duration(intState.InternalDatabase.GetHeartbeatInterval())
+
+	clients := make([]*internalClient.Client, len(members))
+	for i, member := range members {
+		addr := api.NewURL().Scheme("https").Host(member.Address.String())
+		clients[i], err = internalClient.New(*addr, s.ServerCert(), clusterCert, false)
+		if err != nil {
+			return fmt.Errorf("Failed to create HTTPS client for cluster member with address %q: %w", addr.String(), err)
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	for i := range members {
+		// Members under maintenance are expected to go down, so they are not probed.
+		if members[i].Maintenance {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := clients[i].CheckReady(ctx)
+			if err == nil {
+				members[i].Latency = time.Since(start)
+			} else {
+				logger.Debug("Failed to probe cluster member", logger.Ctx{"address": members[i].Address.String(), "error": err})
+			}
+
+			members[i].Status = memberStatus(err, members[i].LastHeartbeat, time.Now(), heartbeatInterval, offlineThreshold)
+			if members[i].Status != types.MemberOnline {
+				logger.Warnf("Failed to get status of cluster member with address %q: %v", members[i].Address.String(), err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// memberStatus returns the status of a cluster member from the outcome of probing it, and from its last heartbeat.
+// A member that could not be probed is still reported online if it responded to a heartbeat within the offline
+// threshold, so that transient failures are tolerated. Otherwise it is unreachable if it still responds to heartbeats,
+// meaning that only this member cannot connect to it, and offline if it does not.
+func memberStatus(probeErr error, lastHeartbeat time.Time, now time.Time, heartbeatInterval time.Duration, offlineThreshold time.Duration) types.MemberStatus {
+	if probeErr == nil {
+		return types.MemberOnline
+	}
+
+	sinceHeartbeat := now.Sub(lastHeartbeat)
+	if offlineThreshold > 0 && sinceHeartbeat < offlineThreshold {
+		return types.MemberOnline
+	}
+
+	if !lastHeartbeat.IsZero() && sinceHeartbeat < heartbeatGracePeriods*heartbeatInterval {
+		return types.MemberUnreachable
+	}
+
+	return types.MemberOffline
+}
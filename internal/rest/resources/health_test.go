@@ -0,0 +1,29 @@
+package resources
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+func TestMemberStatus(t *testing.T) {
+	now := time.Now()
+	interval := 10 * time.Second
+	probeErr := fmt.Errorf("connection refused")
+
+	assert.Equal(t, types.MemberOnline, memberStatus(nil, time.Time{}, now, interval, 0))
+
+	// Members that recently responded to a heartbeat are alive, but cannot be reached from here.
+	assert.Equal(t, types.MemberUnreachable, memberStatus(probeErr, now.Add(-5*time.Second), now, interval, 0))
+
+	// Transient failures are tolerated within the offline threshold.
+	assert.Equal(t, types.MemberOnline, memberStatus(probeErr, now.Add(-5*time.Second), now, interval, time.Minute))
+
+	// Members that stopped responding to heartbeats, or never did, are offline.
+	assert.Equal(t, types.MemberOffline, memberStatus(probeErr, now.Add(-time.Minute), now, interval, 0))
+	assert.Equal(t, types.MemberOffline, memberStatus(probeErr, time.Time{}, now, interval, 0))
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	dqliteClient "github.com/canonical/go-dqlite/client"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared/logger"
 
@@ -31,7 +32,7 @@ func heartbeatPost(s state.State, r *http.Request) response.Response {
 	var hbInfo internalTypes.HeartbeatInfo
 	err := json.NewDecoder(r.Body).Decode(&hbInfo)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	if hbInfo.BeginRound {
@@ -43,7 +44,7 @@ func heartbeatPost(s state.State, r *http.Request) response.Response {
 
 	err = s.Database().IsOpen(r.Context())
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Failed to respond to heartbeat, database is not yet open: %w", err))
+		return rest.SmartError(fmt.Errorf("Failed to respond to heartbeat, database is not yet open: %w", err))
 	}
 
 	clusterMemberList := []types.ClusterMember{}
@@ -51,9 +52,31 @@ func heartbeatPost(s state.State, r *http.Request) response.Response {
 		clusterMemberList = append(clusterMemberList, clusterMember)
 	}
 
-	err = s.Remotes().Replace(s.FileSystem().TrustDir, clusterMemberList...)
+	err = s.Remotes().Replace(clusterMemberList...)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	if hbInfo.TrustedClientsDigest == "" || hbInfo.TrustedClientsDigest != intState.TrustedClients.Digest() {
+		err = ReloadTrustedClients(r.Context(), s)
+		if err != nil {
+			logger.Warn("Failed to reload trusted client certificates", logger.Ctx{"error": err})
+		}
+	}
+
+	err = ReloadCertificateRevocations(r.Context(), s)
+	if err != nil {
+		logger.Warn("Failed to reload certificate revocations", logger.Ctx{"error": err})
+	}
+
+	err = ReloadMaintenance(r.Context(), s)
+	if err != nil {
+		logger.Warn("Failed to reload maintenance status", logger.Ctx{"error": err})
 	}
 
 	var internalSchemaVersion, externalSchemaVersion uint64
@@ -69,41 +92,42 @@ func heartbeatPost(s state.State, r *http.Request) response.Response {
 		return nil
 	})
 	if err != nil {
-		return response.SmartError(err)
-	}
-
-	intState, err := internalState.ToInternal(s)
-	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	if internalSchemaVersion != hbInfo.MaxSchemaInternal || externalSchemaVersion != hbInfo.MaxSchemaExternal {
 		err := intState.InternalDatabase.Update()
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 	}
 
 	// TODO: If our schema version is behind, we should try to update here.
 
-	return response.EmptySyncResponse
+	return response.SyncResponse(true, internalTypes.HeartbeatResponse{Time: time.Now()})
 }
 
 // beginHeartbeat initiates a heartbeat from the leader node to all other cluster members, if we haven't sent one out
 // recently.
 func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.HeartbeatInfo) response.Response {
 	if s.Address().URL.Host != hbReq.LeaderAddress {
-		return response.SmartError(fmt.Errorf("Attempt to initiate heartbeat from non-leader"))
+		return rest.SmartError(fmt.Errorf("Attempt to initiate heartbeat from non-leader: %w", types.ErrNotLeader))
 	}
 
 	// Get the database record of cluster members.
 	var clusterMembers []types.ClusterMember
+	var maintenance map[string]bool
 	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
 		dbClusterMembers, err := cluster.GetCoreClusterMembers(ctx, tx)
 		if err != nil {
 			return err
 		}
 
+		maintenance, err = cluster.GetCoreClusterMembersUnderMaintenance(ctx, tx)
+		if err != nil {
+			return err
+		}
+
 		clusterMembers = make([]types.ClusterMember, 0, len(dbClusterMembers))
 		for _, clusterMember := range dbClusterMembers {
 			apiClusterMember, err := clusterMember.ToAPI()
@@ -114,10 +138,11 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 			clusterMembers = append(clusterMembers, *apiClusterMember)
 		}
 
-		return err
+		// Cluster members learn the DNS names of each other from the heartbeat.
+		return setDNSNames(ctx, tx, clusterMembers)
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// Get dqlite record of cluster members.
@@ -131,6 +156,28 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 		dqliteMap[member] = role
 	}
 
+	// Heartbeats follow dqlite role adjustments, which may have promoted cluster members under maintenance again.
+	if len(maintenance) > 0 {
+		names := make(map[string]string, len(clusterMembers))
+		for _, clusterMember := range clusterMembers {
+			names[clusterMember.Address.String()] = clusterMember.Name
+		}
+
+		demoted, handedOver, err := demoteMaintenanceMembers(ctx, s, names, maintenance)
+		if err != nil {
+			logger.Warn("Failed to demote cluster members under maintenance", logger.Ctx{"error": err})
+		}
+
+		// The new dqlite leader sends the next heartbeat.
+		if handedOver {
+			return response.EmptySyncResponse
+		}
+
+		for _, address := range demoted {
+			dqliteMap[address] = dqliteClient.Spare.String()
+		}
+	}
+
 	// Update database with dqlite member roles.
 	clusterMap := map[string]types.ClusterMember{}
 	for _, clusterMember := range clusterMembers {
@@ -148,7 +195,7 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 
 	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	leaderEntry := clusterMap[s.Address().URL.Host]
@@ -163,9 +210,19 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 	logger.Debug("Beginning new heartbeat round", logger.Ctx{"address": s.Address().URL.Host})
 
 	// Update local record of cluster members from the database, including any pending nodes for authentication.
-	err = s.Remotes().Replace(s.FileSystem().TrustDir, clusterMembers...)
+	err = s.Remotes().Replace(clusterMembers...)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = ReloadTrustedClients(ctx, s)
 	if err != nil {
-		return response.SmartError(err)
+		logger.Warn("Failed to reload trusted client certificates", logger.Ctx{"error": err})
+	}
+
+	err = ReloadMaintenance(ctx, s)
+	if err != nil {
+		logger.Warn("Failed to reload maintenance status", logger.Ctx{"error": err})
 	}
 
 	// Set the time of the last heartbeat to now.
@@ -173,7 +230,7 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 	clusterMap[s.Address().URL.Host] = leaderEntry
 
 	// Record the maximum schema version discovered.
-	hbInfo := internalTypes.HeartbeatInfo{ClusterMembers: clusterMap}
+	hbInfo := internalTypes.HeartbeatInfo{ClusterMembers: clusterMap, TrustedClientsDigest: intState.TrustedClients.Digest()}
 	for _, node := range clusterMembers {
 		if node.SchemaInternalVersion > hbInfo.MaxSchemaInternal {
 			hbInfo.MaxSchemaInternal = node.SchemaInternalVersion
@@ -186,11 +243,13 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 
 	clusterClients, err := s.Cluster(false)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// Use a lock to handle concurrent access to hbInfo.
 	mapLock := sync.RWMutex{}
+	heartbeatErrors := map[string]error{}
+	clockSkews := map[string]time.Duration{s.Address().URL.Host: 0}
 	// Send heartbeat to non-leader members, updating their local member cache and updating the node.
 	// If we sent a heartbeat to this node within double the request timeout, then we can skip the node this round.
 	err = clusterClients.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
@@ -210,9 +269,15 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 			return nil
 		}
 
-		err := intState.InternalDatabase.SendHeartbeat(ctx, &c.Client, hbInfo)
+		sent := time.Now()
+		resp, err := intState.InternalDatabase.SendHeartbeat(ctx, &c.Client, hbInfo)
 		if err != nil {
 			logger.Error("Received error sending heartbeat to cluster member", logger.Ctx{"target": addr, "error": err})
+
+			mapLock.Lock()
+			heartbeatErrors[addr] = err
+			mapLock.Unlock()
+
 			return nil
 		}
 
@@ -220,23 +285,41 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 
 		mapLock.Lock()
 		hbInfo.ClusterMembers[addr] = currentMember
+		if !resp.Time.IsZero() {
+			clockSkews[addr] = clockSkew(resp.Time, sent, currentMember.LastHeartbeat)
+		}
+
 		mapLock.Unlock()
 
 		return nil
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	// Having sent a heartbeat to each valid cluster member, update the database record of members.
 	roleStatusMap := map[string]types.RoleStatus{}
+	var dbClusterMembers []cluster.CoreClusterMember
 	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
-		dbClusterMembers, err := cluster.GetCoreClusterMembers(ctx, tx)
+		var err error
+		dbClusterMembers, err = cluster.GetCoreClusterMembers(ctx, tx)
 		if err != nil {
 			return err
 		}
 
-		for _, clusterMember := range dbClusterMembers {
+		maintenance, err = cluster.GetCoreClusterMembersUnderMaintenance(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		// Warnings are only written when they are filed or resolved, so that heartbeats do not rewrite them each time.
+		// Failing to keep them up to date does not prevent recording the heartbeat.
+		warnings, err := cluster.GetCoreWarnings(ctx, tx)
+		if err != nil {
+			logger.Warn("Failed to get cluster member warnings", logger.Ctx{"error": err})
+		}
+
+		for i, clusterMember := range dbClusterMembers {
 			heartbeatInfo, ok := hbInfo.ClusterMembers[clusterMember.Address]
 			if !ok {
 				continue
@@ -254,20 +337,151 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 			if err != nil {
 				return err
 			}
+
+			skew, ok := clockSkews[clusterMember.Address]
+			if ok {
+				err = cluster.UpdateCoreClusterMemberClockSkew(ctx, tx, clusterMember.Name, skew)
+				if err != nil {
+					return err
+				}
+
+				if skew.Abs() > intState.MaxClockSkew {
+					logger.Warn("Clock of cluster member is skewed", logger.Ctx{"name": clusterMember.Name, "skew": skew.String(), "max": intState.MaxClockSkew.String()})
+				}
+			}
+
+			if warnings != nil {
+				updateMemberWarnings(ctx, tx, warnings, clusterMember, heartbeatErrors[clusterMember.Address], hbInfo)
+			}
+
+			dbClusterMembers[i] = clusterMember
 		}
 
 		return cluster.DeleteExpiredCoreTokenRecords(ctx, tx)
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	hookCtx, hookCancel := context.WithCancel(ctx)
 	err = intState.Hooks.OnHeartbeat(hookCtx, s, roleStatusMap)
 	hookCancel()
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
+	}
+
+	health := types.ClusterHealth{Leader: leaderEntry.Name, Members: make([]types.ClusterMember, 0, len(hbInfo.ClusterMembers))}
+	offlineThreshold := intState.InternalDatabase.GetOfflineThreshold()
+	now := time.Now()
+	for _, clusterMember := range dbClusterMembers {
+		_, ok := hbInfo.ClusterMembers[clusterMember.Address]
+		if !ok {
+			continue
+		}
+
+		member, err := clusterMember.ToAPI()
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		member.ClockSkew = clockSkews[clusterMember.Address]
+		member.Maintenance = maintenance[member.Name]
+		if member.Maintenance {
+			member.Status = types.MemberMaintenance
+		} else {
+			member.Status = memberStatus(heartbeatErrors[clusterMember.Address], member.LastHeartbeat, now, heartbeatInterval, offlineThreshold)
+		}
+
+		health.Members = append(health.Members, *member)
+	}
+
+	hookCtx, hookCancel = context.WithCancel(ctx)
+	err = intState.Hooks.OnClusterHealth(hookCtx, s, health)
+	hookCancel()
+	if err != nil {
+		return rest.SmartError(err)
 	}
 
 	return response.EmptySyncResponse
 }
+
+// clockSkew returns how far ahead of the local clock the given remote time is. As the remote time was taken at some
+// point during a request sent and answered at the given times, it is compared with the midpoint of the request.
+func clockSkew(remote time.Time, sent time.Time, received time.Time) time.Duration {
+	return remote.Sub(sent.Add(received.Sub(sent) / 2))
+}
+
+// setClockSkew sets the clock skew of the given cluster members, as recorded during the last heartbeats.
+func setClockSkew(ctx context.Context, tx *sql.Tx, members []types.ClusterMember) error {
+	skews, err := cluster.GetCoreClusterMembersClockSkew(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for i := range members {
+		members[i].ClockSkew = skews[members[i].Name]
+	}
+
+	return nil
+}
+
+// setDNSNames sets the DNS names registered by the given cluster members.
+func setDNSNames(ctx context.Context, tx *sql.Tx, members []types.ClusterMember) error {
+	dnsNames, err := cluster.GetCoreClusterMembersDNSNames(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for i := range members {
+		members[i].DNSName = dnsNames[members[i].Name]
+	}
+
+	return nil
+}
+
+// updateMemberWarnings files or resolves the warnings about the cluster member that are detected by the heartbeat:
+// whether it responded to the heartbeat, and whether its schema version is behind the rest of the cluster.
+// Only the warnings that were not already filed or resolved, according to the given warnings, are written.
+func updateMemberWarnings(ctx context.Context, tx *sql.Tx, warnings []cluster.CoreWarning, member cluster.CoreClusterMember, heartbeatErr error, hbInfo internalTypes.HeartbeatInfo) {
+	filed := map[string]bool{}
+	for _, warning := range warnings {
+		if warning.Member == member.Name && warning.Entity == "" {
+			filed[warning.Type] = true
+		}
+	}
+
+	offline := heartbeatErr != nil
+	if offline != filed[types.WarningMemberOffline] {
+		var message string
+		if offline {
+			message = fmt.Sprintf("Cluster member did not respond to heartbeat: %v", heartbeatErr)
+		}
+
+		setMemberWarning(ctx, tx, member.Name, types.WarningMemberOffline, offline, message)
+	}
+
+	behind := member.SchemaInternal < hbInfo.MaxSchemaInternal || member.SchemaExternal < hbInfo.MaxSchemaExternal
+	if behind != filed[types.WarningSchemaDivergence] {
+		var message string
+		if behind {
+			message = fmt.Sprintf("Cluster member schema version (internal %d, external %d) is behind the cluster (internal %d, external %d)", member.SchemaInternal, member.SchemaExternal, hbInfo.MaxSchemaInternal, hbInfo.MaxSchemaExternal)
+		}
+
+		setMemberWarning(ctx, tx, member.Name, types.WarningSchemaDivergence, behind, message)
+	}
+}
+
+// setMemberWarning files the warning of the given type about the cluster member, or resolves it if it is no longer
+// active. Errors are logged rather than returned, so that they do not fail the heartbeat.
+func setMemberWarning(ctx context.Context, tx *sql.Tx, member string, warningType string, active bool, message string) {
+	var err error
+	if active {
+		err = cluster.FileCoreWarning(ctx, tx, member, warningType, "", message, time.Now())
+	} else {
+		err = cluster.ResolveCoreWarning(ctx, tx, member, warningType, "")
+	}
+
+	if err != nil {
+		logger.Warn("Failed to update cluster member warning", logger.Ctx{"name": member, "type": warningType, "active": active, "error": err})
+	}
+}
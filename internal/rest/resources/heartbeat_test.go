@@ -0,0 +1,194 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	clusterRequest "github.com/canonical/lxd/lxd/cluster/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/config"
+	"github.com/canonical/microcluster/v3/internal/db"
+	internalAccess "github.com/canonical/microcluster/v3/internal/rest/access"
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+func TestClockSkew(t *testing.T) {
+	sent := time.Now()
+	received := sent.Add(2 * time.Second)
+
+	// The remote time is compared with the midpoint of the request.
+	assert.Equal(t, time.Duration(0), clockSkew(sent.Add(time.Second), sent, received))
+	assert.Equal(t, 4*time.Second, clockSkew(sent.Add(5*time.Second), sent, received))
+	assert.Equal(t, -6*time.Second, clockSkew(sent.Add(-5*time.Second), sent, received))
+}
+
+// Ensures the DNS names registered by cluster members are attached to their records.
+func TestSetDNSNames(t *testing.T) {
+	ctx := context.Background()
+	tx := newTestTx(t)
+
+	for name, address := range map[string]string{"m1": "10.0.0.1:9000", "m2": "10.0.0.2:9000"} {
+		_, err := cluster.CreateCoreClusterMember(ctx, tx, cluster.CoreClusterMember{Name: name, Address: address, Certificate: name, Role: "voter"})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, cluster.UpdateCoreClusterMemberDNSName(ctx, tx, "m1", "m1.example.com"))
+
+	members := []types.ClusterMember{
+		{ClusterMemberLocal: types.ClusterMemberLocal{Name: "m1"}},
+		{ClusterMemberLocal: types.ClusterMemberLocal{Name: "m2", DNSName: "stale.example.com"}},
+	}
+
+	require.NoError(t, setDNSNames(ctx, tx, members))
+	assert.Equal(t, "m1.example.com", members[0].DNSName)
+	assert.Empty(t, members[1].DNSName)
+}
+
+// Ensures heartbeats file and resolve the warnings about cluster members only when their condition changes, without
+// rewriting warnings that are already filed.
+func TestUpdateMemberWarnings(t *testing.T) {
+	ctx := context.Background()
+	tx := newTestTx(t)
+
+	hbInfo := internalTypes.HeartbeatInfo{MaxSchemaInternal: 2, MaxSchemaExternal: 1}
+	member := cluster.CoreClusterMember{Name: "m1", SchemaInternal: 2, SchemaExternal: 1}
+	behind := cluster.CoreClusterMember{Name: "m1", SchemaInternal: 1, SchemaExternal: 1}
+
+	heartbeat := func(member cluster.CoreClusterMember, heartbeatErr error) []cluster.CoreWarning {
+		warnings, err := cluster.GetCoreWarnings(ctx, tx)
+		require.NoError(t, err)
+
+		updateMemberWarnings(ctx, tx, warnings, member, heartbeatErr, hbInfo)
+
+		warnings, err = cluster.GetCoreWarnings(ctx, tx)
+		require.NoError(t, err)
+
+		return warnings
+	}
+
+	require.Empty(t, heartbeat(member, nil))
+
+	warnings := heartbeat(behind, errors.New("connection refused"))
+	require.Len(t, warnings, 2)
+	require.Equal(t, types.WarningMemberOffline, warnings[0].Type)
+	require.Equal(t, types.WarningSchemaDivergence, warnings[1].Type)
+
+	// Warnings that are already filed are left as they are.
+	require.Equal(t, warnings, heartbeat(behind, errors.New("connection timed out")))
+
+	// Warnings are resolved once their condition is gone.
+	warnings = heartbeat(behind, nil)
+	require.Len(t, warnings, 1)
+	require.Equal(t, types.WarningSchemaDivergence, warnings[0].Type)
+
+	require.Empty(t, heartbeat(member, nil))
+
+	// Warnings about entities of the cluster member are left to whoever filed them.
+	require.NoError(t, cluster.FileCoreWarning(ctx, tx, "m1", types.WarningMemberOffline, "disk", "Disk is offline", time.Now()))
+	warnings = heartbeat(member, nil)
+	require.Len(t, warnings, 1)
+	require.Equal(t, "disk", warnings[0].Entity)
+}
+
+// Ensures the control socket access can only be changed by callers of the control socket, or by cluster members
+// notifying their peers, and is only persisted along with the heartbeat settings once it is applied.
+func TestDaemonConfigPut(t *testing.T) {
+	unix := internalAccess.Identity{Method: internalAccess.AuthMethodUnix, Trusted: true}
+	member := internalAccess.Identity{Method: internalAccess.AuthMethodTLS, Trusted: true, Name: "m2"}
+	client := internalAccess.Identity{Method: internalAccess.AuthMethodTLS, Trusted: true}
+	heartbeat := types.HeartbeatConfig{Interval: 5 * time.Second}
+
+	cases := []struct {
+		name            string
+		identity        internalAccess.Identity
+		notification    bool
+		body            string
+		checkErr        error
+		setErr          error
+		expectCode      int
+		expectApplied   bool
+		expectHeartbeat types.HeartbeatConfig
+		expectSocket    types.SocketConfig
+	}{
+		{name: "Heartbeat only", identity: client, body: `{"heartbeat": {"interval": 10000000000}}`, expectCode: http.StatusOK, expectHeartbeat: types.HeartbeatConfig{Interval: 10 * time.Second}},
+		{name: "Socket over the control socket", identity: unix, body: `{"socket": {"group": "admin", "mode": "0640"}}`, expectCode: http.StatusOK, expectApplied: true, expectHeartbeat: heartbeat, expectSocket: types.SocketConfig{Group: "admin", Mode: "0640"}},
+		{name: "Socket notified by a cluster member", identity: member, notification: true, body: `{"socket": {"mode": "0600"}}`, expectCode: http.StatusOK, expectApplied: true, expectHeartbeat: heartbeat, expectSocket: types.SocketConfig{Mode: "0600"}},
+		{name: "Socket from a trusted client", identity: client, body: `{"socket": {"mode": "0600"}}`, expectCode: http.StatusForbidden, expectHeartbeat: heartbeat},
+		{name: "Socket notified by a trusted client", identity: client, notification: true, body: `{"socket": {"mode": "0600"}}`, expectCode: http.StatusForbidden, expectHeartbeat: heartbeat},
+		{name: "Socket mode granting access to other users", identity: unix, body: `{"socket": {"mode": "0666"}}`, expectCode: http.StatusBadRequest, expectHeartbeat: heartbeat},
+		{name: "Socket mode with other bits", identity: unix, body: `{"socket": {"mode": "01660"}}`, expectCode: http.StatusBadRequest, expectHeartbeat: heartbeat},
+		{name: "Malformed socket mode", identity: unix, body: `{"socket": {"mode": "rw"}}`, expectCode: http.StatusBadRequest, expectHeartbeat: heartbeat},
+		{name: "Socket access that can't be checked", identity: unix, body: `{"socket": {"group": "unknown"}}`, checkErr: errors.New("Unknown group"), expectCode: http.StatusBadRequest, expectHeartbeat: heartbeat},
+		{name: "Socket access that can't be applied", identity: unix, body: `{"socket": {"group": "admin"}}`, setErr: errors.New("Failed to change group"), expectCode: http.StatusInternalServerError, expectHeartbeat: heartbeat},
+		{name: "Negative heartbeat interval", identity: unix, body: `{"heartbeat": {"interval": -1}}`, expectCode: http.StatusBadRequest, expectHeartbeat: heartbeat},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "daemon.yaml")
+			daemonConfig := config.NewDaemonConfig(path)
+			daemonConfig.SetHeartbeat(heartbeat)
+			require.NoError(t, daemonConfig.Write())
+
+			var applied []types.SocketConfig
+			s := &internalState.InternalState{
+				InternalDatabase:  db.NewDB(context.Background(), nil, nil, nil, &sys.OS{}, 0),
+				LocalConfig:       func() *config.DaemonConfig { return daemonConfig },
+				CheckSocketAccess: func(socket types.SocketConfig) error { return c.checkErr },
+				SetSocketAccess: func(socket types.SocketConfig) error {
+					if c.setErr != nil {
+						return c.setErr
+					}
+
+					applied = append(applied, socket)
+					return nil
+				},
+			}
+
+			r := httptest.NewRequest(http.MethodPut, "/core/1.0/daemon/config", strings.NewReader(c.body))
+			if c.notification {
+				r.Header.Set("User-Agent", clusterRequest.UserAgentNotifier)
+			}
+
+			w := httptest.NewRecorder()
+			require.NoError(t, daemonConfigPut(s, internalAccess.SetRequestIdentity(r, c.identity)).Render(w))
+			require.Equal(t, c.expectCode, w.Code, w.Body.String())
+
+			if c.expectApplied {
+				require.Equal(t, []types.SocketConfig{c.expectSocket}, applied)
+			} else {
+				require.Empty(t, applied)
+			}
+
+			// Settings missing from the request are kept, and rejected settings are not persisted.
+			persisted := config.NewDaemonConfig(path)
+			require.NoError(t, persisted.Load())
+			require.Equal(t, c.expectHeartbeat, persisted.GetHeartbeat())
+			require.Equal(t, c.expectSocket, persisted.GetSocket())
+
+			w = httptest.NewRecorder()
+			require.NoError(t, daemonConfigGet(s, httptest.NewRequest(http.MethodGet, "/core/1.0/daemon/config", nil)).Render(w))
+			require.Equal(t, http.StatusOK, w.Code)
+
+			resp := struct {
+				Metadata types.RuntimeConfig `json:"metadata"`
+			}{}
+
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, types.RuntimeConfig{Heartbeat: c.expectHeartbeat, Socket: &c.expectSocket}, resp.Metadata)
+		})
+	}
+}
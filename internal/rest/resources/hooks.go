@@ -27,12 +27,12 @@ var hooksCmd = rest.Endpoint{
 func hooksPost(s state.State, r *http.Request) response.Response {
 	hookTypeStr, err := url.PathUnescape(mux.Vars(r)["hookType"])
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	ctx, cancel := context.WithCancel(r.Context())
@@ -48,7 +48,22 @@ func hooksPost(s state.State, r *http.Request) response.Response {
 
 		err = intState.Hooks.PreRemove(ctx, s, req.Force)
 		if err != nil {
-			return response.SmartError(fmt.Errorf("Failed to execute pre-remove hook on cluster member %q: %w", s.Name(), err))
+			return rest.SmartError(fmt.Errorf("Failed to execute pre-remove hook on cluster member %q: %w", s.Name(), err))
+		}
+	case internalTypes.PreRemovePeer:
+		var req internalTypes.HookRemoveMemberOptions
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if req.Member.Name == "" {
+			return rest.SmartError(fmt.Errorf("No removed member given for PreRemovePeer hook execution"))
+		}
+
+		err = intState.Hooks.PreRemovePeer(ctx, s, req.Member, req.Force)
+		if err != nil {
+			return rest.SmartError(fmt.Errorf("Failed to execute pre-remove-peer hook on cluster member %q: %w", s.Name(), err))
 		}
 	case internalTypes.PostRemove:
 		var req internalTypes.HookRemoveMemberOptions
@@ -59,7 +74,23 @@ func hooksPost(s state.State, r *http.Request) response.Response {
 
 		err = intState.Hooks.PostRemove(ctx, s, req.Force)
 		if err != nil {
-			return response.SmartError(fmt.Errorf("Failed to execute post-remove hook on cluster member %q: %w", s.Name(), err))
+			return rest.SmartError(fmt.Errorf("Failed to execute post-remove hook on cluster member %q: %w", s.Name(), err))
+		}
+
+	case internalTypes.OnMemberRemoved:
+		var req internalTypes.HookRemoveMemberOptions
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if req.Member.Name == "" {
+			return rest.SmartError(fmt.Errorf("No removed member given for OnMemberRemoved hook execution"))
+		}
+
+		err = intState.Hooks.OnMemberRemoved(ctx, s, req.Member, req.Force)
+		if err != nil {
+			return rest.SmartError(fmt.Errorf("Failed to run hook after system %q was removed from the cluster: %w", req.Member.Name, err))
 		}
 
 	case internalTypes.OnNewMember:
@@ -70,12 +101,12 @@ func hooksPost(s state.State, r *http.Request) response.Response {
 		}
 
 		if req.NewMember == (types.ClusterMemberLocal{}) {
-			return response.SmartError(fmt.Errorf("No new member name given for NewMember hook execution"))
+			return rest.SmartError(fmt.Errorf("No new member name given for NewMember hook execution"))
 		}
 
 		err = intState.Hooks.OnNewMember(ctx, s, req.NewMember)
 		if err != nil {
-			return response.SmartError(fmt.Errorf("Failed to run hook after system %q has joined the cluster: %w", req.NewMember.Name, err))
+			return rest.SmartError(fmt.Errorf("Failed to run hook after system %q has joined the cluster: %w", req.NewMember.Name, err))
 		}
 	case internalTypes.OnDaemonConfigUpdate:
 		var req types.DaemonConfig
@@ -86,10 +117,10 @@ func hooksPost(s state.State, r *http.Request) response.Response {
 
 		err = intState.Hooks.OnDaemonConfigUpdate(ctx, s, req)
 		if err != nil {
-			return response.SmartError(fmt.Errorf("Failed to run hook on %q after daemon received local config update: %w", s.Name(), err))
+			return rest.SmartError(fmt.Errorf("Failed to run hook on %q after daemon received local config update: %w", s.Name(), err))
 		}
 	default:
-		return response.SmartError(fmt.Errorf("No valid hook found for the given type"))
+		return rest.SmartError(fmt.Errorf("No valid hook found for the given type"))
 	}
 
 	return response.EmptySyncResponse
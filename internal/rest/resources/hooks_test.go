@@ -47,6 +47,18 @@ func (t *hooksSuite) Test_hooks() {
 				return nil
 			},
 
+			PreRemovePeer: func(ctx context.Context, state state.State, member types.ClusterMemberLocal, force bool) error {
+				ranHook = internalTypes.PreRemovePeer
+				isForce = force
+				return nil
+			},
+
+			OnMemberRemoved: func(ctx context.Context, state state.State, member types.ClusterMemberLocal, force bool) error {
+				ranHook = internalTypes.OnMemberRemoved
+				isForce = force
+				return nil
+			},
+
 			OnNewMember: func(ctx context.Context, state state.State, newMember types.ClusterMemberLocal) error {
 				ranHook = internalTypes.OnNewMember
 				return nil
@@ -90,6 +102,18 @@ func (t *hooksSuite) Test_hooks() {
 			hookType:  internalTypes.PreRemove,
 			expectErr: false,
 		},
+		{
+			name:      "Fail to run PreRemovePeer hook without the removed member",
+			req:       internalTypes.HookRemoveMemberOptions{Force: true},
+			hookType:  internalTypes.PreRemovePeer,
+			expectErr: true,
+		},
+		{
+			name:      "Fail to run OnMemberRemoved hook without the removed member",
+			req:       internalTypes.HookRemoveMemberOptions{Force: true},
+			hookType:  internalTypes.OnMemberRemoved,
+			expectErr: true,
+		},
 		{
 			name:      "Fail to run any other hook",
 			req:       internalTypes.HookNewMemberOptions{NewMember: types.ClusterMemberLocal{Name: "n1"}},
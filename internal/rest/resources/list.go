@@ -0,0 +1,96 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// itemFields returns the JSON representation of an item as a map of its fields.
+func itemFields(item any) (map[string]any, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// filterItems returns the items whose fields match all of the given filters, ignoring case.
+func filterItems[T any](items []T, filters map[string]string) ([]T, error) {
+	if len(filters) == 0 {
+		return items, nil
+	}
+
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		fields, err := itemFields(item)
+		if err != nil {
+			return nil, err
+		}
+
+		match := true
+		for key, value := range filters {
+			if !strings.EqualFold(fmt.Sprint(fields[key]), value) {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered, nil
+}
+
+// listResponse filters, paginates and projects the items according to the list options.
+func listResponse[T any](items []T, opts types.ListOptions) response.Response {
+	items, err := filterItems(items, opts.Filters)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	items = items[min(opts.Offset, len(items)):]
+	if opts.Limit > 0 {
+		items = items[:min(opts.Limit, len(items))]
+	}
+
+	if len(opts.Fields) == 0 {
+		return response.SyncResponse(true, items)
+	}
+
+	projected := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		fields, err := itemFields(item)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		selected := make(map[string]any, len(opts.Fields))
+		for _, field := range opts.Fields {
+			value, ok := fields[field]
+			if !ok {
+				return response.BadRequest(fmt.Errorf("Unknown field %q", field))
+			}
+
+			selected[field] = value
+		}
+
+		projected = append(projected, selected)
+	}
+
+	return response.SyncResponse(true, projected)
+}
@@ -0,0 +1,173 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var clusterMemberMaintenanceCmd = rest.Endpoint{
+	Path: "cluster/{name}/maintenance",
+
+	Put: rest.EndpointAction{Handler: clusterMemberMaintenancePut, AccessHandler: access.AllowAuthenticated},
+}
+
+// clusterMemberMaintenancePut puts a cluster member under maintenance, or takes it out of maintenance.
+// The request is handled by the member itself, which hands its dqlite voting role and leadership over to other
+// members when entering maintenance.
+func clusterMemberMaintenancePut(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	resp := s.ForwardToMember(r, name)
+	if resp != nil {
+		return resp
+	}
+
+	req := types.ClusterMemberMaintenance{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.UpdateCoreClusterMemberMaintenance(ctx, tx, name, req.Enabled)
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	intState.Maintenance.Store(req.Enabled)
+
+	if req.Enabled {
+		logger.Info("Cluster member is entering maintenance, handing over dqlite roles", logger.Ctx{"member": name})
+		err = intState.InternalDatabase.Handover(r.Context())
+		if err != nil {
+			return rest.SmartError(fmt.Errorf("Failed to hand over dqlite roles: %w", err))
+		}
+	} else {
+		logger.Info("Cluster member has left maintenance", logger.Ctx{"member": name})
+	}
+
+	return response.EmptySyncResponse
+}
+
+// ReloadMaintenance updates whether the local cluster member is under maintenance from the database.
+func ReloadMaintenance(ctx context.Context, s state.State) error {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return err
+	}
+
+	var maintenance map[string]bool
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		maintenance, err = cluster.GetCoreClusterMembersUnderMaintenance(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	intState.Maintenance.Store(maintenance[s.Name()])
+
+	return nil
+}
+
+// maintenanceDemotions returns the dqlite members under maintenance that hold a role other than spare. The names of
+// the cluster members are given by dqlite address.
+func maintenanceDemotions(nodes []dqliteClient.NodeInfo, names map[string]string, maintenance map[string]bool) []dqliteClient.NodeInfo {
+	demotions := []dqliteClient.NodeInfo{}
+	for _, node := range nodes {
+		if node.Role != dqliteClient.Spare && maintenance[names[node.Address]] {
+			demotions = append(demotions, node)
+		}
+	}
+
+	return demotions
+}
+
+// demoteMaintenanceMembers keeps the cluster members under maintenance as dqlite spares. Dqlite adjusts roles without
+// knowing about maintenance, so it promotes them again if it runs short of voters or stand-bys after they handed their
+// roles over. The local cluster member, which is the dqlite leader, hands its roles over instead. The dqlite addresses
+// of the demoted members are returned, along with whether the local cluster member handed over its roles.
+func demoteMaintenanceMembers(ctx context.Context, s state.State, names map[string]string, maintenance map[string]bool) ([]string, bool, error) {
+	leaderClient, err := s.Database().Leader(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer func() { _ = leaderClient.Close() }()
+
+	nodes, err := s.Database().Cluster(ctx, leaderClient)
+	if err != nil {
+		return nil, false, err
+	}
+
+	demoted := []string{}
+	for _, node := range maintenanceDemotions(nodes, names, maintenance) {
+		if node.Address == s.Address().URL.Host {
+			intState, err := internalState.ToInternal(s)
+			if err != nil {
+				return nil, false, err
+			}
+
+			logger.Info("Cluster member under maintenance is the dqlite leader, handing over dqlite roles", logger.Ctx{"member": s.Name()})
+			err = intState.InternalDatabase.Handover(ctx)
+			if err != nil {
+				return nil, false, fmt.Errorf("Failed to hand over dqlite roles: %w", err)
+			}
+
+			return demoted, true, nil
+		}
+
+		logger.Info("Demoting cluster member under maintenance", logger.Ctx{"member": names[node.Address], "role": node.Role.String()})
+		err = leaderClient.Assign(ctx, node.ID, dqliteClient.Spare)
+		if err != nil {
+			return nil, false, fmt.Errorf("Failed to demote cluster member %q under maintenance: %w", names[node.Address], err)
+		}
+
+		demoted = append(demoted, node.Address)
+	}
+
+	return demoted, false, nil
+}
+
+// setMaintenanceStatus marks the given cluster members that are under maintenance.
+func setMaintenanceStatus(ctx context.Context, tx *sql.Tx, members []types.ClusterMember) error {
+	maintenance, err := cluster.GetCoreClusterMembersUnderMaintenance(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for i := range members {
+		if maintenance[members[i].Name] {
+			members[i].Maintenance = true
+			members[i].Status = types.MemberMaintenance
+		}
+	}
+
+	return nil
+}
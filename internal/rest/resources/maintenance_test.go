@@ -0,0 +1,33 @@
+package resources
+
+import (
+	"testing"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures the cluster members under maintenance are demoted whenever dqlite promoted them again.
+func TestMaintenanceDemotions(t *testing.T) {
+	nodes := []dqliteClient.NodeInfo{
+		{ID: 1, Address: "10.0.0.1:9000", Role: dqliteClient.Voter},
+		{ID: 2, Address: "10.0.0.2:9000", Role: dqliteClient.Voter},
+		{ID: 3, Address: "10.0.0.3:9000", Role: dqliteClient.StandBy},
+		{ID: 4, Address: "10.0.0.4:9000", Role: dqliteClient.Spare},
+		{ID: 5, Address: "10.0.0.5:9000", Role: dqliteClient.Voter},
+	}
+
+	names := map[string]string{
+		"10.0.0.1:9000": "m1",
+		"10.0.0.2:9000": "m2",
+		"10.0.0.3:9000": "m3",
+		"10.0.0.4:9000": "m4",
+	}
+
+	// Members without maintenance, spares, and dqlite members without a cluster member record are left alone.
+	maintenance := map[string]bool{"m2": true, "m3": true, "m4": true}
+	demotions := maintenanceDemotions(nodes, names, maintenance)
+	require.Equal(t, []dqliteClient.NodeInfo{nodes[1], nodes[2]}, demotions)
+
+	require.Empty(t, maintenanceDemotions(nodes, names, map[string]bool{}))
+}
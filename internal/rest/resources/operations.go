@@ -0,0 +1,115 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+// The operations are available before the daemon is initialized, so that the initialization can be followed.
+var operationsCmd = rest.Endpoint{
+	Path:              "operations",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: operationsGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+var operationCmd = rest.Endpoint{
+	Path:              "operations/{id}",
+	AllowedBeforeInit: true,
+
+	Get:    rest.EndpointAction{Handler: operationGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+	Delete: rest.EndpointAction{Handler: operationDelete, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+var operationWebsocketCmd = rest.Endpoint{
+	Path:              "operations/{id}/websocket",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: operationWebsocketGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+// operationsGet returns the running and recently finished operations of the cluster member.
+func operationsGet(s state.State, r *http.Request) response.Response {
+	return response.SyncResponse(true, s.Operations().List())
+}
+
+// operationGet returns the status of an operation.
+func operationGet(s state.State, r *http.Request) response.Response {
+	id, err := url.PathUnescape(mux.Vars(r)["id"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	op, err := s.Operations().Get(id)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, op)
+}
+
+// operationDelete cancels a running operation.
+func operationDelete(s state.State, r *http.Request) response.Response {
+	id, err := url.PathUnescape(mux.Vars(r)["id"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = s.Operations().Cancel(id)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// operationWebsocketGet sends the status of an operation over a websocket every time it is updated, and closes the
+// websocket once the operation finishes.
+func operationWebsocketGet(s state.State, r *http.Request) response.Response {
+	id, err := url.PathUnescape(mux.Vars(r)["id"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	// Check that the operation exists before upgrading the connection, so that the caller gets a proper error.
+	_, err = s.Operations().Get(id)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return rest.WebsocketResponse(r, func(conn *websocket.Conn) error {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		// Stop watching if the caller goes away. Messages sent by the caller are ignored.
+		go func() {
+			defer cancel()
+			for {
+				_, _, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		err := s.Operations().Watch(ctx, id, func(status types.Operation) error {
+			return conn.WriteJSON(status)
+		})
+		if err != nil {
+			return err
+		}
+
+		return conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(5*time.Second))
+	})
+}
@@ -0,0 +1,215 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/internal/utils"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var controlPreflightCmd = rest.Endpoint{
+	Path:              "preflight",
+	AllowedBeforeInit: true,
+
+	Post: rest.EndpointAction{Handler: controlPreflightPost, AccessHandler: access.AllowAuthenticated},
+}
+
+var preflightCmd = rest.Endpoint{
+	Path: "preflight",
+
+	Post: rest.EndpointAction{Handler: preflightPost, AllowUntrusted: true},
+}
+
+// controlPreflightPost checks whether the local system can join the cluster with the given name, address and join
+// token, without joining it. The cluster members in the token are tried in turn until one of them runs the checks.
+func controlPreflightPost(s state.State, r *http.Request) response.Response {
+	status := s.Database().Status()
+	if status != types.DatabaseNotReady {
+		return rest.SmartError(fmt.Errorf("Unable to join cluster: %w: %s", types.ErrAlreadyBootstrapped, status))
+	}
+
+	req := &internalTypes.Control{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.JoinToken == "" {
+		return response.BadRequest(fmt.Errorf("No join token given"))
+	}
+
+	if !req.Address.IsValid() {
+		return response.BadRequest(fmt.Errorf("Address %q is not a valid address and port", req.Address))
+	}
+
+	token, err := internalTypes.DecodeToken(req.JoinToken)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid join token: %w", err))
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = token.Check(req.Address, intState.Project, time.Now())
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	joinRequest, err := newJoinRequest(intState, req, token)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	lastErr := fmt.Errorf("Join token has no cluster member addresses")
+	for _, addr := range token.JoinAddresses {
+		url := api.NewURL().Scheme("https").Host(addr.String())
+		cert, err := shared.GetRemoteCertificate(url.String(), "")
+		if err != nil {
+			lastErr = fmt.Errorf("Cluster member %q is unreachable: %w", addr.String(), err)
+			continue
+		}
+
+		matches, err := token.MatchesCertificate(cert)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if !matches {
+			lastErr = fmt.Errorf("Cluster member %q does not serve the cluster certificate of the join token", addr.String())
+			continue
+		}
+
+		c, err := internalClient.New(*url, s.ServerCert(), cert, false)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		sent := time.Now()
+		resp, err := internalClient.PreflightClusterMember(r.Context(), c, joinRequest)
+		if api.StatusErrorCheck(err, http.StatusForbidden) {
+			return response.SyncResponse(true, types.JoinPreflight{Address: addr, Checks: []types.JoinPreflightCheck{{Name: "token", Error: err.Error()}}})
+		}
+
+		if err != nil {
+			lastErr = fmt.Errorf("Cluster member %q failed to run the join checks: %w", addr.String(), err)
+			continue
+		}
+
+		skew := clockSkew(resp.Time, sent, time.Now()).Abs()
+		clockCheck := types.JoinPreflightCheck{Name: "clock"}
+		if skew > intState.MaxClockSkew {
+			clockCheck.Error = fmt.Sprintf("Local clock differs from the cluster's by %s, synchronize it before joining", skew.Round(time.Second))
+		}
+
+		return response.SyncResponse(true, types.JoinPreflight{Address: addr, Checks: append(resp.Checks, clockCheck)})
+	}
+
+	return rest.SmartError(fmt.Errorf("No cluster member in the join token could run the join checks: %w", lastErr))
+}
+
+// preflightPost runs the checks of a join request without recording the prospective member, and without running the
+// OnJoinRequest hook.
+func preflightPost(s state.State, r *http.Request) response.Response {
+	err := s.Database().IsOpen(r.Context())
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	req := types.ClusterMember{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return rest.SmartError(fmt.Errorf("Failed to parse address of joining system %q: %w", r.RemoteAddr, err))
+	}
+
+	// The checks reveal details of the cluster, so they are only run for holders of a valid join token. The server
+	// certificate of the joiner is regenerated for its new name when it joins, so check the name itself.
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		_, err := validateJoinToken(ctx, tx, req, []string{req.Name}, addrPort.Addr().Unmap())
+
+		return err
+	})
+	if err != nil {
+		logger.Warn("Rejected join checks with an invalid join token", logger.Ctx{"address": r.RemoteAddr, "error": err})
+
+		return response.Forbidden(fmt.Errorf("Invalid join token"))
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	resp := internalTypes.JoinPreflightResponse{
+		Checks: append([]types.JoinPreflightCheck{{Name: "token"}}, joinPreflightChecks(intState, req)...),
+		Time:   time.Now(),
+	}
+
+	return response.SyncResponse(true, resp)
+}
+
+// joinPreflightChecks runs the checks that the join request would go through once its join token is validated, and
+// returns their outcome.
+func joinPreflightChecks(s *internalState.InternalState, req types.ClusterMember) []types.JoinPreflightCheck {
+	checks := []types.JoinPreflightCheck{}
+	check := func(name string, err error) {
+		result := types.JoinPreflightCheck{Name: name}
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		checks = append(checks, result)
+	}
+
+	_, nameExists := s.Remotes().RemotesByName()[req.Name]
+	nameErr := utils.ValidateFQDN(req.Name)
+	if nameErr != nil {
+		nameErr = fmt.Errorf("Cluster member name %q is not a valid FQDN: %w", req.Name, nameErr)
+	} else if nameExists {
+		nameErr = fmt.Errorf("A cluster member named %q already exists", req.Name)
+	}
+
+	check("name", nameErr)
+
+	var addressErr error
+	if s.Remotes().RemoteByAddress(req.Address) != nil {
+		addressErr = fmt.Errorf("A cluster member with address %q already exists", req.Address.String())
+	}
+
+	check("address", addressErr)
+
+	check("extensions", s.Extensions.IsSameVersion(req.Extensions))
+
+	var schemaErr error
+	internalVersion, externalVersion, _ := s.Database().SchemaVersion()
+	if req.SchemaInternalVersion != internalVersion || req.SchemaExternalVersion != externalVersion {
+		schemaErr = fmt.Errorf("Schema version (internal %d, external %d) differs from the cluster's (internal %d, external %d), run the same version as the cluster before joining", req.SchemaInternalVersion, req.SchemaExternalVersion, internalVersion, externalVersion)
+	}
+
+	check("schema", schemaErr)
+
+	return checks
+}
@@ -0,0 +1,158 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/gorilla/mux"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var preparedQueriesCmd = rest.Endpoint{
+	Path: "queries",
+
+	Get: rest.EndpointAction{Handler: preparedQueriesGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var preparedQueryCmd = rest.Endpoint{
+	Path: "queries/{name}",
+
+	Post: rest.EndpointAction{Handler: preparedQueryPost, AccessHandler: preparedQueryAccess},
+}
+
+// preparedQueriesGet returns the database queries registered by the consumer.
+// The supported filter is name.
+func preparedQueriesGet(s state.State, r *http.Request) response.Response {
+	opts, err := types.ParseListOptions(r.URL.Query(), "name")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	queries := make([]types.PreparedQuery, 0, len(intState.PreparedQueries))
+	for _, query := range intState.PreparedQueries {
+		params := query.Parameters
+		if params == nil {
+			params = []string{}
+		}
+
+		queries = append(queries, types.PreparedQuery{Name: query.Name, Description: query.Description, Parameters: params})
+	}
+
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+
+	return listResponse(queries, opts)
+}
+
+// preparedQueryAccess runs the access handler of the prepared query named in the request, once the request is
+// authenticated.
+func preparedQueryAccess(s state.State, r *http.Request) (bool, response.Response) {
+	query, err := preparedQuery(s, r)
+	if err != nil {
+		return false, rest.SmartError(err)
+	}
+
+	if query.AccessHandler == nil {
+		return access.AllowAuthenticated(s, r)
+	}
+
+	return query.AccessHandler(s, r)
+}
+
+// preparedQueryPost runs the prepared query named in the request with the given arguments, in a single transaction.
+func preparedQueryPost(s state.State, r *http.Request) response.Response {
+	query, err := preparedQuery(s, r)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	req := types.PreparedQueryPost{}
+
+	// Parse the request, keeping numbers intact so that integers are not bound as floats.
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	err = decoder.Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	for name := range req.Args {
+		if !shared.ValueInSlice(name, query.Parameters) {
+			return response.BadRequest(fmt.Errorf("Prepared query %q has no parameter %q", query.Name, name))
+		}
+	}
+
+	orderedArgs := make([]any, 0, len(query.Parameters))
+	for _, param := range query.Parameters {
+		arg, ok := req.Args[param]
+		if !ok {
+			return response.BadRequest(fmt.Errorf("Missing argument for parameter %q of prepared query %q", param, query.Name))
+		}
+
+		orderedArgs = append(orderedArgs, arg)
+	}
+
+	args, err := sqlArgs(orderedArgs)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	result := internalTypes.SQLResult{}
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		result = internalTypes.SQLResult{}
+		statement := strings.TrimSpace(query.Statement)
+		if strings.HasPrefix(strings.ToUpper(statement), "SELECT") {
+			return sqlSelect(ctx, tx, statement, &result, args...)
+		}
+
+		return sqlExec(ctx, tx, statement, &result, args...)
+	})
+	if err != nil {
+		return rest.SmartError(fmt.Errorf("Failed to run prepared query %q: %w", query.Name, err))
+	}
+
+	return response.SyncResponse(true, types.PreparedQueryResult{Columns: result.Columns, Rows: result.Rows, RowsAffected: result.RowsAffected})
+}
+
+// preparedQuery returns the prepared query named in the request.
+func preparedQuery(s state.State, r *http.Request) (*internalState.PreparedQuery, error) {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return nil, err
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	query, ok := intState.PreparedQueries[name]
+	if !ok {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Prepared query %q not found", name)
+	}
+
+	return &query, nil
+}
@@ -1,39 +1,157 @@
 package resources
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	dqliteClient "github.com/canonical/go-dqlite/client"
 	"github.com/canonical/lxd/lxd/response"
 
+	"github.com/canonical/microcluster/v3/internal/db"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/rest"
 	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
 	"github.com/canonical/microcluster/v3/state"
 )
 
+// healthCheckTimeout is how long the health check waits for the dqlite leader to respond.
+const healthCheckTimeout = 5 * time.Second
+
+// readyCmd and healthCmd are served to untrusted clients, such as load balancer probes. Their access can be
+// restricted with an access override.
 var readyCmd = rest.Endpoint{
 	AllowedBeforeInit: true,
 	Path:              "ready",
 
-	Get: rest.EndpointAction{Handler: getWaitReady, AccessHandler: access.AllowAuthenticated},
+	Get: rest.EndpointAction{Handler: getWaitReady, AllowUntrusted: true},
+}
+
+var healthCmd = rest.Endpoint{
+	AllowedBeforeInit: true,
+	Path:              "healthz",
+
+	Get: rest.EndpointAction{Handler: healthGet, AllowUntrusted: true},
+}
+
+// controlReadyCmd is served over the control socket, so that local clients can wait for the daemon before
+// bootstrapping or joining a cluster.
+var controlReadyCmd = rest.Endpoint{
+	AllowedBeforeInit: true,
+	Path:              "ready",
+
+	Get: rest.EndpointAction{Handler: controlReadyGet, AccessHandler: access.AllowAuthenticated},
+}
+
+// controlReadyGet succeeds once the daemon is done setting up, whether or not it is part of a cluster.
+func controlReadyGet(s state.State, r *http.Request) response.Response {
+	err := daemonReady(s)
+	if err != nil {
+		return response.Unavailable(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// getWaitReady succeeds only if the daemon is ready, its database is open, and the dqlite leader can be reached, so
+// that load balancers only send requests to cluster members that can serve them. Otherwise it returns 503.
+func getWaitReady(s state.State, r *http.Request) response.Response {
+	err := daemonReady(s)
+	if err != nil {
+		return response.Unavailable(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	leaderClient, _, err := reachLeader(ctx, s.Database())
+	if err != nil {
+		return response.Unavailable(err)
+	}
+
+	_ = leaderClient.Close()
+
+	return response.EmptySyncResponse
 }
 
-func getWaitReady(state state.State, r *http.Request) response.Response {
-	intState, err := internalState.ToInternal(state)
+// daemonReady returns an error if the daemon is shutting down, or is not done setting up.
+func daemonReady(s state.State) error {
+	intState, err := internalState.ToInternal(s)
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
 	if intState.Context.Err() != nil {
-		return response.Unavailable(fmt.Errorf("Daemon is shutting down"))
+		return fmt.Errorf("Daemon is shutting down")
 	}
 
 	select {
 	case <-intState.ReadyCh:
 	default:
-		return response.Unavailable(fmt.Errorf("Daemon is not ready yet"))
+		return fmt.Errorf("Daemon is not ready yet")
 	}
 
-	return response.EmptySyncResponse
+	return nil
+}
+
+// reachLeader returns a client connected to the dqlite leader along with its information, or an error if the database
+// is not open or no leader can be reached. The caller must close the client.
+func reachLeader(ctx context.Context, database db.DB) (*dqliteClient.Client, *dqliteClient.NodeInfo, error) {
+	err := database.IsOpen(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Database is not open: %w", err)
+	}
+
+	leaderClient, err := database.Leader(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to reach the dqlite leader: %w", err)
+	}
+
+	leaderInfo, err := leaderClient.Leader(ctx)
+	if err != nil {
+		_ = leaderClient.Close()
+		return nil, nil, fmt.Errorf("Failed to reach the dqlite leader: %w", err)
+	}
+
+	if leaderInfo == nil {
+		_ = leaderClient.Close()
+		return nil, nil, fmt.Errorf("No dqlite leader is elected")
+	}
+
+	return leaderClient, leaderInfo, nil
+}
+
+// healthGet succeeds only if the daemon is ready, its database is open, and the dqlite leader can be reached, so that
+// the cluster member can serve requests that need quorum. Otherwise it returns 503 with the failed check.
+func healthGet(s state.State, r *http.Request) response.Response {
+	err := daemonReady(s)
+	if err != nil {
+		return response.Unavailable(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	leaderClient, leaderInfo, err := reachLeader(ctx, s.Database())
+	if err != nil {
+		return response.Unavailable(err)
+	}
+
+	defer func() { _ = leaderClient.Close() }()
+
+	members, err := s.Database().Cluster(ctx, leaderClient)
+	if err != nil {
+		return response.Unavailable(err)
+	}
+
+	voters := 0
+	for _, member := range members {
+		if member.Role == dqliteClient.Voter {
+			voters++
+		}
+	}
+
+	return response.SyncResponse(true, types.Health{Database: s.Database().Status(), Leader: leaderInfo.Address, Voters: voters})
 }
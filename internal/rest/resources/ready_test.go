@@ -0,0 +1,89 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/internal/db"
+	"github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// leaderlessDB is a database whose dqlite leader cannot be reached.
+type leaderlessDB struct {
+	db.DB
+
+	open bool
+}
+
+// IsOpen returns an error unless the database is open.
+func (d *leaderlessDB) IsOpen(ctx context.Context) error {
+	if !d.open {
+		return types.ErrNotBootstrapped
+	}
+
+	return nil
+}
+
+// Leader returns an error, as there is no dqlite cluster.
+func (d *leaderlessDB) Leader(ctx context.Context) (*dqliteClient.Client, error) {
+	return nil, fmt.Errorf("No available dqlite leader")
+}
+
+// Ensures the daemon reports itself ready over the control socket once it is set up, but only reports itself ready to
+// load balancers once it is part of a cluster that has quorum.
+func TestReady(t *testing.T) {
+	readyCh := make(chan struct{})
+	close(readyCh)
+
+	shutdownCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		name         string
+		ctx          context.Context
+		readyCh      chan struct{}
+		daemonStatus int
+		readyStatus  int
+	}{
+		{name: "Uninitialized cluster member", ctx: context.Background(), readyCh: readyCh, daemonStatus: http.StatusOK, readyStatus: http.StatusServiceUnavailable},
+		{name: "Daemon setting up", ctx: context.Background(), readyCh: make(chan struct{}), daemonStatus: http.StatusServiceUnavailable, readyStatus: http.StatusServiceUnavailable},
+		{name: "Daemon shutting down", ctx: shutdownCtx, readyCh: readyCh, daemonStatus: http.StatusServiceUnavailable, readyStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &state.InternalState{Context: c.ctx, ReadyCh: c.readyCh}
+
+			for handler, status := range map[string]int{"control": c.daemonStatus, "ready": c.readyStatus, "health": c.readyStatus} {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				w := httptest.NewRecorder()
+				switch handler {
+				case "control":
+					require.NoError(t, controlReadyGet(s, r).Render(w))
+				case "ready":
+					require.NoError(t, getWaitReady(s, r).Render(w))
+				case "health":
+					require.NoError(t, healthGet(s, r).Render(w))
+				}
+
+				require.Equal(t, status, w.Code, handler)
+			}
+		})
+	}
+}
+
+// Ensures quorum is only considered reachable with an open database and a reachable dqlite leader.
+func TestReachLeader(t *testing.T) {
+	_, _, err := reachLeader(context.Background(), &leaderlessDB{open: false})
+	require.ErrorIs(t, err, types.ErrNotBootstrapped)
+
+	_, _, err = reachLeader(context.Background(), &leaderlessDB{open: true})
+	require.ErrorContains(t, err, "Failed to reach the dqlite leader")
+}
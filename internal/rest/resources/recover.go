@@ -0,0 +1,34 @@
+package resources
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/internal/recover"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var recoveryTarballCmd = rest.Endpoint{
+	Path:              "recovery/tarball",
+	AllowedBeforeInit: true,
+	MaxRequestBytes:   recover.MaxRecoveryTarballSize,
+
+	Post: rest.EndpointAction{Handler: recoveryTarballPost, AccessHandler: access.AllowAuthenticated},
+}
+
+// recoveryTarballPost stores the recovery tarball sent by the cluster member that recovered from quorum loss.
+// The database is only replaced with the tarball's contents when the daemon next starts.
+func recoveryTarballPost(s state.State, r *http.Request) response.Response {
+	err := recover.WriteRecoveryTarball(s.FileSystem(), r.Body)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	logger.Warn("Received recovery tarball, the database will be recovered when the daemon is restarted", logger.Ctx{"from": r.RemoteAddr})
+
+	return response.EmptySyncResponse
+}
@@ -0,0 +1,43 @@
+package resources
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/internal/recover"
+	"github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/internal/sys"
+)
+
+// Ensures recovery tarballs are bounded in size, and only stored once they are received entirely.
+func TestRecoveryTarballPost(t *testing.T) {
+	require.Equal(t, int64(recover.MaxRecoveryTarballSize), recoveryTarballCmd.MaxRequestBytes)
+
+	filesystem, err := sys.DefaultOS(t.TempDir(), true)
+	require.NoError(t, err)
+
+	s := &state.InternalState{InternalFileSystem: func() *sys.OS { return filesystem }}
+
+	post := func(tarball []byte, limit int64) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/core/1.0/recovery/tarball", bytes.NewReader(tarball))
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		require.NoError(t, recoveryTarballPost(s, r).Render(w))
+
+		return w.Code
+	}
+
+	// Tarballs over the limit are discarded.
+	require.NotEqual(t, http.StatusOK, post([]byte("tarball"), 4))
+	require.NoFileExists(t, filesystem.RecoveryTarballPath())
+
+	require.Equal(t, http.StatusOK, post([]byte("tarball"), 7))
+	tarball, err := os.ReadFile(filesystem.RecoveryTarballPath())
+	require.NoError(t, err)
+	require.Equal(t, "tarball", string(tarball))
+}
@@ -2,13 +2,18 @@ package resources
 
 import (
 	"fmt"
+	"net/http"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/canonical/lxd/lxd/response"
+
 	"github.com/canonical/microcluster/v3/internal/endpoints"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	"github.com/canonical/microcluster/v3/rest"
 	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
 )
 
 // UnixEndpoints are the endpoints available over the unix socket.
@@ -16,6 +21,9 @@ var UnixEndpoints = rest.Resources{
 	PathPrefix: internalTypes.ControlEndpoint,
 	Endpoints: []rest.Endpoint{
 		controlCmd,
+		controlPreflightCmd,
+		controlProgressCmd,
+		controlReadyCmd,
 		shutdownCmd,
 		tokensCmd,
 	},
@@ -27,11 +35,55 @@ var PublicEndpoints = rest.Resources{
 	Endpoints: []rest.Endpoint{
 		api10Cmd,
 		clusterCertificatesCmd,
+		clusterCertificatesRotationCmd,
 		clusterCmd,
 		clusterMemberCmd,
+		clusterMemberMaintenanceCmd,
+		databaseMembersCmd,
+		databaseBackupsCmd,
+		databaseBackupCmd,
+		daemonInfoCmd,
 		daemonCmd,
+		extensionsCmd,
+		daemonConfigCmd,
+		daemonLogCmd,
+		shutdownCmd,
+		tasksCmd,
+		operationsCmd,
+		operationCmd,
+		operationWebsocketCmd,
+		auditCmd,
+		trustedCertificatesCmd,
+		trustedCertificateCmd,
+		certificateRevocationsCmd,
+		certificateRevocationCmd,
+		warningsCmd,
+		warningCmd,
+		preparedQueriesCmd,
+		preparedQueryCmd,
+		sessionsCmd,
+		upgradeCmd,
 		tokenCmd,
 		readyCmd,
+		healthCmd,
+	},
+}
+
+// PublicEndpointsV2 are the /core/2.0 API endpoints, served alongside the /core/1.0 API endpoints so that clients can
+// move to the new version at their own pace. Endpoints with breaking changes are replaced here by their new version.
+var PublicEndpointsV2 = rest.Resources{
+	PathPrefix: internalTypes.PublicEndpointV2,
+	Endpoints:  PublicEndpoints.Endpoints,
+}
+
+// PublicEndpointVersions are every version of the public core API served by the daemon, from the oldest.
+var PublicEndpointVersions = []rest.Resources{PublicEndpoints, PublicEndpointsV2}
+
+// CoreEndpoints are the /core API endpoints, listing the versions of the public core API.
+var CoreEndpoints = rest.Resources{
+	PathPrefix: internalTypes.CoreEndpoint,
+	Endpoints: []rest.Endpoint{
+		coreVersionsCmd,
 	},
 }
 
@@ -40,13 +92,25 @@ var InternalEndpoints = rest.Resources{
 	PathPrefix: internalTypes.InternalEndpoint,
 	Endpoints: []rest.Endpoint{
 		clusterInternalCmd,
+		preflightCmd,
 		clusterMemberInternalCmd,
+		clusterMemberCertificateCmd,
 		databaseCmd,
+		databaseRollbackCmd,
+		databaseRaftCmd,
+		databaseRaftFilesCmd,
+		databaseSchemaCmd,
+		databaseBackfillsCmd,
+		databaseMaintenanceCmd,
+		databaseStatsCmd,
 		sqlCmd,
+		sqlTransactionCmd,
 		heartbeatCmd,
 		trustCmd,
 		trustEntryCmd,
 		hooksCmd,
+		recoveryTarballCmd,
+		consistencyCmd,
 	},
 }
 
@@ -61,7 +125,7 @@ var InternalEndpoints = rest.Resources{
 //   - It must not have a defined address or certificate.
 func ValidateEndpoints(extensionServers map[string]rest.Server, coreAddress string) error {
 	serverAddresses := map[string]bool{coreAddress: true}
-	baseCoreEndpoints := []rest.Resources{UnixEndpoints, PublicEndpoints, InternalEndpoints}
+	baseCoreEndpoints := append([]rest.Resources{UnixEndpoints, InternalEndpoints, CoreEndpoints}, PublicEndpointVersions...)
 	existingEndpointPaths := map[string]map[string]bool{endpoints.EndpointsCore: {}}
 
 	// Record the paths for all internal endpoints on the core listener.
@@ -90,6 +154,24 @@ func ValidateEndpoints(extensionServers map[string]rest.Server, coreAddress stri
 			return fmt.Errorf("Core API server cannot have a pre-defined address")
 		}
 
+		if server.ACME != nil {
+			if server.CoreAPI {
+				return fmt.Errorf("Core API server cannot have an ACME managed certificate")
+			}
+
+			if server.ACME.DNSName == "" {
+				return fmt.Errorf("Server %q ACME configuration must have a DNS name", serverName)
+			}
+		}
+
+		if server.HTTP2 && server.Address == (types.AddrPort{}) {
+			return fmt.Errorf("Server %q must have a defined address to serve HTTP/2", serverName)
+		}
+
+		if server.GRPCServer != nil && !server.HTTP2 {
+			return fmt.Errorf("Server %q must serve HTTP/2 to serve gRPC", serverName)
+		}
+
 		// Ensure all servers with a defined address are unique.
 		if server.Address != (types.AddrPort{}) {
 			if serverAddresses[server.Address.String()] {
@@ -138,6 +220,81 @@ func ValidateEndpoints(extensionServers map[string]rest.Server, coreAddress stri
 	return nil
 }
 
+// ValidateAccessOverrides checks that every access override applies to an endpoint of the core API, identified by its
+// full path such as "core/control/tokens".
+func ValidateAccessOverrides(overrides map[string]rest.AccessOverride) error {
+	paths := map[string]bool{}
+	for _, resources := range append([]rest.Resources{UnixEndpoints, InternalEndpoints, CoreEndpoints}, PublicEndpointVersions...) {
+		for _, e := range resources.Endpoints {
+			paths[filepath.Join(string(resources.PathPrefix), e.Path)] = true
+		}
+	}
+
+	for path, override := range overrides {
+		if !paths[path] {
+			return fmt.Errorf("Access override %q does not match any core API endpoint", path)
+		}
+
+		if override == nil {
+			return fmt.Errorf("Access override %q cannot be empty", path)
+		}
+	}
+
+	return nil
+}
+
+// OverrideAccess returns a copy of the resources whose endpoints have the access handlers of their actions replaced by
+// the matching access overrides, keyed by the full path of the endpoint. Overrides of /core/1.0 endpoints also apply
+// to the same endpoints of later versions of the public core API, unless they have an override of their own.
+func OverrideAccess(resources rest.Resources, overrides map[string]rest.AccessOverride) rest.Resources {
+	if len(overrides) == 0 {
+		return resources
+	}
+
+	isPublic := slices.Contains(internalTypes.PublicEndpointVersions, resources.PathPrefix)
+	overridden := make([]rest.Endpoint, 0, len(resources.Endpoints))
+	for _, e := range resources.Endpoints {
+		override, ok := overrides[filepath.Join(string(resources.PathPrefix), e.Path)]
+		if !ok && isPublic {
+			override, ok = overrides[filepath.Join(string(internalTypes.PublicEndpoint), e.Path)]
+		}
+
+		if ok {
+			for _, action := range []*rest.EndpointAction{&e.Get, &e.Put, &e.Post, &e.Delete, &e.Patch} {
+				if action.Handler != nil {
+					action.AccessHandler = override(action.AccessHandler)
+				}
+			}
+		}
+
+		overridden = append(overridden, e)
+	}
+
+	return rest.Resources{PathPrefix: resources.PathPrefix, Endpoints: overridden}
+}
+
+// ReadOnly returns a copy of the resources whose endpoints only serve GET requests. Requests with any other method
+// are refused, so that the resources can be exposed to clients that must not modify the cluster.
+func ReadOnly(resources rest.Resources) rest.Resources {
+	readOnly := make([]rest.Endpoint, 0, len(resources.Endpoints))
+	for _, e := range resources.Endpoints {
+		for _, action := range []*rest.EndpointAction{&e.Put, &e.Post, &e.Delete, &e.Patch} {
+			if action.Handler != nil {
+				*action = rest.EndpointAction{Handler: readOnlyHandler}
+			}
+		}
+
+		readOnly = append(readOnly, e)
+	}
+
+	return rest.Resources{PathPrefix: resources.PathPrefix, Endpoints: readOnly}
+}
+
+// readOnlyHandler refuses requests that would modify the cluster.
+func readOnlyHandler(s state.State, r *http.Request) response.Response {
+	return response.ErrorResponse(http.StatusMethodNotAllowed, fmt.Sprintf("%s requests are not allowed over the read-only API", r.Method))
+}
+
 // "core/"   -> "core"
 // "/core"   -> "core"
 // "/core/x" -> "core"
@@ -1,9 +1,16 @@
 package resources
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
 	"testing"
 
+	"github.com/canonical/lxd/lxd/response"
+
 	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
 )
 
 var validServers = map[string]rest.Server{
@@ -95,6 +102,33 @@ var invalidServers = map[string]rest.Server{
 			},
 		},
 	},
+	"http2WithoutAddress": {
+		HTTP2: true,
+		Resources: []rest.Resources{
+			{
+				PathPrefix: "h2",
+				Endpoints: []rest.Endpoint{
+					{
+						Path: "hello",
+					},
+				},
+			},
+		},
+	},
+	"grpcWithoutHTTP2": {
+		ServerConfig: types.ServerConfig{Address: types.AddrPort{AddrPort: netip.MustParseAddrPort("127.0.0.1:9000")}},
+		GRPCServer:   http.NotFoundHandler(),
+		Resources: []rest.Resources{
+			{
+				PathPrefix: "grpc",
+				Endpoints: []rest.Endpoint{
+					{
+						Path: "hello",
+					},
+				},
+			},
+		},
+	},
 }
 
 func TestValidateEndpointsInvalidServers(t *testing.T) {
@@ -108,3 +142,83 @@ func TestValidateEndpointsInvalidServers(t *testing.T) {
 		}
 	}
 }
+
+func TestOverrideAccess(t *testing.T) {
+	calls := 0
+	count := func(next func(s state.State, r *http.Request) (bool, response.Response)) func(s state.State, r *http.Request) (bool, response.Response) {
+		return func(s state.State, r *http.Request) (bool, response.Response) {
+			calls++
+			return next(s, r)
+		}
+	}
+
+	overrides := map[string]rest.AccessOverride{"core/1.0/tokens/{name}": count}
+	err := ValidateAccessOverrides(overrides)
+	if err != nil {
+		t.Errorf("Valid access override failed validation: %v", err)
+	}
+
+	err = ValidateAccessOverrides(map[string]rest.AccessOverride{"core/1.0/unknown": count})
+	if err == nil {
+		t.Errorf("Access override of an unknown endpoint passed validation")
+	}
+
+	// The overridden access handler wraps the one of the endpoint, which still denies unauthenticated requests.
+	for _, e := range OverrideAccess(PublicEndpoints, overrides).Endpoints {
+		if e.Path == "tokens/{name}" {
+			trusted, _ := e.Delete.AccessHandler(nil, &http.Request{})
+			if trusted || calls != 1 {
+				t.Errorf("Access override was not applied")
+			}
+		}
+	}
+
+	// Overrides of /core/1.0 endpoints also apply to later versions of the public core API.
+	for _, e := range OverrideAccess(PublicEndpointsV2, overrides).Endpoints {
+		if e.Path == "tokens/{name}" {
+			_, _ = e.Delete.AccessHandler(nil, &http.Request{})
+			if calls != 2 {
+				t.Errorf("Access override was not applied to /core/2.0")
+			}
+		}
+	}
+
+	// The core endpoints themselves are left untouched.
+	for _, e := range PublicEndpoints.Endpoints {
+		if e.Path == "tokens/{name}" {
+			_, _ = e.Delete.AccessHandler(nil, &http.Request{})
+			if calls != 2 {
+				t.Errorf("Core endpoint was modified by the access override")
+			}
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	for _, e := range ReadOnly(PublicEndpoints).Endpoints {
+		switch e.Path {
+		case "cluster":
+			if e.Get.Handler == nil || e.Get.AccessHandler == nil {
+				t.Errorf("GET action was not kept")
+			}
+
+		case "cluster/{name}":
+			if e.Delete.AllowUntrusted || e.Delete.AccessHandler != nil {
+				t.Errorf("Refused DELETE action does not require authentication")
+			}
+
+			rec := httptest.NewRecorder()
+			err := e.Delete.Handler(nil, &http.Request{Method: http.MethodDelete}).Render(rec)
+			if err != nil || rec.Code != http.StatusMethodNotAllowed {
+				t.Errorf("DELETE request was not refused, got status %d", rec.Code)
+			}
+		}
+	}
+
+	// The core endpoints themselves are left untouched.
+	for _, e := range PublicEndpoints.Endpoints {
+		if e.Path == "cluster/{name}" && e.Delete.AccessHandler == nil {
+			t.Errorf("Core endpoint was modified by the read-only copy")
+		}
+	}
+}
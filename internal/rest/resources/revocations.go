@@ -0,0 +1,214 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/cluster"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var certificateRevocationsCmd = rest.Endpoint{
+	Path: "revocations",
+
+	Get:  rest.EndpointAction{Handler: certificateRevocationsGet, AccessHandler: access.AllowAuthenticated},
+	Post: rest.EndpointAction{Handler: certificateRevocationsPost, AccessHandler: access.AllowAuthenticated},
+}
+
+var certificateRevocationCmd = rest.Endpoint{
+	Path: "revocations/{fingerprint}",
+
+	Delete: rest.EndpointAction{Handler: certificateRevocationDelete, AccessHandler: access.AllowAuthenticated},
+}
+
+// certificateRevocationsGet returns the certificates revoked across the cluster.
+// The supported filter is fingerprint.
+func certificateRevocationsGet(s state.State, r *http.Request) response.Response {
+	opts, err := types.ParseListOptions(r.URL.Query(), "fingerprint")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var revocations []cluster.CoreCertificateRevocation
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		revocations, err = cluster.GetCoreCertificateRevocations(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	result := make([]types.CertificateRevocation, 0, len(revocations))
+	for _, revocation := range revocations {
+		result = append(result, revocation.ToAPI())
+	}
+
+	return listResponse(result, opts)
+}
+
+// certificateRevocationsPost revokes a certificate by its fingerprint. The revocation is recorded in the database, and
+// every cluster member is notified to reload its revoked certificates, so that the certificate is rejected right away
+// rather than on the next heartbeat.
+func certificateRevocationsPost(s state.State, r *http.Request) response.Response {
+	req := types.CertificateRevocationPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	// Notifications only need the revocations to be reloaded, as the database is already up to date.
+	if client.IsNotification(r) {
+		err = ReloadCertificateRevocations(ctx, s)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	fingerprint := strings.ToLower(req.Fingerprint)
+	err = validateFingerprint(fingerprint)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Revoking the cluster certificate would lock every client out, rather than a single compromised certificate.
+	clusterCert, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	if shared.CertFingerprint(clusterCert) == fingerprint {
+		return response.BadRequest(fmt.Errorf("The cluster certificate cannot be revoked, rotate it instead"))
+	}
+
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.CreateCoreCertificateRevocation(ctx, tx, cluster.CoreCertificateRevocation{
+			Fingerprint: fingerprint,
+			Reason:      req.Reason,
+			RevokedAt:   time.Now(),
+		})
+
+		return err
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	logger.Warn("Revoked certificate", logger.Ctx{"fingerprint": fingerprint, "reason": req.Reason})
+
+	// The revocation is committed, so failing to apply it right away is not an error. Every cluster member reloads
+	// the revocations on the next heartbeat.
+	reloadRevocations(ctx, s, func(ctx context.Context, c *client.Client) error {
+		return c.AddCertificateRevocation(ctx, req)
+	})
+
+	return response.EmptySyncResponse
+}
+
+// certificateRevocationDelete lifts the revocation of the certificate with the given fingerprint.
+func certificateRevocationDelete(s state.State, r *http.Request) response.Response {
+	fingerprint, err := url.PathUnescape(mux.Vars(r)["fingerprint"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if client.IsNotification(r) {
+		err = ReloadCertificateRevocations(ctx, s)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	fingerprint = strings.ToLower(fingerprint)
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.DeleteCoreCertificateRevocation(ctx, tx, fingerprint)
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	reloadRevocations(ctx, s, func(ctx context.Context, c *client.Client) error {
+		return c.DeleteCertificateRevocation(ctx, fingerprint)
+	})
+
+	return response.EmptySyncResponse
+}
+
+// reloadRevocations applies a committed change of the revocations to the local daemon, and notifies the other cluster
+// members with the given function. Failures are only logged, as the heartbeat reloads the revocations of every cluster
+// member.
+func reloadRevocations(ctx context.Context, s state.State, notify func(ctx context.Context, c *client.Client) error) {
+	err := ReloadCertificateRevocations(ctx, s)
+	if err != nil {
+		logger.Warn("Failed to reload certificate revocations, waiting for the next heartbeat", logger.Ctx{"error": err})
+	}
+
+	err = notifyTrustedClients(ctx, s, notify)
+	if err != nil {
+		logger.Warn("Failed to notify cluster members of certificate revocations, waiting for the next heartbeat", logger.Ctx{"error": err})
+	}
+}
+
+// validateFingerprint checks that the fingerprint is the lowercase hex encoded SHA-256 hash of a certificate.
+func validateFingerprint(fingerprint string) error {
+	decoded, err := hex.DecodeString(fingerprint)
+	if err != nil || len(decoded) != 32 || fingerprint != strings.ToLower(fingerprint) {
+		return fmt.Errorf("Invalid certificate fingerprint %q, expected a lowercase hex encoded SHA-256 hash", fingerprint)
+	}
+
+	return nil
+}
+
+// ReloadCertificateRevocations replaces the daemon's cache of revoked certificates with those recorded in the database.
+func ReloadCertificateRevocations(ctx context.Context, s state.State) error {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return err
+	}
+
+	var revocations []cluster.CoreCertificateRevocation
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		revocations, err = cluster.GetCoreCertificateRevocations(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to load certificate revocations: %w", err)
+	}
+
+	fingerprints := make([]string, 0, len(revocations))
+	for _, revocation := range revocations {
+		fingerprints = append(fingerprints, revocation.Fingerprint)
+	}
+
+	intState.RevokedCertificates.Replace(fingerprints...)
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package resources
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	internalAccess "github.com/canonical/microcluster/v3/internal/rest/access"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+// defaultSessionExpiry is how long bearer tokens are valid for, unless requested otherwise.
+const defaultSessionExpiry = time.Hour
+
+// maxSessionExpiry is the longest a bearer token can be valid for.
+const maxSessionExpiry = 24 * time.Hour
+
+var sessionsCmd = rest.Endpoint{
+	Path: "sessions",
+
+	Post: rest.EndpointAction{Handler: sessionsPost, AccessHandler: access.AllowAuthenticated},
+}
+
+// sessionsPost issues a bearer token standing for the certificate of a trusted API client.
+// Over the network, the token stands for the client certificate the request was authenticated with. Bearer tokens
+// can't be used to request new tokens. Over the unix socket, it stands for the trusted certificate with the given name.
+// Tokens are not issued for cluster member certificates.
+func sessionsPost(s state.State, r *http.Request) response.Response {
+	req := types.SessionPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return response.BadRequest(err)
+	}
+
+	now := time.Now()
+	if req.ExpiresAt.IsZero() {
+		req.ExpiresAt = now.Add(defaultSessionExpiry)
+	}
+
+	if !req.ExpiresAt.After(now) || req.ExpiresAt.After(now.Add(maxSessionExpiry)) {
+		return response.BadRequest(fmt.Errorf("Session expiry must be within %s", maxSessionExpiry))
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	key := internalAccess.SessionKey(s.ClusterCert().PrivateKey())
+	clients := map[string]x509.Certificate{}
+	if intState.TrustedClients != nil {
+		clients = intState.TrustedClients.CertificatesNative()
+	}
+
+	var fingerprint string
+	if r.RemoteAddr == "@" {
+		if req.Name == "" {
+			return response.BadRequest(fmt.Errorf("Trusted certificate name is required over the unix socket"))
+		}
+
+		fingerprint, err = trustedCertificateFingerprint(r.Context(), s, req.Name)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+	} else {
+		fingerprint = sessionFingerprint(r, clients)
+	}
+
+	_, ok := clients[fingerprint]
+	if !ok {
+		return response.Forbidden(fmt.Errorf("Bearer tokens can only be issued to trusted API clients"))
+	}
+
+	// Tokens only hold whole seconds.
+	expiresAt := req.ExpiresAt.Truncate(time.Second)
+	token, err := internalAccess.NewSessionToken(key, fingerprint, expiresAt)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, types.Session{Token: token, Fingerprint: fingerprint, ExpiresAt: expiresAt})
+}
+
+// sessionFingerprint returns the fingerprint of the trusted client certificate the request was authenticated with.
+// Bearer tokens can't be used to issue new tokens, so that a leaked token can't be renewed past its expiry.
+func sessionFingerprint(r *http.Request, clients map[string]x509.Certificate) string {
+	if r.TLS == nil {
+		return ""
+	}
+
+	for _, cert := range r.TLS.PeerCertificates {
+		fingerprint := shared.CertFingerprint(cert)
+		_, ok := clients[fingerprint]
+		if ok {
+			return fingerprint
+		}
+	}
+
+	return ""
+}
+
+// trustedCertificateFingerprint returns the fingerprint of the trusted client certificate with the given name.
+func trustedCertificateFingerprint(ctx context.Context, s state.State, name string) (string, error) {
+	var clients []cluster.CoreTrustedCertificate
+	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		clients, err = cluster.GetCoreTrustedCertificates(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, c := range clients {
+		if c.Name == name {
+			return c.Fingerprint, nil
+		}
+	}
+
+	return "", api.StatusErrorf(http.StatusNotFound, "Trusted certificate %q not found", name)
+}
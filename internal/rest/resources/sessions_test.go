@@ -0,0 +1,30 @@
+package resources
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures bearer tokens are only issued for the client certificate of the request, and not for another bearer token.
+func TestSessionFingerprint(t *testing.T) {
+	trusted := &x509.Certificate{Raw: []byte("trusted")}
+	untrusted := &x509.Certificate{Raw: []byte("untrusted")}
+	clients := map[string]x509.Certificate{shared.CertFingerprint(trusted): *trusted}
+
+	r := httptest.NewRequest(http.MethodPost, "/core/1.0/sessions", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{untrusted, trusted}}
+	require.Equal(t, shared.CertFingerprint(trusted), sessionFingerprint(r, clients))
+
+	r.TLS.PeerCertificates = []*x509.Certificate{untrusted}
+	require.Empty(t, sessionFingerprint(r, clients))
+
+	r = httptest.NewRequest(http.MethodPost, "/core/1.0/sessions", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	require.Empty(t, sessionFingerprint(r, clients))
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
 
 	internalState "github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/rest"
@@ -23,11 +24,38 @@ var shutdownCmd = rest.Endpoint{
 func shutdownPost(state state.State, r *http.Request) response.Response {
 	intState, err := internalState.ToInternal(state)
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	if intState.Context.Err() != nil {
-		return response.SmartError(fmt.Errorf("Shutdown already in progress"))
+		return rest.SmartError(fmt.Errorf("Shutdown already in progress"))
+	}
+
+	// Requests over the network are drained as part of the shutdown sequence, so this request has to
+	// finish before it can start.
+	if r.RemoteAddr != "@" {
+		return response.ManualResponse(func(w http.ResponseWriter) error {
+			err := response.EmptySyncResponse.Render(w)
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				<-r.Context().Done() // Wait until request is finished.
+				if state.Database().Status() != types.DatabaseWaiting {
+					<-intState.ReadyCh // Wait for daemon to start.
+				}
+
+				exit, stopErr := intState.Stop()
+				if stopErr != nil {
+					logger.Error("Failed to cleanly stop the daemon", logger.Ctx{"error": stopErr})
+				}
+
+				exit()
+			}()
+
+			return nil
+		})
 	}
 
 	return response.ManualResponse(func(w http.ResponseWriter) error {
@@ -38,14 +66,14 @@ func shutdownPost(state state.State, r *http.Request) response.Response {
 
 		// Run shutdown sequence synchronously.
 		exit, stopErr := intState.Stop()
-		err := response.SmartError(stopErr).Render(w)
+		err := rest.SmartError(stopErr).Render(w)
 		if err != nil {
 			return err
 		}
 
 		// Send the response before the daemon process ends.
 		f, ok := w.(http.Flusher)
-		if ok {
+		if !ok {
 			return fmt.Errorf("ResponseWriter is not type http.Flusher")
 		}
 
@@ -27,6 +27,12 @@ var sqlCmd = rest.Endpoint{
 	Post: rest.EndpointAction{Handler: sqlPost, AccessHandler: access.AllowAuthenticated},
 }
 
+var sqlTransactionCmd = rest.Endpoint{
+	Path: "sql/transaction",
+
+	Post: rest.EndpointAction{Handler: sqlTransactionPost, AccessHandler: access.AllowAuthenticated},
+}
+
 // Perform a database dump.
 func sqlGet(state state.State, r *http.Request) response.Response {
 	parentCtx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
@@ -47,7 +53,7 @@ func sqlGet(state state.State, r *http.Request) response.Response {
 		return nil
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	return response.SyncResponse(true, types.SQLDump{Text: dump})
@@ -88,7 +94,7 @@ func sqlPost(state state.State, r *http.Request) response.Response {
 			return err
 		})
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
 		batch.Results = append(batch.Results, result)
@@ -97,9 +103,97 @@ func sqlPost(state state.State, r *http.Request) response.Response {
 	return response.SyncResponse(true, batch)
 }
 
-func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult) error {
+// Execute a list of statements with bind parameters in a single transaction.
+func sqlTransactionPost(state state.State, r *http.Request) response.Response {
+	parentCtx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	req := &types.SQLTransaction{}
+
+	// Parse the request, keeping numbers intact so that integers are not bound as floats.
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	err := decoder.Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if len(req.Statements) == 0 {
+		return response.BadRequest(fmt.Errorf("No statements provided"))
+	}
+
+	statementArgs := make([][]any, len(req.Statements))
+	for i, stmt := range req.Statements {
+		if strings.TrimSpace(stmt.Query) == "" {
+			return response.BadRequest(fmt.Errorf("Statement %d has no query", i))
+		}
+
+		statementArgs[i], err = sqlArgs(stmt.Args)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Statement %d has invalid arguments: %w", i, err))
+		}
+	}
+
+	batch := types.SQLBatch{}
+	err = state.Database().Transaction(parentCtx, func(ctx context.Context, tx *sql.Tx) error {
+		batch.Results = make([]types.SQLResult, 0, len(req.Statements))
+		for i, stmt := range req.Statements {
+			var err error
+			result := types.SQLResult{}
+			query := strings.TrimSpace(stmt.Query)
+			if strings.HasPrefix(strings.ToUpper(query), "SELECT") {
+				err = sqlSelect(ctx, tx, query, &result, statementArgs[i]...)
+			} else {
+				err = sqlExec(ctx, tx, query, &result, statementArgs[i]...)
+			}
+
+			if err != nil {
+				return fmt.Errorf("Statement %d: %w", i, err)
+			}
+
+			batch.Results = append(batch.Results, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, batch)
+}
+
+// sqlArgs converts the JSON bind parameters of a statement to values supported by the database driver.
+func sqlArgs(args []any) ([]any, error) {
+	values := make([]any, 0, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case json.Number:
+			integer, err := value.Int64()
+			if err == nil {
+				values = append(values, integer)
+				continue
+			}
+
+			float, err := value.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("Argument %d is not a valid number: %w", i, err)
+			}
+
+			values = append(values, float)
+		case string, bool, nil:
+			values = append(values, value)
+		default:
+			return nil, fmt.Errorf("Argument %d has unsupported type %T", i, arg)
+		}
+	}
+
+	return values, nil
+}
+
+func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult, args ...any) error {
 	result.Type = "select"
-	rows, err := tx.QueryContext(ctx, query)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("Failed to execute query: %w", err)
 	}
@@ -148,9 +242,9 @@ func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLR
 	return nil
 }
 
-func sqlExec(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult) error {
+func sqlExec(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult, args ...any) error {
 	result.Type = "exec"
-	r, err := tx.ExecContext(ctx, query)
+	r, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("Failed to exec query: %w", err)
 	}
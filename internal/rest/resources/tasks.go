@@ -0,0 +1,22 @@
+package resources
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var tasksCmd = rest.Endpoint{
+	Path:              "tasks",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: tasksGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+func tasksGet(s state.State, r *http.Request) response.Response {
+	return response.SyncResponse(true, s.Tasks().List())
+}
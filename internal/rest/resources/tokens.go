@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/canonical/lxd/lxd/response"
-	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/gorilla/mux"
 
@@ -36,7 +38,7 @@ var tokenCmd = rest.Endpoint{
 	Delete: rest.EndpointAction{Handler: tokenDelete, AccessHandler: access.AllowAuthenticated},
 }
 
-func tokensPost(state state.State, r *http.Request) response.Response {
+func tokensPost(s state.State, r *http.Request) response.Response {
 	req := internalTypes.TokenRequest{}
 
 	// Parse the request.
@@ -47,31 +49,67 @@ func tokensPost(state state.State, r *http.Request) response.Response {
 
 	err = utils.ValidateFQDN(req.Name)
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Token name %q is not a valid FQDN: %w", req.Name, err))
+		return rest.SmartError(fmt.Errorf("Token name %q is not a valid FQDN: %w", req.Name, err))
+	}
+
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	if intState.LocalConfig().GetLocalOnly() {
+		return response.BadRequest(fmt.Errorf("Cluster member %q is only reachable locally, publish it on a network address before issuing join tokens", s.Name()))
+	}
+
+	if req.MaxJoins < 0 {
+		return response.BadRequest(fmt.Errorf("Token max joins cannot be negative"))
+	}
+
+	if req.MaxJoins == 0 {
+		req.MaxJoins = 1
+	}
+
+	if req.Rejoin && req.MaxJoins != 1 {
+		return response.BadRequest(fmt.Errorf("Rejoin tokens can only be used once"))
+	}
+
+	subnets := make([]string, 0, len(req.AllowedSubnets))
+	for _, subnet := range req.AllowedSubnets {
+		prefix, err := netip.ParsePrefix(subnet)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid token subnet %q: %w", subnet, err))
+		}
+
+		subnets = append(subnets, prefix.Masked().String())
 	}
 
 	// Generate join token for new member. This will be stored alongside the join
 	// address and cluster certificate to simplify setup.
-	tokenKey, err := shared.RandomCryptoString()
+	tokenKey, err := internalTypes.NewTokenSecret()
 	if err != nil {
 		return response.InternalError(err)
 	}
 
-	clusterCert, err := state.ClusterCert().PublicKeyX509()
+	clusterCert, err := s.ClusterCert().PublicKeyX509()
 	if err != nil {
 		return response.InternalError(err)
 	}
 
 	joinAddresses := []types.AddrPort{}
-	for _, addr := range state.Remotes().Addresses() {
+	for _, addr := range s.Remotes().Addresses() {
+		// Members initialized with a wildcard address cannot be reached through it.
+		if addr.Addr().IsUnspecified() {
+			continue
+		}
+
 		joinAddresses = append(joinAddresses, addr)
 	}
 
 	if len(joinAddresses) == 0 {
-		logger.Warnf("Failed to check trust store for eligible join addresses. Issuing token with join address %q", state.Address().URL.Host)
-		joinAddresses, err = types.ParseAddrPorts([]string{state.Address().URL.Host})
+		logger.Warnf("Failed to check trust store for eligible join addresses. Issuing token with join address %q", s.Address().URL.Host)
+		joinAddresses, err = types.ParseAddrPorts([]string{s.Address().URL.Host})
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 	}
 
@@ -82,10 +120,19 @@ func tokensPost(state state.State, r *http.Request) response.Response {
 		expiryDate.Time = time.Now().Add(req.ExpireAfter)
 	}
 
-	token := internalTypes.Token{
-		Secret:        tokenKey,
-		Fingerprint:   shared.CertFingerprint(clusterCert),
-		JoinAddresses: joinAddresses,
+	record := cluster.CoreTokenRecord{
+		Name:           req.Name,
+		Secret:         tokenKey,
+		ExpiryDate:     expiryDate,
+		MaxJoins:       req.MaxJoins,
+		AllowedSubnets: strings.Join(subnets, ","),
+		Rejoin:         req.Rejoin,
+		IssuedAt:       sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	token, err := record.Token(clusterCert, joinAddresses, intState.Project, intState.TokenFingerprintHash)
+	if err != nil {
+		return response.InternalError(err)
 	}
 
 	tokenString, err := token.String()
@@ -93,39 +140,61 @@ func tokensPost(state state.State, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
-	err = state.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
 		err = cluster.DeleteExpiredCoreTokenRecords(ctx, tx)
 		if err != nil {
 			return err
 		}
 
-		_, err = cluster.CreateCoreTokenRecord(ctx, tx, cluster.CoreTokenRecord{
-			Name:       req.Name,
-			Secret:     tokenKey,
-			ExpiryDate: expiryDate,
-		})
+		// Rejoin tokens are bound to an existing cluster member.
+		if req.Rejoin {
+			member, err := cluster.GetCoreClusterMember(ctx, tx, req.Name)
+			if err != nil {
+				return fmt.Errorf("Failed to get cluster member %q to rejoin: %w", req.Name, err)
+			}
+
+			if member.Role == cluster.Pending {
+				return api.StatusErrorf(http.StatusBadRequest, "Cluster member %q has not finished joining", req.Name)
+			}
+
+			if member.Name == s.Name() {
+				return api.StatusErrorf(http.StatusBadRequest, "Cluster member %q can't issue a rejoin token for itself", req.Name)
+			}
+		}
+
+		_, err = cluster.CreateCoreTokenRecord(ctx, tx, record)
 		return err
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	return response.SyncResponse(true, tokenString)
 }
 
-func tokensGet(state state.State, r *http.Request) response.Response {
-	clusterCert, err := state.ClusterCert().PublicKeyX509()
+func tokensGet(s state.State, r *http.Request) response.Response {
+	opts, err := types.ParseListOptions(r.URL.Query(), "name")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	clusterCert, err := s.ClusterCert().PublicKeyX509()
 	if err != nil {
 		return response.InternalError(err)
 	}
 
 	joinAddresses := []types.AddrPort{}
-	for _, addr := range state.Remotes().Addresses() {
+	for _, addr := range s.Remotes().Addresses() {
 		joinAddresses = append(joinAddresses, addr)
 	}
 
 	var records []internalTypes.TokenRecord
-	err = state.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
 		var err error
 		tokens, err := cluster.GetCoreTokenRecords(ctx, tx)
 		if err != nil {
@@ -138,7 +207,7 @@ func tokensGet(state state.State, r *http.Request) response.Response {
 				continue
 			}
 
-			apiToken, err := token.ToAPI(clusterCert, joinAddresses)
+			apiToken, err := token.ToAPI(clusterCert, joinAddresses, intState.Project, intState.TokenFingerprintHash)
 			if err != nil {
 				return err
 			}
@@ -149,23 +218,23 @@ func tokensGet(state state.State, r *http.Request) response.Response {
 		return nil
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
-	return response.SyncResponse(true, records)
+	return listResponse(records, opts)
 }
 
 func tokenDelete(state state.State, r *http.Request) response.Response {
 	name, err := url.PathUnescape(mux.Vars(r)["name"])
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	err = state.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
 		return cluster.DeleteCoreTokenRecord(ctx, tx, name)
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	return response.EmptySyncResponse
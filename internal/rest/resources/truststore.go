@@ -2,17 +2,23 @@ package resources
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
 	"github.com/gorilla/mux"
 
 	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/cluster"
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/internal/trust"
 	"github.com/canonical/microcluster/v3/rest"
 	"github.com/canonical/microcluster/v3/rest/access"
@@ -34,6 +40,225 @@ var trustEntryCmd = rest.Endpoint{
 	Delete: rest.EndpointAction{Handler: trustDelete, AccessHandler: access.AllowAuthenticated},
 }
 
+var trustedCertificatesCmd = rest.Endpoint{
+	Path: "truststore",
+
+	Get:  rest.EndpointAction{Handler: trustedCertificatesGet, AccessHandler: access.AllowAuthenticated},
+	Post: rest.EndpointAction{Handler: trustedCertificatesPost, AccessHandler: access.AllowAuthenticated},
+}
+
+var trustedCertificateCmd = rest.Endpoint{
+	Path: "truststore/{name}",
+
+	Delete: rest.EndpointAction{Handler: trustedCertificateDelete, AccessHandler: access.AllowAuthenticated},
+}
+
+// trustedCertificatesGet returns the certificates of the cluster members and of the API clients trusted by the daemon.
+// Supported filters are name, type and fingerprint.
+func trustedCertificatesGet(s state.State, r *http.Request) response.Response {
+	opts, err := types.ParseListOptions(r.URL.Query(), "name", "type", "fingerprint")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	certificates := []types.TrustedCertificate{}
+	for _, remote := range s.Remotes().RemotesByName() {
+		certificates = append(certificates, types.TrustedCertificate{
+			Name:        remote.Name,
+			Type:        types.TrustedCertificateMember,
+			Address:     remote.Address.String(),
+			Fingerprint: shared.CertFingerprint(remote.Certificate.Certificate),
+			Certificate: remote.Certificate,
+		})
+	}
+
+	var clients []cluster.CoreTrustedCertificate
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		clients, err = cluster.GetCoreTrustedCertificates(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	for _, c := range clients {
+		certificate, err := c.ToAPI()
+		if err != nil {
+			return rest.SmartError(fmt.Errorf("Failed to parse trusted certificate %q: %w", c.Name, err))
+		}
+
+		certificates = append(certificates, *certificate)
+	}
+
+	slices.SortFunc(certificates, func(a types.TrustedCertificate, b types.TrustedCertificate) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return listResponse(certificates, opts)
+}
+
+// trustedCertificatesPost trusts the certificate of an API client that is not a cluster member.
+// The certificate is recorded in the database, and every cluster member is notified to reload its trusted clients.
+func trustedCertificatesPost(s state.State, r *http.Request) response.Response {
+	req := types.TrustedCertificatePost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	// Notifications only need the trusted clients to be reloaded, as the database is already up to date.
+	if client.IsNotification(r) {
+		err = ReloadTrustedClients(ctx, s)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("Trusted certificate name must not be empty"))
+	}
+
+	if req.Certificate.Certificate == nil {
+		return response.BadRequest(fmt.Errorf("Trusted certificate %q has no certificate", req.Name))
+	}
+
+	fingerprint := shared.CertFingerprint(req.Certificate.Certificate)
+	for _, remote := range s.Remotes().RemotesByName() {
+		if remote.Name == req.Name || shared.CertFingerprint(remote.Certificate.Certificate) == fingerprint {
+			return response.BadRequest(fmt.Errorf("Trusted certificate %q clashes with cluster member %q", req.Name, remote.Name))
+		}
+	}
+
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.CreateCoreTrustedCertificate(ctx, tx, cluster.CoreTrustedCertificate{
+			Name:        req.Name,
+			Fingerprint: fingerprint,
+			Certificate: req.Certificate.String(),
+		})
+
+		return err
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = notifyTrustedClients(ctx, s, func(ctx context.Context, c *client.Client) error {
+		return c.AddTrustedCertificate(ctx, req)
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = ReloadTrustedClients(ctx, s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// trustedCertificateDelete revokes the certificate of an API client that is not a cluster member.
+// Cluster member certificates can only be revoked by removing the member from the cluster.
+func trustedCertificateDelete(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if client.IsNotification(r) {
+		err = ReloadTrustedClients(ctx, s)
+		if err != nil {
+			return rest.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	_, ok := s.Remotes().RemotesByName()[name]
+	if ok {
+		return response.BadRequest(fmt.Errorf("%q is a cluster member, remove it from the cluster to revoke its certificate", name))
+	}
+
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.DeleteCoreTrustedCertificate(ctx, tx, name)
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = notifyTrustedClients(ctx, s, func(ctx context.Context, c *client.Client) error {
+		return c.DeleteTrustedCertificate(ctx, name)
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = ReloadTrustedClients(ctx, s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// notifyTrustedClients sends the notification to every other cluster member, so that they reload their trusted clients.
+func notifyTrustedClients(ctx context.Context, s state.State, notify func(ctx context.Context, c *client.Client) error) error {
+	cluster, err := s.Cluster(true)
+	if err != nil {
+		return err
+	}
+
+	return cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+		// No need to send a request to ourselves.
+		if s.Address().URL.Host == c.URL().URL.Host {
+			return nil
+		}
+
+		return notify(ctx, c)
+	})
+}
+
+// ReloadTrustedClients replaces the daemon's cache of trusted client certificates with those recorded in the database.
+func ReloadTrustedClients(ctx context.Context, s state.State) error {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return err
+	}
+
+	var clients []cluster.CoreTrustedCertificate
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		clients, err = cluster.GetCoreTrustedCertificates(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to load trusted client certificates: %w", err)
+	}
+
+	certificates := make([]types.X509Certificate, 0, len(clients))
+	for _, c := range clients {
+		certificate, err := types.ParseX509Certificate(c.Certificate)
+		if err != nil {
+			return fmt.Errorf("Failed to parse trusted certificate %q: %w", c.Name, err)
+		}
+
+		certificates = append(certificates, *certificate)
+	}
+
+	intState.TrustedClients.Replace(certificates...)
+
+	return nil
+}
+
 func trustPost(s state.State, r *http.Request) response.Response {
 	req := types.ClusterMemberLocal{}
 
@@ -44,7 +269,7 @@ func trustPost(s state.State, r *http.Request) response.Response {
 	}
 
 	newRemote := trust.Remote{
-		Location:    trust.Location{Name: req.Name, Address: req.Address},
+		Location:    trust.Location{Name: req.Name, Address: req.Address, DNSName: req.DNSName},
 		Certificate: req.Certificate,
 	}
 
@@ -54,7 +279,7 @@ func trustPost(s state.State, r *http.Request) response.Response {
 	if !client.IsNotification(r) {
 		cluster, err := s.Cluster(true)
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
 		err = cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
@@ -66,17 +291,23 @@ func trustPost(s state.State, r *http.Request) response.Response {
 			return internalClient.AddTrustStoreEntry(ctx, &c.Client, req)
 		})
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 	}
 
 	// At this point, the node has joined dqlite so we can add a local record for it if we haven't already from a heartbeat (or if we are the leader).
+	// A reinstalled member that rejoined with its existing name has a new certificate, so its record is updated.
 	remotes := s.Remotes()
-	_, ok := remotes.RemotesByName()[newRemote.Name]
+	existing, ok := remotes.RemotesByName()[newRemote.Name]
 	if !ok {
-		err = remotes.Add(s.FileSystem().TrustDir, newRemote)
+		err = remotes.Add(newRemote)
+		if err != nil {
+			return rest.SmartError(fmt.Errorf("Failed adding local record of newly joined node %q: %w", req.Name, err))
+		}
+	} else if existing.Address != newRemote.Address || !existing.Certificate.Equal(newRemote.Certificate.Certificate) {
+		err = remotes.Update(newRemote)
 		if err != nil {
-			return response.SmartError(fmt.Errorf("Failed adding local record of newly joined node %q: %w", req.Name, err))
+			return rest.SmartError(fmt.Errorf("Failed updating local record of rejoined node %q: %w", req.Name, err))
 		}
 	}
 
@@ -86,7 +317,7 @@ func trustPost(s state.State, r *http.Request) response.Response {
 func trustDelete(s state.State, r *http.Request) response.Response {
 	name, err := url.PathUnescape(mux.Vars(r)["name"])
 	if err != nil {
-		return response.SmartError(err)
+		return rest.SmartError(err)
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
@@ -95,13 +326,13 @@ func trustDelete(s state.State, r *http.Request) response.Response {
 	remotesMap := s.Remotes().RemotesByName()
 	nodeToRemove, ok := remotesMap[name]
 	if !ok {
-		return response.SmartError(fmt.Errorf("No truststore entry found for node with name %q", name))
+		return rest.SmartError(fmt.Errorf("No truststore entry found for node with name %q", name))
 	}
 
 	if !client.IsNotification(r) {
 		cluster, err := s.Cluster(true)
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 
 		err = cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
@@ -113,7 +344,7 @@ func trustDelete(s state.State, r *http.Request) response.Response {
 			return internalClient.DeleteTrustStoreEntry(ctx, &c.Client, name)
 		})
 		if err != nil {
-			return response.SmartError(err)
+			return rest.SmartError(err)
 		}
 	}
 
@@ -123,20 +354,12 @@ func trustDelete(s state.State, r *http.Request) response.Response {
 
 	newRemotes := make([]types.ClusterMember, 0, len(remotesMap))
 	for _, remote := range remotesMap {
-		newRemote := types.ClusterMember{
-			ClusterMemberLocal: types.ClusterMemberLocal{
-				Name:        remote.Name,
-				Address:     remote.Address,
-				Certificate: remote.Certificate,
-			},
-		}
-
-		newRemotes = append(newRemotes, newRemote)
+		newRemotes = append(newRemotes, remote.ClusterMember())
 	}
 
-	err = remotes.Replace(s.FileSystem().TrustDir, newRemotes...)
+	err = remotes.Replace(newRemotes...)
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Failed to remove truststore entry for node with name %q: %w", name, err))
+		return rest.SmartError(fmt.Errorf("Failed to remove truststore entry for node with name %q: %w", name, err))
 	}
 
 	return response.EmptySyncResponse
@@ -0,0 +1,117 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var upgradeCmd = rest.Endpoint{
+	Path:              "upgrade",
+	AllowedBeforeInit: true,
+
+	Get:  rest.EndpointAction{Handler: upgradeGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+	Post: rest.EndpointAction{Handler: upgradePost, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+// upgradePollInterval is how often the upgrade operation checks whether the coordinated upgrade has completed.
+const upgradePollInterval = time.Second
+
+func upgradeGet(s state.State, r *http.Request) response.Response {
+	status := s.Database().Status()
+
+	// If the database is not in a ready or waiting state, we can't be sure it's available for use.
+	if status != types.DatabaseReady && status != types.DatabaseWaiting {
+		return rest.SmartError(api.StatusErrorf(http.StatusServiceUnavailable, "%s", string(status)))
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	schemaInternal, schemaExternal, apiExtensions := s.Database().SchemaVersion()
+	upgradeStatus := types.ClusterUpgradeStatus{
+		Stage:                 intState.InternalDatabase.UpgradeStage(),
+		SchemaInternalVersion: schemaInternal,
+		SchemaExternalVersion: schemaExternal,
+		Extensions:            apiExtensions,
+	}
+
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		clusterMembers, awaitingUpgrade, err := cluster.GetUpgradingClusterMembers(ctx, tx, schemaInternal, schemaExternal, apiExtensions)
+		if err != nil {
+			return err
+		}
+
+		upgradeStatus.Members = make([]types.UpgradeMemberStatus, 0, len(clusterMembers))
+		for _, clusterMember := range clusterMembers {
+			upgradeStatus.Members = append(upgradeStatus.Members, types.UpgradeMemberStatus{
+				Name:                  clusterMember.Name,
+				SchemaInternalVersion: clusterMember.SchemaInternal,
+				SchemaExternalVersion: clusterMember.SchemaExternal,
+				Extensions:            clusterMember.APIExtensions,
+				Ready:                 !awaitingUpgrade[clusterMember.Name],
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return rest.SmartError(fmt.Errorf("Failed to get cluster member versions: %w", err))
+	}
+
+	return response.SyncResponse(true, upgradeStatus)
+}
+
+// upgradePost starts an operation that waits for the coordinated upgrade of the cluster member to complete, and reports
+// its upgrade stage as progress. The operation succeeds once all cluster members are ready and the database is open.
+func upgradePost(s state.State, r *http.Request) response.Response {
+	status := s.Database().Status()
+	if status != types.DatabaseReady && status != types.DatabaseWaiting {
+		return rest.SmartError(api.StatusErrorf(http.StatusServiceUnavailable, "%s", string(status)))
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	op, err := s.Operations().Start("Waiting for the cluster upgrade", func(ctx context.Context, op *state.Operation) (any, error) {
+		ticker := time.NewTicker(upgradePollInterval)
+		defer ticker.Stop()
+
+		for {
+			stage := intState.InternalDatabase.UpgradeStage()
+			status := s.Database().Status()
+			op.SetProgress(map[string]any{"stage": stage, "database": status})
+
+			if stage != types.UpgradeStagePending && status == types.DatabaseReady {
+				return stage, nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return rest.OperationResponse(op)
+}
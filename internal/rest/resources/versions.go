@@ -0,0 +1,24 @@
+package resources
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var coreVersionsCmd = rest.Endpoint{
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: coreVersionsGet, AllowUntrusted: true},
+}
+
+// coreVersionsGet lists the versions of the core API served by the daemon, so that clients can pick the newest
+// version they support.
+func coreVersionsGet(s state.State, r *http.Request) response.Response {
+	return response.SyncResponse(true, types.APIVersions{Versions: internalTypes.PublicEndpointVersions})
+}
@@ -0,0 +1,134 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var warningsCmd = rest.Endpoint{
+	Path: "warnings",
+
+	Get: rest.EndpointAction{Handler: warningsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var warningCmd = rest.Endpoint{
+	Path: "warnings/{uuid}",
+
+	Get:    rest.EndpointAction{Handler: warningGet, AccessHandler: access.AllowAuthenticated},
+	Put:    rest.EndpointAction{Handler: warningPut, AccessHandler: access.AllowAuthenticated},
+	Delete: rest.EndpointAction{Handler: warningDelete, AccessHandler: access.AllowAuthenticated},
+}
+
+// warningsGet returns the warnings filed about every cluster member.
+// Supported filters are member, type, entity and status.
+func warningsGet(s state.State, r *http.Request) response.Response {
+	opts, err := types.ParseListOptions(r.URL.Query(), "member", "type", "entity", "status")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	warnings, err := getWarnings(r.Context(), s)
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return listResponse(warnings, opts)
+}
+
+// warningGet returns the warning with the given UUID.
+func warningGet(s state.State, r *http.Request) response.Response {
+	warningUUID, err := url.PathUnescape(mux.Vars(r)["uuid"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	var warning *cluster.CoreWarning
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		warning, err = cluster.GetCoreWarning(ctx, tx, warningUUID)
+
+		return err
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.SyncResponse(true, warning.ToAPI())
+}
+
+// warningPut acknowledges the warning with the given UUID, or marks it as new again.
+func warningPut(s state.State, r *http.Request) response.Response {
+	warningUUID, err := url.PathUnescape(mux.Vars(r)["uuid"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	req := types.WarningPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = req.Status.Validate()
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.UpdateCoreWarningStatus(ctx, tx, warningUUID, req.Status)
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// warningDelete removes the warning with the given UUID. It is filed again if the condition it reports persists.
+func warningDelete(s state.State, r *http.Request) response.Response {
+	warningUUID, err := url.PathUnescape(mux.Vars(r)["uuid"])
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.DeleteCoreWarning(ctx, tx, warningUUID)
+	})
+	if err != nil {
+		return rest.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// getWarnings returns the warnings filed about every cluster member.
+func getWarnings(ctx context.Context, s state.State) ([]types.Warning, error) {
+	var warnings []cluster.CoreWarning
+	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		warnings, err = cluster.GetCoreWarnings(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]types.Warning, 0, len(warnings))
+	for _, warning := range warnings {
+		result = append(result, warning.ToAPI())
+	}
+
+	return result, nil
+}
@@ -2,25 +2,35 @@ package rest
 
 import (
 	"context"
+	"crypto/x509"
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 
 	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/db"
 	internalAccess "github.com/canonical/microcluster/v3/internal/rest/access"
 	"github.com/canonical/microcluster/v3/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/internal/tracing"
+	"github.com/canonical/microcluster/v3/internal/trust"
 	"github.com/canonical/microcluster/v3/rest"
 	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
 	"github.com/canonical/microcluster/v3/state"
 )
 
@@ -29,6 +39,14 @@ func handleAPIRequest(action rest.EndpointAction, state state.State, w http.Resp
 		return response.NotImplemented(nil)
 	}
 
+	// Reject requests over the rate limit before doing any further work on them.
+	allowed, retryAfter := action.RateLimit.Allow(r)
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+		return rest.SmartError(api.StatusErrorf(http.StatusTooManyRequests, "Too many requests, retry in %s", retryAfter.Round(time.Millisecond)))
+	}
+
 	// If allow untrusted is not set, the request must be authenticated via core authentication (e.g. certificate in truststore).
 	if !action.AllowUntrusted {
 		trusted, resp := access.AllowAuthenticated(state, r)
@@ -111,6 +129,14 @@ func proxyTarget(action rest.EndpointAction, s state.State, r *http.Request) res
 		return response.InternalError(fmt.Errorf("Failed to get a client for the target %q at address %q: %w", target, targetURL.String(), err))
 	}
 
+	// Websockets are relayed between the caller and the target rather than forwarded as a single request.
+	if websocket.IsWebSocketUpgrade(r) {
+		targetURL.URL.RawQuery = r.URL.RawQuery
+		logger.Info("Forwarding websocket to specified target", logger.Ctx{"source": s.Name(), "target": target})
+
+		return proxyWebsocket(r, client, targetURL)
+	}
+
 	// Update request URL.
 	r.RequestURI = ""
 	r.URL.Scheme = targetURL.URL.Scheme
@@ -120,7 +146,7 @@ func proxyTarget(action rest.EndpointAction, s state.State, r *http.Request) res
 	logger.Info("Forwarding request to specified target", logger.Ctx{"source": s.Name(), "target": target})
 	resp, err := client.MakeRequest(r)
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Failed to send request to target %q: %w", target, err))
+		return rest.SmartError(fmt.Errorf("Failed to send request to target %q: %w", target, err))
 	}
 
 	return response.SyncResponse(true, resp.Metadata)
@@ -178,6 +204,73 @@ func handleDatabaseRequest(action rest.EndpointAction, state state.State, w http
 	return response.EmptySyncResponse
 }
 
+// allowsNotification returns whether the caller with the given identity may send notifications and forward requests,
+// which skip the checks and fan-out applied to other requests. Only other cluster members and callers of the control
+// API may, so API clients that are trusted without being cluster members can't claim to.
+func allowsNotification(identity access.Identity) bool {
+	switch identity.Method {
+	case access.AuthMethodUnix, access.AuthMethodControlSecret, access.AuthMethodPreInit:
+		return true
+	case access.AuthMethodTLS:
+		return identity.Name != ""
+	default:
+		return false
+	}
+}
+
+// trustedCertificates returns the certificates trusted on the endpoints of the given version, keyed by fingerprint.
+// API clients trusted without being cluster members are not allowed on the internal endpoints, and revoked
+// certificates are not trusted, even over connections established before they were revoked.
+func trustedCertificates(version string, remotes *trust.Remotes, clients *trust.Clients, revoked *trust.Revocations) map[string]x509.Certificate {
+	trustedCerts := remotes.CertificatesNative()
+	if version != string(internalTypes.InternalEndpoint) && clients != nil {
+		for fingerprint, cert := range clients.CertificatesNative() {
+			trustedCerts[fingerprint] = cert
+		}
+	}
+
+	if revoked != nil {
+		for fingerprint := range trustedCerts {
+			if revoked.IsRevoked(fingerprint) {
+				delete(trustedCerts, fingerprint)
+			}
+		}
+	}
+
+	return trustedCerts
+}
+
+// isDrained returns whether the request is turned away while the cluster member is under maintenance, so that clients
+// move to other members. Only requests to the consumer's endpoints are drained, and requests over the unix socket and
+// notifications from other cluster members are still served.
+func isDrained(version string, r *http.Request, identity access.Identity) bool {
+	if isCoreEndpoint(version) || r.RemoteAddr == "@" {
+		return false
+	}
+
+	return !client.IsForwardedRequest(r) || !allowsNotification(identity)
+}
+
+// checkInitialized returns an error unless the endpoint is allowed before the daemon is initialized, or the database
+// is open.
+func checkInitialized(ctx context.Context, e rest.Endpoint, database db.DB) error {
+	if e.AllowedBeforeInit {
+		return nil
+	}
+
+	return database.IsOpen(ctx)
+}
+
+// isCoreEndpoint returns whether the endpoints with the given version prefix are managed by microcluster.
+func isCoreEndpoint(version string) bool {
+	switch types.EndpointPrefix(version) {
+	case internalTypes.CoreEndpoint, internalTypes.PublicEndpoint, internalTypes.PublicEndpointV2, internalTypes.InternalEndpoint, internalTypes.ControlEndpoint:
+		return true
+	}
+
+	return false
+}
+
 // HandleEndpoint adds the endpoint to the mux router. A function variable is used to implement common logic
 // before calling the endpoint action handler associated with the request method, if it exists.
 func HandleEndpoint(state state.State, mux *mux.Router, version string, e rest.Endpoint) {
@@ -187,10 +280,11 @@ func HandleEndpoint(state state.State, mux *mux.Router, version string, e rest.E
 	}
 
 	route := mux.HandleFunc(url, func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
+		// Continue the trace of the caller, which may be another cluster member forwarding or fanning out a request.
+		r, span := tracing.StartServerSpan(r, url)
+		defer span.End()
 
-		// Actually process the request.
-		var resp response.Response
+		w.Header().Set("Content-Type", "application/json")
 
 		intState, err := internalState.ToInternal(state)
 		if err != nil {
@@ -202,6 +296,15 @@ func HandleEndpoint(state state.State, mux *mux.Router, version string, e rest.E
 			return
 		}
 
+		// Record mutating requests in the audit log, except for the dqlite connections handed over to the database.
+		if intState.AuditLog != nil && isAudited(r) && e.Path != "database" {
+			auditWriter := &auditResponseWriter{ResponseWriter: w}
+			w = auditWriter
+
+			start := time.Now()
+			defer func() { auditRequest(intState, r, auditWriter.statusCode, start) }()
+		}
+
 		// Return Unavailable Error (503) if daemon is shutting down, except for endpoints with AllowedDuringShutdown.
 		if intState.Context.Err() == context.Canceled && !e.AllowedDuringShutdown {
 			err := response.Unavailable(fmt.Errorf("Daemon is shutting down")).Render(w)
@@ -212,10 +315,40 @@ func HandleEndpoint(state state.State, mux *mux.Router, version string, e rest.E
 			return
 		}
 
-		if !e.AllowedBeforeInit {
-			err := state.Database().IsOpen(r.Context())
+		// Bound the size of the request body and the time spent on the request.
+		r, body, cancel, resp := applyEndpointLimits(e, w, r)
+		if resp != nil {
+			err := resp.Render(w)
+			if err != nil {
+				logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			}
+
+			return
+		}
+
+		defer cancel()
+
+		err = checkInitialized(r.Context(), e, state.Database())
+		if err != nil {
+			err := rest.SmartError(err).Render(w)
 			if err != nil {
-				err := response.SmartError(err).Render(w)
+				logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			}
+
+			return
+		}
+
+		// Apply the control socket policy to requests received over the unix socket.
+		if r.RemoteAddr == "@" && intState.ControlSocketPolicy != nil {
+			creds, ok := internalAccess.GetPeerCredentials(r)
+			if !ok {
+				err = fmt.Errorf("Failed to determine the credentials of the calling process")
+			} else {
+				err = intState.ControlSocketPolicy(r, creds)
+			}
+
+			if err != nil {
+				err := response.Forbidden(err).Render(w)
 				if err != nil {
 					logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
 				}
@@ -230,11 +363,23 @@ func HandleEndpoint(state state.State, mux *mux.Router, version string, e rest.E
 			handleRequest = handleDatabaseRequest
 		}
 
-		trusted, err := access.Authenticate(state, r, state.Address().URL.Host, state.Remotes().CertificatesNative())
+		trustedCerts := trustedCertificates(version, state.Remotes(), intState.TrustedClients, intState.RevokedCertificates)
+		// Requests to listeners other than the core API listener are addressed to their own listener.
+		hostAddress := state.Address().URL.Host
+		listenerAddress, ok := internalAccess.ListenerAddress(r)
+		if ok {
+			hostAddress = listenerAddress
+		}
+
+		identity, err := access.AuthenticateIdentity(state, r, hostAddress, trustedCerts)
 		if err != nil && !errors.As(err, &access.ErrInvalidHost{}) {
 			resp = response.Forbidden(fmt.Errorf("Failed to authenticate request: %w", err))
+		} else if client.IsForwardedRequest(r) && !allowsNotification(identity) {
+			resp = response.Forbidden(fmt.Errorf("Only cluster members can send notifications"))
+		} else if intState.Maintenance != nil && intState.Maintenance.Load() && isDrained(version, r, identity) {
+			resp = response.Unavailable(fmt.Errorf("Cluster member is under maintenance"))
 		} else {
-			r = internalAccess.SetRequestAuthentication(r, trusted)
+			r = internalAccess.SetRequestIdentity(r, identity)
 
 			switch r.Method {
 			case "GET":
@@ -252,6 +397,11 @@ func HandleEndpoint(state state.State, mux *mux.Router, version string, e rest.E
 			}
 		}
 
+		// Report requests with bodies over the limit as such, rather than with the error the handler got reading them.
+		if body != nil && body.exceeded {
+			resp = requestTooLarge(e.MaxRequestBytes)
+		}
+
 		// Handle errors.
 		// In case of the database extra care has to be taken to not accidentally write
 		// to the ResponseWriter as the connection gets hijacked and passed to dqlite.
@@ -0,0 +1,191 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	clusterRequest "github.com/canonical/lxd/lxd/cluster/request"
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/internal/db"
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/internal/trust"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// memBackend stores the remotes in memory.
+type memBackend struct {
+	remotes []trust.Remote
+}
+
+func (b *memBackend) Load() ([]trust.Remote, error) { return b.remotes, nil }
+
+func (b *memBackend) Add(remote trust.Remote) error {
+	b.remotes = append(b.remotes, remote)
+	return nil
+}
+
+func (b *memBackend) Replace(remotes []trust.Remote) error {
+	b.remotes = remotes
+	return nil
+}
+
+func (b *memBackend) Watch(refresh func() error) {}
+
+// initDB is a database that is only open once the daemon is initialized.
+type initDB struct {
+	db.DB
+
+	open bool
+}
+
+// IsOpen returns an error unless the database is open.
+func (d *initDB) IsOpen(ctx context.Context) error {
+	if !d.open {
+		return types.ErrNotBootstrapped
+	}
+
+	return nil
+}
+
+// Ensures endpoints are only served before the daemon is initialized if they are allowed before initialization.
+func TestCheckInitialized(t *testing.T) {
+	cases := []struct {
+		name        string
+		endpoint    rest.Endpoint
+		initialized bool
+		expectErr   bool
+	}{
+		{name: "Endpoint before initialization", endpoint: rest.Endpoint{}, expectErr: true},
+		{name: "Endpoint after initialization", endpoint: rest.Endpoint{}, initialized: true},
+		{name: "Endpoint allowed before initialization", endpoint: rest.Endpoint{AllowedBeforeInit: true}},
+		{name: "Endpoint allowed before initialization, after initialization", endpoint: rest.Endpoint{AllowedBeforeInit: true}, initialized: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := checkInitialized(context.Background(), c.endpoint, &initDB{open: c.initialized})
+			if c.expectErr {
+				require.ErrorIs(t, err, types.ErrNotBootstrapped)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// Ensures only cluster members and callers of the control API can send notifications.
+func TestAllowsNotification(t *testing.T) {
+	cases := []struct {
+		name     string
+		identity access.Identity
+		allowed  bool
+	}{
+		{name: "Control socket", identity: access.Identity{Method: access.AuthMethodUnix, Trusted: true}, allowed: true},
+		{name: "Control secret", identity: access.Identity{Method: access.AuthMethodControlSecret, Trusted: true}, allowed: true},
+		{name: "Uninitialized daemon", identity: access.Identity{Method: access.AuthMethodPreInit, Trusted: true}, allowed: true},
+		{name: "Cluster member", identity: access.Identity{Method: access.AuthMethodTLS, Trusted: true, Name: "m1", Fingerprint: "abcd"}, allowed: true},
+		{name: "Trusted client", identity: access.Identity{Method: access.AuthMethodTLS, Trusted: true, Fingerprint: "abcd"}},
+		{name: "Bearer token", identity: access.Identity{Method: access.AuthMethodBearer, Trusted: true, Name: "m1", Fingerprint: "abcd"}},
+		{name: "Untrusted", identity: access.Identity{Method: access.AuthMethodNone}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.allowed, allowsNotification(c.identity))
+		})
+	}
+}
+
+// Ensures that under maintenance, only notifications from cluster members bypass the drain of the consumer's endpoints.
+func TestIsDrained(t *testing.T) {
+	member := access.Identity{Method: access.AuthMethodTLS, Trusted: true, Name: "m1", Fingerprint: "abcd"}
+	trustedClient := access.Identity{Method: access.AuthMethodTLS, Trusted: true, Fingerprint: "abcd"}
+	untrusted := access.Identity{Method: access.AuthMethodNone}
+
+	cases := []struct {
+		name     string
+		version  string
+		notify   bool
+		unix     bool
+		identity access.Identity
+		drained  bool
+	}{
+		{name: "Consumer endpoint", version: "1.0", identity: trustedClient, drained: true},
+		{name: "Core endpoint", version: string(internalTypes.PublicEndpoint), identity: trustedClient},
+		{name: "Control socket", version: "1.0", unix: true, identity: access.Identity{Method: access.AuthMethodUnix, Trusted: true}},
+		{name: "Notification from a cluster member", version: "1.0", notify: true, identity: member},
+		{name: "Request from a cluster member", version: "1.0", identity: member, drained: true},
+		{name: "Notification from a trusted client", version: "1.0", notify: true, identity: trustedClient, drained: true},
+		{name: "Notification from an untrusted caller", version: "1.0", notify: true, identity: untrusted, drained: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/"+c.version+"/resource", nil)
+			if c.unix {
+				r.RemoteAddr = "@"
+			}
+
+			if c.notify {
+				r.Header.Set("User-Agent", clusterRequest.UserAgentNotifier)
+			}
+
+			require.Equal(t, c.drained, isDrained(c.version, r, c.identity))
+		})
+	}
+}
+
+// Ensures trusted clients are only trusted on the public endpoints, and revoked certificates are never trusted.
+func TestTrustedCertificates(t *testing.T) {
+	memberCert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	clientCert, err := shared.TestingAltKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	memberFingerprint := shared.CertFingerprint(memberCert)
+	clientFingerprint := shared.CertFingerprint(clientCert)
+
+	address, err := types.ParseAddrPort("10.0.0.1:9000")
+	require.NoError(t, err)
+
+	remotes := trust.NewRemotes(&memBackend{})
+	err = remotes.Add(trust.Remote{Location: trust.Location{Name: "m1", Address: address}, Certificate: types.X509Certificate{Certificate: memberCert}})
+	require.NoError(t, err)
+
+	clients := &trust.Clients{}
+	clients.Replace(types.X509Certificate{Certificate: clientCert})
+
+	fingerprints := func(version string, revoked *trust.Revocations) []string {
+		result := []string{}
+		for fingerprint := range trustedCertificates(version, remotes, clients, revoked) {
+			result = append(result, fingerprint)
+		}
+
+		return result
+	}
+
+	public := string(internalTypes.PublicEndpoint)
+	internal := string(internalTypes.InternalEndpoint)
+
+	require.ElementsMatch(t, []string{memberFingerprint, clientFingerprint}, fingerprints(public, nil))
+	require.ElementsMatch(t, []string{memberFingerprint}, fingerprints(internal, nil))
+
+	revoked := &trust.Revocations{}
+	revoked.Replace(clientFingerprint)
+	require.ElementsMatch(t, []string{memberFingerprint}, fingerprints(public, revoked))
+
+	revoked.Replace(memberFingerprint)
+	require.ElementsMatch(t, []string{clientFingerprint}, fingerprints(public, revoked))
+	require.Empty(t, fingerprints(internal, revoked))
+
+	// Lifting a revocation restores the trust.
+	revoked.Replace()
+	require.ElementsMatch(t, []string{memberFingerprint, clientFingerprint}, fingerprints(public, revoked))
+}
@@ -9,6 +9,18 @@ type Control struct {
 	Bootstrap  bool              `json:"bootstrap" yaml:"bootstrap"`
 	InitConfig map[string]string `json:"config" yaml:"config"`
 	JoinToken  string            `json:"join_token" yaml:"join_token"`
-	Address    types.AddrPort    `json:"address" yaml:"address"`
 	Name       string            `json:"name" yaml:"name"`
+
+	// Address is advertised to the other cluster members, and must be reachable by them.
+	// If unset when bootstrapping, the member only listens on a free loopback port until it is published on a
+	// network address, and cannot issue join tokens until then.
+	Address types.AddrPort `json:"address" yaml:"address"`
+
+	// ListenAddress is the address the API listens on, such as "[::]:9000" to listen on all interfaces.
+	// If unset, the API listens on Address. If its port is 0, the port of Address is used.
+	ListenAddress types.AddrPort `json:"listen_address" yaml:"listen_address"`
+
+	// DNSName, if set, resolves to Address. Other cluster members look it up again when they fail to reach the
+	// member, so that the cluster survives changes of address.
+	DNSName string `json:"dns_name" yaml:"dns_name"`
 }
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/canonical/microcluster/v3/rest/types"
 )
 
@@ -13,4 +15,14 @@ type HeartbeatInfo struct {
 	ClusterMembers    map[string]types.ClusterMember `json:"cluster_members"     yaml:"cluster_members"`
 	LeaderAddress     string                         `json:"leader_address"      yaml:"leader_address"`
 	DqliteRoles       map[string]string              `json:"dqlite_roles"        yaml:"dqlite_roles"`
+
+	// TrustedClientsDigest identifies the trusted client certificates of the leader. Cluster members only reload their
+	// own when they differ, as changes are also sent to them as notifications.
+	TrustedClientsDigest string `json:"trusted_clients_digest" yaml:"trusted_clients_digest"`
+}
+
+// HeartbeatResponse is the response of a cluster member to a heartbeat sent by the leader.
+type HeartbeatResponse struct {
+	// Time is the time of the cluster member when it handled the heartbeat, used to measure its clock skew.
+	Time time.Time `json:"time" yaml:"time"`
 }
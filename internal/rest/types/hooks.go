@@ -26,12 +26,19 @@ const (
 	// PreRemove is run on a cluster member just before it is removed from the cluster.
 	PreRemove HookType = "pre-remove"
 
+	// PreRemovePeer is run on all other peers just before one is removed from the cluster.
+	PreRemovePeer HookType = "pre-remove-peer"
+
 	// PostRemove is run on all other peers after one is removed from the cluster.
 	PostRemove HookType = "post-remove"
 
 	// OnNewMember is run on each peer after a new cluster member has joined and executed their 'PreJoin' hook.
 	OnNewMember HookType = "on-new-member"
 
+	// OnMemberRemoved is run on each remaining peer after a cluster member has been removed and their 'PostRemove'
+	// hook has run.
+	OnMemberRemoved HookType = "on-member-removed"
+
 	// OnHeartbeat is run after a successful heartbeat round.
 	OnHeartbeat HookType = "on-heartbeat"
 
@@ -39,10 +46,14 @@ const (
 	OnDaemonConfigUpdate HookType = "on-daemon-config-update"
 )
 
-// HookRemoveMemberOptions holds configuration pertaining to the PreRemove and PostRemove hooks.
+// HookRemoveMemberOptions holds configuration pertaining to the PreRemove, PreRemovePeer, PostRemove and
+// OnMemberRemoved hooks.
 type HookRemoveMemberOptions struct {
 	// Force represents whether to run the hook with the `force` option.
 	Force bool `json:"force" yaml:"force"`
+
+	// Member is the cluster member being removed. It is only set for the PreRemovePeer and OnMemberRemoved hooks.
+	Member types.ClusterMemberLocal `json:"member" yaml:"member"`
 }
 
 // HookNewMemberOptions holds configuration pertaining to the OnNewMember hook.
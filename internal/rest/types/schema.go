@@ -0,0 +1,7 @@
+package types
+
+// SchemaRollback represents the arguments for rolling back external schema updates.
+type SchemaRollback struct {
+	// Version is the external schema version to roll back to.
+	Version uint64 `json:"version" yaml:"version"`
+}
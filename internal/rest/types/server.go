@@ -12,15 +12,37 @@ type Server struct {
 	Version    string                `json:"version" yaml:"version"`
 	Ready      bool                  `json:"ready"   yaml:"ready"`
 	Extensions extensions.Extensions `json:"extensions" yaml:"extensions"`
+
+	// Members holds the status of every cluster member as probed by this member.
+	// It is only included for trusted requests once the database is online.
+	Members []types.MemberHealth `json:"members,omitempty" yaml:"members,omitempty"`
+
+	// Warnings holds the warnings about every cluster member that have not been acknowledged.
+	// It is only included for trusted requests once the database is online.
+	Warnings []types.Warning `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+
+	// Certificates holds the expiry of the certificates loaded by this member.
+	// It is only included for trusted requests.
+	Certificates []types.CertificateExpiry `json:"certificates,omitempty" yaml:"certificates,omitempty"`
 }
 
 const (
+	// CoreEndpoint - Lists the versions of the internally managed APIs served by the daemon.
+	CoreEndpoint types.EndpointPrefix = "core"
+
 	// PublicEndpoint - Internally managed APIs.
 	PublicEndpoint types.EndpointPrefix = "core/1.0"
 
+	// PublicEndpointV2 - Version 2.0 of the internally managed APIs, served alongside version 1.0.
+	PublicEndpointV2 types.EndpointPrefix = "core/2.0"
+
 	// InternalEndpoint - All internal endpoints restricted to trusted servers.
 	InternalEndpoint types.EndpointPrefix = "core/internal"
 
 	// ControlEndpoint - All internal endpoints available on the local unix socket.
 	ControlEndpoint types.EndpointPrefix = "core/control"
 )
+
+// PublicEndpointVersions are the versions of the internally managed APIs served by the daemon and supported by its
+// clients, from the oldest to the newest.
+var PublicEndpointVersions = []types.EndpointPrefix{PublicEndpoint, PublicEndpointV2}
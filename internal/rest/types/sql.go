@@ -10,6 +10,19 @@ type SQLQuery struct {
 	Query string `json:"query" yaml:"query"`
 }
 
+// SQLStatement represents a single SQL statement with its bind parameters.
+type SQLStatement struct {
+	Query string `json:"query" yaml:"query"`
+
+	// Args are bound to the placeholders of the query, in order. They may be strings, numbers, booleans or null.
+	Args []any `json:"args" yaml:"args"`
+}
+
+// SQLTransaction represents an ordered list of SQL statements executed in a single transaction.
+type SQLTransaction struct {
+	Statements []SQLStatement `json:"statements" yaml:"statements"`
+}
+
 // SQLBatch represents a batch of SQL results.
 type SQLBatch struct {
 	Results []SQLResult
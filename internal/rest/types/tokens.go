@@ -1,8 +1,16 @@
 package types
 
 import (
+	"crypto"
+	"crypto/rand"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"net/netip"
 	"time"
 
 	"github.com/canonical/microcluster/v3/rest/types"
@@ -12,13 +20,29 @@ import (
 type TokenRequest struct {
 	Name        string        `json:"name" yaml:"name"`
 	ExpireAfter time.Duration `json:"expire_after" yaml:"expire_after"`
+
+	// MaxJoins is the number of systems that may join with the token. Tokens that allow more than one join are not
+	// tied to the name of the joiner. It defaults to 1.
+	MaxJoins int `json:"max_joins" yaml:"max_joins"`
+
+	// AllowedSubnets restricts the token to joiners whose address belongs to one of the given CIDR subnets.
+	AllowedSubnets []string `json:"allowed_subnets" yaml:"allowed_subnets"`
+
+	// Rejoin issues a token for the existing cluster member with the given name, so that it can rejoin with the same
+	// name, address and dqlite ID after being reinstalled, instead of being removed and added as a new member.
+	// Rejoin tokens can only be used once.
+	Rejoin bool `json:"rejoin" yaml:"rejoin"`
 }
 
 // TokenRecord represents the internal record of a join token.
 type TokenRecord struct {
-	Name      string    `json:"name" yaml:"name"`
-	Token     string    `json:"token" yaml:"token"`
-	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+	Name           string    `json:"name" yaml:"name"`
+	Token          string    `json:"token" yaml:"token"`
+	ExpiresAt      time.Time `json:"expires_at" yaml:"expires_at"`
+	MaxJoins       int       `json:"max_joins" yaml:"max_joins"`
+	Joins          int       `json:"joins" yaml:"joins"`
+	AllowedSubnets []string  `json:"allowed_subnets" yaml:"allowed_subnets"`
+	Rejoin         bool      `json:"rejoin" yaml:"rejoin"`
 }
 
 // TokenResponse holds the information for connecting to a cluster by a node with a valid join token.
@@ -42,10 +66,46 @@ type TokenResponse struct {
 	// The trusted member will have already recorded the joiner's information in
 	// its local truststore, and thus will trust requests from the joiner prior to fully joining.
 	TrustedMember types.ClusterMemberLocal `json:"trusted_member" yaml:"trusted_member"`
+
+	// Rejoin is set if the joiner rejoins with the identity of an existing cluster member, using a rejoin token.
+	Rejoin *RejoinInfo `json:"rejoin,omitempty" yaml:"rejoin,omitempty"`
+}
+
+// RejoinInfo holds the dqlite identity of a reinstalled cluster member that rejoins the cluster.
+type RejoinInfo struct {
+	// DqliteID is the dqlite ID of the cluster member, which the joiner keeps.
+	DqliteID uint64 `json:"dqlite_id" yaml:"dqlite_id"`
+
+	// DqliteNodes is the dqlite raft configuration, so that the joiner can find the dqlite leader without being
+	// added to it again.
+	DqliteNodes []DqliteNode `json:"dqlite_nodes" yaml:"dqlite_nodes"`
+}
+
+// DqliteNode is a member of the dqlite raft configuration.
+type DqliteNode struct {
+	ID      uint64 `json:"id" yaml:"id"`
+	Address string `json:"address" yaml:"address"`
+	Role    string `json:"role" yaml:"role"`
 }
 
+// TokenVersion is the version of the join token format issued by this version of MicroCluster.
+// Tokens without a version are of the original format, which only holds the secret, fingerprint and join addresses.
+// Tokens of a later version than TokenVersion are rejected, as they may rely on checks this version does not perform.
+// Version 2 tokens may fingerprint the cluster certificate with another hash than SHA-256.
+const TokenVersion = 2
+
+// tokenSecretSize is the number of random bytes of the secret of a join token.
+const tokenSecretSize = 64
+
+// TokenFingerprintHashes are the hashes that may fingerprint the cluster certificate in a join token.
+var TokenFingerprintHashes = []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512}
+
 // Token holds the information that is presented to the joining node when requesting a token.
 type Token struct {
+	// Version is the version of the token format. Tokens are issued with the lowest version supporting their fields,
+	// so that older joiners can use them where possible.
+	Version int `json:"version,omitempty" yaml:"version,omitempty"`
+
 	// Secret is the underlying secret string used to authenticate the token.
 	Secret string `json:"secret" yaml:"secret"`
 
@@ -53,12 +113,98 @@ type Token struct {
 	// so that the joiner can verify that the public key of the cluster matches this request.
 	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
 
+	// FingerprintHash is the name of the hash of the fingerprint, such as "SHA-384". It defaults to SHA-256.
+	FingerprintHash string `json:"fingerprint_hash,omitempty" yaml:"fingerprint_hash,omitempty"`
+
 	// JoinAddresses is the list of addresses of the existing cluster members that the joiner may supply the token to.
 	// Internally, the first system to accept the token will forward it to the dqlite leader.
 	JoinAddresses []types.AddrPort `json:"join_addresses" yaml:"join_addresses"`
+
+	// IssuedAt is when the token was issued.
+	IssuedAt *time.Time `json:"issued_at,omitempty" yaml:"issued_at,omitempty"`
+
+	// ExpiresAt is when the token expires, if it does.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+
+	// AllowedSubnets restricts the token to joiners whose address belongs to one of the given CIDR subnets.
+	AllowedSubnets []string `json:"allowed_subnets,omitempty" yaml:"allowed_subnets,omitempty"`
+
+	// Project is the name of the project running the cluster, such as "microceph", so that a token is not used to
+	// join a cluster of another project. Clusters of the same project are told apart by the fingerprint.
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// NewTokenSecret returns a random secret for a join token.
+func NewTokenSecret() (string, error) {
+	buf := make([]byte, tokenSecretSize)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate join token secret: %w", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// parseFingerprintHash returns the hash with the given name, which defaults to SHA-256.
+func parseFingerprintHash(name string) (crypto.Hash, error) {
+	if name == "" {
+		return crypto.SHA256, nil
+	}
+
+	for _, hash := range TokenFingerprintHashes {
+		if hash.String() == name {
+			return hash, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unsupported join token fingerprint hash %q", name)
+}
+
+// SetFingerprint sets the fingerprint of the cluster certificate with the given hash, which defaults to SHA-256.
+func (t *Token) SetFingerprint(cert *x509.Certificate, hash crypto.Hash) error {
+	if hash == 0 {
+		hash = crypto.SHA256
+	}
+
+	_, err := parseFingerprintHash(hash.String())
+	if err != nil {
+		return err
+	}
+
+	t.Fingerprint = certificateFingerprint(cert, hash)
+	t.FingerprintHash = ""
+	if hash != crypto.SHA256 {
+		t.FingerprintHash = hash.String()
+	}
+
+	return nil
+}
+
+// MatchesCertificate returns whether the fingerprint of the token is that of the given cluster certificate.
+func (t Token) MatchesCertificate(cert *x509.Certificate) (bool, error) {
+	hash, err := parseFingerprintHash(t.FingerprintHash)
+	if err != nil {
+		return false, err
+	}
+
+	return certificateFingerprint(cert, hash) == t.Fingerprint, nil
 }
 
+// certificateFingerprint returns the hex-encoded digest of the certificate with the given hash.
+func certificateFingerprint(cert *x509.Certificate, hash crypto.Hash) string {
+	h := hash.New()
+	_, _ = h.Write(cert.Raw)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// String encodes the token as a base64 string. The version is set to the lowest one supporting the token's fields.
 func (t Token) String() (string, error) {
+	t.Version = 1
+	if t.FingerprintHash != "" {
+		t.Version = 2
+	}
+
 	tokenData, err := json.Marshal(t)
 	if err != nil {
 		return "", err
@@ -68,17 +214,65 @@ func (t Token) String() (string, error) {
 }
 
 // DecodeToken decodes a base64-encoded token string.
+// Tokens of an unknown version, or fingerprinted with an unsupported hash, are rejected.
 func DecodeToken(tokenString string) (*Token, error) {
 	tokenData, err := base64.StdEncoding.DecodeString(tokenString)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Invalid join token: %w", err)
 	}
 
 	var token Token
 	err = json.Unmarshal(tokenData, &token)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid join token: %w", err)
+	}
+
+	if token.Version < 0 || token.Version > TokenVersion {
+		return nil, fmt.Errorf("Join token version %d is not supported, the latest supported version is %d", token.Version, TokenVersion)
+	}
+
+	_, err = parseFingerprintHash(token.FingerprintHash)
 	if err != nil {
 		return nil, err
 	}
 
 	return &token, nil
 }
+
+// Check returns an error if the token cannot be used by a joiner with the given address, for the given project, at the
+// given time. The cluster member receiving the token performs the same checks, so this only lets the joiner fail
+// early with a clear error.
+func (t Token) Check(address types.AddrPort, project string, now time.Time) error {
+	if t.ExpiresAt != nil && t.ExpiresAt.Before(now) {
+		return fmt.Errorf("%w at %s", types.ErrTokenExpired, t.ExpiresAt.Format(time.RFC3339))
+	}
+
+	if t.Project != "" && project != "" && t.Project != project {
+		return fmt.Errorf("Join token was issued by a cluster of project %q, not %q", t.Project, project)
+	}
+
+	if len(t.AllowedSubnets) == 0 {
+		return nil
+	}
+
+	for _, subnet := range t.AllowedSubnets {
+		prefix, err := netip.ParsePrefix(subnet)
+		if err != nil {
+			return fmt.Errorf("Invalid join token subnet %q: %w", subnet, err)
+		}
+
+		if prefix.Contains(address.Addr().Unmap()) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Join token does not allow joining from address %q", address.Addr().String())
+}
+
+// JoinPreflightResponse holds the outcome of the checks run by an existing cluster member for a prospective member.
+type JoinPreflightResponse struct {
+	Checks []types.JoinPreflightCheck `json:"checks" yaml:"checks"`
+
+	// Time is the time of the cluster member when it ran the checks, used to detect clock skew.
+	Time time.Time `json:"time" yaml:"time"`
+}
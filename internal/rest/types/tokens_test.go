@@ -0,0 +1,159 @@
+package types
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures join token secrets are long random strings.
+func TestNewTokenSecret(t *testing.T) {
+	secret, err := NewTokenSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 2*tokenSecretSize)
+
+	other, err := NewTokenSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret, other)
+}
+
+// Ensures tokens are issued with the lowest version supporting their fields, and that tokens of unknown versions or
+// with an unsupported fingerprint hash are rejected.
+func TestDecodeToken(t *testing.T) {
+	cert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	encode := func(json string) string {
+		return base64.StdEncoding.EncodeToString([]byte(json))
+	}
+
+	for _, hash := range []crypto.Hash{0, crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		token := Token{Secret: "secret", Project: "microcluster"}
+		require.NoError(t, token.SetFingerprint(cert, hash))
+
+		tokenString, err := token.String()
+		require.NoError(t, err)
+
+		decoded, err := DecodeToken(tokenString)
+		require.NoError(t, err)
+		require.Equal(t, "secret", decoded.Secret)
+		require.Equal(t, "microcluster", decoded.Project)
+
+		// Tokens fingerprinted with SHA-256 remain usable by joiners that only support version 1.
+		if hash == 0 || hash == crypto.SHA256 {
+			require.Equal(t, 1, decoded.Version)
+			require.Equal(t, shared.CertFingerprint(cert), decoded.Fingerprint)
+		} else {
+			require.Equal(t, 2, decoded.Version)
+			require.Equal(t, hash.String(), decoded.FingerprintHash)
+		}
+
+		matches, err := decoded.MatchesCertificate(cert)
+		require.NoError(t, err)
+		require.True(t, matches)
+	}
+
+	require.Error(t, (&Token{}).SetFingerprint(cert, crypto.MD5))
+
+	cases := []struct {
+		name      string
+		token     string
+		expectErr bool
+	}{
+		{name: "Original format", token: encode(`{"secret": "secret", "fingerprint": "abc", "join_addresses": ["10.0.0.1:9000"]}`)},
+		{name: "Latest version", token: encode(`{"version": 2, "secret": "secret", "fingerprint": "abc", "fingerprint_hash": "SHA-512"}`)},
+		{name: "Unknown version", token: encode(`{"version": 3, "secret": "secret"}`), expectErr: true},
+		{name: "Negative version", token: encode(`{"version": -1, "secret": "secret"}`), expectErr: true},
+		{name: "Unsupported fingerprint hash", token: encode(`{"version": 2, "secret": "secret", "fingerprint_hash": "MD5"}`), expectErr: true},
+		{name: "Invalid encoding", token: "not a token", expectErr: true},
+		{name: "Invalid content", token: encode(`["secret"]`), expectErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := DecodeToken(c.token)
+			if c.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// Ensures the fingerprint of a token only matches the certificate it was computed from.
+func TestTokenMatchesCertificate(t *testing.T) {
+	cert, err := shared.TestingKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	other, err := shared.TestingAltKeyPair().PublicKeyX509()
+	require.NoError(t, err)
+
+	for _, hash := range []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		token := Token{}
+		require.NoError(t, token.SetFingerprint(cert, hash))
+
+		for c, expected := range map[*x509.Certificate]bool{cert: true, other: false} {
+			matches, err := token.MatchesCertificate(c)
+			require.NoError(t, err)
+			require.Equal(t, expected, matches)
+		}
+	}
+
+	// The fingerprint is not compared if its hash is unknown.
+	_, err = Token{Fingerprint: shared.CertFingerprint(cert), FingerprintHash: "MD5"}.MatchesCertificate(cert)
+	require.Error(t, err)
+}
+
+// Ensures the checks of a token that the joiner can perform before sending it to the cluster.
+func TestTokenCheck(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	cases := []struct {
+		name      string
+		token     Token
+		address   string
+		project   string
+		expectErr bool
+	}{
+		{name: "No restrictions", token: Token{}, address: "10.0.0.1:9000", project: "microcluster"},
+		{name: "Not expired", token: Token{ExpiresAt: &future}, address: "10.0.0.1:9000"},
+		{name: "Expired", token: Token{ExpiresAt: &past}, address: "10.0.0.1:9000", expectErr: true},
+		{name: "Same project", token: Token{Project: "microcluster"}, address: "10.0.0.1:9000", project: "microcluster"},
+		{name: "Other project", token: Token{Project: "microceph"}, address: "10.0.0.1:9000", project: "microovn", expectErr: true},
+		{name: "Token without project", token: Token{}, address: "10.0.0.1:9000", project: "microovn"},
+		{name: "Allowed subnet", token: Token{AllowedSubnets: []string{"192.168.0.0/16", "10.0.0.0/24"}}, address: "10.0.0.1:9000"},
+		{name: "IPv4-mapped address in allowed subnet", token: Token{AllowedSubnets: []string{"10.0.0.0/24"}}, address: "[::ffff:10.0.0.1]:9000"},
+		{name: "Address outside allowed subnets", token: Token{AllowedSubnets: []string{"10.0.0.0/24"}}, address: "10.0.1.1:9000", expectErr: true},
+		{name: "Allowed IPv6 subnet", token: Token{AllowedSubnets: []string{"fd00::/64"}}, address: "[fd00::1]:9000"},
+		{name: "Invalid subnet", token: Token{AllowedSubnets: []string{"10.0.0.0"}}, address: "10.0.0.1:9000", expectErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			address, err := types.ParseAddrPort(c.address)
+			require.NoError(t, err)
+
+			err = c.token.Check(address, c.project, now)
+			if c.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	// Expiry errors can be told apart from other errors.
+	address, err := types.ParseAddrPort("10.0.0.1:9000")
+	require.NoError(t, err)
+	require.ErrorIs(t, Token{ExpiresAt: &past}.Check(address, "", now), types.ErrTokenExpired)
+}
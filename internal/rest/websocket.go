@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/gorilla/websocket"
+
+	"github.com/canonical/microcluster/v3/internal/rest/client"
+	"github.com/canonical/microcluster/v3/rest"
+)
+
+// websocketProxyResponse relays a websocket between the caller and the target cluster member of a forwarded request.
+type websocketProxyResponse struct {
+	response.Response
+
+	target *websocket.Conn
+}
+
+// Render upgrades the caller's connection and relays messages until either side closes its websocket.
+// The target's websocket is closed even if the caller's connection could not be upgraded.
+func (p *websocketProxyResponse) Render(w http.ResponseWriter) error {
+	defer p.target.Close()
+
+	return p.Response.Render(w)
+}
+
+// proxyWebsocket dials the websocket of the target cluster member before upgrading the caller's connection,
+// so that the caller receives the target's error if the target refuses the upgrade.
+func proxyWebsocket(r *http.Request, c *client.Client, targetURL *api.URL) response.Response {
+	targetConn, err := c.DialWebsocket(r.Context(), targetURL)
+	if err != nil {
+		return rest.SmartError(fmt.Errorf("Failed to forward websocket to %q: %w", targetURL.URL.Host, err))
+	}
+
+	return &websocketProxyResponse{
+		Response: rest.WebsocketResponse(r, func(conn *websocket.Conn) error {
+			return mirrorWebsocket(conn, targetConn)
+		}),
+		target: targetConn,
+	}
+}
+
+// mirrorWebsocket relays messages in both directions between two websockets until either side closes.
+// The close status received from one side is passed on to the other.
+func mirrorWebsocket(a *websocket.Conn, b *websocket.Conn) error {
+	errCh := make(chan error, 2)
+	relay := func(dst *websocket.Conn, src *websocket.Conn) {
+		for {
+			msgType, data, err := src.ReadMessage()
+			if err != nil {
+				closeErr := &websocket.CloseError{}
+				if errors.As(err, &closeErr) {
+					err = nil
+					if closeErr.Code != websocket.CloseAbnormalClosure {
+						_ = dst.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeErr.Code, closeErr.Text), time.Now().Add(5*time.Second))
+					}
+				}
+
+				errCh <- err
+				return
+			}
+
+			err = dst.WriteMessage(msgType, data)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+
+	go relay(a, b)
+	go relay(b, a)
+
+	// Close both websockets once either side is done, to unblock the other direction.
+	err := <-errCh
+	_ = a.Close()
+	_ = b.Close()
+	<-errCh
+
+	return err
+}
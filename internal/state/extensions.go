@@ -0,0 +1,66 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sort"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/extensions"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// ClusterExtensions returns the API extensions supported by every cluster member, and the members that lack some of
+// the extensions supported by other members. Pending cluster members are not considered.
+func (s *InternalState) ClusterExtensions(ctx context.Context) (*types.ClusterExtensions, error) {
+	status := s.Database().Status()
+	if status != types.DatabaseReady && status != types.DatabaseWaiting {
+		return nil, api.StatusErrorf(http.StatusServiceUnavailable, "%s", string(status))
+	}
+
+	registries := map[string]extensions.Extensions{}
+	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		var clusterMembers []cluster.CoreClusterMember
+		if status == types.DatabaseReady {
+			clusterMembers, err = cluster.GetCoreClusterMembers(ctx, tx)
+		} else {
+			schemaInternal, schemaExternal, apiExtensions := s.Database().SchemaVersion()
+			clusterMembers, _, err = cluster.GetUpgradingClusterMembers(ctx, tx, schemaInternal, schemaExternal, apiExtensions)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, clusterMember := range clusterMembers {
+			if clusterMember.Role != cluster.Pending {
+				registries[clusterMember.Name] = clusterMember.APIExtensions
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	supported, missing := extensions.Negotiate(registries)
+	clusterExtensions := &types.ClusterExtensions{
+		Supported: supported,
+		Lagging:   make([]types.MemberExtensions, 0, len(missing)),
+	}
+
+	for name, memberMissing := range missing {
+		clusterExtensions.Lagging = append(clusterExtensions.Lagging, types.MemberExtensions{Name: name, Missing: memberMissing})
+	}
+
+	sort.Slice(clusterExtensions.Lagging, func(i, j int) bool {
+		return clusterExtensions.Lagging[i].Name < clusterExtensions.Lagging[j].Name
+	})
+
+	return clusterExtensions, nil
+}
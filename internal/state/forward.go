@@ -0,0 +1,121 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/gorilla/websocket"
+
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+)
+
+// ForwardToMember sends the request to the cluster member with the given name, and returns the member's response.
+// The method, path, query, headers and body of the request are preserved, so the request must be forwarded before its
+// body is read. If the given name is the local member, nil is returned and the request should be handled locally.
+func (s *InternalState) ForwardToMember(r *http.Request, name string) response.Response {
+	if name == s.Name() {
+		return nil
+	}
+
+	remote, ok := s.Remotes().RemotesByName()[name]
+	if !ok {
+		return response.NotFound(fmt.Errorf("No cluster member found with name %q", name))
+	}
+
+	return s.forward(r, remote.Address.String())
+}
+
+// ForwardToLeader sends the request to the dqlite leader, and returns the leader's response.
+// The method, path, query, headers and body of the request are preserved, so the request must be forwarded before its
+// body is read. If the local member is the leader, nil is returned and the request should be handled locally.
+func (s *InternalState) ForwardToLeader(r *http.Request) response.Response {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	leaderClient, err := s.Database().Leader(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	defer func() { _ = leaderClient.Close() }()
+
+	leaderInfo, err := leaderClient.Leader(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if leaderInfo.Address == s.Address().URL.Host {
+		return nil
+	}
+
+	return s.forward(r, leaderInfo.Address)
+}
+
+// forward sends a copy of the request to the cluster member at the given address.
+func (s *InternalState) forward(r *http.Request, address string) response.Response {
+	if websocket.IsWebSocketUpgrade(r) {
+		return response.BadRequest(fmt.Errorf("Websocket requests cannot be forwarded"))
+	}
+
+	publicKey, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return response.InternalError(fmt.Errorf("Failed to parse cluster certificate for request: %w", err))
+	}
+
+	url := api.NewURL().Scheme("https").Host(address)
+	c, err := internalClient.New(*url, s.ServerCert(), publicKey, false)
+	if err != nil {
+		return response.InternalError(fmt.Errorf("Failed to get a client for address %q: %w", address, err))
+	}
+
+	return forwardRequest(r, c)
+}
+
+// forwardRequest sends a copy of the request with the client, and returns the response as received.
+func forwardRequest(r *http.Request, c *internalClient.Client) response.Response {
+	url := c.URL()
+	req := r.Clone(r.Context())
+	req.RequestURI = ""
+	req.URL.Scheme = url.URL.Scheme
+	req.URL.Host = url.URL.Host
+	req.Host = url.URL.Host
+
+	resp, err := c.MakeRawRequest(req)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to forward request to %q: %w", url.URL.Host, err))
+	}
+
+	return &forwardedResponse{resp: resp}
+}
+
+// forwardedResponse relays the response of a cluster member to a forwarded request, including its status code and
+// headers, such as the error code of exported errors.
+type forwardedResponse struct {
+	resp *http.Response
+}
+
+// Render writes the response of the cluster member.
+func (f *forwardedResponse) Render(w http.ResponseWriter) error {
+	defer func() { _ = f.resp.Body.Close() }()
+
+	for key, values := range f.resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	w.WriteHeader(f.resp.StatusCode)
+	_, err := io.Copy(w, f.resp.Body)
+
+	return err
+}
+
+// String returns the status of the response of the cluster member.
+func (f *forwardedResponse) String() string {
+	return f.resp.Status
+}
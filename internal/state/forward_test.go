@@ -0,0 +1,82 @@
+package state
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures forwarded requests reach the cluster member as sent, and that its response is relayed as received.
+func TestForwardRequest(t *testing.T) {
+	var received *http.Request
+	var receivedBody string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = r
+		receivedBody = string(body)
+
+		switch r.URL.Path {
+		case "/1.0/missing":
+			w.Header().Set(types.ErrorCodeHeader, types.ErrorCode(types.ErrMemberNotFound))
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"type": "error", "error_code": 404, "error": "Cluster member not found"}`))
+		case "/1.0/async":
+			w.Header().Set("Location", "/1.0/operations/1")
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"type": "async", "status": "Operation created", "status_code": 100, "operation": "/1.0/operations/1"}`))
+		default:
+			_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200, "metadata": {"name": "n1"}}`))
+		}
+	}))
+	defer server.Close()
+
+	url := api.NewURL().Scheme("https").Host(server.Listener.Addr().String())
+	c, err := internalClient.New(*url, shared.TestingKeyPair(), server.Certificate(), false)
+	require.NoError(t, err)
+
+	forward := func(method string, path string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "https://127.0.0.1:9000"+path, strings.NewReader(body))
+		r.Header.Set("X-Tenant", "tenant")
+
+		recorder := httptest.NewRecorder()
+		require.NoError(t, forwardRequest(r, c).Render(recorder))
+
+		return recorder
+	}
+
+	recorder := forward(http.MethodPut, "/1.0/services?force=1", `{"name": "n1"}`)
+	require.Equal(t, http.MethodPut, received.Method)
+	require.Equal(t, "/1.0/services", received.URL.Path)
+	require.Equal(t, "force=1", received.URL.RawQuery)
+	require.Equal(t, "tenant", received.Header.Get("X-Tenant"))
+	require.Equal(t, `{"name": "n1"}`, receivedBody)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"type": "sync", "status": "Success", "status_code": 200, "metadata": {"name": "n1"}}`, recorder.Body.String())
+
+	// Errors keep their status code, and the error code identifying them.
+	recorder = forward(http.MethodGet, "/1.0/missing", "")
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Equal(t, types.ErrorCode(types.ErrMemberNotFound), recorder.Header().Get(types.ErrorCodeHeader))
+	require.Contains(t, recorder.Body.String(), "Cluster member not found")
+
+	// Asynchronous responses are not turned into synchronous ones.
+	recorder = forward(http.MethodPost, "/1.0/async", "")
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	require.Equal(t, "/1.0/operations/1", recorder.Header().Get("Location"))
+	require.Contains(t, recorder.Body.String(), `"type": "async"`)
+
+	// Cluster members that can't be reached result in an error.
+	server.Close()
+	recorder = forward(http.MethodGet, "/1.0/services", "")
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "Failed to forward request")
+}
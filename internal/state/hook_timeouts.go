@@ -0,0 +1,157 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// defaultHookGracePeriod is how long a hook is waited for after its context is cancelled, unless configured.
+const defaultHookGracePeriod = 5 * time.Second
+
+// HookTimeouts configures how long hooks may run before they are abandoned.
+type HookTimeouts struct {
+	// Default is the timeout of every hook without a timeout of its own. Unset means no deadline.
+	Default time.Duration
+
+	// Hooks holds the timeouts of individual hooks, keyed by the name of the hook, such as "PreJoin".
+	Hooks map[string]time.Duration
+
+	// GracePeriod is how long a hook is still waited for once its context is cancelled, so that hooks observing their
+	// context can return before the daemon reverts what they were doing. It defaults to 5 seconds.
+	GracePeriod time.Duration
+}
+
+// Validate checks that the timeouts are not negative and apply to existing hooks. OnStart cannot have a timeout, as
+// its context is only cancelled when the daemon shuts down.
+func (t HookTimeouts) Validate() error {
+	if t.Default < 0 {
+		return fmt.Errorf("Hook timeout must not be negative")
+	}
+
+	if t.GracePeriod < 0 {
+		return fmt.Errorf("Hook grace period must not be negative")
+	}
+
+	for name, timeout := range t.Hooks {
+		_, ok := reflect.TypeOf(Hooks{}).FieldByName(name)
+		if !ok {
+			return fmt.Errorf("Unknown hook %q", name)
+		}
+
+		if name == "OnStart" {
+			return fmt.Errorf("Hook %q cannot have a timeout", name)
+		}
+
+		if timeout < 0 {
+			return fmt.Errorf("Timeout of hook %q must not be negative", name)
+		}
+	}
+
+	return nil
+}
+
+// timeout returns the timeout of the named hook.
+func (t HookTimeouts) timeout(name string) time.Duration {
+	timeout, ok := t.Hooks[name]
+	if ok {
+		return timeout
+	}
+
+	return t.Default
+}
+
+// contextType and errorType are the types of the first argument and of the result of every hook.
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// WithTimeouts returns a copy of the hooks where each hook runs with a context cancelled after its timeout.
+// A hook that has not returned by then is waited for during the grace period, and then abandoned, failing with an
+// error wrapping types.ErrHookTimeout, so that a hanging hook cannot block the daemon forever. OnStart is left without
+// a deadline.
+// Every hook is wrapped the same way, so it must take a context as its first argument and only return an error.
+func (h Hooks) WithTimeouts(timeouts HookTimeouts) Hooks {
+	gracePeriod := timeouts.GracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = defaultHookGracePeriod
+	}
+
+	hooks := reflect.ValueOf(&h).Elem()
+	for i := 0; i < hooks.NumField(); i++ {
+		name := hooks.Type().Field(i).Name
+		field := hooks.Field(i)
+		if name == "OnStart" || field.IsNil() {
+			continue
+		}
+
+		hook := reflect.ValueOf(field.Interface())
+		timeout := timeouts.timeout(name)
+		field.Set(reflect.MakeFunc(field.Type(), func(args []reflect.Value) []reflect.Value {
+			ctx := args[0].Interface().(context.Context)
+			err := runWithTimeout(ctx, name, timeout, gracePeriod, func(ctx context.Context) error {
+				hookArgs := slices.Clone(args)
+				hookArgs[0] = reflect.ValueOf(&ctx).Elem()
+				err, _ := hook.Call(hookArgs)[0].Interface().(error)
+
+				return err
+			})
+
+			result := reflect.New(errorType).Elem()
+			if err != nil {
+				result.Set(reflect.ValueOf(err))
+			}
+
+			return []reflect.Value{result}
+		}))
+	}
+
+	return h
+}
+
+// runWithTimeout runs the named hook with a context cancelled after the timeout. Unset timeouts mean no deadline.
+// Once the context is done, the hook is waited for during the grace period. Hooks that ignore their context are then
+// abandoned: their goroutine keeps running in the background, possibly while the caller reverts what the hook was
+// doing, until the hook eventually returns.
+func runWithTimeout(ctx context.Context, name string, timeout time.Duration, gracePeriod time.Duration, run func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return run(ctx)
+	}
+
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- run(hookCtx) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-hookCtx.Done():
+	}
+
+	// Give the hook a chance to observe the cancellation of its context.
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-timer.C:
+		logger.Error("Abandoning hook that did not return after its context was cancelled", logger.Ctx{"hook": name, "grace_period": gracePeriod})
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("Hook %q was cancelled: %w", name, errors.Join(ctx.Err(), err))
+	}
+
+	return fmt.Errorf("%w: %q did not complete within %s", types.ErrHookTimeout, name, timeout)
+}
@@ -0,0 +1,128 @@
+package state
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures hooks that outlive their timeout are abandoned with an identifiable error, while OnStart has no deadline.
+func TestHooksWithTimeouts(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+
+	hooks := Hooks{
+		PreJoin: func(ctx context.Context, s State, initConfig map[string]string) error {
+			<-hang
+
+			return nil
+		},
+		PostJoin: func(ctx context.Context, s State, initConfig map[string]string) error {
+			<-ctx.Done()
+
+			return ctx.Err()
+		},
+		PreRemove: func(ctx context.Context, s State, force bool) error {
+			return nil
+		},
+		OnStart: func(ctx context.Context, s State) error {
+			_, ok := ctx.Deadline()
+			require.False(t, ok)
+
+			return nil
+		},
+	}.WithTimeouts(HookTimeouts{Default: time.Hour, Hooks: map[string]time.Duration{"PreJoin": 10 * time.Millisecond, "PostJoin": 10 * time.Millisecond}, GracePeriod: 10 * time.Millisecond})
+
+	// The hook ignores its context, and is abandoned after the grace period.
+	err := hooks.PreJoin(context.Background(), nil, nil)
+	require.ErrorIs(t, err, types.ErrHookTimeout)
+
+	err = hooks.PostJoin(context.Background(), nil, nil)
+	require.ErrorIs(t, err, types.ErrHookTimeout)
+
+	require.NoError(t, hooks.PreRemove(context.Background(), nil, false))
+	require.NoError(t, hooks.OnStart(context.Background(), nil))
+	require.Nil(t, hooks.PostBootstrap)
+
+	// Cancellation by the caller is not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = hooks.PostJoin(ctx, nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, types.ErrHookTimeout)
+}
+
+// Ensures hooks observing their context are waited for once it is cancelled, so that they have returned before the
+// timeout is reported.
+func TestHooksWithTimeoutsGracePeriod(t *testing.T) {
+	returned := false
+	hooks := Hooks{
+		PostJoin: func(ctx context.Context, s State, initConfig map[string]string) error {
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			returned = true
+
+			return ctx.Err()
+		},
+	}.WithTimeouts(HookTimeouts{Default: 10 * time.Millisecond, GracePeriod: time.Minute})
+
+	err := hooks.PostJoin(context.Background(), nil, nil)
+	require.ErrorIs(t, err, types.ErrHookTimeout)
+	require.True(t, returned)
+}
+
+// Ensures every hook other than OnStart gets a timeout, including hooks added after the timeouts were written.
+func TestHooksWithTimeoutsAllHooks(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+
+	var hooks Hooks
+	fields := reflect.ValueOf(&hooks).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		require.Equal(t, reflect.Func, field.Kind(), "Hook %q is not a function", fields.Type().Field(i).Name)
+
+		field.Set(reflect.MakeFunc(field.Type(), func(args []reflect.Value) []reflect.Value {
+			<-hang
+
+			return []reflect.Value{reflect.Zero(errorType)}
+		}))
+	}
+
+	hooks = hooks.WithTimeouts(HookTimeouts{Default: time.Millisecond, GracePeriod: time.Millisecond})
+	fields = reflect.ValueOf(hooks)
+	for i := 0; i < fields.NumField(); i++ {
+		name := fields.Type().Field(i).Name
+		if name == "OnStart" {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			hook := fields.Field(i)
+			args := []reflect.Value{reflect.ValueOf(context.Background())}
+			for j := 1; j < hook.Type().NumIn(); j++ {
+				args = append(args, reflect.Zero(hook.Type().In(j)))
+			}
+
+			err, _ := hook.Call(args)[0].Interface().(error)
+			require.ErrorIs(t, err, types.ErrHookTimeout)
+		})
+	}
+}
+
+// Ensures hook timeouts must be positive and apply to hooks that can have a deadline.
+func TestHookTimeoutsValidate(t *testing.T) {
+	require.NoError(t, HookTimeouts{}.Validate())
+	require.NoError(t, HookTimeouts{Default: time.Minute, Hooks: map[string]time.Duration{"PreInit": time.Second}}.Validate())
+
+	require.Error(t, HookTimeouts{Default: -time.Second}.Validate())
+	require.Error(t, HookTimeouts{GracePeriod: -time.Second}.Validate())
+	require.Error(t, HookTimeouts{Hooks: map[string]time.Duration{"PreInit": -time.Second}}.Validate())
+	require.Error(t, HookTimeouts{Hooks: map[string]time.Duration{"Unknown": time.Second}}.Validate())
+	require.Error(t, HookTimeouts{Hooks: map[string]time.Duration{"OnStart": time.Second}}.Validate())
+}
@@ -1,7 +1,11 @@
 package state
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/canonical/microcluster/v3/rest/types"
 )
@@ -18,6 +22,10 @@ type Hooks struct {
 	// OnStart is run after the daemon is started. Its context will not be cancelled until the daemon is shutting down.
 	OnStart func(ctx context.Context, s State) error
 
+	// OnShutdown is run when a started daemon begins shutting down, while it still serves API requests
+	// and before its database roles are handed off to other cluster members.
+	OnShutdown func(ctx context.Context, s State) error
+
 	// PostJoin is run after the daemon is initialized, joined the cluster and existing members triggered
 	// their 'OnNewMember' hooks.
 	PostJoin func(ctx context.Context, s State, initConfig map[string]string) error
@@ -26,18 +34,300 @@ type Hooks struct {
 	// their 'OnNewMember' hooks.
 	PreJoin func(ctx context.Context, s State, initConfig map[string]string) error
 
+	// OnJoinRequest is run on the cluster member handling a join request, after the join token has been validated
+	// but before the joining system is recorded. Returning an error refuses the join.
+	OnJoinRequest func(ctx context.Context, s State, joiner types.ClusterMemberLocal, initConfig map[string]string) error
+
 	// PreRemove is run on a cluster member just before it is removed from the cluster.
 	PreRemove func(ctx context.Context, s State, force bool) error
 
+	// PreRemovePeer is run on all other peers just before one is removed from the cluster. Unlike PreRemove, it also
+	// runs when a member that cannot be reached is forcefully removed.
+	PreRemovePeer func(ctx context.Context, s State, member types.ClusterMemberLocal, force bool) error
+
 	// PostRemove is run on all other peers after one is removed from the cluster.
 	PostRemove func(ctx context.Context, s State, force bool) error
 
 	// OnHeartbeat is run after a successful heartbeat round.
 	OnHeartbeat func(ctx context.Context, s State, roleStatus map[string]types.RoleStatus) error
 
+	// OnClusterHealth is run on the dqlite leader after each heartbeat round, with the roles, last heartbeats and
+	// statuses of the cluster members, so that changes in the cluster topology can be reacted to without polling.
+	OnClusterHealth func(ctx context.Context, s State, health types.ClusterHealth) error
+
 	// OnNewMember is run on each peer after a new cluster member has joined and executed their 'PreJoin' hook.
 	OnNewMember func(ctx context.Context, s State, newMember types.ClusterMemberLocal) error
 
+	// OnMemberRemoved is run on each remaining peer after a cluster member has been removed and their 'PostRemove'
+	// hook has run, so that references to the removed member, such as firewall rules, can be cleaned up.
+	OnMemberRemoved func(ctx context.Context, s State, member types.ClusterMemberLocal, force bool) error
+
+	// OnMemberRename is run on all cluster members after a cluster member has been renamed, so that references to
+	// the old name can be updated.
+	OnMemberRename func(ctx context.Context, s State, oldName string, newName string) error
+
+	// OnUpgradeStage is run on a cluster member whenever it moves to a new stage of a coordinated upgrade, such as when
+	// it starts waiting for other cluster members to upgrade, and when the upgrade is committed.
+	OnUpgradeStage func(ctx context.Context, s State, stage types.UpgradeStage) error
+
+	// OnDqliteLeadershipChange is run on a cluster member whenever it gains or loses dqlite leadership.
+	// isLeader reports whether this member is now the leader. The name and address of the new leader are empty if
+	// no leader has been elected yet.
+	OnDqliteLeadershipChange func(ctx context.Context, s State, isLeader bool, leaderName string, leaderAddress types.AddrPort) error
+
+	// OnCertificateRotated is run on all cluster members after a coordinated certificate rotation has been committed
+	// and the new certificate has been loaded. When a cluster member renews its server certificate, it is only run on
+	// that cluster member.
+	OnCertificateRotated func(ctx context.Context, s State, name types.CertificateName, fingerprint string) error
+
+	// OnTableChange is run on every cluster member with the rows changed in the tables registered with
+	// cluster.TrackChanges, in the order of the changes. Changes made while the daemon is not running are not
+	// delivered. If unset, the changes of tracked tables are still recorded, but not delivered.
+	OnTableChange func(ctx context.Context, s State, changes []types.TableChange) error
+
 	// OnDaemonConfigUpdate is a post-action hook that is run on all cluster members when any cluster member receives a local configuration update.
 	OnDaemonConfigUpdate func(ctx context.Context, s State, config types.DaemonConfig) error
 }
+
+// HookHandler is a named set of hooks registered by a single subsystem, such as an extension.
+// Any hook left unset is skipped for the handler.
+type HookHandler struct {
+	// Name identifies the handler in the errors returned by its hooks. It must be unique.
+	Name string
+
+	// Priority orders the handlers run for each event, lowest first. Handlers with the same priority run in
+	// order of their names.
+	Priority int
+
+	// Hooks are the functions run by the handler.
+	Hooks Hooks
+}
+
+// MergeHooks combines the hooks of each handler into a single set of hooks.
+// For each event, the hook of every handler is run in order of priority, even if a previous handler failed.
+// The errors of all failed handlers are joined together, each prefixed with the name of its handler.
+func MergeHooks(handlers ...HookHandler) (*Hooks, error) {
+	names := make(map[string]bool, len(handlers))
+	for _, handler := range handlers {
+		if handler.Name == "" {
+			return nil, fmt.Errorf("Hook handler name must not be empty")
+		}
+
+		if names[handler.Name] {
+			return nil, fmt.Errorf("Duplicate hook handler %q", handler.Name)
+		}
+
+		names[handler.Name] = true
+	}
+
+	sorted := slices.Clone(handlers)
+	slices.SortFunc(sorted, func(a HookHandler, b HookHandler) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.Name, b.Name))
+	})
+
+	// Changes of tracked tables are only delivered if a hook consumes them, so leave it unset if no handler has one.
+	var onTableChange func(ctx context.Context, s State, changes []types.TableChange) error
+	for _, handler := range handlers {
+		if handler.Hooks.OnTableChange != nil {
+			onTableChange = func(ctx context.Context, s State, changes []types.TableChange) error {
+				return runHookHandlers(sorted, func(h Hooks) error {
+					if h.OnTableChange == nil {
+						return nil
+					}
+
+					return h.OnTableChange(ctx, s, changes)
+				})
+			}
+
+			break
+		}
+	}
+
+	return &Hooks{
+		PreInit: func(ctx context.Context, s State, bootstrap bool, initConfig map[string]string) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.PreInit == nil {
+					return nil
+				}
+
+				return h.PreInit(ctx, s, bootstrap, initConfig)
+			})
+		},
+		PostBootstrap: func(ctx context.Context, s State, initConfig map[string]string) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.PostBootstrap == nil {
+					return nil
+				}
+
+				return h.PostBootstrap(ctx, s, initConfig)
+			})
+		},
+		OnStart: func(ctx context.Context, s State) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnStart == nil {
+					return nil
+				}
+
+				return h.OnStart(ctx, s)
+			})
+		},
+		OnShutdown: func(ctx context.Context, s State) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnShutdown == nil {
+					return nil
+				}
+
+				return h.OnShutdown(ctx, s)
+			})
+		},
+		PostJoin: func(ctx context.Context, s State, initConfig map[string]string) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.PostJoin == nil {
+					return nil
+				}
+
+				return h.PostJoin(ctx, s, initConfig)
+			})
+		},
+		PreJoin: func(ctx context.Context, s State, initConfig map[string]string) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.PreJoin == nil {
+					return nil
+				}
+
+				return h.PreJoin(ctx, s, initConfig)
+			})
+		},
+		OnJoinRequest: func(ctx context.Context, s State, joiner types.ClusterMemberLocal, initConfig map[string]string) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnJoinRequest == nil {
+					return nil
+				}
+
+				return h.OnJoinRequest(ctx, s, joiner, initConfig)
+			})
+		},
+		PreRemove: func(ctx context.Context, s State, force bool) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.PreRemove == nil {
+					return nil
+				}
+
+				return h.PreRemove(ctx, s, force)
+			})
+		},
+		PreRemovePeer: func(ctx context.Context, s State, member types.ClusterMemberLocal, force bool) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.PreRemovePeer == nil {
+					return nil
+				}
+
+				return h.PreRemovePeer(ctx, s, member, force)
+			})
+		},
+		PostRemove: func(ctx context.Context, s State, force bool) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.PostRemove == nil {
+					return nil
+				}
+
+				return h.PostRemove(ctx, s, force)
+			})
+		},
+		OnHeartbeat: func(ctx context.Context, s State, roleStatus map[string]types.RoleStatus) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnHeartbeat == nil {
+					return nil
+				}
+
+				return h.OnHeartbeat(ctx, s, roleStatus)
+			})
+		},
+		OnClusterHealth: func(ctx context.Context, s State, health types.ClusterHealth) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnClusterHealth == nil {
+					return nil
+				}
+
+				return h.OnClusterHealth(ctx, s, health)
+			})
+		},
+		OnNewMember: func(ctx context.Context, s State, newMember types.ClusterMemberLocal) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnNewMember == nil {
+					return nil
+				}
+
+				return h.OnNewMember(ctx, s, newMember)
+			})
+		},
+		OnMemberRemoved: func(ctx context.Context, s State, member types.ClusterMemberLocal, force bool) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnMemberRemoved == nil {
+					return nil
+				}
+
+				return h.OnMemberRemoved(ctx, s, member, force)
+			})
+		},
+		OnMemberRename: func(ctx context.Context, s State, oldName string, newName string) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnMemberRename == nil {
+					return nil
+				}
+
+				return h.OnMemberRename(ctx, s, oldName, newName)
+			})
+		},
+		OnUpgradeStage: func(ctx context.Context, s State, stage types.UpgradeStage) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnUpgradeStage == nil {
+					return nil
+				}
+
+				return h.OnUpgradeStage(ctx, s, stage)
+			})
+		},
+		OnDqliteLeadershipChange: func(ctx context.Context, s State, isLeader bool, leaderName string, leaderAddress types.AddrPort) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnDqliteLeadershipChange == nil {
+					return nil
+				}
+
+				return h.OnDqliteLeadershipChange(ctx, s, isLeader, leaderName, leaderAddress)
+			})
+		},
+		OnCertificateRotated: func(ctx context.Context, s State, name types.CertificateName, fingerprint string) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnCertificateRotated == nil {
+					return nil
+				}
+
+				return h.OnCertificateRotated(ctx, s, name, fingerprint)
+			})
+		},
+		OnTableChange: onTableChange,
+		OnDaemonConfigUpdate: func(ctx context.Context, s State, config types.DaemonConfig) error {
+			return runHookHandlers(sorted, func(h Hooks) error {
+				if h.OnDaemonConfigUpdate == nil {
+					return nil
+				}
+
+				return h.OnDaemonConfigUpdate(ctx, s, config)
+			})
+		},
+	}, nil
+}
+
+// runHookHandlers runs the hook of each handler in order, and joins the errors of the handlers that failed.
+func runHookHandlers(handlers []HookHandler, run func(h Hooks) error) error {
+	var errs []error
+	for _, handler := range handlers {
+		err := run(handler.Hooks)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Hook handler %q failed: %w", handler.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
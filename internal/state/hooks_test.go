@@ -0,0 +1,61 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures merged hooks run every handler in order of priority and name, and report the errors of all failed handlers.
+func TestMergeHooks(t *testing.T) {
+	var order []string
+	record := func(name string, err error) func(ctx context.Context, s State) error {
+		return func(ctx context.Context, s State) error {
+			order = append(order, name)
+
+			return err
+		}
+	}
+
+	hooks, err := MergeHooks(
+		HookHandler{Name: "late", Priority: 10, Hooks: Hooks{OnStart: record("late", nil)}},
+		HookHandler{Name: "b", Hooks: Hooks{OnStart: record("b", errors.New("b failed"))}},
+		HookHandler{Name: "unset"},
+		HookHandler{Name: "a", Hooks: Hooks{OnStart: record("a", nil)}},
+		HookHandler{Name: "early", Priority: -1, Hooks: Hooks{OnStart: record("early", errors.New("early failed"))}},
+	)
+	require.NoError(t, err)
+
+	err = hooks.OnStart(context.Background(), nil)
+	require.EqualError(t, err, "Hook handler \"early\" failed: early failed\nHook handler \"b\" failed: b failed")
+	require.Equal(t, []string{"early", "a", "b", "late"}, order)
+
+	// Events without any registered hook succeed.
+	require.NoError(t, hooks.PreRemove(context.Background(), nil, false))
+
+	// Changes of tracked tables are only delivered if a handler consumes them.
+	require.Nil(t, hooks.OnTableChange)
+
+	var changes []types.TableChange
+	hooks, err = MergeHooks(
+		HookHandler{Name: "a"},
+		HookHandler{Name: "b", Hooks: Hooks{OnTableChange: func(ctx context.Context, s State, c []types.TableChange) error {
+			changes = append(changes, c...)
+
+			return nil
+		}}},
+	)
+	require.NoError(t, err)
+	require.NoError(t, hooks.OnTableChange(context.Background(), nil, []types.TableChange{{ID: 1, Table: "services"}}))
+	require.Equal(t, []types.TableChange{{ID: 1, Table: "services"}}, changes)
+
+	_, err = MergeHooks(HookHandler{Name: "a"}, HookHandler{Name: "a"})
+	require.Error(t, err)
+
+	_, err = MergeHooks(HookHandler{})
+	require.Error(t, err)
+}
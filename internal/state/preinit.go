@@ -0,0 +1,92 @@
+package state
+
+import (
+	"context"
+
+	"github.com/canonical/lxd/shared"
+
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// PreInitState is the part of the daemon state that can be used before the daemon is initialized, such as by
+// extension endpoints with AllowedBeforeInit set that implement their own initialization or preseed APIs.
+type PreInitState interface {
+	// FileSystem structure.
+	FileSystem() *sys.OS
+
+	// Version is provided by the MicroCluster consumer.
+	Version() string
+
+	// Server certificate is used for server-to-server connection.
+	ServerCert() *shared.CertInfo
+
+	// Initialized returns whether the daemon has bootstrapped or joined a cluster.
+	Initialized() bool
+
+	// Bootstrap initializes the daemon as the first member of a new cluster.
+	Bootstrap(ctx context.Context, name string, address types.AddrPort, initConfig map[string]string) error
+
+	// Join initializes the daemon by joining an existing cluster with a join token.
+	Join(ctx context.Context, name string, address types.AddrPort, token string, initConfig map[string]string) error
+}
+
+// preInitState implements PreInitState over the daemon state.
+type preInitState struct {
+	state *InternalState
+}
+
+// ToPreInit returns the part of the given State that can be used before the daemon is initialized.
+func ToPreInit(s State) (PreInitState, error) {
+	intState, err := ToInternal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return preInitState{state: intState}, nil
+}
+
+// FileSystem can be used to inspect the microcluster filesystem.
+func (s preInitState) FileSystem() *sys.OS {
+	return s.state.FileSystem()
+}
+
+// Version is provided by the MicroCluster consumer.
+func (s preInitState) Version() string {
+	return s.state.Version()
+}
+
+// ServerCert returns the keypair identifying the local system.
+func (s preInitState) ServerCert() *shared.CertInfo {
+	return s.state.ServerCert()
+}
+
+// Initialized returns whether the daemon has bootstrapped or joined a cluster.
+func (s preInitState) Initialized() bool {
+	return s.state.Database().Status() != types.DatabaseNotReady
+}
+
+// Bootstrap initializes the daemon as the first member of a new cluster, in the same way as a request to the control
+// endpoint of the unix socket.
+func (s preInitState) Bootstrap(ctx context.Context, name string, address types.AddrPort, initConfig map[string]string) error {
+	return s.control(ctx, internalTypes.Control{Bootstrap: true, Name: name, Address: address, InitConfig: initConfig})
+}
+
+// Join initializes the daemon by joining an existing cluster with a join token, in the same way as a request to the
+// control endpoint of the unix socket.
+func (s preInitState) Join(ctx context.Context, name string, address types.AddrPort, token string, initConfig map[string]string) error {
+	return s.control(ctx, internalTypes.Control{JoinToken: token, Name: name, Address: address, InitConfig: initConfig})
+}
+
+// control sends the control request to the daemon over its unix socket, so that it is validated and handled like any
+// other initialization request.
+func (s preInitState) control(ctx context.Context, args internalTypes.Control) error {
+	c, err := internalClient.New(s.state.FileSystem().ControlSocket(), nil, nil, false)
+	if err != nil {
+		return err
+	}
+
+	return c.ControlDaemon(ctx, args)
+}
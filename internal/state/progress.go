@@ -0,0 +1,58 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// initProgressBuffer is how many stages are buffered for a subscriber before further stages are dropped.
+const initProgressBuffer = 16
+
+// InitProgress broadcasts the stages reached while the daemon bootstraps or joins a cluster to its subscribers.
+type InitProgress struct {
+	mu          sync.Mutex
+	subscribers map[chan types.InitProgress]struct{}
+}
+
+// Report sends the stage to every subscriber. Stages are dropped for subscribers that are not keeping up, so that
+// initialization is never blocked on them.
+func (p *InitProgress) Report(stage types.InitStage, message string) {
+	if p == nil {
+		return
+	}
+
+	progress := types.InitProgress{Stage: stage, Message: message, Time: time.Now()}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for ch := range p.subscribers {
+		select {
+		case ch <- progress:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the stages reported from now on, and a function to stop receiving them.
+func (p *InitProgress) Subscribe() (<-chan types.InitProgress, func()) {
+	ch := make(chan types.InitProgress, initProgressBuffer)
+
+	p.mu.Lock()
+	if p.subscribers == nil {
+		p.subscribers = map[chan types.InitProgress]struct{}{}
+	}
+
+	p.subscribers[ch] = struct{}{}
+	p.mu.Unlock()
+
+	unsubscribe := func() {
+		p.mu.Lock()
+		delete(p.subscribers, ch)
+		p.mu.Unlock()
+	}
+
+	return ch, unsubscribe
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures reported stages reach every subscriber in order, and that subscribers which are not keeping up or have
+// unsubscribed never block the report.
+func TestInitProgress(t *testing.T) {
+	// Reporting without progress tracking or subscribers does nothing.
+	var unset *InitProgress
+	unset.Report(types.InitStageValidating, "Validating")
+
+	progress := &InitProgress{}
+	progress.Report(types.InitStageValidating, "Validating")
+
+	first, unsubscribeFirst := progress.Subscribe()
+	second, unsubscribeSecond := progress.Subscribe()
+	defer unsubscribeSecond()
+
+	progress.Report(types.InitStageDatabase, "Starting the database")
+	progress.Report(types.InitStageComplete, "Done")
+
+	for _, ch := range []<-chan types.InitProgress{first, second} {
+		for _, expected := range []types.InitStage{types.InitStageDatabase, types.InitStageComplete} {
+			stage := <-ch
+			require.Equal(t, expected, stage.Stage)
+			require.False(t, stage.Time.IsZero())
+		}
+	}
+
+	// Unsubscribed channels no longer receive stages.
+	unsubscribeFirst()
+	progress.Report(types.InitStageHooks, "Running hooks")
+	require.Len(t, first, 0)
+	require.Equal(t, types.InitStageHooks, (<-second).Stage)
+
+	// Stages are dropped for subscribers whose buffer is full.
+	for range initProgressBuffer + 1 {
+		progress.Report(types.InitStageHooks, "Running hooks")
+	}
+
+	require.Len(t, second, initProgressBuffer)
+}
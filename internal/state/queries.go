@@ -0,0 +1,55 @@
+package state
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+)
+
+// PreparedQuery is a named, parameterized database statement registered by the consumer, which clients can run
+// through /core/1.0/queries/{name} without being given access to arbitrary SQL.
+type PreparedQuery struct {
+	// Name identifies the query in the API.
+	Name string
+
+	// Description explains what the query reports or changes, for operator tooling listing the queries.
+	Description string
+
+	// Statement is the SQL statement run by the query. Arguments are bound to its "?" placeholders.
+	// Statements starting with SELECT return rows, while others return the number of affected rows.
+	Statement string
+
+	// Parameters are the names of the arguments of the query, in the order of the placeholders of the statement.
+	// Every argument must be supplied when running the query.
+	Parameters []string
+
+	// AccessHandler further restricts which authenticated clients can run the query, if set.
+	AccessHandler func(state State, r *http.Request) (trusted bool, resp response.Response)
+}
+
+// Validate checks that the query can be registered.
+func (q PreparedQuery) Validate() error {
+	if q.Name == "" {
+		return fmt.Errorf("Prepared query name cannot be empty")
+	}
+
+	if q.Statement == "" {
+		return fmt.Errorf("Prepared query %q has no statement", q.Name)
+	}
+
+	params := make(map[string]bool, len(q.Parameters))
+	for _, param := range q.Parameters {
+		if param == "" {
+			return fmt.Errorf("Prepared query %q has a parameter with no name", q.Name)
+		}
+
+		if params[param] {
+			return fmt.Errorf("Prepared query %q has duplicate parameter %q", q.Name, param)
+		}
+
+		params[param] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures prepared queries must be named, have a statement, and have uniquely named parameters.
+func TestPreparedQueryValidate(t *testing.T) {
+	require.NoError(t, PreparedQuery{Name: "members", Statement: "SELECT name FROM core_cluster_members"}.Validate())
+	require.NoError(t, PreparedQuery{Name: "member", Statement: "SELECT name FROM core_cluster_members WHERE name = ?", Parameters: []string{"name"}}.Validate())
+
+	require.Error(t, PreparedQuery{Statement: "SELECT 1"}.Validate())
+	require.Error(t, PreparedQuery{Name: "empty"}.Validate())
+	require.Error(t, PreparedQuery{Name: "unnamed", Statement: "SELECT ?", Parameters: []string{""}}.Validate())
+	require.Error(t, PreparedQuery{Name: "duplicate", Statement: "SELECT ?, ?", Parameters: []string{"a", "a"}}.Validate())
+}
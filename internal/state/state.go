@@ -2,19 +2,31 @@ package state
 
 import (
 	"context"
+	"crypto"
+	"database/sql"
 	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 
 	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/audit"
 	internalConfig "github.com/canonical/microcluster/v3/internal/config"
 	"github.com/canonical/microcluster/v3/internal/db"
 	"github.com/canonical/microcluster/v3/internal/endpoints"
 	"github.com/canonical/microcluster/v3/internal/extensions"
+	"github.com/canonical/microcluster/v3/internal/operations"
+	"github.com/canonical/microcluster/v3/internal/recover"
+	internalAccess "github.com/canonical/microcluster/v3/internal/rest/access"
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/tasks"
 	"github.com/canonical/microcluster/v3/internal/trust"
 	"github.com/canonical/microcluster/v3/rest/types"
 )
@@ -54,8 +66,32 @@ type State interface {
 	// HasExtension returns whether the given API extension is supported.
 	HasExtension(ext string) bool
 
+	// ClusterExtensions returns the API extensions supported by every cluster member, and the members lagging behind.
+	ClusterExtensions(ctx context.Context) (*types.ClusterExtensions, error)
+
 	// ExtensionServers returns an immutable list of the daemon's additional listeners.
 	ExtensionServers() []string
+
+	// Tasks returns the scheduler for background tasks.
+	Tasks() *tasks.Scheduler
+
+	// Operations returns the manager for asynchronous operations started by API requests.
+	Operations() *operations.Manager
+
+	// Lock acquires a cluster-wide lock with the given name, blocking until it is available or the context is cancelled.
+	Lock(ctx context.Context, name string) (*db.Lock, error)
+
+	// ForwardToMember sends the request to the named cluster member, or returns nil if it's the local member.
+	ForwardToMember(r *http.Request, name string) response.Response
+
+	// ForwardToLeader sends the request to the dqlite leader, or returns nil if it's the local member.
+	ForwardToLeader(r *http.Request) response.Response
+
+	// FileWarning records a warning about the local cluster member, or counts it again if it is already recorded.
+	FileWarning(ctx context.Context, warningType string, entity string, message string) error
+
+	// ResolveWarning removes the warning about the local cluster member with the given type and entity, if any.
+	ResolveWarning(ctx context.Context, warningType string, entity string) error
 }
 
 // InternalState is a gateway to the stateful components of the microcluster daemon.
@@ -66,6 +102,18 @@ type InternalState struct {
 	// Ready channel.
 	ReadyCh chan struct{}
 
+	// StartTime is when the daemon was started.
+	StartTime time.Time
+
+	// Project is the name of the project running MicroCluster.
+	Project string
+
+	// MaxClockSkew is the largest difference between the clocks of cluster members that is tolerated.
+	MaxClockSkew time.Duration
+
+	// TokenFingerprintHash is the hash fingerprinting the cluster certificate in issued join tokens.
+	TokenFingerprintHash crypto.Hash
+
 	// ShutdownDoneCh receives the result of the d.Stop() function and tells the daemon to end.
 	ShutdownDoneCh chan error
 
@@ -76,7 +124,11 @@ type InternalState struct {
 	LocalConfig func() *internalConfig.DaemonConfig
 
 	// SetConfig Applies and commits to memory the supplied daemon configuration.
-	SetConfig func(trust.Location) error
+	// The listen address is only set if the API should listen on a different address than the advertised one.
+	SetConfig func(config trust.Location, listenAddress types.AddrPort) error
+
+	// ReserveListener hands over a listener already bound to the address, which the API adopts when it starts.
+	ReserveListener func(listener net.Listener)
 
 	// Initialize APIs and bootstrap/join database.
 	StartAPI func(ctx context.Context, bootstrap bool, initConfig map[string]string, joinAddresses ...string) error
@@ -87,6 +139,15 @@ type InternalState struct {
 	// ReloadCert reloads the given keypair from the state directory.
 	ReloadCert func(name types.CertificateName) error
 
+	// CheckSocketAccess checks that the group and file mode of the control socket can be changed as given.
+	CheckSocketAccess func(config types.SocketConfig) error
+
+	// SetSocketAccess changes the group and file mode of the control socket.
+	SetSocketAccess func(config types.SocketConfig) error
+
+	// Certificates returns the expiry of the certificates loaded by the daemon.
+	Certificates func() ([]types.CertificateExpiry, error)
+
 	// StopListeners stops the network listeners and the fsnotify listener.
 	StopListeners func() error
 
@@ -102,6 +163,30 @@ type InternalState struct {
 	// Hooks contain external implementations that are triggered by specific cluster actions.
 	Hooks *Hooks
 
+	// ArchiveEncryption configures the encryption of database backups.
+	ArchiveEncryption recover.ArchiveEncryption
+
+	// AuditLog records mutating API requests, if enabled.
+	AuditLog *audit.Log
+
+	// TrustedClients holds the certificates of API clients trusted without being cluster members.
+	TrustedClients *trust.Clients
+
+	// RevokedCertificates holds the fingerprints of certificates rejected by the daemon, even if otherwise trusted.
+	RevokedCertificates *trust.Revocations
+
+	// Maintenance is set while the local cluster member is under maintenance.
+	Maintenance *atomic.Bool
+
+	// InitProgress reports the stages reached while the daemon bootstraps or joins a cluster.
+	InitProgress *InitProgress
+
+	// PreparedQueries are the database statements registered by the consumer, keyed by name.
+	PreparedQueries map[string]PreparedQuery
+
+	// ControlSocketPolicy decides whether requests received over the unix socket are allowed, if set.
+	ControlSocketPolicy func(r *http.Request, creds internalAccess.PeerCredentials) error
+
 	InternalFileSystem       func() *sys.OS
 	InternalAddress          func() *api.URL
 	InternalName             func() string
@@ -111,6 +196,8 @@ type InternalState struct {
 	InternalDatabase         *db.DqliteDB
 	InternalRemotes          func() *trust.Remotes
 	InternalExtensionServers func() []string
+	InternalTasks            *tasks.Scheduler
+	InternalOperations       *operations.Manager
 }
 
 // FileSystem can be used to inspect the microcluster filesystem.
@@ -159,7 +246,44 @@ func (s *InternalState) ExtensionServers() []string {
 	return s.InternalExtensionServers()
 }
 
-// HasExtension returns whether the given API extension is supported.
+// Tasks returns the scheduler for background tasks, which are cancelled when the daemon shuts down.
+func (s *InternalState) Tasks() *tasks.Scheduler {
+	return s.InternalTasks
+}
+
+// Operations returns the manager for asynchronous operations, which are cancelled when the daemon shuts down.
+func (s *InternalState) Operations() *operations.Manager {
+	return s.InternalOperations
+}
+
+// Lock acquires a cluster-wide lock with the given name, blocking until it is available or the context is cancelled.
+// The lock must be released with Unlock, and is lost if its lease cannot be renewed with the database in time.
+func (s *InternalState) Lock(ctx context.Context, name string) (*db.Lock, error) {
+	return s.InternalDatabase.Lock(ctx, name)
+}
+
+// FileWarning records a warning about the local cluster member, listed at /core/1.0/warnings. A warning is recorded
+// once per type and entity, such as the name of a certificate, and filing it again updates its message and counts it
+// again. The database must be online.
+func (s *InternalState) FileWarning(ctx context.Context, warningType string, entity string, message string) error {
+	if warningType == "" {
+		return fmt.Errorf("Warning type cannot be empty")
+	}
+
+	return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.FileCoreWarning(ctx, tx, s.Name(), warningType, entity, message, time.Now())
+	})
+}
+
+// ResolveWarning removes the warning about the local cluster member with the given type and entity, once the condition
+// it reports no longer applies. It is not an error if there is no such warning.
+func (s *InternalState) ResolveWarning(ctx context.Context, warningType string, entity string) error {
+	return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.ResolveCoreWarning(ctx, tx, s.Name(), warningType, entity)
+	})
+}
+
+// HasExtension returns whether the given API extension is supported by the local cluster member.
 func (s *InternalState) HasExtension(ext string) bool {
 	return s.Extensions.HasExtension(ext)
 }
@@ -1,10 +1,14 @@
 package sys
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
@@ -18,26 +22,94 @@ type OS struct {
 	DatabaseDir     string
 	TrustDir        string
 	CertificatesDir string
+	RuntimeDir      string
+	LogDir          string
 	LogFile         string
+
+	// ControlSocketName is the name of the control socket in the abstract namespace, if it is not a file.
+	ControlSocketName string
+}
+
+// Layout places the files of the daemon outside of the state directory, for instance to keep the database on a
+// separate volume, or the control socket in a tmpfs runtime directory when the root filesystem is read-only.
+// Each directory must be an absolute path, and defaults to a location within the state directory if unset.
+type Layout struct {
+	// DatabaseDir holds the database files managed by dqlite.
+	DatabaseDir string
+
+	// RuntimeDir holds the control socket.
+	RuntimeDir string
+
+	// LogDir holds the daemon log file and the audit log. The daemon starts logging to the log file if LogDir is set,
+	// and only to stderr otherwise, until the file log target is selected at runtime.
+	LogDir string
+
+	// ControlSocket, if set, is the name of the control socket in the abstract unix socket namespace, starting with
+	// "@", instead of a file in the RuntimeDir. Abstract sockets are only supported on Linux, vanish with the daemon
+	// and carry no file permissions, so access to them is scoped to the network namespace and the
+	// ControlSocketPolicy of the daemon, which must be set.
+	ControlSocket string
+}
+
+// Validate checks that each directory of the layout is an absolute path.
+func (l Layout) Validate() error {
+	dirs := []struct {
+		name string
+		path string
+	}{
+		{"database", l.DatabaseDir},
+		{"runtime", l.RuntimeDir},
+		{"log", l.LogDir},
+	}
+
+	for _, dir := range dirs {
+		if dir.path != "" && !filepath.IsAbs(dir.path) {
+			return fmt.Errorf("The %s directory %q must be an absolute path", dir.name, dir.path)
+		}
+	}
+
+	if l.ControlSocket != "" {
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("Abstract unix sockets are only supported on Linux")
+		}
+
+		if !IsAbstractSocket(l.ControlSocket) || len(l.ControlSocket) == 1 {
+			return fmt.Errorf("The control socket %q must be an abstract socket name starting with \"@\"", l.ControlSocket)
+		}
+	}
+
+	return nil
 }
 
 // DefaultOS returns a fresh uninitialized OS instance with default values.
 func DefaultOS(stateDir string, createDir bool) (*OS, error) {
+	return NewOS(stateDir, Layout{}, createDir)
+}
+
+// NewOS returns a fresh uninitialized OS instance, with its directories placed according to the layout.
+func NewOS(stateDir string, layout Layout, createDir bool) (*OS, error) {
 	if stateDir == "" {
 		stateDir = os.Getenv(StateDir)
 	}
 
-	// TODO: Configurable log file path.
+	err := layout.Validate()
+	if err != nil {
+		return nil, err
+	}
 
 	os := &OS{
 		StateDir:        stateDir,
-		DatabaseDir:     filepath.Join(stateDir, "database"),
+		DatabaseDir:     cmp.Or(layout.DatabaseDir, filepath.Join(stateDir, "database")),
 		TrustDir:        filepath.Join(stateDir, "truststore"),
 		CertificatesDir: filepath.Join(stateDir, "certificates"),
-		LogFile:         "",
+		RuntimeDir:      cmp.Or(layout.RuntimeDir, stateDir),
+		LogDir:          cmp.Or(layout.LogDir, stateDir),
+		LogFile:         filepath.Join(cmp.Or(layout.LogDir, stateDir), "daemon.log"),
+
+		ControlSocketName: layout.ControlSocket,
 	}
 
-	err := os.init(createDir)
+	err = os.init(createDir)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +126,8 @@ func (s *OS) init(createDir bool) error {
 		{s.DatabaseDir, 0700},
 		{s.TrustDir, 0700},
 		{s.CertificatesDir, 0700},
+		{s.RuntimeDir, 0711},
+		{s.LogDir, 0700},
 	}
 
 	for _, dir := range dirs {
@@ -87,6 +161,17 @@ func (s *OS) init(createDir bool) error {
 // accessible.
 func (s *OS) IsControlSocketPresent() (bool, error) {
 	socketPath := s.ControlSocketPath()
+
+	// Abstract sockets have no file, so they are only present while something listens on them.
+	if IsAbstractSocket(socketPath) {
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			return false, nil
+		}
+
+		return true, conn.Close()
+	}
+
 	_, err := os.Stat(socketPath)
 
 	if err == nil {
@@ -105,9 +190,33 @@ func (s *OS) ControlSocket() api.URL {
 	return *api.NewURL().Scheme("http").Host(s.ControlSocketPath())
 }
 
-// ControlSocketPath returns the filesystem path to the control socket.
+// ControlSocketPath returns the filesystem path to the control socket, or its name if it is an abstract socket.
 func (s *OS) ControlSocketPath() string {
-	return filepath.Join(s.StateDir, "control.socket")
+	if s.ControlSocketName != "" {
+		return s.ControlSocketName
+	}
+
+	return filepath.Join(s.RuntimeDir, "control.socket")
+}
+
+// ControlSecretPath returns the path of the file holding the shared secret of the control API over TCP, if enabled.
+func (s *OS) ControlSecretPath() string {
+	return filepath.Join(s.RuntimeDir, "control.secret")
+}
+
+// ControlSecret reads the shared secret of the control API over TCP.
+func (s *OS) ControlSecret() (string, error) {
+	secret, err := os.ReadFile(s.ControlSecretPath())
+	if err != nil {
+		return "", fmt.Errorf("Failed to read control secret: %w", err)
+	}
+
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// IsAbstractSocket returns whether the given unix socket address is a name in the abstract namespace.
+func IsAbstractSocket(address string) bool {
+	return strings.HasPrefix(address, "@")
 }
 
 // DatabasePath returns the path of the database file managed by dqlite.
@@ -115,6 +224,16 @@ func (s *OS) DatabasePath() string {
 	return filepath.Join(s.DatabaseDir, "db.bin")
 }
 
+// RecoveryTarballPath returns the path of the tarball used to recover the database after quorum loss.
+func (s *OS) RecoveryTarballPath() string {
+	return filepath.Join(s.StateDir, "recovery_db.tar.gz")
+}
+
+// AuditLogPath returns the path of the audit log of mutating API requests.
+func (s *OS) AuditLogPath() string {
+	return filepath.Join(s.LogDir, "audit.log")
+}
+
 // ServerCert gets the local server certificate from the state directory.
 func (s *OS) ServerCert() (*shared.CertInfo, error) {
 	if !shared.PathExists(filepath.Join(s.StateDir, "server.crt")) {
@@ -0,0 +1,28 @@
+package sys
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensures the directories of the layout default to the state directory, along with the log file.
+func TestNewOS(t *testing.T) {
+	stateDir := t.TempDir()
+	filesystem, err := NewOS(stateDir, Layout{}, true)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(stateDir, "database"), filesystem.DatabaseDir)
+	require.Equal(t, stateDir, filesystem.LogDir)
+	require.Equal(t, filepath.Join(stateDir, "daemon.log"), filesystem.LogFile)
+
+	layout := Layout{DatabaseDir: filepath.Join(t.TempDir(), "db"), LogDir: filepath.Join(t.TempDir(), "log")}
+	filesystem, err = NewOS(t.TempDir(), layout, true)
+	require.NoError(t, err)
+	require.Equal(t, layout.DatabaseDir, filesystem.DatabaseDir)
+	require.DirExists(t, layout.DatabaseDir)
+	require.Equal(t, filepath.Join(layout.LogDir, "daemon.log"), filesystem.LogFile)
+
+	_, err = NewOS(t.TempDir(), Layout{LogDir: "log"}, true)
+	require.Error(t, err)
+}
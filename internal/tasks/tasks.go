@@ -0,0 +1,215 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Task is a background job run by the Scheduler.
+type Task struct {
+	// Name uniquely identifies the task.
+	Name string
+
+	// Func is the job to run. Its context is cancelled when the task is removed or the daemon shuts down.
+	Func func(ctx context.Context) error
+
+	// Interval is the delay before each run of the task. For one-shot tasks, it is the delay before the only run.
+	Interval time.Duration
+
+	// Jitter adds a random delay of up to the given duration before each run, so that tasks registered at the same
+	// time on several cluster members do not all run at once.
+	Jitter time.Duration
+
+	// OneShot runs the task only once.
+	OneShot bool
+
+	// Scope determines which cluster members run the task. It defaults to types.TaskScopeMember.
+	Scope types.TaskScope
+}
+
+// entry is a task registered with the Scheduler, along with its status.
+type entry struct {
+	task   Task
+	cancel context.CancelFunc
+	status types.TaskStatus
+}
+
+// Scheduler runs background tasks until the daemon shuts down.
+type Scheduler struct {
+	ctx context.Context
+
+	// isLeader reports whether the local cluster member is currently the dqlite leader.
+	isLeader func(ctx context.Context) (bool, error)
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewScheduler returns a Scheduler whose tasks are cancelled along with the given context.
+func NewScheduler(ctx context.Context, isLeader func(ctx context.Context) (bool, error)) *Scheduler {
+	return &Scheduler{
+		ctx:      ctx,
+		isLeader: isLeader,
+		entries:  map[string]*entry{},
+	}
+}
+
+// Add registers a task and starts scheduling it. A finished one-shot task can be replaced by a task with the same name.
+func (s *Scheduler) Add(task Task) error {
+	if task.Name == "" {
+		return fmt.Errorf("Task name cannot be empty")
+	}
+
+	if task.Func == nil {
+		return fmt.Errorf("Task %q has no function", task.Name)
+	}
+
+	if task.Interval <= 0 && !task.OneShot {
+		return fmt.Errorf("Recurring task %q must have a positive interval", task.Name)
+	}
+
+	if task.Scope == "" {
+		task.Scope = types.TaskScopeMember
+	}
+
+	if task.Scope != types.TaskScopeLeader && task.Scope != types.TaskScopeMember {
+		return fmt.Errorf("Task %q has invalid scope %q", task.Name, task.Scope)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return fmt.Errorf("Cannot add task %q, the daemon is shutting down", task.Name)
+	}
+
+	existing, ok := s.entries[task.Name]
+	if ok && !existing.status.Done {
+		return fmt.Errorf("Task %q already exists", task.Name)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	e := &entry{
+		task:   task,
+		cancel: cancel,
+		status: types.TaskStatus{
+			Name:     task.Name,
+			Scope:    task.Scope,
+			Interval: task.Interval,
+			OneShot:  task.OneShot,
+		},
+	}
+
+	s.entries[task.Name] = e
+
+	go s.run(ctx, e)
+
+	return nil
+}
+
+// Remove cancels the task with the given name and forgets about it.
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[name]
+	if !ok {
+		return fmt.Errorf("Task %q does not exist", name)
+	}
+
+	e.cancel()
+	delete(s.entries, name)
+
+	return nil
+}
+
+// List returns the status of all registered tasks, sorted by name.
+func (s *Scheduler) List() []types.TaskStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]types.TaskStatus, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+
+	return statuses
+}
+
+// run schedules the task until it is done or its context is cancelled.
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+	for {
+		delay := e.task.Interval
+		if e.task.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(e.task.Jitter)))
+		}
+
+		s.mu.Lock()
+		e.status.NextRun = time.Now().Add(delay)
+		s.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.execute(ctx, e)
+
+		if e.task.OneShot {
+			s.mu.Lock()
+			e.status.Done = true
+			e.status.NextRun = time.Time{}
+			s.mu.Unlock()
+
+			return
+		}
+	}
+}
+
+// execute runs the task once, if the local cluster member is within its scope.
+func (s *Scheduler) execute(ctx context.Context, e *entry) {
+	if e.task.Scope == types.TaskScopeLeader {
+		leader, err := s.isLeader(ctx)
+		if err != nil {
+			logger.Debug("Skipping leader task, failed to determine the dqlite leader", logger.Ctx{"task": e.task.Name, "error": err})
+			return
+		}
+
+		if !leader {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	e.status.Running = true
+	e.status.LastRun = time.Now()
+	s.mu.Unlock()
+
+	err := e.task.Func(ctx)
+	if err != nil {
+		logger.Warn("Background task failed", logger.Ctx{"task": e.task.Name, "error": err})
+	}
+
+	s.mu.Lock()
+	e.status.Running = false
+	e.status.Runs++
+	e.status.LastError = ""
+	if err != nil {
+		e.status.LastError = err.Error()
+	}
+
+	s.mu.Unlock()
+}
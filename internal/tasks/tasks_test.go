@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+func TestSchedulerOneShot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewScheduler(ctx, func(ctx context.Context) (bool, error) { return true, nil })
+
+	ran := make(chan struct{})
+	err := s.Add(Task{Name: "once", OneShot: true, Func: func(ctx context.Context) error {
+		close(ran)
+		return errors.New("failed")
+	}})
+	require.NoError(t, err)
+
+	// Names are unique while the task is pending.
+	assert.Error(t, s.Add(Task{Name: "once", OneShot: true, Func: func(ctx context.Context) error { return nil }}))
+
+	<-ran
+	require.Eventually(t, func() bool { return s.List()[0].Done }, time.Second, time.Millisecond)
+
+	status := s.List()[0]
+	assert.Equal(t, uint64(1), status.Runs)
+	assert.Equal(t, "failed", status.LastError)
+	assert.Equal(t, types.TaskScopeMember, status.Scope)
+}
+
+func TestSchedulerLeaderScope(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewScheduler(ctx, func(ctx context.Context) (bool, error) { return false, nil })
+
+	err := s.Add(Task{Name: "leader", Interval: time.Millisecond, Scope: types.TaskScopeLeader, Func: func(ctx context.Context) error {
+		t.Error("Leader task ran on a non-leader")
+		return nil
+	}})
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, uint64(0), s.List()[0].Runs)
+
+	require.NoError(t, s.Remove("leader"))
+	assert.Empty(t, s.List())
+
+	// Tasks cannot be added once the daemon is shutting down.
+	cancel()
+	assert.Error(t, s.Add(Task{Name: "late", Interval: time.Second, Func: func(ctx context.Context) error { return nil }}))
+}
@@ -0,0 +1,80 @@
+// Package tracing creates OpenTelemetry spans for API requests, forwarded cluster requests and database access.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by microcluster.
+const instrumentationName = "github.com/canonical/microcluster/v3"
+
+// tracer returns the tracer of the global tracer provider. Like the global text map propagator used to propagate
+// spans, it is a no-op unless the application sets it with otel.SetTracerProvider.
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts an internal span as a child of any span in the context.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServerSpan starts a span for an incoming request, continuing the trace propagated in its headers.
+// The returned request carries the span in its context.
+func StartServerSpan(r *http.Request, route string) (*http.Request, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer().Start(ctx, r.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", r.URL.Path),
+			attribute.String("client.address", r.RemoteAddr),
+		),
+	)
+
+	return r.WithContext(ctx), span
+}
+
+// StartClientSpan starts a span for an outgoing request, and propagates it in the request headers.
+// The returned request carries the span in its context.
+func StartClientSpan(r *http.Request, name string) (*http.Request, trace.Span) {
+	ctx, span := tracer().Start(r.Context(), name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("server.address", r.URL.Host),
+			attribute.String("url.path", r.URL.Path),
+		),
+	)
+
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+
+	Inject(ctx, r.Header)
+
+	return r.WithContext(ctx), span
+}
+
+// Inject adds the trace context of ctx to the headers of an outgoing request.
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// End records the error, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,154 @@
+package trust
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/renameio"
+	"gopkg.in/yaml.v3"
+
+	"github.com/canonical/microcluster/v3/internal/sys"
+)
+
+// Backend stores the remotes of the truststore. By default, each remote is stored as a yaml file in the truststore
+// directory, but the remotes can instead be kept in other storage such as a hardware keystore or a secret manager.
+type Backend interface {
+	// Load returns every stored remote.
+	Load() ([]Remote, error)
+
+	// Add stores a new remote. It fails if a remote with the same name is already stored.
+	Add(remote Remote) error
+
+	// Replace stores the given remotes, and removes any other stored remote.
+	Replace(remotes []Remote) error
+
+	// Watch calls refresh whenever the stored remotes are changed outside of the daemon.
+	// Backends that can't detect such changes may ignore it.
+	Watch(refresh func() error)
+}
+
+// FileBackend stores each remote as a yaml file in a directory.
+type FileBackend struct {
+	dir     string
+	watcher *sys.Watcher
+}
+
+// NewFileBackend returns a Backend storing the remotes in the given directory.
+// If a watcher is given, changes to the directory will refresh the truststore.
+func NewFileBackend(dir string, watcher *sys.Watcher) *FileBackend {
+	return &FileBackend{dir: dir, watcher: watcher}
+}
+
+// Load reads any yaml files in the directory and parses them into a list of remotes.
+func (b *FileBackend) Load() ([]Remote, error) {
+	files, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read trust directory: %q: %w", b.dir, err)
+	}
+
+	remotes := make([]Remote, 0, len(files))
+	for _, file := range files {
+		fileName := file.Name()
+		if file.IsDir() || !strings.HasSuffix(fileName, ".yaml") {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(b.dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read file %q: %w", fileName, err)
+		}
+
+		remote := Remote{}
+		err = yaml.Unmarshal(content, &remote)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse yaml for %q: %w", fileName, err)
+		}
+
+		remotes = append(remotes, remote)
+	}
+
+	return remotes, nil
+}
+
+// Add writes the remote to a new yaml file in the directory.
+func (b *FileBackend) Add(remote Remote) error {
+	bytes, err := yaml.Marshal(remote)
+	if err != nil {
+		return fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
+	}
+
+	path := filepath.Join(b.dir, fmt.Sprintf("%s.yaml", remote.Name))
+	_, err = os.Stat(path)
+	if err == nil {
+		return fmt.Errorf("Remote at %q already exists", path)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Failed to check remote path %q: %w", path, err)
+	}
+
+	err = renameio.WriteFile(path, bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to write %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// Replace writes a yaml file in the directory for each remote, and removes any other file.
+func (b *FileBackend) Replace(remotes []Remote) error {
+	names := make(map[string]bool, len(remotes))
+	for _, remote := range remotes {
+		bytes, err := yaml.Marshal(remote)
+		if err != nil {
+			return fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
+		}
+
+		remotePath := filepath.Join(b.dir, fmt.Sprintf("%s.yaml", remote.Name))
+		err = renameio.WriteFile(remotePath, bytes, 0644)
+		if err != nil {
+			return fmt.Errorf("Failed to write %q: %w", remotePath, err)
+		}
+
+		names[remote.Name] = true
+	}
+
+	allEntries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+
+	// Remove any outdated entries.
+	for _, entry := range allEntries {
+		name, _, _ := strings.Cut(entry.Name(), ".yaml")
+		if !names[name] {
+			remotePath := filepath.Join(b.dir, fmt.Sprintf("%s.yaml", name))
+			err = os.Remove(remotePath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Watch calls refresh whenever a yaml file in the directory is changed, if the backend has a watcher.
+func (b *FileBackend) Watch(refresh func() error) {
+	if b.watcher == nil {
+		return
+	}
+
+	b.watcher.Watch(b.dir, "yaml", func(path string, event fsnotify.Op) error {
+		err := refresh()
+		if err != nil {
+			return fmt.Errorf("Unable to refresh remotes in path %q: %w", path, err)
+		}
+
+		return nil
+	})
+}
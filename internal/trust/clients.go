@@ -0,0 +1,62 @@
+package trust
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/canonical/lxd/shared"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Clients holds the certificates of API clients that are trusted without being cluster members.
+// The certificates are recorded in the database, and cached here so they can be checked on every request.
+type Clients struct {
+	data     map[string]types.X509Certificate
+	updateMu sync.RWMutex
+}
+
+// Replace replaces the set of trusted client certificates.
+func (c *Clients) Replace(certificates ...types.X509Certificate) {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	c.data = make(map[string]types.X509Certificate, len(certificates))
+	for _, cert := range certificates {
+		c.data[shared.CertFingerprint(cert.Certificate)] = cert
+	}
+}
+
+// CertificatesNative returns the trusted client certificates as native x509.Certificate types, keyed by fingerprint.
+func (c *Clients) CertificatesNative() map[string]x509.Certificate {
+	c.updateMu.RLock()
+	defer c.updateMu.RUnlock()
+
+	certMap := make(map[string]x509.Certificate, len(c.data))
+	for fingerprint, cert := range c.data {
+		certMap[fingerprint] = *cert.Certificate
+	}
+
+	return certMap
+}
+
+// Digest identifies the set of trusted client certificates, so that cluster members can tell whether their sets differ
+// without exchanging the certificates.
+func (c *Clients) Digest() string {
+	c.updateMu.RLock()
+	defer c.updateMu.RUnlock()
+
+	fingerprints := make([]string, 0, len(c.data))
+	for fingerprint := range c.data {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	sort.Strings(fingerprints)
+	digest := sha256.Sum256([]byte(strings.Join(fingerprints, ",")))
+
+	return hex.EncodeToString(digest[:])
+}
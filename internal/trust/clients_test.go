@@ -0,0 +1,32 @@
+package trust
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures the digest of the trusted clients only depends on the set of certificates.
+func TestClientsDigest(t *testing.T) {
+	certA := types.X509Certificate{Certificate: &x509.Certificate{Raw: []byte("a")}}
+	certB := types.X509Certificate{Certificate: &x509.Certificate{Raw: []byte("b")}}
+
+	clients := &Clients{}
+	empty := clients.Digest()
+
+	clients.Replace(certA, certB)
+	digest := clients.Digest()
+	require.NotEqual(t, empty, digest)
+
+	clients.Replace(certB, certA)
+	require.Equal(t, digest, clients.Digest())
+
+	clients.Replace(certA)
+	require.NotEqual(t, digest, clients.Digest())
+
+	clients.Replace()
+	require.Equal(t, empty, clients.Digest())
+}
@@ -1,20 +1,17 @@
 package trust
 
 import (
+	"context"
 	"crypto/x509"
-	"errors"
 	"fmt"
 	"math/rand"
-	"os"
-	"path/filepath"
-	"strings"
+	"net"
+	"net/netip"
 	"sync"
 
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
-	"github.com/google/renameio"
-	"gopkg.in/yaml.v3"
 
 	"github.com/canonical/microcluster/v3/client"
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
@@ -25,6 +22,8 @@ import (
 type Remotes struct {
 	data     map[string]Remote
 	updateMu sync.RWMutex
+
+	backend Backend
 }
 
 // Remote represents a yaml file with credentials to be read by the daemon.
@@ -37,41 +36,37 @@ type Remote struct {
 type Location struct {
 	Name    string         `yaml:"name"`
 	Address types.AddrPort `yaml:"address"`
+
+	// DNSName, if set, resolves to the current address of the remote, which may differ from its recorded address.
+	DNSName string `yaml:"dns_name,omitempty"`
+
+	// ResolvedAddress, if set, is the address the DNS name resolved to when the remote was last reached there instead
+	// of at its recorded address. Connections to the remote are made to it directly.
+	ResolvedAddress types.AddrPort `yaml:"resolved_address,omitempty"`
 }
 
-// Load reads any yaml files in the given directory and parses them into a set of Remotes.
-func (r *Remotes) Load(dir string) error {
+// NewRemotes returns an empty set of remotes, stored by the given backend.
+func NewRemotes(backend Backend) *Remotes {
+	return &Remotes{data: map[string]Remote{}, backend: backend}
+}
+
+// Load reads the remotes from the backend.
+func (r *Remotes) Load() error {
 	r.updateMu.Lock()
 	defer r.updateMu.Unlock()
 
-	files, err := os.ReadDir(dir)
+	remotes, err := r.backend.Load()
 	if err != nil {
-		return fmt.Errorf("Unable to read trust directory: %q: %w", dir, err)
+		return err
 	}
 
 	remoteData := map[string]Remote{}
-	for _, file := range files {
-		fileName := file.Name()
-		if file.IsDir() || !strings.HasSuffix(fileName, ".yaml") {
-			continue
-		}
-
-		content, err := os.ReadFile(filepath.Join(dir, fileName))
-		if err != nil {
-			return fmt.Errorf("Unable to read file %q: %w", fileName, err)
-		}
-
-		remote := &Remote{}
-		err = yaml.Unmarshal(content, remote)
-		if err != nil {
-			return fmt.Errorf("Unable to parse yaml for %q: %w", fileName, err)
-		}
-
+	for _, remote := range remotes {
 		if remote.Certificate.Certificate == nil {
 			return fmt.Errorf("Failed to parse local record %q. Found empty certificate", remote.Name)
 		}
 
-		remoteData[remote.Name] = *remote
+		remoteData[remote.Name] = remote
 	}
 
 	// If the refreshed truststore data is empty, and we already had data in the truststore,
@@ -88,7 +83,7 @@ func (r *Remotes) Load(dir string) error {
 }
 
 // Add adds a new local cluster member record for the remotes.
-func (r *Remotes) Add(dir string, remotes ...Remote) error {
+func (r *Remotes) Add(remotes ...Remote) error {
 	r.updateMu.Lock()
 	defer r.updateMu.Unlock()
 
@@ -102,35 +97,54 @@ func (r *Remotes) Add(dir string, remotes ...Remote) error {
 			return fmt.Errorf("A remote with name %q already exists", remote.Name)
 		}
 
-		bytes, err := yaml.Marshal(remote)
+		err := r.backend.Add(remote)
 		if err != nil {
-			return fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
+			return err
 		}
 
-		path := filepath.Join(dir, fmt.Sprintf("%s.yaml", remote.Name))
-		_, err = os.Stat(path)
-		if err == nil {
-			return fmt.Errorf("Remote at %q already exists", path)
-		}
+		// Add the remote manually so we can use it right away without waiting for the backend to be reloaded.
+		r.data[remote.Name] = remote
+	}
 
-		if !errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("Failed to check remote path %q: %w", path, err)
-		}
+	return nil
+}
 
-		err = renameio.WriteFile(path, bytes, 0644)
-		if err != nil {
-			return fmt.Errorf("Failed to write %q: %w", path, err)
+// Update replaces the record of the existing remote with the same name, for instance once a cluster member has been
+// reinstalled with a new certificate.
+func (r *Remotes) Update(remote Remote) error {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	if remote.Certificate.Certificate == nil {
+		return fmt.Errorf("Failed to parse local record %q. Found empty certificate", remote.Name)
+	}
+
+	_, ok := r.data[remote.Name]
+	if !ok {
+		return fmt.Errorf("No remote with name %q exists", remote.Name)
+	}
+
+	remotes := make([]Remote, 0, len(r.data))
+	for name, existing := range r.data {
+		if name == remote.Name {
+			existing = remote
 		}
 
-		// Add the remote manually so we can use it right away without waiting for inotify.
-		r.data[remote.Name] = remote
+		remotes = append(remotes, existing)
 	}
 
+	err := r.backend.Replace(remotes)
+	if err != nil {
+		return err
+	}
+
+	r.data[remote.Name] = remote
+
 	return nil
 }
 
-// Replace replaces the in-memory and locally stored remotes with the given list from the database.
-func (r *Remotes) Replace(dir string, newRemotes ...types.ClusterMember) error {
+// Replace replaces the in-memory and stored remotes with the given list from the database.
+func (r *Remotes) Replace(newRemotes ...types.ClusterMember) error {
 	r.updateMu.Lock()
 	defer r.updateMu.Unlock()
 
@@ -138,54 +152,33 @@ func (r *Remotes) Replace(dir string, newRemotes ...types.ClusterMember) error {
 		return fmt.Errorf("Received empty remotes")
 	}
 
-	remoteData := map[string]Remote{}
+	remotes := make([]Remote, 0, len(newRemotes))
+	remoteData := make(map[string]Remote, len(newRemotes))
 	for _, remote := range newRemotes {
 		newRemote := Remote{
-			Location:    Location{Name: remote.Name, Address: remote.Address},
+			Location:    Location{Name: remote.Name, Address: remote.Address, DNSName: remote.DNSName},
 			Certificate: remote.Certificate,
 		}
 
-		if remote.Certificate.Certificate == nil {
-			return fmt.Errorf("Failed to parse local record %q. Found empty certificate", remote.Name)
+		// Keep the address the remote was last reached at, as long as it is recorded at the same address and DNS name.
+		existing, ok := r.data[remote.Name]
+		if ok && existing.Address == newRemote.Address && existing.DNSName == newRemote.DNSName {
+			newRemote.ResolvedAddress = existing.ResolvedAddress
 		}
 
-		bytes, err := yaml.Marshal(newRemote)
-		if err != nil {
-			return fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
-		}
-
-		remotePath := filepath.Join(dir, fmt.Sprintf("%s.yaml", remote.Name))
-		err = renameio.WriteFile(remotePath, bytes, 0644)
-		if err != nil {
-			return fmt.Errorf("Failed to write %q: %w", remotePath, err)
+		if remote.Certificate.Certificate == nil {
+			return fmt.Errorf("Failed to parse local record %q. Found empty certificate", remote.Name)
 		}
 
+		remotes = append(remotes, newRemote)
 		remoteData[remote.Name] = newRemote
 	}
 
-	allEntries, err := os.ReadDir(dir)
+	err := r.backend.Replace(remotes)
 	if err != nil {
 		return err
 	}
 
-	// Remove any outdated entries.
-	for _, entry := range allEntries {
-		name, _, _ := strings.Cut(entry.Name(), ".yaml")
-		_, ok := remoteData[name]
-
-		if !ok {
-			remotePath := filepath.Join(dir, fmt.Sprintf("%s.yaml", name))
-			err = os.Remove(remotePath)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if len(remoteData) == 0 {
-		return fmt.Errorf("Failed to parse new remotes")
-	}
-
 	r.data = remoteData
 
 	return nil
@@ -247,6 +240,102 @@ func (r *Remotes) RemoteByAddress(addrPort types.AddrPort) *Remote {
 	return nil
 }
 
+// Current returns the address at which the remote recorded with the given address was last reached, or the given
+// address if it has not moved.
+func (r *Remotes) Current(address string) string {
+	r.updateMu.RLock()
+	defer r.updateMu.RUnlock()
+
+	for _, remote := range r.data {
+		if remote.Address.String() == address && remote.ResolvedAddress != (types.AddrPort{}) {
+			return remote.ResolvedAddress.String()
+		}
+	}
+
+	return address
+}
+
+// Resolve returns the address that the DNS name of the remote recorded with the given address currently resolves to,
+// keeping the recorded port.
+func (r *Remotes) Resolve(ctx context.Context, address string) (string, error) {
+	addrPort, err := types.ParseAddrPort(address)
+	if err != nil {
+		return "", err
+	}
+
+	remote := r.RemoteByAddress(addrPort)
+	if remote == nil {
+		return "", fmt.Errorf("No remote with address %q exists", address)
+	}
+
+	if remote.DNSName == "" {
+		return "", fmt.Errorf("Remote %q has no DNS name", remote.Name)
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", remote.DNSName)
+	if err != nil {
+		return "", fmt.Errorf("Failed to resolve DNS name %q of remote %q: %w", remote.DNSName, remote.Name, err)
+	}
+
+	// Prefer an address of the same family as the recorded one.
+	addr := addrs[0]
+	for _, a := range addrs {
+		if a.Unmap().Is4() == addrPort.Addr().Unmap().Is4() {
+			addr = a
+			break
+		}
+	}
+
+	return netip.AddrPortFrom(addr.Unmap(), addrPort.Port()).String(), nil
+}
+
+// Resolved records that the remote recorded with the given address was reached at the resolved address, so that
+// connections to it, including those made after a restart, go to the resolved address directly.
+func (r *Remotes) Resolved(address string, resolved string) {
+	resolvedAddr, err := types.ParseAddrPort(resolved)
+	if err != nil {
+		logger.Warn("Invalid resolved address of remote", logger.Ctx{"address": address, "resolved": resolved, "error": err})
+		return
+	}
+
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	remotes := make([]Remote, 0, len(r.data))
+	var moved *Remote
+	for _, remote := range r.data {
+		if remote.Address.String() == address {
+			// Reaching the remote at its recorded address again forgets the resolved address.
+			if remote.Address == resolvedAddr {
+				resolvedAddr = types.AddrPort{}
+			}
+
+			if remote.ResolvedAddress == resolvedAddr {
+				return
+			}
+
+			remote.ResolvedAddress = resolvedAddr
+			moved = &remote
+		}
+
+		remotes = append(remotes, remote)
+	}
+
+	if moved == nil {
+		return
+	}
+
+	err = r.backend.Replace(remotes)
+	if err != nil {
+		logger.Warn("Failed to record resolved address of remote", logger.Ctx{"name": moved.Name, "address": address, "resolved": resolved, "error": err})
+		return
+	}
+
+	logger.Info("Cluster member moved to a new address", logger.Ctx{"name": moved.Name, "address": address, "resolved": resolved})
+
+	r.data[moved.Name] = *moved
+}
+
 // RemoteByCertificateFingerprint returns a remote whose certificate fingerprint matches the provided fingerprint.
 func (r *Remotes) RemoteByCertificateFingerprint(fingerprint string) *Remote {
 	r.updateMu.RLock()
@@ -308,6 +397,18 @@ func (r *Remotes) RemotesByName() map[string]Remote {
 	return remoteData
 }
 
+// ClusterMember returns the Remote as a cluster member, as stored by Replace.
+func (r *Remote) ClusterMember() types.ClusterMember {
+	return types.ClusterMember{
+		ClusterMemberLocal: types.ClusterMemberLocal{
+			Name:        r.Name,
+			Address:     r.Address,
+			Certificate: r.Certificate,
+			DNSName:     r.DNSName,
+		},
+	}
+}
+
 // URL returns the parsed URL of the Remote.
 func (r *Remote) URL() api.URL {
 	return *api.NewURL().Scheme("https").Host(r.Address.String())
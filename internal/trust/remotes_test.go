@@ -0,0 +1,173 @@
+package trust
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// memBackend stores the remotes in memory.
+type memBackend struct {
+	remotes    []Remote
+	replaceErr error
+}
+
+func (b *memBackend) Load() ([]Remote, error) { return b.remotes, nil }
+
+func (b *memBackend) Add(remote Remote) error {
+	b.remotes = append(b.remotes, remote)
+	return nil
+}
+
+func (b *memBackend) Replace(remotes []Remote) error {
+	if b.replaceErr != nil {
+		return b.replaceErr
+	}
+
+	b.remotes = remotes
+	return nil
+}
+
+func (b *memBackend) Watch(refresh func() error) {}
+
+func newTestRemote(t *testing.T, name string, address string) Remote {
+	addrPort, err := types.ParseAddrPort(address)
+	require.NoError(t, err)
+
+	return Remote{
+		Location:    Location{Name: name, Address: addrPort},
+		Certificate: types.X509Certificate{Certificate: &x509.Certificate{}},
+	}
+}
+
+// Ensures Update replaces the record of a single remote, in memory and in the backend.
+func TestRemotesUpdate(t *testing.T) {
+	backend := &memBackend{}
+	remotes := NewRemotes(backend)
+
+	m1 := newTestRemote(t, "m1", "10.0.0.1:9000")
+	m2 := newTestRemote(t, "m2", "10.0.0.2:9000")
+	require.NoError(t, remotes.Add(m1, m2))
+
+	// The rejoining member keeps its name, with a new certificate and DNS name.
+	updated := newTestRemote(t, "m1", "10.0.0.1:9000")
+	updated.DNSName = "m1.example.com"
+	require.NoError(t, remotes.Update(updated))
+
+	byName := remotes.RemotesByName()
+	require.Len(t, byName, 2)
+	require.Equal(t, "m1.example.com", byName["m1"].DNSName)
+	require.Same(t, updated.Certificate.Certificate, byName["m1"].Certificate.Certificate)
+	require.Equal(t, m2, byName["m2"])
+	require.Equal(t, "m1", remotes.RemoteByAddress(m1.Address).Name)
+
+	require.Len(t, backend.remotes, 2)
+	for _, remote := range backend.remotes {
+		require.Equal(t, byName[remote.Name], remote)
+	}
+
+	// Only existing remotes with a certificate can be updated.
+	require.Error(t, remotes.Update(newTestRemote(t, "m3", "10.0.0.3:9000")))
+
+	noCert := newTestRemote(t, "m1", "10.0.0.1:9000")
+	noCert.Certificate.Certificate = nil
+	require.Error(t, remotes.Update(noCert))
+
+	// A remote isn't changed in memory if the backend fails to store it.
+	backend.replaceErr = errors.New("Failed to store remotes")
+	moved := newTestRemote(t, "m1", "10.0.0.4:9000")
+	require.ErrorIs(t, remotes.Update(moved), backend.replaceErr)
+	require.Equal(t, m1.Address, remotes.RemotesByName()["m1"].Address)
+}
+
+// Ensures remotes are stored back with every field they were loaded with.
+func TestRemoteClusterMember(t *testing.T) {
+	backend := &memBackend{}
+	remotes := NewRemotes(backend)
+
+	m1 := newTestRemote(t, "m1", "10.0.0.1:9000")
+	m1.DNSName = "m1.example.com"
+	require.NoError(t, remotes.Add(m1))
+
+	remote := remotes.RemotesByName()["m1"]
+	member := remote.ClusterMember()
+	require.Equal(t, types.ClusterMemberLocal{Name: "m1", Address: m1.Address, Certificate: m1.Certificate, DNSName: "m1.example.com"}, member.ClusterMemberLocal)
+
+	require.NoError(t, remotes.Replace(member))
+	require.Equal(t, []Remote{m1}, backend.remotes)
+}
+
+// Ensures remotes take their DNS names from the database, and keep the address they were last reached at.
+func TestRemotesReplace(t *testing.T) {
+	backend := &memBackend{}
+	remotes := NewRemotes(backend)
+
+	m1 := newTestRemote(t, "m1", "10.0.0.1:9000")
+	m1.DNSName = "m1.example.com"
+	require.NoError(t, remotes.Add(m1))
+
+	remotes.Resolved("10.0.0.1:9000", "10.0.1.1:9000")
+
+	member := types.ClusterMember{ClusterMemberLocal: types.ClusterMemberLocal{Name: "m1", Address: m1.Address, Certificate: m1.Certificate, DNSName: "m1.example.com"}}
+	require.NoError(t, remotes.Replace(member))
+	require.Equal(t, "10.0.1.1:9000", remotes.Current("10.0.0.1:9000"))
+
+	// The resolved address is forgotten once the DNS name changes.
+	member.DNSName = "m1.example.org"
+	require.NoError(t, remotes.Replace(member))
+	require.Equal(t, "m1.example.org", remotes.RemotesByName()["m1"].DNSName)
+	require.Equal(t, "10.0.0.1:9000", remotes.Current("10.0.0.1:9000"))
+
+	// A DNS name removed from the database is removed from the remote.
+	member.DNSName = ""
+	require.NoError(t, remotes.Replace(member))
+	require.Empty(t, remotes.RemotesByName()["m1"].DNSName)
+}
+
+// Ensures the address a remote was reached at is recorded in memory and in the backend.
+func TestRemotesResolved(t *testing.T) {
+	backend := &memBackend{}
+	remotes := NewRemotes(backend)
+
+	m1 := newTestRemote(t, "m1", "10.0.0.1:9000")
+	m1.DNSName = "localhost"
+	m2 := newTestRemote(t, "m2", "10.0.0.2:9000")
+	require.NoError(t, remotes.Add(m1, m2))
+
+	resolved, err := remotes.Resolve(context.Background(), "10.0.0.1:9000")
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1:9000", resolved)
+
+	// Only remotes with a DNS name can be resolved.
+	_, err = remotes.Resolve(context.Background(), "10.0.0.2:9000")
+	require.Error(t, err)
+
+	_, err = remotes.Resolve(context.Background(), "10.0.0.3:9000")
+	require.Error(t, err)
+
+	remotes.Resolved("10.0.0.1:9000", resolved)
+	require.Equal(t, resolved, remotes.Current("10.0.0.1:9000"))
+	require.Equal(t, "10.0.0.2:9000", remotes.Current("10.0.0.2:9000"))
+	require.Equal(t, m1.Address, remotes.RemotesByName()["m1"].Address)
+
+	for _, remote := range backend.remotes {
+		if remote.Name == "m1" {
+			require.Equal(t, resolved, remote.ResolvedAddress.String())
+		}
+	}
+
+	// The resolved address is not recorded if the backend fails to store it.
+	backend.replaceErr = errors.New("Failed to store remotes")
+	remotes.Resolved("10.0.0.1:9000", "10.0.1.1:9000")
+	require.Equal(t, resolved, remotes.Current("10.0.0.1:9000"))
+	backend.replaceErr = nil
+
+	// Reaching the remote at its recorded address again forgets the resolved address.
+	remotes.Resolved("10.0.0.1:9000", "10.0.0.1:9000")
+	require.Equal(t, "10.0.0.1:9000", remotes.Current("10.0.0.1:9000"))
+}
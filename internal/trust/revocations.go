@@ -0,0 +1,39 @@
+package trust
+
+import (
+	"crypto/x509"
+	"sync"
+
+	"github.com/canonical/lxd/shared"
+)
+
+// Revocations holds the fingerprints of revoked certificates, which are rejected even if they are otherwise trusted.
+// The revocations are recorded in the database, and cached here so they can be checked on every TLS handshake.
+type Revocations struct {
+	data     map[string]bool
+	updateMu sync.RWMutex
+}
+
+// Replace replaces the set of revoked certificate fingerprints.
+func (r *Revocations) Replace(fingerprints ...string) {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	r.data = make(map[string]bool, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		r.data[fingerprint] = true
+	}
+}
+
+// IsRevoked returns whether the certificate with the given fingerprint is revoked.
+func (r *Revocations) IsRevoked(fingerprint string) bool {
+	r.updateMu.RLock()
+	defer r.updateMu.RUnlock()
+
+	return r.data[fingerprint]
+}
+
+// IsRevokedCertificate returns whether the certificate is revoked.
+func (r *Revocations) IsRevokedCertificate(cert *x509.Certificate) bool {
+	return r.IsRevoked(shared.CertFingerprint(cert))
+}
@@ -3,51 +3,42 @@ package trust
 import (
 	"fmt"
 	"sync"
-
-	"github.com/fsnotify/fsnotify"
-
-	"github.com/canonical/microcluster/v3/internal/sys"
 )
 
-// Store represents a directory of remotes watched by the fsnotify Watcher.
+// Store represents the remotes of the truststore, as kept up to date by its backend.
 type Store struct {
-	remotesMu sync.RWMutex // Mutex for coordinating manual and fsnotify access to remotes.
+	remotesMu sync.RWMutex // Mutex for coordinating manual and backend access to remotes.
 	remotes   *Remotes     // Should never be called directly, instead use Remotes().
 
-	refresh func(path string) error
+	refresh func() error
 }
 
-// Init initializes the remotes in the truststore, seeds the rand package for selecting remotes at random, and watches
-// the truststore directory for updates.
-func Init(watcher *sys.Watcher, onUpdate func(oldRemotes, newRemotes Remotes) error, dir string) (*Store, error) {
-	ts := &Store{remotes: &Remotes{}}
+// Init initializes the remotes in the truststore from the backend, and watches the backend for updates.
+func Init(backend Backend, onUpdate func(oldRemotes, newRemotes Remotes) error) (*Store, error) {
+	ts := &Store{remotes: NewRemotes(backend)}
 	ts.remotesMu.Lock()
 	defer ts.remotesMu.Unlock()
 
-	ts.remotes.data = map[string]Remote{}
-	err := ts.remotes.Load(dir)
+	err := ts.remotes.Load()
 	if err != nil {
 		return nil, err
 	}
 
-	ts.refresh = func(path string) error {
+	ts.refresh = func() error {
 		ts.remotesMu.Lock()
 		defer func() {
 			ts.remotesMu.Unlock()
 		}()
 
-		err := ts.remotes.Load(dir)
+		err := ts.remotes.Load()
 		if err != nil {
-			return fmt.Errorf("Unable to refresh remotes in path %q: %w", path, err)
+			return fmt.Errorf("Unable to refresh remotes: %w", err)
 		}
 
 		return nil
 	}
 
-	// Watch on the truststore directory for yaml updates.
-	watcher.Watch(dir, "yaml", func(path string, event fsnotify.Op) error {
-		return ts.refresh(path)
-	})
+	backend.Watch(ts.refresh)
 
 	return ts, nil
 }
@@ -62,5 +53,5 @@ func (ts *Store) Remotes() *Remotes {
 
 // Refresh reloads the truststore and runs any associated hooks.
 func (ts *Store) Refresh() error {
-	return ts.refresh("*")
+	return ts.refresh()
 }
@@ -0,0 +1,122 @@
+// Package watchdog reports the state of the daemon to systemd with the sd_notify protocol, and keeps the systemd
+// watchdog fed for as long as the daemon passes its health checks.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+const (
+	// Ready tells the service manager that the daemon has started.
+	Ready = "READY=1"
+
+	// Stopping tells the service manager that the daemon is shutting down.
+	Stopping = "STOPPING=1"
+
+	// alive feeds the watchdog of the service manager.
+	alive = "WATCHDOG=1"
+)
+
+// Check is a self-check of the daemon's health.
+type Check struct {
+	// Name identifies the check in the logs.
+	Name string
+
+	// Run returns an error if the daemon is unhealthy.
+	Run func(ctx context.Context) error
+}
+
+// Notify sends the state to the service manager over the socket in $NOTIFY_SOCKET.
+// It does nothing if the daemon was not started by a service manager expecting notifications.
+func Notify(state string) error {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return nil
+	}
+
+	// Abstract sockets are given with a leading @.
+	if strings.HasPrefix(socketPath, "@") {
+		socketPath = "\x00" + socketPath[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("Failed to connect to the service manager notification socket: %w", err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.Write([]byte(state))
+	if err != nil {
+		return fmt.Errorf("Failed to notify the service manager: %w", err)
+	}
+
+	return nil
+}
+
+// Interval returns how often the service manager expects its watchdog to be fed, from $WATCHDOG_USEC.
+// It returns 0 if the watchdog is not enabled for this process.
+func Interval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+
+	// The watchdog may be meant for another process of the service.
+	pid := os.Getenv("WATCHDOG_PID")
+	if pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+
+	interval, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("Invalid watchdog interval %q", usec)
+	}
+
+	return time.Duration(interval) * time.Microsecond, nil
+}
+
+// Run feeds the watchdog twice per interval until the context is cancelled, as long as all checks pass.
+// Once the daemon fails its checks for longer than the interval, the service manager restarts it.
+func Run(ctx context.Context, interval time.Duration, checks []Check) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, interval/2)
+		healthy := true
+		for _, check := range checks {
+			err := check.Run(checkCtx)
+			if err != nil {
+				logger.Warn("Daemon health check failed, not feeding the watchdog", logger.Ctx{"check": check.Name, "error": err})
+				healthy = false
+			}
+		}
+
+		cancel()
+
+		// Don't feed the watchdog for checks cut short by the shutdown.
+		if !healthy || ctx.Err() != nil {
+			continue
+		}
+
+		err := Notify(alive)
+		if err != nil {
+			logger.Error("Failed to feed the watchdog", logger.Ctx{"error": err})
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// listenNotifySocket listens on a notification socket, and points $NOTIFY_SOCKET to it.
+func listenNotifySocket(t *testing.T) *net.UnixConn {
+	path := filepath.Join(t.TempDir(), "notify.socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	return conn
+}
+
+// readNotification returns the next notification received on the socket.
+func readNotification(t *testing.T, conn *net.UnixConn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+// Ensures notifications are sent over $NOTIFY_SOCKET, and skipped without it.
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	require.NoError(t, Notify(Ready))
+
+	conn := listenNotifySocket(t)
+	require.NoError(t, Notify(Ready))
+	require.Equal(t, Ready, readNotification(t, conn))
+}
+
+// Ensures the watchdog interval is only used when it is meant for this process.
+func TestInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	interval, err := Interval()
+	require.NoError(t, err)
+	require.Zero(t, interval)
+
+	t.Setenv("WATCHDOG_USEC", "2000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	interval, err = Interval()
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, interval)
+
+	t.Setenv("WATCHDOG_PID", "1")
+	interval, err = Interval()
+	require.NoError(t, err)
+	require.Zero(t, interval)
+
+	t.Setenv("WATCHDOG_PID", "")
+	t.Setenv("WATCHDOG_USEC", "invalid")
+	_, err = Interval()
+	require.Error(t, err)
+}
+
+// Ensures the watchdog is only fed while all checks pass.
+func TestRun(t *testing.T) {
+	conn := listenNotifySocket(t)
+
+	healthy := make(chan bool, 1)
+	healthy <- false
+	checks := []Check{{Name: "test", Run: func(ctx context.Context) error {
+		select {
+		case ok := <-healthy:
+			if !ok {
+				return errors.New("Unhealthy")
+			}
+		default:
+		}
+
+		return nil
+	}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	go Run(ctx, 100*time.Millisecond, checks)
+
+	// The first round fails, so the watchdog is first fed on the second.
+	require.Equal(t, alive, readNotification(t, conn))
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
@@ -1,47 +1,174 @@
 package microcluster
 
 import (
+	"bytes"
 	"context"
 	"crypto/x509"
+	"database/sql"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v3"
 
 	"github.com/canonical/microcluster/v3/client"
 	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/audit"
 	"github.com/canonical/microcluster/v3/internal/daemon"
+	"github.com/canonical/microcluster/v3/internal/db"
+	"github.com/canonical/microcluster/v3/internal/db/update"
+	"github.com/canonical/microcluster/v3/internal/discovery"
+	"github.com/canonical/microcluster/v3/internal/endpoints"
+	"github.com/canonical/microcluster/v3/internal/kine"
+	"github.com/canonical/microcluster/v3/internal/logging"
 	"github.com/canonical/microcluster/v3/internal/recover"
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/internal/trust"
+	"github.com/canonical/microcluster/v3/rest"
 	"github.com/canonical/microcluster/v3/rest/types"
 )
 
 // DaemonArgs are the data needed to start a MicroCluster daemon.
 type DaemonArgs = daemon.Args
 
+// SchemaNamespace is an independently versioned series of schema updates, whose tables are prefixed with its name.
+type SchemaNamespace = update.Namespace
+
+// DatabaseMaintenanceSchedule configures the periodic vacuum or analysis of the database by the dqlite leader.
+type DatabaseMaintenanceSchedule = db.MaintenanceSchedule
+
+// DatabaseBackfill is a data migration run once across the cluster after the schema is updated, in resumable batches.
+type DatabaseBackfill = update.Backfill
+
+// DqliteOptions tunes the local dqlite node of a MicroCluster daemon.
+type DqliteOptions = db.DqliteOptions
+
+// ClientTransportOptions tunes the connections made by a MicroCluster daemon to other cluster members.
+type ClientTransportOptions = internalClient.TransportOptions
+
+// AuditLogOptions configures the audit log of mutating API requests received by a MicroCluster daemon.
+type AuditLogOptions = audit.Options
+
+// FilesystemLayout places the files of a MicroCluster daemon outside of its state directory.
+type FilesystemLayout = sys.Layout
+
+// TLSPolicy customizes the TLS configuration of the API listeners of a MicroCluster daemon, and of its connections to
+// other cluster members.
+type TLSPolicy = endpoints.TLSPolicy
+
+// TrustStoreBackend stores the remotes of the truststore of a MicroCluster daemon.
+type TrustStoreBackend = trust.Backend
+
+// TrustStoreRemote is a cluster member recorded in the truststore.
+type TrustStoreRemote = trust.Remote
+
+// TrustStoreLocation is the name and address of a cluster member recorded in the truststore.
+type TrustStoreLocation = trust.Location
+
+// ArchiveEncryption configures the encryption of database backups and recovery tarballs.
+type ArchiveEncryption = recover.ArchiveEncryption
+
+// DatabaseBackupSchedule configures the periodic database backups taken by a MicroCluster daemon.
+type DatabaseBackupSchedule = recover.BackupSchedule
+
+// EtcdOptions enables an etcd v3 compatible API backed by the database of a MicroCluster daemon.
+//
+// The consumer only provides the etcd gRPC service, with Server, and the daemon owns everything around it: the
+// EtcdBackend passed to Server, the TLS listener at Address, and the HTTP/2 server that rejects clients without a
+// trusted certificate before they reach the service. On shutdown, the daemon stops accepting connections, waits for
+// requests and watches to end for up to DaemonArgs.DrainConnectionsTimeout, then closes the remaining connections.
+// The consumer must not serve the handler itself, and can release anything the service holds once the daemon stopped.
+type EtcdOptions = kine.Options
+
+// EtcdBackend stores the keys of the etcd compatible API with the revision semantics of etcd v3.
+type EtcdBackend = kine.Backend
+
+// EtcdKeyValue is a revision of a key of the etcd compatible API.
+type EtcdKeyValue = kine.KeyValue
+
+// EtcdEvent is a change to a key of the etcd compatible API.
+type EtcdEvent = kine.Event
+
+// EtcdWatchResult holds the changes of a watch of keys of the etcd compatible API.
+type EtcdWatchResult = kine.WatchResult
+
+// JoinTokenRequest holds the name, expiry, usage limit and subnet restrictions of a join token.
+type JoinTokenRequest = internalTypes.TokenRequest
+
+// Errors returned by the methods of MicroCluster and by clients of the daemon, which can be compared with errors.Is.
+var (
+	// ErrNotBootstrapped is returned when the cluster member is not part of a cluster yet.
+	ErrNotBootstrapped = types.ErrNotBootstrapped
+
+	// ErrAlreadyBootstrapped is returned when bootstrapping or joining a cluster from a member that is already part of one.
+	ErrAlreadyBootstrapped = types.ErrAlreadyBootstrapped
+
+	// ErrNotLeader is returned when a request that only the dqlite leader can serve reaches another cluster member.
+	ErrNotLeader = types.ErrNotLeader
+
+	// ErrDatabaseUnavailable is returned when a database transaction keeps failing because of a dqlite leadership
+	// change or a locked database, which is expected to resolve itself.
+	ErrDatabaseUnavailable = types.ErrDatabaseUnavailable
+
+	// ErrMemberNotFound is returned when no cluster member exists with the given name.
+	ErrMemberNotFound = types.ErrMemberNotFound
+
+	// ErrTokenExpired is returned when joining a cluster with a join token past its expiry.
+	ErrTokenExpired = types.ErrTokenExpired
+
+	// ErrHookTimeout is returned when a hook does not complete within its configured timeout.
+	ErrHookTimeout = types.ErrHookTimeout
+)
+
 // MicroCluster contains some basic filesystem information for interacting with the MicroCluster daemon.
 type MicroCluster struct {
 	FileSystem *sys.OS
 
 	args Args
+
+	// daemon is the daemon started by Start, if any.
+	daemonMu sync.Mutex
+	daemon   *daemon.Daemon
 }
 
 // Args contains options for configuring MicroCluster.
 type Args struct {
 	StateDir string
 
+	// Layout places the database, the control socket and the logs outside of the state directory.
+	// Clients of the daemon must use the same layout to find its control socket.
+	Layout FilesystemLayout
+
 	Client *client.Client
-	Proxy  func(*http.Request) (*url.URL, error)
+
+	// ControlTCPAddress, if set, reaches the daemon over the loopback TCP address set in DaemonArgs.ControlTCPAddress
+	// instead of the control socket. The shared secret of the daemon is read from its runtime directory.
+	ControlTCPAddress string
+
+	// Proxy is used by the clients created by MicroCluster to reach the daemon.
+	// Connections between cluster members go through ClientTransportOptions.Proxy of the daemon instead.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// Interceptors wrap the requests sent by the clients created by MicroCluster. They are not added to Client.
+	// Requests sent by the daemon, including notifications to other cluster members, are wrapped by
+	// ClientTransportOptions.Interceptors of the daemon instead.
+	Interceptors []client.Interceptor
+
+	// ArchiveEncryption configures the encryption of the database backup and recovery tarball
+	// written by RecoverFromQuorumLoss.
+	ArchiveEncryption ArchiveEncryption
 }
 
 // App returns an instance of MicroCluster with a newly initialized filesystem if one does not exist.
@@ -53,7 +180,7 @@ func App(args Args) (*MicroCluster, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Missing absolute state directory: %w", err)
 	}
-	os, err := sys.DefaultOS(stateDir, true)
+	os, err := sys.NewOS(stateDir, args.Layout, true)
 	if err != nil {
 		return nil, err
 	}
@@ -67,8 +194,19 @@ func App(args Args) (*MicroCluster, error) {
 // Start starts up a brand new MicroCluster daemon. Only the local control socket will be available at this stage, no
 // database exists yet. Any api or schema extensions can be applied here.
 func (m *MicroCluster) Start(ctx context.Context, daemonArgs DaemonArgs) error {
-	// Initialize the logger.
-	err := logger.InitLogger(m.FileSystem.LogFile, "", daemonArgs.Verbose, daemonArgs.Debug, nil)
+	// Initialize the logger. Its level and target can be changed at runtime with SetLogLevel and SetLogConfig.
+	logConfig := types.LogConfig{Level: types.LogLevelWarn, Target: types.LogTargetStderr}
+	if m.args.Layout.LogDir != "" {
+		logConfig.Target = types.LogTargetFile
+	}
+
+	if daemonArgs.Debug {
+		logConfig.Level = types.LogLevelDebug
+	} else if daemonArgs.Verbose {
+		logConfig.Level = types.LogLevelInfo
+	}
+
+	err := logging.Init(m.FileSystem.LogFile, filepath.Base(os.Args[0]), logConfig)
 	if err != nil {
 		return err
 	}
@@ -77,13 +215,23 @@ func (m *MicroCluster) Start(ctx context.Context, daemonArgs DaemonArgs) error {
 	defer logger.Info("Daemon stopped")
 	d := daemon.NewDaemon(cluster.GetCallerProject())
 
+	m.daemonMu.Lock()
+	m.daemon = d
+	m.daemonMu.Unlock()
+
+	defer func() {
+		m.daemonMu.Lock()
+		m.daemon = nil
+		m.daemonMu.Unlock()
+	}()
+
 	chIgnore := make(chan os.Signal, 1)
 	signal.Notify(chIgnore, unix.SIGHUP)
 
 	ctx, cancel := signal.NotifyContext(ctx, unix.SIGPWR, unix.SIGTERM, unix.SIGINT, unix.SIGQUIT)
 	defer cancel()
 
-	err = d.Run(ctx, m.FileSystem.StateDir, daemonArgs)
+	err = d.Run(ctx, m.FileSystem.StateDir, m.args.Layout, daemonArgs)
 	if err != nil {
 		return fmt.Errorf("Daemon stopped with error: %w", err)
 	}
@@ -91,7 +239,50 @@ func (m *MicroCluster) Start(ctx context.Context, daemonArgs DaemonArgs) error {
 	return nil
 }
 
-// Status returns basic status information about the cluster.
+// runningDaemon waits for the daemon started by Start to be ready, and returns it.
+func (m *MicroCluster) runningDaemon(ctx context.Context) (*daemon.Daemon, error) {
+	m.daemonMu.Lock()
+	d := m.daemon
+	m.daemonMu.Unlock()
+
+	if d == nil {
+		return nil, fmt.Errorf("Daemon is not running in this process")
+	}
+
+	select {
+	case <-d.ReadyChan:
+		return d, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("Failed waiting for the daemon to be ready: %w", ctx.Err())
+	}
+}
+
+// AttachServer adds an additional listener to the daemon started by Start, without restarting it.
+// The listener is started right away if its address is set, and either the daemon is initialized or the server is
+// available before initialization. Otherwise, it is started once the daemon is initialized, or an address is set
+// with the daemon's server configuration.
+// Servers extending the core API can only be supplied in DaemonArgs.ExtensionServers.
+func (m *MicroCluster) AttachServer(ctx context.Context, name string, server rest.Server) error {
+	d, err := m.runningDaemon(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.AttachServer(name, server)
+}
+
+// DetachServer stops and removes an additional listener from the daemon started by Start.
+func (m *MicroCluster) DetachServer(ctx context.Context, name string) error {
+	d, err := m.runningDaemon(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.DetachServer(name)
+}
+
+// Status returns basic status information about the cluster. Once the database is online, it includes the status,
+// last heartbeat and roundtrip latency of every cluster member, as probed by the local daemon.
 func (m *MicroCluster) Status(ctx context.Context) (*internalTypes.Server, error) {
 	c, err := m.LocalClient()
 	if err != nil {
@@ -107,6 +298,18 @@ func (m *MicroCluster) Status(ctx context.Context) (*internalTypes.Server, error
 	return &server, nil
 }
 
+// Health checks that the local cluster member is ready, its database is open and the dqlite leader can be reached.
+// The same check is served without authentication at /core/1.0/healthz for load balancers, unless restricted with
+// DaemonArgs.CoreAccessOverride.
+func (m *MicroCluster) Health(ctx context.Context) (*types.Health, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetHealth(ctx)
+}
+
 // Ready waits for the daemon to report it has finished initial setup and is ready to be bootstrapped or join an
 // existing cluster.
 func (m *MicroCluster) Ready(ctx context.Context) error {
@@ -143,7 +346,7 @@ func (m *MicroCluster) Ready(ctx context.Context) error {
 				logger.Debugf("Checking if MicroCluster daemon is ready (attempt %d)", i)
 			}
 
-			err = c.CheckReady(ctx)
+			err = c.CheckDaemonReady(ctx)
 			if err != nil {
 				errLast = err
 				if doLog {
@@ -168,6 +371,22 @@ func (m *MicroCluster) Ready(ctx context.Context) error {
 	return nil
 }
 
+// Shutdown gracefully stops the daemon. Its OnShutdown hook is run, in-flight API requests are drained,
+// its dqlite roles are handed off to other cluster members, and the database is closed before it exits.
+func (m *MicroCluster) Shutdown(ctx context.Context) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	err = c.ShutdownDaemon(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to shut down daemon: %w", err)
+	}
+
+	return nil
+}
+
 // NewCluster bootstrapps a brand new cluster with this daemon as its only member.
 func (m *MicroCluster) NewCluster(ctx context.Context, name string, address string, config map[string]string) error {
 	c, err := m.LocalClient()
@@ -183,6 +402,30 @@ func (m *MicroCluster) NewCluster(ctx context.Context, name string, address stri
 	return c.ControlDaemon(ctx, internalTypes.Control{Bootstrap: true, Address: addr, Name: name, InitConfig: config})
 }
 
+// NewLocalCluster bootstraps a single-node cluster that is not exposed on the network. The daemon's API only listens
+// on a free loopback port, and the member cannot issue join tokens until it is published with PublishLocalCluster.
+// This is useful for consumers that only need the local database.
+func (m *MicroCluster) NewLocalCluster(ctx context.Context, name string, config map[string]string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.ControlDaemon(ctx, internalTypes.Control{Bootstrap: true, Name: name, InitConfig: config})
+}
+
+// PublishLocalCluster moves a cluster bootstrapped with NewLocalCluster to the given network address, after which it
+// can issue join tokens. The daemon must be stopped, and picks up the new address when it is started again.
+// A database backup is taken in the state directory before making any changes.
+func (m *MicroCluster) PublishLocalCluster(address string) error {
+	addr, err := types.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("Received invalid address %q: %w", address, err)
+	}
+
+	return recover.PublishMember(m.FileSystem, addr, m.args.ArchiveEncryption)
+}
+
 // JoinCluster joins an existing cluster with a join token supplied by an existing cluster member.
 func (m *MicroCluster) JoinCluster(ctx context.Context, name string, address string, token string, initConfig map[string]string) error {
 	c, err := m.LocalClient()
@@ -198,6 +441,185 @@ func (m *MicroCluster) JoinCluster(ctx context.Context, name string, address str
 	return c.ControlDaemon(ctx, internalTypes.Control{JoinToken: token, Address: addr, Name: name, InitConfig: initConfig})
 }
 
+// JoinClusterWithProgress joins an existing cluster like JoinCluster, and calls progress with each stage reached by the
+// daemon while joining, such as validating the token, exchanging certificates, joining the database and running hooks.
+// progress is not called after JoinClusterWithProgress returns.
+func (m *MicroCluster) JoinClusterWithProgress(ctx context.Context, name string, address string, token string, initConfig map[string]string, progress func(progress types.InitProgress)) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	addr, err := types.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("Received invalid address %q: %w", address, err)
+	}
+
+	return controlWithProgress(ctx, c, internalTypes.Control{JoinToken: token, Address: addr, Name: name, InitConfig: initConfig}, progress)
+}
+
+// initProgressWait is how long the progress of a successful initialization is still received once the daemon responds.
+const initProgressWait = 5 * time.Second
+
+// controlWithProgress sends the control data to the daemon while streaming the stages it reaches to progress.
+func controlWithProgress(ctx context.Context, c *client.Client, args internalTypes.Control, progress func(progress types.InitProgress)) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	subscribed := make(chan struct{})
+	watchErr := make(chan error, 1)
+	go func() {
+		once := sync.Once{}
+		watchErr <- c.WatchInitProgress(watchCtx, func() { once.Do(func() { close(subscribed) }) }, progress)
+	}()
+
+	select {
+	case <-subscribed:
+	case err := <-watchErr:
+		if err == nil {
+			err = fmt.Errorf("Stream ended before subscribing")
+		}
+
+		return fmt.Errorf("Failed to watch initialization progress: %w", err)
+	case <-ctx.Done():
+		<-watchErr
+		return ctx.Err()
+	}
+
+	err := c.ControlDaemon(ctx, args)
+	if err == nil {
+		// The stream ends once the daemon reports the initialization as complete.
+		select {
+		case <-watchErr:
+			return nil
+		case <-time.After(initProgressWait):
+		}
+	}
+
+	cancel()
+	<-watchErr
+
+	return err
+}
+
+// Init initializes the daemon according to the given YAML preseed, as described by types.Preseed. Any certificates
+// of the preseed are installed before bootstrapping, after which the daemon bootstraps a new cluster or joins an
+// existing one with the preseed's join token.
+func (m *MicroCluster) Init(ctx context.Context, preseedYAML []byte) error {
+	preseed := types.Preseed{}
+	decoder := yaml.NewDecoder(bytes.NewReader(preseedYAML))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&preseed)
+	if err != nil {
+		return fmt.Errorf("Failed to parse preseed: %w", err)
+	}
+
+	err = preseed.Validate()
+	if err != nil {
+		return fmt.Errorf("Invalid preseed: %w", err)
+	}
+
+	if preseed.Name == "" {
+		preseed.Name, err = os.Hostname()
+		if err != nil {
+			return fmt.Errorf("Failed to get hostname for the member name: %w", err)
+		}
+	}
+
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	for name, keyPair := range preseed.Certificates {
+		err = c.UpdateCertificate(ctx, types.CertificateName(name), keyPair)
+		if err != nil {
+			return fmt.Errorf("Failed to install preseeded %q certificate: %w", name, err)
+		}
+	}
+
+	return c.ControlDaemon(ctx, internalTypes.Control{
+		Bootstrap:     preseed.Bootstrap,
+		JoinToken:     preseed.JoinToken,
+		Name:          preseed.Name,
+		Address:       preseed.Address,
+		ListenAddress: preseed.ListenAddress,
+		DNSName:       preseed.DNSName,
+		InitConfig:    preseed.InitConfig,
+	})
+}
+
+// PreflightJoin checks whether the daemon can join an existing cluster with the given name, address and join token,
+// without joining it. An existing cluster member from the token validates the token, and checks that the name and
+// address are free and that the schema and API extensions match the cluster's. The clock of the daemon is also
+// compared to the cluster member's. The returned error describes every failed check, along with the outcome of all checks.
+func (m *MicroCluster) PreflightJoin(ctx context.Context, name string, address string, token string) (*types.JoinPreflight, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := types.ParseAddrPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("Received invalid address %q: %w", address, err)
+	}
+
+	preflight, err := c.PreflightJoin(ctx, internalTypes.Control{JoinToken: token, Address: addr, Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	return preflight, preflight.Err()
+}
+
+// NewClusterWithListenAddress bootstraps a new cluster like NewCluster, but the API listens on listenAddress while
+// address is advertised to other cluster members. This allows listening on all interfaces, for instance on "[::]:9000",
+// while advertising a specific global address.
+func (m *MicroCluster) NewClusterWithListenAddress(ctx context.Context, name string, address string, listenAddress string, config map[string]string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	addr, listenAddr, err := parseAddresses(address, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	return c.ControlDaemon(ctx, internalTypes.Control{Bootstrap: true, Address: addr, ListenAddress: listenAddr, Name: name, InitConfig: config})
+}
+
+// JoinClusterWithListenAddress joins an existing cluster like JoinCluster, but the API listens on listenAddress while
+// address is advertised to other cluster members.
+func (m *MicroCluster) JoinClusterWithListenAddress(ctx context.Context, name string, address string, listenAddress string, token string, initConfig map[string]string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	addr, listenAddr, err := parseAddresses(address, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	return c.ControlDaemon(ctx, internalTypes.Control{JoinToken: token, Address: addr, ListenAddress: listenAddr, Name: name, InitConfig: initConfig})
+}
+
+// parseAddresses parses the advertised and listen addresses of a daemon.
+func parseAddresses(address string, listenAddress string) (types.AddrPort, types.AddrPort, error) {
+	addr, err := types.ParseAddrPort(address)
+	if err != nil {
+		return types.AddrPort{}, types.AddrPort{}, fmt.Errorf("Received invalid address %q: %w", address, err)
+	}
+
+	listenAddr, err := types.ParseAddrPort(listenAddress)
+	if err != nil {
+		return types.AddrPort{}, types.AddrPort{}, fmt.Errorf("Received invalid listen address %q: %w", listenAddress, err)
+	}
+
+	return addr, listenAddr, nil
+}
+
 // GetDqliteClusterMembers retrieves the current local cluster configuration
 // (derived from the trust store & dqlite metadata); it does not query the
 // database.
@@ -207,20 +629,34 @@ func (m *MicroCluster) GetDqliteClusterMembers() ([]cluster.DqliteMember, error)
 	return recover.GetDqliteClusterMembers(m.FileSystem)
 }
 
+// ListDiscoveredPeers queries the local network over mDNS for daemons of the same project that were started with
+// discovery enabled and are not yet part of a cluster. Answers are collected until the context is done, or for a
+// short default period if it has no deadline.
+func (m *MicroCluster) ListDiscoveredPeers(ctx context.Context) ([]types.DiscoveredPeer, error) {
+	peers, err := discovery.Browse(ctx, cluster.GetCallerProject())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to discover peers: %w", err)
+	}
+
+	return peers, nil
+}
+
 // RecoverFromQuorumLoss can be used to recover database access when a quorum of
 // members is lost and cannot be recovered (e.g. hardware failure).
 // This function requires that:
 //   - All cluster members' databases are not running
 //   - The current member has the most up-to-date raft log (usually the member
-//     which was most recently the leader)
+//     which was most recently the leader), which can be checked by comparing
+//     the raft state reported by DumpRaftState on each member with
+//     DatabaseRaftState.Compare
 //
 // RecoverFromQuorumLoss will take a database backup before attempting the
 // recovery operation.
 //
 // RecoverFromQuorumLoss should be invoked _exactly once_ for the entire cluster.
 // This function creates a gz-compressed tarball and returns its path. This
-// tarball should be manually copied by the user to the state dir of all other
-// cluster members.
+// tarball should be copied to the state dir of all other cluster members,
+// either manually or with DistributeRecoveryTarball.
 //
 // On start, Microcluster will automatically check for & load the recovery
 // tarball. A database backup will be taken before the load.
@@ -236,69 +672,368 @@ func (m *MicroCluster) RecoverFromQuorumLoss(members []cluster.DqliteMember) (st
 		return "", err
 	}
 
-	return recover.RecoverFromQuorumLoss(m.FileSystem, members)
+	return recover.RecoverFromQuorumLoss(m.FileSystem, members, m.args.ArchiveEncryption)
 }
 
-// NewJoinToken creates and records a new join token containing all the necessary credentials for joining a cluster.
-// Join tokens are tied to the server certificate of the joining node, and will be deleted once the node has joined the
-// cluster.
-func (m *MicroCluster) NewJoinToken(ctx context.Context, name string, expireAfter time.Duration) (string, error) {
-	c, err := m.LocalClient()
+// RecoveryEdit renders the local dqlite cluster members as commented YAML, and passes it to edit, which returns the
+// edited YAML, for instance from a text editor. The edited members are validated and passed to RecoverFromQuorumLoss,
+// whose requirements also apply to RecoveryEdit. It returns the path of the recovery tarball.
+// The edit is aborted if no changes are made, if members are added, removed or renamed, if a role is invalid, or if
+// the local member would no longer be a voter.
+func (m *MicroCluster) RecoveryEdit(edit func(membersYaml []byte) ([]byte, error)) (string, error) {
+	members, err := m.GetDqliteClusterMembers()
 	if err != nil {
 		return "", err
 	}
 
-	secret, err := c.RequestToken(ctx, name, expireAfter)
+	membersYaml, err := recover.RenderMembersYaml(members)
 	if err != nil {
 		return "", err
 	}
 
-	return secret, nil
+	content, err := edit(membersYaml)
+	if err != nil {
+		return "", err
+	}
+
+	newMembers, err := recover.ParseMembersYaml(m.FileSystem, members, content)
+	if err != nil {
+		return "", err
+	}
+
+	return recover.RecoverFromQuorumLoss(m.FileSystem, newMembers, m.args.ArchiveEncryption)
 }
 
-// ListJoinTokens lists all the join tokens currently available for use.
-func (m *MicroCluster) ListJoinTokens(ctx context.Context) ([]internalTypes.TokenRecord, error) {
+// Snapshot writes an archive of the state of the local cluster member to the given path, which must not exist: its
+// database, trust store, certificates and daemon configuration. The member can be rebuilt from it with Restore, for
+// instance after reprovisioning the machine.
+// The archive contains the private keys of the member. It is encrypted if ArchiveEncryption has a passphrase, but
+// never with the cluster key, as that key is part of the snapshot.
+// The database files are captured as they are, so the daemon must be stopped.
+func (m *MicroCluster) Snapshot(ctx context.Context, path string) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "unix", m.FileSystem.ControlSocketPath())
+	if err == nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("The daemon must be stopped to take a member state snapshot")
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	return recover.CreateSnapshot(m.FileSystem, path, m.args.ArchiveEncryption)
+}
+
+// Restore rebuilds the state of the local cluster member from the snapshot at the given path, created by Snapshot.
+// The daemon must not be running, and the state directory must not belong to an initialized cluster member. The
+// daemon can then be started, and rejoins the cluster with the name, address and certificates of the snapshot.
+func (m *MicroCluster) Restore(ctx context.Context, path string) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "unix", m.FileSystem.ControlSocketPath())
+	if err == nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("The daemon must be stopped to restore a member state snapshot")
+	}
+
+	return recover.RestoreSnapshot(m.FileSystem, path, m.args.ArchiveEncryption)
+}
+
+// DistributeRecoveryTarball sends the tarball created by RecoverFromQuorumLoss
+// to the cluster members at the given addresses, instead of it being copied
+// manually. The daemons of those members must be running, and must be
+// restarted afterwards to load the tarball. The local daemon does not need to
+// be running.
+//
+// All addresses are attempted, and the returned error lists the members the
+// tarball could not be sent to.
+func (m *MicroCluster) DistributeRecoveryTarball(ctx context.Context, addresses []string) error {
+	return recover.DistributeRecoveryTarball(ctx, m.FileSystem, addresses)
+}
+
+// GetClusterMembers returns the cluster members recorded in the database, with their role, schema versions, API
+// extensions, last heartbeat and clock skew. The status of each member is probed from the local daemon.
+func (m *MicroCluster) GetClusterMembers(ctx context.Context) ([]types.ClusterMember, error) {
+	return m.GetClusterMembersWithOptions(ctx, types.ListOptions{})
+}
+
+// GetClusterMembersWithOptions returns the cluster members selected by the list options, like GetClusterMembers.
+// Supported filters are name, address, role and status.
+func (m *MicroCluster) GetClusterMembersWithOptions(ctx context.Context, opts types.ListOptions) ([]types.ClusterMember, error) {
 	c, err := m.LocalClient()
 	if err != nil {
 		return nil, err
 	}
 
-	records, err := c.GetTokenRecords(ctx)
+	members, err := c.GetClusterMembersWithOptions(ctx, opts)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Failed to get cluster members: %w", err)
 	}
 
-	return records, nil
+	return members, nil
 }
 
-// RevokeJoinToken revokes the token record stored under the given name.
-func (m *MicroCluster) RevokeJoinToken(ctx context.Context, name string) error {
+// RenameClusterMember changes the name of a cluster member, updating the database record and the truststore of all
+// cluster members. The OnMemberRename hook runs on every member so that references to the old name can be updated.
+func (m *MicroCluster) RenameClusterMember(ctx context.Context, oldName string, newName string) error {
 	c, err := m.LocalClient()
 	if err != nil {
 		return err
 	}
 
-	err = c.DeleteTokenRecord(ctx, name)
+	err = c.RenameClusterMember(ctx, oldName, newName)
 	if err != nil {
-		return err
+		return fmt.Errorf("Failed to rename cluster member %q: %w", oldName, err)
 	}
 
 	return nil
 }
 
-// LocalClient returns a client connected to the local control socket.
-func (m *MicroCluster) LocalClient() (*client.Client, error) {
-	c := m.args.Client
-	if c == nil {
-		internalClient, err := internalClient.New(m.FileSystem.ControlSocket(), nil, nil, false)
-		if err != nil {
-			return nil, err
-		}
+// SetMemberMaintenance puts a cluster member under maintenance, or takes it out of maintenance, so that its host can be
+// rebooted without failover churn or false alarms. While under maintenance, the member hands its dqlite voting role and
+// leadership over to other members, answers requests to the consumer's endpoints with 503 Service Unavailable unless
+// they come from the unix socket or from other cluster members, and is listed with the MAINTENANCE status instead of
+// being probed. The member must be reachable for its maintenance to be set.
+func (m *MicroCluster) SetMemberMaintenance(ctx context.Context, name string, on bool) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
 
-		c = &client.Client{Client: *internalClient}
+	err = c.SetClusterMemberMaintenance(ctx, name, on)
+	if err != nil {
+		return fmt.Errorf("Failed to set maintenance of cluster member %q: %w", name, err)
 	}
 
-	if m.args.Proxy != nil {
+	return nil
+}
+
+// RemoveClusterMember removes a cluster member. The member runs its PreRemove hook and is reset, while the remaining
+// members run their PreRemovePeer hook before the removal and their PostRemove and OnMemberRemoved hooks after it.
+// With force, errors from the removed member are ignored, and a member that cannot be reached is evicted from dqlite,
+// the truststore and the database of the remaining cluster without its involvement. If it was a voter, another member
+// is promoted in its place.
+func (m *MicroCluster) RemoveClusterMember(ctx context.Context, name string, force bool) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	err = c.DeleteClusterMember(ctx, name, force)
+	if err != nil {
+		return fmt.Errorf("Failed to remove cluster member %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// ClusterUpgradeStatus returns the progress of a coordinated upgrade. Cluster members that restarted with a newer schema
+// or API extensions wait in the pending stage until all cluster members are ready, and only then commit the schema updates.
+func (m *MicroCluster) ClusterUpgradeStatus(ctx context.Context) (*types.ClusterUpgradeStatus, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	upgradeStatus, err := c.GetUpgradeStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get cluster upgrade status: %w", err)
+	}
+
+	return upgradeStatus, nil
+}
+
+// WaitClusterUpgrade waits for the coordinated upgrade of the local cluster member to complete, without holding a
+// request open for the duration of the upgrade. It returns the final upgrade stage of the cluster member.
+func (m *MicroCluster) WaitClusterUpgrade(ctx context.Context) (types.UpgradeStage, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return "", err
+	}
+
+	stage, err := c.WaitUpgrade(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Failed to wait for the cluster upgrade: %w", err)
+	}
+
+	return stage, nil
+}
+
+// DaemonInfo returns runtime information about the local daemon, such as its uptime, versions, extension servers and
+// resource usage.
+func (m *MicroCluster) DaemonInfo(ctx context.Context) (*types.DaemonInfo, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.GetDaemonInfo(ctx, &c.Client)
+}
+
+// ClusterDaemonInfo returns runtime information about the daemon of every cluster member. Members that cannot be
+// reached are included with their error set.
+func (m *MicroCluster) ClusterDaemonInfo(ctx context.Context) ([]types.DaemonInfo, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.GetClusterDaemonInfo(ctx, &c.Client)
+}
+
+// GetDaemonConfig returns the runtime configuration of the local daemon.
+func (m *MicroCluster) GetDaemonConfig(ctx context.Context) (*types.RuntimeConfig, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetRuntimeConfig(ctx)
+}
+
+// SetDaemonConfig updates the runtime configuration of all cluster members. If the daemon is not yet initialized, the
+// configuration is persisted locally and applied once the daemon bootstraps or joins a cluster, even across restarts.
+// If config.Socket is nil, the control socket settings are left unchanged.
+func (m *MicroCluster) SetDaemonConfig(ctx context.Context, config types.RuntimeConfig) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	err = c.UpdateRuntimeConfig(ctx, config)
+	if err != nil {
+		return fmt.Errorf("Failed to update daemon configuration: %w", err)
+	}
+
+	return nil
+}
+
+// SetControlSocketAccess changes the group and file mode of the control socket on all cluster members, leaving the
+// rest of the runtime configuration unchanged. The settings are applied immediately and persisted, so that a
+// management agent can hand access to the control socket over to another one, such as by changing its group.
+// It must be called over the control socket, and the mode cannot grant access to other users.
+func (m *MicroCluster) SetControlSocketAccess(ctx context.Context, socket types.SocketConfig) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	config, err := c.GetRuntimeConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get daemon configuration: %w", err)
+	}
+
+	config.Socket = &socket
+	err = c.UpdateRuntimeConfig(ctx, *config)
+	if err != nil {
+		return fmt.Errorf("Failed to update control socket access: %w", err)
+	}
+
+	return nil
+}
+
+// NewJoinToken creates and records a new join token containing all the necessary credentials for joining a cluster.
+// Join tokens are tied to the server certificate of the joining node, and will be deleted once the node has joined the
+// cluster.
+func (m *MicroCluster) NewJoinToken(ctx context.Context, name string, expireAfter time.Duration) (string, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := c.RequestToken(ctx, name, expireAfter)
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// NewJoinTokenWithOptions creates and records a new join token with the given options.
+// A token with MaxJoins greater than 1 may be handed to many joining systems, and is not tied to their names.
+// It is deleted once it has been used MaxJoins times, or once it expires.
+// If AllowedSubnets is set, only systems whose address belongs to one of the subnets may join with the token.
+// If Rejoin is set, the token lets the reinstalled cluster member with the given name rejoin with its existing
+// address and dqlite ID, instead of having to be removed and added as a new member.
+func (m *MicroCluster) NewJoinTokenWithOptions(ctx context.Context, tokenRequest JoinTokenRequest) (string, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := c.RequestTokenWithOptions(ctx, tokenRequest)
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// ListJoinTokens lists all the join tokens currently available for use.
+func (m *MicroCluster) ListJoinTokens(ctx context.Context) ([]internalTypes.TokenRecord, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := c.GetTokenRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// RevokeJoinToken revokes the token record stored under the given name.
+func (m *MicroCluster) RevokeJoinToken(ctx context.Context, name string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	err = c.DeleteTokenRecord(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LocalClient returns a client connected to the local control socket.
+func (m *MicroCluster) LocalClient() (*client.Client, error) {
+	c := m.args.Client
+	if c == nil && m.args.ControlTCPAddress != "" {
+		addr, err := types.ParseAddrPort(m.args.ControlTCPAddress)
+		if err != nil {
+			return nil, fmt.Errorf("Received invalid control TCP address %q: %w", m.args.ControlTCPAddress, err)
+		}
+
+		secret, err := m.FileSystem.ControlSecret()
+		if err != nil {
+			return nil, err
+		}
+
+		internalClient, err := internalClient.NewControlTCP(addr, secret)
+		if err != nil {
+			return nil, err
+		}
+
+		c = &client.Client{Client: *internalClient}
+		c.AddInterceptors(m.args.Interceptors...)
+	} else if c == nil {
+		internalClient, err := internalClient.New(m.FileSystem.ControlSocket(), nil, nil, false)
+		if err != nil {
+			return nil, err
+		}
+
+		c = &client.Client{Client: *internalClient}
+		c.AddInterceptors(m.args.Interceptors...)
+	}
+
+	if m.args.Proxy != nil {
 		tx, ok := c.Client.Client.Transport.(*http.Transport)
 		if !ok {
 			return nil, fmt.Errorf("Invalid underlying client transport, expected %T, got %T", &http.Transport{}, c.Client.Client.Transport)
@@ -326,6 +1061,14 @@ func (m *MicroCluster) RemoteClient(address string) (*client.Client, error) {
 	return m.RemoteClientWithCert(address, publicKey)
 }
 
+// ClusterPool returns a pool of clients to every member of the cluster reachable through the given addresses. Reads
+// are routed to the nearest healthy member and writes to the dqlite leader, and the members are re-resolved at the
+// given interval until ctx is cancelled or the pool is closed.
+// The filesystem will be parsed for the cluster and server certificates.
+func (m *MicroCluster) ClusterPool(ctx context.Context, interval time.Duration, addresses ...string) (*client.ClusterPool, error) {
+	return client.NewClusterPool(ctx, addresses, m.RemoteClient, interval)
+}
+
 // RemoteClientWithCert gets a client for the specified cluster member URL using the remote server cert.
 // The filesystem will be parsed for the server client certificate.
 func (m *MicroCluster) RemoteClientWithCert(address string, cert *x509.Certificate) (*client.Client, error) {
@@ -343,6 +1086,7 @@ func (m *MicroCluster) RemoteClientWithCert(address string, cert *x509.Certifica
 		}
 
 		c = &client.Client{Client: *internalClient}
+		c.AddInterceptors(m.args.Interceptors...)
 	}
 
 	if m.args.Proxy != nil {
@@ -358,6 +1102,354 @@ func (m *MicroCluster) RemoteClientWithCert(address string, cert *x509.Certifica
 	return c, nil
 }
 
+// AuditLog returns the entries of the local daemon's audit log recorded since the given time, most recent first.
+// The audit log must be enabled with DaemonArgs.AuditLog.
+func (m *MicroCluster) AuditLog(ctx context.Context, since time.Time, opts types.ListOptions) ([]types.AuditEntry, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetAuditLog(ctx, since, opts)
+}
+
+// TrustedCertificates returns the certificates of the cluster members and of the API clients trusted by the cluster.
+func (m *MicroCluster) TrustedCertificates(ctx context.Context, opts types.ListOptions) ([]types.TrustedCertificate, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetTrustedCertificates(ctx, opts)
+}
+
+// TrustCertificate trusts the certificate of an API client that is not a cluster member, so that it can access the
+// API of every cluster member. Access to the internal API remains restricted to cluster members.
+func (m *MicroCluster) TrustCertificate(ctx context.Context, name string, cert *x509.Certificate) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.AddTrustedCertificate(ctx, types.TrustedCertificatePost{Name: name, Certificate: types.X509Certificate{Certificate: cert}})
+}
+
+// RevokeCertificate revokes the certificate of the API client with the given name, on every cluster member.
+func (m *MicroCluster) RevokeCertificate(ctx context.Context, name string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.DeleteTrustedCertificate(ctx, name)
+}
+
+// GetCertificateRevocations returns the certificates revoked across the cluster.
+func (m *MicroCluster) GetCertificateRevocations(ctx context.Context) ([]types.CertificateRevocation, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetCertificateRevocations(ctx)
+}
+
+// AddCertificateRevocation revokes the certificate with the given fingerprint on every cluster member, with the given
+// reason. Connections presenting the certificate are rejected right away, even if it belongs to a cluster member or
+// a trusted API client, without rotating the cluster certificate.
+func (m *MicroCluster) AddCertificateRevocation(ctx context.Context, fingerprint string, reason string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.AddCertificateRevocation(ctx, types.CertificateRevocationPost{Fingerprint: fingerprint, Reason: reason})
+}
+
+// DeleteCertificateRevocation lifts the revocation of the certificate with the given fingerprint on every cluster member.
+func (m *MicroCluster) DeleteCertificateRevocation(ctx context.Context, fingerprint string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.DeleteCertificateRevocation(ctx, fingerprint)
+}
+
+// Warnings returns the warnings filed about every cluster member, such as expiring certificates or members that
+// are offline. Warnings that have not been acknowledged are also included in the output of Status.
+func (m *MicroCluster) Warnings(ctx context.Context, opts types.ListOptions) ([]types.Warning, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetWarnings(ctx, opts)
+}
+
+// AcknowledgeWarning acknowledges the warning with the given UUID, so that it is no longer included in the output of
+// Status. The warning is kept until the condition it reports is resolved.
+func (m *MicroCluster) AcknowledgeWarning(ctx context.Context, uuid string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.UpdateWarning(ctx, uuid, types.WarningPut{Status: types.WarningStatusAcknowledged})
+}
+
+// DeleteWarning removes the warning with the given UUID. It is filed again if the condition it reports persists.
+func (m *MicroCluster) DeleteWarning(ctx context.Context, uuid string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.DeleteWarning(ctx, uuid)
+}
+
+// PreparedQueries returns the database queries registered in DaemonArgs.PreparedQueries.
+func (m *MicroCluster) PreparedQueries(ctx context.Context) ([]types.PreparedQuery, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetPreparedQueries(ctx)
+}
+
+// RunPreparedQuery runs the named database query registered in DaemonArgs.PreparedQueries with the given arguments,
+// keyed by parameter name.
+func (m *MicroCluster) RunPreparedQuery(ctx context.Context, name string, args map[string]any) (*types.PreparedQueryResult, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.RunPreparedQuery(ctx, name, args)
+}
+
+// CreateSession returns a bearer token standing for the trusted certificate of the API client with the given name,
+// valid for the given duration, or an hour if unset. Clients that can't easily authenticate with their certificate,
+// such as web UIs and scripts, can send it in the Authorization header of their requests to any cluster member.
+// The token is no longer accepted once the certificate is revoked.
+func (m *MicroCluster) CreateSession(ctx context.Context, name string, expiry time.Duration) (*types.Session, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	req := types.SessionPost{Name: name}
+	if expiry != 0 {
+		req.ExpiresAt = time.Now().Add(expiry)
+	}
+
+	return c.CreateSession(ctx, req)
+}
+
+// SchemaRollback reverts the external schema updates of the cluster until the given external schema version is reached,
+// using the rollbacks supplied in DaemonArgs.ExtensionsSchemaRollback. The local daemon takes a database backup in its
+// state directory before making any changes.
+//
+// Cluster members keep running with their current set of schema updates, so they should be restarted with a version
+// whose schema matches the target version. Members that still carry the reverted updates will wait for the rest of
+// the cluster to upgrade before re-applying them.
+func (m *MicroCluster) SchemaRollback(ctx context.Context, targetVersion uint64) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	err = internalClient.RollbackSchema(ctx, &c.Client, internalTypes.SchemaRollback{Version: targetVersion})
+	if err != nil {
+		return fmt.Errorf("Failed to roll back schema: %w", err)
+	}
+
+	return nil
+}
+
+// ClusterExtensions returns the API extensions supported by every cluster member, and the members that lack some of
+// the extensions supported by other members, for instance while the cluster is being upgraded.
+func (m *MicroCluster) ClusterExtensions(ctx context.Context) (*types.ClusterExtensions, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetClusterExtensions(ctx)
+}
+
+// ValidateLocalState cross-checks the trust store, the go-dqlite cluster.yaml and info.yaml files of the local cluster
+// member against the core_cluster_members table and the dqlite raft configuration, and reports stale addresses,
+// orphaned remotes and other mismatches.
+// If repair is true, the trust store and cluster.yaml are rewritten to match the cluster membership. Mismatches in
+// info.yaml or in the database itself are only reported, as they require the member to be restarted, recovered, or
+// removed from the cluster.
+func (m *MicroCluster) ValidateLocalState(ctx context.Context, repair bool) (*types.ConsistencyReport, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.CheckConsistency(ctx, &c.Client, repair)
+}
+
+// GetLogConfig returns the logging configuration of the local daemon.
+func (m *MicroCluster) GetLogConfig(ctx context.Context) (*types.LogConfig, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetLogConfig(ctx)
+}
+
+// SetLogConfig changes the level and target of the log of the local daemon, without restarting it. Unset fields are
+// left unchanged. The change is not persisted, so the daemon logs according to its DaemonArgs again once restarted.
+func (m *MicroCluster) SetLogConfig(ctx context.Context, config types.LogConfig) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.UpdateLogConfig(ctx, config)
+}
+
+// SetLogLevel changes the level of the log of the local daemon, without restarting it.
+func (m *MicroCluster) SetLogLevel(ctx context.Context, level types.LogLevel) error {
+	return m.SetLogConfig(ctx, types.LogConfig{Level: level})
+}
+
+// DatabaseStats returns statistics about the statements run against the database by the local cluster member,
+// such as how often each one was run, by which function, and how long it took.
+func (m *MicroCluster) DatabaseStats(ctx context.Context) (*types.DatabaseStats, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.GetDatabaseStats(ctx, &c.Client)
+}
+
+// Database opens the database of the cluster through the dqlite driver, for tooling running outside the daemon, such
+// as migrations or data fixers. The local cluster member must be initialized, and its certificates readable by the
+// calling process. If readOnly is set, statements that modify the database fail.
+// The returned handle must be closed by the caller.
+func (m *MicroCluster) Database(ctx context.Context, readOnly bool) (*sql.DB, error) {
+	return db.OpenExternal(ctx, m.FileSystem, readOnly)
+}
+
+// SchemaStatus returns the schema versions of the local cluster member and of every cluster member recorded in the
+// database, along with the statements creating the database schema. Upgrade tooling can use it to verify that all
+// cluster members have converged on the same schema before proceeding.
+func (m *MicroCluster) SchemaStatus(ctx context.Context) (*types.DatabaseSchema, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.GetDatabaseSchema(ctx, &c.Client)
+}
+
+// DatabaseUsage returns the space used by the database, and its disk usage on each cluster member.
+func (m *MicroCluster) DatabaseUsage(ctx context.Context) (*types.DatabaseUsage, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.GetDatabaseUsage(ctx, &c.Client)
+}
+
+// MaintainDatabase runs the given maintenance, such as a vacuum, on the database through the dqlite leader, and
+// returns the space used by the database afterwards.
+func (m *MicroCluster) MaintainDatabase(ctx context.Context, maintenance types.DatabaseMaintenance) (*types.DatabaseUsage, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.MaintainDatabase(ctx, &c.Client, maintenance)
+}
+
+// RaftFiles returns the raft metadata, segment and snapshot files of the dqlite node of the local cluster member,
+// along with their index ranges and sizes.
+func (m *MicroCluster) RaftFiles(ctx context.Context) ([]types.DatabaseRaftFile, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.GetRaftFiles(ctx, &c.Client)
+}
+
+// CompactRaftLog removes the raft snapshots and segments that the dqlite node of the local cluster member no longer
+// needs, such as ones left behind when the daemon stopped before raft cleaned them up. Closed segments are kept if
+// they hold any of the given number of trailing entries before the latest snapshot, which should match the
+// DqliteOptions snapshot trailing the daemon is started with, or the dqlite default if it is 0.
+// Raft owns these files while the database is running, so the daemon must be stopped.
+func (m *MicroCluster) CompactRaftLog(trailing uint64) (*types.DatabaseRaftCompaction, error) {
+	isSocketPresent, err := m.FileSystem.IsControlSocketPresent()
+	if err != nil {
+		return nil, err
+	}
+
+	if isSocketPresent {
+		return nil, fmt.Errorf("Daemon is running (socket path exists: %q)", m.FileSystem.ControlSocketPath())
+	}
+
+	compaction, err := db.CompactRaftFiles(m.FileSystem.DatabaseDir, trailing)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to compact raft log: %w", err)
+	}
+
+	return &compaction, nil
+}
+
+// DumpRaftState reads the raft state and lists the raft files of the local dqlite data directory, without going
+// through the daemon. It can be used while the database is stopped, such as to compare the last raft index of each
+// cluster member and find the one with the most up-to-date raft log before calling RecoverFromQuorumLoss.
+// The raft files may change while they are read if the database is running.
+func (m *MicroCluster) DumpRaftState() (*types.DatabaseRaftDump, error) {
+	dump, err := db.ReadRaftDump(m.FileSystem.DatabaseDir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read raft state: %w", err)
+	}
+
+	return &dump, nil
+}
+
+// DatabaseBackfills returns the progress of the data backfills of the cluster.
+func (m *MicroCluster) DatabaseBackfills(ctx context.Context) ([]types.DatabaseBackfill, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.GetDatabaseBackfills(ctx, &c.Client)
+}
+
+// DatabaseBackups returns the database backups in the state directory of the local cluster member, from oldest to newest.
+func (m *MicroCluster) DatabaseBackups(ctx context.Context) ([]types.DatabaseBackup, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetDatabaseBackups(ctx)
+}
+
+// DownloadDatabaseBackup writes the database backup with the given name, from the state directory of the local cluster
+// member, to w.
+func (m *MicroCluster) DownloadDatabaseBackup(ctx context.Context, name string, w io.Writer) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.DownloadDatabaseBackup(ctx, name, w)
+}
+
 // SQL performs either a GET or POST on /internal/sql with a given query. This is a useful helper for using direct SQL.
 func (m *MicroCluster) SQL(ctx context.Context, query string) (string, *internalTypes.SQLBatch, error) {
 	if query == "-" {
@@ -392,3 +1484,14 @@ func (m *MicroCluster) SQL(ctx context.Context, query string) (string, *internal
 
 	return "", batch, err
 }
+
+// SQLTransaction executes the given statements with their bind parameters in a single transaction, returning the
+// result of each statement in order. If any statement fails, none of the changes are committed.
+func (m *MicroCluster) SQLTransaction(ctx context.Context, statements []internalTypes.SQLStatement) (*internalTypes.SQLBatch, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return internalClient.PostSQLTransaction(ctx, &c.Client, internalTypes.SQLTransaction{Statements: statements})
+}
@@ -0,0 +1,133 @@
+package microcluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/client"
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Ensures the initialization request is only sent once the progress stream is subscribed to, and that the stages
+// reached by the daemon are received until it responds.
+func TestControlWithProgress(t *testing.T) {
+	cases := []struct {
+		name         string
+		subscribe    bool
+		unavailable  bool
+		controlErr   bool
+		expectSent   bool
+		expectStages []types.InitStage
+		expectErr    string
+	}{
+		{name: "Successful initialization", subscribe: true, expectSent: true, expectStages: []types.InitStage{types.InitStageDatabase, types.InitStageComplete}},
+		{name: "Failed initialization", subscribe: true, controlErr: true, expectSent: true, expectErr: "Failed to join the cluster"},
+		{name: "Progress stream unavailable", unavailable: true, expectErr: "Failed to watch initialization progress"},
+		{name: "Daemon never subscribes", expectErr: context.DeadlineExceeded.Error()},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var mu sync.Mutex
+			sent := false
+			controlled := make(chan struct{})
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /core/control/progress", func(w http.ResponseWriter, r *http.Request) {
+				if c.unavailable {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"type": "error", "error_code": 404, "error": "not found"}`))
+
+					return
+				}
+
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				if !c.subscribe {
+					<-r.Context().Done()
+					return
+				}
+
+				_, _ = fmt.Fprint(w, "event: subscribed\ndata: {}\n\n")
+				w.(http.Flusher).Flush()
+
+				select {
+				case <-controlled:
+				case <-r.Context().Done():
+					return
+				}
+
+				for _, stage := range c.expectStages {
+					data, _ := json.Marshal(types.InitProgress{Stage: stage})
+					_, _ = fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+					w.(http.Flusher).Flush()
+				}
+
+				// The stream only ends on its own once the initialization is complete.
+				if !c.controlErr {
+					return
+				}
+
+				<-r.Context().Done()
+			})
+
+			mux.HandleFunc("POST /core/control", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				sent = true
+				mu.Unlock()
+
+				close(controlled)
+				if c.controlErr {
+					w.WriteHeader(http.StatusInternalServerError)
+					_, _ = w.Write([]byte(`{"type": "error", "error_code": 500, "error": "Failed to join the cluster"}`))
+
+					return
+				}
+
+				_, _ = w.Write([]byte(`{"type": "sync", "status": "Success", "status_code": 200}`))
+			})
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			daemonClient, err := internalClient.New(*api.NewURL().Scheme("http").Host(server.Listener.Addr().String()), nil, nil, false)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			stages := []types.InitStage{}
+			err = controlWithProgress(ctx, &client.Client{Client: *daemonClient}, internalTypes.Control{}, func(progress types.InitProgress) {
+				stages = append(stages, progress.Stage)
+			})
+
+			if c.expectErr != "" {
+				require.ErrorContains(t, err, c.expectErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.Equal(t, c.expectSent, sent)
+
+			if c.expectStages != nil {
+				require.Equal(t, c.expectStages, stages)
+			} else {
+				require.Empty(t, stages)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/spf13/cobra"
+
+	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/microcluster"
+)
+
+// NewClusterCmd returns the command used to list and remove cluster members, to put them under maintenance, to check
+// the consistency of the local state, and to recover the cluster if quorum is lost.
+func NewClusterCmd(opts Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cluster",
+		Short: "Manage cluster members",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newClusterListCmd(opts))
+	cmd.AddCommand(newClusterRemoveCmd(opts))
+	cmd.AddCommand(newClusterMaintenanceCmd(opts))
+	cmd.AddCommand(newClusterCheckCmd(opts))
+	cmd.AddCommand(NewRecoverCmd(opts))
+
+	return cmd
+}
+
+type cmdClusterList struct {
+	opts Options
+
+	flagLocal  bool
+	flagFormat string
+}
+
+func newClusterListCmd(opts Options) *cobra.Command {
+	c := &cmdClusterList{opts: opts}
+	cmd := &cobra.Command{
+		Use:   "list [<address>]",
+		Short: "List cluster members locally, or remotely if an address is specified",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().BoolVarP(&c.flagLocal, "local", "l", false, "Display the locally available cluster info (no database query)")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", cli.TableFormatTable, "Format (csv|json|table|yaml|compact)")
+
+	return cmd
+}
+
+func (c *cmdClusterList) run(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return cmd.Help()
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	if c.flagLocal {
+		return c.listLocal(m)
+	}
+
+	var client *client.Client
+
+	// Get a local client connected to the unix socket if no address is specified.
+	if len(args) == 1 {
+		client, err = m.RemoteClient(args[0])
+	} else {
+		client, err = m.LocalClient()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.list(cmd.Context(), client)
+}
+
+func (c *cmdClusterList) list(ctx context.Context, client *client.Client) error {
+	clusterMembers, err := client.GetClusterMembers(ctx)
+	if err != nil {
+		return err
+	}
+
+	data := make([][]string, len(clusterMembers))
+	for i, clusterMember := range clusterMembers {
+		latency := "-"
+		if clusterMember.Latency > 0 {
+			latency = clusterMember.Latency.Round(time.Microsecond).String()
+		}
+
+		data[i] = []string{clusterMember.Name, clusterMember.Address.String(), clusterMember.Role, shared.CertFingerprint(clusterMember.Certificate.Certificate), string(clusterMember.Status), latency}
+	}
+
+	header := []string{"NAME", "ADDRESS", "ROLE", "FINGERPRINT", "STATUS", "LATENCY"}
+	sort.Sort(cli.SortColumnsNaturally(data))
+
+	return cli.RenderTable(c.flagFormat, header, data, clusterMembers)
+}
+
+func (c *cmdClusterList) listLocal(m *microcluster.MicroCluster) error {
+	members, err := m.GetDqliteClusterMembers()
+	if err != nil {
+		return err
+	}
+
+	data := make([][]string, len(members))
+	for i, member := range members {
+		data[i] = []string{strconv.FormatUint(member.DqliteID, 10), member.Name, member.Address, member.Role}
+	}
+
+	header := []string{"ID", "NAME", "ADDRESS", "ROLE"}
+	sort.Sort(cli.SortColumnsNaturally(data))
+
+	return cli.RenderTable(c.flagFormat, header, data, members)
+}
+
+type cmdClusterRemove struct {
+	opts Options
+
+	flagForce bool
+}
+
+func newClusterRemoveCmd(opts Options) *cobra.Command {
+	c := &cmdClusterRemove{opts: opts}
+	cmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove the cluster member with the given name",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, "Forcibly remove the cluster member")
+
+	return cmd
+}
+
+func (c *cmdClusterRemove) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	return m.RemoveClusterMember(cmd.Context(), args[0], c.flagForce)
+}
+
+type cmdClusterMaintenance struct {
+	opts Options
+
+	flagDisable bool
+}
+
+func newClusterMaintenanceCmd(opts Options) *cobra.Command {
+	c := &cmdClusterMaintenance{opts: opts}
+	cmd := &cobra.Command{
+		Use:   "maintenance <name>",
+		Short: "Put the cluster member with the given name under maintenance",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().BoolVar(&c.flagDisable, "disable", false, "Take the cluster member out of maintenance")
+
+	return cmd
+}
+
+func (c *cmdClusterMaintenance) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	return m.SetMemberMaintenance(cmd.Context(), args[0], !c.flagDisable)
+}
+
+type cmdClusterCheck struct {
+	opts Options
+
+	flagRepair bool
+	flagFormat string
+}
+
+func newClusterCheckCmd(opts Options) *cobra.Command {
+	c := &cmdClusterCheck{opts: opts}
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check the local trust store and dqlite configuration against the cluster members",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().BoolVar(&c.flagRepair, "repair", false, "Repair the mismatches where possible")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", cli.TableFormatTable, "Format (csv|json|table|yaml|compact)")
+
+	return cmd
+}
+
+func (c *cmdClusterCheck) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	report, err := m.ValidateLocalState(cmd.Context(), c.flagRepair)
+	if err != nil {
+		return err
+	}
+
+	data := make([][]string, len(report.Issues))
+	for i, issue := range report.Issues {
+		data[i] = []string{string(issue.Source), issue.Member, issue.Description, issue.Expected, issue.Found, strconv.FormatBool(issue.Repaired)}
+	}
+
+	header := []string{"SOURCE", "MEMBER", "DESCRIPTION", "EXPECTED", "FOUND", "REPAIRED"}
+
+	return cli.RenderTable(c.flagFormat, header, data, report)
+}
@@ -0,0 +1,47 @@
+// Package cmd provides ready-made cobra commands to manage a MicroCluster daemon, for use in the command line tools
+// of MicroCluster based projects.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canonical/microcluster/v3/microcluster"
+)
+
+// Options configures the commands.
+type Options struct {
+	// Name is the name of the command line tool, used in the examples of each command.
+	Name string
+
+	// Args returns the arguments used to access the daemon. It is called each time a command runs, so that it can
+	// read the values of persistent flags such as the state directory. If unset, the default state directory is used.
+	Args func() microcluster.Args
+}
+
+// app returns the MicroCluster instance used to run a command.
+func (o Options) app() (*microcluster.MicroCluster, error) {
+	args := microcluster.Args{}
+	if o.Args != nil {
+		args = o.Args()
+	}
+
+	m, err := microcluster.App(args)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to configure MicroCluster: %w", err)
+	}
+
+	return m, nil
+}
+
+// Commands returns all the commands of the package, to be added to the root command of a command line tool.
+func Commands(opts Options) []*cobra.Command {
+	return []*cobra.Command{
+		NewInitCmd(opts),
+		NewTokensCmd(opts),
+		NewClusterCmd(opts),
+		NewStatusCmd(opts),
+		NewSQLCmd(opts),
+	}
+}
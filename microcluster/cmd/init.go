@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canonical/microcluster/v3/microcluster"
+)
+
+type cmdInit struct {
+	opts Options
+
+	flagBootstrap bool
+	flagToken     string
+	flagConfig    []string
+	flagListen    string
+	flagLocal     bool
+	flagDryRun    bool
+	flagPreseed   bool
+}
+
+// NewInitCmd returns the command used to bootstrap a new cluster, or to join an existing one.
+func NewInitCmd(opts Options) *cobra.Command {
+	c := &cmdInit{opts: opts}
+	cmd := &cobra.Command{
+		Use:   "init <name> [<address>]",
+		Short: "Initialize the network endpoint and create or join a new cluster",
+		RunE:  c.run,
+		Example: fmt.Sprintf(`  %[1]s init member1 127.0.0.1:8443 --bootstrap
+    %[1]s init member1 127.0.0.1:8443 --token <token>
+    %[1]s init member1 127.0.0.1:8443 --token <token> --dry-run
+    %[1]s init member1 [2001:db8::1]:8443 --listen-address [::]:8443 --bootstrap
+    %[1]s init member1 --local
+    %[1]s init --preseed < preseed.yaml`, opts.Name),
+	}
+
+	cmd.Flags().BoolVar(&c.flagBootstrap, "bootstrap", false, "Configure a new cluster with this daemon")
+	cmd.Flags().StringVar(&c.flagToken, "token", "", "Join a cluster with a join token")
+	cmd.Flags().StringSliceVar(&c.flagConfig, "config", nil, "Extra configuration to be applied during bootstrap")
+	cmd.Flags().StringVar(&c.flagListen, "listen-address", "", "Address to listen on, if different from the advertised address")
+	cmd.Flags().BoolVar(&c.flagLocal, "local", false, "Configure a new single-node cluster that is only reachable locally")
+	cmd.Flags().BoolVar(&c.flagDryRun, "dry-run", false, "Check whether the cluster can be joined with the token, without joining it")
+	cmd.Flags().BoolVar(&c.flagPreseed, "preseed", false, "Initialize the daemon according to a YAML preseed read from stdin")
+	cmd.MarkFlagsMutuallyExclusive("bootstrap", "token", "local", "preseed")
+	cmd.MarkFlagsMutuallyExclusive("listen-address", "local")
+
+	return cmd
+}
+
+func (c *cmdInit) run(cmd *cobra.Command, args []string) error {
+	if c.flagPreseed {
+		return c.preseed(cmd, args)
+	}
+
+	if (c.flagLocal && len(args) != 1) || (!c.flagLocal && len(args) != 2) {
+		return cmd.Help()
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	conf := make(map[string]string, len(c.flagConfig))
+	for _, setting := range c.flagConfig {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return fmt.Errorf("Malformed additional configuration value %s", setting)
+		}
+
+		conf[key] = value
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+	defer cancel()
+
+	if c.flagDryRun && c.flagToken == "" {
+		return fmt.Errorf("Only joining with a token can be checked with --dry-run")
+	}
+
+	if c.flagLocal {
+		return m.NewLocalCluster(ctx, args[0], conf)
+	}
+
+	if c.flagBootstrap {
+		if c.flagListen != "" {
+			return m.NewClusterWithListenAddress(ctx, args[0], args[1], c.flagListen, conf)
+		}
+
+		return m.NewCluster(ctx, args[0], args[1], conf)
+	}
+
+	if c.flagToken != "" && c.flagDryRun {
+		return c.preflight(ctx, cmd, m, args[0], args[1])
+	}
+
+	if c.flagToken != "" {
+		if c.flagListen != "" {
+			return m.JoinClusterWithListenAddress(ctx, args[0], args[1], c.flagListen, c.flagToken, conf)
+		}
+
+		return m.JoinCluster(ctx, args[0], args[1], c.flagToken, conf)
+	}
+
+	return fmt.Errorf("Option must be one of bootstrap or token")
+}
+
+// preseed initializes the daemon according to the preseed read from stdin.
+func (c *cmdInit) preseed(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	preseed, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return fmt.Errorf("Failed to read preseed: %w", err)
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+	defer cancel()
+
+	return m.Init(ctx, preseed)
+}
+
+// preflight prints the outcome of the checks run before joining the cluster with the token.
+func (c *cmdInit) preflight(ctx context.Context, cmd *cobra.Command, m *microcluster.MicroCluster, name string, address string) error {
+	preflight, err := m.PreflightJoin(ctx, name, address, c.flagToken)
+	if preflight == nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Checks run by cluster member %s:\n", preflight.Address.String())
+	for _, check := range preflight.Checks {
+		result := "OK"
+		if check.Error != "" {
+			result = "FAILED: " + check.Error
+		}
+
+		fmt.Fprintf(out, " - %s: %s\n", check.Name, result)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Cluster cannot be joined")
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/termios"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+const recoveryConfirmation = `You should only run this command if:
+ - A quorum of cluster members is permanently lost
+ - You are *absolutely* sure all daemons are stopped
+ - This instance has the most up to date database
+
+Do you want to proceed? (yes/no): `
+
+type cmdRecover struct {
+	opts Options
+
+	flagDistributeTo []string
+}
+
+// NewRecoverCmd returns the command used to recover the cluster from this member if quorum is lost.
+// The new member roles are edited interactively, or read as YAML from stdin if it is not a terminal.
+func NewRecoverCmd(opts Options) *cobra.Command {
+	c := &cmdRecover{opts: opts}
+	cmd := &cobra.Command{
+		Use:     "recover",
+		Aliases: []string{"edit"},
+		Short:   "Recover the cluster from this member if quorum is lost",
+		RunE:    c.run,
+	}
+
+	cmd.Flags().StringSliceVar(&c.flagDistributeTo, "distribute-to", nil, "Send the recovery tarball to the cluster members at the given addresses")
+
+	return cmd
+}
+
+func (c *cmdRecover) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+
+	if termios.IsTerminal(unix.Stdin) {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Fprint(out, recoveryConfirmation)
+
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSuffix(input, "\n")
+
+		if strings.ToLower(input) != "yes" {
+			fmt.Fprintln(out, "Cluster recovery aborted; no changes made")
+			return nil
+		}
+	}
+
+	tarballPath, err := m.RecoveryEdit(func(membersYaml []byte) ([]byte, error) {
+		if !termios.IsTerminal(unix.Stdin) {
+			return io.ReadAll(os.Stdin)
+		}
+
+		return shared.TextEditor("", membersYaml)
+	})
+	if err != nil {
+		return fmt.Errorf("Cluster recovery: %w", err)
+	}
+
+	fmt.Fprintf(out, "Cluster changes applied; new database state saved to %s\n\n", tarballPath)
+
+	if len(c.flagDistributeTo) > 0 {
+		err = m.DistributeRecoveryTarball(cmd.Context(), c.flagDistributeTo)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Recovery tarball sent to %s.\n\n", strings.Join(c.flagDistributeTo, ", "))
+	} else {
+		fmt.Fprintf(out, "*Before* starting any cluster member, copy %s to %s on all remaining cluster members.\n\n", tarballPath, tarballPath)
+	}
+
+	fmt.Fprintln(out, "The daemon will load this file during startup.")
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// NewSQLCmd returns the command used to run SQL queries against the cluster database.
+func NewSQLCmd(opts Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "sql <query>",
+		Short: "Execute a SQL query against the daemon",
+		Example: fmt.Sprintf(`  %[1]s sql "SELECT * FROM core_cluster_members"
+    %[1]s sql .dump`, opts.Name),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			m, err := opts.app()
+			if err != nil {
+				return err
+			}
+
+			dump, batch, err := m.SQL(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if dump != "" {
+				fmt.Fprint(out, dump)
+				return nil
+			}
+
+			for i, result := range batch.Results {
+				if len(batch.Results) > 1 {
+					fmt.Fprintf(out, "=> Query %d:\n\n", i)
+				}
+
+				if result.Type == "select" {
+					printSelectResult(out, result.Columns, result.Rows)
+				} else {
+					fmt.Fprintf(out, "Rows affected: %d\n", result.RowsAffected)
+				}
+
+				if len(batch.Results) > 1 {
+					fmt.Fprintln(out)
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// printSelectResult renders the rows returned by a query as a table.
+func printSelectResult(out io.Writer, columns []string, rows [][]any) {
+	table := tablewriter.NewWriter(out)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader(columns)
+	for _, row := range rows {
+		data := []string{}
+		for _, col := range row {
+			data = append(data, fmt.Sprintf("%v", col))
+		}
+
+		table.Append(data)
+	}
+
+	table.Render()
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/spf13/cobra"
+)
+
+type cmdStatus struct {
+	opts Options
+
+	flagFormat string
+}
+
+// NewStatusCmd returns the command used to show the status of the local daemon, and of the cluster members as seen
+// by the local daemon.
+func NewStatusCmd(opts Options) *cobra.Command {
+	c := &cmdStatus{opts: opts}
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the daemon and of the cluster members",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", cli.TableFormatTable, "Format (csv|json|table|yaml|compact)")
+
+	return cmd
+}
+
+func (c *cmdStatus) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	status, err := m.Status(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if c.flagFormat == cli.TableFormatTable {
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "Name: %s\n", status.Name)
+		fmt.Fprintf(out, "Address: %s\n", status.Address.String())
+		fmt.Fprintf(out, "Version: %s\n", status.Version)
+		fmt.Fprintf(out, "Ready: %t\n", status.Ready)
+
+		if len(status.Members) == 0 {
+			return nil
+		}
+
+		fmt.Fprintln(out)
+	}
+
+	data := make([][]string, len(status.Members))
+	for i, member := range status.Members {
+		latency := "-"
+		if member.Latency > 0 {
+			latency = member.Latency.Round(time.Microsecond).String()
+		}
+
+		lastHeartbeat := "-"
+		if !member.LastHeartbeat.IsZero() {
+			lastHeartbeat = member.LastHeartbeat.Format(time.RFC3339)
+		}
+
+		data[i] = []string{member.Name, member.Address.String(), string(member.Status), lastHeartbeat, latency}
+	}
+
+	header := []string{"NAME", "ADDRESS", "STATUS", "LAST HEARTBEAT", "LATENCY"}
+	sort.Sort(cli.SortColumnsNaturally(data))
+
+	return cli.RenderTable(c.flagFormat, header, data, status)
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/spf13/cobra"
+
+	"github.com/canonical/microcluster/v3/microcluster"
+)
+
+// NewTokensCmd returns the command used to add, list and revoke join tokens.
+func NewTokensCmd(opts Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Manage join tokens",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newTokensAddCmd(opts))
+	cmd.AddCommand(newTokensListCmd(opts))
+	cmd.AddCommand(newTokensRevokeCmd(opts))
+
+	return cmd
+}
+
+type cmdTokensAdd struct {
+	opts Options
+
+	flagExpireAfter    string
+	flagMaxJoins       int
+	flagAllowedSubnets []string
+	flagRejoin         bool
+}
+
+func newTokensAddCmd(opts Options) *cobra.Command {
+	c := &cmdTokensAdd{opts: opts}
+	cmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Add a new join token under the given name",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().StringVarP(&c.flagExpireAfter, "expire-after", "e", "3h", "Set the lifetime for the token")
+	cmd.Flags().IntVar(&c.flagMaxJoins, "max-joins", 1, "Number of systems that may join with the token")
+	cmd.Flags().StringSliceVar(&c.flagAllowedSubnets, "allowed-subnet", nil, "Only allow joining systems with an address in the given CIDR subnet")
+	cmd.Flags().BoolVar(&c.flagRejoin, "rejoin", false, "Let the reinstalled cluster member with the given name rejoin with its existing identity")
+
+	return cmd
+}
+
+func (c *cmdTokensAdd) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	expireAfter, err := time.ParseDuration(c.flagExpireAfter)
+	if err != nil {
+		return fmt.Errorf("Invalid value for expire-after: %w", err)
+	}
+
+	token, err := m.NewJoinTokenWithOptions(cmd.Context(), microcluster.JoinTokenRequest{
+		Name:           args[0],
+		ExpireAfter:    expireAfter,
+		MaxJoins:       c.flagMaxJoins,
+		AllowedSubnets: c.flagAllowedSubnets,
+		Rejoin:         c.flagRejoin,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), token)
+
+	return nil
+}
+
+type cmdTokensList struct {
+	opts Options
+
+	flagFormat string
+}
+
+func newTokensListCmd(opts Options) *cobra.Command {
+	c := &cmdTokensList{opts: opts}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List join tokens available for use",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", cli.TableFormatTable, "Format (csv|json|table|yaml|compact)")
+
+	return cmd
+}
+
+func (c *cmdTokensList) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := c.opts.app()
+	if err != nil {
+		return err
+	}
+
+	records, err := m.ListJoinTokens(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	data := make([][]string, len(records))
+	for i, record := range records {
+		data[i] = []string{record.Name, record.Token, record.ExpiresAt.String(), fmt.Sprintf("%d/%d", record.Joins, record.MaxJoins)}
+	}
+
+	header := []string{"NAME", "TOKENS", "EXPIRES AT", "JOINS"}
+	sort.Sort(cli.SortColumnsNaturally(data))
+
+	return cli.RenderTable(c.flagFormat, header, data, records)
+}
+
+func newTokensRevokeCmd(opts Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <name>",
+		Short: "Revoke the join token with the given name",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			m, err := opts.app()
+			if err != nil {
+				return err
+			}
+
+			return m.RevokeJoinToken(cmd.Context(), args[0])
+		},
+	}
+}
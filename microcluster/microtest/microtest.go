@@ -0,0 +1,248 @@
+// Package microtest runs clusters of in-process MicroCluster daemons for integration tests.
+package microtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/microcluster"
+)
+
+// DefaultTimeout bounds how long NewCluster waits for the daemons to start and form a cluster, if the context has no deadline.
+const DefaultTimeout = 2 * time.Minute
+
+// Options configures a test cluster.
+type Options struct {
+	// Members is the number of daemons to start. It defaults to 1.
+	Members int
+
+	// DaemonArgs returns the arguments used to start the daemon with the given index.
+	// If unset, or if the returned arguments have no version, the version is set to "test".
+	DaemonArgs func(index int) microcluster.DaemonArgs
+
+	// InitConfig is passed to the bootstrap and join hooks of every member.
+	InitConfig map[string]string
+
+	// StateDir is the directory under which the state directory of each member is created.
+	// If empty, a temporary directory is created and removed when the cluster is closed.
+	StateDir string
+}
+
+// Member is a daemon of a test cluster.
+type Member struct {
+	// Name is the cluster member name, such as "member-0".
+	Name string
+
+	// Address is the address of the member's core API.
+	Address string
+
+	// App is the MicroCluster instance managing the daemon.
+	App *microcluster.MicroCluster
+
+	// Client is connected to the daemon's control socket.
+	Client *client.Client
+
+	cancel context.CancelFunc
+	done   chan error
+}
+
+// Cluster is a set of in-process daemons that have formed a cluster.
+type Cluster struct {
+	// Members holds the daemons in the order they joined. The first member bootstrapped the cluster.
+	Members []*Member
+
+	stateDir   string
+	removeDirs bool
+}
+
+// Start starts a test cluster, failing the test if it cannot be formed. The cluster is closed when the test ends.
+func Start(t testing.TB, opts Options) *Cluster {
+	t.Helper()
+
+	c, err := NewCluster(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Failed to start test cluster: %v", err)
+	}
+
+	t.Cleanup(func() {
+		err := c.Close()
+		if err != nil {
+			t.Errorf("Failed to stop test cluster: %v", err)
+		}
+	})
+
+	return c
+}
+
+// NewCluster starts the configured number of daemons, each with its own state directory and a free local address.
+// The first daemon bootstraps the cluster and the others join it one at a time.
+// The returned cluster must be closed to stop the daemons.
+func NewCluster(ctx context.Context, opts Options) (*Cluster, error) {
+	if opts.Members <= 0 {
+		opts.Members = 1
+	}
+
+	_, ok := ctx.Deadline()
+	if !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+
+	c := &Cluster{stateDir: opts.StateDir}
+	if c.stateDir == "" {
+		dir, err := os.MkdirTemp("", "microtest-")
+		if err != nil {
+			return nil, err
+		}
+
+		c.stateDir = dir
+		c.removeDirs = true
+	}
+
+	err := c.start(ctx, opts)
+	if err != nil {
+		return nil, errors.Join(err, c.Close())
+	}
+
+	return c, nil
+}
+
+// start starts each daemon and adds it to the cluster.
+func (c *Cluster) start(ctx context.Context, opts Options) error {
+	for i := 0; i < opts.Members; i++ {
+		member, err := c.startMember(ctx, i, opts)
+		if err != nil {
+			return err
+		}
+
+		c.Members = append(c.Members, member)
+
+		if i == 0 {
+			err = member.App.NewCluster(ctx, member.Name, member.Address, opts.InitConfig)
+			if err != nil {
+				return fmt.Errorf("Failed to bootstrap %q: %w", member.Name, err)
+			}
+
+			continue
+		}
+
+		token, err := c.Members[0].App.NewJoinToken(ctx, member.Name, 0)
+		if err != nil {
+			return fmt.Errorf("Failed to issue join token for %q: %w", member.Name, err)
+		}
+
+		err = member.App.JoinCluster(ctx, member.Name, member.Address, token, opts.InitConfig)
+		if err != nil {
+			return fmt.Errorf("Failed to join %q to the cluster: %w", member.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// startMember starts the daemon with the given index and waits for it to be ready.
+func (c *Cluster) startMember(ctx context.Context, index int, opts Options) (*Member, error) {
+	name := fmt.Sprintf("member-%d", index)
+
+	address, err := freeAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := microcluster.App(microcluster.Args{StateDir: filepath.Join(c.stateDir, name)})
+	if err != nil {
+		return nil, err
+	}
+
+	daemonArgs := microcluster.DaemonArgs{}
+	if opts.DaemonArgs != nil {
+		daemonArgs = opts.DaemonArgs(index)
+	}
+
+	if daemonArgs.Version == "" {
+		daemonArgs.Version = "test"
+	}
+
+	// The daemon outlives the context used to form the cluster, and is stopped by Close.
+	daemonCtx, cancel := context.WithCancel(context.Background())
+	member := &Member{
+		Name:    name,
+		Address: address,
+		App:     app,
+		cancel:  cancel,
+		done:    make(chan error, 1),
+	}
+
+	go func() {
+		member.done <- app.Start(daemonCtx, daemonArgs)
+	}()
+
+	err = app.Ready(ctx)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("Daemon %q did not become ready: %w", name, err), member.stop())
+	}
+
+	member.Client, err = app.LocalClient()
+	if err != nil {
+		return nil, errors.Join(err, member.stop())
+	}
+
+	return member, nil
+}
+
+// Close stops all daemons, the most recently joined first, and removes their state directories if they were
+// created by NewCluster.
+func (c *Cluster) Close() error {
+	var errs []error
+	for i := len(c.Members) - 1; i >= 0; i-- {
+		err := c.Members[i].stop()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to stop %q: %w", c.Members[i].Name, err))
+		}
+	}
+
+	c.Members = nil
+
+	if c.removeDirs {
+		err := os.RemoveAll(c.stateDir)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// stop gracefully shuts the daemon down, and waits for it to exit.
+func (m *Member) stop() error {
+	if m.Client != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		_ = m.App.Shutdown(ctx)
+		cancel()
+	}
+
+	// Cancelling the daemon's context stops it if it was not shut down gracefully.
+	m.cancel()
+
+	return <-m.done
+}
+
+// freeAddress returns a local address with a port that is currently unused.
+func freeAddress() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = listener.Close() }()
+
+	return listener.Addr().String(), nil
+}
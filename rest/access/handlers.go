@@ -4,10 +4,12 @@ import (
 	"crypto/x509"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/logger"
 
 	"github.com/canonical/microcluster/v3/internal/endpoints"
@@ -49,15 +51,33 @@ func AllowAuthenticated(state state.State, r *http.Request) (bool, response.Resp
 
 // Authenticate ensures the request certificates are trusted against the given set of trusted certificates.
 // - Requests over the unix socket are always allowed.
-// - HTTP requests require the TLS Peer certificate to match an entry in the supplied map of certificates.
+// - HTTP requests require the TLS Peer certificate to match an entry in the supplied map of certificates, or a bearer
+// token standing for such a certificate.
 func Authenticate(state state.State, r *http.Request, hostAddress string, trustedCerts map[string]x509.Certificate) (bool, error) {
+	identity, err := AuthenticateIdentity(state, r, hostAddress, trustedCerts)
+
+	return identity.Trusted, err
+}
+
+// AuthenticateIdentity authenticates the request like Authenticate, and returns the identity of its caller.
+func AuthenticateIdentity(state state.State, r *http.Request, hostAddress string, trustedCerts map[string]x509.Certificate) (Identity, error) {
 	if r.RemoteAddr == "@" {
-		return true, nil
+		identity := Identity{Method: AuthMethodUnix, Trusted: true}
+		if access.ControlSecretVerified(r) {
+			identity.Method = AuthMethodControlSecret
+		}
+
+		creds, ok := GetPeerCredentials(r)
+		if ok {
+			identity.PeerCredentials = &creds
+		}
+
+		return identity, nil
 	}
 
 	intState, err := internalState.ToInternal(state)
 	if err != nil {
-		return false, err
+		return Identity{Method: AuthMethodNone}, err
 	}
 
 	// Check if it's the core API listener and if it is using the server.crt.
@@ -67,14 +87,19 @@ func Authenticate(state state.State, r *http.Request, hostAddress string, truste
 	if ok {
 		if state.ServerCert().Fingerprint() == network.TLS().Fingerprint() {
 			logger.Info("Allowing unauthenticated request to un-initialized system")
-			return true, nil
+			identity := Identity{Method: AuthMethodPreInit, Trusted: true}
+			if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+				identity.Fingerprint = shared.CertFingerprint(r.TLS.PeerCertificates[0])
+			}
+
+			return identity, nil
 		}
 	}
 
 	// Ensure the given host address is valid.
 	hostAddrPort, err := types.ParseAddrPort(hostAddress)
 	if err != nil {
-		return false, fmt.Errorf("Invalid host address %q", hostAddress)
+		return Identity{Method: AuthMethodNone}, fmt.Errorf("Invalid host address %q", hostAddress)
 	}
 
 	switch r.Host {
@@ -85,13 +110,42 @@ func Authenticate(state state.State, r *http.Request, hostAddress string, truste
 				if trusted {
 					logger.Debugf("Trusting HTTP request to %q from %q with fingerprint %q", r.URL.String(), r.RemoteAddr, fingerprint)
 
-					return trusted, nil
+					return trustedIdentity(state, AuthMethodTLS, fingerprint), nil
 				}
 			}
 		}
+
+		// Clients that can't easily present a certificate may instead present a bearer token standing for a trusted one.
+		token, ok := access.GetBearerToken(r)
+		if ok {
+			fingerprint, err := access.ParseSessionToken(access.SessionKey(state.ClusterCert().PrivateKey()), token, time.Now())
+			if err != nil {
+				return Identity{Method: AuthMethodNone}, err
+			}
+
+			_, trusted := trustedCerts[fingerprint]
+			if trusted {
+				logger.Debugf("Trusting HTTP request to %q from %q with bearer token for fingerprint %q", r.URL.String(), r.RemoteAddr, fingerprint)
+
+				return trustedIdentity(state, AuthMethodBearer, fingerprint), nil
+			}
+		}
 	default:
-		return false, ErrInvalidHost{error: fmt.Errorf("Invalid request address %q", r.Host)}
+		return Identity{Method: AuthMethodNone}, ErrInvalidHost{error: fmt.Errorf("Invalid request address %q", r.Host)}
+	}
+
+	return Identity{Method: AuthMethodNone}, nil
+}
+
+// trustedIdentity returns the identity of a caller trusted with the certificate of the given fingerprint, named after
+// the cluster member owning the certificate, if any.
+func trustedIdentity(state state.State, method AuthMethod, fingerprint string) Identity {
+	identity := Identity{Method: method, Trusted: true, Fingerprint: fingerprint}
+
+	remote := state.Remotes().RemoteByCertificateFingerprint(fingerprint)
+	if remote != nil {
+		identity.Name = remote.Name
 	}
 
-	return false, nil
+	return identity
 }
@@ -0,0 +1,41 @@
+package access
+
+import (
+	"net/http"
+
+	"github.com/canonical/microcluster/v3/internal/rest/access"
+)
+
+// AuthMethod is the way the caller of a request was authenticated.
+type AuthMethod = access.AuthMethod
+
+const (
+	// AuthMethodNone is used for callers that were not authenticated.
+	AuthMethodNone = access.AuthMethodNone
+
+	// AuthMethodUnix is used for callers connected over the unix socket.
+	AuthMethodUnix = access.AuthMethodUnix
+
+	// AuthMethodControlSecret is used for callers of the control API over TCP holding its shared secret.
+	AuthMethodControlSecret = access.AuthMethodControlSecret
+
+	// AuthMethodPreInit is used for callers of a daemon that is not initialized yet, which are all trusted.
+	AuthMethodPreInit = access.AuthMethodPreInit
+
+	// AuthMethodTLS is used for callers presenting a trusted TLS client certificate.
+	AuthMethodTLS = access.AuthMethodTLS
+
+	// AuthMethodBearer is used for callers presenting a bearer token standing for a trusted certificate.
+	AuthMethodBearer = access.AuthMethodBearer
+)
+
+// Identity identifies the caller of a request, as verified by the authentication layer.
+type Identity = access.Identity
+
+// GetIdentity returns the identity of the caller of the request, as verified by the authentication layer before any
+// access handler or endpoint handler is called. Handlers can use it to tell apart callers over the unix socket,
+// cluster members by name, and API clients by certificate fingerprint.
+// It returns false for requests that did not go through authentication.
+func GetIdentity(r *http.Request) (Identity, bool) {
+	return access.GetRequestIdentity(r)
+}
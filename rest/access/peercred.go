@@ -0,0 +1,54 @@
+package access
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+
+	"github.com/canonical/microcluster/v3/internal/rest/access"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+// PeerCredentials identifies the process that sent a request over the unix socket.
+type PeerCredentials = access.PeerCredentials
+
+// SocketPolicy decides whether a request received over the unix socket from a process with the given credentials
+// is allowed. Returning an error rejects the request.
+type SocketPolicy func(r *http.Request, creds PeerCredentials) error
+
+// GetPeerCredentials returns the credentials of the process that sent the request over the unix socket.
+// It returns false for requests received over the network.
+func GetPeerCredentials(r *http.Request) (PeerCredentials, bool) {
+	return access.GetPeerCredentials(r)
+}
+
+// AllowUIDs returns a SocketPolicy which only allows requests from processes running as one of the given users.
+func AllowUIDs(uids ...uint32) SocketPolicy {
+	return func(r *http.Request, creds PeerCredentials) error {
+		if !shared.ValueInSlice(creds.UID, uids) {
+			return fmt.Errorf("User %d is not allowed to access %q", creds.UID, r.URL.Path)
+		}
+
+		return nil
+	}
+}
+
+// AllowSocketPolicy returns an access handler which applies the given SocketPolicy to requests received over the
+// unix socket. Requests received over the network are rejected.
+func AllowSocketPolicy(policy SocketPolicy) func(state state.State, r *http.Request) (bool, response.Response) {
+	return func(state state.State, r *http.Request) (bool, response.Response) {
+		creds, ok := GetPeerCredentials(r)
+		if !ok {
+			return false, response.Forbidden(fmt.Errorf("Request was not received over the unix socket"))
+		}
+
+		err := policy(r, creds)
+		if err != nil {
+			return false, response.Forbidden(err)
+		}
+
+		return true, nil
+	}
+}
@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// errorResponse is an error response identifying the exported error it was created from.
+type errorResponse struct {
+	response.Response
+
+	code string
+}
+
+// SmartError returns an error response for err, as response.SmartError does. If err wraps one of the exported errors of
+// the types package, such as types.ErrMemberNotFound, the response carries its code in the types.ErrorCodeHeader
+// header, so that clients can match the error with errors.Is.
+func SmartError(err error) response.Response {
+	resp := response.SmartError(err)
+
+	code := types.ErrorCode(err)
+	if code == "" {
+		return resp
+	}
+
+	return &errorResponse{Response: resp, code: code}
+}
+
+// Render sets the error code header, and writes the error response.
+func (resp *errorResponse) Render(w http.ResponseWriter) error {
+	w.Header().Set(types.ErrorCodeHeader, resp.code)
+
+	return resp.Response.Render(w)
+}
@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+func TestSmartError(t *testing.T) {
+	render := func(err error) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		require.NoError(t, SmartError(err).Render(w))
+
+		return w
+	}
+
+	// Exported errors are identified by their error code, along with the status of the error.
+	w := render(api.StatusErrorf(http.StatusNotFound, "%w: %q", types.ErrMemberNotFound, "m1"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, types.ErrorCode(types.ErrMemberNotFound), w.Header().Get(types.ErrorCodeHeader))
+	assert.ErrorIs(t, types.IdentifyError(w.Header().Get(types.ErrorCodeHeader), "Cluster member not found"), types.ErrMemberNotFound)
+
+	w = render(fmt.Errorf("Failed to join: %w", types.ErrTokenExpired))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, types.ErrorCode(types.ErrTokenExpired), w.Header().Get(types.ErrorCodeHeader))
+
+	// Other errors have no error code.
+	w = render(errors.New(types.ErrNotLeader.Error()))
+	assert.Empty(t, w.Header().Get(types.ErrorCodeHeader))
+}
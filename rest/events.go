@@ -0,0 +1,146 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// eventStreamHeartbeat is how often a comment is sent over an idle event stream, so that proxies and clients do not
+// consider the connection dead.
+const eventStreamHeartbeat = 15 * time.Second
+
+// Event is a server-sent event.
+type Event struct {
+	// ID identifies the event. Clients reconnecting to the stream send the ID of the last event they received, so
+	// that the stream can resume after it.
+	ID string
+
+	// Type is the name of the event. Unnamed events are of type "message".
+	Type string
+
+	// Data is the payload of the event, encoded as JSON.
+	Data any
+}
+
+// eventStreamResponse is a response that streams server-sent events.
+type eventStreamResponse struct {
+	r      *http.Request
+	stream func(ctx context.Context, lastEventID string, send func(event Event) error) error
+}
+
+// EventStreamResponse returns a response that streams server-sent events to the client, as sent by stream, until
+// stream returns or the client disconnects. lastEventID is the ID of the last event received by a reconnecting client,
+// or empty. Heartbeat comments are sent while the stream is idle.
+// As the response is already sent, any error returned by stream is sent to the client as an "error" event and logged.
+func EventStreamResponse(r *http.Request, stream func(ctx context.Context, lastEventID string, send func(event Event) error) error) response.Response {
+	return &eventStreamResponse{r: r, stream: stream}
+}
+
+// Render sends the events of the stream as they are produced.
+func (resp *eventStreamResponse) Render(w http.ResponseWriter) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return response.InternalError(fmt.Errorf("ResponseWriter is not type http.Flusher")).Render(w)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	mu := sync.Mutex{}
+	write := func(msg string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		_, err := fmt.Fprint(w, msg)
+		if err != nil {
+			return err
+		}
+
+		flusher.Flush()
+
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(resp.r.Context())
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(eventStreamHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := write(": heartbeat\n\n")
+			if err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	send := func(event Event) error {
+		msg, err := formatEvent(event)
+		if err != nil {
+			return err
+		}
+
+		return write(msg)
+	}
+
+	err := resp.stream(ctx, resp.r.Header.Get("Last-Event-ID"), send)
+	if err != nil && ctx.Err() == nil {
+		logger.Error("Event stream failed", logger.Ctx{"url": resp.r.URL.String(), "error": err})
+
+		_ = send(Event{Type: "error", Data: err.Error()})
+	}
+
+	return nil
+}
+
+// String returns the response type.
+func (resp *eventStreamResponse) String() string {
+	return "event stream"
+}
+
+// formatEvent encodes the event in the text/event-stream format.
+func formatEvent(event Event) (string, error) {
+	if strings.ContainsAny(event.ID+event.Type, "\r\n") {
+		return "", fmt.Errorf("Event ID and type cannot contain newlines")
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode event data: %w", err)
+	}
+
+	msg := strings.Builder{}
+	if event.ID != "" {
+		msg.WriteString("id: " + event.ID + "\n")
+	}
+
+	if event.Type != "" {
+		msg.WriteString("event: " + event.Type + "\n")
+	}
+
+	// JSON encoding escapes newlines, so the data always fits on a single line.
+	msg.WriteString("data: " + string(data) + "\n\n")
+
+	return msg.String(), nil
+}
@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStreamResponse(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Last-Event-ID", "1")
+
+	resp := EventStreamResponse(r, func(ctx context.Context, lastEventID string, send func(event Event) error) error {
+		assert.Equal(t, "1", lastEventID)
+
+		err := send(Event{ID: "2", Type: "progress", Data: map[string]int{"percent": 50}})
+		if err != nil {
+			return err
+		}
+
+		err = send(Event{Data: "done\nnow"})
+		if err != nil {
+			return err
+		}
+
+		return errors.New("failed")
+	})
+
+	w := httptest.NewRecorder()
+	require.NoError(t, resp.Render(w))
+
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "id: 2\nevent: progress\ndata: {\"percent\":50}\n\ndata: \"done\\nnow\"\n\nevent: error\ndata: \"failed\"\n\n", w.Body.String())
+}
@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/state"
+)
+
+// operationResponse is the response to a request that started an asynchronous operation.
+type operationResponse struct {
+	op *state.Operation
+}
+
+// OperationResponse returns a response to a request that started an asynchronous operation with
+// State.Operations().Start. The caller receives the status of the operation and its URL under /core/1.0/operations,
+// which can be used to follow its progress, wait for it to finish, or cancel it.
+func OperationResponse(op *state.Operation) response.Response {
+	return &operationResponse{op: op}
+}
+
+// Render writes the status of the operation with the 202 Accepted status code.
+func (resp *operationResponse) Render(w http.ResponseWriter) error {
+	url := api.NewURL().Path("core", "1.0", "operations", resp.op.ID()).String()
+	body := api.ResponseRaw{
+		Type:       api.AsyncResponse,
+		Status:     api.OperationCreated.String(),
+		StatusCode: int(api.OperationCreated),
+		Operation:  url,
+		Metadata:   resp.op.Status(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", url)
+	w.WriteHeader(http.StatusAccepted)
+
+	return json.NewEncoder(w).Encode(body)
+}
+
+// String returns the response type.
+func (resp *operationResponse) String() string {
+	return "operation"
+}
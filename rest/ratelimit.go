@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/canonical/microcluster/v3/internal/rest/access"
+)
+
+// RateLimitKey determines how requests are grouped when applying a RateLimit.
+type RateLimitKey string
+
+const (
+	// RateLimitByFingerprint limits each trusted client certificate fingerprint separately.
+	// Requests from untrusted clients are grouped by remote address instead, as they can present any certificate.
+	RateLimitByFingerprint RateLimitKey = "fingerprint"
+
+	// RateLimitByAddress limits each remote address separately.
+	RateLimitByAddress RateLimitKey = "address"
+)
+
+// maxRateLimitBuckets is the number of clients tracked by a RateLimit before the least recently seen ones are forgotten.
+const maxRateLimitBuckets = 4096
+
+// RateLimit restricts how often each client may call an endpoint action, using a token bucket per client.
+// The same RateLimit may be shared by several actions, in which case they draw from the same buckets.
+// Requests over the unix socket are never limited.
+type RateLimit struct {
+	// RequestsPerSecond is the rate at which a client's bucket is refilled.
+	RequestsPerSecond float64
+
+	// Burst is the maximum number of requests a client can make at once. It defaults to 1.
+	Burst int
+
+	// Key determines how clients are told apart. It defaults to RateLimitByFingerprint.
+	Key RateLimitKey
+
+	mu      sync.Mutex
+	buckets map[string]*rateLimitBucket
+}
+
+// rateLimitBucket holds the remaining tokens of a single client.
+type rateLimitBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Allow records a request and reports whether it is within the limit.
+// If it is not, it also returns how long the client should wait before retrying.
+func (l *RateLimit) Allow(r *http.Request) (bool, time.Duration) {
+	if l == nil || r.RemoteAddr == "@" || l.RequestsPerSecond <= 0 {
+		return true, 0
+	}
+
+	return l.allow(l.clientKey(r), time.Now())
+}
+
+// clientKey returns the identifier of the client that sent the request.
+func (l *RateLimit) clientKey(r *http.Request) string {
+	if l.Key != RateLimitByAddress {
+		identity, ok := access.GetRequestIdentity(r)
+		if ok && identity.Trusted && identity.Fingerprint != "" {
+			return identity.Fingerprint
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// allow takes a token from the bucket of the given client at the given time.
+func (l *RateLimit) allow(key string, now time.Time) (bool, time.Duration) {
+	burst := float64(max(l.Burst, 1))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = map[string]*rateLimitBucket{}
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			l.prune(now, burst)
+		}
+
+		bucket = &rateLimitBucket{tokens: burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.RequestsPerSecond)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.RequestsPerSecond * float64(time.Second))
+
+		return false, wait
+	}
+
+	bucket.tokens--
+
+	return true, 0
+}
+
+// prune forgets clients whose buckets have refilled completely, as they are indistinguishable from new clients.
+// If none has, the least recently seen client is forgotten instead, so that new clients can always be tracked.
+func (l *RateLimit) prune(now time.Time, burst float64) {
+	var oldestKey string
+	var oldest time.Time
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.RequestsPerSecond >= burst {
+			delete(l.buckets, key)
+			continue
+		}
+
+		if oldestKey == "" || bucket.last.Before(oldest) {
+			oldestKey = key
+			oldest = bucket.last
+		}
+	}
+
+	if len(l.buckets) >= maxRateLimitBuckets {
+		delete(l.buckets, oldestKey)
+	}
+}
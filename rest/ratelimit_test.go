@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/microcluster/v3/internal/rest/access"
+)
+
+func TestRateLimitAllow(t *testing.T) {
+	limit := &RateLimit{RequestsPerSecond: 2, Burst: 2}
+	now := time.Now()
+
+	// The burst is available immediately, then the client has to wait for a refill.
+	for i := 0; i < 2; i++ {
+		allowed, _ := limit.allow("a", now)
+		assert.True(t, allowed)
+	}
+
+	allowed, wait := limit.allow("a", now)
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// Other clients are limited separately.
+	allowed, _ = limit.allow("b", now)
+	assert.True(t, allowed)
+
+	allowed, _ = limit.allow("a", now.Add(500*time.Millisecond))
+	assert.True(t, allowed)
+}
+
+func TestRateLimitClientKey(t *testing.T) {
+	r := &http.Request{RemoteAddr: "10.0.0.1:5000"}
+
+	assert.Equal(t, "10.0.0.1", (&RateLimit{}).clientKey(r))
+	assert.Equal(t, "10.0.0.1", (&RateLimit{Key: RateLimitByAddress}).clientKey(r))
+
+	// Only trusted clients are told apart by their certificate, as untrusted ones can present a new one each time.
+	trusted := access.SetRequestIdentity(r, access.Identity{Method: access.AuthMethodTLS, Trusted: true, Fingerprint: "abcd"})
+	assert.Equal(t, "abcd", (&RateLimit{}).clientKey(trusted))
+	assert.Equal(t, "10.0.0.1", (&RateLimit{Key: RateLimitByAddress}).clientKey(trusted))
+
+	untrusted := access.SetRequestIdentity(r, access.Identity{Method: access.AuthMethodNone, Fingerprint: "abcd"})
+	assert.Equal(t, "10.0.0.1", (&RateLimit{}).clientKey(untrusted))
+
+	// Unix socket requests and unset limits are never limited.
+	var unset *RateLimit
+	allowed, _ := unset.Allow(r)
+	assert.True(t, allowed)
+
+	limit := &RateLimit{RequestsPerSecond: 1}
+	for i := 0; i < 3; i++ {
+		allowed, _ = limit.Allow(&http.Request{RemoteAddr: "@"})
+		assert.True(t, allowed)
+	}
+}
+
+func TestRateLimitEviction(t *testing.T) {
+	limit := &RateLimit{RequestsPerSecond: 1}
+	now := time.Now()
+
+	// Fill the limit with clients that have all used up their bucket, the first one least recently.
+	for i := 0; i < maxRateLimitBuckets; i++ {
+		allowed, _ := limit.allow(strconv.Itoa(i), now.Add(time.Duration(i)*time.Microsecond))
+		assert.True(t, allowed)
+	}
+
+	// A new client is still tracked, at the expense of the least recently seen one.
+	now = now.Add(maxRateLimitBuckets * time.Microsecond)
+	allowed, _ := limit.allow("new", now)
+	assert.True(t, allowed)
+	assert.Len(t, limit.buckets, maxRateLimitBuckets)
+	assert.NotContains(t, limit.buckets, "0")
+
+	allowed, _ = limit.allow("new", now)
+	assert.False(t, allowed)
+
+	// Clients whose buckets have refilled are forgotten first, here all of them.
+	allowed, _ = limit.allow("other", now.Add(time.Second))
+	assert.True(t, allowed)
+	assert.Len(t, limit.buckets, 1)
+}
@@ -16,12 +16,20 @@ type EndpointAlias struct {
 	Path string // Path pattern for this alias.
 }
 
+// AccessOverride returns the access handler to use for an action of a core API endpoint, given the access handler the
+// action was defined with, which is nil if it has none. The returned handler can wrap the given one, or replace it.
+// It only runs once the request is authenticated, unless the action allows untrusted requests.
+type AccessOverride func(next func(state state.State, r *http.Request) (trusted bool, resp response.Response)) func(state state.State, r *http.Request) (trusted bool, resp response.Response)
+
 // EndpointAction represents an action on an API endpoint.
+// Both the Handler and the AccessHandler can get the identity of the caller, as verified when authenticating the
+// request, with access.GetIdentity.
 type EndpointAction struct {
 	Handler        func(state state.State, r *http.Request) response.Response
 	AccessHandler  func(state state.State, r *http.Request) (trusted bool, resp response.Response)
 	AllowUntrusted bool
-	ProxyTarget    bool // Allow forwarding of the request to a target if ?target=name is specified.
+	ProxyTarget    bool       // Allow forwarding of the request to a target if ?target=name is specified.
+	RateLimit      *RateLimit // Limit how often each client may call this action. Unset means unlimited.
 }
 
 // Endpoint represents a URL in our API.
@@ -36,7 +44,17 @@ type Endpoint struct {
 	Patch   EndpointAction
 
 	AllowedDuringShutdown bool // Whether we should return Unavailable Error (503) if daemon is shutting down.
-	AllowedBeforeInit     bool // Whether we should return Unavailabel Error (503) if the daemon has not been initialized (is not yet part of a cluster).
+
+	// AllowedBeforeInit sets whether the endpoint is served before the daemon has been initialized (is not yet part
+	// of a cluster), rather than returning Unavailable Error (503). Its handlers can use state.ToPreInit to inspect and
+	// initialize the daemon, as the database and cluster members are not available until then. Extension servers with
+	// such endpoints are started before initialization, even without PreInit set.
+	AllowedBeforeInit bool
+
+	MaxRequestBytes int64         // Maximum size of request bodies. Larger requests are rejected with 413. Unset means unlimited.
+	ReadTimeout     time.Duration // Maximum time to read the request body. Unset means unlimited.
+	WriteTimeout    time.Duration // Maximum time to handle the request and write the response. Unset means unlimited.
+	HandlerTimeout  time.Duration // Deadline of the context of the request passed to the handler. Unset means no deadline.
 }
 
 // Resources represents all the resources served over the same path.
@@ -53,6 +71,7 @@ type Server struct {
 	CoreAPI bool
 
 	// PreInit determines whether the Server should be available prior to initializing the daemon.
+	// Servers with endpoints that set AllowedBeforeInit are also available, but only serve those endpoints until then.
 	PreInit bool
 
 	// ServeUnix sets whether the resources of this endpoint should also be served over the unix socket.
@@ -64,10 +83,28 @@ type Server struct {
 	// In case there isn't any custom certificate it falls back to the cluster certificate of the core API.
 	DedicatedCertificate bool
 
+	// ACME provisions and renews the listener's certificate from an ACME certificate authority such as Let's Encrypt.
+	// The certificate is stored in the daemon's state `/certificates` directory, like a custom certificate.
+	// Until it is first issued, the listener uses a dedicated self signed certificate.
+	ACME *types.ACMEConfig
+
 	// Resources is the list of resources offered by this server.
 	Resources []Resources
 
-	// DrainConnectionsTimeout is the amount of time to allow for all connections to drain when shutting down.
-	// If it's 0, the connections are not drained when shutting down.
+	// HTTP2 enables HTTP/2 on the server's listener, negotiated with each client through TLS ALPN alongside HTTP/1.1.
+	// The server must have its own address, as the listener of the core API is shared with other servers.
+	HTTP2 bool
+
+	// GRPCServer, such as a *grpc.Server, serves the gRPC requests received by the server, so that gRPC and REST APIs
+	// can share the same listener and certificate. Requests made over HTTP/2 with a gRPC content type are handed to it,
+	// and all others to Resources. It requires HTTP2.
+	// gRPC requests do not go through the access handlers of Resources, so the gRPC server must authenticate them
+	// itself, for instance from the TLS certificate of the peer.
+	GRPCServer http.Handler
+
+	// DrainConnectionsTimeout is the amount of time to allow for all connections to drain when shutting down, or when
+	// the server is restarted to apply a new configuration.
+	// If it's 0, the connections are not drained. It is ignored for servers that are part of the core API, which use
+	// the drain timeout of the daemon.
 	DrainConnectionsTimeout time.Duration
 }
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"time"
+)
+
+// ACMEConfig configures the provisioning of an additional listener's certificate from an ACME certificate
+// authority such as Let's Encrypt. Ownership of the DNS name is proven with the HTTP-01 challenge, so the
+// challenge address must be reachable on port 80 of the DNS name. Certificates are issued by the dqlite leader and
+// shared with the other cluster members, so the DNS name must reach the leader while a certificate is being issued.
+type ACMEConfig struct {
+	// DNSName is the domain name the certificate is issued for.
+	DNSName string `json:"dns_name" yaml:"dns_name"`
+
+	// Email is the contact address registered with the ACME account. It is optional.
+	Email string `json:"email" yaml:"email"`
+
+	// DirectoryURL is the ACME directory of the certificate authority. It defaults to Let's Encrypt.
+	DirectoryURL string `json:"directory_url" yaml:"directory_url"`
+
+	// ChallengeAddress is the address on which HTTP-01 challenges are answered while a certificate is being issued.
+	// It defaults to ":80".
+	ChallengeAddress string `json:"challenge_address" yaml:"challenge_address"`
+
+	// RenewBefore is how long before expiry the certificate is renewed. It defaults to 30 days.
+	RenewBefore time.Duration `json:"renew_before" yaml:"renew_before"`
+}
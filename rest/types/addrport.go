@@ -57,6 +57,12 @@ func (a *AddrPort) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	// An empty string is the representation of an unset AddrPort.
+	if addrPortStr == "" {
+		*a = AddrPort{}
+		return nil
+	}
+
 	*a, err = ParseAddrPort(addrPortStr)
 	if err != nil {
 		return err
@@ -73,6 +79,12 @@ func (a *AddrPort) UnmarshalYAML(unmarshal func(v any) error) error {
 		return err
 	}
 
+	// An empty string is the representation of an unset AddrPort.
+	if addrPortStr == "" {
+		*a = AddrPort{}
+		return nil
+	}
+
 	*a, err = ParseAddrPort(addrPortStr)
 	if err != nil {
 		return err
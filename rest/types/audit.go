@@ -0,0 +1,53 @@
+package types
+
+import (
+	"time"
+)
+
+// AuditCallerProtocol identifies how the caller of an audited request reached the daemon.
+type AuditCallerProtocol string
+
+const (
+	// AuditCallerUnix is a caller connected over the control socket.
+	AuditCallerUnix AuditCallerProtocol = "unix"
+
+	// AuditCallerTLS is a caller connected over the network, identified by its TLS client certificate.
+	AuditCallerTLS AuditCallerProtocol = "tls"
+)
+
+// AuditCaller identifies the origin of an audited request.
+type AuditCaller struct {
+	Protocol AuditCallerProtocol `json:"protocol" yaml:"protocol"`
+
+	// Address is the remote address of the connection. It is empty for callers connected over the control socket.
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+
+	// Fingerprint is the fingerprint of the TLS client certificate, and Name the cluster member it belongs to, if any.
+	Fingerprint string `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// UID and PID identify the process connected over the control socket, if known.
+	UID *uint32 `json:"uid,omitempty" yaml:"uid,omitempty"`
+	PID *int32  `json:"pid,omitempty" yaml:"pid,omitempty"`
+
+	// ForwardedAddress and ForwardedUsername identify the original caller of a request forwarded by another
+	// cluster member.
+	ForwardedAddress  string `json:"forwarded_address,omitempty" yaml:"forwarded_address,omitempty"`
+	ForwardedUsername string `json:"forwarded_username,omitempty" yaml:"forwarded_username,omitempty"`
+}
+
+// AuditEntry records a mutating API request received by a cluster member, along with its result.
+type AuditEntry struct {
+	Time   time.Time   `json:"time" yaml:"time"`
+	Caller AuditCaller `json:"caller" yaml:"caller"`
+	Method string      `json:"method" yaml:"method"`
+	Path   string      `json:"path" yaml:"path"`
+	Query  string      `json:"query,omitempty" yaml:"query,omitempty"`
+
+	// RequestSize is the size of the request body in bytes, if known. The body itself is not recorded, as it may
+	// hold secrets such as join tokens.
+	RequestSize int64 `json:"request_size" yaml:"request_size"`
+
+	StatusCode int           `json:"status_code" yaml:"status_code"`
+	Duration   time.Duration `json:"duration" yaml:"duration"`
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"time"
 )
 
 // CertificateName represents the name of a certificate.
@@ -25,6 +26,47 @@ type KeyPair struct {
 	CA   string `json:"ca" yaml:"ca"`
 }
 
+// CertificateRotationPhase is a phase of a coordinated certificate rotation.
+type CertificateRotationPhase string
+
+const (
+	// CertificateRotationStage writes the new keypair next to the active one without loading it.
+	CertificateRotationStage CertificateRotationPhase = "stage"
+
+	// CertificateRotationCommit replaces the active keypair with the staged one and reloads it.
+	CertificateRotationCommit CertificateRotationPhase = "commit"
+
+	// CertificateRotationAbort discards the staged keypair.
+	CertificateRotationAbort CertificateRotationPhase = "abort"
+)
+
+// CertificateRotation is sent to each cluster member during a coordinated certificate rotation.
+type CertificateRotation struct {
+	Phase CertificateRotationPhase `json:"phase" yaml:"phase"`
+
+	// KeyPair is the keypair to stage. Only used by the stage phase.
+	KeyPair KeyPair `json:"keypair" yaml:"keypair"`
+
+	// Fingerprint is the fingerprint that the staged certificate must have to be committed. Only used by the commit phase.
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+}
+
+// CertificateRotationStatus is returned by a cluster member after staging or committing a certificate.
+type CertificateRotationStatus struct {
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+}
+
+// CertificateExpiry reports when a certificate loaded by a cluster member expires.
+type CertificateExpiry struct {
+	Name          CertificateName `json:"name"           yaml:"name"`
+	Fingerprint   string          `json:"fingerprint"    yaml:"fingerprint"`
+	NotAfter      time.Time       `json:"not_after"      yaml:"not_after"`
+	DaysRemaining int             `json:"days_remaining" yaml:"days_remaining"`
+
+	// SelfSigned is set if the certificate was not issued by a CA, in which case the cluster member can renew it.
+	SelfSigned bool `json:"self_signed" yaml:"self_signed"`
+}
+
 // ClusterCertificatePut represents the content of a new cluster keypair and CA.
 type ClusterCertificatePut struct {
 	PublicKey  string `json:"public_key"  yaml:"public_key"`
@@ -14,8 +14,36 @@ type ClusterMember struct {
 	SchemaExternalVersion uint64                `json:"schema_external_version" yaml:"schema_external_version"`
 	LastHeartbeat         time.Time             `json:"last_heartbeat" yaml:"last_heartbeat"`
 	Status                MemberStatus          `json:"status" yaml:"status"`
+	Latency               time.Duration         `json:"latency" yaml:"latency"`
 	Extensions            extensions.Extensions `json:"extensions" yaml:"extensions"`
 	Secret                string                `json:"secret" yaml:"secret"`
+	InitConfig            map[string]string     `json:"init_config,omitempty" yaml:"init_config,omitempty"`
+	Maintenance           bool                  `json:"maintenance" yaml:"maintenance"`
+
+	// ClockSkew is how far ahead of the clock of the dqlite leader the clock of the cluster member was at its last
+	// heartbeat. It is negative if the clock of the cluster member is behind.
+	ClockSkew time.Duration `json:"clock_skew" yaml:"clock_skew"`
+
+	// Time is the time of a joining cluster member when it sent its join request, used to detect clock skew.
+	Time time.Time `json:"time,omitempty" yaml:"time,omitempty"`
+}
+
+// MemberHealth represents the status of a cluster member as probed by another cluster member.
+type MemberHealth struct {
+	Name          string       `json:"name" yaml:"name"`
+	Address       AddrPort     `json:"address" yaml:"address"`
+	Status        MemberStatus `json:"status" yaml:"status"`
+	LastHeartbeat time.Time    `json:"last_heartbeat" yaml:"last_heartbeat"`
+
+	// Latency is the roundtrip time of the probe. It is 0 if the member could not be reached.
+	Latency time.Duration `json:"latency" yaml:"latency"`
+}
+
+// ClusterHealth represents the state of the cluster members as recorded by the dqlite leader at the end of a heartbeat
+// round. Pending cluster members are not included.
+type ClusterHealth struct {
+	Leader  string          `json:"leader" yaml:"leader"`
+	Members []ClusterMember `json:"members" yaml:"members"`
 }
 
 // ClusterMemberLocal represents local information about a new cluster member.
@@ -23,6 +51,20 @@ type ClusterMemberLocal struct {
 	Name        string          `json:"name" yaml:"name"`
 	Address     AddrPort        `json:"address" yaml:"address"`
 	Certificate X509Certificate `json:"certificate" yaml:"certificate"`
+
+	// DNSName, if set, resolves to the address of the cluster member. Connections to the cluster member that fail
+	// are retried at the address it currently resolves to, so that the cluster survives changes of address.
+	DNSName string `json:"dns_name,omitempty" yaml:"dns_name,omitempty"`
+}
+
+// ClusterMemberMaintenance represents whether a cluster member is under maintenance.
+type ClusterMemberMaintenance struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// ClusterMemberRename represents the new name of a cluster member.
+type ClusterMemberRename struct {
+	Name string `json:"name" yaml:"name"`
 }
 
 // MemberStatus represents the online status of a cluster member.
@@ -43,9 +85,14 @@ const (
 	// MemberOnline should be the MemberStatus when the node is online and reachable.
 	MemberOnline MemberStatus = "ONLINE"
 
-	// MemberUnreachable should be the MemberStatus when we were not able to connect to the node.
+	// MemberUnreachable should be the MemberStatus when we were not able to connect to the node,
+	// although it still responds to heartbeats.
 	MemberUnreachable MemberStatus = "UNREACHABLE"
 
+	// MemberOffline should be the MemberStatus when we were not able to connect to the node,
+	// and it has stopped responding to heartbeats.
+	MemberOffline MemberStatus = "OFFLINE"
+
 	// MemberNotTrusted should be the MemberStatus when there is no local yaml entry for this node.
 	MemberNotTrusted MemberStatus = "NOT TRUSTED"
 
@@ -55,6 +102,10 @@ const (
 	// MemberUpgrading should be the MemberStatus if the system is awaiting or performing a schema upgrade.
 	MemberUpgrading MemberStatus = "UPGRADING"
 
+	// MemberMaintenance should be the MemberStatus when the node has been put under maintenance by an operator.
+	// Such nodes are not probed, so they are not reported as offline while they are down.
+	MemberMaintenance MemberStatus = "MAINTENANCE"
+
 	// MemberNeedsUpgrade should be the MemberStatus if the system needs to receive a schema upgrade to be compatible with other cluster members.
 	MemberNeedsUpgrade MemberStatus = "NEEDS UPGRADE"
 )
@@ -1,8 +1,76 @@
 package types
 
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"time"
+)
+
+// DefaultSocketMode is the file mode of the control socket, unless set otherwise with SocketConfig.
+const DefaultSocketMode fs.FileMode = 0660
+
 // DaemonConfig is the in memory version of the local daemon.yaml file.
 type DaemonConfig struct {
-	Name    string                  `json:"name" yaml:"name"`
-	Address AddrPort                `json:"address" yaml:"address"`
-	Servers map[string]ServerConfig `json:"servers" yaml:"servers"`
+	Name          string                  `json:"name" yaml:"name"`
+	Address       AddrPort                `json:"address" yaml:"address"`
+	ListenAddress AddrPort                `json:"listen_address" yaml:"listen_address"`
+	Servers       map[string]ServerConfig `json:"servers" yaml:"servers"`
+	Heartbeat     HeartbeatConfig         `json:"heartbeat" yaml:"heartbeat,omitempty"`
+	Socket        SocketConfig            `json:"socket" yaml:"socket,omitempty"`
+
+	// DNSName, if set, resolves to Address, and is shared with the other cluster members.
+	DNSName string `json:"dns_name" yaml:"dns_name,omitempty"`
+
+	// LocalOnly is set if the cluster was bootstrapped without a network address, and has not been published yet.
+	LocalOnly bool `json:"local_only" yaml:"local_only,omitempty"`
+}
+
+// RuntimeConfig is the part of the daemon configuration that can be changed while the daemon is running.
+type RuntimeConfig struct {
+	Heartbeat HeartbeatConfig `json:"heartbeat" yaml:"heartbeat"`
+
+	// Socket is the ownership and permissions of the control socket. If nil, they are left unchanged. They can only
+	// be changed over the control socket.
+	Socket *SocketConfig `json:"socket,omitempty" yaml:"socket,omitempty"`
+}
+
+// HeartbeatConfig holds the heartbeat and failure-detection settings of the daemon.
+type HeartbeatConfig struct {
+	// Interval is the time between heartbeat rounds. If 0, the interval the daemon was started with is used.
+	// Changes apply right away, but dqlite role adjustments keep the interval the daemon was started with.
+	Interval time.Duration `json:"interval" yaml:"interval,omitempty"`
+
+	// OfflineThreshold is how long an unreachable member may go without a heartbeat before it is reported offline.
+	// If 0, members are reported offline as soon as they cannot be reached.
+	OfflineThreshold time.Duration `json:"offline_threshold" yaml:"offline_threshold,omitempty"`
+}
+
+// SocketConfig holds the ownership and permissions of the control socket.
+type SocketConfig struct {
+	// Group is the Unix group owning the control socket. If empty, the group the daemon was started with is used.
+	Group string `json:"group" yaml:"group,omitempty"`
+
+	// Mode is the file mode of the control socket in octal, such as "0660". If empty, DefaultSocketMode is used.
+	// It cannot grant access to other users than the owner and the group.
+	Mode string `json:"mode" yaml:"mode,omitempty"`
+}
+
+// FileMode returns the file mode of the control socket.
+func (c SocketConfig) FileMode() (fs.FileMode, error) {
+	if c.Mode == "" {
+		return DefaultSocketMode, nil
+	}
+
+	mode, err := strconv.ParseUint(c.Mode, 8, 32)
+	if err != nil || fs.FileMode(mode)&^fs.ModePerm != 0 {
+		return 0, fmt.Errorf("Invalid control socket mode %q", c.Mode)
+	}
+
+	// Any user that can connect to the control socket is fully trusted.
+	if fs.FileMode(mode)&0007 != 0 {
+		return 0, fmt.Errorf("Control socket mode %q cannot grant access to other users", c.Mode)
+	}
+
+	return fs.FileMode(mode), nil
 }
@@ -0,0 +1,49 @@
+package types
+
+// ConsistencySource is the piece of local state of a cluster member in which a consistency issue was found.
+type ConsistencySource string
+
+const (
+	// ConsistencyTrustStore is the trust store of cluster member remotes.
+	ConsistencyTrustStore ConsistencySource = "truststore"
+
+	// ConsistencyDqliteCluster is the go-dqlite cluster.yaml file, listing the known dqlite members.
+	ConsistencyDqliteCluster ConsistencySource = "cluster.yaml"
+
+	// ConsistencyDqliteInfo is the go-dqlite info.yaml file, recording the ID and address of the local dqlite member.
+	ConsistencyDqliteInfo ConsistencySource = "info.yaml"
+
+	// ConsistencyDatabase is the core_cluster_members table.
+	ConsistencyDatabase ConsistencySource = "core_cluster_members"
+)
+
+// ConsistencyIssue is a mismatch between the local state of a cluster member and the cluster membership recorded in
+// the database and the dqlite raft configuration.
+type ConsistencyIssue struct {
+	// Source is the piece of local state in which the issue was found.
+	Source ConsistencySource `json:"source" yaml:"source"`
+
+	// Member is the name of the cluster member the issue relates to, if known.
+	Member string `json:"member" yaml:"member"`
+
+	// Description describes the mismatch.
+	Description string `json:"description" yaml:"description"`
+
+	// Expected is the value recorded in the cluster membership, if any.
+	Expected string `json:"expected" yaml:"expected"`
+
+	// Found is the value found in the local state, if any.
+	Found string `json:"found" yaml:"found"`
+
+	// Repaired is whether the local state was rewritten to fix the issue.
+	Repaired bool `json:"repaired" yaml:"repaired"`
+}
+
+// ConsistencyReport is the result of cross-checking the local state of a cluster member.
+type ConsistencyReport struct {
+	// Member is the name of the cluster member that was checked.
+	Member string `json:"member" yaml:"member"`
+
+	// Issues are the mismatches found.
+	Issues []ConsistencyIssue `json:"issues" yaml:"issues"`
+}
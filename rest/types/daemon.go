@@ -0,0 +1,53 @@
+package types
+
+import (
+	"time"
+)
+
+// DaemonInfo holds runtime information about the daemon of a cluster member.
+type DaemonInfo struct {
+	Name    string `json:"name" yaml:"name"`
+	Address string `json:"address" yaml:"address"`
+
+	// PID is the process ID of the daemon.
+	PID int `json:"pid" yaml:"pid"`
+
+	// StartedAt is when the daemon was started, and Uptime is the time elapsed since then.
+	StartedAt time.Time     `json:"started_at" yaml:"started_at"`
+	Uptime    time.Duration `json:"uptime" yaml:"uptime"`
+
+	// GoVersion is the version of Go the daemon was built with.
+	GoVersion string `json:"go_version" yaml:"go_version"`
+
+	// MicroclusterVersion is the version of the microcluster module the daemon was built with, if known.
+	MicroclusterVersion string `json:"microcluster_version" yaml:"microcluster_version"`
+
+	// Version is provided by the MicroCluster consumer.
+	Version string `json:"version" yaml:"version"`
+
+	// Servers maps the name of each extension server to its listen address.
+	// The address is empty if the server is not listening.
+	Servers map[string]string `json:"servers" yaml:"servers"`
+
+	Resources DaemonResources `json:"resources" yaml:"resources"`
+
+	// Error is set if the information could not be retrieved from the cluster member.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// DaemonResources holds the resource usage of the daemon process.
+type DaemonResources struct {
+	// CPUUser and CPUSystem are the CPU time spent by the daemon in user and kernel mode.
+	CPUUser   time.Duration `json:"cpu_user" yaml:"cpu_user"`
+	CPUSystem time.Duration `json:"cpu_system" yaml:"cpu_system"`
+
+	// MaxRSS is the peak resident set size of the daemon, in bytes.
+	MaxRSS uint64 `json:"max_rss" yaml:"max_rss"`
+
+	// MemoryHeap is the memory allocated by the Go heap, and MemorySystem the memory obtained from the OS by the
+	// Go runtime, in bytes.
+	MemoryHeap   uint64 `json:"memory_heap" yaml:"memory_heap"`
+	MemorySystem uint64 `json:"memory_system" yaml:"memory_system"`
+
+	Goroutines int `json:"goroutines" yaml:"goroutines"`
+}
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"cmp"
+	"time"
+)
+
 // DatabaseStatus is the current status of the database.
 type DatabaseStatus string
 
@@ -19,3 +24,281 @@ const (
 	// DatabaseOffline indicates that the database is offline.
 	DatabaseOffline DatabaseStatus = "Database is offline"
 )
+
+// TableOperation is the kind of change made to a row of a table whose changes are tracked.
+type TableOperation string
+
+const (
+	// TableInsert indicates that the row was inserted.
+	TableInsert TableOperation = "insert"
+
+	// TableUpdate indicates that the row was updated.
+	TableUpdate TableOperation = "update"
+
+	// TableDelete indicates that the row was deleted.
+	TableDelete TableOperation = "delete"
+)
+
+// TableChange describes a change to a row of a table whose changes are tracked.
+type TableChange struct {
+	// ID orders the changes. It increases with each change made to any tracked table.
+	ID int64 `json:"id" yaml:"id"`
+
+	Table     string         `json:"table" yaml:"table"`
+	Operation TableOperation `json:"operation" yaml:"operation"`
+
+	// RowID is the rowid of the changed row.
+	RowID int64 `json:"row_id" yaml:"row_id"`
+
+	ChangedAt time.Time `json:"changed_at" yaml:"changed_at"`
+}
+
+// DatabaseSchema describes the schema of the database, and the schema versions of each cluster member, so that it can
+// be verified that all cluster members have converged on the same schema.
+type DatabaseSchema struct {
+	// Internal is the internal schema version of the responding cluster member.
+	Internal uint64 `json:"internal" yaml:"internal"`
+
+	// External is the external schema version of the responding cluster member.
+	External uint64 `json:"external" yaml:"external"`
+
+	// Converged is true if all cluster members, except pending ones, have the same schema versions as the responding
+	// cluster member.
+	Converged bool `json:"converged" yaml:"converged"`
+
+	// Members holds the schema versions recorded for each cluster member.
+	Members []DatabaseSchemaMember `json:"members" yaml:"members"`
+
+	// Dump holds the statements creating every table, index, view and trigger of the database.
+	Dump string `json:"dump" yaml:"dump"`
+}
+
+// DatabaseSchemaMember holds the schema versions recorded for a cluster member.
+type DatabaseSchemaMember struct {
+	Name     string `json:"name" yaml:"name"`
+	Address  string `json:"address" yaml:"address"`
+	Role     string `json:"role" yaml:"role"`
+	Internal uint64 `json:"internal" yaml:"internal"`
+	External uint64 `json:"external" yaml:"external"`
+}
+
+// DatabaseStats holds aggregate statistics about the queries run against the database by a cluster member
+// since it started.
+type DatabaseStats struct {
+	// Queries is the total number of statements executed.
+	Queries uint64 `json:"queries" yaml:"queries"`
+
+	// Errors is the number of statements that failed.
+	Errors uint64 `json:"errors" yaml:"errors"`
+
+	// SlowQueries is the number of statements that took longer than the slow query threshold.
+	SlowQueries uint64 `json:"slow_queries" yaml:"slow_queries"`
+
+	// TotalDuration is the time spent executing all statements.
+	TotalDuration time.Duration `json:"total_duration" yaml:"total_duration"`
+
+	// SlowQueryThreshold is the duration above which statements are logged as slow.
+	SlowQueryThreshold time.Duration `json:"slow_query_threshold" yaml:"slow_query_threshold"`
+
+	// Statements holds the statistics of each distinct statement and caller.
+	Statements []DatabaseQueryStats `json:"statements" yaml:"statements"`
+}
+
+// DatabaseQueryStats holds statistics about a single statement run from a single caller.
+type DatabaseQueryStats struct {
+	// Query is the SQL text of the statement.
+	Query string `json:"query" yaml:"query"`
+
+	// Caller is the function that ran the statement.
+	Caller string `json:"caller" yaml:"caller"`
+
+	// Count is the number of times the statement was executed.
+	Count uint64 `json:"count" yaml:"count"`
+
+	// Errors is the number of executions that failed.
+	Errors uint64 `json:"errors" yaml:"errors"`
+
+	// SlowQueries is the number of executions that took longer than the slow query threshold.
+	SlowQueries uint64 `json:"slow_queries" yaml:"slow_queries"`
+
+	// TotalDuration is the time spent executing the statement.
+	TotalDuration time.Duration `json:"total_duration" yaml:"total_duration"`
+
+	// MaxDuration is the longest execution of the statement.
+	MaxDuration time.Duration `json:"max_duration" yaml:"max_duration"`
+}
+
+// DatabaseRaftState is the raft state persisted by the dqlite node of a cluster member.
+type DatabaseRaftState struct {
+	// Term is the current raft term of the node.
+	Term uint64 `json:"term" yaml:"term"`
+
+	// SnapshotTerm and SnapshotIndex identify the most recent snapshot taken by the node.
+	SnapshotTerm  uint64 `json:"snapshot_term" yaml:"snapshot_term"`
+	SnapshotIndex uint64 `json:"snapshot_index" yaml:"snapshot_index"`
+
+	// LastIndex and LastTerm are the index and term of the last entry of the raft log, including the entries of the
+	// segment being written, or of the most recent snapshot if the log holds no later entries.
+	LastIndex uint64 `json:"last_index" yaml:"last_index"`
+	LastTerm  uint64 `json:"last_term" yaml:"last_term"`
+}
+
+// Compare orders raft states by how up-to-date their raft log is, as raft does when electing a leader: by the term of
+// the last log entry, then by its index. It returns -1 if the log of s is less up-to-date than the log of other, 0 if
+// they are as up-to-date, and +1 otherwise.
+func (s DatabaseRaftState) Compare(other DatabaseRaftState) int {
+	return cmp.Or(cmp.Compare(s.LastTerm, other.LastTerm), cmp.Compare(s.LastIndex, other.LastIndex))
+}
+
+// DatabaseRaftFileType is the type of a file in the dqlite data directory.
+type DatabaseRaftFileType string
+
+const (
+	// DatabaseRaftMetadata is a raft metadata file, holding the current term and vote.
+	DatabaseRaftMetadata DatabaseRaftFileType = "metadata"
+
+	// DatabaseRaftClosedSegment is a closed raft log segment, holding the entries of its index range.
+	DatabaseRaftClosedSegment DatabaseRaftFileType = "closed-segment"
+
+	// DatabaseRaftOpenSegment is the raft log segment being written, or one preallocated for later entries.
+	DatabaseRaftOpenSegment DatabaseRaftFileType = "open-segment"
+
+	// DatabaseRaftSnapshot is a raft snapshot, or the ".meta" file describing it.
+	DatabaseRaftSnapshot DatabaseRaftFileType = "snapshot"
+)
+
+// DatabaseRaftFile is a raft file in the dqlite data directory of a cluster member.
+type DatabaseRaftFile struct {
+	// Name is the file name.
+	Name string `json:"name" yaml:"name"`
+
+	// Type is the type of the raft file.
+	Type DatabaseRaftFileType `json:"type" yaml:"type"`
+
+	// FirstIndex and LastIndex are the range of raft log entries held by a closed segment. For a snapshot, both are
+	// the index of the last entry it includes. They are unset for other files.
+	FirstIndex uint64 `json:"first_index" yaml:"first_index"`
+	LastIndex  uint64 `json:"last_index" yaml:"last_index"`
+
+	// Term is the term of the last entry included by a snapshot. It is unset for other files.
+	Term uint64 `json:"term" yaml:"term"`
+
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size" yaml:"size"`
+}
+
+// DatabaseRaftDump is the raft state persisted by the dqlite node of a cluster member, along with the raft files it was
+// read from.
+type DatabaseRaftDump struct {
+	DatabaseRaftState `yaml:",inline"`
+
+	// Files are the raft files of the dqlite data directory, sorted by name.
+	Files []DatabaseRaftFile `json:"files" yaml:"files"`
+}
+
+// DatabaseRaftCompaction is the result of compacting the raft files of a cluster member.
+type DatabaseRaftCompaction struct {
+	// Removed are the raft files that were removed.
+	Removed []DatabaseRaftFile `json:"removed" yaml:"removed"`
+
+	// Freed is the space freed in bytes.
+	Freed int64 `json:"freed" yaml:"freed"`
+}
+
+// DatabaseMember describes a dqlite cluster member, as seen from the dqlite leader.
+type DatabaseMember struct {
+	DatabaseRaftState `yaml:",inline"`
+
+	// Name is the name of the cluster member, if it is in the trust store.
+	Name string `json:"name" yaml:"name"`
+
+	// Address is the dqlite address of the member.
+	Address string `json:"address" yaml:"address"`
+
+	// DqliteID is the ID of the member's dqlite node.
+	DqliteID uint64 `json:"dqlite_id" yaml:"dqlite_id"`
+
+	// Role is the dqlite role of the member, such as "voter", "stand-by" or "spare".
+	Role string `json:"role" yaml:"role"`
+
+	// Leader is set for the dqlite leader.
+	Leader bool `json:"leader" yaml:"leader"`
+
+	// Reachable is whether the leader could retrieve the member's raft state. The raft state of unreachable
+	// members is left unset.
+	Reachable bool `json:"reachable" yaml:"reachable"`
+
+	// Lag is how many log entries the member's last index trails the leader's.
+	Lag uint64 `json:"lag" yaml:"lag"`
+}
+
+// DatabaseBackup is a backup of the database of a cluster member, stored in its state directory. Backups taken while
+// the daemon is running hold the database files dumped through dqlite, and those taken while it is stopped hold the
+// database directory.
+type DatabaseBackup struct {
+	// Name is the file name of the backup.
+	Name string `json:"name" yaml:"name"`
+
+	// Size is the size of the backup in bytes.
+	Size int64 `json:"size" yaml:"size"`
+
+	// CreatedAt is when the backup was taken.
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// DatabaseBackfill is the progress of a data backfill, run once across the cluster after the schema is updated.
+type DatabaseBackfill struct {
+	// Name identifies the backfill.
+	Name string `json:"name" yaml:"name"`
+
+	// Progress is the point from which the backfill resumes, as recorded by its last committed batch.
+	Progress string `json:"progress" yaml:"progress"`
+
+	// Batches is the number of batches committed so far.
+	Batches int64 `json:"batches" yaml:"batches"`
+
+	// Done is set once the backfill is complete.
+	Done bool `json:"done" yaml:"done"`
+
+	// UpdatedAt is when the backfill was registered, or last committed a batch.
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// DatabaseMaintenance selects the maintenance to run on the database.
+type DatabaseMaintenance struct {
+	// Vacuum rebuilds the database, reclaiming the space of deleted data.
+	Vacuum bool `json:"vacuum" yaml:"vacuum"`
+
+	// Analyze gathers statistics about the tables and indexes of the database, to help plan queries.
+	Analyze bool `json:"analyze" yaml:"analyze"`
+}
+
+// DatabaseUsage is the space used by the database.
+type DatabaseUsage struct {
+	// PageSize is the size of a database page in bytes.
+	PageSize int64 `json:"page_size" yaml:"page_size"`
+
+	// PageCount is the number of pages of the database, including free pages.
+	PageCount int64 `json:"page_count" yaml:"page_count"`
+
+	// FreePages is the number of unused pages, whose space is reclaimed by a vacuum.
+	FreePages int64 `json:"free_pages" yaml:"free_pages"`
+
+	// Members is the disk usage of the database on each cluster member.
+	Members []DatabaseMemberUsage `json:"members" yaml:"members"`
+}
+
+// DatabaseMemberUsage is the disk usage of the database on a cluster member.
+type DatabaseMemberUsage struct {
+	// Name is the name of the cluster member.
+	Name string `json:"name" yaml:"name"`
+
+	// Address is the address of the cluster member.
+	Address string `json:"address" yaml:"address"`
+
+	// Reachable is whether the disk usage of the member could be retrieved.
+	Reachable bool `json:"reachable" yaml:"reachable"`
+
+	// Size is the size in bytes of the database directory of the member, including raft segments and snapshots.
+	Size int64 `json:"size" yaml:"size"`
+}
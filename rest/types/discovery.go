@@ -0,0 +1,9 @@
+package types
+
+// DiscoveredPeer represents an uninitialized daemon that announced itself on the local network.
+type DiscoveredPeer struct {
+	Name        string   `json:"name" yaml:"name"`
+	Address     AddrPort `json:"address" yaml:"address"`
+	Fingerprint string   `json:"fingerprint" yaml:"fingerprint"`
+	Version     string   `json:"version" yaml:"version"`
+}
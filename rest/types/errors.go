@@ -0,0 +1,90 @@
+package types
+
+import (
+	"errors"
+)
+
+// ErrorCodeHeader is the header of API error responses holding the code of the exported error they wrap, if any.
+const ErrorCodeHeader = "X-Microcluster-Error-Code"
+
+var (
+	// ErrNotBootstrapped is returned when the cluster member is not part of a cluster yet.
+	ErrNotBootstrapped = errors.New(string(DatabaseNotReady))
+
+	// ErrAlreadyBootstrapped is returned when bootstrapping or joining a cluster from a cluster member that is already
+	// part of one.
+	ErrAlreadyBootstrapped = errors.New("Daemon is already initialized")
+
+	// ErrNotLeader is returned when a request that only the dqlite leader can serve reaches another cluster member.
+	ErrNotLeader = errors.New("Cluster member is not the dqlite leader")
+
+	// ErrDatabaseUnavailable is returned when a database transaction keeps failing because of a dqlite leadership
+	// change or a locked database, which is expected to resolve itself.
+	ErrDatabaseUnavailable = errors.New("Database is temporarily unavailable")
+
+	// ErrMemberNotFound is returned when no cluster member exists with the given name.
+	ErrMemberNotFound = errors.New("Cluster member not found")
+
+	// ErrTokenExpired is returned when joining a cluster with a join token past its expiry.
+	ErrTokenExpired = errors.New("Join token expired")
+
+	// ErrHookTimeout is returned when a hook does not complete within its configured timeout.
+	ErrHookTimeout = errors.New("Hook timed out")
+)
+
+// apiErrors are the exported errors identified in API responses, along with their error codes.
+var apiErrors = []struct {
+	code string
+	err  error
+}{
+	{code: "not-bootstrapped", err: ErrNotBootstrapped},
+	{code: "already-bootstrapped", err: ErrAlreadyBootstrapped},
+	{code: "not-leader", err: ErrNotLeader},
+	{code: "database-unavailable", err: ErrDatabaseUnavailable},
+	{code: "member-not-found", err: ErrMemberNotFound},
+	{code: "token-expired", err: ErrTokenExpired},
+	{code: "hook-timeout", err: ErrHookTimeout},
+}
+
+// ErrorCode returns the code of the exported error wrapped by err, such as ErrMemberNotFound, or an empty string if
+// it wraps none of them.
+func ErrorCode(err error) string {
+	for _, apiErr := range apiErrors {
+		if errors.Is(err, apiErr.err) {
+			return apiErr.code
+		}
+	}
+
+	return ""
+}
+
+// identifiedError is an error message received from the API, which matches one of the exported errors.
+type identifiedError struct {
+	message string
+	err     error
+}
+
+// Error returns the error message as received from the API.
+func (e identifiedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the exported error identified by the error code of the response.
+func (e identifiedError) Unwrap() error {
+	return e.err
+}
+
+// IdentifyError returns an error with the given message received from the API. If the error code of the response
+// identifies one of the exported errors, such as ErrMemberNotFound, the returned error matches it with errors.Is.
+// Cluster members keep the error code when responding with an error they received from another cluster member.
+func IdentifyError(code string, message string) error {
+	if code != "" {
+		for _, apiErr := range apiErrors {
+			if apiErr.code == code {
+				return identifiedError{message: message, err: apiErr.err}
+			}
+		}
+	}
+
+	return errors.New(message)
+}
@@ -0,0 +1,17 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+// ServerSentEvent is an event received from a server-sent event stream.
+type ServerSentEvent struct {
+	// ID identifies the event, if set by the server.
+	ID string `json:"id" yaml:"id"`
+
+	// Type is the name of the event, or "message" if the server did not set one.
+	Type string `json:"type" yaml:"type"`
+
+	// Data is the JSON encoded payload of the event.
+	Data json.RawMessage `json:"data" yaml:"data"`
+}
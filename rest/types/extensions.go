@@ -0,0 +1,31 @@
+package types
+
+// ClusterExtensions describes the API extensions supported across the cluster.
+type ClusterExtensions struct {
+	// Supported lists the API extensions supported by every cluster member.
+	Supported []string `json:"supported" yaml:"supported"`
+
+	// Lagging lists the cluster members that lack some of the API extensions supported by other members,
+	// for instance while the cluster is being upgraded.
+	Lagging []MemberExtensions `json:"lagging" yaml:"lagging"`
+}
+
+// MemberExtensions describes the API extensions that a cluster member lacks.
+type MemberExtensions struct {
+	// Name is the name of the cluster member.
+	Name string `json:"name" yaml:"name"`
+
+	// Missing lists the API extensions supported by other members, but not by this one.
+	Missing []string `json:"missing" yaml:"missing"`
+}
+
+// HasExtension returns whether the given API extension is supported by every cluster member.
+func (e ClusterExtensions) HasExtension(name string) bool {
+	for _, extension := range e.Supported {
+		if extension == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,13 @@
+package types
+
+// Health is the result of the health check of a cluster member, as served to load balancers and orchestrators.
+type Health struct {
+	// Database is the status of the local database.
+	Database DatabaseStatus `json:"database" yaml:"database"`
+
+	// Leader is the address of the dqlite leader reached by the cluster member.
+	Leader string `json:"leader" yaml:"leader"`
+
+	// Voters is the number of voting dqlite members, as reported by the leader.
+	Voters int `json:"voters" yaml:"voters"`
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"time"
+)
+
+// InitStage is a stage reached by the daemon while it bootstraps or joins a cluster.
+type InitStage string
+
+const (
+	// InitStageValidating is reported while the initialization request and any join token are checked.
+	InitStageValidating InitStage = "validating"
+
+	// InitStagePreInitHook is reported while the PreInit hook runs.
+	InitStagePreInitHook InitStage = "pre-init-hook"
+
+	// InitStageJoinRequest is reported while a cluster member from the join token is asked to admit the daemon.
+	InitStageJoinRequest InitStage = "join-request"
+
+	// InitStageCertificates is reported while the certificates received from the cluster are installed.
+	InitStageCertificates InitStage = "certificates"
+
+	// InitStageDatabase is reported while the database is created or joined, and its schema version is checked
+	// against the rest of the cluster.
+	InitStageDatabase InitStage = "database"
+
+	// InitStageHooks is reported while the post-bootstrap or join hooks run, and the other cluster members are
+	// notified of the new member.
+	InitStageHooks InitStage = "hooks"
+
+	// InitStageComplete is reported once the daemon has bootstrapped or joined the cluster.
+	InitStageComplete InitStage = "complete"
+)
+
+// InitProgress reports a stage reached by the daemon while it bootstraps or joins a cluster.
+type InitProgress struct {
+	Stage   InitStage `json:"stage" yaml:"stage"`
+	Message string    `json:"message" yaml:"message"`
+	Time    time.Time `json:"time" yaml:"time"`
+}
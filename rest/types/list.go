@@ -0,0 +1,79 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ListOptions selects which items of a collection endpoint are returned, and which of their fields.
+type ListOptions struct {
+	// Limit is the maximum number of items to return. If 0, all items are returned.
+	Limit int
+
+	// Offset is the number of items to skip, after filtering.
+	Offset int
+
+	// Filters only returns items whose field, as named in the item's JSON representation, has the given value.
+	// Each endpoint supports its own set of filters.
+	Filters map[string]string
+
+	// Fields only returns the given fields of each item, as named in the item's JSON representation.
+	Fields []string
+}
+
+// Values returns the options as URL query parameters.
+func (o ListOptions) Values() url.Values {
+	values := url.Values{}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.Itoa(o.Limit))
+	}
+
+	if o.Offset > 0 {
+		values.Set("offset", strconv.Itoa(o.Offset))
+	}
+
+	if len(o.Fields) > 0 {
+		values.Set("fields", strings.Join(o.Fields, ","))
+	}
+
+	for key, value := range o.Filters {
+		values.Set(key, value)
+	}
+
+	return values
+}
+
+// ParseListOptions parses the options from URL query parameters. Only parameters named in filterKeys are
+// considered filters.
+func ParseListOptions(values url.Values, filterKeys ...string) (ListOptions, error) {
+	opts := ListOptions{Filters: map[string]string{}}
+
+	var err error
+	if values.Has("limit") {
+		opts.Limit, err = strconv.Atoi(values.Get("limit"))
+		if err != nil || opts.Limit < 0 {
+			return ListOptions{}, fmt.Errorf("Invalid limit %q", values.Get("limit"))
+		}
+	}
+
+	if values.Has("offset") {
+		opts.Offset, err = strconv.Atoi(values.Get("offset"))
+		if err != nil || opts.Offset < 0 {
+			return ListOptions{}, fmt.Errorf("Invalid offset %q", values.Get("offset"))
+		}
+	}
+
+	if values.Get("fields") != "" {
+		opts.Fields = strings.Split(values.Get("fields"), ",")
+	}
+
+	for _, key := range filterKeys {
+		if values.Has(key) {
+			opts.Filters[key] = values.Get(key)
+		}
+	}
+
+	return opts, nil
+}
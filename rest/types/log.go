@@ -0,0 +1,62 @@
+package types
+
+import (
+	"fmt"
+)
+
+// LogLevel is the verbosity of the daemon's log.
+type LogLevel string
+
+const (
+	// LogLevelDebug logs debug, informational, warning and error messages.
+	LogLevelDebug LogLevel = "debug"
+
+	// LogLevelInfo logs informational, warning and error messages.
+	LogLevelInfo LogLevel = "info"
+
+	// LogLevelWarn logs warning and error messages.
+	LogLevelWarn LogLevel = "warn"
+)
+
+// LogTarget is where the daemon's log is written. The log is always written to stderr as well.
+type LogTarget string
+
+const (
+	// LogTargetStderr only writes the log to stderr.
+	LogTargetStderr LogTarget = "stderr"
+
+	// LogTargetFile writes the log to the daemon.log file of the log directory, which defaults to the state directory.
+	LogTargetFile LogTarget = "file"
+
+	// LogTargetSyslog writes the log to the local syslog daemon.
+	LogTargetSyslog LogTarget = "syslog"
+
+	// LogTargetJournald writes the log to the systemd journal.
+	LogTargetJournald LogTarget = "journald"
+)
+
+// LogConfig is the logging configuration of the daemon, which can be changed at runtime.
+type LogConfig struct {
+	// Level is the verbosity of the log. If empty when updating the configuration, the level is left unchanged.
+	Level LogLevel `json:"level" yaml:"level"`
+
+	// Target is where the log is written. If empty when updating the configuration, the target is left unchanged.
+	Target LogTarget `json:"target" yaml:"target"`
+}
+
+// Validate checks that the level and target, if set, are supported.
+func (c LogConfig) Validate() error {
+	switch c.Level {
+	case "", LogLevelDebug, LogLevelInfo, LogLevelWarn:
+	default:
+		return fmt.Errorf("Invalid log level %q", c.Level)
+	}
+
+	switch c.Target {
+	case "", LogTargetStderr, LogTargetFile, LogTargetSyslog, LogTargetJournald:
+	default:
+		return fmt.Errorf("Invalid log target %q", c.Target)
+	}
+
+	return nil
+}
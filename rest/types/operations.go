@@ -0,0 +1,45 @@
+package types
+
+import (
+	"time"
+)
+
+// OperationStatus is the state of an asynchronous operation.
+type OperationStatus string
+
+const (
+	// OperationRunning is the status of an operation that has not finished yet.
+	OperationRunning OperationStatus = "running"
+
+	// OperationSuccess is the status of an operation that finished without error.
+	OperationSuccess OperationStatus = "success"
+
+	// OperationFailure is the status of an operation that returned an error.
+	OperationFailure OperationStatus = "failure"
+
+	// OperationCancelled is the status of an operation that was cancelled before it finished.
+	OperationCancelled OperationStatus = "cancelled"
+)
+
+// Operation represents a long-running action started by an API request, which runs in the background on the
+// cluster member that received the request.
+type Operation struct {
+	ID          string          `json:"id" yaml:"id"`
+	Description string          `json:"description" yaml:"description"`
+	Status      OperationStatus `json:"status" yaml:"status"`
+
+	// Progress holds arbitrary progress information reported by the operation while it runs.
+	Progress map[string]any `json:"progress,omitempty" yaml:"progress,omitempty"`
+
+	// Result holds the value returned by the operation once it succeeds.
+	Result any `json:"result,omitempty" yaml:"result,omitempty"`
+
+	Err       string    `json:"err,omitempty" yaml:"err,omitempty"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// Done returns whether the operation has finished.
+func (o Operation) Done() bool {
+	return o.Status != OperationRunning
+}
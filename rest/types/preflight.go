@@ -0,0 +1,36 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// JoinPreflightCheck is the outcome of a check run before joining a cluster.
+type JoinPreflightCheck struct {
+	// Name identifies the check, such as "token", "schema" or "clock".
+	Name string `json:"name" yaml:"name"`
+
+	// Error describes why the check failed. It is empty if the check passed.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// JoinPreflight holds the outcome of the checks run by a prospective cluster member against an existing cluster,
+// without joining it.
+type JoinPreflight struct {
+	// Address is the address of the existing cluster member that ran the checks.
+	Address AddrPort `json:"address" yaml:"address"`
+
+	Checks []JoinPreflightCheck `json:"checks" yaml:"checks"`
+}
+
+// Err returns an error describing every failed check, or nil if all checks passed.
+func (p JoinPreflight) Err() error {
+	errs := []error{}
+	for _, check := range p.Checks {
+		if check.Error != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", check.Name, check.Error))
+		}
+	}
+
+	return errors.Join(errs...)
+}
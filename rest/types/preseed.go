@@ -0,0 +1,50 @@
+package types
+
+import (
+	"fmt"
+)
+
+// Preseed declares how a daemon is initialized, so that it can be provisioned without interaction.
+type Preseed struct {
+	// Name of the cluster member. It defaults to the hostname.
+	Name string `json:"name" yaml:"name"`
+
+	// Address is advertised to the other cluster members, and must be reachable by them.
+	Address AddrPort `json:"address" yaml:"address"`
+
+	// ListenAddress is the address the API listens on, if not Address.
+	ListenAddress AddrPort `json:"listen_address" yaml:"listen_address"`
+
+	// DNSName resolves to Address, and is looked up again by other cluster members that fail to reach the member.
+	DNSName string `json:"dns_name" yaml:"dns_name"`
+
+	// Bootstrap creates a new cluster with the daemon as its only member.
+	Bootstrap bool `json:"bootstrap" yaml:"bootstrap"`
+
+	// JoinToken joins an existing cluster with a join token issued by one of its members.
+	JoinToken string `json:"join_token" yaml:"join_token"`
+
+	// InitConfig is passed to the initialization hooks of the consumer.
+	InitConfig map[string]string `json:"init_config" yaml:"init_config"`
+
+	// Certificates are keypairs to install before bootstrapping, keyed by certificate name, such as the cluster
+	// certificate or the certificate of an extension server. Joining members receive them from the cluster instead.
+	Certificates map[string]KeyPair `json:"certificates" yaml:"certificates"`
+}
+
+// Validate checks that the preseed describes exactly one way of initializing the daemon.
+func (p Preseed) Validate() error {
+	if p.Bootstrap == (p.JoinToken != "") {
+		return fmt.Errorf("Preseed must either bootstrap a cluster or join one with a token")
+	}
+
+	if !p.Address.IsValid() {
+		return fmt.Errorf("Preseed must have a valid address")
+	}
+
+	if len(p.Certificates) > 0 && !p.Bootstrap {
+		return fmt.Errorf("Certificates can only be preseeded when bootstrapping a cluster")
+	}
+
+	return nil
+}
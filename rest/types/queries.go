@@ -0,0 +1,21 @@
+package types
+
+// PreparedQuery describes a database query registered by the MicroCluster consumer.
+type PreparedQuery struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	Parameters  []string `json:"parameters" yaml:"parameters"`
+}
+
+// PreparedQueryPost holds the arguments of a prepared query, keyed by parameter name.
+type PreparedQueryPost struct {
+	Args map[string]any `json:"args" yaml:"args"`
+}
+
+// PreparedQueryResult is the result of running a prepared query. Queries returning rows set the columns and rows,
+// while others set the number of affected rows.
+type PreparedQueryResult struct {
+	Columns      []string `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Rows         [][]any  `json:"rows,omitempty" yaml:"rows,omitempty"`
+	RowsAffected int64    `json:"rows_affected" yaml:"rows_affected"`
+}
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"time"
+)
+
+// SessionPost is used to request a bearer token standing for the certificate of a trusted API client, for clients
+// that can't easily authenticate with their certificate.
+type SessionPost struct {
+	// Name is the name of the trusted certificate the token stands for. It is only used for requests over the unix
+	// socket. Otherwise, the token stands for the certificate the request was authenticated with.
+	Name string `json:"name" yaml:"name"`
+
+	// ExpiresAt is when the token expires. It defaults to an hour from now, and can't be more than a day away.
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
+// Session is a bearer token standing for the certificate of a trusted API client. It is sent in the Authorization
+// header of requests as "Bearer <token>", and is accepted by every cluster member until it expires, the certificate
+// is revoked, or the cluster certificate is replaced.
+type Session struct {
+	// Token is the bearer token.
+	Token string `json:"token" yaml:"token"`
+
+	// Fingerprint is the fingerprint of the trusted certificate the token stands for.
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+
+	// ExpiresAt is when the token expires.
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+}
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"time"
+)
+
+// TaskScope determines which cluster members run a background task.
+type TaskScope string
+
+const (
+	// TaskScopeLeader runs the task only on the cluster member that is currently the dqlite leader.
+	TaskScopeLeader TaskScope = "leader"
+
+	// TaskScopeMember runs the task on every cluster member.
+	TaskScopeMember TaskScope = "member"
+)
+
+// TaskStatus represents the state of a background task registered with the daemon's task scheduler.
+type TaskStatus struct {
+	Name      string        `json:"name" yaml:"name"`
+	Scope     TaskScope     `json:"scope" yaml:"scope"`
+	Interval  time.Duration `json:"interval" yaml:"interval"`
+	OneShot   bool          `json:"one_shot" yaml:"one_shot"`
+	Running   bool          `json:"running" yaml:"running"`
+	Done      bool          `json:"done" yaml:"done"`
+	Runs      uint64        `json:"runs" yaml:"runs"`
+	LastRun   time.Time     `json:"last_run" yaml:"last_run"`
+	LastError string        `json:"last_error" yaml:"last_error"`
+	NextRun   time.Time     `json:"next_run" yaml:"next_run"`
+}
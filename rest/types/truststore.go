@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+)
+
+// TrustedCertificateType identifies why a certificate is trusted.
+type TrustedCertificateType string
+
+const (
+	// TrustedCertificateMember is the certificate of a cluster member, managed by joining and removing members.
+	TrustedCertificateMember TrustedCertificateType = "member"
+
+	// TrustedCertificateClient is the certificate of an API client that is not a cluster member.
+	TrustedCertificateClient TrustedCertificateType = "client"
+)
+
+// TrustedCertificate is an entry of the trust store.
+type TrustedCertificate struct {
+	Name string                 `json:"name" yaml:"name"`
+	Type TrustedCertificateType `json:"type" yaml:"type"`
+
+	// Address is the address of the cluster member. It is empty for client certificates.
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+
+	Fingerprint string          `json:"fingerprint" yaml:"fingerprint"`
+	Certificate X509Certificate `json:"certificate" yaml:"certificate"`
+}
+
+// TrustedCertificatePost is used to trust the certificate of an API client that is not a cluster member.
+type TrustedCertificatePost struct {
+	Name        string          `json:"name" yaml:"name"`
+	Certificate X509Certificate `json:"certificate" yaml:"certificate"`
+}
+
+// CertificateRevocation is a revoked certificate. Connections presenting it are rejected by every cluster member,
+// even if the certificate belongs to a cluster member or a trusted client.
+type CertificateRevocation struct {
+	Fingerprint string    `json:"fingerprint" yaml:"fingerprint"`
+	Reason      string    `json:"reason" yaml:"reason"`
+	RevokedAt   time.Time `json:"revoked_at" yaml:"revoked_at"`
+}
+
+// CertificateRevocationPost is used to revoke a certificate, by its fingerprint, across the cluster.
+type CertificateRevocationPost struct {
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+	Reason      string `json:"reason" yaml:"reason"`
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"github.com/canonical/microcluster/v3/internal/extensions"
+)
+
+// UpgradeStage represents the progress of a cluster member through a coordinated schema and API upgrade.
+type UpgradeStage string
+
+const (
+	// UpgradeStageNone should be the UpgradeStage when the cluster member has not taken part in an upgrade since it started.
+	UpgradeStageNone UpgradeStage = "NONE"
+
+	// UpgradeStagePending should be the UpgradeStage when the cluster member has restarted with a newer schema or
+	// API extensions, and is waiting for the other cluster members to do the same.
+	UpgradeStagePending UpgradeStage = "PENDING"
+
+	// UpgradeStageCommitted should be the UpgradeStage when all cluster members were ready and the schema updates have
+	// been applied.
+	UpgradeStageCommitted UpgradeStage = "COMMITTED"
+)
+
+// ClusterUpgradeStatus represents the progress of a coordinated upgrade, as seen by a cluster member.
+type ClusterUpgradeStatus struct {
+	Stage                 UpgradeStage          `json:"stage" yaml:"stage"`
+	SchemaInternalVersion uint64                `json:"schema_internal_version" yaml:"schema_internal_version"`
+	SchemaExternalVersion uint64                `json:"schema_external_version" yaml:"schema_external_version"`
+	Extensions            extensions.Extensions `json:"extensions" yaml:"extensions"`
+	Members               []UpgradeMemberStatus `json:"members" yaml:"members"`
+}
+
+// UpgradeMemberStatus represents the versions recorded by a cluster member, and whether they match the versions of the
+// cluster member reporting the upgrade status.
+type UpgradeMemberStatus struct {
+	Name                  string                `json:"name" yaml:"name"`
+	SchemaInternalVersion uint64                `json:"schema_internal_version" yaml:"schema_internal_version"`
+	SchemaExternalVersion uint64                `json:"schema_external_version" yaml:"schema_external_version"`
+	Extensions            extensions.Extensions `json:"extensions" yaml:"extensions"`
+	Ready                 bool                  `json:"ready" yaml:"ready"`
+}
@@ -0,0 +1,7 @@
+package types
+
+// APIVersions lists the versions of the core API served by a daemon.
+type APIVersions struct {
+	// Versions holds the prefixes of each version of the core API, such as "core/1.0", from the oldest to the newest.
+	Versions []EndpointPrefix `json:"versions" yaml:"versions"`
+}
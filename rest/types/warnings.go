@@ -0,0 +1,59 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// WarningStatus is the status of a warning.
+type WarningStatus string
+
+const (
+	// WarningStatusNew is the status of a warning that has not been acknowledged.
+	WarningStatusNew WarningStatus = "new"
+
+	// WarningStatusAcknowledged is the status of a warning that has been acknowledged. It is kept until the condition
+	// it reports is resolved, but no longer included in the status of the daemon.
+	WarningStatusAcknowledged WarningStatus = "acknowledged"
+)
+
+// Validate checks that the status is known.
+func (s WarningStatus) Validate() error {
+	switch s {
+	case WarningStatusNew, WarningStatusAcknowledged:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid warning status %q", s)
+}
+
+const (
+	// WarningCertificateExpiring is filed for a certificate loaded by a cluster member that is about to expire.
+	WarningCertificateExpiring = "certificate-expiring"
+
+	// WarningMemberOffline is filed by the dqlite leader for a cluster member that did not respond to a heartbeat.
+	WarningMemberOffline = "member-offline"
+
+	// WarningSchemaDivergence is filed by the dqlite leader for a cluster member whose schema version is behind the
+	// rest of the cluster.
+	WarningSchemaDivergence = "schema-divergence"
+)
+
+// Warning reports a condition about a cluster member that needs attention. A warning is filed once per cluster
+// member, type and entity, and counts how many times the condition was seen until it is resolved.
+type Warning struct {
+	UUID        string        `json:"uuid" yaml:"uuid"`
+	Member      string        `json:"member" yaml:"member"`
+	Type        string        `json:"type" yaml:"type"`
+	Entity      string        `json:"entity" yaml:"entity"`
+	Message     string        `json:"message" yaml:"message"`
+	Status      WarningStatus `json:"status" yaml:"status"`
+	Count       int           `json:"count" yaml:"count"`
+	FirstSeenAt time.Time     `json:"first_seen_at" yaml:"first_seen_at"`
+	LastSeenAt  time.Time     `json:"last_seen_at" yaml:"last_seen_at"`
+}
+
+// WarningPut is used to acknowledge a warning, or to mark it as new again.
+type WarningPut struct {
+	Status WarningStatus `json:"status" yaml:"status"`
+}
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/gorilla/websocket"
+)
+
+// websocketUpgrader upgrades requests that have already been authenticated by the endpoint's access handlers,
+// so the origin of the request is not checked.
+var websocketUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// websocketResponse is a response that upgrades the connection to a websocket and hands it over to a stream function.
+type websocketResponse struct {
+	r      *http.Request
+	stream func(conn *websocket.Conn) error
+}
+
+// WebsocketResponse returns a response that upgrades the request's connection to a websocket, and then calls stream
+// with the connection. The connection is closed once stream returns.
+// As the connection is hijacked, any error returned by stream is only logged. Requests to an endpoint action with
+// ProxyTarget set are forwarded to the target cluster member with their websocket intact.
+func WebsocketResponse(r *http.Request, stream func(conn *websocket.Conn) error) response.Response {
+	return &websocketResponse{r: r, stream: stream}
+}
+
+// Render upgrades the connection and runs the stream function.
+func (resp *websocketResponse) Render(w http.ResponseWriter) error {
+	if !websocket.IsWebSocketUpgrade(resp.r) {
+		return response.BadRequest(fmt.Errorf("Expected a websocket upgrade request")).Render(w)
+	}
+
+	// The upgrader replies to the request itself if the upgrade fails, so there is nothing left to render.
+	conn, err := websocketUpgrader.Upgrade(w, resp.r, nil)
+	if err != nil {
+		logger.Error("Failed to upgrade connection to websocket", logger.Ctx{"url": resp.r.URL.String(), "error": err})
+		return nil
+	}
+
+	defer conn.Close()
+
+	err = resp.stream(conn)
+	if err != nil {
+		logger.Error("Websocket stream failed", logger.Ctx{"url": resp.r.URL.String(), "error": err})
+	}
+
+	return nil
+}
+
+// String returns the response type.
+func (resp *websocketResponse) String() string {
+	return "websocket"
+}
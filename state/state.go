@@ -1,9 +1,47 @@
 package state
 
-import "github.com/canonical/microcluster/v3/internal/state"
+import (
+	"github.com/canonical/microcluster/v3/internal/db"
+	"github.com/canonical/microcluster/v3/internal/operations"
+	"github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/internal/tasks"
+)
 
 // State exposes the internal daemon state for use with extended API handlers.
 type State = state.State
 
 // Hooks exposes the Hooks struct to be imported by the upstream project.
 type Hooks = state.Hooks
+
+// HookHandler is a named set of hooks registered by a single subsystem, run alongside the hooks of other subsystems.
+type HookHandler = state.HookHandler
+
+// HookTimeouts configures how long hooks may run before they are abandoned.
+type HookTimeouts = state.HookTimeouts
+
+// Task is a background job that can be registered with the scheduler returned by State.Tasks.
+type Task = tasks.Task
+
+// TaskScheduler runs background tasks until the daemon shuts down.
+type TaskScheduler = tasks.Scheduler
+
+// Lock is a cluster-wide lock acquired with State.Lock.
+type Lock = db.Lock
+
+// Operation is a long-running action started with the manager returned by State.Operations.
+type Operation = operations.Operation
+
+// OperationFunc is the work of an operation.
+type OperationFunc = operations.Func
+
+// PreInitState is the part of the daemon state that can be used before the daemon is initialized, by extension
+// endpoints with AllowedBeforeInit set.
+type PreInitState = state.PreInitState
+
+// ToPreInit returns the part of the given State that can be used before the daemon is initialized.
+func ToPreInit(s State) (PreInitState, error) {
+	return state.ToPreInit(s)
+}
+
+// PreparedQuery is a named, parameterized database statement that clients can run through /core/1.0/queries/{name}.
+type PreparedQuery = state.PreparedQuery